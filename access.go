@@ -0,0 +1,118 @@
+package cloudstorage
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ReadAccess is one recorded ReadAt call, of the client or of an ObjectReaderAt
+type ReadAccess struct {
+	// Op is the reading operation, ReadAt or ReaderAt
+	Op        string `json:"op"`
+	Bucket    string `json:"bucket"`
+	Object    string `json:"object"`
+	RequestID string `json:"request_id"`
+	Offset    int64  `json:"offset"`
+	// Length is the requested length, Bytes the length read
+	Length   int           `json:"length"`
+	Bytes    int           `json:"bytes"`
+	Duration time.Duration `json:"duration_ns"`
+	// CacheHit is set for reads served from the read-ahead window
+	CacheHit bool `json:"cache_hit"`
+	// Error is the read's error message, end of file excluded
+	Error string `json:"error,omitempty"`
+}
+
+// AccessRecorder receives recorded reads, must be safe for concurrent use
+type AccessRecorder interface {
+	RecordAccess(ReadAccess)
+}
+
+// accessStart returns the start time of a read, zero when reads aren't recorded
+func (cs *cloudStorageClient) accessStart() time.Time {
+	if cs.config.AccessRecorder == nil {
+		return time.Time{}
+	}
+	return cs.now()
+}
+
+// recordAccess records a read started at given time to the configured access recorder,
+// one of every AccessSampleEvery reads when sampling
+func (cs *cloudStorageClient) recordAccess(op *operation, off int64, length, n int, start time.Time, hit bool, err error) {
+	rec := cs.config.AccessRecorder
+	if rec == nil {
+		return
+	}
+	if every := cs.config.AccessSampleEvery; every > 1 && (atomic.AddUint64(&cs.accessSeq, 1)-1)%uint64(every) != 0 {
+		return
+	}
+	a := ReadAccess{
+		Op:        op.name,
+		Bucket:    op.bucket,
+		Object:    op.object,
+		RequestID: op.requestID,
+		Offset:    off,
+		Length:    length,
+		Bytes:     n,
+		Duration:  cs.since(start),
+		CacheHit:  hit,
+	}
+	if err != nil && err != io.EOF {
+		a.Error = err.Error()
+	}
+	op.logger.Debug("read access", zap.String("filepath", op.object), zap.Int64("offset", off), zap.Int("length", length), zap.Int("bytes", n), zap.Duration("duration", a.Duration), zap.Bool("cacheHit", hit))
+	rec.RecordAccess(a)
+}
+
+// MemoryAccessRecorder keeps recorded reads in memory, for tests & short captures
+type MemoryAccessRecorder struct {
+	mu       sync.Mutex
+	accesses []ReadAccess
+}
+
+// RecordAccess appends the read
+func (m *MemoryAccessRecorder) RecordAccess(a ReadAccess) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accesses = append(m.accesses, a)
+}
+
+// Dump returns the recorded reads in record order
+func (m *MemoryAccessRecorder) Dump() []ReadAccess {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]ReadAccess{}, m.accesses...)
+}
+
+// NDJSONAccessRecorder writes recorded reads as line delimited JSON, for offline analysis
+type NDJSONAccessRecorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewNDJSONAccessRecorder returns a recorder writing to given writer, writes are serialized
+func NewNDJSONAccessRecorder(w io.Writer) *NDJSONAccessRecorder {
+	return &NDJSONAccessRecorder{enc: json.NewEncoder(w)}
+}
+
+// RecordAccess writes the read as one JSON line, reads after a write error are dropped
+func (r *NDJSONAccessRecorder) RecordAccess(a ReadAccess) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = r.enc.Encode(a)
+	}
+}
+
+// Err returns the first write error
+func (r *NDJSONAccessRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
@@ -0,0 +1,147 @@
+package cloudstorage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func accessFixture(t *testing.T) (*cloudStorageClient, CloudFileRequest) {
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", bytes.Repeat([]byte("0123456789"), 100), nil)
+	cs := newFakeClient(t, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+	return cs, cfr
+}
+
+func TestAccessRecorder(t *testing.T) {
+	cs, cfr := accessFixture(t)
+	rec := &MemoryAccessRecorder{}
+	cs.config.AccessRecorder = rec
+
+	ra, err := cs.NewReaderAt(context.Background(), cfr, WithReadAhead(400))
+	require.NoError(t, err)
+	defer ra.Close()
+	p := make([]byte, 100)
+	for _, off := range []int64{0, 100, 200, 700} {
+		_, err := ra.ReadAt(p, off)
+		require.NoError(t, err)
+	}
+	_, err = ra.ReadAt(p, 950)
+	require.ErrorIs(t, err, io.EOF)
+	_, err = cs.ReadAt(context.Background(), cfr, p[:10], 500)
+	require.NoError(t, err)
+
+	accesses := rec.Dump()
+	require.Len(t, accesses, 6)
+	// the sequential read fetches the window, the next one is served from it
+	for i, want := range []struct {
+		op     string
+		offset int64
+		length int
+		bytes  int
+		hit    bool
+	}{
+		{"ReaderAt", 0, 100, 100, false},
+		{"ReaderAt", 100, 100, 100, false},
+		{"ReaderAt", 200, 100, 100, true},
+		{"ReaderAt", 700, 100, 100, false},
+		{"ReaderAt", 950, 100, 50, false},
+		{"ReadAt", 500, 10, 10, false},
+	} {
+		a := accesses[i]
+		require.Equal(t, want.op, a.Op, i)
+		require.Equal(t, "bucket", a.Bucket)
+		require.Equal(t, "path/file.bin", a.Object)
+		require.Equal(t, want.offset, a.Offset, i)
+		require.Equal(t, want.length, a.Length, i)
+		require.Equal(t, want.bytes, a.Bytes, i)
+		require.Equal(t, want.hit, a.CacheHit, i)
+		require.Empty(t, a.Error, "end of file isn't an error")
+		require.GreaterOrEqual(t, int64(a.Duration), int64(0))
+	}
+}
+
+func TestAccessSampling(t *testing.T) {
+	cs, cfr := accessFixture(t)
+	rec := &MemoryAccessRecorder{}
+	cs.config.AccessRecorder = rec
+	cs.config.AccessSampleEvery = 3
+
+	ra, err := cs.NewReaderAt(context.Background(), cfr)
+	require.NoError(t, err)
+	defer ra.Close()
+	p := make([]byte, 10)
+	for off := int64(0); off < 70; off += 10 {
+		_, err := ra.ReadAt(p, off)
+		require.NoError(t, err)
+	}
+	offsets := []int64{}
+	for _, a := range rec.Dump() {
+		offsets = append(offsets, a.Offset)
+	}
+	require.Equal(t, []int64{0, 30, 60}, offsets)
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestNDJSONAccessRecorder(t *testing.T) {
+	cs, cfr := accessFixture(t)
+	var buf bytes.Buffer
+	rec := NewNDJSONAccessRecorder(&buf)
+	cs.config.AccessRecorder = rec
+
+	p := make([]byte, 10)
+	for _, off := range []int64{0, 20} {
+		_, err := cs.ReadAt(context.Background(), cfr, p, off)
+		require.NoError(t, err)
+	}
+	require.NoError(t, rec.Err())
+	sc := bufio.NewScanner(&buf)
+	lines := []ReadAccess{}
+	for sc.Scan() {
+		var a ReadAccess
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &a))
+		lines = append(lines, a)
+	}
+	require.Len(t, lines, 2)
+	require.Equal(t, int64(20), lines[1].Offset)
+	require.Equal(t, 10, lines[1].Bytes)
+
+	failing := NewNDJSONAccessRecorder(failingWriter{})
+	failing.RecordAccess(ReadAccess{})
+	failing.RecordAccess(ReadAccess{})
+	require.EqualError(t, failing.Err(), "disk full")
+}
+
+func TestAccessRecorderDisabledAllocs(t *testing.T) {
+	cs, cfr := accessFixture(t)
+	ra, err := cs.NewReaderAt(context.Background(), cfr, WithReadAhead(1000))
+	require.NoError(t, err)
+	defer ra.Close()
+	p := make([]byte, 10)
+	_, err = ra.ReadAt(p, 0)
+	require.NoError(t, err)
+	_, err = ra.ReadAt(p, 10)
+	require.NoError(t, err)
+
+	// window hits don't allocate without recorder
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := ra.ReadAt(p, 20); err != nil {
+			t.Fatal(err)
+		}
+	})
+	require.Zero(t, allocs)
+}
@@ -1,12 +1,11 @@
-
-package api 
+package api
 
 import (
 	"errors"
 	"regexp"
-	"strings" 
+	"strings"
 
-	"github.com/micro/go-micro/registry"   
+	"github.com/micro/go-micro/registry"
 	"github.com/micro/go-micro/server"
 )
 
@@ -0,0 +1,298 @@
+package cloudstorage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_GETTING_ATTRS            string = "error getting cloud file attributes"
+	ERROR_UPDATING_METADATA        string = "error updating cloud file metadata"
+	ERROR_NOT_SUPPORTED_BY_BACKEND string = "not supported by the storage backend"
+)
+
+var (
+	ErrNotSupportedByBackend = errors.NewAppError(ERROR_NOT_SUPPORTED_BY_BACKEND)
+)
+
+// AttrField is a bitmask of the ObjectAttrs fields not every backend populates
+type AttrField uint32
+
+const (
+	AttrGeneration AttrField = 1 << iota
+	AttrMetageneration
+	AttrCRC32C
+	AttrMD5
+	AttrEtag
+	AttrStorageClass
+	AttrCreated
+	AttrUpdated
+)
+
+// GCS_ATTR_FIELDS are the fields Cloud Storage populates for every object, MD5 is also set
+// for objects that weren't composed
+const GCS_ATTR_FIELDS = AttrGeneration | AttrMetageneration | AttrCRC32C | AttrEtag | AttrStorageClass | AttrCreated | AttrUpdated
+
+var attrFieldNames = []string{"generation", "metageneration", "crc32c", "md5", "etag", "storage_class", "created", "updated"}
+
+// String returns the names of the fields, separated by |
+func (f AttrField) String() string {
+	names := []string{}
+	for i, name := range attrFieldNames {
+		if f&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// NotSupportedError is returned by features depending on object attributes the backend didn't populate,
+// e.g. checksum verification of an object without checksums, matches ErrNotSupportedByBackend with errors.Is
+type NotSupportedError struct {
+	Feature string
+	Bucket  string
+	Object  string
+	// Missing are the fields the feature depends on the object's attributes don't have
+	Missing AttrField
+}
+
+func (e NotSupportedError) Error() string {
+	return fmt.Sprintf("%s %s for %s/%s: %s not populated", e.Feature, ERROR_NOT_SUPPORTED_BY_BACKEND, e.Bucket, e.Object, e.Missing)
+}
+
+// Is matches ErrNotSupportedByBackend
+func (e NotSupportedError) Is(target error) bool {
+	return target == ErrNotSupportedByBackend
+}
+
+// requireAttrs returns a NotSupportedError unless given attributes have the fields given feature depends on
+func requireAttrs(attrs *ObjectAttrs, fields AttrField, feature string) error {
+	if missing := fields &^ attrs.Valid; missing != 0 {
+		return NotSupportedError{Feature: feature, Bucket: attrs.Bucket, Object: attrs.Name, Missing: missing}
+	}
+	return nil
+}
+
+// ObjectAttrs are the attributes of a cloud file
+type ObjectAttrs struct {
+	Bucket string
+	Name   string
+	// NameNotDecoded is set when the client's NameCodec failed to decode the stored name,
+	// e.g. in a bucket with names stored before it was set, Name is then the stored name
+	NameNotDecoded     bool
+	Size               int64
+	ContentType        string
+	ContentEncoding    string
+	ContentLanguage    string
+	ContentDisposition string
+	CacheControl       string
+	CRC32C             uint32
+	MD5                []byte
+	// HasMD5 is set when the object has an MD5, composed objects have a CRC32C only
+	HasMD5 bool
+	// Etag is the HTTP entity tag of the object, changes with content or metadata
+	Etag string
+	// Generation is the content version of the object
+	Generation int64
+	// Metageneration is the metadata version of the object's generation,
+	// use with WithIfMetagenerationMatch for read-modify-write
+	Metageneration int64
+	StorageClass   string
+	Metadata       map[string]string
+	Created        time.Time
+	Updated        time.Time
+	// Valid are the optional fields the backend populated, the others are zero & not to be relied on,
+	// e.g. a zero Generation of a backend without generations isn't a missing object
+	Valid AttrField
+}
+
+// Has reports whether the backend populated all given fields
+func (a *ObjectAttrs) Has(fields AttrField) bool {
+	return a.Valid&fields == fields
+}
+
+func newObjectAttrs(attrs *storage.ObjectAttrs) *ObjectAttrs {
+	if attrs == nil {
+		return nil
+	}
+	valid := GCS_ATTR_FIELDS
+	if len(attrs.MD5) > 0 {
+		valid |= AttrMD5
+	}
+	return &ObjectAttrs{
+		Bucket:             attrs.Bucket,
+		Name:               attrs.Name,
+		Size:               attrs.Size,
+		ContentType:        attrs.ContentType,
+		ContentEncoding:    attrs.ContentEncoding,
+		ContentLanguage:    attrs.ContentLanguage,
+		ContentDisposition: attrs.ContentDisposition,
+		CacheControl:       attrs.CacheControl,
+		CRC32C:             attrs.CRC32C,
+		MD5:                attrs.MD5,
+		HasMD5:             len(attrs.MD5) > 0,
+		Etag:               attrs.Etag,
+		Generation:         attrs.Generation,
+		Metageneration:     attrs.Metageneration,
+		StorageClass:       attrs.StorageClass,
+		Metadata:           attrs.Metadata,
+		Created:            attrs.Created,
+		Updated:            attrs.Updated,
+		Valid:              valid,
+	}
+}
+
+// WithIfMetagenerationMatch makes the download or metadata update conditional,
+// the request fails if the object's metageneration changed
+func WithIfMetagenerationMatch(metageneration int64) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.metagenerationMatch = metageneration
+	}
+}
+
+// WithGeneration selects a specific object generation for reads
+func WithGeneration(generation int64) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.generation = generation
+	}
+}
+
+// WithIfGenerationMatch makes the upload or DeleteObject conditional on the object's generation,
+// zero requires that the object doesn't exist
+func WithIfGenerationMatch(generation int64) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.generationMatch = generation
+		cfr.hasGenerationMatch = true
+	}
+}
+
+// generationConditions returns the conditions of given generation match, zero matches missing objects
+func generationConditions(generation int64) storage.Conditions {
+	if generation == 0 {
+		return storage.Conditions{DoesNotExist: true}
+	}
+	return storage.Conditions{GenerationMatch: generation}
+}
+
+// objectHandle returns request's object handle, with request's generation & preconditions
+func (cs *cloudStorageClient) objectHandle(cfr CloudFileRequest, name string) *storage.ObjectHandle {
+	obj := cs.bucketHandle(cfr).Object(name)
+	if cfr.generation != 0 {
+		obj = obj.Generation(cfr.generation)
+	}
+	if cfr.metagenerationMatch != 0 {
+		obj = obj.If(storage.Conditions{MetagenerationMatch: cfr.metagenerationMatch})
+	}
+	return obj
+}
+
+// bucketHandle returns the handle of request's bucket, requests billed to the user project when set
+func (cs *cloudStorageClient) bucketHandle(cfr CloudFileRequest) *storage.BucketHandle {
+	bkt := cs.client.Bucket(cfr.bucket)
+	if cfr.userProject != "" {
+		bkt = bkt.UserProject(cfr.userProject)
+	}
+	return bkt
+}
+
+// GetAttrs returns attributes of the cloud file at request's bucket & filepath
+func (cs *cloudStorageClient) GetAttrs(ctx context.Context, cfr CloudFileRequest) (*ObjectAttrs, error) {
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return nil, err
+	}
+	if cfr.bucket == "" {
+		return nil, ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return nil, ErrFileNameMissing
+	}
+	op := cs.startOperation(ctx, "GetAttrs", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	attrs, err := cs.statObject(ctx, op, cfr)
+	if err != nil {
+		op.logger.Error(ERROR_GETTING_ATTRS, zap.Error(err), zap.String("filepath", op.object))
+		return nil, op.wrapError(err, "%s %s", ERROR_GETTING_ATTRS, op.object)
+	}
+	return cfr.logicalAttrs(attrs), nil
+}
+
+// ListObjectsInfo lists attributes of objects under request path
+func (cs *cloudStorageClient) ListObjectsInfo(ctx context.Context, cfr CloudFileRequest) ([]*ObjectAttrs, error) {
+	cfr, err := cs.prefixRequest(ctx, cfr)
+	if err != nil {
+		return nil, err
+	}
+	if cfr.bucket == "" {
+		return nil, ErrBucketNameMissing
+	}
+	op := cs.startOperation(ctx, "ListObjectsInfo", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	prefix := dirPrefix(cfr.path)
+	op.object = prefix
+
+	infos := []*ObjectAttrs{}
+	it := cs.bucketHandle(cfr).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		objAttrs, err := it.Next()
+		if err != nil {
+			if err == iterator.Done {
+				break
+			}
+			op.logger.Error(ERROR_LISTING_OBJECTS, zap.Error(err), zap.Int("listed", len(infos)))
+			names := make([]string, len(infos))
+			for i, info := range infos {
+				names[i] = info.Name
+			}
+			return nil, op.wrapError(partialList(names, err), ERROR_LISTING_OBJECTS)
+		}
+		infos = append(infos, cfr.logicalAttrs(newObjectAttrs(objAttrs)))
+	}
+	if cfr.mapsNames() {
+		sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	}
+	return infos, nil
+}
+
+// UpdateMetadata merges given metadata into the cloud file's custom metadata,
+// keys with empty values are removed, returns updated attributes.
+// Use WithIfMetagenerationMatch to fail if metadata changed since it was read.
+func (cs *cloudStorageClient) UpdateMetadata(ctx context.Context, cfr CloudFileRequest, metadata map[string]string) (*ObjectAttrs, error) {
+	if err := cs.mutation(); err != nil {
+		return nil, err
+	}
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return nil, err
+	}
+	if cfr.bucket == "" {
+		return nil, ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return nil, ErrFileNameMissing
+	}
+	op := cs.startOperation(ctx, "UpdateMetadata", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	attrs, err := cs.objectHandle(cfr, op.object).Update(ctx, storage.ObjectAttrsToUpdate{Metadata: metadata})
+	cs.invalidate(cfr.bucket, op.object)
+	if err != nil {
+		op.logger.Error(ERROR_UPDATING_METADATA, zap.Error(err), zap.String("filepath", op.object))
+		return nil, op.wrapError(err, "%s %s", ERROR_UPDATING_METADATA, op.object)
+	}
+	op.generation = attrs.Generation
+	op.logger.Debug("cloud file metadata updated", zap.String("filepath", op.object), zap.Int64("metageneration", attrs.Metageneration))
+	return cfr.logicalAttrs(newObjectAttrs(attrs)), nil
+}
@@ -0,0 +1,81 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewObjectAttrs(t *testing.T) {
+	require.Nil(t, newObjectAttrs(nil))
+
+	attrs := newObjectAttrs(&storage.ObjectAttrs{
+		Bucket:         "bucket",
+		Name:           "path/file.json",
+		Size:           12,
+		Etag:           "CKih16GjycICEAE=",
+		Generation:     1600000000000000,
+		Metageneration: 3,
+		Metadata:       map[string]string{"k": "v"},
+	})
+	require.Equal(t, "CKih16GjycICEAE=", attrs.Etag)
+	require.Equal(t, int64(1600000000000000), attrs.Generation)
+	require.Equal(t, int64(3), attrs.Metageneration)
+	require.Equal(t, "v", attrs.Metadata["k"])
+	require.True(t, attrs.Has(GCS_ATTR_FIELDS))
+	require.False(t, attrs.Has(AttrMD5))
+	require.True(t, newObjectAttrs(&storage.ObjectAttrs{MD5: []byte{1}}).Has(AttrMD5|AttrCRC32C))
+}
+
+// TestAttrsConformance asserts the backend populates its fields on the attributes of every call returning them
+func TestAttrsConformance(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "file.txt", "path", 0)
+	require.NoError(t, err)
+
+	up, err := cs.Upload(ctx, strings.NewReader("content"), cfr)
+	require.NoError(t, err)
+	got, err := cs.GetAttrs(ctx, cfr)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	dl, err := cs.Download(ctx, &buf, cfr)
+	require.NoError(t, err)
+	listed, err := cs.ListObjectsInfo(ctx, cfr)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+
+	for _, attrs := range []*ObjectAttrs{up.Attrs, got, dl.Attrs, listed[0]} {
+		require.True(t, attrs.Has(GCS_ATTR_FIELDS|AttrMD5), "missing %s", (GCS_ATTR_FIELDS|AttrMD5)&^attrs.Valid)
+		require.NotZero(t, attrs.Generation)
+		require.NotZero(t, attrs.Metageneration)
+		require.NotZero(t, attrs.CRC32C)
+		require.NotEmpty(t, attrs.Etag)
+		require.NotEmpty(t, attrs.StorageClass)
+		require.False(t, attrs.Created.IsZero())
+		require.False(t, attrs.Updated.IsZero())
+	}
+}
+
+func TestNotSupportedByBackend(t *testing.T) {
+	attrs := &ObjectAttrs{Bucket: "bucket", Name: "file.txt", Size: 7, Valid: AttrEtag | AttrUpdated}
+	require.NoError(t, requireAttrs(attrs, AttrEtag, "feature"))
+
+	err := requireAttrs(attrs, AttrGeneration|AttrCRC32C|AttrEtag, "feature")
+	require.True(t, errors.Is(err, ErrNotSupportedByBackend))
+	var nse NotSupportedError
+	require.True(t, errors.As(err, &nse))
+	require.Equal(t, AttrGeneration|AttrCRC32C, nse.Missing)
+	require.Equal(t, "feature not supported by the storage backend for bucket/file.txt: generation|crc32c not populated", err.Error())
+
+	// stored content without a CRC32C isn't taken for a digest mismatch
+	err = casStored(attrs, &spooled{size: 7, crc: 1}, "digest")
+	require.True(t, errors.Is(err, ErrNotSupportedByBackend))
+	require.False(t, errors.Is(err, ErrDigestMismatch))
+}
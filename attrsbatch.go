@@ -0,0 +1,243 @@
+package cloudstorage
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	// DEFAULT_ATTRS_BATCH_CONCURRENCY is the default number of concurrent attrs calls of GetAttrsBatch
+	DEFAULT_ATTRS_BATCH_CONCURRENCY = 32
+	// DEFAULT_ATTRS_BATCH_LIST_THRESHOLD is the default number of names from which GetAttrsBatch
+	// lists names sharing a prefix instead of fetching each
+	DEFAULT_ATTRS_BATCH_LIST_THRESHOLD = 1000
+)
+
+// AttrsBatchStrategy selects how GetAttrsBatch fetches attributes
+type AttrsBatchStrategy int
+
+const (
+	// AttrsBatchAuto lists the names' range when there are at least ListThreshold names sharing a prefix,
+	// fetches each name otherwise
+	AttrsBatchAuto AttrsBatchStrategy = iota
+	// AttrsBatchStat fetches the attributes of each name
+	AttrsBatchStat
+	// AttrsBatchList lists the objects between the first & last of the names under their common prefix,
+	// a listing page covers up to a thousand objects, far cheaper than one call per name
+	// when the range holds few objects other than the named ones
+	AttrsBatchList
+)
+
+// AttrsBatchOptions configure GetAttrsBatch
+type AttrsBatchOptions struct {
+	// Concurrency is the number of concurrent attrs calls, defaults to DEFAULT_ATTRS_BATCH_CONCURRENCY
+	Concurrency int
+	// Strategy is the fetch strategy, defaults to AttrsBatchAuto
+	Strategy AttrsBatchStrategy
+	// ListThreshold is the number of names from which AttrsBatchAuto lists,
+	// defaults to DEFAULT_ATTRS_BATCH_LIST_THRESHOLD
+	ListThreshold int
+}
+
+// AttrsBatchOption sets attrs batch options
+type AttrsBatchOption func(o *AttrsBatchOptions)
+
+// WithAttrsBatchConcurrency sets the number of concurrent attrs calls
+func WithAttrsBatchConcurrency(n int) AttrsBatchOption {
+	return func(o *AttrsBatchOptions) {
+		o.Concurrency = n
+	}
+}
+
+// WithAttrsBatchStrategy forces given fetch strategy
+func WithAttrsBatchStrategy(strategy AttrsBatchStrategy) AttrsBatchOption {
+	return func(o *AttrsBatchOptions) {
+		o.Strategy = strategy
+	}
+}
+
+// WithAttrsBatchListThreshold sets the number of names from which the batch lists instead of fetching each
+func WithAttrsBatchListThreshold(n int) AttrsBatchOption {
+	return func(o *AttrsBatchOptions) {
+		o.ListThreshold = n
+	}
+}
+
+// AttrsResult is the attributes of one GetAttrsBatch name
+type AttrsResult struct {
+	Name  string
+	Attrs *ObjectAttrs
+	// Err is the name's failure, missing objects match ErrObjectNotFound
+	Err error
+}
+
+// GetAttrsBatch returns the attributes of given bucket's named objects in input order, names are
+// relative to the context's scope. Attributes are fetched concurrently, or listed with AttrsBatchList,
+// see AttrsBatchAuto for the default choice. Missing objects, forbidden & invalid names fail their
+// result only, the batch fails when the listing does or the context is done, names not fetched
+// by then fail with its error.
+func (cs *cloudStorageClient) GetAttrsBatch(ctx context.Context, bucket string, names []string, opts ...AttrsBatchOption) ([]AttrsResult, error) {
+	bOpts := AttrsBatchOptions{Concurrency: DEFAULT_ATTRS_BATCH_CONCURRENCY, ListThreshold: DEFAULT_ATTRS_BATCH_LIST_THRESHOLD}
+	for _, opt := range opts {
+		opt(&bOpts)
+	}
+	if bOpts.Concurrency <= 0 {
+		bOpts.Concurrency = DEFAULT_ATTRS_BATCH_CONCURRENCY
+	}
+	batch, err := cs.scoped(ctx, CloudFileRequest{bucket: bucket})
+	if err != nil {
+		return nil, err
+	}
+	if batch.bucket == "" {
+		return nil, ErrBucketNameMissing
+	}
+	op := cs.startOperation(ctx, "GetAttrsBatch", batch)
+	defer op.finish()
+	ctx = op.ctx
+
+	results := make([]AttrsResult, len(names))
+	cfrs := make([]CloudFileRequest, len(names))
+	stored := []string{}
+	for i, name := range names {
+		results[i].Name = name
+		if name == "" {
+			results[i].Err = ErrFileNameMissing
+			continue
+		}
+		if cfrs[i], err = cs.scoped(ctx, CloudFileRequest{bucket: bucket, file: name}); err != nil {
+			results[i].Err = err
+			continue
+		}
+		stored = append(stored, cfrs[i].objectPath())
+	}
+
+	prefix := commonPrefix(stored)
+	list := bOpts.Strategy == AttrsBatchList ||
+		(bOpts.Strategy == AttrsBatchAuto && len(stored) >= bOpts.ListThreshold && prefix != "")
+	if list {
+		op.object = prefix
+		err = cs.listAttrsBatch(ctx, op, batch, cfrs, results)
+	} else {
+		err = cs.statAttrsBatch(ctx, op, cfrs, results, bOpts.Concurrency)
+	}
+	if err != nil {
+		op.logger.Error(ERROR_GETTING_ATTRS, zap.Error(err), zap.Int("names", len(names)), zap.Bool("listed", list))
+		return results, op.wrapError(err, "%s %s", ERROR_GETTING_ATTRS, prefix)
+	}
+	op.logger.Debug("cloud file attributes batch", zap.Int("names", len(names)), zap.Bool("listed", list))
+	return results, nil
+}
+
+// statAttrsBatch fetches the attributes of each request concurrently, results failed already are left alone.
+// Names not fetched once the context is done fail with its error, which is returned.
+func (cs *cloudStorageClient) statAttrsBatch(ctx context.Context, op *operation, cfrs []CloudFileRequest, results []AttrsResult, concurrency int) error {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, cfr := range cfrs {
+		if results[i].Err != nil {
+			continue
+		}
+		sem <- struct{}{}
+		if err := ctx.Err(); err != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(i int, cfr CloudFileRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			itemOp := op.forObject(cfr.bucket, cfr.objectPath())
+			attrs, err := cs.statObject(ctx, itemOp, cfr)
+			if err != nil {
+				results[i].Err = itemOp.wrapError(err, "%s %s", ERROR_GETTING_ATTRS, itemOp.object)
+				return
+			}
+			results[i].Attrs = cfr.logicalAttrs(attrs)
+		}(i, cfr)
+	}
+	wg.Wait()
+	err := ctx.Err()
+	if err != nil {
+		for i := range results {
+			if results[i].Err == nil && results[i].Attrs == nil {
+				results[i].Err = err
+			}
+		}
+	}
+	return err
+}
+
+// listAttrsBatch lists the objects between the first & last requests' names under their common prefix,
+// requests not listed fail with ErrObjectNotFound. A failed listing fails every result not failed already.
+func (cs *cloudStorageClient) listAttrsBatch(ctx context.Context, op *operation, batch CloudFileRequest, cfrs []CloudFileRequest, results []AttrsResult) error {
+	wanted := map[string][]int{}
+	first, last := "", ""
+	for i, cfr := range cfrs {
+		if results[i].Err != nil {
+			continue
+		}
+		name := cfr.objectPath()
+		wanted[name] = append(wanted[name], i)
+		if first == "" || name < first {
+			first = name
+		}
+		if name > last {
+			last = name
+		}
+	}
+	if len(wanted) == 0 {
+		return nil
+	}
+
+	q := &storage.Query{Prefix: op.object, StartOffset: first, EndOffset: last + "\x00"}
+	it := cs.bucketHandle(batch).Objects(ctx, q)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			for i := range results {
+				if results[i].Err == nil {
+					results[i].Err = err
+				}
+			}
+			return err
+		}
+		for _, i := range wanted[attrs.Name] {
+			results[i].Attrs = cfrs[i].logicalAttrs(newObjectAttrs(attrs))
+		}
+		delete(wanted, attrs.Name)
+	}
+
+	// the listing found the bucket, names left are missing objects
+	op.bucketListed = true
+	for name, idx := range wanted {
+		itemOp := op.forObject(batch.bucket, name)
+		err := itemOp.wrapError(storage.ErrObjectNotExist, "%s %s", ERROR_GETTING_ATTRS, name)
+		for _, i := range idx {
+			results[i].Err = err
+		}
+	}
+	return nil
+}
+
+// commonPrefix returns the longest prefix shared by given names
+func commonPrefix(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	prefix := names[0]
+	for _, name := range names[1:] {
+		for !strings.HasPrefix(name, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,127 @@
+package cloudstorage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingFail counts the object stat & list calls of the fake, forbidding object stats of given name
+func countingFail(f *fakeGCS, forbidden string) (stats, lists func() int) {
+	var mu sync.Mutex
+	var nStats, nLists int
+	f.fail = func(r *http.Request) int {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/o"):
+			nLists++
+		case strings.Contains(r.URL.Path, "/o/"):
+			nStats++
+			if forbidden != "" && strings.HasSuffix(r.URL.Path, "/o/"+forbidden) {
+				return http.StatusForbidden
+			}
+		}
+		return 0
+	}
+	stats = func() int { mu.Lock(); defer mu.Unlock(); return nStats }
+	lists = func() int { mu.Lock(); defer mu.Unlock(); return nLists }
+	return stats, lists
+}
+
+func TestGetAttrsBatch(t *testing.T) {
+	f := newFakeGCS()
+	for i := 0; i < 5; i++ {
+		f.put("bucket", fmt.Sprintf("manifest/part-%d", i), []byte(strings.Repeat("x", i)), nil)
+	}
+	f.put("bucket", "other/part-0", []byte("x"), nil)
+	stats, lists := countingFail(f, "manifest/part-3")
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	names := []string{"manifest/part-4", "manifest/missing", "manifest/part-0", "manifest/part-3", "", "manifest/part-4", "other/part-0"}
+	results, err := cs.GetAttrsBatch(ctx, "bucket", names, WithAttrsBatchConcurrency(2))
+	require.NoError(t, err)
+	require.Len(t, results, len(names))
+	for i, res := range results {
+		require.Equal(t, names[i], res.Name)
+	}
+	require.Equal(t, int64(4), results[0].Attrs.Size)
+	require.ErrorIs(t, results[1].Err, ErrObjectNotFound)
+	require.Equal(t, "manifest/part-0", results[2].Attrs.Name)
+	require.ErrorIs(t, results[3].Err, ErrPermissionDenied)
+	require.Equal(t, ErrFileNameMissing, results[4].Err)
+	require.Equal(t, "manifest/part-4", results[5].Attrs.Name)
+	require.Equal(t, "other/part-0", results[6].Attrs.Name)
+	require.Equal(t, 6, stats())
+	require.Zero(t, lists())
+
+	_, err = cs.GetAttrsBatch(ctx, "", names)
+	require.Equal(t, ErrBucketNameMissing, err)
+}
+
+func TestGetAttrsBatchListing(t *testing.T) {
+	f := newFakeGCS()
+	f.pageSize = 3
+	for i := 0; i < 10; i++ {
+		f.put("bucket", fmt.Sprintf("tenant-a/manifest/part-%d", i), []byte(strings.Repeat("x", i)), nil)
+	}
+	// outside the names' range, not listed
+	f.put("bucket", "tenant-a/manifest/index", []byte("x"), nil)
+	f.put("bucket", "tenant-a/other", []byte("x"), nil)
+	stats, lists := countingFail(f, "")
+	cs := newFakeClient(t, f)
+	ctx := tenantContext()
+
+	names := []string{"manifest/part-7", "manifest/part-2", "manifest/part-5", "manifest/part-55", "manifest/part-2"}
+	results, err := cs.GetAttrsBatch(ctx, "bucket", names, WithAttrsBatchListThreshold(5))
+	require.NoError(t, err)
+	require.Zero(t, stats())
+	// part-2 through part-7 in pages of 3
+	require.Equal(t, 2, lists())
+	require.Equal(t, "manifest/part-7", results[0].Attrs.Name)
+	require.Equal(t, int64(7), results[0].Attrs.Size)
+	require.Equal(t, "manifest/part-2", results[1].Attrs.Name)
+	require.Equal(t, "manifest/part-5", results[2].Attrs.Name)
+	require.ErrorIs(t, results[3].Err, ErrObjectNotFound)
+	require.Equal(t, "manifest/part-2", results[4].Attrs.Name)
+
+	// below the threshold each name is fetched, unless listing is forced
+	_, err = cs.GetAttrsBatch(ctx, "bucket", names[:2])
+	require.NoError(t, err)
+	require.Equal(t, 2, stats())
+	results, err = cs.GetAttrsBatch(ctx, "bucket", names[:2], WithAttrsBatchStrategy(AttrsBatchList))
+	require.NoError(t, err)
+	require.Equal(t, 2, stats())
+	require.Equal(t, 4, lists())
+	require.Equal(t, int64(2), results[1].Attrs.Size)
+
+	// fetching each name can be forced too
+	_, err = cs.GetAttrsBatch(ctx, "bucket", names, WithAttrsBatchListThreshold(5), WithAttrsBatchStrategy(AttrsBatchStat))
+	require.NoError(t, err)
+	require.Equal(t, 2+len(names), stats())
+	require.Equal(t, 4, lists())
+}
+
+func TestGetAttrsBatchCancelled(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "a", []byte("x"), nil)
+	cs := newFakeClient(t, f)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, strategy := range []AttrsBatchStrategy{AttrsBatchStat, AttrsBatchList} {
+		results, err := cs.GetAttrsBatch(ctx, "bucket", []string{"a", "b"}, WithAttrsBatchStrategy(strategy))
+		require.ErrorIs(t, err, context.Canceled)
+		require.Len(t, results, 2)
+		for _, res := range results {
+			require.Nil(t, res.Attrs)
+			require.ErrorIs(t, res.Err, context.Canceled)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_RECORDING_AUDIT_EVENT string = "error recording audit event"
+	ERROR_OPENING_AUDIT_LOG     string = "error opening audit log"
+	ERROR_FLUSHING_AUDIT_EVENTS string = "error flushing audit events"
+)
+
+const (
+	// DEFAULT_AUDIT_BATCH_SIZE is the number of events BucketAuditSink buffers before writing an audit object
+	DEFAULT_AUDIT_BATCH_SIZE = 1000
+	// DEFAULT_AUDIT_FLUSH_TIMEOUT bounds the upload of one audit object
+	DEFAULT_AUDIT_FLUSH_TIMEOUT = 30 * time.Second
+)
+
+// AuditOutcome is the outcome of an audited operation
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+)
+
+// AuditEvent records one mutating operation, names are the stored ones, scope prefix included.
+// The JSON schema is stable, fields are only ever added.
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+	// Op is the mutating operation, e.g. UploadFile or DeleteObjects
+	Op string `json:"op"`
+	// Principal is the client's configured principal
+	Principal string `json:"principal,omitempty"`
+	RequestID string `json:"request_id"`
+	Bucket    string `json:"bucket"`
+	// Object is the operation's object, or prefix for operations on many objects
+	Object string `json:"object,omitempty"`
+	// Generation is the written generation, or the one the operation was conditioned on, when known
+	Generation int64         `json:"generation,omitempty"`
+	Bytes      int64         `json:"bytes"`
+	Duration   time.Duration `json:"duration_ns"`
+	Outcome    AuditOutcome  `json:"outcome"`
+	// Error is the failure's message
+	Error string `json:"error,omitempty"`
+}
+
+// AuditSink receives an event after every mutating operation, must be safe for concurrent use.
+// Record errors don't fail the operation, they're logged & counted by AuditFailures.
+type AuditSink interface {
+	Record(AuditEvent) error
+}
+
+// auditedOperations are the operations changing bucket content or configuration,
+// composite ones also record the uploads they issue
+var auditedOperations = map[string]bool{
+	"UploadFile": true, "UploadFanOut": true, "PublishSet": true, "StagedUpload": true,
+	"CopyFrom": true, "DeleteObject": true, "DeleteObjects": true, "UpdateMetadata": true,
+	"SetObjectTags": true, "PublishPointer": true, "EnsureDir": true, "ProcessManifest": true,
+	"RestoreSnapshot": true, "ReconcileBuckets": true, "CleanupStaging": true, "CleanupOrphans": true,
+	"EnsureRoutedBuckets": true, "SetBucketVersioning": true, "SetBucketLabels": true,
+	"RestoreSoftDeleted": true, "SetBucketSoftDelete": true, "BackupPrefixIncremental": true,
+	"RenameByRule": true,
+}
+
+type auditKey struct{}
+
+// withoutAudit returns a context whose operations aren't audited, for the audit sinks' own writes
+func withoutAudit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, auditKey{}, true)
+}
+
+// audited reports whether an operation of given name & context is audited
+func (cs *cloudStorageClient) audited(ctx context.Context, name string) bool {
+	if cs.config.Audit == nil || !auditedOperations[name] {
+		return false
+	}
+	return !auditSuppressed(ctx)
+}
+
+// auditSuppressed reports whether given context's operations are the audit sinks' own writes
+func auditSuppressed(ctx context.Context) bool {
+	suppressed, _ := ctx.Value(auditKey{}).(bool)
+	return suppressed
+}
+
+// audit records the finished operation with the configured audit sink, failed when an error was wrapped
+// with the operation, sink failures are counted & logged
+func (op *operation) audit() {
+	if !op.audited {
+		return
+	}
+	ev := AuditEvent{
+		Time:       op.cs.now(),
+		Op:         op.name,
+		Principal:  op.cs.config.Principal,
+		RequestID:  op.requestID,
+		Bucket:     op.bucket,
+		Object:     op.object,
+		Generation: op.generation,
+		Bytes:      op.bytes,
+		Duration:   op.cs.since(op.start),
+		Outcome:    AuditSuccess,
+	}
+	if err := op.outcome.failure(); err != nil {
+		ev.Outcome, ev.Error = AuditFailure, err.Error()
+	}
+	if err := op.cs.config.Audit.Record(ev); err != nil {
+		atomic.AddInt64(&op.cs.auditFailures, 1)
+		op.logger.Error(ERROR_RECORDING_AUDIT_EVENT, zap.Error(err), zap.String("bucket", op.bucket), zap.String("filepath", op.object))
+	}
+}
+
+// opOutcome is the first failure of an operation, shared by its per object copies
+type opOutcome struct {
+	mu  sync.Mutex
+	err error
+}
+
+// fail records given failure, unless one was recorded before
+func (o *opOutcome) fail(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.err == nil {
+		o.err = err
+	}
+}
+
+// failure returns the operation's first failure
+func (o *opOutcome) failure() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.err
+}
+
+// AuditFailures returns the number of audit events the audit sink failed to record
+func (cs *cloudStorageClient) AuditFailures() int64 {
+	return atomic.LoadInt64(&cs.auditFailures)
+}
+
+// FileAuditSink appends audit events to a local file as JSON lines
+type FileAuditSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileAuditSink opens given file for appending, creating it when missing
+func NewFileAuditSink(filePath string) (*FileAuditSink, error) {
+	f, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, errors.WrapError(err, "%s %s", ERROR_OPENING_AUDIT_LOG, filePath)
+	}
+	return &FileAuditSink{f: f}, nil
+}
+
+// Record appends given event as one line, in a single write
+func (s *FileAuditSink) Record(ev AuditEvent) error {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return os.ErrClosed
+	}
+	_, err = s.f.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file, later events fail to record
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// BucketAuditSink writes audit events as JSON lines objects to a bucket, one or more per hour,
+// named <prefix>/<yyyy>/<mm>/<dd>/<hh>/<unique id>-audit.ndjson. Events are batched, an object
+// is written when the batch is full, when an event of another hour arrives & on Close.
+// Its writes aren't audited, the sink may write through the client it audits.
+type BucketAuditSink struct {
+	storage   CloudStorage
+	bucket    string
+	prefix    string
+	batchSize int
+
+	mu     sync.Mutex
+	hour   time.Time
+	buf    bytes.Buffer
+	events int
+	closed bool
+}
+
+// NewBucketAuditSink returns a sink writing audit objects under given bucket & prefix with given client,
+// flushing every batchSize events, DEFAULT_AUDIT_BATCH_SIZE when not positive
+func NewBucketAuditSink(cs CloudStorage, bucket, prefix string, batchSize int) *BucketAuditSink {
+	if batchSize <= 0 {
+		batchSize = DEFAULT_AUDIT_BATCH_SIZE
+	}
+	return &BucketAuditSink{storage: cs, bucket: bucket, prefix: strings.Trim(prefix, "/"), batchSize: batchSize}
+}
+
+// Record adds given event to the batch of its hour, writing the previous hour's or a full batch
+func (s *BucketAuditSink) Record(ev AuditEvent) error {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return os.ErrClosed
+	}
+	hour := ev.Time.UTC().Truncate(time.Hour)
+	if s.events > 0 && !hour.Equal(s.hour) {
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+	s.hour = hour
+	s.buf.Write(line)
+	s.buf.WriteByte('\n')
+	s.events++
+	if s.events >= s.batchSize {
+		return s.flush()
+	}
+	return nil
+}
+
+// Flush writes the buffered events, if any
+func (s *BucketAuditSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+// flush writes the batch to a new audit object, the batch is kept for the next flush on failure
+func (s *BucketAuditSink) flush() error {
+	if s.events == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(withoutAudit(context.Background()), DEFAULT_AUDIT_FLUSH_TIMEOUT)
+	defer cancel()
+	prefix := path.Join(s.prefix, s.hour.Format("2006/01/02/15"))
+	_, err := s.storage.UploadUnique(ctx, bytes.NewReader(s.buf.Bytes()), s.bucket, prefix, "audit.ndjson", WithContentType("application/x-ndjson"))
+	if err != nil {
+		return errors.WrapError(err, "%s, %d events", ERROR_FLUSHING_AUDIT_EVENTS, s.events)
+	}
+	s.buf.Reset()
+	s.events = 0
+	return nil
+}
+
+// Close writes the buffered events, later events fail to record
+func (s *BucketAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	if err := s.flush(); err != nil {
+		return err
+	}
+	s.closed = true
+	return nil
+}
@@ -0,0 +1,226 @@
+package cloudstorage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memoryAuditSink collects audit events, failing with err when set
+type memoryAuditSink struct {
+	mu     sync.Mutex
+	events []AuditEvent
+	err    error
+}
+
+func (s *memoryAuditSink) Record(ev AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, ev)
+	return nil
+}
+
+func (s *memoryAuditSink) recorded() []AuditEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AuditEvent{}, s.events...)
+}
+
+func TestAuditEventSchema(t *testing.T) {
+	ev := AuditEvent{
+		Time:       time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC),
+		Op:         "UploadFile",
+		Principal:  "svc@project.iam.gserviceaccount.com",
+		RequestID:  "req-1",
+		Bucket:     "bucket",
+		Object:     "path/file.bin",
+		Generation: 7,
+		Bytes:      42,
+		Duration:   1500 * time.Millisecond,
+		Outcome:    AuditFailure,
+		Error:      "boom",
+	}
+	// the schema is consumed by compliance tooling, changes must only add fields
+	want := `{"time":"2024-01-02T03:04:05.006Z","op":"UploadFile","principal":"svc@project.iam.gserviceaccount.com",` +
+		`"request_id":"req-1","bucket":"bucket","object":"path/file.bin","generation":7,"bytes":42,` +
+		`"duration_ns":1500000000,"outcome":"failure","error":"boom"}`
+	data, err := json.Marshal(ev)
+	require.NoError(t, err)
+	require.JSONEq(t, want, string(data))
+
+	var decoded AuditEvent
+	require.NoError(t, json.Unmarshal([]byte(want), &decoded))
+	require.Equal(t, ev, decoded)
+
+	// optional fields are omitted
+	data, err = json.Marshal(AuditEvent{Time: ev.Time, Op: "DeleteObjects", RequestID: "req-2", Bucket: "bucket", Outcome: AuditSuccess})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"time":"2024-01-02T03:04:05.006Z","op":"DeleteObjects","request_id":"req-2","bucket":"bucket","bytes":0,"duration_ns":0,"outcome":"success"}`, string(data))
+}
+
+func TestAuditMutations(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/a.txt", []byte("a"), nil)
+	sink := &memoryAuditSink{}
+	cs := newFakeClient(t, f)
+	cs.config.Audit, cs.config.Principal = sink, "svc"
+	ctx := WithRequestID(context.Background(), "req-1")
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	res, err := cs.Upload(ctx, strings.NewReader("content"), cfr)
+	require.NoError(t, err)
+	_, err = cs.UpdateMetadata(ctx, cfr, map[string]string{"k": "v"})
+	require.NoError(t, err)
+	// reads & verifications aren't audited
+	_, err = cs.GetAttrs(ctx, cfr)
+	require.NoError(t, err)
+	_, err = cs.ProcessManifest(ctx, strings.NewReader(`{"name":"path/a.txt"}`+"\n"), ManifestVerifyExists, WithManifestBucket("bucket"))
+	require.NoError(t, err)
+	require.NoError(t, cs.DeleteObject(ctx, cfr))
+
+	f.fail = func(r *http.Request) int {
+		if strings.HasPrefix(r.URL.Path, "/upload/") {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	_, err = cs.Upload(ctx, strings.NewReader("content"), cfr)
+	require.Error(t, err)
+
+	events := sink.recorded()
+	require.Len(t, events, 4)
+	for i, op := range []string{"UploadFile", "UpdateMetadata", "DeleteObject", "UploadFile"} {
+		require.Equal(t, op, events[i].Op)
+		require.Equal(t, "svc", events[i].Principal)
+		require.Equal(t, "req-1", events[i].RequestID)
+		require.Equal(t, "bucket", events[i].Bucket)
+		require.Equal(t, "path/file.bin", events[i].Object)
+		require.False(t, events[i].Time.IsZero())
+	}
+	require.Equal(t, AuditSuccess, events[0].Outcome)
+	require.Equal(t, res.Attrs.Generation, events[0].Generation)
+	require.Equal(t, int64(len("content")), events[0].Bytes)
+	require.Equal(t, res.Attrs.Generation, events[1].Generation)
+	require.Equal(t, AuditSuccess, events[2].Outcome)
+	require.Equal(t, AuditFailure, events[3].Outcome)
+	require.NotEmpty(t, events[3].Error)
+	require.Equal(t, int64(0), cs.AuditFailures())
+}
+
+func TestAuditSinkFailure(t *testing.T) {
+	f := newFakeGCS()
+	sink := &memoryAuditSink{err: errors.New("sink down")}
+	cs := newFakeClient(t, f)
+	cs.config.Audit = sink
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	// the sink's failure doesn't fail the operation, it's counted
+	_, err = cs.Upload(context.Background(), strings.NewReader("content"), cfr)
+	require.NoError(t, err)
+	require.NoError(t, cs.DeleteObject(context.Background(), cfr))
+	require.Equal(t, int64(2), cs.AuditFailures())
+}
+
+func TestFileAuditSink(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.ndjson")
+	sink, err := NewFileAuditSink(logPath)
+	require.NoError(t, err)
+	cs := newFakeClient(t, newFakeGCS())
+	cs.config.Audit = sink
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	_, err = cs.Upload(context.Background(), strings.NewReader("content"), cfr)
+	require.NoError(t, err)
+	require.NoError(t, cs.DeleteObject(context.Background(), cfr))
+	require.NoError(t, sink.Close())
+	require.ErrorIs(t, sink.Record(AuditEvent{}), os.ErrClosed)
+
+	// reopened logs are appended to
+	sink, err = NewFileAuditSink(logPath)
+	require.NoError(t, err)
+	require.NoError(t, sink.Record(AuditEvent{Op: "EnsureDir", Bucket: "bucket", Outcome: AuditSuccess}))
+	require.NoError(t, sink.Close())
+
+	file, err := os.Open(logPath)
+	require.NoError(t, err)
+	defer file.Close()
+	ops := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var ev AuditEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &ev))
+		ops = append(ops, ev.Op)
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, []string{"UploadFile", "DeleteObject", "EnsureDir"}, ops)
+}
+
+func TestBucketAuditSink(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	clock := newFakeClock()
+	cs.clock = clock
+	// the sink writes through the client it audits, its own uploads aren't audited
+	sink := NewBucketAuditSink(cs, "audit", "/logs/", 2)
+	cs.config.Audit = sink
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+	upload := func() {
+		_, err := cs.Upload(context.Background(), strings.NewReader("content"), cfr)
+		require.NoError(t, err)
+	}
+
+	upload()
+	require.Empty(t, stagedNames(f, "audit", ""), "events are batched")
+	upload()
+	require.Len(t, stagedNames(f, "audit", "logs/2024/01/01/00/"), 1, "full batches are written")
+
+	// an event of the next hour writes the previous hour's batch
+	upload()
+	clock.Advance(time.Hour)
+	upload()
+	require.Len(t, stagedNames(f, "audit", "logs/2024/01/01/00/"), 2)
+	require.Empty(t, stagedNames(f, "audit", "logs/2024/01/01/01/"))
+
+	// the client's Close flushes the sink
+	require.NoError(t, cs.Close())
+	names := stagedNames(f, "audit", "logs/")
+	sort.Strings(names)
+	require.Len(t, names, 3)
+	require.Regexp(t, regexp.MustCompile(`^logs/2024/01/01/01/[0-9A-Z]{26}-audit\.ndjson$`), names[2])
+
+	events := 0
+	for _, name := range names {
+		data, attrs, ok := f.get("audit", name)
+		require.True(t, ok)
+		require.Equal(t, "application/x-ndjson", attrs.ContentType)
+		for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+			var ev AuditEvent
+			require.NoError(t, json.Unmarshal(line, &ev))
+			require.Equal(t, "UploadFile", ev.Op)
+			require.Equal(t, "bucket", ev.Bucket)
+			events++
+		}
+	}
+	require.Equal(t, 4, events)
+	require.ErrorIs(t, sink.Record(AuditEvent{}), os.ErrClosed)
+}
@@ -0,0 +1,214 @@
+package cloudstorage
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_BACKING_UP_PREFIX     string = "error backing up prefix"
+	ERROR_READING_BACKUP_MARKER string = "error reading backup marker"
+	ERROR_WRITING_BACKUP_MARKER string = "error writing backup marker"
+)
+
+const (
+	// DEFAULT_BACKUP_MARKER_PREFIX is the destination path prefix of backup completion markers
+	DEFAULT_BACKUP_MARKER_PREFIX = ".backup-markers"
+	// DEFAULT_BACKUP_OVERLAP is how long before a marker's high-water the next run looks, for clock skew
+	// between the client & the service's update times
+	DEFAULT_BACKUP_OVERLAP = time.Minute
+)
+
+// BackupMarker records a completed incremental backup, the next run starts from its high-water
+type BackupMarker struct {
+	Source BucketPrefix `json:"source"`
+	Dest   BucketPrefix `json:"dest"`
+	// Since is the update time objects were selected after, zero for a full backup
+	Since time.Time `json:"since"`
+	// HighWater is the time the run started listing, objects updated later are left to the next run
+	HighWater time.Time `json:"high_water"`
+	// Objects & Bytes are the copied objects & their size
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+	// Deleted is the number of mirrored deletions
+	Deleted   int64     `json:"deleted"`
+	Completed time.Time `json:"completed"`
+	RequestID string    `json:"request_id"`
+}
+
+// BackupOptions configure BackupPrefixIncremental
+type BackupOptions struct {
+	// MirrorDeletes deletes destination objects missing from the source
+	MirrorDeletes bool
+	// Overlap is subtracted from a marker's high-water, defaults to DEFAULT_BACKUP_OVERLAP,
+	// objects updated in the overlap are only copied again when they differ
+	Overlap time.Duration
+	// Concurrency is the number of concurrent copies & deletes, defaults to DEFAULT_RECONCILE_CONCURRENCY
+	Concurrency int
+	// Trace, when set, receives the decision records of the backup's reconcile
+	Trace OperationTrace
+}
+
+// BackupOption sets backup options
+type BackupOption func(o *BackupOptions)
+
+// WithMirrorDeletes makes the backup delete destination objects deleted from the source
+func WithMirrorDeletes() BackupOption {
+	return func(o *BackupOptions) {
+		o.MirrorDeletes = true
+	}
+}
+
+// WithBackupOverlap sets how long before the last marker's high-water the backup looks
+func WithBackupOverlap(overlap time.Duration) BackupOption {
+	return func(o *BackupOptions) {
+		o.Overlap = overlap
+	}
+}
+
+// WithBackupConcurrency sets the number of concurrent copies & deletes
+func WithBackupConcurrency(n int) BackupOption {
+	return func(o *BackupOptions) {
+		o.Concurrency = n
+	}
+}
+
+// WithBackupTrace sends the decision record of every compared object to given trace
+func WithBackupTrace(trace OperationTrace) BackupOption {
+	return func(o *BackupOptions) {
+		o.Trace = trace
+	}
+}
+
+// BackupReport reports an incremental backup's counts & actions, for backup verification
+type BackupReport struct {
+	Source BucketPrefix `json:"source"`
+	Dest   BucketPrefix `json:"dest"`
+	// Since is the update time objects were selected after, zero for a full backup,
+	// FromMarker is set when it was read from the last marker
+	Since      time.Time `json:"since"`
+	FromMarker bool      `json:"from_marker"`
+	HighWater  time.Time `json:"high_water"`
+	// SourceObjects & DestObjects are the objects listed on each side
+	SourceObjects int64 `json:"source_objects"`
+	DestObjects   int64 `json:"dest_objects"`
+	// Unchanged is the number of source objects not updated since, or already in sync
+	Unchanged    int64 `json:"unchanged"`
+	Copied       int64 `json:"copied"`
+	CopiedBytes  int64 `json:"copied_bytes"`
+	Deleted      int64 `json:"deleted"`
+	DeletedBytes int64 `json:"deleted_bytes"`
+	Failed       int64 `json:"failed"`
+	// Actions are the copies & deletes in name order
+	Actions []ReconcileAction `json:"actions"`
+	// Marker is the completion marker's name in the destination bucket, empty when the run failed
+	Marker string `json:"marker"`
+}
+
+// backupMarkerName returns the destination object name of given prefix's backup marker
+func backupMarkerName(prefix string) string {
+	return path.Join(DEFAULT_BACKUP_MARKER_PREFIX, dirPrefix(prefix), "marker.json")
+}
+
+// ReadLastBackupMarker returns the completion marker of the last successful backup of prefix into given bucket,
+// a missing marker matches ErrObjectNotFound
+func (cs *cloudStorageClient) ReadLastBackupMarker(ctx context.Context, dst BucketRef, prefix string) (BackupMarker, error) {
+	cfr, err := dst.Request(backupMarkerName(prefix))
+	if err != nil {
+		return BackupMarker{}, err
+	}
+	var marker BackupMarker
+	if err := cs.ReadJSON(ctx, cfr, &marker); err != nil {
+		if !isNotFound(err) {
+			cs.logger.Error(ERROR_READING_BACKUP_MARKER, zap.Error(err), zap.String("bucket", dst.Name()), zap.String("prefix", prefix))
+		}
+		return BackupMarker{}, err
+	}
+	return marker, nil
+}
+
+// BackupPrefixIncremental copies source objects under prefix updated after since to the same names
+// in the destination bucket, server side, objects left unchanged aren't copied again.
+// A zero since resumes from the last backup marker's high-water, less the overlap,
+// or backs up every object without marker. Once every copy succeeded, a marker recording the run's
+// high-water & counts is written under DEFAULT_BACKUP_MARKER_PREFIX of the destination,
+// a whole bucket backup mirroring deletions removes the previous marker before writing the new one.
+// Objects are compared like ReconcileBuckets, copies keep the source's encryption.
+func (cs *cloudStorageClient) BackupPrefixIncremental(ctx context.Context, src, dst BucketRef, prefix string, since time.Time, opts ...BackupOption) (BackupReport, error) {
+	if err := cs.mutation(); err != nil {
+		return BackupReport{}, err
+	}
+	bOpts := BackupOptions{Overlap: DEFAULT_BACKUP_OVERLAP}
+	for _, opt := range opts {
+		opt(&bOpts)
+	}
+	op := cs.startOperation(ctx, "BackupPrefixIncremental", CloudFileRequest{bucket: dst.Name()})
+	defer op.finish()
+	ctx = op.ctx
+	op.object = dirPrefix(prefix)
+	ctx = WithRequestID(ctx, op.requestID)
+
+	report := BackupReport{Since: since}
+	if since.IsZero() {
+		marker, err := cs.ReadLastBackupMarker(ctx, dst, prefix)
+		switch {
+		case err == nil:
+			report.Since, report.FromMarker = marker.HighWater.Add(-bOpts.Overlap), true
+		case !isNotFound(err):
+			return BackupReport{}, op.wrapError(err, "%s %s", ERROR_READING_BACKUP_MARKER, op.object)
+		}
+	}
+
+	report.HighWater = cs.now()
+	rr, err := cs.ReconcileBuckets(ctx, BucketPrefix{Bucket: src.Name(), Prefix: prefix}, BucketPrefix{Bucket: dst.Name(), Prefix: prefix}, ReconcileOptions{
+		DeleteExtraneous: bOpts.MirrorDeletes,
+		UpdatedAfter:     report.Since,
+		Concurrency:      bOpts.Concurrency,
+		Trace:            bOpts.Trace,
+	})
+	report.Source, report.Dest, report.Actions = rr.Source, rr.Dest, rr.Actions
+	report.SourceObjects, report.DestObjects, report.Unchanged = rr.SourceObjects, rr.DestObjects, rr.InSync+rr.Filtered
+	for _, a := range rr.Actions {
+		switch {
+		case a.Err != nil:
+			report.Failed++
+		case a.Action == ReconcileDelete:
+			report.Deleted++
+			report.DeletedBytes += a.Size
+		default:
+			report.Copied++
+			report.CopiedBytes += a.Size
+		}
+	}
+	op.bytes = report.CopiedBytes
+	if err != nil {
+		op.logger.Error(ERROR_BACKING_UP_PREFIX, zap.Error(err), zap.Int64("copied", report.Copied), zap.Int64("failed", report.Failed))
+		return report, err
+	}
+
+	cfr, err := dst.Request(backupMarkerName(prefix))
+	if err != nil {
+		return report, err
+	}
+	marker := BackupMarker{
+		Source:    report.Source,
+		Dest:      report.Dest,
+		Since:     report.Since,
+		HighWater: report.HighWater,
+		Objects:   report.Copied,
+		Bytes:     report.CopiedBytes,
+		Deleted:   report.Deleted,
+		Completed: cs.now(),
+		RequestID: op.requestID,
+	}
+	if _, err := cs.WriteJSON(ctx, cfr, marker); err != nil {
+		op.logger.Error(ERROR_WRITING_BACKUP_MARKER, zap.Error(err), zap.String("marker", cfr.objectPath()))
+		return report, op.wrapError(err, "%s %s", ERROR_WRITING_BACKUP_MARKER, cfr.objectPath())
+	}
+	report.Marker = cfr.objectPath()
+	op.logger.Info("prefix backed up", zap.String("source", src.Name()), zap.String("dest", dst.Name()), zap.Time("since", report.Since), zap.Int64("copied", report.Copied), zap.Int64("deleted", report.Deleted))
+	return report, nil
+}
@@ -0,0 +1,115 @@
+package cloudstorage
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupPrefixIncremental(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "data/a.txt", []byte("a"), nil)
+	f.put("bucket", "data/b.txt", []byte("bb"), nil)
+	f.put("bucket", "other/c.txt", []byte("c"), nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	src, dst := cs.Bucket("bucket"), cs.Bucket("backup")
+
+	_, err := cs.ReadLastBackupMarker(ctx, dst, "data")
+	require.ErrorIs(t, err, ErrObjectNotFound)
+
+	// without marker every object is copied
+	report, err := cs.BackupPrefixIncremental(ctx, src, dst, "data", time.Time{})
+	require.NoError(t, err)
+	require.False(t, report.FromMarker)
+	require.True(t, report.Since.IsZero())
+	require.Equal(t, int64(2), report.Copied)
+	require.Equal(t, int64(3), report.CopiedBytes)
+	require.Equal(t, ".backup-markers/data/marker.json", report.Marker)
+	for _, name := range []string{"data/a.txt", "data/b.txt"} {
+		_, _, ok := f.get("backup", name)
+		require.True(t, ok, name)
+	}
+	_, _, ok := f.get("backup", "other/c.txt")
+	require.False(t, ok)
+	marker, err := cs.ReadLastBackupMarker(ctx, dst, "data")
+	require.NoError(t, err)
+	require.Equal(t, report.HighWater.UTC(), marker.HighWater.UTC())
+	require.Equal(t, int64(2), marker.Objects)
+	require.Equal(t, int64(3), marker.Bytes)
+	require.Equal(t, BucketPrefix{Bucket: "bucket", Prefix: "data"}, marker.Source)
+
+	// the next run starts from the marker, unchanged objects in the overlap aren't copied again
+	f.put("bucket", "data/b.txt", []byte("changed"), nil)
+	f.put("bucket", "data/new.txt", []byte("new"), nil)
+	require.NoError(t, cs.DeleteObject(ctx, CloudFileRequest{bucket: "bucket", path: "data", file: "a.txt"}))
+	report, err = cs.BackupPrefixIncremental(ctx, src, dst, "data", time.Time{})
+	require.NoError(t, err)
+	require.True(t, report.FromMarker)
+	require.Equal(t, marker.HighWater.Add(-DEFAULT_BACKUP_OVERLAP).UTC(), report.Since.UTC())
+	require.Equal(t, []string{"update:b.txt", "copy:new.txt"}, backupActions(report))
+	require.Equal(t, int64(10), report.CopiedBytes)
+	require.Zero(t, report.Deleted)
+	_, _, ok = f.get("backup", "data/a.txt")
+	require.True(t, ok, "deletions aren't mirrored by default")
+
+	// deletions mirrored on request
+	report, err = cs.BackupPrefixIncremental(ctx, src, dst, "data", time.Time{}, WithMirrorDeletes())
+	require.NoError(t, err)
+	require.Equal(t, []string{"delete:a.txt"}, backupActions(report))
+	require.Equal(t, int64(1), report.DeletedBytes)
+	require.Equal(t, int64(2), report.Unchanged)
+	marker, err = cs.ReadLastBackupMarker(ctx, dst, "data")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), marker.Deleted)
+	require.Zero(t, marker.Objects)
+}
+
+// backupActions returns report's actions as kind:name
+func backupActions(report BackupReport) []string {
+	return reconcileActions(ReconcileReport{Actions: report.Actions})
+}
+
+func TestBackupPrefixIncrementalSince(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "data/old.txt", []byte("old"), nil)
+	since := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	f.put("bucket", "data/recent.txt", []byte("recent"), nil)
+	cs := newFakeClient(t, f)
+
+	// an explicit since ignores the marker
+	report, err := cs.BackupPrefixIncremental(context.Background(), cs.Bucket("bucket"), cs.Bucket("backup"), "data", since)
+	require.NoError(t, err)
+	require.False(t, report.FromMarker)
+	require.Equal(t, []string{"copy:recent.txt"}, backupActions(report))
+	require.Equal(t, int64(1), report.Unchanged)
+	require.Equal(t, int64(2), report.SourceObjects)
+}
+
+func TestBackupPrefixIncrementalFailure(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "data/a.txt", []byte("a"), nil)
+	f.put("bucket", "data/b.txt", []byte("b"), nil)
+	f.fail = func(r *http.Request) int {
+		if strings.Contains(r.URL.Path, "/o/data/b.txt/rewriteTo/") {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	dst := cs.Bucket("backup")
+
+	// a failed copy writes no marker, the next run starts over
+	report, err := cs.BackupPrefixIncremental(context.Background(), cs.Bucket("bucket"), dst, "data", time.Time{})
+	require.ErrorIs(t, err, ErrPermissionDenied)
+	require.Equal(t, int64(1), report.Copied)
+	require.Equal(t, int64(1), report.Failed)
+	require.Empty(t, report.Marker)
+	_, err = cs.ReadLastBackupMarker(context.Background(), dst, "data")
+	require.ErrorIs(t, err, ErrObjectNotFound)
+}
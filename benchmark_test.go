@@ -0,0 +1,418 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+	raw "google.golang.org/api/storage/v1"
+)
+
+// benchListObjects is the number of synthetic objects listing benchmarks list
+const benchListObjects = 100000
+
+// benchSizes are the object sizes of upload & download benchmarks
+var benchSizes = []struct {
+	name string
+	size int
+}{
+	{"1KB", 1024},
+	{"1MB", 1024 * 1024},
+	{"100MB", 100 * 1024 * 1024},
+}
+
+// benchEnv is the backend of the core path benchmarks, the in process fake server,
+// or the emulator at STORAGE_EMULATOR_HOST when set, so absolute numbers are meaningful
+type benchEnv struct {
+	cs     *cloudStorageClient
+	bucket string
+	// fake is the in process server, nil against the emulator
+	fake *fakeGCS
+}
+
+func newBenchEnv(b *testing.B) *benchEnv {
+	b.Helper()
+	host := os.Getenv("STORAGE_EMULATOR_HOST")
+	if host == "" {
+		f := newFakeGCS()
+		return &benchEnv{cs: newFakeClient(b, f), bucket: "bucket", fake: f}
+	}
+
+	// the storage client targets the emulator by itself, the JSON API client is pointed at it
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx, option.WithoutAuthentication())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { client.Close() })
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	bucket := fmt.Sprintf("bench-%d", time.Now().UnixNano())
+	if err := client.Bucket(bucket).Create(ctx, "bench", nil); err != nil {
+		b.Fatal(err)
+	}
+	return &benchEnv{
+		cs: &cloudStorageClient{
+			client:  client,
+			jsonAPI: &jsonAPIClient{client: &http.Client{}, endpoint: strings.TrimSuffix(host, "/") + "/storage/v1/"},
+			logger:  &recordingLogger{},
+		},
+		bucket: bucket,
+	}
+}
+
+// request returns the request of given name under the benchmark path
+func (e *benchEnv) request(b *testing.B, name string) CloudFileRequest {
+	b.Helper()
+	cfr, err := NewCloudFileRequest(e.bucket, name, "bench", 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return cfr
+}
+
+// seed stores given objects under the benchmark path, directly in the fake,
+// with concurrent uploads against the emulator
+func (e *benchEnv) seed(b *testing.B, names []string, data func(name string) []byte) {
+	b.Helper()
+	if e.fake != nil {
+		for _, name := range names {
+			e.fake.put(e.bucket, "bench/"+name, data(name), nil)
+		}
+		return
+	}
+	work := make(chan string)
+	errs := make(chan error, 1)
+	var wg sync.WaitGroup
+	for w := 0; w < 32; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range work {
+				cfr, err := NewCloudFileRequest(e.bucket, name, "bench", 0)
+				if err == nil {
+					_, err = e.cs.Upload(context.Background(), bytes.NewReader(data(name)), cfr)
+				}
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+				}
+			}
+		}()
+	}
+	for _, name := range names {
+		work <- name
+	}
+	close(work)
+	wg.Wait()
+	select {
+	case err := <-errs:
+		b.Fatal(err)
+	default:
+	}
+}
+
+// benchContent returns size bytes of generated content, fixtures aren't kept in the repo
+func benchContent(size int) []byte {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(content)
+	return content
+}
+
+func BenchmarkUploadFile(b *testing.B) {
+	for _, s := range benchSizes {
+		b.Run(s.name, func(b *testing.B) {
+			env := newBenchEnv(b)
+			content := benchContent(s.size)
+			cfr := env.request(b, "upload.bin")
+			b.SetBytes(int64(s.size))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := env.cs.UploadFile(context.Background(), bytes.NewReader(content), cfr); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDownloadFile(b *testing.B) {
+	for _, s := range benchSizes {
+		b.Run(s.name, func(b *testing.B) {
+			env := newBenchEnv(b)
+			content := benchContent(s.size)
+			env.seed(b, []string{"download.bin"}, func(string) []byte { return content })
+			cfr := env.request(b, "download.bin")
+			b.SetBytes(int64(s.size))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := env.cs.DownloadFile(context.Background(), io.Discard, cfr); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// benchSmallSizes are the object sizes of the byte slice versus reader & writer benchmarks
+var benchSmallSizes = []struct {
+	name string
+	size int
+}{
+	{"1KB", 1024},
+	{"64KB", 64 * 1024},
+	{"512KB", 512 * 1024},
+}
+
+func BenchmarkUploadBytes(b *testing.B) {
+	for _, s := range benchSmallSizes {
+		content := benchContent(s.size)
+		for _, path := range []string{"bytes", "reader"} {
+			b.Run(s.name+"/"+path, func(b *testing.B) {
+				env := newBenchEnv(b)
+				cfr := env.request(b, "small.json")
+				b.SetBytes(int64(s.size))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					var err error
+					if path == "bytes" {
+						_, err = env.cs.UploadBytes(context.Background(), content, cfr)
+					} else {
+						_, err = env.cs.Upload(context.Background(), bytes.NewReader(content), cfr)
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkDownloadBytes(b *testing.B) {
+	for _, s := range benchSmallSizes {
+		content := benchContent(s.size)
+		for _, path := range []string{"bytes", "writer"} {
+			b.Run(s.name+"/"+path, func(b *testing.B) {
+				env := newBenchEnv(b)
+				env.seed(b, []string{"small.json"}, func(string) []byte { return content })
+				cfr := env.request(b, "small.json")
+				b.SetBytes(int64(s.size))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					var err error
+					if path == "bytes" {
+						_, err = env.cs.DownloadBytes(context.Background(), cfr, 1<<20)
+					} else {
+						var buf bytes.Buffer
+						_, err = env.cs.Download(context.Background(), &buf, cfr)
+					}
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkReadAtPatterns(b *testing.B) {
+	content := benchContent(8 * 1024 * 1024)
+	chunk := 64 * 1024
+	offsets := map[string]func(i int) int64{
+		"sequential": func(i int) int64 { return int64(i*chunk) % int64(len(content)-chunk) },
+		"random": func(i int) int64 {
+			return rand.New(rand.NewSource(int64(i))).Int63n(int64(len(content) - chunk))
+		},
+	}
+	for _, pattern := range []string{"sequential", "random"} {
+		b.Run(pattern, func(b *testing.B) {
+			env := newBenchEnv(b)
+			env.seed(b, []string{"readat.bin"}, func(string) []byte { return content })
+			cfr := env.request(b, "readat.bin")
+			p := make([]byte, chunk)
+			b.SetBytes(int64(chunk))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := env.cs.ReadAt(context.Background(), cfr, p, offsets[pattern](i)); err != nil && err != io.EOF {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkListObjects(b *testing.B) {
+	env := newBenchEnv(b)
+	if env.fake != nil {
+		// service listings are pages of at most 1000 objects
+		env.fake.pageSize = 1000
+	}
+	names := make([]string, benchListObjects)
+	for i := range names {
+		names[i] = fmt.Sprintf("list/%06d.json", i)
+	}
+	env.seed(b, names, func(string) []byte { return []byte("{}") })
+	cfr, err := NewCloudFileRequest(env.bucket, "", "bench/list", 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		listed, err := env.cs.ListObjects(context.Background(), cfr)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(listed) != benchListObjects {
+			b.Fatalf("listed %d objects, want %d", len(listed), benchListObjects)
+		}
+	}
+	b.ReportMetric(float64(benchListObjects), "objects/op")
+}
+
+func BenchmarkParallelUpload(b *testing.B) {
+	size := 64 * 1024 * 1024
+	content := benchContent(size)
+	for _, parallelism := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("parallelism=%d", parallelism), func(b *testing.B) {
+			env := newBenchEnv(b)
+			cfr := env.request(b, "parallel.bin")
+			opts := []UploadOption{WithChunkSize(4 * 1024 * 1024), WithParallelUpload(1, parallelism)}
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := env.cs.UploadFromReaderAt(context.Background(), bytes.NewReader(content), int64(size), cfr, opts...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkParallelDownload downloads one object as concurrent ranges, there's no parallel download method,
+// callers split downloads with OpenRangeReader
+func BenchmarkParallelDownload(b *testing.B) {
+	size := 64 * 1024 * 1024
+	content := benchContent(size)
+	for _, concurrency := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			env := newBenchEnv(b)
+			env.seed(b, []string{"parallel.bin"}, func(string) []byte { return content })
+			cfr := env.request(b, "parallel.bin")
+			part := int64(size / concurrency)
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				var wg sync.WaitGroup
+				errs := make(chan error, concurrency)
+				for c := 0; c < concurrency; c++ {
+					wg.Add(1)
+					go func(offset int64) {
+						defer wg.Done()
+						r, err := env.cs.OpenRangeReader(context.Background(), cfr, offset, part)
+						if err != nil {
+							errs <- err
+							return
+						}
+						defer r.Close()
+						if _, err := io.Copy(io.Discard, r); err != nil {
+							errs <- err
+						}
+					}(int64(c) * part)
+				}
+				wg.Wait()
+				close(errs)
+				if err := <-errs; err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// benchWalkObjects is the number of synthetic objects walk benchmarks walk
+const benchWalkObjects = 200000
+
+// benchListing serves listings of sorted synthetic names, binary searched, with a per page latency
+// standing in for the service's, the fake's listings scan every object
+type benchListing struct {
+	bucket  string
+	names   []string
+	latency time.Duration
+}
+
+func (l *benchListing) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	prefix, endOffset := q.Get("prefix"), q.Get("endOffset")
+	from := prefix
+	for _, offset := range []string{q.Get("startOffset"), q.Get("pageToken")} {
+		if offset > from {
+			from = offset
+		}
+	}
+	max, _ := strconv.Atoi(q.Get("maxResults"))
+	if max <= 0 || max > 1000 {
+		max = 1000
+	}
+	resp := raw.Objects{}
+	i := sort.SearchStrings(l.names, from)
+	for ; i < len(l.names) && strings.HasPrefix(l.names[i], prefix) && (endOffset == "" || l.names[i] < endOffset); i++ {
+		if len(resp.Items) == max {
+			resp.NextPageToken = l.names[i]
+			break
+		}
+		resp.Items = append(resp.Items, &raw.Object{Bucket: l.bucket, Name: l.names[i], Size: 2})
+	}
+	time.Sleep(l.latency)
+	writeJSON(w, resp)
+}
+
+func BenchmarkWalkObjects(b *testing.B) {
+	listing := &benchListing{bucket: "bucket", names: make([]string, benchWalkObjects), latency: 20 * time.Millisecond}
+	for i := range listing.names {
+		listing.names[i] = fmt.Sprintf("walk/%07d.json", i)
+	}
+	cs := newFakeClient(b, listing)
+	cfr, err := NewCloudFileRequest("bucket", "", "walk", 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, shards := range []int{1, 8, 32} {
+		b.Run(fmt.Sprintf("shards-%d", shards), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				walked, err := cs.WalkObjects(context.Background(), cfr, func(*ObjectAttrs) error { return nil }, WithWalkShards(shards))
+				if err != nil {
+					b.Fatal(err)
+				}
+				if walked != benchWalkObjects {
+					b.Fatalf("walked %d objects, want %d", walked, benchWalkObjects)
+				}
+			}
+			b.ReportMetric(float64(benchWalkObjects), "objects/op")
+		})
+	}
+}
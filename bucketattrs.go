@@ -0,0 +1,241 @@
+package cloudstorage
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_GETTING_BUCKET_ATTRS string = "error getting bucket attributes"
+	ERROR_UPDATING_BUCKET      string = "error updating bucket attributes"
+	ERROR_INVALID_RPO          string = "invalid bucket recovery point objective"
+)
+
+var (
+	ErrInvalidRPO = errors.NewAppError(ERROR_INVALID_RPO)
+)
+
+// BucketRPO is the recovery point objective of a dual-region bucket's replication
+type BucketRPO string
+
+const (
+	// RPODefault replicates with the default, best effort, replication
+	RPODefault BucketRPO = "DEFAULT"
+	// RPOAsyncTurbo replicates with turbo replication, objects are replicated within 15 minutes
+	RPOAsyncTurbo BucketRPO = "ASYNC_TURBO"
+)
+
+// storageRPO returns the storage client's RPO of given RPO, false for unknown RPOs
+func storageRPO(rpo BucketRPO) (storage.RPO, bool) {
+	switch rpo {
+	case RPODefault:
+		return storage.RPODefault, true
+	case RPOAsyncTurbo:
+		return storage.RPOAsyncTurbo, true
+	}
+	return storage.RPOUnknown, false
+}
+
+// BucketAttrs are the bucket attributes compliance & setup checks look at
+type BucketAttrs struct {
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	// LocationType is region, dual-region or multi-region
+	LocationType string `json:"location_type"`
+	// DataLocations are the regions of a configurable dual-region bucket, empty for other buckets
+	DataLocations []string `json:"data_locations"`
+	// RPO is the replication's recovery point objective of dual-region buckets, empty for other buckets
+	RPO               BucketRPO `json:"rpo"`
+	StorageClass      string    `json:"storage_class"`
+	VersioningEnabled bool      `json:"versioning_enabled"`
+	// RetentionPeriod is the minimum object age before deletion or replacement, zero without retention policy
+	RetentionPeriod time.Duration `json:"retention_period"`
+	// RetentionLocked marks a retention policy that can't be removed or shortened
+	RetentionLocked bool `json:"retention_locked"`
+	// RetentionEffective is when the retention policy took effect, zero without retention policy
+	RetentionEffective time.Time `json:"retention_effective"`
+	// DefaultEventBasedHold places objects created in the bucket under event-based hold
+	DefaultEventBasedHold bool `json:"default_event_based_hold"`
+	// SoftDeleteRetention is how long deleted objects stay restorable, zero with soft delete disabled
+	SoftDeleteRetention time.Duration `json:"soft_delete_retention"`
+	// SoftDeleteEffective is when the soft delete retention took effect, zero with soft delete disabled
+	SoftDeleteEffective      time.Time         `json:"soft_delete_effective"`
+	UniformBucketLevelAccess bool              `json:"uniform_bucket_level_access"`
+	Labels                   map[string]string `json:"labels"`
+	Created                  time.Time         `json:"created"`
+	Metageneration           int64             `json:"metageneration"`
+}
+
+// newBucketAttrs returns the package bucket attributes of given storage attributes
+func newBucketAttrs(attrs *storage.BucketAttrs) *BucketAttrs {
+	ba := &BucketAttrs{
+		Name:                     attrs.Name,
+		Location:                 attrs.Location,
+		LocationType:             attrs.LocationType,
+		StorageClass:             attrs.StorageClass,
+		VersioningEnabled:        attrs.VersioningEnabled,
+		DefaultEventBasedHold:    attrs.DefaultEventBasedHold,
+		UniformBucketLevelAccess: attrs.UniformBucketLevelAccess.Enabled,
+		Labels:                   map[string]string{},
+		Created:                  attrs.Created,
+		Metageneration:           attrs.MetaGeneration,
+	}
+	if attrs.RPO != storage.RPOUnknown {
+		ba.RPO = BucketRPO(attrs.RPO.String())
+	}
+	if attrs.CustomPlacementConfig != nil && len(attrs.CustomPlacementConfig.DataLocations) > 0 {
+		ba.DataLocations = append([]string{}, attrs.CustomPlacementConfig.DataLocations...)
+	}
+	if attrs.RetentionPolicy != nil {
+		ba.RetentionPeriod = attrs.RetentionPolicy.RetentionPeriod
+		ba.RetentionLocked = attrs.RetentionPolicy.IsLocked
+		ba.RetentionEffective = attrs.RetentionPolicy.EffectiveTime
+	}
+	for k, v := range attrs.Labels {
+		ba.Labels[k] = v
+	}
+	return ba
+}
+
+// scopedBucket returns the bucket of given name, the context scope's bucket when empty
+func (cs *cloudStorageClient) scopedBucket(ctx context.Context, bucket string) (CloudFileRequest, error) {
+	cfr, err := cs.scoped(ctx, CloudFileRequest{bucket: bucket})
+	if err != nil {
+		return CloudFileRequest{}, err
+	}
+	if cfr.bucket == "" {
+		return CloudFileRequest{}, ErrBucketNameMissing
+	}
+	return cfr, nil
+}
+
+// GetBucketAttrs returns attributes of given bucket, a missing bucket matches ErrBucketNotFound.
+// The soft delete policy isn't known to the storage client version, it's read with a second call.
+func (cs *cloudStorageClient) GetBucketAttrs(ctx context.Context, bucket string) (*BucketAttrs, error) {
+	cfr, err := cs.scopedBucket(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	op := cs.startOperation(ctx, "GetBucketAttrs", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	attrs, err := cs.bucketHandle(cfr).Attrs(ctx)
+	if err != nil {
+		op.logger.Error(ERROR_GETTING_BUCKET_ATTRS, zap.Error(err), zap.String("bucket", cfr.bucket))
+		return nil, op.wrapError(err, "%s %s", ERROR_GETTING_BUCKET_ATTRS, cfr.bucket)
+	}
+	ba := newBucketAttrs(attrs)
+	ba.SoftDeleteRetention, ba.SoftDeleteEffective, err = cs.softDeleteRetention(ctx, op, cfr)
+	if err != nil {
+		op.logger.Error(ERROR_GETTING_SOFT_DELETE_POLICY, zap.Error(err), zap.String("bucket", cfr.bucket))
+		return nil, op.wrapError(err, "%s %s", ERROR_GETTING_SOFT_DELETE_POLICY, cfr.bucket)
+	}
+	return ba, nil
+}
+
+// SetBucketVersioning enables or suspends object versioning of given bucket
+func (cs *cloudStorageClient) SetBucketVersioning(ctx context.Context, bucket string, enabled bool) error {
+	if err := cs.mutation(); err != nil {
+		return err
+	}
+	if err := cs.bucketMutation(bucket); err != nil {
+		return err
+	}
+	cfr, err := cs.scopedBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	op := cs.startOperation(ctx, "SetBucketVersioning", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	if _, err := cs.bucketHandle(cfr).Update(ctx, storage.BucketAttrsToUpdate{VersioningEnabled: enabled}); err != nil {
+		op.logger.Error(ERROR_UPDATING_BUCKET, zap.Error(err), zap.String("bucket", cfr.bucket), zap.Bool("versioning", enabled))
+		return op.wrapError(err, "%s %s", ERROR_UPDATING_BUCKET, cfr.bucket)
+	}
+	op.logger.Info("bucket versioning updated", zap.String("bucket", cfr.bucket), zap.Bool("versioning", enabled))
+	return nil
+}
+
+// SetBucketRPO sets the replication's recovery point objective of given dual-region bucket,
+// RPOAsyncTurbo turns on turbo replication, other RPOs fail with ErrInvalidRPO.
+// The service rejects turbo replication of single region & multi-region buckets.
+func (cs *cloudStorageClient) SetBucketRPO(ctx context.Context, bucket string, rpo BucketRPO) error {
+	if err := cs.mutation(); err != nil {
+		return err
+	}
+	if err := cs.bucketMutation(bucket); err != nil {
+		return err
+	}
+	srpo, ok := storageRPO(rpo)
+	if !ok {
+		return errors.WrapError(ErrInvalidRPO, "%s %q", ERROR_INVALID_RPO, rpo)
+	}
+	cfr, err := cs.scopedBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	op := cs.startOperation(ctx, "SetBucketRPO", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	if _, err := cs.bucketHandle(cfr).Update(ctx, storage.BucketAttrsToUpdate{RPO: srpo}); err != nil {
+		op.logger.Error(ERROR_UPDATING_BUCKET, zap.Error(err), zap.String("bucket", cfr.bucket), zap.String("rpo", string(rpo)))
+		return op.wrapError(err, "%s %s", ERROR_UPDATING_BUCKET, cfr.bucket)
+	}
+	op.logger.Info("bucket rpo updated", zap.String("bucket", cfr.bucket), zap.String("rpo", string(rpo)))
+	return nil
+}
+
+// SetBucketLabels replaces the labels of given bucket, labels not given are removed.
+// The update is conditional on the metageneration read, retried on concurrent updates.
+func (cs *cloudStorageClient) SetBucketLabels(ctx context.Context, bucket string, labels map[string]string) error {
+	if err := cs.mutation(); err != nil {
+		return err
+	}
+	if err := cs.bucketMutation(bucket); err != nil {
+		return err
+	}
+	cfr, err := cs.scopedBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	op := cs.startOperation(ctx, "SetBucketLabels", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	bkt := cs.bucketHandle(cfr)
+	for attempt := 1; ; attempt++ {
+		attrs, err := bkt.Attrs(ctx)
+		if err != nil {
+			op.logger.Error(ERROR_GETTING_BUCKET_ATTRS, zap.Error(err), zap.String("bucket", cfr.bucket))
+			return op.wrapError(err, "%s %s", ERROR_GETTING_BUCKET_ATTRS, cfr.bucket)
+		}
+		update := storage.BucketAttrsToUpdate{}
+		for k, v := range labels {
+			update.SetLabel(k, v)
+		}
+		for k := range attrs.Labels {
+			if _, ok := labels[k]; !ok {
+				update.DeleteLabel(k)
+			}
+		}
+
+		_, err = bkt.If(storage.BucketConditions{MetagenerationMatch: attrs.MetaGeneration}).Update(ctx, update)
+		if err == nil {
+			op.logger.Info("bucket labels updated", zap.String("bucket", cfr.bucket), zap.Int("labels", len(labels)))
+			return nil
+		}
+		// bucket label updates share the tag update attempts
+		if !isPreconditionFailed(err) || attempt >= tagUpdateAttempts {
+			op.logger.Error(ERROR_UPDATING_BUCKET, zap.Error(err), zap.String("bucket", cfr.bucket), zap.Int("attempt", attempt))
+			return op.wrapError(err, "%s %s", ERROR_UPDATING_BUCKET, cfr.bucket)
+		}
+		op.logger.Info("bucket metadata changed, retrying label update", zap.String("bucket", cfr.bucket), zap.Int("attempt", attempt))
+	}
+}
@@ -0,0 +1,283 @@
+package cloudstorage
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/comfforts/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+	raw "google.golang.org/api/storage/v1"
+)
+
+func TestGetBucketAttrs(t *testing.T) {
+	f := newFakeGCS()
+	f.buckets = map[string]bool{"bucket": true}
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	f.bucketAttrs = map[string]*raw.Bucket{"bucket": {
+		Name:                  "bucket",
+		Location:              "US-EAST1",
+		LocationType:          "region",
+		StorageClass:          "STANDARD",
+		Versioning:            &raw.BucketVersioning{Enabled: true},
+		RetentionPolicy:       &raw.BucketRetentionPolicy{RetentionPeriod: 86400, IsLocked: true, EffectiveTime: created.Format(time.RFC3339)},
+		DefaultEventBasedHold: true,
+		IamConfiguration: &raw.BucketIamConfiguration{
+			UniformBucketLevelAccess: &raw.BucketIamConfigurationUniformBucketLevelAccess{Enabled: true},
+		},
+		Labels:         map[string]string{"env": "prod"},
+		TimeCreated:    created.Format(time.RFC3339),
+		Metageneration: 3,
+	}}
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	attrs, err := cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, &BucketAttrs{
+		Name:                     "bucket",
+		Location:                 "US-EAST1",
+		LocationType:             "region",
+		StorageClass:             "STANDARD",
+		VersioningEnabled:        true,
+		RetentionPeriod:          24 * time.Hour,
+		RetentionLocked:          true,
+		RetentionEffective:       created,
+		DefaultEventBasedHold:    true,
+		UniformBucketLevelAccess: true,
+		Labels:                   map[string]string{"env": "prod"},
+		Created:                  created,
+		Metageneration:           3,
+	}, attrs)
+
+	_, err = cs.GetBucketAttrs(ctx, "missing")
+	require.ErrorIs(t, err, ErrBucketNotFound)
+	_, err = cs.GetBucketAttrs(ctx, "")
+	require.Equal(t, ErrBucketNameMissing, err)
+
+	// the scope's bucket applies to unnamed buckets
+	attrs, err = cs.GetBucketAttrs(tenantContext(), "")
+	require.NoError(t, err)
+	require.Equal(t, "bucket", attrs.Name)
+}
+
+func TestSetBucketVersioning(t *testing.T) {
+	f := newFakeGCS()
+	f.buckets = map[string]bool{"bucket": true}
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	require.NoError(t, cs.SetBucketVersioning(ctx, "bucket", true))
+	attrs, err := cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.True(t, attrs.VersioningEnabled)
+
+	require.NoError(t, cs.SetBucketVersioning(ctx, "bucket", false))
+	attrs, err = cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.False(t, attrs.VersioningEnabled)
+
+	require.ErrorIs(t, cs.SetBucketVersioning(ctx, "missing", true), ErrBucketNotFound)
+}
+
+func TestSetBucketLabels(t *testing.T) {
+	f := newFakeGCS()
+	f.buckets = map[string]bool{"bucket": true}
+	f.bucketAttrs = map[string]*raw.Bucket{"bucket": {Name: "bucket", Labels: map[string]string{"env": "prod", "owner": "ops"}, Metageneration: 1}}
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	// labels are replaced, not merged
+	require.NoError(t, cs.SetBucketLabels(ctx, "bucket", map[string]string{"env": "staging", "team": "data"}))
+	attrs, err := cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"env": "staging", "team": "data"}, attrs.Labels)
+
+	// a concurrent update between read & write is retried
+	raced := false
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodPatch && !raced {
+			raced = true
+			return http.StatusPreconditionFailed
+		}
+		return 0
+	}
+	require.NoError(t, cs.SetBucketLabels(ctx, "bucket", map[string]string{}))
+	require.True(t, raced)
+	attrs, err = cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.Empty(t, attrs.Labels)
+
+	require.ErrorIs(t, cs.SetBucketLabels(ctx, "missing", map[string]string{"env": "prod"}), ErrBucketNotFound)
+}
+
+func TestBucketRPO(t *testing.T) {
+	f := newFakeGCS()
+	f.buckets = map[string]bool{"bucket": true}
+	f.bucketAttrs = map[string]*raw.Bucket{"bucket": {
+		Name:                  "bucket",
+		Location:              "US",
+		LocationType:          "dual-region",
+		CustomPlacementConfig: &raw.BucketCustomPlacementConfig{DataLocations: []string{"US-EAST1", "US-WEST1"}},
+		Rpo:                   "DEFAULT",
+		Metageneration:        1,
+	}}
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	attrs, err := cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, LocationTypeDualRegion, attrs.LocationType)
+	require.Equal(t, []string{"US-EAST1", "US-WEST1"}, attrs.DataLocations)
+	require.Equal(t, RPODefault, attrs.RPO)
+
+	require.NoError(t, cs.SetBucketRPO(ctx, "bucket", RPOAsyncTurbo))
+	attrs, err = cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, RPOAsyncTurbo, attrs.RPO)
+
+	err = cs.SetBucketRPO(ctx, "bucket", "SYNC")
+	appErr, ok := err.(errors.AppError)
+	require.True(t, ok, "%T %v", err, err)
+	require.Equal(t, ErrInvalidRPO, appErr.Inner)
+	require.ErrorIs(t, cs.SetBucketRPO(ctx, "missing", RPODefault), ErrBucketNotFound)
+	requireScopeViolation(t, cs.Scoped("bucket", "tenant-a").SetBucketRPO(ctx, "bucket", RPODefault))
+}
+
+func TestAssertBucketPolicy(t *testing.T) {
+	f := newFakeGCS()
+	f.buckets = map[string]bool{"dr": true, "regional": true}
+	f.bucketAttrs = map[string]*raw.Bucket{
+		"dr": {
+			Name:                  "dr",
+			Location:              "US",
+			LocationType:          "dual-region",
+			CustomPlacementConfig: &raw.BucketCustomPlacementConfig{DataLocations: []string{"US-EAST1", "US-WEST1"}},
+			Rpo:                   "ASYNC_TURBO",
+			Versioning:            &raw.BucketVersioning{Enabled: true},
+			RetentionPolicy:       &raw.BucketRetentionPolicy{RetentionPeriod: 7 * 86400, EffectiveTime: "2026-01-02T03:04:05Z"},
+			IamConfiguration: &raw.BucketIamConfiguration{
+				UniformBucketLevelAccess: &raw.BucketIamConfigurationUniformBucketLevelAccess{Enabled: true},
+			},
+			Metageneration: 1,
+		},
+		"regional": {Name: "regional", Location: "US-EAST1", LocationType: "region", Metageneration: 1},
+	}
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	want := BucketPolicyAssertion{
+		LocationType:             LocationTypeDualRegion,
+		DataLocations:            []string{"us-west1", "us-east1"},
+		RPO:                      RPOAsyncTurbo,
+		VersioningEnabled:        true,
+		UniformBucketLevelAccess: true,
+		MinRetention:             7 * 24 * time.Hour,
+	}
+
+	require.NoError(t, cs.AssertBucketPolicy(ctx, "dr", want))
+	require.NoError(t, cs.AssertBucketPolicy(ctx, "regional", BucketPolicyAssertion{}), "nothing declared, nothing checked")
+
+	// every violation is listed
+	err := cs.AssertBucketPolicy(ctx, "regional", want)
+	require.ErrorIs(t, err, ErrBucketPolicyViolation)
+	var policyErr BucketPolicyError
+	require.True(t, stderrors.As(err, &policyErr))
+	require.Equal(t, "regional", policyErr.Bucket)
+	require.Equal(t, []string{
+		`location type "region", want "dual-region"`,
+		"data locations [], want [us-west1 us-east1]",
+		`rpo "", want "ASYNC_TURBO"`,
+		"versioning disabled",
+		"uniform bucket level access disabled",
+		"retention period 0s, want at least 168h0m0s",
+	}, policyErr.Violations)
+
+	want.RetentionLocked = true
+	err = cs.AssertBucketPolicy(ctx, "dr", want)
+	require.True(t, stderrors.As(err, &policyErr))
+	require.Equal(t, []string{"retention policy unlocked"}, policyErr.Violations)
+
+	require.ErrorIs(t, cs.AssertBucketPolicy(ctx, "missing", want), ErrBucketNotFound)
+}
+
+func TestBucketRetentionPolicy(t *testing.T) {
+	f := newFakeGCS()
+	f.buckets = map[string]bool{"bucket": true}
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	require.NoError(t, cs.SetBucketRetentionPolicy(ctx, "bucket", time.Hour))
+	attrs, err := cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, attrs.RetentionPeriod)
+	require.False(t, attrs.RetentionLocked)
+	require.False(t, attrs.RetentionEffective.IsZero())
+
+	// unlocked policies can be shortened & removed
+	require.NoError(t, cs.SetBucketRetentionPolicy(ctx, "bucket", 0))
+	attrs, err = cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.Zero(t, attrs.RetentionPeriod)
+	require.ErrorIs(t, cs.LockBucketRetentionPolicy(ctx, "bucket", "bucket"), ErrNoRetentionPolicy)
+
+	// locking needs the bucket's name as confirm token
+	require.NoError(t, cs.SetBucketRetentionPolicy(ctx, "bucket", 24*time.Hour))
+	for _, confirm := range []string{"", "yes", "other"} {
+		require.Equal(t, ErrRetentionLockUnconfirmed, cs.LockBucketRetentionPolicy(ctx, "bucket", confirm))
+	}
+	attrs, err = cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.False(t, attrs.RetentionLocked)
+	require.NoError(t, cs.LockBucketRetentionPolicy(ctx, "bucket", "bucket"))
+	require.NoError(t, cs.LockBucketRetentionPolicy(ctx, "bucket", "bucket"), "locked policies are left alone")
+	attrs, err = cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.True(t, attrs.RetentionLocked)
+	require.Equal(t, 24*time.Hour, attrs.RetentionPeriod)
+
+	// locked policies can only be lengthened
+	for _, period := range []time.Duration{time.Hour, 0} {
+		err = cs.SetBucketRetentionPolicy(ctx, "bucket", period)
+		require.ErrorIs(t, err, ErrRetentionPolicyLocked)
+		var se StorageError
+		require.True(t, stderrors.As(err, &se))
+		require.Equal(t, "SetBucketRetentionPolicy", se.Op)
+	}
+	require.NoError(t, cs.SetBucketRetentionPolicy(ctx, "bucket", 48*time.Hour))
+
+	// the service's rejection is classified too, e.g. a lock landing after the policy was read
+	require.ErrorIs(t, (&operation{name: "SetBucketRetentionPolicy"}).wrapError(
+		&googleapi.Error{Code: http.StatusForbidden, Message: "Cannot reduce retention duration of a locked Retention Policy for bucket 'bucket'."},
+		"%s %s", ERROR_UPDATING_RETENTION_POLICY, "bucket"), ErrRetentionPolicyLocked)
+	require.NotErrorIs(t, (&operation{name: "GetAttrs"}).wrapError(&googleapi.Error{Code: http.StatusForbidden, Message: "denied"}, "error"), ErrRetentionPolicyLocked)
+
+	for _, period := range []time.Duration{-time.Second, 1500 * time.Millisecond, MAX_RETENTION_PERIOD + time.Second} {
+		require.Equal(t, ErrInvalidRetentionPeriod, cs.SetBucketRetentionPolicy(ctx, "bucket", period), "period %s", period)
+	}
+	require.ErrorIs(t, cs.SetBucketRetentionPolicy(ctx, "missing", time.Hour), ErrBucketNotFound)
+	require.ErrorIs(t, cs.LockBucketRetentionPolicy(ctx, "missing", "missing"), ErrBucketNotFound)
+	requireScopeViolation(t, cs.Scoped("bucket", "tenant-a").LockBucketRetentionPolicy(ctx, "bucket", "bucket"))
+}
+
+func TestDefaultEventBasedHold(t *testing.T) {
+	f := newFakeGCS()
+	f.buckets = map[string]bool{"bucket": true}
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	require.NoError(t, cs.SetDefaultEventBasedHold(ctx, "bucket", true))
+	attrs, err := cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.True(t, attrs.DefaultEventBasedHold)
+
+	require.NoError(t, cs.SetDefaultEventBasedHold(ctx, "bucket", false))
+	attrs, err = cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.False(t, attrs.DefaultEventBasedHold)
+
+	require.ErrorIs(t, cs.SetDefaultEventBasedHold(ctx, "missing", true), ErrBucketNotFound)
+	requireScopeViolation(t, cs.Scoped("bucket", "tenant-a").SetDefaultEventBasedHold(ctx, "bucket", true))
+}
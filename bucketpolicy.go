@@ -0,0 +1,116 @@
+package cloudstorage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_BUCKET_POLICY_VIOLATION string = "bucket policy assertion failed"
+)
+
+var (
+	ErrBucketPolicyViolation = errors.NewAppError(ERROR_BUCKET_POLICY_VIOLATION)
+)
+
+// LocationTypeDualRegion is the location type of dual-region buckets
+const LocationTypeDualRegion = "dual-region"
+
+// BucketPolicyAssertion declares the bucket configuration a deployment depends on,
+// zero fields aren't checked
+type BucketPolicyAssertion struct {
+	// LocationType is the required location type, e.g. LocationTypeDualRegion
+	LocationType string
+	// DataLocations are the regions a configurable dual-region bucket must replicate to, in any order
+	DataLocations []string
+	// RPO is the required replication recovery point objective, e.g. RPOAsyncTurbo
+	RPO BucketRPO
+	// VersioningEnabled requires object versioning on
+	VersioningEnabled bool
+	// UniformBucketLevelAccess requires uniform bucket level access on
+	UniformBucketLevelAccess bool
+	// MinRetention is the shortest retention period allowed
+	MinRetention time.Duration
+	// RetentionLocked requires a locked retention policy
+	RetentionLocked bool
+}
+
+// BucketPolicyError lists every violated assertion of a bucket, matches ErrBucketPolicyViolation with errors.Is
+type BucketPolicyError struct {
+	Bucket     string
+	Violations []string
+}
+
+func (e BucketPolicyError) Error() string {
+	return fmt.Sprintf("%s for bucket %s: %s", ERROR_BUCKET_POLICY_VIOLATION, e.Bucket, strings.Join(e.Violations, "; "))
+}
+
+// Is matches ErrBucketPolicyViolation
+func (e BucketPolicyError) Is(target error) bool {
+	return target == ErrBucketPolicyViolation
+}
+
+// violations returns the assertions given attributes violate, in field order
+func (want BucketPolicyAssertion) violations(attrs *BucketAttrs) []string {
+	violations := []string{}
+	if want.LocationType != "" && !strings.EqualFold(attrs.LocationType, want.LocationType) {
+		violations = append(violations, fmt.Sprintf("location type %q, want %q", attrs.LocationType, want.LocationType))
+	}
+	if len(want.DataLocations) > 0 && !sameLocations(attrs.DataLocations, want.DataLocations) {
+		violations = append(violations, fmt.Sprintf("data locations %v, want %v", attrs.DataLocations, want.DataLocations))
+	}
+	if want.RPO != "" && attrs.RPO != want.RPO {
+		violations = append(violations, fmt.Sprintf("rpo %q, want %q", attrs.RPO, want.RPO))
+	}
+	if want.VersioningEnabled && !attrs.VersioningEnabled {
+		violations = append(violations, "versioning disabled")
+	}
+	if want.UniformBucketLevelAccess && !attrs.UniformBucketLevelAccess {
+		violations = append(violations, "uniform bucket level access disabled")
+	}
+	if want.MinRetention > 0 && attrs.RetentionPeriod < want.MinRetention {
+		violations = append(violations, fmt.Sprintf("retention period %s, want at least %s", attrs.RetentionPeriod, want.MinRetention))
+	}
+	if want.RetentionLocked && !attrs.RetentionLocked {
+		violations = append(violations, "retention policy unlocked")
+	}
+	return violations
+}
+
+// sameLocations reports whether given location sets are equal, regardless of order & case
+func sameLocations(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := map[string]int{}
+	for _, l := range got {
+		seen[strings.ToUpper(l)]++
+	}
+	for _, l := range want {
+		if seen[strings.ToUpper(l)] == 0 {
+			return false
+		}
+		seen[strings.ToUpper(l)]--
+	}
+	return true
+}
+
+// AssertBucketPolicy checks given bucket's configuration against the declared expectations, e.g. in
+// readiness probes refusing to start against a misconfigured bucket. Every violated assertion is listed
+// in a single BucketPolicyError, failures reading the bucket's attributes are returned as is.
+func (cs *cloudStorageClient) AssertBucketPolicy(ctx context.Context, bucket string, want BucketPolicyAssertion) error {
+	attrs, err := cs.GetBucketAttrs(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	if violations := want.violations(attrs); len(violations) > 0 {
+		cs.logger.Error(ERROR_BUCKET_POLICY_VIOLATION, zap.Strings("violations", violations), zap.String("bucket", attrs.Name))
+		return BucketPolicyError{Bucket: attrs.Name, Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package cloudstorage
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+)
+
+// WithUserProject bills request's storage calls to given project, for requester pays buckets
+func WithUserProject(project string) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.userProject = project
+	}
+}
+
+// WithKMSKey encrypts uploaded object with given Cloud KMS key name
+func WithKMSKey(keyName string) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.kmsKeyName = keyName
+	}
+}
+
+// BucketRef is a bucket bound to a client, its methods run the client's object operations
+// with the bucket & the handle's defaults pre-bound, so bucket names & object paths can't be transposed.
+// Objects are named by their full path, e.g. reports/2023/q1.csv. Handles are values, cheap to copy,
+// the With methods return modified copies.
+type BucketRef struct {
+	client      CloudStorage
+	name        string
+	userProject string
+	kmsKeyName  string
+}
+
+// Bucket returns a handle of the named bucket on the client
+func (cs *cloudStorageClient) Bucket(name string) BucketRef {
+	return BucketRef{client: cs, name: name}
+}
+
+// Name returns the bucket name
+func (b BucketRef) Name() string {
+	return b.name
+}
+
+// WithUserProject returns a copy of the handle billing its calls to given project
+func (b BucketRef) WithUserProject(project string) BucketRef {
+	b.userProject = project
+	return b
+}
+
+// WithKMSKey returns a copy of the handle encrypting uploads with given Cloud KMS key name
+func (b BucketRef) WithKMSKey(keyName string) BucketRef {
+	b.kmsKeyName = keyName
+	return b
+}
+
+// Request builds the cloud file request of named object in the bucket, the handle's defaults
+// are applied first, given options override them
+func (b BucketRef) Request(name string, opts ...CloudFileRequestOption) (CloudFileRequest, error) {
+	dir, file := path.Split(name)
+	return b.client.NewFileRequest(b.name, file, strings.TrimSuffix(dir, "/"), 0, b.options(opts)...)
+}
+
+// options returns the handle's default request options followed by given options
+func (b BucketRef) options(opts []CloudFileRequestOption) []CloudFileRequestOption {
+	defaults := []CloudFileRequestOption{}
+	if b.userProject != "" {
+		defaults = append(defaults, WithUserProject(b.userProject))
+	}
+	if b.kmsKeyName != "" {
+		defaults = append(defaults, WithKMSKey(b.kmsKeyName))
+	}
+	return append(defaults, opts...)
+}
+
+// Upload uploads named object's content from given reader
+func (b BucketRef) Upload(ctx context.Context, r io.Reader, name string, opts ...CloudFileRequestOption) (UploadResult, error) {
+	cfr, err := b.Request(name, opts...)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	return b.client.Upload(ctx, r, cfr)
+}
+
+// Download copies named object's content to given writer
+func (b BucketRef) Download(ctx context.Context, w io.Writer, name string, opts ...CloudFileRequestOption) (DownloadResult, error) {
+	cfr, err := b.Request(name, opts...)
+	if err != nil {
+		return DownloadResult{}, err
+	}
+	return b.client.Download(ctx, w, cfr)
+}
+
+// ReadAt reads len(p) bytes of named object at given offset
+func (b BucketRef) ReadAt(ctx context.Context, name string, p []byte, off int64, opts ...CloudFileRequestOption) (int, error) {
+	cfr, err := b.Request(name, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return b.client.ReadAt(ctx, cfr, p, off)
+}
+
+// OpenReader returns a live reader of named object, must be closed
+func (b BucketRef) OpenReader(ctx context.Context, name string, opts ...CloudFileRequestOption) (*ObjectReader, error) {
+	cfr, err := b.Request(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return b.client.OpenReader(ctx, cfr)
+}
+
+// Attrs returns named object's attributes
+func (b BucketRef) Attrs(ctx context.Context, name string, opts ...CloudFileRequestOption) (*ObjectAttrs, error) {
+	cfr, err := b.Request(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return b.client.GetAttrs(ctx, cfr)
+}
+
+// Exists reports whether named object exists
+func (b BucketRef) Exists(ctx context.Context, name string, opts ...CloudFileRequestOption) (bool, error) {
+	cfr, err := b.Request(name, opts...)
+	if err != nil {
+		return false, err
+	}
+	return b.client.Exists(ctx, cfr)
+}
+
+// List lists the names of objects under given path prefix, the whole bucket when empty,
+// unlike ListObjects the listing is limited to the prefix
+func (b BucketRef) List(ctx context.Context, prefix string, opts ...CloudFileRequestOption) ([]string, error) {
+	infos, err := b.ListInfo(ctx, prefix, opts...)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name
+	}
+	return names, nil
+}
+
+// ListInfo lists the attributes of objects under given path prefix, the whole bucket when empty
+func (b BucketRef) ListInfo(ctx context.Context, prefix string, opts ...CloudFileRequestOption) ([]*ObjectAttrs, error) {
+	cfr, err := b.dirRequest(prefix, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return b.client.ListObjectsInfo(ctx, cfr)
+}
+
+// Delete deletes named object, objects need a path like with DeleteObject
+func (b BucketRef) Delete(ctx context.Context, name string, opts ...CloudFileRequestOption) error {
+	cfr, err := b.Request(name, opts...)
+	if err != nil {
+		return err
+	}
+	return b.client.DeleteObject(ctx, cfr)
+}
+
+// DeleteAll deletes objects under given path prefix, the whole bucket when empty, like DeleteObjectsWithReport
+func (b BucketRef) DeleteAll(ctx context.Context, prefix string, opts ...CloudFileRequestOption) (DeleteReport, error) {
+	cfr, err := b.dirRequest(prefix, opts...)
+	if err != nil {
+		return DeleteReport{}, err
+	}
+	return b.client.DeleteObjectsWithReport(ctx, cfr)
+}
+
+// dirRequest builds the request of given path prefix in the bucket, the bucket request when empty
+func (b BucketRef) dirRequest(prefix string, opts ...CloudFileRequestOption) (CloudFileRequest, error) {
+	if dir := dirPrefix(prefix); dir != "" {
+		return b.Request(dir, opts...)
+	}
+	return NewBucketRequest(b.name, b.options(opts)...)
+}
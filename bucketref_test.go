@@ -0,0 +1,162 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketRef(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "other/c.json", []byte("{}"), nil)
+	f.put("archive", "reports/a.json", []byte("archived"), nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	reports := cs.Bucket("bucket")
+	require.Equal(t, "bucket", reports.Name())
+
+	res, err := reports.Upload(ctx, strings.NewReader(`{"a":1}`), "reports/2023/a.json", WithContentType("application/json"))
+	require.NoError(t, err)
+	require.Equal(t, "reports/2023/a.json", res.Attrs.Name)
+	_, err = reports.Upload(ctx, strings.NewReader(`{"b":2}`), "reports/2023/b.json")
+	require.NoError(t, err)
+
+	ok, err := reports.Exists(ctx, "reports/2023/a.json")
+	require.NoError(t, err)
+	require.True(t, ok)
+	attrs, err := reports.Attrs(ctx, "reports/2023/a.json")
+	require.NoError(t, err)
+	require.Equal(t, "application/json", attrs.ContentType)
+
+	var buf bytes.Buffer
+	_, err = reports.Download(ctx, &buf, "reports/2023/a.json")
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1}`, buf.String())
+
+	p := make([]byte, 3)
+	n, err := reports.ReadAt(ctx, "reports/2023/b.json", p, 1)
+	require.NoError(t, err)
+	require.Equal(t, `"b"`, string(p[:n]))
+
+	r, err := reports.OpenReader(ctx, "reports/2023/b.json")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	require.Equal(t, `{"b":2}`, string(data))
+
+	// listings are limited to the prefix & the bound bucket
+	names, err := reports.List(ctx, "reports")
+	require.NoError(t, err)
+	require.Equal(t, []string{"reports/2023/a.json", "reports/2023/b.json"}, names)
+	infos, err := reports.ListInfo(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, infos, 3)
+
+	require.NoError(t, reports.Delete(ctx, "reports/2023/a.json"))
+	ok, err = reports.Exists(ctx, "reports/2023/a.json")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	report, err := reports.DeleteAll(ctx, "reports/")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), report.Deleted)
+	_, _, ok = f.get("bucket", "other/c.json")
+	require.True(t, ok)
+	_, _, ok = f.get("archive", "reports/a.json")
+	require.True(t, ok, "other buckets are left alone")
+}
+
+// testKMSKey is the Cloud KMS key name of encryption tests
+const testKMSKey = "projects/p/locations/l/keyRings/r/cryptoKeys/k"
+
+// recordKMSKeys records the KMS key name of the fake's upload starts & rewrites, keyed by request kind
+func recordKMSKeys(f *fakeGCS) map[string][]string {
+	var mu sync.Mutex
+	keys := map[string][]string{}
+	f.fail = func(r *http.Request) int {
+		kind, key := "", r.URL.Query().Get("kmsKeyName")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/upload/") && r.URL.Query().Get("upload_id") == "":
+			kind = "upload"
+		case strings.Contains(r.URL.Path, "/rewriteTo/"):
+			kind, key = "rewrite", r.URL.Query().Get("destinationKmsKeyName")
+		default:
+			return 0
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		keys[kind] = append(keys[kind], key)
+		return 0
+	}
+	return keys
+}
+
+func TestBucketRefDefaults(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	var mu sync.Mutex
+	projects, keys := map[string]bool{}, map[string]bool{}
+	f.fail = func(r *http.Request) int {
+		mu.Lock()
+		defer mu.Unlock()
+		// media reads carry the project in a header
+		project := r.URL.Query().Get("userProject")
+		if project == "" {
+			project = r.Header.Get("X-Goog-User-Project")
+		}
+		projects[project] = true
+		if strings.HasPrefix(r.URL.Path, "/upload/") {
+			keys[r.URL.Query().Get("kmsKeyName")] = true
+		}
+		return 0
+	}
+	ctx := context.Background()
+	plain := cs.Bucket("bucket")
+	billed := plain.WithUserProject("billing").WithKMSKey(testKMSKey)
+
+	_, err := billed.Upload(ctx, strings.NewReader("data"), "path/file.bin")
+	require.NoError(t, err)
+	_, err = billed.Attrs(ctx, "path/file.bin")
+	require.NoError(t, err)
+	_, err = billed.Download(ctx, io.Discard, "path/file.bin")
+	require.NoError(t, err)
+	_, err = billed.List(ctx, "path")
+	require.NoError(t, err)
+	require.NoError(t, billed.Delete(ctx, "path/file.bin"))
+	require.Equal(t, map[string]bool{"billing": true}, projects)
+	require.Equal(t, map[string]bool{testKMSKey: true}, keys)
+
+	// handles are values, request options override the handle's defaults
+	projects = map[string]bool{}
+	_, err = plain.Upload(ctx, strings.NewReader("data"), "path/file.bin")
+	require.NoError(t, err)
+	_, err = billed.Attrs(ctx, "path/file.bin", WithUserProject("other"))
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"": true, "other": true}, projects)
+
+	cfr, err := billed.Request("path/to/file.bin")
+	require.NoError(t, err)
+	require.Equal(t, "bucket", cfr.bucket)
+	require.Equal(t, "path/to", cfr.path)
+	require.Equal(t, "file.bin", cfr.file)
+	require.Equal(t, "billing", cfr.userProject)
+}
+
+func TestBucketRefValidation(t *testing.T) {
+	cs := newFakeClient(t, newFakeGCS())
+	ctx := context.Background()
+
+	_, err := cs.Bucket("").Upload(ctx, strings.NewReader("data"), "path/file.bin")
+	require.ErrorIs(t, err, ErrBucketNameMissing)
+	_, err = cs.Bucket("bucket").Upload(ctx, strings.NewReader("data"), "path/")
+	require.ErrorIs(t, err, ErrFileNameMissing)
+	_, err = cs.Bucket("bucket").Attrs(ctx, "path/file.bin", WithProfile("unknown"))
+	require.Equal(t, ErrUnknownProfile, errorInner(err))
+}
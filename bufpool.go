@@ -0,0 +1,61 @@
+package cloudstorage
+
+import (
+	"io"
+	"sync"
+)
+
+const (
+	// DEFAULT_COPY_BUFFER_SIZE is the default transfer buffer size, io.Copy's buffer size
+	DEFAULT_COPY_BUFFER_SIZE = ThirtyTwoKB
+	// MAX_POOLED_BUFFER_SIZE is the largest transfer buffer kept for reuse
+	MAX_POOLED_BUFFER_SIZE BufferSize = 1024 * 1024 // 1MB
+)
+
+// bufferPool reuses transfer buffers across copies, safe for concurrent use.
+// Buffers larger than MAX_POOLED_BUFFER_SIZE aren't retained.
+type bufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+func newBufferPool(size BufferSize) *bufferPool {
+	if size <= 0 {
+		size = DEFAULT_COPY_BUFFER_SIZE
+	}
+	p := &bufferPool{size: int(size)}
+	p.pool.New = func() interface{} {
+		buf := make([]byte, p.size)
+		return &buf
+	}
+	return p
+}
+
+func (p *bufferPool) get() *[]byte {
+	return p.pool.Get().(*[]byte)
+}
+
+func (p *bufferPool) put(buf *[]byte) {
+	if cap(*buf) > int(MAX_POOLED_BUFFER_SIZE) {
+		return
+	}
+	p.pool.Put(buf)
+}
+
+// copy copies src to dst with a pooled buffer
+func (p *bufferPool) copy(dst io.Writer, src io.Reader) (int64, error) {
+	buf := p.get()
+	defer p.put(buf)
+	return io.CopyBuffer(dst, src, *buf)
+}
+
+// defaultBufferPool serves clients created without NewCloudStorageClient
+var defaultBufferPool = newBufferPool(DEFAULT_COPY_BUFFER_SIZE)
+
+// buffers returns the client's transfer buffer pool
+func (cs *cloudStorageClient) buffers() *bufferPool {
+	if cs.bufPool != nil {
+		return cs.bufPool
+	}
+	return defaultBufferPool
+}
@@ -0,0 +1,73 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferPool(t *testing.T) {
+	p := newBufferPool(0)
+	buf := p.get()
+	require.Equal(t, int(DEFAULT_COPY_BUFFER_SIZE), len(*buf))
+	p.put(buf)
+
+	p = newBufferPool(OneKB)
+	buf = p.get()
+	require.Equal(t, int(OneKB), len(*buf))
+
+	var dst bytes.Buffer
+	n, err := p.copy(struct{ io.Writer }{&dst}, strings.NewReader(strings.Repeat("x", 5000)))
+	require.NoError(t, err)
+	require.Equal(t, int64(5000), n)
+	require.Equal(t, 5000, dst.Len())
+
+	// oversized buffers aren't retained
+	big := newBufferPool(MAX_POOLED_BUFFER_SIZE * 2)
+	buf = big.get()
+	big.put(buf)
+	require.NotSame(t, buf, big.get())
+}
+
+// writerOnly & readerOnly hide ReaderFrom & WriterTo so copies use a buffer
+type writerOnly struct{ io.Writer }
+type readerOnly struct{ io.Reader }
+
+func BenchmarkCopyBuffer(b *testing.B) {
+	data := bytes.Repeat([]byte("x"), 256*1024)
+
+	b.Run("io.Copy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			io.Copy(writerOnly{io.Discard}, readerOnly{bytes.NewReader(data)})
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		p := newBufferPool(DEFAULT_COPY_BUFFER_SIZE)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p.copy(writerOnly{io.Discard}, readerOnly{bytes.NewReader(data)})
+		}
+	})
+}
+
+func BenchmarkDownload(b *testing.B) {
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", bytes.Repeat([]byte("x"), 256*1024), nil)
+	cs := newFakeClient(b, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := cs.Download(context.Background(), io.Discard, cfr); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
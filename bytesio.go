@@ -0,0 +1,89 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/comfforts/errors"
+)
+
+const ERROR_OBJECT_TOO_LARGE string = "object exceeds the download's max size"
+
+var (
+	ErrObjectTooLarge = errors.NewAppError(ERROR_OBJECT_TOO_LARGE)
+)
+
+// ObjectTooLargeError is returned by DownloadBytes for objects over its max size,
+// matches ErrObjectTooLarge with errors.Is
+type ObjectTooLargeError struct {
+	MaxSize int64
+	// Size is the stored object's size, or the bytes read when transcoded content crossed the max size
+	Size int64
+}
+
+func (e ObjectTooLargeError) Error() string {
+	return fmt.Sprintf("%s %d: object of %d bytes", ERROR_OBJECT_TOO_LARGE, e.MaxSize, e.Size)
+}
+
+// Is matches ErrObjectTooLarge
+func (e ObjectTooLargeError) Is(target error) bool {
+	return target == ErrObjectTooLarge
+}
+
+// UploadBytes uploads given content like Upload, in a single request without a resumable session,
+// with the content's CRC32C computed up front unless set with WithCRC32C. Suited to small objects,
+// the slice isn't copied & mustn't be modified until the upload returns.
+func (cs *cloudStorageClient) UploadBytes(ctx context.Context, data []byte, cfr CloudFileRequest) (UploadResult, error) {
+	if cfr.IsZero() {
+		return UploadResult{}, ErrEmptyRequest
+	}
+	cfr.size, cfr.singleRequest = int64(len(data)), true
+	if !cfr.sendCRC32C {
+		cfr.crc32c, cfr.sendCRC32C = crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)), true
+	}
+	return cs.Upload(ctx, bytes.NewReader(data), cfr)
+}
+
+// DownloadBytes downloads the object's content like Download into a slice allocated once, of the size
+// looked up before the read. Objects over maxSize bytes fail with ObjectTooLargeError before anything
+// is read, transcoded ones once their decompressed content crosses it, zero or less doesn't limit.
+func (cs *cloudStorageClient) DownloadBytes(ctx context.Context, cfr CloudFileRequest, maxSize int64) ([]byte, error) {
+	w := &bytesWriter{max: maxSize}
+	if _, err := cs.Download(ctx, w, cfr); err != nil {
+		return nil, err
+	}
+	return w.buf, nil
+}
+
+// sizedWriter is a download writer told the object's size before the read, the stored size,
+// exact unless the download is transcoded
+type sizedWriter interface {
+	expect(size int64, exact bool) error
+}
+
+// bytesWriter collects downloaded content in a slice sized by expect, up to max bytes when positive
+type bytesWriter struct {
+	buf []byte
+	max int64
+}
+
+func (w *bytesWriter) expect(size int64, exact bool) error {
+	if w.max > 0 && size > w.max {
+		if exact {
+			return ObjectTooLargeError{MaxSize: w.max, Size: size}
+		}
+		size = w.max
+	}
+	w.buf = make([]byte, 0, size)
+	return nil
+}
+
+func (w *bytesWriter) Write(p []byte) (int, error) {
+	if n := int64(len(w.buf) + len(p)); w.max > 0 && n > w.max {
+		return 0, ObjectTooLargeError{MaxSize: w.max, Size: n}
+	}
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
@@ -0,0 +1,109 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"hash/crc32"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadBytes(t *testing.T) {
+	f := newFakeGCS()
+	var resumable int64
+	f.fail = func(r *http.Request) int {
+		if r.URL.Query().Get("uploadType") == "resumable" {
+			atomic.AddInt64(&resumable, 1)
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "blob.json", "data", 0)
+	require.NoError(t, err)
+	// content over a chunk is still sent in one request
+	cfr.chunkSize = 256 * 1024
+	content := bytes.Repeat([]byte(`{"k":"v"}`), 64*1024)
+
+	res, err := cs.UploadBytes(ctx, content, cfr)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), res.Bytes)
+	require.Equal(t, crc32.Checksum(content, crc32.MakeTable(crc32.Castagnoli)), res.Attrs.CRC32C)
+	require.Zero(t, atomic.LoadInt64(&resumable))
+	stored, _, ok := f.get("bucket", "data/blob.json")
+	require.True(t, ok)
+	require.Equal(t, content, stored)
+
+	_, err = cs.Upload(ctx, bytes.NewReader(content), cfr)
+	require.NoError(t, err)
+	require.NotZero(t, atomic.LoadInt64(&resumable), "readers are uploaded in chunks")
+
+	// the precomputed checksum is sent, a declared one wins
+	declared := cfr
+	WithCRC32C(1)(&declared)
+	_, err = cs.UploadBytes(ctx, content, declared)
+	require.Error(t, err)
+	_, err = cs.UploadBytes(ctx, []byte{}, cfr)
+	require.NoError(t, err)
+}
+
+func TestDownloadBytes(t *testing.T) {
+	f := newFakeGCS()
+	var reads int64
+	f.fail = func(r *http.Request) int {
+		if pathSegments(r)[0] == "bucket" {
+			atomic.AddInt64(&reads, 1)
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	f.put("bucket", "data/blob.json", content, nil)
+	f.put("bucket", "data/empty.json", nil, nil)
+	cfr, err := NewCloudFileRequest("bucket", "blob.json", "data", 0)
+	require.NoError(t, err)
+
+	for _, max := range []int64{0, int64(len(content)), 1 << 30} {
+		data, err := cs.DownloadBytes(ctx, cfr, max)
+		require.NoError(t, err)
+		require.Equal(t, content, data)
+		require.Equal(t, len(content), cap(data), "allocated once")
+	}
+
+	require.NotZero(t, atomic.LoadInt64(&reads))
+	atomic.StoreInt64(&reads, 0)
+	_, err = cs.DownloadBytes(ctx, cfr, int64(len(content))-1)
+	require.ErrorIs(t, err, ErrObjectTooLarge)
+	var tooLarge ObjectTooLargeError
+	require.True(t, stderrors.As(err, &tooLarge))
+	require.Equal(t, ObjectTooLargeError{MaxSize: int64(len(content)) - 1, Size: int64(len(content))}, tooLarge)
+	require.Zero(t, atomic.LoadInt64(&reads), "content isn't read")
+
+	empty, err := NewCloudFileRequest("bucket", "empty.json", "data", 0)
+	require.NoError(t, err)
+	data, err := cs.DownloadBytes(ctx, empty, 10)
+	require.NoError(t, err)
+	require.NotNil(t, data)
+	require.Empty(t, data)
+
+	missing, err := NewCloudFileRequest("bucket", "missing.json", "data", 0)
+	require.NoError(t, err)
+	data, err = cs.DownloadBytes(ctx, missing, 10)
+	require.Error(t, err)
+	require.Nil(t, data)
+
+	// transcoded content is only known too large once read past the max size
+	w := &bytesWriter{max: 8}
+	require.NoError(t, w.expect(100, false))
+	require.Equal(t, 8, cap(w.buf))
+	_, err = w.Write([]byte("01234"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("56789"))
+	require.ErrorIs(t, err, ErrObjectTooLarge)
+	require.Equal(t, "01234", string(w.buf))
+}
@@ -0,0 +1,146 @@
+package cloudstorage
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/metadata"
+)
+
+// callExtras are the custom headers & API parameters set on an operation's API calls
+type callExtras struct {
+	header http.Header
+	params url.Values
+}
+
+type callExtrasKey struct{}
+
+// empty reports whether there's nothing to set
+func (e callExtras) empty() bool {
+	return len(e.header) == 0 && len(e.params) == 0
+}
+
+// with returns a copy of the extras, given extras' values replacing the keys they set
+func (e callExtras) with(o callExtras) callExtras {
+	merged := callExtras{header: http.Header{}, params: url.Values{}}
+	for _, src := range []callExtras{e, o} {
+		for k, vs := range src.header {
+			merged.header[k] = append([]string{}, vs...)
+		}
+		for k, vs := range src.params {
+			merged.params[k] = append([]string{}, vs...)
+		}
+	}
+	return merged
+}
+
+// callHeader returns the extras setting given header, in canonical form
+func callHeader(key, value string) callExtras {
+	h := http.Header{}
+	h.Set(key, value)
+	return callExtras{header: h}
+}
+
+// callParams returns the extras setting the query parameters of given call options
+func callParams(opts ...googleapi.CallOption) callExtras {
+	params := url.Values{}
+	for _, opt := range opts {
+		if m, ok := opt.(googleapi.MultiCallOption); ok {
+			k, vs := m.GetMulti()
+			params[k] = append([]string{}, vs...)
+			continue
+		}
+		k, v := opt.Get()
+		params.Set(k, v)
+	}
+	return callExtras{params: params}
+}
+
+// WithCustomHeader sets an HTTP header on every API call of the request's operation, e.g. a header
+// an access perimeter requires. Unstable: headers aren't validated, replacing one the SDK sets,
+// e.g. an authorization or checksum header, breaks calls. Calls of gRPC clients carry the header
+// as outgoing metadata.
+func WithCustomHeader(key, value string) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.callExtras = cfr.callExtras.with(callHeader(key, value))
+	}
+}
+
+// WithCallOption sets API query parameters on every API call of the request's operation, e.g.
+// googleapi.QuotaUser, or googleapi.QueryParameter for parameters the SDK doesn't model yet.
+// Unstable: parameters aren't validated & replace the ones the SDK sets, HTTP clients only.
+func WithCallOption(opts ...googleapi.CallOption) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.callExtras = cfr.callExtras.with(callParams(opts...))
+	}
+}
+
+// WithContextHeader returns a copy of given context setting an HTTP header on every API call of
+// operations started with it, like WithCustomHeader, for operations without a request, e.g. SampleUsage.
+// Request headers take precedence. Unstable, see WithCustomHeader.
+func WithContextHeader(ctx context.Context, key, value string) context.Context {
+	return withCallExtras(ctx, callHeader(key, value))
+}
+
+// WithContextCallOption returns a copy of given context setting API query parameters on every API call
+// of operations started with it, like WithCallOption. Unstable, see WithCallOption.
+func WithContextCallOption(ctx context.Context, opts ...googleapi.CallOption) context.Context {
+	return withCallExtras(ctx, callParams(opts...))
+}
+
+// withCallExtras returns a copy of given context carrying its extras with given ones,
+// headers also as gRPC outgoing metadata
+func withCallExtras(ctx context.Context, extras callExtras) context.Context {
+	if extras.empty() {
+		return ctx
+	}
+	current, _ := ctx.Value(callExtrasKey{}).(callExtras)
+	extras = current.with(extras)
+	if len(extras.header) > 0 {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		md = md.Copy()
+		for k, vs := range extras.header {
+			md.Set(k, vs...)
+		}
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+	return context.WithValue(ctx, callExtrasKey{}, extras)
+}
+
+// callExtrasTransport sets the extras of the request's context on sent requests
+type callExtrasTransport struct {
+	base http.RoundTripper
+}
+
+func (t *callExtrasTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	extras, ok := r.Context().Value(callExtrasKey{}).(callExtras)
+	if !ok || extras.empty() {
+		return t.base.RoundTrip(r)
+	}
+	// round trippers don't modify the caller's request
+	r = r.Clone(r.Context())
+	for k, vs := range extras.header {
+		r.Header[k] = vs
+	}
+	if len(extras.params) > 0 {
+		query := r.URL.Query()
+		for k, vs := range extras.params {
+			query[k] = vs
+		}
+		r.URL.RawQuery = query.Encode()
+	}
+	return t.base.RoundTrip(r)
+}
+
+// withCallExtrasTransport returns a copy of given HTTP client setting the extras of requests' contexts
+func withCallExtrasTransport(hc *http.Client) *http.Client {
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped := *hc
+	wrapped.Transport = &callExtrasTransport{base: base}
+	return &wrapped
+}
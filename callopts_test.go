@@ -0,0 +1,165 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/metadata"
+)
+
+// sentCall is the header & query of a request the fake received
+type sentCall struct {
+	method string
+	header http.Header
+	query  url.Values
+}
+
+// recordCalls records the header & query of every request the fake receives
+func recordCalls(f *fakeGCS) func() []sentCall {
+	var mu sync.Mutex
+	calls := []sentCall{}
+	f.fail = func(r *http.Request) int {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, sentCall{method: r.Method, header: r.Header.Clone(), query: r.URL.Query()})
+		return 0
+	}
+	return func() []sentCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]sentCall{}, calls...)
+	}
+}
+
+func TestCallExtras(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "data/a.json", []byte("a"), nil)
+	f.put("bucket", "data/b.json", []byte("b"), nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	calls := recordCalls(f)
+
+	cfr, err := NewCloudFileRequest("bucket", "c.json", "data", 0,
+		WithCustomHeader("x-perimeter-audit", "audit-1"),
+		WithCallOption(googleapi.QuotaUser("tenant-a"), googleapi.QueryParameter("preview", "1", "2")),
+	)
+	require.NoError(t, err)
+	_, err = cs.UploadFile(ctx, bytes.NewReader([]byte("c")), cfr)
+	require.NoError(t, err)
+	sent := calls()
+	require.NotEmpty(t, sent)
+	for _, call := range sent {
+		require.Equal(t, []string{"audit-1"}, call.header.Values("X-Perimeter-Audit"), call.method)
+		require.Equal(t, "tenant-a", call.query.Get("quotaUser"))
+		require.Equal(t, []string{"1", "2"}, call.query["preview"])
+	}
+
+	// every call of a multi call operation carries them, nested operations don't repeat them
+	counter, err := NewCloudFileRequest("bucket", "seq", "counters", 0, WithCustomHeader("X-Perimeter-Audit", "audit-2"))
+	require.NoError(t, err)
+	start := len(calls())
+	_, err = cs.IncrementCounter(ctx, counter, 1)
+	require.NoError(t, err)
+	sent = calls()[start:]
+	require.GreaterOrEqual(t, len(sent), 2)
+	for _, call := range sent {
+		require.Equal(t, []string{"audit-2"}, call.header.Values("X-Perimeter-Audit"), call.method)
+	}
+
+	// operations without a request take them from the context, request values win
+	hctx := WithContextHeader(WithContextCallOption(ctx, googleapi.QuotaUser("ctx-user")), "X-Perimeter-Audit", "ctx")
+	start = len(calls())
+	_, err = cs.ListTree(hctx, "bucket", "data", 0)
+	require.NoError(t, err)
+	sent = calls()[start:]
+	require.Len(t, sent, 1)
+	require.Equal(t, "ctx", sent[0].header.Get("X-Perimeter-Audit"))
+	require.Equal(t, "ctx-user", sent[0].query.Get("quotaUser"))
+
+	start = len(calls())
+	_, err = cs.GetAttrs(hctx, cfr)
+	require.NoError(t, err)
+	sent = calls()[start:]
+	require.Equal(t, []string{"audit-1"}, sent[0].header.Values("X-Perimeter-Audit"))
+	require.Equal(t, "tenant-a", sent[0].query.Get("quotaUser"))
+
+	// requests without extras send none
+	plain, err := NewCloudFileRequest("bucket", "a.json", "data", 0)
+	require.NoError(t, err)
+	start = len(calls())
+	_, err = cs.GetAttrs(ctx, plain)
+	require.NoError(t, err)
+	sent = calls()[start:]
+	require.Empty(t, sent[0].header.Get("X-Perimeter-Audit"))
+	require.Empty(t, sent[0].query.Get("quotaUser"))
+}
+
+func TestCallExtrasReaders(t *testing.T) {
+	f := newFakeGCS()
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	f.put("bucket", "data/a.bin", content, nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	calls := recordCalls(f)
+	cfr, err := NewCloudFileRequest("bucket", "a.bin", "data", 0,
+		WithCustomHeader("X-Perimeter-Audit", "audit-1"), WithCallOption(googleapi.QuotaUser("tenant-a")))
+	require.NoError(t, err)
+
+	// attribute lookups & reads of opened readers, reader ats & parallel downloads carry them
+	for name, read := range map[string]func() error{
+		"OpenReader": func() error {
+			or, err := cs.OpenReader(ctx, cfr)
+			if err != nil {
+				return err
+			}
+			if _, err := io.ReadAll(or); err != nil {
+				return err
+			}
+			return or.Close()
+		},
+		"NewReaderAt": func() error {
+			ra, err := cs.NewReaderAt(ctx, cfr)
+			if err != nil {
+				return err
+			}
+			_, err = ra.ReadAt(make([]byte, 10), 100)
+			return err
+		},
+		"DownloadToWriterAt": func() error {
+			_, err := cs.DownloadToWriterAt(ctx, &memWriterAt{}, cfr, WithDownloadChunkSize(256), WithDownloadParallelism(2))
+			return err
+		},
+	} {
+		start := len(calls())
+		require.NoError(t, read(), name)
+		sent := calls()[start:]
+		require.GreaterOrEqual(t, len(sent), 2, name)
+		for _, call := range sent {
+			require.Equal(t, []string{"audit-1"}, call.header.Values("X-Perimeter-Audit"), "%s %s %v", name, call.method, call.query)
+			require.Equal(t, "tenant-a", call.query.Get("quotaUser"), name)
+		}
+	}
+}
+
+func TestCallExtrasMetadata(t *testing.T) {
+	cs := newFakeClient(t, newFakeGCS())
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-existing", "kept")
+	cfr, err := NewCloudFileRequest("bucket", "a.json", "data", 0, WithCustomHeader("X-Perimeter-Audit", "audit-1"))
+	require.NoError(t, err)
+
+	// gRPC calls carry the headers as outgoing metadata
+	op := cs.startOperation(WithContextHeader(ctx, "X-Perimeter-Audit", "ctx"), "GetAttrs", cfr)
+	md, ok := metadata.FromOutgoingContext(op.ctx)
+	require.True(t, ok)
+	require.Equal(t, []string{"audit-1"}, md.Get("x-perimeter-audit"))
+	require.Equal(t, []string{"kept"}, md.Get("x-existing"))
+
+	require.Equal(t, ctx, withCallExtras(ctx, CloudFileRequest{bucket: "bucket"}.callExtras), "contexts of requests without extras are kept")
+}
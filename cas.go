@@ -0,0 +1,144 @@
+package cloudstorage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"io"
+
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_INVALID_DIGEST  string = "invalid SHA-256 digest"
+	ERROR_DIGEST_MISMATCH string = "content doesn't match its digest"
+)
+
+var (
+	ErrInvalidDigest  = errors.NewAppError(ERROR_INVALID_DIGEST)
+	ErrDigestMismatch = errors.NewAppError(ERROR_DIGEST_MISMATCH)
+)
+
+// DigestMismatchError is returned when content stored under a digest doesn't match it,
+// matches ErrDigestMismatch with errors.Is
+type DigestMismatchError struct {
+	Bucket string
+	Object string
+	// Digest is the object's name digest, Got the read content's, empty when the stored
+	// object's size or CRC32C already differ from the uploaded content's
+	Digest string
+	Got    string
+}
+
+func (e DigestMismatchError) Error() string {
+	return fmt.Sprintf("%s %s/%s: got %q", ERROR_DIGEST_MISMATCH, e.Bucket, e.Object, e.Got)
+}
+
+// Is matches ErrDigestMismatch
+func (e DigestMismatchError) Is(target error) bool {
+	return target == ErrDigestMismatch
+}
+
+// casRequest returns the request of given digest's object under prefix, the bucket may come from the context scope
+func casRequest(bucket, prefix, digest string) (CloudFileRequest, error) {
+	if len(digest) != sha256.Size*2 {
+		return CloudFileRequest{}, ErrInvalidDigest
+	}
+	// lower case only, the same content is never stored under two names
+	for _, c := range digest {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return CloudFileRequest{}, ErrInvalidDigest
+		}
+	}
+	return CloudFileRequest{bucket: bucket, path: prefix, file: digest}, nil
+}
+
+// PutCAS stores given content under its lower case hex SHA-256 digest in bucket & prefix, unless already stored,
+// returns the digest & whether this call created the object. The stream is spooled while hashed,
+// the upload is conditional on the object not existing & verified with the spooled content's CRC32C,
+// a concurrent upload of the same content counts as stored. An object already stored under the digest
+// with another size or CRC32C fails with DigestMismatchError, one without a CRC32C with ErrNotSupportedByBackend.
+func (cs *cloudStorageClient) PutCAS(ctx context.Context, bucket, prefix string, r io.Reader) (string, bool, error) {
+	if err := cs.mutation(); err != nil {
+		return "", false, err
+	}
+	// the limit applies to spooling, an unbounded stream never fills the spool
+	limited, err := cs.limitUpload(r, 0)
+	if err != nil {
+		return "", false, err
+	}
+	sum := sha256.New()
+	sp, err := spool(io.TeeReader(limited, sum), cs.spoolThreshold(), cs.config.SpoolDir, cs.buffers())
+	if stderrors.Is(err, ErrMaxUploadExceeded) {
+		cs.logger.Error(ERROR_MAX_UPLOAD_EXCEEDED, zap.Error(err), zap.String("bucket", bucket), zap.String("prefix", prefix))
+		return "", false, err
+	}
+	if err != nil {
+		cs.logger.Error(ERROR_SPOOLING_UPLOAD, zap.Error(err), zap.String("bucket", bucket), zap.String("prefix", prefix))
+		return "", false, errors.WrapError(err, "%s %s", ERROR_SPOOLING_UPLOAD, prefix)
+	}
+	defer sp.Close()
+	digest := hex.EncodeToString(sum.Sum(nil))
+	cfr, err := casRequest(bucket, prefix, digest)
+	if err != nil {
+		return "", false, err
+	}
+
+	attrs, err := cs.GetAttrs(ctx, cfr)
+	switch {
+	case err == nil:
+		return digest, false, casStored(attrs, sp, digest)
+	case !isNotFound(err):
+		return "", false, err
+	}
+
+	upload := cfr
+	WithIfGenerationMatch(0)(&upload)
+	WithCRC32C(sp.crc)(&upload)
+	_, err = cs.Upload(ctx, sp, upload)
+	if err == nil {
+		return digest, true, nil
+	}
+	if !isPreconditionFailed(err) {
+		return "", false, err
+	}
+	// lost the race to a concurrent upload, stored content is checked like an existing object's,
+	// the upload dropped the cached missing entry
+	if attrs, err = cs.GetAttrs(ctx, cfr); err != nil {
+		return "", false, err
+	}
+	return digest, false, casStored(attrs, sp, digest)
+}
+
+// casStored checks an object stored under the digest has the spooled content's size & CRC32C
+func casStored(attrs *ObjectAttrs, sp *spooled, digest string) error {
+	if err := requireAttrs(attrs, AttrCRC32C, "PutCAS"); err != nil {
+		return err
+	}
+	if attrs.Size != sp.size || attrs.CRC32C != sp.crc {
+		return DigestMismatchError{Bucket: attrs.Bucket, Object: attrs.Name, Digest: digest}
+	}
+	return nil
+}
+
+// GetCAS copies the content stored under given digest in bucket & prefix to given writer, returns the bytes written.
+// Content not matching the digest fails with DigestMismatchError once written, callers discard what they received.
+func (cs *cloudStorageClient) GetCAS(ctx context.Context, bucket, prefix, digest string, w io.Writer) (int64, error) {
+	cfr, err := casRequest(bucket, prefix, digest)
+	if err != nil {
+		return 0, err
+	}
+	sum := sha256.New()
+	res, err := cs.Download(ctx, io.MultiWriter(w, sum), cfr)
+	if err != nil {
+		return res.Bytes, err
+	}
+	if got := hex.EncodeToString(sum.Sum(nil)); got != digest {
+		cs.logger.Error(ERROR_DIGEST_MISMATCH, zap.String("bucket", res.Attrs.Bucket), zap.String("filepath", res.Attrs.Name), zap.String("got", got))
+		return res.Bytes, DigestMismatchError{Bucket: res.Attrs.Bucket, Object: res.Attrs.Name, Digest: digest, Got: got}
+	}
+	return res.Bytes, nil
+}
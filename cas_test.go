@@ -0,0 +1,111 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestPutCAS(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	digest, created, err := cs.PutCAS(ctx, "bucket", "objects", strings.NewReader("artifact"))
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Equal(t, sha256Hex("artifact"), digest)
+	data, _, ok := f.get("bucket", "objects/"+digest)
+	require.True(t, ok)
+	require.Equal(t, "artifact", string(data))
+
+	// stored content isn't uploaded again
+	calls := recordRequests(f, http.MethodPost, nil)
+	again, created, err := cs.PutCAS(ctx, "bucket", "objects", strings.NewReader("artifact"))
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, digest, again)
+	require.Empty(t, *calls)
+
+	var buf bytes.Buffer
+	n, err := cs.GetCAS(ctx, "bucket", "objects", digest, &buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), n)
+	require.Equal(t, "artifact", buf.String())
+
+	// zero byte content has a digest like any other
+	digest, created, err = cs.PutCAS(ctx, "bucket", "objects", strings.NewReader(""))
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", digest)
+	buf.Reset()
+	n, err = cs.GetCAS(ctx, "bucket", "objects", digest, &buf)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	for _, digest := range []string{"", "abc", strings.ToUpper(sha256Hex("artifact")), sha256Hex("artifact")[:63] + "g"} {
+		_, err = cs.GetCAS(ctx, "bucket", "objects", digest, &buf)
+		require.Equal(t, ErrInvalidDigest, err, digest)
+	}
+}
+
+func TestPutCASRace(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	digest := sha256Hex("artifact")
+
+	// another writer stores the object between the lookup & the upload
+	race := func(content string) {
+		f.fail = func(r *http.Request) int {
+			if r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/upload/") {
+				f.fail = nil
+				f.put("bucket", "objects/"+digest, []byte(content), nil)
+			}
+			return 0
+		}
+	}
+	race("artifact")
+	got, created, err := cs.PutCAS(ctx, "bucket", "objects", strings.NewReader("artifact"))
+	require.NoError(t, err, "a lost race to the same content is stored content")
+	require.False(t, created)
+	require.Equal(t, digest, got)
+
+	// content stored under the wrong digest is never taken for the uploaded content
+	f.objects = map[string]*fakeObject{}
+	race("tampered")
+	_, _, err = cs.PutCAS(ctx, "bucket", "objects", strings.NewReader("artifact"))
+	require.ErrorIs(t, err, ErrDigestMismatch)
+}
+
+func TestCASDigestMismatch(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	digest := sha256Hex("artifact")
+	f.put("bucket", "objects/"+digest, []byte("tampered"), nil)
+
+	_, _, err := cs.PutCAS(ctx, "bucket", "objects", strings.NewReader("artifact"))
+	require.ErrorIs(t, err, ErrDigestMismatch)
+
+	var buf bytes.Buffer
+	_, err = cs.GetCAS(ctx, "bucket", "objects", digest, &buf)
+	require.ErrorIs(t, err, ErrDigestMismatch)
+	var mismatch DigestMismatchError
+	require.True(t, errors.As(err, &mismatch))
+	require.Equal(t, digest, mismatch.Digest)
+	require.Equal(t, sha256Hex("tampered"), mismatch.Got)
+	require.Equal(t, "objects/"+digest, mismatch.Object)
+}
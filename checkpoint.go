@@ -0,0 +1,222 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"os"
+	"path/filepath"
+
+	"github.com/comfforts/errors"
+)
+
+const (
+	ERROR_CHECKPOINT_MISMATCH string = "checkpoint is for another operation"
+	ERROR_LOADING_CHECKPOINT  string = "error loading checkpoint"
+	ERROR_SAVING_CHECKPOINT   string = "error saving checkpoint"
+)
+
+var (
+	ErrCheckpointMismatch = errors.NewAppError(ERROR_CHECKPOINT_MISMATCH)
+)
+
+// DEFAULT_CHECKPOINT_EVERY is the default number of processed names between checkpoint saves
+const DEFAULT_CHECKPOINT_EVERY = 1000
+
+// Checkpointer persists the progress of a bulk prefix operation, a later run of the same operation
+// with the same checkpointer resumes after the saved cursor. Must be safe for use by one operation at a time.
+type Checkpointer interface {
+	// Save replaces the saved state
+	Save(state []byte) error
+	// Load returns the saved state, nil when nothing was saved
+	Load() ([]byte, error)
+}
+
+// checkpointState is the saved progress of a bulk operation
+type checkpointState struct {
+	Op    string `json:"op"`
+	Scope string `json:"scope"`
+	// Cursor is the last name processed, every name up to it is done
+	Cursor string `json:"cursor"`
+	// Markers are the directory markers listed before the cursor, removed at the end of DeleteObjects
+	Markers []string `json:"markers,omitempty"`
+	// Parents are the objects with content named like a directory listed before the cursor, also removed at the end
+	Parents []string `json:"parents,omitempty"`
+	// Done is set once the operation completed, the next run starts over
+	Done bool `json:"done"`
+}
+
+// checkpoint tracks an operation's progress, saving it every given number of processed names.
+// A nil checkpoint, for operations without checkpointer, does nothing.
+type checkpoint struct {
+	cp      Checkpointer
+	every   int
+	state   checkpointState
+	pending int
+}
+
+// loadCheckpoint returns the checkpoint of given operation & scope, resumed from the saved state
+// unless it's done. Fails with ErrCheckpointMismatch when the state was saved by another operation.
+func loadCheckpoint(cp Checkpointer, every int, op, scope string) (*checkpoint, error) {
+	if cp == nil {
+		return nil, nil
+	}
+	if every <= 0 {
+		every = DEFAULT_CHECKPOINT_EVERY
+	}
+	c := &checkpoint{cp: cp, every: every, state: checkpointState{Op: op, Scope: scope}}
+	data, err := cp.Load()
+	if err != nil {
+		return nil, errors.WrapError(err, ERROR_LOADING_CHECKPOINT)
+	}
+	if len(data) == 0 {
+		return c, nil
+	}
+	var saved checkpointState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, errors.WrapError(err, ERROR_LOADING_CHECKPOINT)
+	}
+	if saved.Op != op || saved.Scope != scope {
+		return nil, errors.WrapError(ErrCheckpointMismatch, "%s %s %s", ERROR_CHECKPOINT_MISMATCH, saved.Op, saved.Scope)
+	}
+	if !saved.Done {
+		c.state = saved
+	}
+	return c, nil
+}
+
+// cursor returns the name to resume after, empty for a fresh run
+func (c *checkpoint) cursor() string {
+	if c == nil {
+		return ""
+	}
+	return c.state.Cursor
+}
+
+// markers returns the directory markers listed by the interrupted run
+func (c *checkpoint) markers() []string {
+	if c == nil {
+		return nil
+	}
+	return c.state.Markers
+}
+
+// parents returns the parent objects listed by the interrupted run
+func (c *checkpoint) parents() []string {
+	if c == nil {
+		return nil
+	}
+	return c.state.Parents
+}
+
+// due counts a processed name, reports whether a save is due
+func (c *checkpoint) due() bool {
+	if c == nil {
+		return false
+	}
+	c.pending++
+	return c.pending >= c.every
+}
+
+// save saves given cursor, markers & parents, every name up to the cursor must be done
+func (c *checkpoint) save(cursor string, markers, parents []string) error {
+	if c == nil {
+		return nil
+	}
+	c.pending = 0
+	c.state.Cursor, c.state.Markers, c.state.Parents = cursor, markers, parents
+	return c.write()
+}
+
+// finish saves the operation done
+func (c *checkpoint) finish() error {
+	if c == nil {
+		return nil
+	}
+	c.state = checkpointState{Op: c.state.Op, Scope: c.state.Scope, Done: true}
+	return c.write()
+}
+
+func (c *checkpoint) write() error {
+	data, err := json.Marshal(c.state)
+	if err != nil {
+		return errors.WrapError(err, ERROR_SAVING_CHECKPOINT)
+	}
+	if err := c.cp.Save(data); err != nil {
+		return errors.WrapError(err, ERROR_SAVING_CHECKPOINT)
+	}
+	return nil
+}
+
+// FileCheckpointer saves checkpoints to a local file, replaced atomically
+type FileCheckpointer struct {
+	Path string
+}
+
+// Save writes state to a temp file next to the checkpoint file & renames it over the checkpoint
+func (f FileCheckpointer) Save(state []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(state); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+// Load reads the checkpoint file, nil when it doesn't exist
+func (f FileCheckpointer) Load() ([]byte, error) {
+	data, err := os.ReadFile(f.Path)
+	if stderrors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// ObjectCheckpointer saves checkpoints to a cloud file, e.g. for pods without persistent volumes
+type ObjectCheckpointer struct {
+	ctx     context.Context
+	storage CloudStorage
+	cfr     CloudFileRequest
+}
+
+// NewObjectCheckpointer returns a checkpointer saving to the cloud file of given request,
+// through given client, with given context
+func NewObjectCheckpointer(ctx context.Context, storage CloudStorage, cfr CloudFileRequest) *ObjectCheckpointer {
+	return &ObjectCheckpointer{ctx: ctx, storage: storage, cfr: cfr}
+}
+
+// Save uploads state over the checkpoint file
+func (o *ObjectCheckpointer) Save(state []byte) error {
+	_, err := o.storage.Upload(o.ctx, bytes.NewReader(state), o.cfr)
+	return err
+}
+
+// Load downloads the checkpoint file, nil when it doesn't exist
+func (o *ObjectCheckpointer) Load() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := o.storage.Download(o.ctx, &buf, o.cfr); err != nil {
+		if stderrors.Is(err, ErrObjectNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WithCheckpointer makes DeleteObjects save its progress with given checkpointer every given number
+// of names, defaults to DEFAULT_CHECKPOINT_EVERY, & resume after the saved cursor.
+// Progress isn't saved past a failed delete, a resumed run retries it.
+func WithCheckpointer(cp Checkpointer, every int) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.checkpointer = cp
+		cfr.checkpointEvery = every
+	}
+}
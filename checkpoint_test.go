@@ -0,0 +1,232 @@
+package cloudstorage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/comfforts/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// memCheckpointer keeps saved checkpoints in memory
+type memCheckpointer struct {
+	mu    sync.Mutex
+	saves [][]byte
+}
+
+func (m *memCheckpointer) Save(state []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saves = append(m.saves, append([]byte{}, state...))
+	return nil
+}
+
+func (m *memCheckpointer) Load() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.saves) == 0 {
+		return nil, nil
+	}
+	return m.saves[len(m.saves)-1], nil
+}
+
+func (m *memCheckpointer) state(t *testing.T) checkpointState {
+	data, err := m.Load()
+	require.NoError(t, err)
+	var state checkpointState
+	require.NoError(t, json.Unmarshal(data, &state))
+	return state
+}
+
+// recordRequests records the paths of requests with given method, calling fn with the count
+func recordRequests(f *fakeGCS, method string, fn func(n int)) *[]string {
+	var mu sync.Mutex
+	paths := []string{}
+	f.fail = func(r *http.Request) int {
+		if r.Method != method {
+			return 0
+		}
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		n := len(paths)
+		mu.Unlock()
+		if fn != nil {
+			fn(n)
+		}
+		return 0
+	}
+	return &paths
+}
+
+func TestFileCheckpointer(t *testing.T) {
+	cp := FileCheckpointer{Path: filepath.Join(t.TempDir(), "delete.ckpt")}
+	state, err := cp.Load()
+	require.NoError(t, err)
+	require.Nil(t, state)
+
+	require.NoError(t, cp.Save([]byte("one")))
+	require.NoError(t, cp.Save([]byte("two")))
+	state, err = cp.Load()
+	require.NoError(t, err)
+	require.Equal(t, "two", string(state))
+	matches, err := filepath.Glob(cp.Path + ".*")
+	require.NoError(t, err)
+	require.Empty(t, matches, "temp files are removed")
+}
+
+func TestObjectCheckpointer(t *testing.T) {
+	cs := newFakeClient(t, newFakeGCS())
+	cp := NewObjectCheckpointer(context.Background(), cs, CloudFileRequest{bucket: "bucket", path: "jobs", file: "delete.ckpt"})
+	state, err := cp.Load()
+	require.NoError(t, err)
+	require.Nil(t, state)
+
+	require.NoError(t, cp.Save([]byte(`{"cursor":"a"}`)))
+	state, err = cp.Load()
+	require.NoError(t, err)
+	require.Equal(t, `{"cursor":"a"}`, string(state))
+}
+
+func TestDeleteObjectsResume(t *testing.T) {
+	f := newFakeGCS()
+	for i := 0; i < 10; i++ {
+		f.put("bucket", fmt.Sprintf("data/%02d.json", i), []byte("{}"), nil)
+	}
+	f.put("bucket", "data/dir/", nil, nil)
+	f.put("bucket", "data/dir/x.json", []byte("{}"), nil)
+	cs := newFakeClient(t, f)
+	cp := &memCheckpointer{}
+
+	// restarted while deleting the 6th object
+	ctx, cancel := context.WithCancel(context.Background())
+	recordRequests(f, http.MethodDelete, func(n int) {
+		if n == 6 {
+			cancel()
+		}
+	})
+	req := CloudFileRequest{bucket: "bucket", path: "data", removeDirMarkers: true}
+	WithCheckpointer(cp, 2)(&req)
+	_, err := cs.DeleteObjectsWithReport(ctx, req)
+	require.Error(t, err)
+	state := cp.state(t)
+	require.False(t, state.Done)
+	require.Equal(t, "data/03.json", state.Cursor)
+
+	deleted := recordRequests(f, http.MethodDelete, nil)
+	report, err := cs.DeleteObjectsWithReport(context.Background(), req)
+	require.NoError(t, err)
+	for _, p := range *deleted {
+		require.NotContains(t, []string{"00", "01", "02", "03"}, strings.TrimSuffix(filepath.Base(p), ".json"), p)
+	}
+	// names deleted after the last save are gone from the listing, the rest & the marker are deleted
+	require.GreaterOrEqual(t, report.Deleted, int64(6))
+	require.Zero(t, report.Failed)
+	f.mu.Lock()
+	require.Empty(t, f.objects)
+	f.mu.Unlock()
+	require.True(t, cp.state(t).Done)
+
+	// a done checkpoint starts a fresh run
+	f.put("bucket", "data/00.json", []byte("{}"), nil)
+	report, err = cs.DeleteObjectsWithReport(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), report.Deleted)
+}
+
+func TestDeleteObjectsCheckpointStopsAtFailure(t *testing.T) {
+	f := newFakeGCS()
+	for i := 0; i < 6; i++ {
+		f.put("bucket", fmt.Sprintf("data/%02d.json", i), []byte("{}"), nil)
+	}
+	denied := true
+	f.fail = func(r *http.Request) int {
+		if denied && r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/03.json") {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	cp := &memCheckpointer{}
+	req := CloudFileRequest{bucket: "bucket", path: "data"}
+	WithCheckpointer(cp, 1)(&req)
+
+	report, err := cs.DeleteObjectsWithReport(context.Background(), req)
+	require.ErrorIs(t, err, ErrPermissionDenied)
+	require.Equal(t, int64(5), report.Deleted)
+	require.Equal(t, "data/02.json", cp.state(t).Cursor)
+
+	// the resumed run retries the failed name, the rest are gone
+	denied = false
+	report, err = cs.DeleteObjectsWithReport(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), report.Deleted)
+	require.True(t, cp.state(t).Done)
+}
+
+func TestReconcileBucketsResume(t *testing.T) {
+	f := newFakeGCS()
+	for i := 0; i < 12; i++ {
+		f.put("bucket", fmt.Sprintf("data/%02d.bin", i), []byte(fmt.Sprint(i)), nil)
+	}
+	cs := newFakeClient(t, f)
+	cp := &memCheckpointer{}
+	src, dst := BucketPrefix{Bucket: "bucket", Prefix: "data"}, BucketPrefix{Bucket: "mirror", Prefix: "data"}
+	opts := ReconcileOptions{Concurrency: 1, Checkpointer: cp, CheckpointEvery: 3}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	recordRequests(f, http.MethodPost, func(n int) {
+		if n == 8 {
+			cancel()
+		}
+	})
+	_, err := cs.ReconcileBuckets(ctx, src, dst, opts)
+	require.Error(t, err)
+	cursor := cp.state(t).Cursor
+	require.Equal(t, "05.bin", cursor)
+
+	copies := recordRequests(f, http.MethodPost, nil)
+	report, err := cs.ReconcileBuckets(context.Background(), src, dst, opts)
+	require.NoError(t, err)
+	for _, a := range report.Actions {
+		require.Greater(t, a.Name, cursor)
+	}
+	for _, p := range *copies {
+		name := strings.SplitN(strings.TrimPrefix(p, "/storage/v1/b/bucket/o/data/"), "/", 2)[0]
+		require.Greater(t, name, cursor, p)
+	}
+	require.True(t, cp.state(t).Done)
+
+	// fresh run, everything in sync
+	recordRequests(f, http.MethodPost, nil)
+	report, err = cs.ReconcileBuckets(context.Background(), src, dst, opts)
+	require.NoError(t, err)
+	require.Empty(t, report.Actions)
+	require.Equal(t, int64(12), report.InSync)
+}
+
+func TestCheckpointMismatch(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "data/a.json", []byte("{}"), nil)
+	cs := newFakeClient(t, f)
+	cp := &memCheckpointer{}
+	req := CloudFileRequest{bucket: "bucket", path: "data"}
+	WithCheckpointer(cp, 1)(&req)
+	require.NoError(t, cp.Save([]byte(`{"op":"DeleteObjects","scope":"bucket/other/","cursor":"other/a.json"}`)))
+
+	_, err := cs.DeleteObjectsWithReport(context.Background(), req)
+	require.Error(t, err)
+	var appErr errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	_, _, ok := f.get("bucket", "data/a.json")
+	require.True(t, ok)
+
+	_, err = cs.ReconcileBuckets(context.Background(), BucketPrefix{Bucket: "bucket"}, BucketPrefix{Bucket: "mirror"}, ReconcileOptions{Checkpointer: cp})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ERROR_RECONCILING_BUCKETS)
+}
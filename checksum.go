@@ -0,0 +1,140 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_VERIFYING_OBJECT string = "error verifying cloud file"
+	ERROR_COMPOSE_CHECKSUM string = "composed object checksum mismatch"
+)
+
+// VerifyResult is the comparison of local content with the stored object
+type VerifyResult struct {
+	// Attrs are the stored object's attributes
+	Attrs *ObjectAttrs
+	// Bytes is the size of the local content
+	Bytes int64
+	// CRC32C is the checksum of the local content
+	CRC32C uint32
+	// MD5Checked is set when the stored object has an MD5 it was compared with,
+	// composed objects have none & are verified by CRC32C only
+	MD5Checked bool
+	// Match is set when the local content's size & checksums match the stored object's
+	Match bool
+}
+
+// VerifyObject compares given local content with the cloud file at request's bucket & filepath,
+// by size & CRC32C, and by MD5 when the stored object has one. Objects with neither checksum,
+// on backends not populating them, fail with ErrNotSupportedByBackend.
+func (cs *cloudStorageClient) VerifyObject(ctx context.Context, cfr CloudFileRequest, content io.Reader) (VerifyResult, error) {
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	if cfr.bucket == "" {
+		return VerifyResult{}, ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return VerifyResult{}, ErrFileNameMissing
+	}
+	op := cs.startOperation(ctx, "VerifyObject", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	stored, err := cs.fetchAttrs(ctx, op, cfr)
+	if err != nil {
+		op.logger.Error(ERROR_GETTING_ATTRS, zap.Error(err), zap.String("filepath", op.object))
+		return VerifyResult{}, op.wrapError(err, "%s %s", ERROR_GETTING_ATTRS, op.object)
+	}
+	attrs := newObjectAttrs(stored)
+	if !attrs.Has(AttrCRC32C) && !attrs.HasMD5 {
+		err := requireAttrs(attrs, AttrCRC32C, "VerifyObject")
+		op.logger.Error(ERROR_VERIFYING_OBJECT, zap.Error(err), zap.String("filepath", op.object))
+		return VerifyResult{}, op.wrapError(err, "%s %s", ERROR_VERIFYING_OBJECT, op.object)
+	}
+
+	crc := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	var sum hash.Hash
+	w := io.Writer(crc)
+	if attrs.HasMD5 {
+		sum = md5.New()
+		w = io.MultiWriter(crc, sum)
+	}
+	n, err := cs.buffers().copy(w, content)
+	if err != nil {
+		op.logger.Error(ERROR_VERIFYING_OBJECT, zap.Error(err), zap.String("filepath", op.object))
+		return VerifyResult{}, op.wrapError(err, "%s %s", ERROR_VERIFYING_OBJECT, op.object)
+	}
+
+	res := VerifyResult{
+		Attrs:      cfr.logicalAttrs(attrs),
+		Bytes:      n,
+		CRC32C:     crc.Sum32(),
+		MD5Checked: attrs.HasMD5,
+	}
+	res.Match = n == attrs.Size && (!attrs.Has(AttrCRC32C) || res.CRC32C == attrs.CRC32C) && (sum == nil || bytes.Equal(sum.Sum(nil), attrs.MD5))
+	if !res.Match {
+		op.logger.Info("cloud file differs from local content", zap.String("filepath", op.object), zap.Uint32("want", attrs.CRC32C), zap.Uint32("got", res.CRC32C), zap.Int64("size", n))
+	}
+	return res, nil
+}
+
+// CombineCRC32C returns the CRC32C of the concatenation of two byte sequences, given the CRC32C of each
+// & the length of the second, the checksum of chunked content or of an object composed of parts
+func CombineCRC32C(crc1, crc2 uint32, len2 int64) uint32 {
+	if len2 <= 0 {
+		return crc1
+	}
+	// zlib's crc32_combine, crc1 is shifted over len2 zero bytes by squaring the one zero bit operator
+	var even, odd [32]uint32
+	odd[0] = crc32.Castagnoli
+	row := uint32(1)
+	for n := 1; n < 32; n++ {
+		odd[n] = row
+		row <<= 1
+	}
+	// two, then four zero bits
+	gf2MatrixSquare(&even, &odd)
+	gf2MatrixSquare(&odd, &even)
+	for {
+		gf2MatrixSquare(&even, &odd)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&even, crc1)
+		}
+		if len2 >>= 1; len2 == 0 {
+			break
+		}
+		gf2MatrixSquare(&odd, &even)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&odd, crc1)
+		}
+		if len2 >>= 1; len2 == 0 {
+			break
+		}
+	}
+	return crc1 ^ crc2
+}
+
+func gf2MatrixTimes(mat *[32]uint32, vec uint32) uint32 {
+	var sum uint32
+	for i := 0; vec != 0; i, vec = i+1, vec>>1 {
+		if vec&1 != 0 {
+			sum ^= mat[i]
+		}
+	}
+	return sum
+}
+
+func gf2MatrixSquare(square, mat *[32]uint32) {
+	for n := range mat {
+		square[n] = gf2MatrixTimes(mat, mat[n])
+	}
+}
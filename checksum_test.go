@@ -0,0 +1,92 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"hash/crc32"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCombineCRC32C(t *testing.T) {
+	table := crc32.MakeTable(crc32.Castagnoli)
+	content := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(content)
+	for _, split := range []int{0, 1, 7, 4096, 65536, 99999, 100000} {
+		a, b := content[:split], content[split:]
+		combined := CombineCRC32C(crc32.Checksum(a, table), crc32.Checksum(b, table), int64(len(b)))
+		require.Equal(t, crc32.Checksum(content, table), combined, "split at %d", split)
+	}
+}
+
+func TestVerifyComposedObject(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "composed.bin", "path", 0)
+	require.NoError(t, err)
+
+	// 5 parts, the last one short
+	content := make([]byte, 4*256*1024+1000)
+	rand.New(rand.NewSource(2)).Read(content)
+	res, err := cs.UploadFromReaderAt(ctx, bytes.NewReader(content), int64(len(content)), cfr, WithChunkSize(256*1024), WithParallelUpload(1, 3))
+	require.NoError(t, err)
+	require.False(t, res.Attrs.HasMD5)
+	require.Empty(t, res.Attrs.MD5)
+	_, stored, _ := f.get("bucket", "path/composed.bin")
+	require.Equal(t, int64(5), stored.ComponentCount)
+	require.Equal(t, crc32.Checksum(content, crc32.MakeTable(crc32.Castagnoli)), res.Attrs.CRC32C)
+
+	// downloads verify by CRC32C
+	var buf bytes.Buffer
+	dl, err := cs.Download(ctx, &buf, cfr)
+	require.NoError(t, err)
+	require.True(t, dl.Verified)
+	require.Equal(t, content, buf.Bytes())
+
+	// the missing MD5 isn't a mismatch
+	v, err := cs.VerifyObject(ctx, cfr, bytes.NewReader(content))
+	require.NoError(t, err)
+	require.True(t, v.Match)
+	require.False(t, v.MD5Checked)
+	require.Equal(t, int64(len(content)), v.Bytes)
+
+	changed := append([]byte{}, content...)
+	changed[300000] ^= 1
+	v, err = cs.VerifyObject(ctx, cfr, bytes.NewReader(changed))
+	require.NoError(t, err)
+	require.False(t, v.Match)
+}
+
+func TestVerifyObject(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "a.txt", "path", 0)
+	require.NoError(t, err)
+	res, err := cs.Upload(ctx, strings.NewReader("verify me"), cfr)
+	require.NoError(t, err)
+	require.True(t, res.Attrs.HasMD5)
+
+	v, err := cs.VerifyObject(ctx, cfr, strings.NewReader("verify me"))
+	require.NoError(t, err)
+	require.True(t, v.Match)
+	require.True(t, v.MD5Checked)
+	require.Equal(t, res.Attrs.Generation, v.Attrs.Generation)
+
+	for _, content := range []string{"verify m", "verify me!", "verify mE"} {
+		v, err = cs.VerifyObject(ctx, cfr, strings.NewReader(content))
+		require.NoError(t, err)
+		require.False(t, v.Match, content)
+	}
+
+	missing, err := NewCloudFileRequest("bucket", "missing.txt", "path", 0)
+	require.NoError(t, err)
+	_, err = cs.VerifyObject(ctx, missing, strings.NewReader(""))
+	require.ErrorIs(t, err, ErrObjectNotFound)
+	_, err = cs.VerifyObject(ctx, CloudFileRequest{bucket: "bucket"}, strings.NewReader(""))
+	require.Equal(t, ErrFileNameMissing, err)
+}
@@ -0,0 +1,324 @@
+package cloudstorage
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/comfforts/errors"
+)
+
+const ERROR_CIRCUIT_OPEN string = "storage circuit open"
+
+var (
+	ErrCircuitOpen = errors.NewAppError(ERROR_CIRCUIT_OPEN)
+)
+
+const (
+	// DEFAULT_CIRCUIT_FAILURE_RATE is the failure rate of a class's requests opening its circuit
+	DEFAULT_CIRCUIT_FAILURE_RATE = 0.5
+	// DEFAULT_CIRCUIT_MIN_REQUESTS is the number of requests in the window before the failure rate counts
+	DEFAULT_CIRCUIT_MIN_REQUESTS = 20
+	// DEFAULT_CIRCUIT_WINDOW is the rolling window failure rates are measured over
+	DEFAULT_CIRCUIT_WINDOW = 30 * time.Second
+	// DEFAULT_CIRCUIT_OPEN_DURATION is how long an open circuit fails requests before probing
+	DEFAULT_CIRCUIT_OPEN_DURATION = 15 * time.Second
+	// DEFAULT_CIRCUIT_PROBES is the number of concurrent probe requests of a half open circuit
+	DEFAULT_CIRCUIT_PROBES = 1
+	// circuitWindowBuckets is the number of buckets the rolling window is counted in
+	circuitWindowBuckets = 10
+)
+
+// CircuitClass is the class of requests sharing a circuit
+type CircuitClass string
+
+const (
+	CircuitRead  CircuitClass = "read"
+	CircuitWrite CircuitClass = "write"
+	CircuitList  CircuitClass = "list"
+)
+
+// CircuitState is the state of a circuit
+type CircuitState string
+
+const (
+	// CircuitClosed passes requests
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen fails requests without sending them
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen passes probe requests, closing the circuit on success & opening it on failure
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitBreakerConfig configures the client's circuit breaker, every field defaults when zero
+type CircuitBreakerConfig struct {
+	// FailureRate is the fraction of failed requests in the window opening a class's circuit
+	FailureRate float64 `json:"failure_rate"`
+	// MinRequests is the number of requests in the window before the failure rate counts
+	MinRequests int `json:"min_requests"`
+	// Window is the rolling window failure rates are measured over
+	Window time.Duration `json:"window"`
+	// OpenDuration is how long an open circuit fails requests before it half opens to probe
+	OpenDuration time.Duration `json:"open_duration"`
+	// Probes is the number of concurrent probe requests of a half open circuit
+	Probes int `json:"probes"`
+	// OnStateChange is called on every circuit state transition, optional, must not block
+	OnStateChange func(CircuitStateChange) `json:"-"`
+}
+
+// CircuitStateChange describes a circuit state transition
+type CircuitStateChange struct {
+	Class CircuitClass
+	From  CircuitState
+	To    CircuitState
+	At    time.Time
+	// FailureRate is the window's failure rate when the circuit opened, zero for other transitions
+	FailureRate float64
+}
+
+// CircuitRecorder receives circuit state transitions, implemented by metrics recorders watching service health
+type CircuitRecorder interface {
+	RecordCircuit(CircuitStateChange)
+}
+
+// CircuitOpenError is returned for requests failed fast by an open circuit,
+// matches ErrCircuitOpen with errors.Is
+type CircuitOpenError struct {
+	Class CircuitClass
+	// RetryIn is the time until the circuit probes again, zero while a probe is in flight
+	RetryIn time.Duration
+}
+
+func (e CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s for %s requests, next probe in %s", ERROR_CIRCUIT_OPEN, e.Class, e.RetryIn)
+}
+
+// Is matches ErrCircuitOpen
+func (e CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// requestClass returns the circuit class of given service request
+func requestClass(r *http.Request) CircuitClass {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		// object & bucket listings
+		if p := strings.TrimSuffix(r.URL.Path, "/"); strings.HasSuffix(p, "/o") || strings.HasSuffix(p, "/b") {
+			return CircuitList
+		}
+		return CircuitRead
+	default:
+		return CircuitWrite
+	}
+}
+
+// circuitFailure reports whether a request's outcome counts against its circuit, only transport failures,
+// timeouts & server errors do, caller caused failures, e.g. invalid requests, missing objects,
+// denied access, throttling or canceled contexts, don't
+func circuitFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !stderrors.Is(err, context.Canceled) && !stderrors.Is(err, ErrCircuitOpen)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// circuitBucket counts the requests of a slice of the rolling window
+type circuitBucket struct {
+	start  time.Time
+	ok     int
+	failed int
+}
+
+// circuit is the state of one request class
+type circuit struct {
+	state    CircuitState
+	buckets  [circuitWindowBuckets]circuitBucket
+	openedAt time.Time
+	probes   int
+}
+
+// circuitBreaker fails requests fast while a class's recent failure rate is over the threshold
+type circuitBreaker struct {
+	config   CircuitBreakerConfig
+	now      func() time.Time
+	recorder CircuitRecorder
+
+	mu       sync.Mutex
+	circuits map[CircuitClass]*circuit
+}
+
+// newCircuitBreaker returns a breaker of given config, defaulted
+func newCircuitBreaker(cfg CircuitBreakerConfig, now func() time.Time, recorder CircuitRecorder) *circuitBreaker {
+	if cfg.FailureRate <= 0 {
+		cfg.FailureRate = DEFAULT_CIRCUIT_FAILURE_RATE
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = DEFAULT_CIRCUIT_MIN_REQUESTS
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DEFAULT_CIRCUIT_WINDOW
+	}
+	if cfg.OpenDuration <= 0 {
+		cfg.OpenDuration = DEFAULT_CIRCUIT_OPEN_DURATION
+	}
+	if cfg.Probes <= 0 {
+		cfg.Probes = DEFAULT_CIRCUIT_PROBES
+	}
+	return &circuitBreaker{config: cfg, now: now, recorder: recorder, circuits: map[CircuitClass]*circuit{}}
+}
+
+// circuit returns given class's circuit, under the lock
+func (b *circuitBreaker) circuit(class CircuitClass) *circuit {
+	c, ok := b.circuits[class]
+	if !ok {
+		c = &circuit{state: CircuitClosed}
+		b.circuits[class] = c
+	}
+	return c
+}
+
+// state returns the state of given class's circuit
+func (b *circuitBreaker) state(class CircuitClass) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.circuit(class).state
+}
+
+// allow admits a request of given class, returns whether it's a half open circuit's probe,
+// fails with CircuitOpenError while the circuit is open or its probes are in flight
+func (b *circuitBreaker) allow(class CircuitClass) (bool, error) {
+	b.mu.Lock()
+	c := b.circuit(class)
+	var changes []CircuitStateChange
+	defer func() {
+		b.mu.Unlock()
+		b.notify(changes)
+	}()
+
+	now := b.now()
+	switch c.state {
+	case CircuitOpen:
+		if wait := c.openedAt.Add(b.config.OpenDuration).Sub(now); wait > 0 {
+			return false, CircuitOpenError{Class: class, RetryIn: wait}
+		}
+		changes = append(changes, b.transition(class, c, CircuitHalfOpen, now, 0))
+		fallthrough
+	case CircuitHalfOpen:
+		if c.probes >= b.config.Probes {
+			return false, CircuitOpenError{Class: class}
+		}
+		c.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// record counts the outcome of an admitted request, opening the circuit once the window's failure rate
+// crosses the threshold, probes close or reopen a half open circuit
+func (b *circuitBreaker) record(class CircuitClass, probe, failed bool) {
+	b.mu.Lock()
+	c := b.circuit(class)
+	var changes []CircuitStateChange
+	defer func() {
+		b.mu.Unlock()
+		b.notify(changes)
+	}()
+
+	now := b.now()
+	if probe {
+		c.probes--
+		if c.state != CircuitHalfOpen {
+			return
+		}
+		if failed {
+			c.openedAt = now
+			changes = append(changes, b.transition(class, c, CircuitOpen, now, 0))
+		} else {
+			c.buckets = [circuitWindowBuckets]circuitBucket{}
+			changes = append(changes, b.transition(class, c, CircuitClosed, now, 0))
+		}
+		return
+	}
+	if c.state != CircuitClosed {
+		// requests admitted before the circuit opened
+		return
+	}
+
+	width := b.config.Window / circuitWindowBuckets
+	start := now.Truncate(width)
+	bucket := &c.buckets[(start.UnixNano()/int64(width))%circuitWindowBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = circuitBucket{start: start}
+	}
+	if !failed {
+		bucket.ok++
+		return
+	}
+	bucket.failed++
+
+	var ok, failures int
+	for _, bk := range c.buckets {
+		if now.Sub(bk.start) < b.config.Window {
+			ok, failures = ok+bk.ok, failures+bk.failed
+		}
+	}
+	total := ok + failures
+	if rate := float64(failures) / float64(total); total >= b.config.MinRequests && rate >= b.config.FailureRate {
+		c.openedAt = now
+		changes = append(changes, b.transition(class, c, CircuitOpen, now, rate))
+	}
+}
+
+// transition moves the circuit to given state, under the lock
+func (b *circuitBreaker) transition(class CircuitClass, c *circuit, to CircuitState, at time.Time, rate float64) CircuitStateChange {
+	change := CircuitStateChange{Class: class, From: c.state, To: to, At: at, FailureRate: rate}
+	c.state = to
+	return change
+}
+
+// notify reports given transitions to the callback & metrics recorder, outside the lock
+func (b *circuitBreaker) notify(changes []CircuitStateChange) {
+	for _, change := range changes {
+		if b.config.OnStateChange != nil {
+			b.config.OnStateChange(change)
+		}
+		if b.recorder != nil {
+			b.recorder.RecordCircuit(change)
+		}
+	}
+}
+
+// breakerTransport sends requests through the circuit of their class
+type breakerTransport struct {
+	base    http.RoundTripper
+	breaker *circuitBreaker
+}
+
+func (t *breakerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	class := requestClass(r)
+	probe, err := t.breaker.allow(class)
+	if err != nil {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(r)
+	t.breaker.record(class, probe, circuitFailure(resp, err))
+	return resp, err
+}
+
+// withBreaker returns a copy of given HTTP client sending requests through the breaker
+func withBreaker(hc *http.Client, b *circuitBreaker) *http.Client {
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped := *hc
+	wrapped.Transport = &breakerTransport{base: base, breaker: b}
+	return &wrapped
+}
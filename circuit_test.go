@@ -0,0 +1,219 @@
+package cloudstorage
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+// circuitChanges records circuit transitions
+type circuitChanges struct {
+	mu      sync.Mutex
+	changes []CircuitStateChange
+}
+
+func (c *circuitChanges) RecordCircuit(change CircuitStateChange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes = append(c.changes, change)
+}
+
+func (c *circuitChanges) states() []CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	states := []CircuitState{}
+	for _, change := range c.changes {
+		states = append(states, change.To)
+	}
+	return states
+}
+
+// newBreakerClient returns a fake backed client sending requests through a circuit breaker, without retries
+func newBreakerClient(t *testing.T, h http.Handler, cfg CircuitBreakerConfig, clock *fakeClock) (*cloudStorageClient, *circuitBreaker) {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	breaker := newCircuitBreaker(cfg, clock.Now, nil)
+	client, err := storage.NewClient(
+		context.Background(),
+		option.WithEndpoint(srv.URL+"/storage/v1/"),
+		option.WithHTTPClient(withBreaker(srv.Client(), breaker)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	cs := newFakeClient(t, h)
+	cs.client = client
+	cs.config.Retry = &RetryPolicy{MaxAttempts: 1}
+	WithClock(clock)(cs)
+	return cs, breaker
+}
+
+func TestCircuitFailureClassification(t *testing.T) {
+	status := func(code int) *http.Response {
+		return &http.Response{StatusCode: code}
+	}
+	// caller caused outcomes never count
+	for _, code := range []int{http.StatusOK, http.StatusNotModified, http.StatusBadRequest, http.StatusUnauthorized,
+		http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusRequestedRangeNotSatisfiable, http.StatusTooManyRequests} {
+		require.False(t, circuitFailure(status(code), nil), code)
+	}
+	require.False(t, circuitFailure(nil, context.Canceled))
+	require.False(t, circuitFailure(nil, CircuitOpenError{Class: CircuitRead}))
+
+	// server errors, timeouts & transport failures do
+	for _, code := range []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		require.True(t, circuitFailure(status(code), nil), code)
+	}
+	require.True(t, circuitFailure(nil, context.DeadlineExceeded))
+	require.True(t, circuitFailure(nil, &net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	require.True(t, circuitFailure(nil, errors.New("unexpected EOF")))
+}
+
+func TestRequestClass(t *testing.T) {
+	classes := map[string]CircuitClass{
+		"GET /storage/v1/b/bucket/o":                CircuitList,
+		"GET /storage/v1/b":                         CircuitList,
+		"GET /storage/v1/b/bucket/o/path%2Ffile":    CircuitRead,
+		"GET /bucket/path/file":                     CircuitRead,
+		"HEAD /storage/v1/b/bucket":                 CircuitRead,
+		"POST /upload/storage/v1/b/bucket/o":        CircuitWrite,
+		"DELETE /storage/v1/b/bucket/o/path%2Ffile": CircuitWrite,
+		"PATCH /storage/v1/b/bucket":                CircuitWrite,
+	}
+	for req, class := range classes {
+		parts := strings.SplitN(req, " ", 2)
+		r := httptest.NewRequest(parts[0], parts[1], nil)
+		require.Equal(t, class, requestClass(r), req)
+	}
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	clock := newFakeClock()
+	recorder := &circuitChanges{}
+	callbacks := []CircuitStateChange{}
+	b := newCircuitBreaker(CircuitBreakerConfig{
+		FailureRate: 0.5, MinRequests: 4, Window: 10 * time.Second, OpenDuration: 5 * time.Second,
+		OnStateChange: func(change CircuitStateChange) { callbacks = append(callbacks, change) },
+	}, clock.Now, recorder)
+	send := func(class CircuitClass, failed bool) error {
+		probe, err := b.allow(class)
+		if err == nil {
+			b.record(class, probe, failed)
+		}
+		return err
+	}
+
+	// failures under the minimum requests or the rate keep the circuit closed
+	require.NoError(t, send(CircuitRead, true))
+	require.NoError(t, send(CircuitRead, true))
+	require.NoError(t, send(CircuitRead, false))
+	require.Equal(t, CircuitClosed, b.state(CircuitRead))
+	// failures older than the window don't count
+	clock.Advance(11 * time.Second)
+	require.NoError(t, send(CircuitRead, false))
+	require.NoError(t, send(CircuitRead, true))
+	require.Equal(t, CircuitClosed, b.state(CircuitRead))
+	require.NoError(t, send(CircuitRead, false))
+	require.NoError(t, send(CircuitRead, true))
+	require.Equal(t, CircuitOpen, b.state(CircuitRead), "2 of 4 failed")
+
+	// open circuits fail fast with the time until the next probe, other classes pass
+	clock.Advance(2 * time.Second)
+	err := send(CircuitRead, false)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	var open CircuitOpenError
+	require.True(t, errors.As(err, &open))
+	require.Equal(t, CircuitOpenError{Class: CircuitRead, RetryIn: 3 * time.Second}, open)
+	require.NoError(t, send(CircuitWrite, false))
+
+	// a failed probe reopens the circuit
+	clock.Advance(3 * time.Second)
+	probe, err := b.allow(CircuitRead)
+	require.NoError(t, err)
+	require.True(t, probe)
+	require.Equal(t, CircuitHalfOpen, b.state(CircuitRead))
+	_, err = b.allow(CircuitRead)
+	require.Equal(t, CircuitOpenError{Class: CircuitRead}, err, "one probe at a time")
+	b.record(CircuitRead, true, true)
+	require.Equal(t, CircuitOpen, b.state(CircuitRead))
+	require.ErrorIs(t, send(CircuitRead, false), ErrCircuitOpen)
+
+	// a succeeded probe closes it, with a fresh window
+	clock.Advance(5 * time.Second)
+	require.NoError(t, send(CircuitRead, false))
+	require.Equal(t, CircuitClosed, b.state(CircuitRead))
+	require.NoError(t, send(CircuitRead, true))
+	require.Equal(t, CircuitClosed, b.state(CircuitRead))
+
+	require.Equal(t, []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed}, recorder.states())
+	require.Equal(t, recorder.changes, callbacks)
+	require.Equal(t, CircuitStateChange{Class: CircuitRead, From: CircuitClosed, To: CircuitOpen, At: callbacks[0].At, FailureRate: 0.5}, callbacks[0])
+}
+
+func TestCircuitBreakerClient(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/file.json", []byte("{}"), nil)
+	var mu sync.Mutex
+	outage, calls := true, 0
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		down := outage
+		mu.Unlock()
+		if down {
+			writeAPIError(w, http.StatusServiceUnavailable, "backend unavailable")
+			return
+		}
+		f.ServeHTTP(w, r)
+	})
+	clock := newFakeClock()
+	cs, breaker := newBreakerClient(t, h, CircuitBreakerConfig{MinRequests: 3, OpenDuration: time.Minute}, clock)
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+	missing, err := NewCloudFileRequest("bucket", "missing.json", "path", 0)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = cs.GetAttrs(ctx, cfr)
+		require.Error(t, err)
+		require.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+	require.Equal(t, CircuitOpen, breaker.state(CircuitRead))
+
+	// requests fail fast without reaching the service
+	_, err = cs.GetAttrs(ctx, cfr)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	var open CircuitOpenError
+	require.True(t, errors.As(err, &open))
+	require.Equal(t, time.Minute, open.RetryIn)
+	mu.Lock()
+	require.Equal(t, 3, calls)
+	mu.Unlock()
+
+	// the probe closes the circuit once the service recovered
+	mu.Lock()
+	outage = false
+	mu.Unlock()
+	clock.Advance(time.Minute)
+	_, err = cs.GetAttrs(ctx, cfr)
+	require.NoError(t, err)
+	require.Equal(t, CircuitClosed, breaker.state(CircuitRead))
+
+	// missing objects are the caller's, they never trip the circuit
+	for i := 0; i < 5; i++ {
+		_, err = cs.GetAttrs(ctx, missing)
+		require.ErrorIs(t, err, ErrObjectNotFound)
+	}
+	require.Equal(t, CircuitClosed, breaker.state(CircuitRead))
+}
@@ -0,0 +1,58 @@
+package cloudstorage
+
+import (
+	"time"
+)
+
+// Clock is the client's time source, used for every time comparison the client makes
+type Clock interface {
+	Now() time.Time
+}
+
+// TimerClock is a Clock scheduling calls, the client's first byte deadlines are timed with it when its clock
+// implements it, with wall clock timers otherwise
+type TimerClock interface {
+	Clock
+	// AfterFunc calls f in its own goroutine once d elapsed on the clock, unless stop is called first,
+	// stop reports whether it prevented the call
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+// realClock is the wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// ClientOption sets client options not carried by the config
+type ClientOption func(cs *cloudStorageClient)
+
+// WithClock sets the client's time source, defaults to the wall clock
+func WithClock(clock Clock) ClientOption {
+	return func(cs *cloudStorageClient) {
+		cs.clock = clock
+	}
+}
+
+// now returns the client clock's current time
+func (cs *cloudStorageClient) now() time.Time {
+	if cs == nil || cs.clock == nil {
+		return time.Now()
+	}
+	return cs.clock.Now()
+}
+
+// since returns the client clock's time elapsed since given time
+func (cs *cloudStorageClient) since(t time.Time) time.Duration {
+	return cs.now().Sub(t)
+}
+
+// afterFunc calls f once d elapsed on the client clock when it's a TimerClock, on the wall clock otherwise,
+// unless the returned stop func is called first
+func (cs *cloudStorageClient) afterFunc(d time.Duration, f func()) (stop func() bool) {
+	if tc, ok := cs.clock.(TimerClock); ok {
+		return tc.AfterFunc(d, f)
+	}
+	return time.AfterFunc(d, f).Stop
+}
@@ -0,0 +1,131 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manually advanced clock, safe for concurrent use, its timers fire when advanced past
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// fakeTimer is a call scheduled on a fake clock
+type fakeTimer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.stopped = true
+		go t.f()
+	}
+	c.timers = pending
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if t.stopped {
+			return false
+		}
+		t.stopped = true
+		return true
+	}
+}
+
+// pendingTimers returns the number of timers neither fired nor stopped
+func (c *fakeClock) pendingTimers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, t := range c.timers {
+		if !t.stopped {
+			n++
+		}
+	}
+	return n
+}
+
+func TestClientClock(t *testing.T) {
+	clock := newFakeClock()
+	cs := &cloudStorageClient{}
+	WithClock(clock)(cs)
+	require.Equal(t, clock.Now(), cs.now())
+	clock.Advance(time.Minute)
+	require.Equal(t, time.Minute, cs.since(clock.Now().Add(-time.Minute)))
+
+	// clients without a clock use the wall clock
+	before := time.Now()
+	require.False(t, (&cloudStorageClient{}).now().Before(before))
+}
+
+func TestClockDrivesTimings(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 3000)
+	clock := newFakeClock()
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", content, nil)
+	f.fail = func(r *http.Request) int {
+		// content reads take 3s on the client clock
+		if r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/storage/v1/") {
+			clock.Advance(3 * time.Second)
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	WithClock(clock)(cs)
+	rl := cs.logger.(*recordingLogger)
+	cs.config.SlowOpThreshold = 2 * time.Second
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	res, err := cs.Download(context.Background(), &buf, cfr)
+	require.NoError(t, err)
+	require.Equal(t, 3*time.Second, res.Duration)
+	require.Equal(t, 3*time.Second, res.TimeToFirstByte)
+	require.Equal(t, float64(1000), res.BytesPerSecond)
+
+	var slow []logEntry
+	for _, e := range rl.all() {
+		if e.msg == "slow storage operation" {
+			slow = append(slow, e)
+		}
+	}
+	require.Len(t, slow, 1)
+	require.Equal(t, 3*time.Second, slow[0].fields["elapsed"])
+}
@@ -2,34 +2,285 @@ package cloudstorage
 
 import (
 	"context"
-	"fmt"
-	"io" 
+	"encoding/json"
+	stderrors "errors"
+	"hash/crc32"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
-	"cloud.google.com/go/storage" 
+	"cloud.google.com/go/storage"
 	"github.com/comfforts/errors"
 	"github.com/comfforts/logger"
 	"go.uber.org/zap"
 	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 )
 
-type CloudStorage interface {
-	// UploadFile uploads file to given cloud bucket & filepath, creates a new one or replaces existing
+//go:generate moq -out cloudstoragemock/cloudstorage.go -pkg cloudstoragemock -stub . CloudStorage Uploader Downloader Lister Deleter
+
+// Uploader uploads objects
+type Uploader interface {
+	// UploadFile uploads file to given cloud bucket & filepath, creates a new one or replaces existing,
+	// returns the bytes sent, n > 0 with an error is a partial transfer that wasn't committed
 	UploadFile(context.Context, io.Reader, CloudFileRequest) (int64, error)
-	// DownloadFile copies content of file at given cloud bucket & filepath to given file
+	// Upload uploads file like UploadFile, returns upload result.
+	// A context cancelled before the commit fails with ctx.Err() & leaves no object, an object committed anyway is removed
+	Upload(context.Context, io.Reader, CloudFileRequest) (UploadResult, error)
+	// UploadBytes uploads given content like Upload, in a single request with the content's CRC32C
+	UploadBytes(ctx context.Context, data []byte, cfr CloudFileRequest) (UploadResult, error)
+	// UploadFromReaderAt uploads size bytes of given reader at like Upload, large content in parallel composed parts
+	UploadFromReaderAt(ctx context.Context, r io.ReaderAt, size int64, cfr CloudFileRequest, opts ...UploadOption) (UploadResult, error)
+	// UploadFromFile uploads local file at given path with UploadFromReaderAt
+	UploadFromFile(ctx context.Context, filePath string, cfr CloudFileRequest, opts ...UploadOption) (UploadResult, error)
+	// UploadFanOut uploads file once to primary & replica destinations, returns per destination results
+	UploadFanOut(ctx context.Context, r io.Reader, primary CloudFileRequest, replicas []CloudFileRequest, opts ...FanOutOption) (FanOutResult, error)
+	// PublishSet uploads data objects, then the manifest once all succeeded, returns every object's generation
+	PublishSet(ctx context.Context, items []UploadItem, manifest UploadItem, opts ...PublishOption) (PublishReport, error)
+	// StagedUpload uploads content to a staging object, validates it & promotes it to the final request's object,
+	// rejected content is removed & fails with ErrValidationFailed
+	StagedUpload(ctx context.Context, r io.Reader, final CloudFileRequest, validate StagedValidator, opts ...StagingOption) (UploadResult, error)
+	// UploadUnique uploads content under a new collision resistant, time ordered name in given bucket & prefix,
+	// returns the final name
+	UploadUnique(ctx context.Context, r io.Reader, bucket, prefix, originalName string, opts ...CloudFileRequestOption) (UniqueUploadResult, error)
+}
+
+// Downloader reads object content
+type Downloader interface {
+	// DownloadFile copies content of file at given cloud bucket & filepath to given file,
+	// returns the bytes written, n > 0 with an error is a partial transfer
 	DownloadFile(context.Context, io.Writer, CloudFileRequest) (int64, error)
+	// Download copies file content like DownloadFile, returns download result
+	Download(context.Context, io.Writer, CloudFileRequest) (DownloadResult, error)
+	// DownloadBytes returns file content like Download, allocated once, objects over maxSize fail with ErrObjectTooLarge
+	DownloadBytes(ctx context.Context, cfr CloudFileRequest, maxSize int64) ([]byte, error)
+	// DownloadToWriterAt downloads file into given writer at with concurrent range reads, verified by combined CRC32C,
+	// chunks optionally sized by measured throughput
+	DownloadToWriterAt(ctx context.Context, w io.WriterAt, cfr CloudFileRequest, opts ...DownloadOption) (DownloadResult, error)
+	// DownloadHead copies the first n stored bytes of the cloud file, the whole file when smaller
+	DownloadHead(ctx context.Context, cfr CloudFileRequest, n int64, w io.Writer) (int64, error)
+	// DownloadTail copies the last n stored bytes of the cloud file, the whole file when smaller
+	DownloadTail(ctx context.Context, cfr CloudFileRequest, n int64, w io.Writer) (int64, error)
 	// Reads file data of givine length at given offset
 	ReadAt(ctx context.Context, cfr CloudFileRequest, p []byte, off int64) (int, error)
-	// ListObjects lists objects at given cloud bucket
+	// VerifyObject compares local content with file at given cloud bucket & filepath by size & CRC32C,
+	// and by MD5 when the stored object has one
+	VerifyObject(ctx context.Context, cfr CloudFileRequest, content io.Reader) (VerifyResult, error)
+	// OpenReader returns a live reader of file at given cloud bucket & filepath, must be closed
+	OpenReader(ctx context.Context, cfr CloudFileRequest) (*ObjectReader, error)
+	// OpenRangeReader returns a live reader of length bytes from given offset of the file, to the end when length is -1
+	OpenRangeReader(ctx context.Context, cfr CloudFileRequest, offset, length int64) (*ObjectReader, error)
+	// NewReaderAt returns a reader at over file at given cloud bucket & filepath, with optional read-ahead, must be closed
+	NewReaderAt(ctx context.Context, cfr CloudFileRequest, opts ...ReaderAtOption) (*ObjectReaderAt, error)
+}
+
+// RecordStore reads & writes JSON, NDJSON & CSV encoded objects
+type RecordStore interface {
+	// ReadJSON decodes JSON content of file at given cloud bucket & filepath into v
+	ReadJSON(ctx context.Context, cfr CloudFileRequest, v interface{}) error
+	// ReadNDJSON streams line delimited JSON records of file at given cloud bucket & filepath through fn
+	ReadNDJSON(ctx context.Context, cfr CloudFileRequest, fn func(json.RawMessage) error, opts ...JSONOption) error
+	// WriteJSON uploads v as JSON to given cloud bucket & filepath
+	WriteJSON(ctx context.Context, cfr CloudFileRequest, v interface{}, opts ...JSONOption) (UploadResult, error)
+	// WriteNDJSON uploads records as line delimited JSON to given cloud bucket & filepath
+	WriteNDJSON(ctx context.Context, cfr CloudFileRequest, next func() (interface{}, bool), opts ...JSONOption) (UploadResult, error)
+	// ReadCSV streams CSV rows of file at given cloud bucket & filepath through fn
+	ReadCSV(ctx context.Context, cfr CloudFileRequest, fn func(header []string, record []string) error, opts ...CSVOption) error
+	// WriteCSV uploads header & rows as CSV to given cloud bucket & filepath
+	WriteCSV(ctx context.Context, cfr CloudFileRequest, header []string, rows func() ([]string, bool), opts ...CSVOption) (UploadResult, error)
+}
+
+// Lister lists & finds objects
+type Lister interface {
+	// ListObjects lists objects at given cloud bucket, returns no names on error,
+	// names listed before a failure are carried by PartialListError,
+	// callers without list permission get ErrPermissionDenied
 	ListObjects(context.Context, CloudFileRequest) ([]string, error)
-	// DeleteObject delete file at given cloud bucket & filepath
-	DeleteObject(context.Context, CloudFileRequest) error
-	// DeleteObjects delete files at given cloud bucket
-	DeleteObjects(context.Context, CloudFileRequest) error
-	// Close closes storage client connections
+	// ListObjectsInfo lists attributes of objects under request path
+	ListObjectsInfo(context.Context, CloudFileRequest) ([]*ObjectAttrs, error)
+	// ListDir lists files & sub directories directly under request path
+	ListDir(context.Context, CloudFileRequest) (DirListing, error)
+	// ListTree lists the prefix hierarchy under given prefix breadth first down to maxDepth, with per prefix counts
+	ListTree(ctx context.Context, bucket, prefix string, maxDepth int, opts ...TreeOption) (*PrefixTree, error)
+	// WalkObjects streams the attributes of objects under request path to given func, in concurrent name range shards
+	WalkObjects(ctx context.Context, cfr CloudFileRequest, fn WalkFunc, opts ...WalkOption) (int64, error)
+	// ExportInventory streams the attributes of objects under request path to given writer, returns row count
+	ExportInventory(ctx context.Context, cfr CloudFileRequest, w io.Writer, format InventoryFormat) (int64, error)
+	// FindObjectsByTag returns attributes of objects under prefix with given tag value
+	FindObjectsByTag(ctx context.Context, bucket, prefix, key, value string) ([]*ObjectAttrs, error)
+	// FindStrayObjects returns the bucket's objects stored under names that requests, normalized, can't reach
+	FindStrayObjects(ctx context.Context, bucket string) ([]StrayObject, error)
+}
+
+// Deleter deletes objects
+type Deleter interface {
+	// DeleteObject delete file at given cloud bucket & filepath, with given request options,
+	// e.g. WithIfGenerationMatch, WithDeleteDryRun, WithDeleteToTrash, WithUserProject
+	DeleteObject(ctx context.Context, req CloudFileRequest, opts ...CloudFileRequestOption) error
+	// DeleteObjects delete files at given cloud bucket, under request path when set,
+	// directory markers are kept unless requested with WithRemoveDirMarkers
+	DeleteObjects(ctx context.Context, req CloudFileRequest, opts ...CloudFileRequestOption) error
+	// DeleteObjectsWithReport deletes files like DeleteObjects, returns deleted, skipped & failed counts & bytes freed
+	DeleteObjectsWithReport(ctx context.Context, req CloudFileRequest, opts ...CloudFileRequestOption) (DeleteReport, error)
+	// CleanupStaging deletes staged objects older than given age, left by crashed staged uploads
+	CleanupStaging(ctx context.Context, bucket string, olderThan time.Duration, opts ...StagingOption) (DeleteReport, error)
+	// CleanupOrphans deletes old unreferenced temporary objects of staged & parallel uploads
+	CleanupOrphans(ctx context.Context, bucket string, opts GCOptions) (GCReport, error)
+}
+
+// AttrsManager reads & updates object attributes, metadata & tags
+type AttrsManager interface {
+	// GetAttrs returns attributes of file at given cloud bucket & filepath
+	GetAttrs(context.Context, CloudFileRequest) (*ObjectAttrs, error)
+	// GetAttrsBatch returns attributes of given bucket's named files in input order, failures recorded per name
+	GetAttrsBatch(ctx context.Context, bucket string, names []string, opts ...AttrsBatchOption) ([]AttrsResult, error)
+	// Exists reports whether file at given cloud bucket & filepath exists
+	Exists(context.Context, CloudFileRequest) (bool, error)
+	// Invalidate drops the cached existence of given bucket object, for changes made outside this client,
+	// the object name is the stored one, context scopes don't apply
+	Invalidate(bucket, object string)
+	// UpdateMetadata merges given custom metadata into file's metadata, returns updated attributes
+	UpdateMetadata(ctx context.Context, cfr CloudFileRequest, metadata map[string]string) (*ObjectAttrs, error)
+	// SetObjectTags merges given tags into the tags of file at given cloud bucket & filepath
+	SetObjectTags(ctx context.Context, cfr CloudFileRequest, tags map[string]string) (map[string]string, error)
+	// GetObjectTags returns the tags of file at given cloud bucket & filepath
+	GetObjectTags(ctx context.Context, cfr CloudFileRequest) (map[string]string, error)
+	// WaitVisible waits until the token's uploaded generation is observable through the client, bypassing caches
+	WaitVisible(ctx context.Context, token VisibilityToken, timeout time.Duration) (*ObjectAttrs, error)
+}
+
+// Replicator copies objects within & across buckets & clients
+type Replicator interface {
+	// CopyFrom copies source client's file to given cloud bucket & filepath
+	CopyFrom(ctx context.Context, source CloudStorage, src, dst CloudFileRequest, opts ...CopyOption) (CopyResult, error)
+	// TransformObject streams the src file through given transform into the dst file, committed only when the transform succeeds
+	TransformObject(ctx context.Context, src, dst CloudFileRequest, transform TransformFunc, opts ...TransformOption) (TransformResult, error)
+	// ReconcileBuckets copies missing & changed source objects to the destination, optionally deleting extraneous ones
+	ReconcileBuckets(ctx context.Context, src, dst BucketPrefix, opts ReconcileOptions) (ReconcileReport, error)
+	// RenameByRule moves the bucket's objects to the names given by rule with verified server side copies,
+	// reporting collisions of names mapped to the same new name
+	RenameByRule(ctx context.Context, bucket string, rule RenameRule, opts ...RenameOption) (RenameReport, error)
+	// BackupPrefixIncremental copies source objects under prefix updated after since, or after the last backup marker,
+	// to the destination bucket & writes a new marker
+	BackupPrefixIncremental(ctx context.Context, src, dst BucketRef, prefix string, since time.Time, opts ...BackupOption) (BackupReport, error)
+	// ReadLastBackupMarker returns the marker of the last successful backup of prefix into given bucket
+	ReadLastBackupMarker(ctx context.Context, dst BucketRef, prefix string) (BackupMarker, error)
+}
+
+// Versioner reads & restores object generations
+type Versioner interface {
+	// SnapshotPrefix returns the generations of files under request path that were live at given time
+	SnapshotPrefix(ctx context.Context, cfr CloudFileRequest, at time.Time) ([]ObjectVersion, error)
+	// RestoreSnapshot copies snapshot generations under given destination prefix, or over live files when empty
+	RestoreSnapshot(ctx context.Context, snapshot []ObjectVersion, dstPrefix string, opts ...RestoreOption) (RestoreReport, error)
+	// ListLatestVersions streams the latest version of every object under request path, in constant memory
+	ListLatestVersions(ctx context.Context, cfr CloudFileRequest, fn func(ObjectVersion) error, opts ...VersionListOption) error
+	// ListSoftDeleted returns the soft deleted generations of request's file, or of the objects under request path
+	ListSoftDeleted(ctx context.Context, cfr CloudFileRequest) ([]ObjectVersion, error)
+	// RestoreSoftDeleted restores given soft deleted generation of request's file as its live object
+	RestoreSoftDeleted(ctx context.Context, cfr CloudFileRequest, generation int64) (*ObjectAttrs, error)
+}
+
+// PointerStore publishes pointer files, counters & content addressed objects
+type PointerStore interface {
+	// PublishPointer replaces pointer file payload, conditional on the generation read, retried on concurrent updates
+	PublishPointer(ctx context.Context, pointer CloudFileRequest, payload []byte, opts ...PointerOption) error
+	// ReadPointer returns pointer file payload & generation
+	ReadPointer(ctx context.Context, pointer CloudFileRequest) ([]byte, int64, error)
+	// PutCAS stores content under its SHA-256 digest in given bucket & prefix unless already stored,
+	// returns the digest & whether the object was created
+	PutCAS(ctx context.Context, bucket, prefix string, r io.Reader) (string, bool, error)
+	// GetCAS copies content stored under given digest to given writer, verified against the digest
+	GetCAS(ctx context.Context, bucket, prefix, digest string, w io.Writer) (int64, error)
+	// IncrementCounter adds delta to the counter object's value, conditional on the generation read,
+	// retried on concurrent increments, returns the new value
+	IncrementCounter(ctx context.Context, counter CloudFileRequest, delta int64) (int64, error)
+	// ReadCounter returns the counter object's value, zero when missing
+	ReadCounter(ctx context.Context, counter CloudFileRequest) (int64, error)
+}
+
+// BucketManager reads & configures buckets
+type BucketManager interface {
+	// Bucket returns a handle running object operations in the named bucket, with per bucket defaults
+	Bucket(name string) BucketRef
+	// EnsureRoutedBuckets creates the missing buckets given routing keys route to, returns the created buckets
+	EnsureRoutedBuckets(ctx context.Context, keys []string) ([]string, error)
+	// GetBucketAttrs returns attributes of given bucket, location, replication, storage class, versioning, retention, holds, soft delete & labels
+	GetBucketAttrs(ctx context.Context, bucket string) (*BucketAttrs, error)
+	// SetBucketVersioning enables or suspends object versioning of given bucket
+	SetBucketVersioning(ctx context.Context, bucket string, enabled bool) error
+	// SetBucketLabels replaces the labels of given bucket
+	SetBucketLabels(ctx context.Context, bucket string, labels map[string]string) error
+	// SetBucketSoftDelete sets the soft delete retention of given bucket, zero disables soft delete
+	SetBucketSoftDelete(ctx context.Context, bucket string, retention time.Duration) error
+	// SetBucketRPO sets the replication recovery point objective of given dual-region bucket, RPOAsyncTurbo for turbo replication
+	SetBucketRPO(ctx context.Context, bucket string, rpo BucketRPO) error
+	// SetBucketRetentionPolicy sets the minimum object age before deletion or replacement in given bucket, zero removes it,
+	// locked policies can't be shortened, failing with ErrRetentionPolicyLocked
+	SetBucketRetentionPolicy(ctx context.Context, bucket string, period time.Duration) error
+	// LockBucketRetentionPolicy irreversibly locks the retention policy of given bucket, confirm must be the bucket's name
+	LockBucketRetentionPolicy(ctx context.Context, bucket, confirm string) error
+	// SetDefaultEventBasedHold sets whether objects created in given bucket are placed under event-based hold
+	SetDefaultEventBasedHold(ctx context.Context, bucket string, enabled bool) error
+	// AssertBucketPolicy checks given bucket's configuration, returns every violated expectation in one error
+	AssertBucketPolicy(ctx context.Context, bucket string, want BucketPolicyAssertion) error
+}
+
+// URLSigner signs object URLs
+type URLSigner interface {
+	// SignedURL returns a signed URL for file at given cloud bucket & filepath
+	SignedURL(ctx context.Context, cfr CloudFileRequest, opts SignedURLOptions) (string, error)
+	// SignedURLs returns signed URLs for given files in input order, signing credentials prepared once
+	SignedURLs(ctx context.Context, cfrs []CloudFileRequest, opts SignedURLOptions) ([]SignedURLResult, error)
+}
+
+// UsageSampler samples & records storage usage
+type UsageSampler interface {
+	// SampleUsage returns the object counts & sizes of given bucket grouped by prefix up to depth segments deep
+	SampleUsage(ctx context.Context, bucket string, depth int) (UsageSnapshot, error)
+	// WriteUsageSnapshot writes given usage snapshot as a dated NDJSON object under the request's path
+	WriteUsageSnapshot(ctx context.Context, snapshot UsageSnapshot, cfr CloudFileRequest) (UploadResult, error)
+	// ReadUsageHistory reads the usage snapshots under the request's path taken since given time
+	ReadUsageHistory(ctx context.Context, cfr CloudFileRequest, since time.Time) ([]UsageSnapshot, error)
+}
+
+// CloudStorage is the full client, composed of the narrower interfaces consumers can depend on instead
+type CloudStorage interface {
+	Uploader
+	Downloader
+	RecordStore
+	Lister
+	Deleter
+	AttrsManager
+	Replicator
+	Versioner
+	PointerStore
+	BucketManager
+	URLSigner
+	UsageSampler
+
+	// EnsureDir creates directory marker object for given bucket & prefix, if missing
+	EnsureDir(ctx context.Context, bucket, prefix string) error
+	// ProcessManifest runs given batch action for every object named in the manifest, returns outcome report
+	ProcessManifest(ctx context.Context, r io.Reader, action ManifestAction, opts ...ManifestOption) (ManifestReport, error)
+	// ProcessPrefix streams the objects under request path to given handler in name order, recording each processed,
+	// skipping objects processed by earlier runs
+	ProcessPrefix(ctx context.Context, cfr CloudFileRequest, handler ProcessHandler, opts ...ProcessOption) (ProcessReport, error)
+	// NewFileRequest builds a cloud file request, failing on upload profiles unknown to the client
+	NewFileRequest(bucketName, fileName, path string, modTime int64, opts ...CloudFileRequestOption) (CloudFileRequest, error)
+	// AuditFailures returns the number of audit events the configured audit sink failed to record
+	AuditFailures() int64
+	// Scoped returns a client restricted to given bucket & path prefix, sharing this client's connections
+	Scoped(bucket, prefix string) CloudStorage
+	// Close closes the client like CloseWithContext, within DEFAULT_CLOSE_TIMEOUT
 	Close() error
+	// CloseWithContext stops new operations, waits for in-flight ones, flushes buffered components
+	// & closes storage client connections, bounded by the context
+	CloseWithContext(ctx context.Context) error
 }
 
 const (
@@ -42,12 +293,18 @@ const (
 	ERROR_MISSING_FILE_NAME       string = "file name missing"
 	ERROR_STALE_UPLOAD            string = "storage bucket object has updates"
 	ERROR_STALE_DOWNLOAD          string = "file object has updates"
+	ERROR_CHECKSUM_MISMATCH       string = "downloaded content checksum mismatch"
+	ERROR_UPLOAD_CANCELLED        string = "upload cancelled"
+	ERROR_UPLOAD_PREFLIGHT        string = "upload pre-flight lookup failed"
+	ERROR_REMOVING_PARTIAL_UPLOAD string = "error removing partially uploaded object"
 )
 
 var (
 	ErrBucketNameMissing = errors.NewAppError(ERROR_MISSING_BUCKET_NAME)
 	ErrFilePathMissing   = errors.NewAppError(ERROR_MISSING_FILE_PATH)
 	ErrFileNameMissing   = errors.NewAppError(ERROR_MISSING_FILE_NAME)
+	ErrStaleUpload       = errors.NewAppError(ERROR_STALE_UPLOAD)
+	ErrStaleDownload     = errors.NewAppError(ERROR_STALE_DOWNLOAD)
 )
 
 type BufferSize int64
@@ -58,14 +315,113 @@ const (
 	DEFAULT_BUFFER_SIZE            = OneKB
 )
 
+// DEFAULT_TRASH_PREFIX is the path prefix WithDeleteToTrash moves deleted objects under when empty
+const DEFAULT_TRASH_PREFIX = ".trash"
+
 type CloudStorageClientConfig struct {
+	// CredsPath is the credentials file, a service account key or external account configuration,
+	// checked by the constructor, the application default credentials apply when empty
 	CredsPath string `json:"creds_path"`
+	// SkipCredsValidation leaves the credentials file to the storage client, e.g. for other credential types
+	SkipCredsValidation bool `json:"skip_creds_validation"`
+	// SpoolThreshold is the size limit for spooling non seekable upload readers in memory,
+	// larger streams are spooled to a temp file, defaults to DEFAULT_SPOOL_THRESHOLD
+	SpoolThreshold int64 `json:"spool_threshold"`
+	// SpoolDir is the directory for upload spool temp files, defaults to os.TempDir
+	SpoolDir string `json:"spool_dir"`
+	// UploadPolicy are upload guardrails applied to every upload, optional
+	UploadPolicy *UploadPolicy `json:"upload_policy"`
+	// BufferSize is the upload & download transfer buffer size, defaults to DEFAULT_COPY_BUFFER_SIZE
+	BufferSize BufferSize `json:"buffer_size"`
+	// SlowOpThreshold is the duration after which operations are logged as slow, zero disables
+	SlowOpThreshold time.Duration `json:"slow_op_threshold"`
+	// SignedURLCacheSize is the number of signed URLs cached, zero disables the cache
+	SignedURLCacheSize int `json:"signed_url_cache_size"`
+	// SignedURLCacheMinRemaining is the fraction of TTL a cached URL must still be valid for,
+	// defaults to DEFAULT_SIGNED_URL_MIN_REMAINING
+	SignedURLCacheMinRemaining float64 `json:"signed_url_cache_min_remaining"`
+	// Metrics receives transfer metrics, optional
+	Metrics MetricsRecorder `json:"-"`
+	// CostLabel labels the object names of transfer & request metrics for cost attribution,
+	// defaults to FirstSegmentLabel
+	CostLabel CostLabelFunc `json:"-"`
+	// CircuitBreaker makes requests fail fast with CircuitOpenError while the recent failure rate
+	// of their class, reads, writes or listings, is over the threshold, optional
+	CircuitBreaker *CircuitBreakerConfig `json:"circuit_breaker"`
+	// AccessRecorder receives the offset, length & latency of every ReadAt call, of the client
+	// & of readers at, for tuning chunked readers, optional, reads aren't timed when unset
+	AccessRecorder AccessRecorder `json:"-"`
+	// AccessSampleEvery records one of every given number of reads, every read when not above 1
+	AccessSampleEvery int `json:"access_sample_every"`
+	// ReadOnly makes every mutating method fail with ErrReadOnlyClient without issuing a request
+	ReadOnly bool `json:"read_only"`
+	// ExistenceCacheSize is the number of objects whose existence & attributes are cached for
+	// Exists & GetAttrs, zero disables the cache. Changes made outside this client are seen
+	// only once the entry expires or is dropped with Invalidate.
+	ExistenceCacheSize int `json:"existence_cache_size"`
+	// ExistenceCacheTTL is how long found objects are cached, zero doesn't cache them
+	ExistenceCacheTTL time.Duration `json:"existence_cache_ttl"`
+	// ExistenceCacheNegativeTTL is how long missing objects are cached, zero doesn't cache them
+	ExistenceCacheNegativeTTL time.Duration `json:"existence_cache_negative_ttl"`
+	// Retry makes the client retry metadata lookups & download reads itself, preferring server
+	// retry hints, optional, the storage client's default retries apply when unset
+	Retry *RetryPolicy `json:"retry"`
+	// UploadProfiles are the named object attribute templates requests select with WithProfile, optional
+	UploadProfiles map[string]ObjectAttrsTemplate `json:"upload_profiles"`
+	// StrictScope makes requests with a scoped context fail with ErrOutOfScope when naming a bucket
+	// other than the scope's, the scope's path prefix applies in any bucket otherwise
+	StrictScope bool `json:"strict_scope"`
+	// DeadlineBudget tunes the deadline check of composite operations, the defaults apply when zero
+	DeadlineBudget DeadlineBudget `json:"deadline_budget"`
+	// Router resolves the bucket of requests with a routing key, optional
+	Router BucketRouter `json:"-"`
+	// ProjectID is the project EnsureRoutedBuckets creates missing buckets in
+	ProjectID string `json:"project_id"`
+	// StrictPreflight makes uploads fail before reading the caller's reader when the pre-flight
+	// attributes lookup is denied or the service is unreachable, on when unset.
+	// Uploads proceed on any lookup failure when turned off.
+	StrictPreflight *bool `json:"strict_preflight"`
+	// ModTimeSkew is the clock skew tolerated comparing request modTimes with object update times
+	// in stale upload & download checks, defaults to DEFAULT_MOD_TIME_SKEW, negative tolerates none
+	ModTimeSkew time.Duration `json:"mod_time_skew"`
+	// MaxConcurrentTransfers bounds the client's concurrent uploads, downloads, range reads & part uploads,
+	// bulk operations' workers included, zero doesn't bound them. Transfers wait for a slot,
+	// open readers hold theirs until closed.
+	MaxConcurrentTransfers int `json:"max_concurrent_transfers"`
+	// MaxUploadBytes fails uploads reading more bytes from the caller, or declaring a larger size,
+	// with MaxUploadExceededError & commits nothing, spooled, staged & parallel uploads included.
+	// Zero doesn't limit uploads.
+	MaxUploadBytes int64 `json:"max_upload_bytes"`
+	// NameCodec encodes request paths into the stored object names & decodes listed names, optional.
+	// Stored names the codec can't decode are returned as stored, object attributes flagged NameNotDecoded.
+	NameCodec NameCodec `json:"-"`
+	// Audit receives an event after every mutating operation, optional
+	Audit AuditSink `json:"-"`
+	// Principal identifies the client's caller in audit events, e.g. the service account
+	Principal string `json:"principal"`
 }
 
 type cloudStorageClient struct {
-	client *storage.Client
-	config CloudStorageClientConfig
-	logger logger.AppLogger
+	// accessSeq counts reads for access sampling, auditFailures failed audit records,
+	// first for 64 bit atomic alignment
+	accessSeq     uint64
+	auditFailures int64
+	client        *storage.Client
+	// jsonAPI calls the JSON API methods the storage client version lacks
+	jsonAPI    *jsonAPIClient
+	config     CloudStorageClientConfig
+	logger     logger.AppLogger
+	urlCache   *signedURLCache
+	existCache *existenceCache
+	bufPool    *bufferPool
+	transfers  *transferLimiter
+	clock      Clock
+	// sleeper replaces retry waits, for tests
+	sleeper func(ctx context.Context, d time.Duration) error
+	// bound restricts the clients returned by Scoped
+	bound *boundary
+	// life tracks in-flight operations & the components flushed on close, shared with scoped clients
+	life *lifecycle
 }
 
 type GCPStorageReadAtAdaptor struct {
@@ -83,48 +439,346 @@ func (ra *GCPStorageReadAtAdaptor) ReadAt(p []byte, off int64) (n int, err error
 	return ra.Reader.Read(p)
 }
 
-// NewCloudStorageClient takes client config & logger, returns cloud storage client
-func NewCloudStorageClient(cfg CloudStorageClientConfig, logger logger.AppLogger) (*cloudStorageClient, error) {
+// NewCloudStorageClient takes client config, logger & client options, returns cloud storage client
+func NewCloudStorageClient(cfg CloudStorageClientConfig, logger logger.AppLogger, opts ...ClientOption) (*cloudStorageClient, error) {
 	if logger == nil {
 		return nil, errors.NewAppError(errors.ERROR_MISSING_REQUIRED)
 	}
+	if cfg.CredsPath != "" && !cfg.SkipCredsValidation {
+		if err := validateCredsFile(cfg.CredsPath); err != nil {
+			logger.Error(ERROR_CREATING_STORAGE_CLIENT, zap.Error(err))
+			return nil, err
+		}
+	}
 	os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", cfg.CredsPath)
-	client, err := storage.NewClient(context.Background())
+	var breaker *circuitBreaker
+	if cfg.CircuitBreaker != nil {
+		recorder, _ := cfg.Metrics.(CircuitRecorder)
+		breaker = newCircuitBreaker(*cfg.CircuitBreaker, time.Now, recorder)
+	}
+	requests, _ := cfg.Metrics.(RequestRecorder)
+	// requests are accounted as sent, with their call extras, below the breaker
+	wrap := func(hc *http.Client) *http.Client {
+		hc = withCallExtrasTransport(hc)
+		if requests != nil {
+			hc = withAccounting(hc, requests, cfg.CostLabel)
+		}
+		if breaker != nil {
+			hc = withBreaker(hc, breaker)
+		}
+		return hc
+	}
+	hc, _, err := htransport.NewClient(context.Background(), option.WithScopes(storage.ScopeFullControl))
 	if err != nil {
 		logger.Error(ERROR_CREATING_STORAGE_CLIENT, zap.Error(err))
 		return nil, errors.WrapError(err, ERROR_CREATING_STORAGE_CLIENT)
 	}
+	client, err := storage.NewClient(context.Background(), option.WithHTTPClient(wrap(hc)))
+	if err != nil {
+		logger.Error(ERROR_CREATING_STORAGE_CLIENT, zap.Error(err))
+		return nil, errors.WrapError(err, ERROR_CREATING_STORAGE_CLIENT)
+	}
+	jsonAPI, err := newJSONAPIClient(context.Background())
+	if err != nil {
+		logger.Error(ERROR_CREATING_STORAGE_CLIENT, zap.Error(err))
+		return nil, errors.WrapError(err, ERROR_CREATING_STORAGE_CLIENT)
+	}
+	jsonAPI.client = wrap(jsonAPI.client)
 
 	loaderClient := &cloudStorageClient{
-		client: client,
-		config: cfg,
-		logger: logger,
+		client:    client,
+		jsonAPI:   jsonAPI,
+		config:    cfg,
+		logger:    logger,
+		bufPool:   newBufferPool(cfg.BufferSize),
+		transfers: newTransferLimiter(cfg.MaxConcurrentTransfers),
+		clock:     realClock{},
+		life:      newLifecycle(),
+	}
+	for _, opt := range opts {
+		opt(loaderClient)
+	}
+	if breaker != nil {
+		breaker.now = loaderClient.now
+	}
+	if cfg.SignedURLCacheSize > 0 {
+		loaderClient.urlCache = newSignedURLCache(cfg.SignedURLCacheSize, cfg.SignedURLCacheMinRemaining, loaderClient.now)
+	}
+	if cfg.ExistenceCacheSize > 0 {
+		loaderClient.existCache = newExistenceCache(cfg.ExistenceCacheSize, cfg.ExistenceCacheTTL, cfg.ExistenceCacheNegativeTTL)
+		loaderClient.existCache.now = loaderClient.now
 	}
 
 	return loaderClient, nil
 }
 
 type CloudFileRequest struct {
-	bucket  string
-	file    string
-	path    string
-	modTime int64
+	bucket    string
+	file      string
+	path      string
+	modTime   time.Time
+	requestID string
+	// modTimeUnchecked takes modTime arguments for unix seconds without the plausibility check
+	modTimeUnchecked bool
+	// knownGeneration & knownMetageneration identify the object the caller's copy was read from
+	knownGeneration     int64
+	knownMetageneration int64
+
+	contentEncoding string
+	readCompressed  bool
+
+	removeDirMarkers bool
+	noSpool          bool
+	chunkSize        int
+	// singleRequest uploads without a resumable session
+	singleRequest bool
+	shards        int
+	// firstByteTimeout bounds the open & first read of downloads
+	firstByteTimeout time.Duration
+
+	inventoryFields []InventoryField
+
+	checkpointer    Checkpointer
+	checkpointEvery int
+	// unconditionalDelete deletes listed objects whatever their generation
+	unconditionalDelete bool
+	// deleteDryRun counts deletes without deleting, trashPrefix moves deleted objects under it
+	deleteDryRun bool
+	trashPrefix  string
+	// wholeBucket is set by NewBucketRequest, allowing listings & deletes of every object
+	wholeBucket bool
+
+	scoped      bool
+	scopePrefix string
+	// names is the client's name codec the scoped request's path, file & scope prefix are encoded with
+	names NameCodec
+
+	metagenerationMatch int64
+	generation          int64
+	readOffset          int64
+	size                int64
+	crc32c              uint32
+	sendCRC32C          bool
+	generationMatch     int64
+	hasGenerationMatch  bool
+
+	contentType        string
+	contentLanguage    string
+	contentDisposition string
+	cacheControl       string
+	profile            string
+	metadata           map[string]string
+	policy             *UploadPolicy
+
+	userProject string
+	kmsKeyName  string
+	routingKey  string
+
+	detectConcurrentWrite bool
+	// callExtras are the custom headers & API parameters set on the operation's calls
+	callExtras callExtras
+}
+
+// CloudFileRequestOption sets optional cloud file request values
+type CloudFileRequestOption func(*CloudFileRequest)
+
+// WithFileRequestID sets the request ID used in the request's logs & errors,
+// takes precedence over the ID set with WithRequestID on the context
+func WithFileRequestID(id string) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.requestID = id
+	}
+}
+
+// WithContentEncoding sets the content encoding of uploaded object, e.g. gzip
+func WithContentEncoding(encoding string) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.contentEncoding = encoding
+	}
+}
+
+// WithContentType sets the content type of uploaded object
+func WithContentType(contentType string) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.contentType = contentType
+	}
+}
+
+// WithMetadata sets the custom metadata of uploaded object
+func WithMetadata(metadata map[string]string) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.metadata = metadata
+	}
 }
 
-// NewCloudFileRequest takes bucket name, file name & filepath, return cloud storage request
-func NewCloudFileRequest(bucketName, fileName, path string, modTime int64) (CloudFileRequest, error) {
-	if bucketName == "" {
+// WithRawDownload disables decompressive transcoding on download,
+// objects stored with gzip content encoding are downloaded as stored gzip bytes
+func WithRawDownload() CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.readCompressed = true
+	}
+}
+
+// WithRemoveDirMarkers makes DeleteObjects also remove the directory marker objects,
+// markers are removed after the objects they contain
+func WithRemoveDirMarkers() CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.removeDirMarkers = true
+	}
+}
+
+// WithUnconditionalDelete makes DeleteObjects delete the listed objects whatever their generation,
+// objects replaced since they were listed included
+func WithUnconditionalDelete() CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.unconditionalDelete = true
+	}
+}
+
+// WithDeleteDryRun makes deletes look objects up & count what they would delete, without deleting
+func WithDeleteDryRun() CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.deleteDryRun = true
+	}
+}
+
+// WithDeleteToTrash makes deletes copy objects under given path prefix, their names appended, before
+// deleting them, DEFAULT_TRASH_PREFIX when empty. Only the generation copied is deleted.
+func WithDeleteToTrash(prefix string) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		if prefix == "" {
+			prefix = DEFAULT_TRASH_PREFIX
+		}
+		cfr.trashPrefix = prefix
+	}
+}
+
+// WithoutSpooling uploads non seekable readers directly, without spooling,
+// for huge streams known to be safe for retries
+func WithoutSpooling() CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.noSpool = true
+	}
+}
+
+// WithCRC32C sets the expected CRC32C of uploaded content, verified by the service on commit
+func WithCRC32C(crc uint32) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.crc32c = crc
+		cfr.sendCRC32C = true
+	}
+}
+
+// NewCloudFileRequest takes bucket name, file name, filepath & options, return cloud storage request,
+// the bucket name can be empty with WithRoutingKey. A positive modTime is the caller's copy's
+// modification time in unix seconds, uploads fail with ErrStaleUpload when the object was updated
+// later & downloads with ErrStaleDownload when it was updated earlier, within the client's ModTimeSkew.
+// WithModTime is preferred, modTimes past MAX_MOD_TIME_SECONDS, e.g. of time.UnixMilli, fail with
+// ModTimeUnitError unless WithUncheckedModTime.
+// Requests need a file name or path, whole bucket requests are built with NewBucketRequest.
+// Object names the service would reject fail with InvalidObjectNameError.
+func NewCloudFileRequest(bucketName, fileName, path string, modTime int64, opts ...CloudFileRequestOption) (CloudFileRequest, error) {
+	cfr := CloudFileRequest{
+		bucket: bucketName,
+		file:   fileName,
+		path:   path,
+	}
+	for _, opt := range opts {
+		opt(&cfr)
+	}
+	if modTime > 0 && cfr.modTime.IsZero() {
+		if !cfr.modTimeUnchecked {
+			if err := checkModTime(modTime); err != nil {
+				return CloudFileRequest{}, err
+			}
+		}
+		cfr.modTime = time.Unix(modTime, 0)
+	}
+	// routed requests get their bucket from the client's router
+	if cfr.bucket == "" && cfr.routingKey == "" {
 		return CloudFileRequest{}, ErrBucketNameMissing
 	}
-	return CloudFileRequest{
-		bucket:  bucketName,
-		file:    fileName,
-		path:    path,
-		modTime: modTime,
-	}, nil
+	if cfr.bucketWide() {
+		return CloudFileRequest{}, ErrBucketWideRequest
+	}
+	if err := ValidateObjectName(NormalizeName(cfr.objectPath())); err != nil {
+		return CloudFileRequest{}, err
+	}
+	return cfr, nil
+}
+
+// objectPath returns request's object name, file name joined to path when set,
+// with the shard segment in between for sharded layouts
+func (cfr CloudFileRequest) objectPath() string {
+	if cfr.sharded() {
+		return ShardedName(cfr.path, cfr.file, cfr.shards)
+	}
+	if cfr.path != "" {
+		return filepath.Join(cfr.path, cfr.file)
+	}
+	return cfr.file
 }
 
-func (cs *cloudStorageClient) ReadAt(ctx context.Context, cfr CloudFileRequest, p []byte, off int64) (int, error) {
+// UploadResult is the result of a successful upload, failed uploads return the bytes sent & request ID
+type UploadResult struct {
+	// Bytes is the number of bytes uploaded, set on failures too,
+	// Bytes > 0 with an error is a partial transfer that wasn't committed
+	Bytes int64
+	// RequestID is the ID included in the upload's logs & errors
+	RequestID string
+	// Spool is how the reader was consumed, directly or spooled to memory or temp file
+	Spool SpoolMode
+	// Attrs are the uploaded object's attributes
+	Attrs *ObjectAttrs
+	// Duration is the time from opening the object writer to commit, spooling excluded
+	Duration time.Duration
+	// BytesPerSecond is the upload throughput over Duration
+	BytesPerSecond float64
+	// Token identifies the uploaded generation by stored name, for WaitVisible
+	Token VisibilityToken
+	// ObservedGeneration is the generation seen before the upload, zero when absent,
+	// set with WithDetectConcurrentWrite
+	ObservedGeneration int64
+	// ConcurrentWriteDetected reports another write of the object between the observed generation
+	// & the upload's commit, set with WithDetectConcurrentWrite
+	ConcurrentWriteDetected bool
+}
+
+// DownloadResult is the result of a successful download, failed downloads return the bytes written & request ID
+type DownloadResult struct {
+	// Bytes is the number of bytes written to the writer,
+	// decompressed byte count when the download was transcoded,
+	// stored (compressed) byte count otherwise, including raw downloads.
+	// Set on failures too, Bytes > 0 with an error is a partial transfer.
+	Bytes int64
+	// RequestID is the ID included in the download's logs & errors
+	RequestID string
+	// Transcoded is set when gzip encoded object was served decompressed,
+	// checksum isn't verified for transcoded downloads as the stored CRC covers compressed bytes
+	Transcoded bool
+	// Verified is set when downloaded content matched the stored CRC32C checksum
+	Verified bool
+	// Attrs are the downloaded object's attributes
+	Attrs *ObjectAttrs
+	// Duration is the time from opening the object reader to the last byte written
+	Duration time.Duration
+	// TimeToFirstByte is the time from opening the object reader to the first byte written
+	TimeToFirstByte time.Duration
+	// BytesPerSecond is the download throughput over Duration
+	BytesPerSecond float64
+	// Chunks is the number of range reads of a parallel download,
+	// ChunkSize the size of its last cut chunk, the adapted size when adaptive
+	Chunks    int
+	ChunkSize int64
+}
+
+// ReadAt reads len(p) bytes of the cloud file at given offset with a range read,
+// returns io.EOF when fewer bytes remain. Reads use the caller's context only.
+func (cs *cloudStorageClient) ReadAt(ctx context.Context, cfr CloudFileRequest, p []byte, off int64) (n int, err error) {
+	cfr, err = cs.request(ctx, cfr)
+	if err != nil {
+		return 0, err
+	}
 	if cfr.file == "" {
 		return 0, ErrFileNameMissing
 	}
@@ -133,124 +787,451 @@ func (cs *cloudStorageClient) ReadAt(ctx context.Context, cfr CloudFileRequest,
 		return 0, ErrBucketNameMissing
 	}
 
-	fPath := cfr.file
-	if cfr.path != "" {
-		fPath = filepath.Join(cfr.path, cfr.file)
-	}
-
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	op := cs.startOperation(ctx, "ReadAt", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	fPath := op.object
+	start := cs.accessStart()
+	defer func() {
+		cs.recordAccess(op, off, len(p), n, start, false, err)
+	}()
 
 	// check for object existence
-	obj := cs.client.Bucket(cfr.bucket).Object(fPath)
+	obj := cs.bucketHandle(cfr).Object(fPath)
 	attrs, err := obj.Attrs(ctx)
 	if err != nil {
-		cs.logger.Error("cloud file inaccessible", zap.Error(err), zap.String("filepath", fPath))
-		return 0, errors.WrapError(err, "cloud file inaccessible %s", fPath)
+		op.logger.Error("cloud file inaccessible", zap.Error(err), zap.String("filepath", fPath))
+		return 0, op.wrapError(err, "cloud file inaccessible %s", fPath)
+	}
+	op.logger.Debug("reading cloud file chunk", zap.String("filepath", fPath), zap.Int64("created", attrs.Created.Unix()), zap.Int64("updated", attrs.Updated.Unix()))
+	if off >= attrs.Size {
+		return 0, io.EOF
+	}
+
+	release, err := op.acquireTransfer(ctx)
+	if err != nil {
+		op.logger.Error(ERROR_WAITING_TRANSFER_SLOT, zap.Error(err), zap.String("filepath", fPath))
+		return 0, op.wrapError(err, "%s %s", ERROR_WAITING_TRANSFER_SLOT, fPath)
 	}
-	cs.logger.Debug("reading cloud file chunk", zap.String("filepath", fPath), zap.Int64("created", attrs.Created.Unix()), zap.Int64("updated", attrs.Updated.Unix()))
+	defer release()
 
-	// open a reader for the object in the bucket
-	rc, err := obj.NewReader(ctx)
+	// open a range reader for the chunk, pinned to the checked generation
+	rc, err := op.openStarted(ctx, cfr, func(ctx context.Context) (*storage.Reader, error) {
+		return obj.Generation(attrs.Generation).NewRangeReader(ctx, off, int64(len(p)))
+	})
 	if err != nil {
-		cs.logger.Error("error reading cloud file", zap.Error(err), zap.String("filepath", fPath))
-		return 0, errors.WrapError(err, "error reading cloud file %s", fPath)
+		op.logger.Error("error reading cloud file", zap.Error(err), zap.String("filepath", fPath))
+		return 0, op.wrapError(err, "error reading cloud file %s", fPath)
 	}
-	rcReadAt := &GCPStorageReadAtAdaptor{rc}
 	defer func() {
-		if err := rcReadAt.Reader.Close(); err != nil {
-			cs.logger.Error("error closing cloud file reader", zap.Error(err), zap.String("filepath", fPath))
+		if err := rc.Close(); err != nil {
+			op.logger.Error("error closing cloud file reader", zap.Error(err), zap.String("filepath", fPath))
 		}
 	}()
 
-	return rcReadAt.ReadAt(p, off)
+	n, err = io.ReadFull(rc, p)
+	op.bytes += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		return n, io.EOF
+	}
+	if err != nil {
+		op.logger.Error("error reading cloud file", zap.Error(err), zap.String("filepath", fPath))
+		return n, op.wrapError(err, "error reading cloud file %s", fPath)
+	}
+	if off+int64(n) >= attrs.Size {
+		return n, io.EOF
+	}
+	return n, nil
 }
 
 func (cs *cloudStorageClient) UploadFile(ct context.Context, file io.Reader, cfr CloudFileRequest) (int64, error) {
+	res, err := cs.Upload(ct, file, cfr)
+	return res.Bytes, err
+}
+
+func (cs *cloudStorageClient) Upload(ct context.Context, file io.Reader, cfr CloudFileRequest) (UploadResult, error) {
+	if err := cs.mutation(); err != nil {
+		return UploadResult{}, err
+	}
+	cfr, err := cs.request(ct, cfr)
+	if err != nil {
+		return UploadResult{}, err
+	}
 	if cfr.file == "" {
-		return 0, ErrFileNameMissing
+		return UploadResult{}, ErrFileNameMissing
 	}
-	fPath := cfr.file
-	if cfr.path != "" {
-		fPath = filepath.Join(cfr.path, cfr.file)
+	cfr, err = cs.applyProfile(cfr)
+	if err != nil {
+		return UploadResult{}, err
 	}
+	op := cs.startOperation(ct, "UploadFile", cfr)
+	defer op.finish()
+	ct = op.ctx
+	fPath := op.object
+	defer cs.invalidate(cfr.bucket, fPath)
 
-	ctx, cancel := context.WithTimeout(ct, time.Second*50)
-	defer cancel()
+	_, seekable := file.(io.Seeker)
 
-	// Upload an object with storage.Writer.
-	obj := cs.client.Bucket(cfr.bucket).Object(fPath)
-	attrs, err := obj.Attrs(ctx)
+	policy, err := cs.uploadPolicy(cfr)
 	if err != nil {
-		cs.logger.Debug("cloud file doesn't exist, will create new", zap.String("filepath", fPath))
-	} else {
-		cs.logger.Debug("cloud file exists", zap.Int64("created", attrs.Created.Unix()), zap.Int64("updated", attrs.Updated.Unix()), zap.String("filepath", fPath))
+		op.logger.Error(ERROR_POLICY_VIOLATION, zap.Error(err), zap.String("filepath", fPath))
+		return UploadResult{}, op.wrapError(err, "%s %s", ERROR_POLICY_VIOLATION, fPath)
 	}
 
+	// the existing generation is looked up before the caller's reader is read, so doomed uploads
+	// don't consume non rewindable streams
+	obj := cs.bucketHandle(cfr).Object(fPath)
+	var prevGen int64
+	preCtx, preCancel := context.WithTimeout(ct, DEFAULT_UPLOAD_TIMEOUT)
+	attrs, err := obj.Attrs(preCtx)
+	preCancel()
+	switch {
+	case err == nil:
+		prevGen = attrs.Generation
+		op.logger.Debug("cloud file exists", zap.Int64("created", attrs.Created.Unix()), zap.Int64("updated", attrs.Updated.Unix()), zap.String("filepath", fPath))
+	case cs.strictPreflight() && preflightFatal(err):
+		op.logger.Error(ERROR_UPLOAD_PREFLIGHT, zap.Error(err), zap.String("filepath", fPath))
+		return UploadResult{}, op.wrapError(err, "%s %s", ERROR_UPLOAD_PREFLIGHT, fPath)
+	default:
+		op.logger.Debug("cloud file doesn't exist, will create new", zap.Error(err), zap.String("filepath", fPath))
+	}
+	if err == nil || err == storage.ErrObjectNotExist {
+		if cs.freshness(cfr, attrs) == freshnessObjectNewer {
+			op.logger.Error(ERROR_STALE_UPLOAD, zap.String("filepath", fPath), zap.Time("modTime", cfr.modTime), zap.Int64("knownGeneration", cfr.knownGeneration))
+			return UploadResult{}, op.wrapError(ErrStaleUpload, "%s %s", ERROR_STALE_UPLOAD, fPath)
+		}
+	}
+
+	release, err := op.acquireTransfer(ct)
+	if err != nil {
+		op.logger.Error(ERROR_WAITING_TRANSFER_SLOT, zap.Error(err), zap.String("filepath", fPath))
+		return UploadResult{}, op.wrapError(err, "%s %s", ERROR_WAITING_TRANSFER_SLOT, fPath)
+	}
+	defer release()
+
+	contentType := cfr.contentType
+	if policy != nil {
+		file, contentType, err = applyUploadPolicy(policy, cfr, fPath, file)
+		if err != nil {
+			op.logger.Error(ERROR_POLICY_VIOLATION, zap.Error(err), zap.String("filepath", fPath))
+			return UploadResult{}, op.wrapError(err, "%s %s", ERROR_POLICY_VIOLATION, fPath)
+		}
+	}
+
+	// the limit applies to spooling too, an unbounded stream never fills the spool
+	if file, err = cs.limitUpload(file, cfr.size); err != nil {
+		op.logger.Error(ERROR_MAX_UPLOAD_EXCEEDED, zap.Error(err), zap.String("filepath", fPath))
+		return UploadResult{}, op.wrapError(err, "%s %s", ERROR_MAX_UPLOAD_EXCEEDED, fPath)
+	}
+	if cfr.size > 0 {
+		file = &sizeCheckReader{r: file, size: cfr.size}
+	}
+
+	// spool non seekable readers so the upload can be retried & checksummed
+	spoolMode := SpoolNone
+	var sp *spooled
+	if !seekable && !cfr.noSpool {
+		var err error
+		sp, err = spool(file, cs.spoolThreshold(), cs.config.SpoolDir, cs.buffers())
+		if ctxErr := ct.Err(); ctxErr != nil {
+			// the stream may have ended on cancellation, nothing is uploaded
+			if sp != nil {
+				sp.Close()
+			}
+			op.logger.Error(ERROR_UPLOAD_CANCELLED, zap.Error(ctxErr), zap.String("filepath", fPath))
+			return UploadResult{}, op.wrapError(ctxErr, "%s %s", ERROR_UPLOAD_CANCELLED, fPath)
+		}
+		if stderrors.Is(err, ErrMaxUploadExceeded) {
+			op.logger.Error(ERROR_MAX_UPLOAD_EXCEEDED, zap.Error(err), zap.String("filepath", fPath))
+			return UploadResult{}, op.wrapError(err, "%s %s", ERROR_MAX_UPLOAD_EXCEEDED, fPath)
+		}
+		if err != nil {
+			op.logger.Error(ERROR_SPOOLING_UPLOAD, zap.Error(err), zap.String("filepath", fPath))
+			return UploadResult{}, op.wrapError(err, "%s %s", ERROR_SPOOLING_UPLOAD, fPath)
+		}
+		defer func() {
+			if err := sp.Close(); err != nil {
+				op.logger.Error("error removing upload spool", zap.Error(err), zap.String("filepath", fPath))
+			}
+		}()
+		file, spoolMode = sp, sp.mode
+		op.logger.Debug("upload stream spooled", zap.String("filepath", fPath), zap.String("spool", string(spoolMode)), zap.Int64("size", sp.size))
+	}
+
+	ctx, cancel := context.WithTimeout(ct, DEFAULT_UPLOAD_TIMEOUT)
+	defer cancel()
+
+	// Upload an object with storage.Writer.
+	op.startTransfer(TransferUpload)
+	if cfr.hasGenerationMatch {
+		obj = obj.If(generationConditions(cfr.generationMatch))
+	}
 	wc := obj.NewWriter(ctx)
+	if cfr.contentEncoding != "" {
+		wc.ContentEncoding = cfr.contentEncoding
+	}
+	// declared content type wins, policy sniffed type is used when none declared
+	if cfr.contentType != "" {
+		wc.ContentType = cfr.contentType
+	} else if contentType != "" {
+		wc.ContentType = contentType
+	}
+	wc.ContentLanguage = cfr.contentLanguage
+	wc.ContentDisposition = cfr.contentDisposition
+	wc.CacheControl = cfr.cacheControl
+	wc.KMSKeyName = cfr.kmsKeyName
+	if cfr.metadata != nil {
+		wc.Metadata = cfr.metadata
+	}
+	// zero chunk size uploads in a single request
+	if cfr.singleRequest {
+		wc.ChunkSize = 0
+	} else if cfr.chunkSize > 0 {
+		wc.ChunkSize = cfr.chunkSize
+	}
+	if cfr.sendCRC32C {
+		wc.CRC32C = cfr.crc32c
+		wc.SendCRC32C = true
+	} else if sp != nil {
+		wc.CRC32C = sp.crc
+		wc.SendCRC32C = true
+	}
+	closed := false
 	defer func() {
+		if closed {
+			return
+		}
+		// cancel before close aborts the upload, partial content isn't committed
+		cancel()
 		if err := wc.Close(); err != nil {
-			cs.logger.Error("error closing cloud file", zap.Error(err), zap.String("filepath", fPath))
+			op.logger.Error("error closing cloud file", zap.Error(err), zap.String("filepath", fPath))
 		}
 	}()
 
-	nBytes, err := io.Copy(wc, file)
+	nBytes, err := cs.buffers().copy(wc, &countingReader{r: file, op: op})
+	// failures past this point return the bytes sent
+	partial := UploadResult{Bytes: nBytes, RequestID: op.requestID}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		// readers may end early on cancellation, e.g. a disconnected client's request body,
+		// the deferred close aborts the upload so truncated content isn't committed
+		op.logger.Error(ERROR_UPLOAD_CANCELLED, zap.Error(ctxErr), zap.String("filepath", fPath), zap.Int64("bytes", nBytes))
+		err = op.wrapError(ctxErr, "%s %s", ERROR_UPLOAD_CANCELLED, fPath)
+		op.endTransfer(nBytes, err)
+		return partial, err
+	}
+	if stderrors.Is(err, ErrMaxUploadExceeded) {
+		// the deferred close aborts the upload, content up to the limit isn't committed
+		op.logger.Error(ERROR_MAX_UPLOAD_EXCEEDED, zap.Error(err), zap.String("filepath", fPath), zap.Int64("bytes", nBytes))
+		err = op.wrapError(err, "%s %s", ERROR_MAX_UPLOAD_EXCEEDED, fPath)
+		op.endTransfer(nBytes, err)
+		return partial, err
+	}
 	if err != nil {
-		cs.logger.Error("error uploading file", zap.Error(err), zap.String("filepath", fPath))
-		return 0, errors.WrapError(err, "error uploading file %s", fPath)
+		op.logger.Error("error uploading file", zap.Error(err), zap.String("filepath", fPath))
+		err = op.wrapError(err, "error uploading file %s", fPath)
+		op.endTransfer(nBytes, err)
+		return partial, err
 	}
-	cs.logger.Debug("cloud file created/updated", zap.String("filepath", fPath))
-	return nBytes, nil
+
+	// writes landing while content was sent
+	landed := cfr.detectConcurrentWrite && cs.generationChanged(ctx, op, cfr, fPath, prevGen)
+
+	// object is committed on close
+	closed = true
+	if err := wc.Close(); err != nil {
+		op.logger.Error("error closing cloud file", zap.Error(err), zap.String("filepath", fPath))
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// the commit may have raced the cancellation
+			err = cs.removePartialUpload(op, cfr, fPath, prevGen, nBytes, ctxErr)
+		} else {
+			err = op.wrapError(err, "error closing cloud file %s", fPath)
+		}
+		op.endTransfer(nBytes, err)
+		return partial, err
+	}
+	op.storageClass = wc.Attrs().StorageClass
+	m := op.endTransfer(nBytes, nil)
+	op.generation = wc.Attrs().Generation
+	op.logger.Debug("cloud file created/updated", zap.String("filepath", fPath), zap.Duration("duration", m.Duration))
+	res := UploadResult{
+		Bytes:          nBytes,
+		RequestID:      op.requestID,
+		Spool:          spoolMode,
+		Attrs:          cfr.logicalAttrs(newObjectAttrs(wc.Attrs())),
+		Duration:       m.Duration,
+		BytesPerSecond: m.BytesPerSecond,
+		Token:          visibilityToken(wc.Attrs()),
+	}
+	if cfr.detectConcurrentWrite {
+		res.ObservedGeneration = prevGen
+		res.ConcurrentWriteDetected = cs.concurrentWrite(ctx, op, cfr, fPath, prevGen, wc.Attrs().Generation, landed)
+	}
+	return res, nil
+}
+
+// removePartialUpload deletes the object of an upload cancelled while closing, when committed anyway,
+// i.e. a generation other than the one seen before the upload, of the uploaded size.
+// Returns the cancellation error, reporting the cleanup.
+func (cs *cloudStorageClient) removePartialUpload(op *operation, cfr CloudFileRequest, name string, prevGen, size int64, cause error) error {
+	// the upload's context is done, cleanup runs regardless
+	ctx := context.Background()
+	obj := cs.bucketHandle(cfr).Object(name)
+	attrs, err := obj.Attrs(ctx)
+	if err != nil || attrs.Generation == prevGen || attrs.Size != size {
+		return op.wrapError(cause, "%s %s", ERROR_UPLOAD_CANCELLED, name)
+	}
+	err = obj.If(storage.Conditions{GenerationMatch: attrs.Generation}).Delete(ctx)
+	if err != nil && err != storage.ErrObjectNotExist {
+		op.logger.Error(ERROR_REMOVING_PARTIAL_UPLOAD, zap.Error(err), zap.String("filepath", name), zap.Int64("generation", attrs.Generation))
+		return op.wrapError(cause, "%s %s, %s generation %d: %s", ERROR_UPLOAD_CANCELLED, name, ERROR_REMOVING_PARTIAL_UPLOAD, attrs.Generation, err.Error())
+	}
+	op.logger.Info("cancelled upload committed, object removed", zap.String("filepath", name), zap.Int64("generation", attrs.Generation))
+	return op.wrapError(cause, "%s %s, committed generation %d removed", ERROR_UPLOAD_CANCELLED, name, attrs.Generation)
+}
+
+// strictPreflight reports whether uploads abort on pre-flight lookup failures other than not found,
+// on unless disabled
+func (cs *cloudStorageClient) strictPreflight() bool {
+	return cs.config.StrictPreflight == nil || *cs.config.StrictPreflight
+}
+
+// spoolThreshold returns configured in memory spool limit or the default
+func (cs *cloudStorageClient) spoolThreshold() int64 {
+	if cs.config.SpoolThreshold > 0 {
+		return cs.config.SpoolThreshold
+	}
+	return DEFAULT_SPOOL_THRESHOLD
 }
 
 func (cs *cloudStorageClient) DownloadFile(ct context.Context, file io.Writer, cfr CloudFileRequest) (int64, error) {
+	res, err := cs.Download(ct, file, cfr)
+	return res.Bytes, err
+}
+
+func (cs *cloudStorageClient) Download(ct context.Context, file io.Writer, cfr CloudFileRequest) (DownloadResult, error) {
+	cfr, err := cs.request(ct, cfr)
+	if err != nil {
+		return DownloadResult{}, err
+	}
 	if cfr.file == "" {
-		return 0, ErrFileNameMissing
+		return DownloadResult{}, ErrFileNameMissing
 	}
-	fPath := cfr.file
-	if cfr.path != "" {
-		fPath = filepath.Join(cfr.path, cfr.file)
+	op := cs.startOperation(ct, "DownloadFile", cfr)
+	defer op.finish()
+	ct = op.ctx
+	fPath := op.object
+
+	// the slot is waited for before the download's timeout starts
+	release, err := op.acquireTransfer(ct)
+	if err != nil {
+		op.logger.Error(ERROR_WAITING_TRANSFER_SLOT, zap.Error(err), zap.String("filepath", fPath))
+		return DownloadResult{}, op.wrapError(err, "%s %s", ERROR_WAITING_TRANSFER_SLOT, fPath)
 	}
+	defer release()
 
-	ctx, cancel := context.WithTimeout(ct, time.Second*50)
+	ctx, cancel := context.WithTimeout(ct, DEFAULT_DOWNLOAD_TIMEOUT)
 	defer cancel()
 
 	// download an object with storage.Reader.
-	obj := cs.client.Bucket(cfr.bucket).Object(fPath)
-	attrs, err := obj.Attrs(ctx)
+	obj := cs.retrying(cs.objectHandle(cfr, fPath))
+	var attrs *storage.ObjectAttrs
+	err = op.retry(ctx, func() (err error) {
+		attrs, err = obj.Attrs(ctx)
+		return err
+	})
 	if err != nil {
-		cs.logger.Error("cloud file inaccessible", zap.Error(err), zap.String("filepath", fPath))
-		return 0, errors.WrapError(err, "cloud file inaccessible %s", fPath)
+		op.logger.Error("cloud file inaccessible", zap.Error(err), zap.String("filepath", fPath))
+		return DownloadResult{}, op.wrapError(err, "cloud file inaccessible %s", fPath)
+	}
+	op.logger.Debug("downloading cloud file", zap.String("filepath", fPath), zap.Int64("created", attrs.Created.Unix()), zap.Int64("updated", attrs.Updated.Unix()))
+	if cs.freshness(cfr, attrs) == freshnessCallerNewer {
+		op.logger.Error(ERROR_STALE_DOWNLOAD, zap.String("filepath", fPath), zap.Time("modTime", cfr.modTime), zap.Int64("knownGeneration", cfr.knownGeneration))
+		return DownloadResult{}, op.wrapError(ErrStaleDownload, "%s %s", ERROR_STALE_DOWNLOAD, fPath)
 	}
-	cs.logger.Debug("downloading cloud file", zap.String("filepath", fPath), zap.Int64("created", attrs.Created.Unix()), zap.Int64("updated", attrs.Updated.Unix()))
 
-	rc, err := obj.NewReader(ctx)
+	// gzip encoded objects are decompressed by GCS unless read compressed
+	transcoded := attrs.ContentEncoding == "gzip" && !cfr.readCompressed
+	if transcoded {
+		op.logger.Debug("cloud file will be transcoded, skipping checksum verification", zap.String("filepath", fPath))
+	}
+	if sw, ok := file.(sizedWriter); ok {
+		if err := sw.expect(attrs.Size, !transcoded); err != nil {
+			op.logger.Error(ERROR_OBJECT_TOO_LARGE, zap.Error(err), zap.String("filepath", fPath), zap.Int64("size", attrs.Size))
+			return DownloadResult{}, op.wrapError(err, "%s %s", ERROR_OBJECT_TOO_LARGE, fPath)
+		}
+	}
+
+	// pin read to the generation checksum was fetched for
+	op.startTransfer(TransferDownload)
+	op.storageClass = attrs.StorageClass
+	rc, err := op.openStarted(ctx, cfr, func(ctx context.Context) (rc *storage.Reader, err error) {
+		err = op.retry(ctx, func() (err error) {
+			rc, err = obj.Generation(attrs.Generation).ReadCompressed(cfr.readCompressed).NewReader(ctx)
+			return err
+		})
+		return rc, err
+	})
 	if err != nil {
-		cs.logger.Error("error reading cloud file", zap.Error(err), zap.String("filepath", fPath))
-		return 0, errors.WrapError(err, "error reading cloud file %s", fPath)
+		op.logger.Error("error reading cloud file", zap.Error(err), zap.String("filepath", fPath))
+		err = op.wrapError(err, "error reading cloud file %s", fPath)
+		op.endTransfer(0, err)
+		return DownloadResult{}, err
 	}
 	defer func() {
 		if err := rc.Close(); err != nil {
-			cs.logger.Error("error closing cloud file", zap.Error(err), zap.String("filepath", fPath))
+			op.logger.Error("error closing cloud file", zap.Error(err), zap.String("filepath", fPath))
 		}
 	}()
 
-	nBytes, err := io.Copy(file, rc)
+	hasher := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	w := file
+	if !transcoded {
+		w = io.MultiWriter(file, hasher)
+	}
+
+	nBytes, err := cs.buffers().copy(&countingWriter{w: w, op: op}, rc)
 	if err != nil {
-		cs.logger.Error("error copying cloud file", zap.Error(err), zap.String("filepath", fPath))
-		return 0, errors.WrapError(err, "error copying cloud file %s", fPath)
+		op.logger.Error("error copying cloud file", zap.Error(err), zap.String("filepath", fPath))
+		err = op.wrapError(err, "error copying cloud file %s", fPath)
+		op.endTransfer(nBytes, err)
+		return DownloadResult{Bytes: nBytes, RequestID: op.requestID}, err
+	}
+
+	if !transcoded && hasher.Sum32() != attrs.CRC32C {
+		op.logger.Error(ERROR_CHECKSUM_MISMATCH, zap.String("filepath", fPath), zap.Uint32("want", attrs.CRC32C), zap.Uint32("got", hasher.Sum32()))
+		err := op.wrapError(errors.NewAppError(ERROR_CHECKSUM_MISMATCH), "%s %s", ERROR_CHECKSUM_MISMATCH, fPath)
+		op.endTransfer(nBytes, err)
+		// the writer holds the mismatched content, callers discard it
+		return DownloadResult{Bytes: nBytes, RequestID: op.requestID}, err
 	}
 
-	return nBytes, nil
+	m := op.endTransfer(nBytes, nil)
+	return DownloadResult{
+		Bytes:           nBytes,
+		RequestID:       op.requestID,
+		Transcoded:      transcoded,
+		Verified:        !transcoded,
+		Attrs:           cfr.logicalAttrs(newObjectAttrs(attrs)),
+		Duration:        m.Duration,
+		TimeToFirstByte: m.TimeToFirstByte,
+		BytesPerSecond:  m.BytesPerSecond,
+	}, nil
 }
 
 func (cs *cloudStorageClient) ListObjects(ctx context.Context, req CloudFileRequest) ([]string, error) {
+	req, err := cs.prefixRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
 	if req.bucket == "" {
 		return nil, ErrBucketNameMissing
 	}
+	op := cs.startOperation(ctx, "ListObjects", req)
+	defer op.finish()
+	ctx = op.ctx
 
-	bucket := cs.client.Bucket(req.bucket)
-	it := bucket.Objects(ctx, nil)
+	bucket := cs.bucketHandle(req)
+	it := bucket.Objects(ctx, &storage.Query{Prefix: req.scopePrefix})
 	names := []string{}
 	for {
 		objAttrs, err := it.Next()
@@ -258,66 +1239,309 @@ func (cs *cloudStorageClient) ListObjects(ctx context.Context, req CloudFileRequ
 			if err == iterator.Done {
 				break
 			} else {
-				cs.logger.Error(ERROR_LISTING_OBJECTS, zap.Error(err))
-				return names, errors.WrapError(err, ERROR_LISTING_OBJECTS)
+				op.logger.Error(ERROR_LISTING_OBJECTS, zap.Error(err), zap.Int("listed", len(names)))
+				return nil, op.wrapError(partialList(names, err), ERROR_LISTING_OBJECTS)
 			}
 		}
 		names = append(names, objAttrs.Name)
 	}
-	return names, nil
+	return req.logicalNames(names), nil
 }
 
-func (cs *cloudStorageClient) DeleteObject(ctx context.Context, req CloudFileRequest) error {
+// DeleteObject deletes the object at request's bucket & filepath, the name taken literally, wildcard
+// characters aren't expanded. Takes the delete options of DeleteObjects: WithDeleteDryRun only looks
+// the object up, WithDeleteToTrash moves it under the trash prefix, deletes are conditional on the
+// generation set with WithIfGenerationMatch. Bulk deletes share its single delete.
+func (cs *cloudStorageClient) DeleteObject(ctx context.Context, req CloudFileRequest, opts ...CloudFileRequestOption) error {
+	for _, opt := range opts {
+		opt(&req)
+	}
+	if !req.deleteDryRun {
+		if err := cs.mutation(); err != nil {
+			return err
+		}
+	}
+	req, err := cs.request(ctx, req)
+	if err != nil {
+		return err
+	}
 	if req.bucket == "" {
 		return ErrBucketNameMissing
 	}
-	if req.path == "" {
-		return ErrFilePathMissing
-	}
 	if req.file == "" {
 		return ErrFileNameMissing
 	}
 
-	bucket := cs.client.Bucket(req.bucket)
-	objName := fmt.Sprintf("%s/%s", req.path, req.file)
+	objName := req.objectPath()
+	op := cs.startOperation(ctx, "DeleteObject", req)
+	defer op.finish()
+	ctx = op.ctx
+	op.audited = op.audited && !req.deleteDryRun
+	op.object, op.generation = objName, req.generation
 
-	if err := bucket.Object(objName).Delete(ctx); err != nil {
-		cs.logger.Error(ERROR_DELETING_OBJECT, zap.Error(err))
-		return errors.WrapError(err, ERROR_DELETING_OBJECT)
+	var cond *storage.Conditions
+	if req.hasGenerationMatch {
+		c := generationConditions(req.generationMatch)
+		cond = &c
+	}
+	// dry runs & trashed deletes need the live generation
+	generation := req.generationMatch
+	if req.deleteDryRun || req.trashPrefix != "" {
+		obj := cs.bucketHandle(req).Object(objName)
+		if cond != nil {
+			obj = obj.If(*cond)
+		}
+		attrs, err := obj.Attrs(ctx)
+		if err != nil {
+			op.logger.Error(ERROR_DELETING_OBJECT, zap.Error(err))
+			return op.wrapError(err, ERROR_DELETING_OBJECT)
+		}
+		generation = attrs.Generation
+	}
+	if err := cs.deleteObject(ctx, req, objName, generation, cond); err != nil {
+		op.logger.Error(ERROR_DELETING_OBJECT, zap.Error(err))
+		return op.wrapError(err, ERROR_DELETING_OBJECT)
 	}
 	return nil
 }
 
-func (cs *cloudStorageClient) DeleteObjects(ctx context.Context, req CloudFileRequest) error {
+// deleteObject deletes the named object, conditional on given conditions when set, with request's
+// delete options: dry runs delete nothing, trashed deletes copy given generation under the trash prefix
+// & delete that generation only. Single & bulk deletes share it, so their options can't diverge.
+func (cs *cloudStorageClient) deleteObject(ctx context.Context, req CloudFileRequest, name string, generation int64, cond *storage.Conditions) error {
+	if req.deleteDryRun {
+		return nil
+	}
+	bh := cs.bucketHandle(req)
+	defer cs.invalidate(req.bucket, name)
+	if req.trashPrefix != "" {
+		trashName := req.trashName(name)
+		_, err := bh.Object(trashName).CopierFrom(bh.Object(name).Generation(generation)).Run(ctx)
+		cs.invalidate(req.bucket, trashName)
+		if err != nil {
+			return err
+		}
+		cond = &storage.Conditions{GenerationMatch: generation}
+	}
+	obj := bh.Object(name)
+	if cond != nil {
+		obj = obj.If(*cond)
+	}
+	return obj.Delete(ctx)
+}
+
+// trashRoot returns the stored prefix trashed objects are copied under
+func (cfr CloudFileRequest) trashRoot() string {
+	return cfr.scopePrefix + dirPrefix(cfr.trashPrefix)
+}
+
+// trashName returns the name the stored object is trashed under
+func (cfr CloudFileRequest) trashName(name string) string {
+	return cfr.trashRoot() + strings.TrimPrefix(name, cfr.scopePrefix)
+}
+
+func (cs *cloudStorageClient) DeleteObjects(ctx context.Context, req CloudFileRequest, opts ...CloudFileRequestOption) error {
+	_, err := cs.DeleteObjectsWithReport(ctx, req, opts...)
+	return err
+}
+
+// DeleteReport counts the objects of a bulk delete, accurate up to the point a failed or cancelled delete stopped
+type DeleteReport struct {
+	// Deleted is the number of objects deleted, directory markers included
+	Deleted int64 `json:"deleted"`
+	// Skipped is the number of kept directory markers & objects already gone when deleted
+	Skipped int64 `json:"skipped"`
+	// Failed is the number of objects that couldn't be deleted
+	Failed int64 `json:"failed"`
+	// BytesFreed is the total size of deleted objects
+	BytesFreed int64 `json:"bytes_freed"`
+	// Changed is the number of objects replaced or updated since listed, kept unless deleted unconditionally
+	Changed int64 `json:"changed"`
+}
+
+// DeleteObjectsWithReport deletes objects like DeleteObjects, returns deleted, skipped & failed counts.
+// Failed deletes don't stop the run, the first failure is returned with the report,
+// objects already gone count as skipped, not failed. Deletes are pinned to the generation listed,
+// objects replaced since, e.g. by uploads landing during long runs, are kept & counted as changed,
+// unless requested with WithUnconditionalDelete. Names ending with a slash, directory markers
+// & objects named like the prefix itself, are deleted after every child, deepest first,
+// & kept when any child delete failed or its child changed, so a failed run never orphans children & a re-run converges.
+// Cancellation stops the run. With WithCheckpointer the run resumes after the saved cursor,
+// the report counts this run only. Objects are deleted like DeleteObject's, with the same options:
+// dry runs count what they would delete & save no checkpoint, WithDeleteToTrash moves the objects
+// under the trash prefix, which isn't deleted itself. Generations set with WithIfGenerationMatch don't
+// apply, every object is pinned to its listed generation.
+func (cs *cloudStorageClient) DeleteObjectsWithReport(ctx context.Context, req CloudFileRequest, opts ...CloudFileRequestOption) (DeleteReport, error) {
+	for _, opt := range opts {
+		opt(&req)
+	}
+	if req.deleteDryRun {
+		req.checkpointer = nil
+	} else if err := cs.mutation(); err != nil {
+		return DeleteReport{}, err
+	}
+	req, err := cs.prefixRequest(ctx, req)
+	if err != nil {
+		return DeleteReport{}, err
+	}
 	if req.bucket == "" {
-		return ErrBucketNameMissing
+		return DeleteReport{}, ErrBucketNameMissing
 	}
-	bucket := cs.client.Bucket(req.bucket)
-	it := bucket.Objects(ctx, nil)
-	for {
+	op := cs.startOperation(ctx, "DeleteObjects", req)
+	defer op.finish()
+	ctx = op.ctx
+	op.audited = op.audited && !req.deleteDryRun
+	prefix := dirPrefix(req.path)
+
+	ckpt, err := loadCheckpoint(req.checkpointer, req.checkpointEvery, "DeleteObjects", req.bucket+"/"+prefix)
+	if err != nil {
+		op.logger.Error(ERROR_LOADING_CHECKPOINT, zap.Error(err))
+		return DeleteReport{}, op.wrapError(err, ERROR_DELETING_OBJECTS)
+	}
+	if cursor := ckpt.cursor(); cursor != "" {
+		op.logger.Info("resuming delete from checkpoint", zap.String("cursor", cursor))
+	}
+
+	report := DeleteReport{}
+	var firstErr error
+	// changed objects keep their parents, like failed ones
+	changed := []string{}
+	del := func(attrs *storage.ObjectAttrs) bool {
+		var cond *storage.Conditions
+		if attrs.Generation > 0 && !req.unconditionalDelete {
+			cond = &storage.Conditions{GenerationMatch: attrs.Generation}
+		}
+		err := cs.deleteObject(ctx, req, attrs.Name, attrs.Generation, cond)
+		switch {
+		case err == nil:
+			report.Deleted++
+			report.BytesFreed += attrs.Size
+		case err == storage.ErrObjectNotExist:
+			report.Skipped++
+		case isPreconditionFailed(err):
+			report.Changed++
+			changed = append(changed, attrs.Name)
+			op.logger.Info("object changed since listed, kept", zap.String("filepath", attrs.Name), zap.Int64("generation", attrs.Generation))
+			return false
+		default:
+			report.Failed++
+			op.logger.Error(ERROR_DELETING_OBJECTS, zap.Error(err), zap.String("filepath", attrs.Name))
+			if firstErr == nil {
+				firstErr = op.wrapError(err, ERROR_DELETING_OBJECTS)
+			}
+			return false
+		}
+		return true
+	}
+
+	// markers & parent objects listed by an interrupted run are removed with this run's
+	markers, parents := []*storage.ObjectAttrs{}, []*storage.ObjectAttrs{}
+	for _, name := range ckpt.markers() {
+		markers = append(markers, &storage.ObjectAttrs{Bucket: req.bucket, Name: name})
+	}
+	for _, name := range ckpt.parents() {
+		parents = append(parents, &storage.ObjectAttrs{Bucket: req.bucket, Name: name})
+	}
+	// progress is saved up to the last name processed, never past a failure
+	cursor := ckpt.cursor()
+	saveCheckpoint := func() {
+		if firstErr != nil {
+			return
+		}
+		if err := ckpt.save(cursor, objectNames(markers), objectNames(parents)); err != nil {
+			op.logger.Error(ERROR_SAVING_CHECKPOINT, zap.Error(err), zap.String("cursor", cursor))
+			firstErr = op.wrapError(err, ERROR_DELETING_OBJECTS)
+		}
+	}
+
+	it := cs.bucketHandle(req).Objects(ctx, &storage.Query{Prefix: prefix, StartOffset: cursor})
+	for ctx.Err() == nil {
 		objAttrs, err := it.Next()
 		if err != nil {
 			if err == iterator.Done {
 				break
 			} else {
-				cs.logger.Error(ERROR_LISTING_OBJECTS, zap.Error(err))
-				return errors.WrapError(err, ERROR_LISTING_OBJECTS)
+				op.logger.Error(ERROR_LISTING_OBJECTS, zap.Error(err))
+				saveCheckpoint()
+				if firstErr == nil {
+					firstErr = op.wrapError(err, ERROR_LISTING_OBJECTS)
+				}
+				return report, firstErr
 			}
 		}
-		cs.logger.Info("object attributes", zap.Any("objAttrs", objAttrs))
-		if err := bucket.Object(objAttrs.Name).Delete(ctx); err != nil {
-			cs.logger.Error(ERROR_DELETING_OBJECTS, zap.Error(err))
-			return errors.WrapError(err, ERROR_DELETING_OBJECTS)
+		if objAttrs.Name <= ckpt.cursor() {
+			continue
+		}
+		if req.trashPrefix != "" && strings.HasPrefix(objAttrs.Name, req.trashRoot()) {
+			// trashed objects are kept
+		} else if isDirMarker(objAttrs) {
+			markers = append(markers, objAttrs)
+		} else if strings.HasSuffix(objAttrs.Name, "/") {
+			// objects with content named like a directory, e.g. the prefix itself, go after their children
+			parents = append(parents, objAttrs)
+		} else {
+			op.logger.Info("object attributes", zap.Any("objAttrs", objAttrs))
+			del(objAttrs)
+		}
+		cursor = objAttrs.Name
+		if ckpt.due() {
+			saveCheckpoint()
 		}
 	}
-	return nil
-}
+	if err := ctx.Err(); err != nil {
+		op.logger.Error(ERROR_DELETING_OBJECTS, zap.Error(err), zap.Int64("deleted", report.Deleted))
+		saveCheckpoint()
+		if firstErr == nil {
+			firstErr = op.wrapError(err, ERROR_DELETING_OBJECTS)
+		}
+		return report, firstErr
+	}
 
-func (cs *cloudStorageClient) Close() error {
-	err := cs.client.Close()
-	if err != nil {
-		cs.logger.Error("error closing storage client", zap.Error(err))
-		return errors.WrapError(err, "error closing storage client")
+	// contents are deleted, remove parents, markers only on request, nested ones first.
+	// Parents of failed deletes are kept, a failed run never orphans children.
+	deferred := parents
+	if req.removeDirMarkers {
+		deferred = append(deferred, markers...)
+	} else {
+		report.Skipped += int64(len(markers))
 	}
-	return nil
+	if firstErr != nil {
+		report.Skipped += int64(len(deferred))
+		deferred = nil
+	}
+	if len(deferred) > 0 {
+		// parents are retried from the checkpoint if removal is interrupted
+		saveCheckpoint()
+	}
+	byName := map[string]*storage.ObjectAttrs{}
+	for _, attrs := range deferred {
+		byName[attrs.Name] = attrs
+	}
+	names := objectNames(deferred)
+	sortMarkersDeepestFirst(names)
+	failed := []string{}
+	for _, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		if hasChildIn(name, failed) || hasChildIn(name, changed) {
+			report.Skipped++
+			continue
+		}
+		op.logger.Info("removing directory marker", zap.String("filepath", name))
+		if !del(byName[name]) {
+			failed = append(failed, name)
+		}
+	}
+	if err := ctx.Err(); err != nil && firstErr == nil {
+		firstErr = op.wrapError(err, ERROR_DELETING_OBJECTS)
+	}
+	if firstErr == nil {
+		if err := ckpt.finish(); err != nil {
+			op.logger.Error(ERROR_SAVING_CHECKPOINT, zap.Error(err))
+			firstErr = op.wrapError(err, ERROR_DELETING_OBJECTS)
+		}
+	}
+	op.logger.Debug("objects deleted", zap.Int64("deleted", report.Deleted), zap.Int64("skipped", report.Skipped), zap.Int64("failed", report.Failed), zap.Int64("bytesFreed", report.BytesFreed))
+	return report, firstErr
 }
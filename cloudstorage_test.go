@@ -2,14 +2,16 @@ package cloudstorage
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/csv"
-	"encoding/json" 
-	"fmt" 
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/comfforts/errors"
 	"github.com/comfforts/logger"
@@ -46,6 +48,12 @@ func TestCloudFileStorage(t *testing.T) {
 		"file upload & delete succeeds":           testUploadDelete,
 		"file upload, download & delete succeeds": testUploadDownloadDelete,
 		"file download, succeeds":                 testDownloadFile,
+		"gzip file download, raw & transcoded":    testDownloadTranscoding,
+		"directory marker & listing succeeds":     testEnsureDirListDir,
+		"attrs & conditional metadata update":     testAttrsMetadataUpdate,
+		"object tags set, get & find":             testObjectTags,
+		"cancelled upload isn't committed":        testUploadCancelled,
+		"bucket retention & default hold":         testBucketRetention,
 	} {
 		testCfg := getTestConfig()
 		t.Run(scenario, func(t *testing.T) {
@@ -110,6 +118,49 @@ func testUploadDelete(t *testing.T, client CloudStorage, testCfg testConfig) {
 	require.NoError(t, err)
 }
 
+func testUploadCancelled(t *testing.T, client CloudStorage, testCfg testConfig) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfr, err := NewCloudFileRequest(testCfg.bucket, "testUpCancel.bin", testCfg.dir, 0, WithoutSpooling())
+	require.NoError(t, err)
+
+	// the stream ends cleanly right after the caller cancels, as a disconnected client's body might
+	r := &cancellingReader{chunk: bytes.Repeat([]byte("x"), 256*1024), after: 8, cancel: cancel}
+	_, err = client.Upload(ctx, r, cfr)
+	require.ErrorIs(t, err, context.Canceled)
+
+	exists, err := client.Exists(context.Background(), cfr)
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+// testBucketRetention sets & removes the test bucket's retention policy & default hold, against the emulator
+// only, retained objects of a real bucket outlive the test. The policy is never locked, locks are irreversible.
+func testBucketRetention(t *testing.T, client CloudStorage, testCfg testConfig) {
+	if os.Getenv("STORAGE_EMULATOR_HOST") == "" {
+		t.Skip("retention policies change the test bucket, emulator only")
+	}
+	ctx := context.Background()
+	require.NoError(t, client.SetBucketRetentionPolicy(ctx, testCfg.bucket, time.Second))
+	defer func() {
+		require.NoError(t, client.SetBucketRetentionPolicy(ctx, testCfg.bucket, 0))
+	}()
+	require.NoError(t, client.SetDefaultEventBasedHold(ctx, testCfg.bucket, true))
+	defer func() {
+		require.NoError(t, client.SetDefaultEventBasedHold(ctx, testCfg.bucket, false))
+	}()
+
+	attrs, err := client.GetBucketAttrs(ctx, testCfg.bucket)
+	require.NoError(t, err)
+	if attrs.RetentionPeriod == 0 {
+		t.Skip("emulator doesn't keep retention policies")
+	}
+	require.Equal(t, time.Second, attrs.RetentionPeriod)
+	require.False(t, attrs.RetentionLocked)
+	require.True(t, attrs.DefaultEventBasedHold)
+}
+
 func testUploadDownloadDelete(t *testing.T, client CloudStorage, testCfg testConfig) {
 	name := "testUpDoDe"
 	dataDir := fmt.Sprintf("%s/%s", testCfg.dir, "delivery")
@@ -234,6 +285,164 @@ func testDownloadFile(t *testing.T, client CloudStorage, testCfg testConfig) {
 	require.Equal(t, true, n > 0)
 }
 
+func testDownloadTranscoding(t *testing.T, client CloudStorage, testCfg testConfig) {
+	items, err := json.Marshal(createStoreJSONList())
+	require.NoError(t, err)
+
+	var gzBuf bytes.Buffer
+	gzw := gzip.NewWriter(&gzBuf)
+	_, err = gzw.Write(items)
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfr, err := NewCloudFileRequest(testCfg.bucket, "testGzip.json", testCfg.dir, 0, WithContentEncoding("gzip"))
+	require.NoError(t, err)
+
+	nUp, err := client.UploadFile(ctx, bytes.NewReader(gzBuf.Bytes()), cfr)
+	require.NoError(t, err)
+	require.Equal(t, int64(gzBuf.Len()), nUp)
+
+	// default download is transcoded, returns decompressed bytes
+	var buf bytes.Buffer
+	res, err := client.Download(ctx, &buf, cfr)
+	require.NoError(t, err)
+	require.Equal(t, true, res.Transcoded)
+	require.Equal(t, false, res.Verified)
+	require.Equal(t, int64(len(items)), res.Bytes)
+	require.Equal(t, items, buf.Bytes())
+
+	// raw download returns stored gzip bytes, verified against stored checksum
+	rawCfr, err := NewCloudFileRequest(testCfg.bucket, "testGzip.json", testCfg.dir, 0, WithRawDownload())
+	require.NoError(t, err)
+
+	buf.Reset()
+	res, err = client.Download(ctx, &buf, rawCfr)
+	require.NoError(t, err)
+	require.Equal(t, false, res.Transcoded)
+	require.Equal(t, true, res.Verified)
+	require.Equal(t, nUp, res.Bytes)
+	require.Equal(t, gzBuf.Bytes(), buf.Bytes())
+
+	err = client.DeleteObject(ctx, cfr)
+	require.NoError(t, err)
+}
+
+func testEnsureDirListDir(t *testing.T, client CloudStorage, testCfg testConfig) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := filepath.Join(testCfg.dir, "markers")
+	err := client.EnsureDir(ctx, testCfg.bucket, dir)
+	require.NoError(t, err)
+	// idempotent
+	err = client.EnsureDir(ctx, testCfg.bucket, dir+"/")
+	require.NoError(t, err)
+
+	cfr, err := NewCloudFileRequest(testCfg.bucket, "", dir, 0)
+	require.NoError(t, err)
+	listing, err := client.ListDir(ctx, cfr)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(listing.Files))
+
+	fileCfr, err := NewCloudFileRequest(testCfg.bucket, "file.json", dir, 0)
+	require.NoError(t, err)
+	_, err = client.UploadFile(ctx, bytes.NewReader([]byte("{}")), fileCfr)
+	require.NoError(t, err)
+
+	listing, err = client.ListDir(ctx, cfr)
+	require.NoError(t, err)
+	require.Equal(t, []string{dirPrefix(dir) + "file.json"}, listing.Files)
+
+	// contents deleted, marker kept
+	err = client.DeleteObjects(ctx, cfr)
+	require.NoError(t, err)
+	parent, err := NewCloudFileRequest(testCfg.bucket, "", testCfg.dir, 0)
+	require.NoError(t, err)
+	listing, err = client.ListDir(ctx, parent)
+	require.NoError(t, err)
+	require.Contains(t, listing.Dirs, dirPrefix(dir))
+
+	// marker removed on request
+	cfr, err = NewCloudFileRequest(testCfg.bucket, "", dir, 0, WithRemoveDirMarkers())
+	require.NoError(t, err)
+	err = client.DeleteObjects(ctx, cfr)
+	require.NoError(t, err)
+	listing, err = client.ListDir(ctx, parent)
+	require.NoError(t, err)
+	require.NotContains(t, listing.Dirs, dirPrefix(dir))
+}
+
+func testAttrsMetadataUpdate(t *testing.T, client CloudStorage, testCfg testConfig) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfr, err := NewCloudFileRequest(testCfg.bucket, "testAttrs.json", testCfg.dir, 0)
+	require.NoError(t, err)
+	res, err := client.Upload(ctx, bytes.NewReader([]byte("{}")), cfr)
+	require.NoError(t, err)
+	require.NotEmpty(t, res.Attrs.Etag)
+
+	attrs, err := client.GetAttrs(ctx, cfr)
+	require.NoError(t, err)
+	require.Equal(t, res.Attrs.Etag, attrs.Etag)
+	require.Equal(t, res.Attrs.Metageneration, attrs.Metageneration)
+
+	condCfr, err := NewCloudFileRequest(testCfg.bucket, "testAttrs.json", testCfg.dir, 0, WithIfMetagenerationMatch(attrs.Metageneration))
+	require.NoError(t, err)
+	updated, err := client.UpdateMetadata(ctx, condCfr, map[string]string{"state": "reviewed"})
+	require.NoError(t, err)
+	require.Equal(t, attrs.Metageneration+1, updated.Metageneration)
+
+	// stale metageneration fails
+	_, err = client.UpdateMetadata(ctx, condCfr, map[string]string{"state": "stale"})
+	require.Error(t, err)
+
+	err = client.DeleteObject(ctx, cfr)
+	require.NoError(t, err)
+}
+
+func testObjectTags(t *testing.T, client CloudStorage, testCfg testConfig) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfr, err := NewCloudFileRequest(testCfg.bucket, "testTags.json", testCfg.dir, 0, WithMetadata(map[string]string{"state": "new"}))
+	require.NoError(t, err)
+	_, err = client.Upload(ctx, bytes.NewReader([]byte("{}")), cfr)
+	require.NoError(t, err)
+
+	tags, err := client.SetObjectTags(ctx, cfr, map[string]string{"tenant": "acme", "retention": "7y"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"tenant": "acme", "retention": "7y"}, tags)
+
+	tags, err = client.SetObjectTags(ctx, cfr, map[string]string{"retention": ""})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"tenant": "acme"}, tags)
+
+	// unrelated metadata preserved
+	attrs, err := client.GetAttrs(ctx, cfr)
+	require.NoError(t, err)
+	require.Equal(t, "new", attrs.Metadata["state"])
+
+	tags, err = client.GetObjectTags(ctx, cfr)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"tenant": "acme"}, tags)
+
+	found, err := client.FindObjectsByTag(ctx, testCfg.bucket, testCfg.dir, "tenant", "acme")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, attrs.Name, found[0].Name)
+
+	found, err = client.FindObjectsByTag(ctx, testCfg.bucket, testCfg.dir, "tenant", "other")
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	err = client.DeleteObject(ctx, cfr)
+	require.NoError(t, err)
+}
+
 func createJSONFile(dir, name string) (string, error) {
 	fPath := fmt.Sprintf("%s.json", name)
 	if dir != "" {
@@ -0,0 +1,77 @@
+package cloudstoragetest
+
+import (
+	"bytes"
+	"testing"
+)
+
+// AssertObjectExists checks the named object exists
+func AssertObjectExists(t testing.TB, b Backend, bucket, name string) bool {
+	t.Helper()
+	_, ok, err := b.ReadObject(bucket, name)
+	if err != nil {
+		t.Errorf("reading %s/%s: %v", bucket, name, err)
+		return false
+	}
+	if !ok {
+		t.Errorf("object %s/%s missing", bucket, name)
+	}
+	return ok
+}
+
+// AssertObjectMissing checks the named object doesn't exist
+func AssertObjectMissing(t testing.TB, b Backend, bucket, name string) bool {
+	t.Helper()
+	_, ok, err := b.ReadObject(bucket, name)
+	if err != nil {
+		t.Errorf("reading %s/%s: %v", bucket, name, err)
+		return false
+	}
+	if ok {
+		t.Errorf("object %s/%s exists", bucket, name)
+	}
+	return !ok
+}
+
+// AssertObjectContent checks the named object exists with given content
+func AssertObjectContent(t testing.TB, b Backend, bucket, name string, want []byte) bool {
+	t.Helper()
+	data, ok, err := b.ReadObject(bucket, name)
+	switch {
+	case err != nil:
+		t.Errorf("reading %s/%s: %v", bucket, name, err)
+		return false
+	case !ok:
+		t.Errorf("object %s/%s missing", bucket, name)
+		return false
+	case !bytes.Equal(data, want):
+		t.Errorf("object %s/%s content differs, %d bytes, want %d", bucket, name, len(data), len(want))
+		return false
+	}
+	return true
+}
+
+// AssertManifest checks every object of the manifest exists with its content
+func AssertManifest(t testing.TB, b Backend, m Manifest) bool {
+	t.Helper()
+	ok := true
+	for _, obj := range m.Objects {
+		ok = AssertObjectContent(t, b, m.Bucket, obj.Name, obj.Content) && ok
+	}
+	return ok
+}
+
+// AssertPrefixEmpty checks no object is left under given prefix
+func AssertPrefixEmpty(t testing.TB, b Backend, bucket, prefix string) bool {
+	t.Helper()
+	names, err := b.ListNames(bucket, prefix)
+	if err != nil {
+		t.Errorf("listing %s/%s: %v", bucket, prefix, err)
+		return false
+	}
+	if len(names) > 0 {
+		t.Errorf("prefix %s/%s holds %d objects: %v", bucket, prefix, len(names), names)
+		return false
+	}
+	return true
+}
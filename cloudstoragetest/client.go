@@ -0,0 +1,62 @@
+package cloudstoragetest
+
+import (
+	"context"
+	"io"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// clientBackend is a backend of a storage client, e.g. of the emulator at STORAGE_EMULATOR_HOST
+type clientBackend struct {
+	ctx    context.Context
+	client *storage.Client
+}
+
+// NewClientBackend returns a backend writing through given storage client, objects' update time
+// is the service's write time
+func NewClientBackend(ctx context.Context, client *storage.Client) Backend {
+	return &clientBackend{ctx: ctx, client: client}
+}
+
+func (cb *clientBackend) PutObject(bucket string, obj Object) error {
+	wc := cb.client.Bucket(bucket).Object(obj.Name).NewWriter(cb.ctx)
+	wc.ContentType = obj.ContentType
+	wc.Metadata = obj.Metadata
+	wc.SendCRC32C = true
+	wc.CRC32C = obj.CRC32C
+	if _, err := wc.Write(obj.Content); err != nil {
+		wc.Close()
+		return err
+	}
+	return wc.Close()
+}
+
+func (cb *clientBackend) ReadObject(bucket, name string) ([]byte, bool, error) {
+	rc, err := cb.client.Bucket(bucket).Object(name).NewReader(cb.ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	return data, err == nil, err
+}
+
+func (cb *clientBackend) ListNames(bucket, prefix string) ([]string, error) {
+	names := []string{}
+	it := cb.client.Bucket(bucket).Objects(cb.ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, attrs.Name)
+	}
+}
@@ -0,0 +1,232 @@
+// Package cloudstoragetest builds deterministic object trees for tests of cloudstorage & its consumers,
+// against the package's in process fake or the emulator, with assertion helpers.
+package cloudstoragetest
+
+import (
+	"crypto/md5"
+	"hash/crc32"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	// DEFAULT_BUCKET is the bucket trees are built in unless set with Tree.Bucket
+	DEFAULT_BUCKET = "bucket"
+	// DEFAULT_SEED is the content seed of trees unless set with Tree.Seed
+	DEFAULT_SEED int64 = 1
+)
+
+// Object is a fixture object, as written & recorded in the manifest
+type Object struct {
+	Name        string
+	Content     []byte
+	ContentType string
+	Metadata    map[string]string
+	// Updated is the object's update time, zero for the backend's write time
+	Updated time.Time
+	// CRC32C & MD5 are the content's checksums
+	CRC32C uint32
+	MD5    []byte
+}
+
+// Size returns the object's content size
+func (o Object) Size() int64 {
+	return int64(len(o.Content))
+}
+
+// FileOption sets fixture object attributes
+type FileOption func(o *Object)
+
+// WithUpdated sets the object's update time, backends not able to set it, e.g. the emulator,
+// keep their write time
+func WithUpdated(t time.Time) FileOption {
+	return func(o *Object) {
+		o.Updated = t
+	}
+}
+
+// WithContent sets the object's content instead of the seeded content
+func WithContent(data []byte) FileOption {
+	return func(o *Object) {
+		o.Content = data
+	}
+}
+
+// WithContentType sets the object's content type
+func WithContentType(contentType string) FileOption {
+	return func(o *Object) {
+		o.ContentType = contentType
+	}
+}
+
+// WithMetadata sets the object's custom metadata
+func WithMetadata(metadata map[string]string) FileOption {
+	return func(o *Object) {
+		o.Metadata = metadata
+	}
+}
+
+// Backend is the storage fixture trees are written to & asserted against
+type Backend interface {
+	// PutObject writes given object, replacing any existing one
+	PutObject(bucket string, obj Object) error
+	// ReadObject returns the content of the named object, false when missing
+	ReadObject(bucket, name string) ([]byte, bool, error)
+	// ListNames returns the names of the objects under given prefix, in name order
+	ListNames(bucket, prefix string) ([]string, error)
+}
+
+// fixtureEntry is a tree object not built yet
+type fixtureEntry struct {
+	name string
+	size int64
+	opts []FileOption
+}
+
+// Tree builds an object tree, directories are name prefixes
+type Tree struct {
+	bucket  string
+	seed    int64
+	dir     string
+	entries []fixtureEntry
+}
+
+// NewTree returns an empty tree of DEFAULT_BUCKET, seeded with DEFAULT_SEED
+func NewTree() *Tree {
+	return &Tree{bucket: DEFAULT_BUCKET, seed: DEFAULT_SEED}
+}
+
+// Bucket sets the bucket the tree is built in
+func (t *Tree) Bucket(bucket string) *Tree {
+	t.bucket = bucket
+	return t
+}
+
+// Seed sets the seed of the objects' content
+func (t *Tree) Seed(seed int64) *Tree {
+	t.seed = seed
+	return t
+}
+
+// Dir sets the directory later files are added under, relative to the tree's root, empty for the root
+func (t *Tree) Dir(path string) *Tree {
+	t.dir = strings.Trim(path, "/")
+	return t
+}
+
+// File adds a file of given size to the current directory, its content seeded pseudo random bytes
+func (t *Tree) File(name string, size int64, opts ...FileOption) *Tree {
+	t.entries = append(t.entries, fixtureEntry{name: t.path(name), size: size, opts: opts})
+	return t
+}
+
+// Marker adds the current directory's marker object, e.g. logs/2024/, empty unless WithContent
+func (t *Tree) Marker(opts ...FileOption) *Tree {
+	t.entries = append(t.entries, fixtureEntry{name: t.dir + "/", opts: opts})
+	return t
+}
+
+// path returns the name of given file in the current directory
+func (t *Tree) path(name string) string {
+	if t.dir == "" {
+		return name
+	}
+	return t.dir + "/" + name
+}
+
+// Objects returns the tree's objects, in name order, later additions of a name replacing earlier ones
+func (t *Tree) Objects() []Object {
+	byName := map[string]Object{}
+	for _, e := range t.entries {
+		obj := Object{Name: e.name, Content: Content(t.seed, e.name, e.size)}
+		for _, opt := range e.opts {
+			opt(&obj)
+		}
+		obj.CRC32C = crc32.Checksum(obj.Content, crc32.MakeTable(crc32.Castagnoli))
+		sum := md5.Sum(obj.Content)
+		obj.MD5 = sum[:]
+		byName[obj.Name] = obj
+	}
+	objects := make([]Object, 0, len(byName))
+	for _, obj := range byName {
+		objects = append(objects, obj)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return objects
+}
+
+// Build writes the tree's objects to given backend, failing the test on errors,
+// & returns the manifest of what was written
+func (t *Tree) Build(tb testing.TB, b Backend) Manifest {
+	tb.Helper()
+	m := Manifest{Bucket: t.bucket, Objects: t.Objects()}
+	for _, obj := range m.Objects {
+		if err := b.PutObject(t.bucket, obj); err != nil {
+			tb.Fatalf("writing fixture %s/%s: %v", t.bucket, obj.Name, err)
+		}
+	}
+	return m
+}
+
+// Content returns the deterministic content of the named object, seeded by name so content doesn't
+// depend on the other objects of a tree
+func Content(seed int64, name string, size int64) []byte {
+	if size <= 0 {
+		return []byte{}
+	}
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	r := rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
+	data := make([]byte, size)
+	r.Read(data)
+	return data
+}
+
+// Manifest is what a tree build wrote
+type Manifest struct {
+	Bucket string
+	// Objects are the objects written, in name order
+	Objects []Object
+}
+
+// Get returns the named object
+func (m Manifest) Get(name string) (Object, bool) {
+	i := sort.Search(len(m.Objects), func(i int) bool { return m.Objects[i].Name >= name })
+	if i < len(m.Objects) && m.Objects[i].Name == name {
+		return m.Objects[i], true
+	}
+	return Object{}, false
+}
+
+// Under returns the objects under given prefix
+func (m Manifest) Under(prefix string) []Object {
+	objects := []Object{}
+	for _, obj := range m.Objects {
+		if strings.HasPrefix(obj.Name, prefix) {
+			objects = append(objects, obj)
+		}
+	}
+	return objects
+}
+
+// Names returns the names of the objects under given prefix
+func (m Manifest) Names(prefix string) []string {
+	names := []string{}
+	for _, obj := range m.Under(prefix) {
+		names = append(names, obj.Name)
+	}
+	return names
+}
+
+// Bytes returns the content size of the objects under given prefix
+func (m Manifest) Bytes(prefix string) int64 {
+	var n int64
+	for _, obj := range m.Under(prefix) {
+		n += obj.Size()
+	}
+	return n
+}
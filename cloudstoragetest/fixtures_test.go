@@ -0,0 +1,110 @@
+package cloudstoragetest
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memBackend is an in memory backend
+type memBackend struct {
+	mu      sync.Mutex
+	objects map[string]Object
+}
+
+func (mb *memBackend) PutObject(bucket string, obj Object) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.objects[bucket+"/"+obj.Name] = obj
+	return nil
+}
+
+func (mb *memBackend) ReadObject(bucket, name string) ([]byte, bool, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	obj, ok := mb.objects[bucket+"/"+name]
+	return obj.Content, ok, nil
+}
+
+func (mb *memBackend) ListNames(bucket, prefix string) ([]string, error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	names := []string{}
+	for key := range mb.objects {
+		if name := strings.TrimPrefix(key, bucket+"/"); name != key && strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// failedTB records the failures of assertions expected to fail
+type failedTB struct {
+	testing.TB
+	failures []string
+}
+
+func (ft *failedTB) Errorf(format string, args ...interface{}) {
+	ft.failures = append(ft.failures, fmt.Sprintf(format, args...))
+}
+
+func TestTreeBuild(t *testing.T) {
+	t1 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := &memBackend{objects: map[string]Object{}}
+	m := NewTree().
+		Dir("logs/2024").File("a.json", 1024, WithUpdated(t1), WithContentType("application/json")).File("b.json", 0).
+		Dir("logs").Marker().
+		Dir("").File("readme.txt", 0, WithContent([]byte("readme"))).
+		Build(t, b)
+
+	require.Equal(t, DEFAULT_BUCKET, m.Bucket)
+	require.Equal(t, []string{"logs/", "logs/2024/a.json", "logs/2024/b.json", "readme.txt"}, m.Names(""))
+	a, ok := m.Get("logs/2024/a.json")
+	require.True(t, ok)
+	require.Equal(t, int64(1024), a.Size())
+	require.Equal(t, t1, a.Updated)
+	require.Equal(t, "application/json", a.ContentType)
+	require.Len(t, a.MD5, 16)
+	require.Equal(t, int64(1030), m.Bytes(""))
+	require.Equal(t, int64(1024), m.Bytes("logs/2024/"))
+	_, ok = m.Get("logs/2024")
+	require.False(t, ok)
+
+	// content is stable across builds & independent of the other objects
+	again := NewTree().Dir("logs/2024").File("a.json", 1024).Objects()
+	require.Equal(t, a.Content, again[0].Content)
+	require.Equal(t, a.CRC32C, again[0].CRC32C)
+	reseeded := NewTree().Seed(2).Dir("logs/2024").File("a.json", 1024).Objects()
+	require.False(t, bytes.Equal(a.Content, reseeded[0].Content))
+	require.False(t, bytes.Equal(Content(DEFAULT_SEED, "x", 64), Content(DEFAULT_SEED, "y", 64)))
+
+	require.True(t, AssertManifest(t, b, m))
+	require.True(t, AssertObjectExists(t, b, "bucket", "logs/"))
+	require.True(t, AssertObjectContent(t, b, "bucket", "readme.txt", []byte("readme")))
+	require.True(t, AssertObjectMissing(t, b, "bucket", "logs/2023/c.json"))
+	require.True(t, AssertPrefixEmpty(t, b, "bucket", "logs/2023/"))
+}
+
+func TestAssertionFailures(t *testing.T) {
+	b := &memBackend{objects: map[string]Object{}}
+	NewTree().Bucket("other").File("a.txt", 4).Build(t, b)
+
+	ft := &failedTB{TB: t}
+	require.False(t, AssertObjectExists(ft, b, "other", "b.txt"))
+	require.False(t, AssertObjectContent(ft, b, "other", "a.txt", []byte("aaaa")))
+	require.False(t, AssertObjectMissing(ft, b, "other", "a.txt"))
+	require.False(t, AssertPrefixEmpty(ft, b, "other", ""))
+	require.Equal(t, []string{
+		"object other/b.txt missing",
+		"object other/a.txt content differs, 4 bytes, want 4",
+		"object other/a.txt exists",
+		"prefix other/ holds 1 objects: [a.txt]",
+	}, ft.failures)
+}
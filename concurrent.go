@@ -0,0 +1,73 @@
+package cloudstorage
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+// WithDetectConcurrentWrite reports, with UploadResult.ConcurrentWriteDetected, another write of the object
+// landing between the generation observed before the upload & the upload's commit.
+// The upload isn't failed, last writer wins, WithIfGenerationMatch rejects such writes instead.
+// Writes landing while content is sent are always detected, writes racing the commit itself
+// only in versioned buckets, where the generation the upload replaced is listed.
+func WithDetectConcurrentWrite() CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.detectConcurrentWrite = true
+	}
+}
+
+// generationChanged reports whether the stored generation of the object isn't the observed one,
+// zero observed generation for an absent object. Lookup errors report no change.
+func (cs *cloudStorageClient) generationChanged(ctx context.Context, op *operation, cfr CloudFileRequest, name string, observed int64) bool {
+	var current int64
+	attrs, err := cs.bucketHandle(cfr).Object(name).Attrs(ctx)
+	switch {
+	case err == nil:
+		current = attrs.Generation
+	case err != storage.ErrObjectNotExist:
+		op.logger.Debug("error checking concurrent write", zap.Error(err), zap.String("filepath", name))
+		return false
+	}
+	return current != observed
+}
+
+// predecessor returns the newest generation of the object older than given one,
+// false when none is listed, e.g. in unversioned buckets. Listing errors report none.
+func (cs *cloudStorageClient) predecessor(ctx context.Context, op *operation, cfr CloudFileRequest, name string, generation int64) (int64, bool) {
+	var prev int64
+	found := false
+	it := cs.bucketHandle(cfr).Objects(ctx, &storage.Query{Prefix: name, Versions: true})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return prev, found
+		}
+		if err != nil {
+			op.logger.Debug("error listing concurrent write versions", zap.Error(err), zap.String("filepath", name))
+			return 0, false
+		}
+		if attrs.Name == name && attrs.Generation < generation && attrs.Generation > prev {
+			prev, found = attrs.Generation, true
+		}
+	}
+}
+
+// concurrentWrite reports whether another write landed between the observed generation & the committed one,
+// landed reports a write seen before the commit. Detection is logged at info level, the logger
+// has no warning level, & doesn't fail the upload.
+func (cs *cloudStorageClient) concurrentWrite(ctx context.Context, op *operation, cfr CloudFileRequest, name string, observed, generation int64, landed bool) bool {
+	prev, listed := cs.predecessor(ctx, op, cfr, name, generation)
+	if !landed && !(listed && prev != observed) {
+		return false
+	}
+	op.logger.Info("concurrent write detected, last writer wins",
+		zap.String("filepath", name),
+		zap.Int64("observed", observed),
+		zap.Int64("predecessor", prev),
+		zap.Int64("generation", generation),
+	)
+	return true
+}
@@ -0,0 +1,115 @@
+package cloudstorage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// interleavedReader runs write once, when the upload content is fully read
+type interleavedReader struct {
+	r     io.Reader
+	once  sync.Once
+	write func()
+}
+
+func (ir *interleavedReader) Read(p []byte) (int, error) {
+	n, err := ir.r.Read(p)
+	if err == io.EOF {
+		ir.once.Do(ir.write)
+	}
+	return n, err
+}
+
+// writeOnCommit has the fake store a competing generation of given object right before the upload's commit
+func writeOnCommit(f *fakeGCS, bucket, name string) *bool {
+	written := false
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/upload/") && !written {
+			written = true
+			f.put(bucket, name, []byte("replica"), nil)
+		}
+		return 0
+	}
+	return &written
+}
+
+func TestUploadDetectConcurrentWrite(t *testing.T) {
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "a.txt", "data", 0, WithDetectConcurrentWrite())
+	require.NoError(t, err)
+
+	t.Run("no concurrent write", func(t *testing.T) {
+		f := newFakeGCS()
+		f.versioned = true
+		cs := newFakeClient(t, f)
+		res, err := cs.Upload(ctx, strings.NewReader("first"), cfr)
+		require.NoError(t, err)
+		require.False(t, res.ConcurrentWriteDetected)
+		require.Zero(t, res.ObservedGeneration)
+
+		res2, err := cs.Upload(ctx, strings.NewReader("second"), cfr)
+		require.NoError(t, err)
+		require.False(t, res2.ConcurrentWriteDetected)
+		require.Equal(t, res.Attrs.Generation, res2.ObservedGeneration)
+	})
+
+	t.Run("write while sending", func(t *testing.T) {
+		f := newFakeGCS()
+		f.put("bucket", "data/a.txt", []byte("v1"), nil)
+		_, before, _ := f.get("bucket", "data/a.txt")
+		cs := newFakeClient(t, f)
+		r := &interleavedReader{r: strings.NewReader("ours"), write: func() {
+			f.put("bucket", "data/a.txt", []byte("replica"), nil)
+		}}
+		// unspooled, the reader is read while content is sent
+		streamed, err := NewCloudFileRequest("bucket", "a.txt", "data", 0, WithDetectConcurrentWrite(), WithoutSpooling())
+		require.NoError(t, err)
+		res, err := cs.Upload(ctx, r, streamed)
+		require.NoError(t, err, "last writer wins, the upload isn't failed")
+		require.True(t, res.ConcurrentWriteDetected)
+		require.Equal(t, before.Generation, res.ObservedGeneration)
+		data, _, _ := f.get("bucket", "data/a.txt")
+		require.Equal(t, "ours", string(data))
+	})
+
+	t.Run("write racing commit, versioned", func(t *testing.T) {
+		f := newFakeGCS()
+		f.versioned = true
+		f.put("bucket", "data/a.txt", []byte("v1"), nil)
+		f.put("bucket", "data/a.txt.bak", []byte("unrelated"), nil)
+		cs := newFakeClient(t, f)
+		written := writeOnCommit(f, "bucket", "data/a.txt")
+		res, err := cs.Upload(ctx, strings.NewReader("ours"), cfr)
+		require.NoError(t, err)
+		require.True(t, *written)
+		require.True(t, res.ConcurrentWriteDetected)
+	})
+
+	t.Run("write racing commit, unversioned", func(t *testing.T) {
+		// the replaced generation isn't listed, the race goes unnoticed
+		f := newFakeGCS()
+		f.put("bucket", "data/a.txt", []byte("v1"), nil)
+		cs := newFakeClient(t, f)
+		written := writeOnCommit(f, "bucket", "data/a.txt")
+		res, err := cs.Upload(ctx, strings.NewReader("ours"), cfr)
+		require.NoError(t, err)
+		require.True(t, *written)
+		require.False(t, res.ConcurrentWriteDetected)
+	})
+
+	t.Run("detection off", func(t *testing.T) {
+		f := newFakeGCS()
+		f.versioned = true
+		cs := newFakeClient(t, f)
+		writeOnCommit(f, "bucket", "data/a.txt")
+		res, err := cs.Upload(ctx, strings.NewReader("ours"), CloudFileRequest{bucket: "bucket", path: "data", file: "a.txt"})
+		require.NoError(t, err)
+		require.False(t, res.ConcurrentWriteDetected)
+	})
+}
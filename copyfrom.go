@@ -0,0 +1,223 @@
+package cloudstorage
+
+import (
+	"context"
+	"hash"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_COPYING_OBJECT string = "error copying cloud file"
+)
+
+// DEFAULT_COPY_MAX_RESUMES is the default number of times a broken source stream is resumed
+const DEFAULT_COPY_MAX_RESUMES = 3
+
+// CopyOptions configure CopyFrom
+type CopyOptions struct {
+	// Progress, when set, is called with bytes copied & total bytes as the stream is copied
+	Progress func(copied, total int64)
+	// MaxResumes is the number of times a broken source stream is resumed with a range read
+	MaxResumes int
+}
+
+// CopyOption sets copy options
+type CopyOption func(o *CopyOptions)
+
+// WithCopyProgress sets the copy progress callback
+func WithCopyProgress(fn func(copied, total int64)) CopyOption {
+	return func(o *CopyOptions) {
+		o.Progress = fn
+	}
+}
+
+// WithMaxResumes sets the number of times a broken source stream is resumed
+func WithMaxResumes(n int) CopyOption {
+	return func(o *CopyOptions) {
+		o.MaxResumes = n
+	}
+}
+
+// CopyResult is the result of a successful copy, failed streamed copies return the bytes read from the source
+type CopyResult struct {
+	// Bytes is the number of bytes copied, Bytes > 0 with an error is a partial transfer that wasn't committed
+	Bytes int64
+	// ServerSide is set when the service copied the object, no bytes were streamed
+	ServerSide bool
+	// Resumes is the number of times the source stream was resumed
+	Resumes int
+	// Attrs are the copied object's attributes
+	Attrs *ObjectAttrs
+	// Duration is the time from starting the copy to commit, source open excluded for streamed copies
+	Duration time.Duration
+	// BytesPerSecond is the copy throughput over Duration
+	BytesPerSecond float64
+}
+
+// resumingReader reads the source object, reopening it at the current offset when the stream breaks
+type resumingReader struct {
+	ctx        context.Context
+	source     CloudStorage
+	cfr        CloudFileRequest
+	r          *ObjectReader
+	off        int64
+	total      int64
+	resumes    int
+	maxResumes int
+	hasher     hash.Hash32
+	progress   func(copied, total int64)
+	logger     func(err error)
+}
+
+func (rr *resumingReader) Read(p []byte) (int, error) {
+	for {
+		n, err := rr.r.Read(p)
+		if n > 0 {
+			rr.off += int64(n)
+			rr.hasher.Write(p[:n])
+			if rr.progress != nil {
+				rr.progress(rr.off, rr.total)
+			}
+		}
+		if err == nil || err == io.EOF || n > 0 {
+			return n, err
+		}
+		if rr.resumes >= rr.maxResumes || rr.ctx.Err() != nil {
+			return n, err
+		}
+		rr.logger(err)
+		rr.r.Close()
+
+		cfr := rr.cfr
+		WithReadOffset(rr.off)(&cfr)
+		r, oErr := rr.source.OpenReader(rr.ctx, cfr)
+		if oErr != nil {
+			return 0, oErr
+		}
+		rr.r = r
+		rr.resumes++
+	}
+}
+
+// CopyFrom copies the source client's src object to dst in this client's storage.
+// The service copies the object when both clients share the storage client,
+// otherwise the content is streamed, pinned to the source generation,
+// resumed with range reads when the stream breaks & verified with the source's CRC32C.
+func (cs *cloudStorageClient) CopyFrom(ctx context.Context, source CloudStorage, src, dst CloudFileRequest, opts ...CopyOption) (CopyResult, error) {
+	if err := cs.mutation(); err != nil {
+		return CopyResult{}, err
+	}
+	src, err := cs.request(ctx, src)
+	if err != nil {
+		return CopyResult{}, err
+	}
+	dst, err = cs.request(ctx, dst)
+	if err != nil {
+		return CopyResult{}, err
+	}
+	for _, cfr := range []CloudFileRequest{src, dst} {
+		if cfr.bucket == "" {
+			return CopyResult{}, ErrBucketNameMissing
+		}
+		if cfr.file == "" {
+			return CopyResult{}, ErrFileNameMissing
+		}
+	}
+	cOpts := CopyOptions{MaxResumes: DEFAULT_COPY_MAX_RESUMES}
+	for _, opt := range opts {
+		opt(&cOpts)
+	}
+	op := cs.startOperation(ctx, "CopyFrom", dst)
+	defer op.finish()
+	ctx = op.ctx
+	srcPath := src.objectPath()
+
+	if sc, ok := source.(*cloudStorageClient); ok && sc.client == cs.client {
+		srcObj := cs.objectHandle(src, srcPath)
+		start := cs.now()
+		copier := cs.bucketHandle(dst).Object(op.object).CopierFrom(srcObj)
+		copier.DestinationKMSKeyName = dst.kmsKeyName
+		attrs, err := copier.Run(ctx)
+		cs.invalidate(dst.bucket, op.object)
+		if err != nil {
+			op.logger.Error(ERROR_COPYING_OBJECT, zap.Error(err), zap.String("source", srcPath), zap.String("filepath", op.object))
+			return CopyResult{}, op.wrapError(err, "%s %s", ERROR_COPYING_OBJECT, srcPath)
+		}
+		op.bytes, op.generation = attrs.Size, attrs.Generation
+		d := cs.since(start)
+		return CopyResult{
+			Bytes:          attrs.Size,
+			ServerSide:     true,
+			Attrs:          dst.logicalAttrs(newObjectAttrs(attrs)),
+			Duration:       d,
+			BytesPerSecond: bytesPerSecond(attrs.Size, d),
+		}, nil
+	}
+
+	// stored bytes are copied, gzip content isn't transcoded
+	WithRawDownload()(&src)
+	r, err := source.OpenReader(ctx, src)
+	if err != nil {
+		op.logger.Error(ERROR_COPYING_OBJECT, zap.Error(err), zap.String("source", srcPath), zap.String("filepath", op.object))
+		return CopyResult{}, op.wrapError(err, "%s %s", ERROR_COPYING_OBJECT, srcPath)
+	}
+	srcAttrs := r.Attrs
+	// resumed reads stay on the copied generation
+	WithGeneration(srcAttrs.Generation)(&src)
+
+	rr := &resumingReader{
+		ctx:        ctx,
+		source:     source,
+		cfr:        src,
+		r:          r,
+		total:      srcAttrs.Size,
+		maxResumes: cOpts.MaxResumes,
+		hasher:     crc32.New(crc32.MakeTable(crc32.Castagnoli)),
+		progress:   cOpts.Progress,
+		logger: func(err error) {
+			op.logger.Info("source stream broken, resuming", zap.Error(err), zap.String("source", srcPath))
+		},
+	}
+	defer func() {
+		rr.r.Close()
+	}()
+
+	// source attributes carry over unless set on the destination request,
+	// the service verifies the source CRC32C on commit
+	if dst.contentType == "" {
+		dst.contentType = srcAttrs.ContentType
+	}
+	if dst.contentEncoding == "" {
+		dst.contentEncoding = srcAttrs.ContentEncoding
+	}
+	if dst.metadata == nil {
+		dst.metadata = srcAttrs.Metadata
+	}
+	WithCRC32C(srcAttrs.CRC32C)(&dst)
+	WithSize(srcAttrs.Size)(&dst)
+
+	res, err := cs.Upload(WithRequestID(ctx, op.requestID), rr, dst)
+	op.bytes = rr.off
+	if err != nil {
+		op.logger.Error(ERROR_COPYING_OBJECT, zap.Error(err), zap.String("source", srcPath), zap.String("filepath", op.object), zap.Int("resumes", rr.resumes))
+		return CopyResult{Bytes: rr.off, Resumes: rr.resumes}, op.wrapError(err, "%s %s", ERROR_COPYING_OBJECT, srcPath)
+	}
+	if rr.hasher.Sum32() != srcAttrs.CRC32C {
+		op.logger.Error(ERROR_CHECKSUM_MISMATCH, zap.String("source", srcPath), zap.Uint32("want", srcAttrs.CRC32C), zap.Uint32("got", rr.hasher.Sum32()))
+		return CopyResult{Bytes: rr.off, Resumes: rr.resumes}, op.wrapError(errors.NewAppError(ERROR_CHECKSUM_MISMATCH), "%s %s", ERROR_CHECKSUM_MISMATCH, srcPath)
+	}
+	op.generation = res.Attrs.Generation
+	op.logger.Debug("cloud file copied", zap.String("source", srcPath), zap.String("filepath", op.object), zap.Int64("bytes", res.Bytes), zap.Int("resumes", rr.resumes))
+	return CopyResult{
+		Bytes:          res.Bytes,
+		Resumes:        rr.resumes,
+		Attrs:          res.Attrs,
+		Duration:       res.Duration,
+		BytesPerSecond: res.BytesPerSecond,
+	}, nil
+}
@@ -0,0 +1,119 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failSDKReopens fails every other ranged content read, starting with the first,
+// the storage SDK reopens broken streams with ranged reads, failing those breaks the stream for good
+func failSDKReopens() func(r *http.Request) int {
+	var ranged int32
+	return func(r *http.Request) int {
+		if strings.HasPrefix(r.URL.Path, "/storage/") || r.Header.Get("Range") == "" {
+			return 0
+		}
+		if atomic.AddInt32(&ranged, 1)%2 == 1 {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+}
+
+func TestCopyFrom(t *testing.T) {
+	content := bytes.Repeat([]byte("copy across projects "), 50*1024)
+	ctx := context.Background()
+
+	src, err := NewCloudFileRequest("src", "file.bin", "path", 0)
+	require.NoError(t, err)
+	dst, err := NewCloudFileRequest("dst", "file.bin", "copied", 0)
+	require.NoError(t, err)
+
+	t.Run("streamed across clients", func(t *testing.T) {
+		srcFake, dstFake := newFakeGCS(), newFakeGCS()
+		srcFake.put("src", "path/file.bin", content, map[string]string{"owner": "acme"})
+		srcCS, dstCS := newFakeClient(t, srcFake), newFakeClient(t, dstFake)
+
+		var last, total int64
+		res, err := dstCS.CopyFrom(ctx, srcCS, src, dst, WithCopyProgress(func(copied, size int64) {
+			last, total = copied, size
+		}))
+		require.NoError(t, err)
+		require.False(t, res.ServerSide)
+		require.Equal(t, int64(len(content)), res.Bytes)
+		require.Equal(t, 0, res.Resumes)
+		require.Equal(t, int64(len(content)), last)
+		require.Equal(t, int64(len(content)), total)
+
+		data, attrs, ok := dstFake.get("dst", "copied/file.bin")
+		require.True(t, ok)
+		require.Equal(t, content, data)
+		require.Equal(t, "acme", attrs.Metadata["owner"])
+		_, srcAttrs, _ := srcFake.get("src", "path/file.bin")
+		require.Equal(t, srcAttrs.Crc32c, attrs.Crc32c)
+	})
+
+	t.Run("broken stream resumed", func(t *testing.T) {
+		srcFake, dstFake := newFakeGCS(), newFakeGCS()
+		srcFake.put("src", "path/file.bin", content, nil)
+		srcFake.cutReads = 2
+		srcFake.fail = failSDKReopens()
+		srcCS, dstCS := newFakeClient(t, srcFake), newFakeClient(t, dstFake)
+
+		res, err := dstCS.CopyFrom(ctx, srcCS, src, dst)
+		require.NoError(t, err)
+		require.Equal(t, 2, res.Resumes)
+		data, _, ok := dstFake.get("dst", "copied/file.bin")
+		require.True(t, ok)
+		require.Equal(t, content, data)
+	})
+
+	t.Run("resumes exhausted", func(t *testing.T) {
+		srcFake, dstFake := newFakeGCS(), newFakeGCS()
+		srcFake.put("src", "path/file.bin", content, nil)
+		srcFake.cutReads = 3
+		srcFake.fail = failSDKReopens()
+		srcCS, dstCS := newFakeClient(t, srcFake), newFakeClient(t, dstFake)
+
+		_, err := dstCS.CopyFrom(ctx, srcCS, src, dst, WithMaxResumes(1))
+		require.Error(t, err)
+		_, _, ok := dstFake.get("dst", "copied/file.bin")
+		require.False(t, ok)
+	})
+
+	t.Run("server side with shared client", func(t *testing.T) {
+		f := newFakeGCS()
+		f.put("src", "path/file.bin", content, nil)
+		cs := newFakeClient(t, f)
+
+		res, err := cs.CopyFrom(ctx, cs, src, dst)
+		require.NoError(t, err)
+		require.True(t, res.ServerSide)
+		require.Equal(t, int64(len(content)), res.Bytes)
+		data, _, ok := f.get("dst", "copied/file.bin")
+		require.True(t, ok)
+		require.Equal(t, content, data)
+	})
+}
+
+func TestCopyFromKMSKey(t *testing.T) {
+	f := newFakeGCS()
+	f.put("src", "path/file.bin", []byte("data"), nil)
+	keys := recordKMSKeys(f)
+	cs := newFakeClient(t, f)
+	src, err := NewCloudFileRequest("src", "file.bin", "path", 0)
+	require.NoError(t, err)
+	dst, err := NewCloudFileRequest("dst", "file.bin", "copied", 0, WithKMSKey(testKMSKey))
+	require.NoError(t, err)
+
+	res, err := cs.CopyFrom(context.Background(), cs, src, dst)
+	require.NoError(t, err)
+	require.True(t, res.ServerSide)
+	require.Equal(t, []string{testKMSKey}, keys["rewrite"])
+}
@@ -0,0 +1,417 @@
+package cloudstorage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/comfforts/logger"
+	"go.uber.org/zap"
+)
+
+const ERROR_WRITING_COST_REPORT string = "error writing cost report"
+
+// OperationClass is the pricing class of a storage API request
+type OperationClass string
+
+const (
+	// OperationClassA are the higher priced requests, writes, listings & bucket changes
+	OperationClassA OperationClass = "A"
+	// OperationClassB are the lower priced requests, object & bucket reads
+	OperationClassB OperationClass = "B"
+	// OperationFree are requests without operation charge, deletes
+	OperationFree OperationClass = "free"
+)
+
+// apiOperationClasses are the pricing classes of the storage API methods the client calls,
+// methods missing here count as class A
+var apiOperationClasses = map[string]OperationClass{
+	"objects.insert":       OperationClassA,
+	"objects.list":         OperationClassA,
+	"objects.patch":        OperationClassA,
+	"objects.update":       OperationClassA,
+	"objects.compose":      OperationClassA,
+	"objects.rewrite":      OperationClassA,
+	"objects.copy":         OperationClassA,
+	"objects.restore":      OperationClassA,
+	"buckets.insert":       OperationClassA,
+	"buckets.list":         OperationClassA,
+	"buckets.patch":        OperationClassA,
+	"buckets.update":       OperationClassA,
+	"buckets.setIamPolicy": OperationClassA,
+	"objects.get":          OperationClassB,
+	"buckets.get":          OperationClassB,
+	"buckets.getIamPolicy": OperationClassB,
+	"objects.delete":       OperationFree,
+	"buckets.delete":       OperationFree,
+}
+
+// apiOperationClass returns the pricing class of given storage API method
+func apiOperationClass(api string) OperationClass {
+	if class, ok := apiOperationClasses[api]; ok {
+		return class
+	}
+	return OperationClassA
+}
+
+// TransferDirection is the direction content bytes of a transfer moved in
+type TransferDirection string
+
+const (
+	TransferUpload   TransferDirection = "upload"
+	TransferDownload TransferDirection = "download"
+)
+
+// CostLabelFunc returns the cost attribution label of given stored object name or listed prefix,
+// e.g. the team owning the prefix, empty for unattributed objects
+type CostLabelFunc func(bucket, name string) string
+
+// FirstSegmentLabel is the default CostLabelFunc, labels names by their first path segment,
+// names without one, e.g. root objects, are unattributed
+func FirstSegmentLabel(bucket, name string) string {
+	if i := strings.IndexByte(name, '/'); i > 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// RequestMetrics describe one storage API request, retries & every request of a resumable upload included
+type RequestMetrics struct {
+	// API is the storage API method, e.g. objects.insert
+	API    string
+	Class  OperationClass
+	Bucket string
+	// Object is the object name, or the prefix of listings, empty for bucket requests
+	Object string
+	Label  string
+	// Status is the response status, zero when the request failed without response
+	Status int
+	// RequestBytes & ResponseBytes are the body sizes sent & received
+	RequestBytes  int64
+	ResponseBytes int64
+	// Err is the transport error, nil when a response was received
+	Err error
+}
+
+// RequestRecorder receives the metrics of every storage API request, implemented by metrics
+// recorders attributing costs, requests are counted below the circuit breaker, rejected ones aren't sent
+type RequestRecorder interface {
+	RecordRequest(RequestMetrics)
+}
+
+// costLabel returns the cost label of given stored name with the configured label func
+func (cs *cloudStorageClient) costLabel(bucket, name string) string {
+	return labelWith(cs.config.CostLabel)(bucket, name)
+}
+
+// labelWith returns given label func, defaulted
+func labelWith(label CostLabelFunc) CostLabelFunc {
+	if label == nil {
+		return FirstSegmentLabel
+	}
+	return label
+}
+
+// apiRequest returns the storage API method, bucket & object, or listed prefix, of given request,
+// JSON API paths are /storage/v1/b/{bucket}/o/{object}, upload paths prefixed with /upload,
+// other paths are XML API reads of /{bucket}/{object}
+func apiRequest(r *http.Request) (api, bucket, object string) {
+	p := r.URL.EscapedPath()
+	i := strings.Index(p, "/storage/v1/")
+	if i < 0 {
+		segs := strings.SplitN(strings.TrimPrefix(p, "/"), "/", 2)
+		bucket, _ = url.PathUnescape(segs[0])
+		if len(segs) == 2 {
+			object, _ = url.PathUnescape(segs[1])
+		}
+		return "objects.get", bucket, object
+	}
+	upload := strings.HasSuffix(p[:i], "/upload")
+	segs := strings.Split(strings.Trim(p[i+len("/storage/v1/"):], "/"), "/")
+	for j := range segs {
+		segs[j], _ = url.PathUnescape(segs[j])
+	}
+	if segs[0] != "b" {
+		return "", "", ""
+	}
+	method := map[string]string{
+		http.MethodGet:    "get",
+		http.MethodHead:   "get",
+		http.MethodPatch:  "patch",
+		http.MethodPut:    "update",
+		http.MethodDelete: "delete",
+		http.MethodPost:   "insert",
+	}[r.Method]
+	switch {
+	case len(segs) == 1:
+		// bucket listings & creations
+		if method == "get" {
+			return "buckets.list", "", ""
+		}
+		return "buckets." + method, "", ""
+	case len(segs) == 2:
+		return "buckets." + method, segs[1], ""
+	case len(segs) == 3 && segs[2] == "iam":
+		if method == "get" {
+			return "buckets.getIamPolicy", segs[1], ""
+		}
+		return "buckets.setIamPolicy", segs[1], ""
+	case len(segs) == 3 && segs[2] == "o":
+		if upload {
+			// resumable session requests are uploads of the session's object
+			return "objects.insert", segs[1], r.URL.Query().Get("name")
+		}
+		if method == "get" {
+			return "objects.list", segs[1], r.URL.Query().Get("prefix")
+		}
+		return "objects." + method, segs[1], r.URL.Query().Get("name")
+	case len(segs) == 3:
+		return "buckets." + method, segs[1], ""
+	case len(segs) == 4:
+		return "objects." + method, segs[1], segs[3]
+	}
+	// object methods, e.g. compose, rewriteTo, copyTo & restore
+	switch segs[4] {
+	case "rewriteTo":
+		return "objects.rewrite", segs[1], segs[3]
+	case "copyTo":
+		return "objects.copy", segs[1], segs[3]
+	}
+	return "objects." + segs[4], segs[1], segs[3]
+}
+
+// accountingTransport reports every request with its class, label & body sizes
+type accountingTransport struct {
+	base     http.RoundTripper
+	recorder RequestRecorder
+	label    CostLabelFunc
+	// sessions are the object names of resumable upload sessions, by upload ID, their chunk requests carry none
+	sessions sync.Map
+}
+
+func (t *accountingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	api, bucket, object := apiRequest(r)
+	uploadID := r.URL.Query().Get("upload_id")
+	if uploadID != "" && object == "" {
+		if name, ok := t.sessions.Load(uploadID); ok {
+			object = name.(string)
+		}
+	}
+	m := RequestMetrics{API: api, Class: apiOperationClass(api), Bucket: bucket, Object: object, Label: t.label(bucket, object)}
+	var sent *countingBody
+	if r.Body != nil {
+		// round trippers don't modify the caller's request
+		sent = &countingBody{ReadCloser: r.Body}
+		r = r.Clone(r.Context())
+		r.Body = sent
+	}
+
+	resp, err := t.base.RoundTrip(r)
+	if sent != nil {
+		m.RequestBytes = sent.n
+	}
+	if err != nil {
+		m.Err = err
+		t.recorder.RecordRequest(m)
+		return nil, err
+	}
+	switch {
+	case r.URL.Query().Get("uploadType") == "resumable" && uploadID == "" && resp.StatusCode == http.StatusOK:
+		if loc, err := url.Parse(resp.Header.Get("Location")); err == nil && loc.Query().Get("upload_id") != "" {
+			t.sessions.Store(loc.Query().Get("upload_id"), object)
+		}
+	case uploadID != "" && resp.StatusCode < 300:
+		// the session's last chunk is committed
+		t.sessions.Delete(uploadID)
+	}
+	m.Status = resp.StatusCode
+	body := &countingBody{ReadCloser: resp.Body}
+	body.onClose = func() {
+		m.ResponseBytes = body.n
+		t.recorder.RecordRequest(m)
+	}
+	resp.Body = body
+	return resp, nil
+}
+
+// countingBody counts the bytes read from a request or response body, calls onClose once when closed
+type countingBody struct {
+	io.ReadCloser
+	n       int64
+	once    sync.Once
+	onClose func()
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.onClose != nil {
+		b.once.Do(b.onClose)
+	}
+	return err
+}
+
+// withAccounting returns a copy of given HTTP client reporting requests to given recorder
+func withAccounting(hc *http.Client, recorder RequestRecorder, label CostLabelFunc) *http.Client {
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped := *hc
+	wrapped.Transport = &accountingTransport{base: base, recorder: recorder, label: labelWith(label)}
+	return &wrapped
+}
+
+// LabelCost are the operations & bytes attributed to one label
+type LabelCost struct {
+	Label string `json:"label"`
+	// ClassA, ClassB & Free are the numbers of requests answered, by pricing class
+	ClassA int64 `json:"class_a"`
+	ClassB int64 `json:"class_b"`
+	Free   int64 `json:"free"`
+	// RequestBytes & ResponseBytes are the request & response body sizes sent & received
+	RequestBytes  int64 `json:"request_bytes"`
+	ResponseBytes int64 `json:"response_bytes"`
+	// Uploaded & Downloaded are the content bytes of transfers, by storage class,
+	// the empty class when the object's class wasn't known
+	Uploaded   map[string]int64 `json:"uploaded"`
+	Downloaded map[string]int64 `json:"downloaded"`
+}
+
+// CostReport are the costs accumulated over a period, grouped by label
+type CostReport struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Labels are in label order, the unattributed label first
+	Labels []LabelCost `json:"labels"`
+}
+
+// CostSink receives the periodically flushed reports of a CostAggregator
+type CostSink interface {
+	WriteCostReport(context.Context, CostReport) error
+}
+
+// CostAggregator accumulates request & transfer metrics into cost reports, set as the client's
+// Metrics recorder, or called by it, safe for concurrent use
+type CostAggregator struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	start  time.Time
+	labels map[string]*LabelCost
+}
+
+// NewCostAggregator returns an aggregator starting its first period now
+func NewCostAggregator() *CostAggregator {
+	return &CostAggregator{now: time.Now, start: time.Now(), labels: map[string]*LabelCost{}}
+}
+
+// label returns given label's costs, under the lock
+func (a *CostAggregator) label(label string) *LabelCost {
+	lc, ok := a.labels[label]
+	if !ok {
+		lc = &LabelCost{Label: label, Uploaded: map[string]int64{}, Downloaded: map[string]int64{}}
+		a.labels[label] = lc
+	}
+	return lc
+}
+
+// RecordRequest counts answered requests by class, requests failed without response aren't charged
+func (a *CostAggregator) RecordRequest(m RequestMetrics) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	lc := a.label(m.Label)
+	lc.RequestBytes += m.RequestBytes
+	lc.ResponseBytes += m.ResponseBytes
+	if m.Status == 0 {
+		return
+	}
+	switch m.Class {
+	case OperationClassA:
+		lc.ClassA++
+	case OperationClassB:
+		lc.ClassB++
+	default:
+		lc.Free++
+	}
+}
+
+// RecordTransfer sums transferred content bytes by direction & storage class, failed transfers included
+func (a *CostAggregator) RecordTransfer(m TransferMetrics) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	lc := a.label(m.Label)
+	switch m.Direction {
+	case TransferUpload:
+		lc.Uploaded[m.StorageClass] += m.Bytes
+	case TransferDownload:
+		lc.Downloaded[m.StorageClass] += m.Bytes
+	}
+}
+
+// Report returns the costs accumulated since the period started
+func (a *CostAggregator) Report() CostReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.report()
+}
+
+// Flush returns the costs accumulated since the period started & starts a new period
+func (a *CostAggregator) Flush() CostReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	report := a.report()
+	a.start, a.labels = report.End, map[string]*LabelCost{}
+	return report
+}
+
+// report returns a copy of the period's costs, under the lock
+func (a *CostAggregator) report() CostReport {
+	report := CostReport{Start: a.start, End: a.now(), Labels: []LabelCost{}}
+	for _, lc := range a.labels {
+		c := *lc
+		c.Uploaded, c.Downloaded = map[string]int64{}, map[string]int64{}
+		for class, n := range lc.Uploaded {
+			c.Uploaded[class] = n
+		}
+		for class, n := range lc.Downloaded {
+			c.Downloaded[class] = n
+		}
+		report.Labels = append(report.Labels, c)
+	}
+	sort.Slice(report.Labels, func(i, j int) bool {
+		return report.Labels[i].Label < report.Labels[j].Label
+	})
+	return report
+}
+
+// Run flushes a report to given sink every interval until the context is done, then flushes the last period.
+// Failed writes are logged to the optional logger, their reports aren't retried.
+func (a *CostAggregator) Run(ctx context.Context, interval time.Duration, sink CostSink, l logger.AppLogger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	write := func(ctx context.Context) {
+		if err := sink.WriteCostReport(ctx, a.Flush()); err != nil && l != nil {
+			l.Error(ERROR_WRITING_COST_REPORT, zap.Error(err))
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			write(context.Background())
+			return
+		case <-ticker.C:
+			write(ctx)
+		}
+	}
+}
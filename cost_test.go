@@ -0,0 +1,247 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+// newCostClient returns a fake backed client accounting its requests & transfers in given aggregator,
+// labeled with given func, the default when nil
+func newCostClient(t *testing.T, h http.Handler, a *CostAggregator, label CostLabelFunc) *cloudStorageClient {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	client, err := storage.NewClient(
+		context.Background(),
+		option.WithEndpoint(srv.URL+"/storage/v1/"),
+		option.WithHTTPClient(withAccounting(srv.Client(), a, label)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	cs := newFakeClient(t, h)
+	cs.client = client
+	cs.jsonAPI.client = withAccounting(cs.jsonAPI.client, a, label)
+	cs.config.Metrics, cs.config.CostLabel = a, label
+	return cs
+}
+
+func TestAPIRequestClasses(t *testing.T) {
+	for req, want := range map[string][3]string{
+		"GET /storage/v1/b/bucket/o?prefix=team-a%2F":                             {"objects.list", "bucket", "team-a/"},
+		"GET /storage/v1/b/bucket/o/team-a%2Fx.json":                              {"objects.get", "bucket", "team-a/x.json"},
+		"PATCH /storage/v1/b/bucket/o/team-a%2Fx.json":                            {"objects.patch", "bucket", "team-a/x.json"},
+		"DELETE /storage/v1/b/bucket/o/team-a%2Fx.json":                           {"objects.delete", "bucket", "team-a/x.json"},
+		"POST /upload/storage/v1/b/bucket/o?uploadType=multipart&name=team-a%2Fx": {"objects.insert", "bucket", "team-a/x"},
+		"PUT /upload/storage/v1/b/bucket/o?uploadType=resumable&upload_id=1":      {"objects.insert", "bucket", ""},
+		"POST /storage/v1/b/bucket/o/team-a%2Fx/compose":                          {"objects.compose", "bucket", "team-a/x"},
+		"POST /storage/v1/b/bucket/o/team-a%2Fx/rewriteTo/b/other/o/team-b%2Fy":   {"objects.rewrite", "bucket", "team-a/x"},
+		"POST /storage/v1/b/bucket/o/team-a%2Fx/restore":                          {"objects.restore", "bucket", "team-a/x"},
+		"GET /bucket/team-a/x.json":                                               {"objects.get", "bucket", "team-a/x.json"},
+		"GET /storage/v1/b/bucket":                                                {"buckets.get", "bucket", ""},
+		"PATCH /storage/v1/b/bucket":                                              {"buckets.patch", "bucket", ""},
+		"GET /storage/v1/b?project=p":                                             {"buckets.list", "", ""},
+		"POST /storage/v1/b?project=p":                                            {"buckets.insert", "", ""},
+		"GET /storage/v1/b/bucket/iam":                                            {"buckets.getIamPolicy", "bucket", ""},
+	} {
+		parts := strings.SplitN(req, " ", 2)
+		api, bucket, object := apiRequest(httptest.NewRequest(parts[0], parts[1], nil))
+		require.Equal(t, want, [3]string{api, bucket, object}, req)
+	}
+
+	// every request the client sends has a class, deletes are free
+	require.Equal(t, OperationClassA, apiOperationClass("objects.insert"))
+	require.Equal(t, OperationClassA, apiOperationClass("objects.list"))
+	require.Equal(t, OperationClassA, apiOperationClass("objects.rewrite"))
+	require.Equal(t, OperationClassB, apiOperationClass("objects.get"))
+	require.Equal(t, OperationClassB, apiOperationClass("buckets.get"))
+	require.Equal(t, OperationFree, apiOperationClass("objects.delete"))
+	require.Equal(t, OperationClassA, apiOperationClass("objects.unknown"), "unknown methods are charged as class A")
+}
+
+func TestFirstSegmentLabel(t *testing.T) {
+	require.Equal(t, "team-a", FirstSegmentLabel("bucket", "team-a/reports/x.json"))
+	require.Equal(t, "team-a", FirstSegmentLabel("bucket", "team-a/"))
+	require.Equal(t, "", FirstSegmentLabel("bucket", "x.json"))
+	require.Equal(t, "", FirstSegmentLabel("bucket", ""))
+}
+
+func TestCostAttribution(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "team-b/data.json", []byte(`{"b":1}`), nil)
+	a := NewCostAggregator()
+	cs := newCostClient(t, f, a, nil)
+	ctx := context.Background()
+
+	up, err := NewCloudFileRequest("bucket", "x.json", "team-a", 0)
+	require.NoError(t, err)
+	_, err = cs.Upload(ctx, strings.NewReader(`{"a":1}`), up)
+	require.NoError(t, err)
+	down, err := NewCloudFileRequest("bucket", "data.json", "team-b", 0)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	_, err = cs.Download(ctx, &buf, down)
+	require.NoError(t, err)
+	_, err = cs.ListObjectsInfo(ctx, CloudFileRequest{bucket: "bucket", path: "team-b"})
+	require.NoError(t, err)
+	require.NoError(t, cs.DeleteObject(ctx, up))
+
+	report := a.Report()
+	require.Len(t, report.Labels, 2)
+	teamA, teamB := report.Labels[0], report.Labels[1]
+	require.Equal(t, "team-a", teamA.Label)
+	require.Equal(t, map[string]int64{"STANDARD": 7}, teamA.Uploaded)
+	require.Empty(t, teamA.Downloaded)
+	require.Equal(t, int64(1), teamA.ClassA, "the insert")
+	require.Equal(t, int64(1), teamA.Free, "the delete")
+	require.Greater(t, teamA.RequestBytes, int64(7))
+
+	require.Equal(t, "team-b", teamB.Label)
+	require.Equal(t, map[string]int64{"STANDARD": 7}, teamB.Downloaded)
+	require.Empty(t, teamB.Uploaded)
+	require.Equal(t, int64(1), teamB.ClassA, "the listing")
+	require.Equal(t, int64(2), teamB.ClassB, "the attributes & content reads")
+	require.Greater(t, teamB.ResponseBytes, int64(7))
+
+	// custom labels
+	a = NewCostAggregator()
+	cs = newCostClient(t, f, a, func(bucket, name string) string { return bucket })
+	_, err = cs.Download(ctx, &buf, down)
+	require.NoError(t, err)
+	report = a.Report()
+	require.Len(t, report.Labels, 1)
+	require.Equal(t, "bucket", report.Labels[0].Label)
+	require.Equal(t, map[string]int64{"STANDARD": 7}, report.Labels[0].Downloaded)
+	require.Equal(t, int64(2), report.Labels[0].ClassB)
+}
+
+func TestCostAggregatorRequests(t *testing.T) {
+	a := NewCostAggregator()
+	a.RecordRequest(RequestMetrics{Class: OperationClassA, Label: "team-a", Status: http.StatusOK, RequestBytes: 10})
+	a.RecordRequest(RequestMetrics{Class: OperationClassB, Label: "team-a", Status: http.StatusNotFound, ResponseBytes: 5})
+	a.RecordRequest(RequestMetrics{Class: OperationClassA, Label: "team-a", Err: errors.New("connection reset")})
+	a.RecordTransfer(TransferMetrics{Direction: TransferUpload, Label: "team-a", StorageClass: "NEARLINE", Bytes: 3})
+	a.RecordTransfer(TransferMetrics{Direction: TransferUpload, Label: "team-a", StorageClass: "STANDARD", Bytes: 4})
+
+	report := a.Report()
+	require.Equal(t, []LabelCost{{
+		Label: "team-a", ClassA: 1, ClassB: 1, RequestBytes: 10, ResponseBytes: 5,
+		Uploaded: map[string]int64{"NEARLINE": 3, "STANDARD": 4}, Downloaded: map[string]int64{},
+	}}, report.Labels, "requests failed without response aren't charged")
+
+	// reports are copies
+	report.Labels[0].Uploaded["NEARLINE"] = 0
+	require.Equal(t, int64(3), a.Report().Labels[0].Uploaded["NEARLINE"])
+}
+
+// costReports records written cost reports
+type costReports struct {
+	mu      sync.Mutex
+	reports []CostReport
+}
+
+func (c *costReports) WriteCostReport(ctx context.Context, r CostReport) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reports = append(c.reports, r)
+	return nil
+}
+
+func (c *costReports) written() []CostReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CostReport{}, c.reports...)
+}
+
+func TestCostAggregatorFlush(t *testing.T) {
+	clock := newFakeClock()
+	a := NewCostAggregator()
+	a.now, a.start = clock.Now, clock.Now()
+	a.RecordRequest(RequestMetrics{Class: OperationClassB, Label: "team-a", Status: http.StatusOK})
+	clock.Advance(time.Hour)
+
+	// flushes start a new period
+	report := a.Flush()
+	require.Equal(t, clock.Now().Add(-time.Hour), report.Start)
+	require.Equal(t, clock.Now(), report.End)
+	require.Equal(t, int64(1), report.Labels[0].ClassB)
+	report = a.Report()
+	require.Equal(t, clock.Now(), report.Start)
+	require.Empty(t, report.Labels)
+
+	// periodic flushes, the last period is flushed when stopped
+	sink := &costReports{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.Run(ctx, 10*time.Millisecond, sink, nil)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return len(sink.written()) > 0 }, time.Second, time.Millisecond)
+	a.RecordRequest(RequestMetrics{Class: OperationClassA, Label: "team-b", Status: http.StatusOK})
+	cancel()
+	<-done
+	var classA int64
+	for _, r := range sink.written() {
+		for _, lc := range r.Labels {
+			classA += lc.ClassA
+		}
+	}
+	require.Equal(t, int64(1), classA)
+}
+
+// roundTripFunc is an http.RoundTripper of a func
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+func TestAccountingResumableSessions(t *testing.T) {
+	a := NewCostAggregator()
+	tr := &accountingTransport{recorder: a, label: FirstSegmentLabel, base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		_, err := io.Copy(io.Discard, r.Body)
+		require.NoError(t, err)
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody}
+		switch {
+		case r.URL.Query().Get("upload_id") == "":
+			resp.Header.Set("Location", "https://storage.example/upload/storage/v1/b/bucket/o?uploadType=resumable&upload_id=s1")
+		case r.Header.Get("Content-Range") != "bytes 0-3/8":
+			// chunks before the last are acknowledged but not committed
+		default:
+			resp.StatusCode = http.StatusPermanentRedirect
+		}
+		return resp, nil
+	})}
+	send := func(method, target, contentRange string) {
+		r := httptest.NewRequest(method, target, strings.NewReader("data"))
+		r.Header.Set("Content-Range", contentRange)
+		resp, err := tr.RoundTrip(r)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	// chunk requests are attributed to the session's object until committed
+	send(http.MethodPost, "/upload/storage/v1/b/bucket/o?uploadType=resumable&name=team-a%2Fbig.bin", "")
+	send(http.MethodPut, "/upload/storage/v1/b/bucket/o?uploadType=resumable&upload_id=s1", "bytes 0-3/8")
+	send(http.MethodPut, "/upload/storage/v1/b/bucket/o?uploadType=resumable&upload_id=s1", "bytes 4-7/8")
+	_, ok := tr.sessions.Load("s1")
+	require.False(t, ok, "committed sessions are dropped")
+
+	report := a.Report()
+	require.Len(t, report.Labels, 1)
+	require.Equal(t, "team-a", report.Labels[0].Label)
+	require.Equal(t, int64(3), report.Labels[0].ClassA)
+	require.Equal(t, int64(12), report.Labels[0].RequestBytes)
+}
@@ -0,0 +1,165 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_READING_COUNTER  string = "error reading counter"
+	ERROR_UPDATING_COUNTER string = "error updating counter"
+	ERROR_INVALID_COUNTER  string = "counter content isn't an integer"
+	ERROR_COUNTER_OVERFLOW string = "counter increment overflows"
+)
+
+var (
+	ErrInvalidCounter  = errors.NewAppError(ERROR_INVALID_COUNTER)
+	ErrCounterOverflow = errors.NewAppError(ERROR_COUNTER_OVERFLOW)
+)
+
+// Counter read-modify-write attempts & conflict backoff of IncrementCounter
+const (
+	DEFAULT_COUNTER_ATTEMPTS        = 32
+	DEFAULT_COUNTER_BACKOFF_INITIAL = 10 * time.Millisecond
+	DEFAULT_COUNTER_BACKOFF_MAX     = time.Second
+)
+
+// counterValue is the JSON content of a counter object
+type counterValue struct {
+	Value *int64 `json:"value"`
+}
+
+// counterState is a counter read, the generation zero when the counter doesn't exist
+type counterState struct {
+	value      int64
+	generation int64
+	json       bool
+}
+
+// parseCounter parses counter content, a plain integer or a JSON object with an integer value
+func parseCounter(content []byte) (int64, bool, error) {
+	text := strings.TrimSpace(string(content))
+	if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return value, false, nil
+	}
+	var cv counterValue
+	if err := json.Unmarshal([]byte(text), &cv); err != nil || cv.Value == nil {
+		return 0, false, ErrInvalidCounter
+	}
+	return *cv.Value, true, nil
+}
+
+// encode returns the content of given counter value, in the format read
+func (s counterState) encode(value int64) ([]byte, string) {
+	if s.json {
+		content, _ := json.Marshal(counterValue{Value: &value})
+		return content, "application/json"
+	}
+	return []byte(strconv.FormatInt(value, 10)), "text/plain"
+}
+
+// readCounter returns the counter's value & generation, zero for a missing counter
+func (cs *cloudStorageClient) readCounter(ctx context.Context, counter CloudFileRequest) (counterState, error) {
+	content, generation, err := cs.readPointer(ctx, counter)
+	if isNotFound(err) {
+		return counterState{}, nil
+	}
+	if err != nil {
+		return counterState{}, err
+	}
+	value, isJSON, err := parseCounter(content)
+	if err != nil {
+		return counterState{}, err
+	}
+	return counterState{value: value, generation: generation, json: isJSON}, nil
+}
+
+// ReadCounter returns the counter object's value, zero when the counter doesn't exist
+func (cs *cloudStorageClient) ReadCounter(ctx context.Context, counter CloudFileRequest) (int64, error) {
+	counter, err := cs.request(ctx, counter)
+	if err != nil {
+		return 0, err
+	}
+	if counter.bucket == "" {
+		return 0, ErrBucketNameMissing
+	}
+	if counter.file == "" {
+		return 0, ErrFileNameMissing
+	}
+	op := cs.startOperation(ctx, "ReadCounter", counter)
+	defer op.finish()
+	ctx = op.ctx
+
+	state, err := cs.readCounter(WithRequestID(ctx, op.requestID), counter)
+	if err != nil {
+		op.logger.Error(ERROR_READING_COUNTER, zap.Error(err), zap.String("filepath", op.object))
+		return 0, op.wrapError(err, "%s %s", ERROR_READING_COUNTER, op.object)
+	}
+	return state.value, nil
+}
+
+// IncrementCounter adds delta to the counter object's value & returns the new value, a missing counter
+// starts at zero. The counter holds a plain integer, or a JSON object with an integer value, kept in
+// the format read. Writes are conditional on the generation read, or on the counter not existing,
+// so concurrent increments are never lost: an increment losing the race re-reads after a backoff,
+// up to DEFAULT_COUNTER_ATTEMPTS. The new value is unique to the call, e.g. as a batch sequence number.
+func (cs *cloudStorageClient) IncrementCounter(ctx context.Context, counter CloudFileRequest, delta int64) (int64, error) {
+	if err := cs.mutation(); err != nil {
+		return 0, err
+	}
+	counter, err := cs.request(ctx, counter)
+	if err != nil {
+		return 0, err
+	}
+	if counter.bucket == "" {
+		return 0, ErrBucketNameMissing
+	}
+	if counter.file == "" {
+		return 0, ErrFileNameMissing
+	}
+	op := cs.startOperation(ctx, "IncrementCounter", counter)
+	defer op.finish()
+	ctx = op.ctx
+	ctx = WithRequestID(ctx, op.requestID)
+
+	backoff := &Backoff{Initial: DEFAULT_COUNTER_BACKOFF_INITIAL, Max: DEFAULT_COUNTER_BACKOFF_MAX, Multiplier: DEFAULT_RETRY_MULTIPLIER, Jitter: 1}
+	for attempt := 1; ; attempt++ {
+		state, err := cs.readCounter(ctx, counter)
+		if err != nil {
+			op.logger.Error(ERROR_READING_COUNTER, zap.Error(err), zap.String("filepath", op.object))
+			return 0, op.wrapError(err, "%s %s", ERROR_READING_COUNTER, op.object)
+		}
+		if (delta > 0 && state.value > math.MaxInt64-delta) || (delta < 0 && state.value < math.MinInt64-delta) {
+			op.logger.Error(ERROR_COUNTER_OVERFLOW, zap.String("filepath", op.object), zap.Int64("value", state.value), zap.Int64("delta", delta))
+			return 0, op.wrapError(ErrCounterOverflow, "%s %s", ERROR_COUNTER_OVERFLOW, op.object)
+		}
+		value := state.value + delta
+		content, contentType := state.encode(value)
+
+		cfr := counter
+		cfr.contentType = contentType
+		WithIfGenerationMatch(state.generation)(&cfr)
+		res, err := cs.Upload(ctx, bytes.NewReader(content), cfr)
+		if err == nil {
+			op.generation = res.Attrs.Generation
+			op.logger.Debug("counter incremented", zap.String("filepath", op.object), zap.Int64("value", value), zap.Int("attempt", attempt))
+			return value, nil
+		}
+		if !isPreconditionFailed(err) || attempt >= DEFAULT_COUNTER_ATTEMPTS {
+			op.logger.Error(ERROR_UPDATING_COUNTER, zap.Error(err), zap.String("filepath", op.object), zap.Int("attempt", attempt))
+			return 0, op.wrapError(err, "%s %s", ERROR_UPDATING_COUNTER, op.object)
+		}
+		op.logger.Debug("counter changed, retrying increment", zap.String("filepath", op.object), zap.Int("attempt", attempt))
+		if err := cs.sleep(ctx, backoff.Next()); err != nil {
+			return 0, op.wrapError(err, "%s %s", ERROR_UPDATING_COUNTER, op.object)
+		}
+	}
+}
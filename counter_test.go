@@ -0,0 +1,140 @@
+package cloudstorage
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementCounter(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	counter, err := NewCloudFileRequest("bucket", "batch-seq", "exports", 0)
+	require.NoError(t, err)
+
+	value, err := cs.ReadCounter(ctx, counter)
+	require.NoError(t, err)
+	require.Zero(t, value, "missing counters read zero")
+
+	value, err = cs.IncrementCounter(ctx, counter, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), value)
+	value, err = cs.IncrementCounter(ctx, counter, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), value)
+	data, attrs, _ := f.get("bucket", "exports/batch-seq")
+	require.Equal(t, "11", string(data))
+	require.Equal(t, "text/plain", attrs.ContentType)
+
+	value, err = cs.IncrementCounter(ctx, counter, -12)
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), value)
+	value, err = cs.ReadCounter(ctx, counter)
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), value)
+
+	// JSON counters stay JSON, whitespace around plain integers is tolerated
+	f.put("bucket", "exports/json-seq", []byte(`{"value": 41}`), nil)
+	jsonCounter, err := NewCloudFileRequest("bucket", "json-seq", "exports", 0)
+	require.NoError(t, err)
+	value, err = cs.IncrementCounter(ctx, jsonCounter, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(42), value)
+	data, _, _ = f.get("bucket", "exports/json-seq")
+	require.JSONEq(t, `{"value": 42}`, string(data))
+	f.put("bucket", "exports/batch-seq", []byte("7\n"), nil)
+	value, err = cs.IncrementCounter(ctx, counter, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), value)
+
+	f.put("bucket", "exports/batch-seq", []byte("seven"), nil)
+	_, err = cs.IncrementCounter(ctx, counter, 1)
+	require.ErrorIs(t, err, ErrInvalidCounter)
+	_, err = cs.ReadCounter(ctx, counter)
+	require.ErrorIs(t, err, ErrInvalidCounter)
+
+	f.put("bucket", "exports/batch-seq", []byte("9223372036854775807"), nil)
+	_, err = cs.IncrementCounter(ctx, counter, 1)
+	require.ErrorIs(t, err, ErrCounterOverflow)
+	value, err = cs.IncrementCounter(ctx, counter, math.MinInt64)
+	require.NoError(t, err)
+	require.Equal(t, int64(-1), value)
+
+	_, err = cs.IncrementCounter(ctx, CloudFileRequest{bucket: "bucket"}, 1)
+	require.Equal(t, ErrFileNameMissing, err)
+}
+
+func TestIncrementCounterConcurrent(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	counter, err := NewCloudFileRequest("bucket", "batch-seq", "exports", 0)
+	require.NoError(t, err)
+
+	const workers, increments = 16, 5
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := map[int64]bool{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				value, err := cs.IncrementCounter(ctx, counter, 1)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				if seen[value] {
+					t.Errorf("value %d returned twice", value)
+				}
+				seen[value] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// no lost increments, every value from 1 to the total handed out once
+	value, err := cs.ReadCounter(ctx, counter)
+	require.NoError(t, err)
+	require.Equal(t, int64(workers*increments), value)
+	require.Len(t, seen, workers*increments)
+}
+
+func TestIncrementCounterContended(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "exports/batch-seq", []byte("1"), nil)
+	// another writer bumps the counter before every write
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/upload/") {
+			f.put("bucket", "exports/batch-seq", []byte("1"), nil)
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	waits := []time.Duration{}
+	cs.sleeper = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	counter, err := NewCloudFileRequest("bucket", "batch-seq", "exports", 0)
+	require.NoError(t, err)
+
+	_, err = cs.IncrementCounter(context.Background(), counter, 1)
+	require.True(t, isPreconditionFailed(err))
+	require.Len(t, waits, DEFAULT_COUNTER_ATTEMPTS-1)
+	for _, wait := range waits {
+		require.LessOrEqual(t, wait, DEFAULT_COUNTER_BACKOFF_MAX)
+	}
+	data, _, _ := f.get("bucket", "exports/batch-seq")
+	require.Equal(t, "1", string(data))
+}
@@ -0,0 +1,65 @@
+package cloudstorage
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/comfforts/errors"
+)
+
+const (
+	ERROR_CREDS_NOT_FOUND   string = "creds file not found"
+	ERROR_CREDS_UNREADABLE  string = "creds file unreadable"
+	ERROR_CREDS_MALFORMED   string = "creds file is not valid JSON"
+	ERROR_CREDS_UNSUPPORTED string = "creds file type unsupported"
+	ERROR_CREDS_INCOMPLETE  string = "creds file incomplete"
+)
+
+var (
+	ErrCredsNotFound    = errors.NewAppError(ERROR_CREDS_NOT_FOUND)
+	ErrCredsUnreadable  = errors.NewAppError(ERROR_CREDS_UNREADABLE)
+	ErrCredsMalformed   = errors.NewAppError(ERROR_CREDS_MALFORMED)
+	ErrCredsUnsupported = errors.NewAppError(ERROR_CREDS_UNSUPPORTED)
+	ErrCredsIncomplete  = errors.NewAppError(ERROR_CREDS_INCOMPLETE)
+)
+
+// credsRequiredFields are the fields each supported credentials file type needs
+var credsRequiredFields = map[string][]string{
+	"service_account":  {"client_email", "private_key"},
+	"external_account": {"audience", "subject_token_type", "token_url"},
+}
+
+// validateCredsFile checks the credentials file at given path is readable JSON of a service account key
+// or an external account configuration, with the fields its type needs, so a wrong path fails the
+// constructor instead of the first request's token fetch
+func validateCredsFile(path string) error {
+	data, err := os.ReadFile(path)
+	switch {
+	case stderrors.Is(err, fs.ErrNotExist):
+		return errors.WrapError(ErrCredsNotFound, "%s at %s", ERROR_CREDS_NOT_FOUND, path)
+	case err != nil:
+		return errors.WrapError(ErrCredsUnreadable, "%s at %s: %s", ERROR_CREDS_UNREADABLE, path, err.Error())
+	}
+	creds := map[string]interface{}{}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return errors.WrapError(ErrCredsMalformed, "%s at %s: %s", ERROR_CREDS_MALFORMED, path, err.Error())
+	}
+	typ, _ := creds["type"].(string)
+	required, ok := credsRequiredFields[typ]
+	if !ok {
+		return errors.WrapError(ErrCredsUnsupported, "creds file at %s is type %q, expected service_account or external_account", path, typ)
+	}
+	missing := []string{}
+	for _, field := range required {
+		if v, _ := creds[field].(string); v == "" {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.WrapError(ErrCredsIncomplete, "%s at %s, %s without %s", ERROR_CREDS_INCOMPLETE, path, typ, strings.Join(missing, ", "))
+	}
+	return nil
+}
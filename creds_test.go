@@ -0,0 +1,54 @@
+package cloudstorage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/comfforts/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// requireCredsError checks the client constructor fails with given credentials error before building the client
+func requireCredsError(t *testing.T, credsPath string, want error, msg string) {
+	t.Helper()
+	cs, err := NewCloudStorageClient(CloudStorageClientConfig{CredsPath: credsPath}, &recordingLogger{})
+	require.Nil(t, cs)
+	appErr, ok := err.(errors.AppError)
+	require.True(t, ok, "%T %v", err, err)
+	require.Equal(t, want, appErr.Inner)
+	require.Contains(t, err.Error(), msg)
+}
+
+func TestCredsValidation(t *testing.T) {
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(p, []byte(content), 0600))
+		return p
+	}
+
+	missing := filepath.Join(dir, "missing.json")
+	requireCredsError(t, missing, ErrCredsNotFound, "creds file not found at "+missing)
+	// directories stand in for unreadable files, permissions don't stop root
+	requireCredsError(t, dir, ErrCredsUnreadable, "creds file unreadable at "+dir)
+	requireCredsError(t, write("truncated.json", `{"type": "service_account",`), ErrCredsMalformed, "creds file is not valid JSON")
+	requireCredsError(t, write("user.json", `{"type": "authorized_user"}`), ErrCredsUnsupported, `is type "authorized_user", expected service_account`)
+	requireCredsError(t, write("untyped.json", `{}`), ErrCredsUnsupported, `is type "", expected service_account`)
+	requireCredsError(t, write("partial.json", `{"type": "service_account", "client_email": "sa@project.iam.gserviceaccount.com"}`), ErrCredsIncomplete, "service_account without private_key")
+
+	require.NoError(t, validateCredsFile(writeServiceAccountKey(t)))
+	require.NoError(t, validateCredsFile(write("external.json", `{
+		"type": "external_account",
+		"audience": "//iam.googleapis.com/projects/1/locations/global/workloadIdentityPools/pool/providers/provider",
+		"subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+		"token_url": "https://sts.googleapis.com/v1/token",
+		"credential_source": {"file": "/var/run/token"}
+	}`)))
+
+	// eager validation can be skipped for other credential types
+	cs, err := NewCloudStorageClient(CloudStorageClientConfig{CredsPath: write("other.json", `{"type": "authorized_user", "client_id": "1", "client_secret": "s", "refresh_token": "r"}`), SkipCredsValidation: true}, &recordingLogger{})
+	require.NoError(t, err)
+	require.NoError(t, cs.Close())
+}
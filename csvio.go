@@ -0,0 +1,198 @@
+package cloudstorage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_READING_CSV string = "error reading cloud file csv"
+)
+
+// utf8BOM is the byte order mark some CSV producers prepend
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// gzipMagic are the leading bytes of gzip content
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// RowError reports the data row of a failed CSV record, 0 for the header row, unwraps to the record's error
+type RowError struct {
+	Row int64
+	Err error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Err.Error())
+}
+
+// Unwrap returns the record's error
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// CSVOptions configure CSV helpers
+type CSVOptions struct {
+	// Comma is the field delimiter, defaults to ','
+	Comma rune
+	// Comment, when set, starts lines ignored by the reader
+	Comment rune
+	// Gzip compresses written content & sets gzip content encoding
+	Gzip bool
+}
+
+// CSVOption sets CSV helper options
+type CSVOption func(o *CSVOptions)
+
+// WithComma sets the CSV field delimiter
+func WithComma(comma rune) CSVOption {
+	return func(o *CSVOptions) {
+		o.Comma = comma
+	}
+}
+
+// WithComment sets the CSV comment character
+func WithComment(comment rune) CSVOption {
+	return func(o *CSVOptions) {
+		o.Comment = comment
+	}
+}
+
+// WithCSVGzip compresses written CSV content
+func WithCSVGzip() CSVOption {
+	return func(o *CSVOptions) {
+		o.Gzip = true
+	}
+}
+
+func csvOptions(opts []CSVOption) CSVOptions {
+	cOpts := CSVOptions{Comma: ','}
+	for _, opt := range opts {
+		opt(&cOpts)
+	}
+	return cOpts
+}
+
+// csvSource returns given reader without BOM, decompressed when content is gzip
+// that wasn't transcoded on download
+func csvSource(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(head, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(zr)
+	}
+	head, err = br.Peek(len(utf8BOM))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(head, utf8BOM) {
+		br.Discard(len(utf8BOM))
+	}
+	return br, nil
+}
+
+// scanCSV calls fn with the header & every data row of given reader, stops on the first error
+func scanCSV(r io.Reader, cOpts CSVOptions, fn func(header []string, record []string) error) error {
+	src, err := csvSource(r)
+	if err != nil {
+		return err
+	}
+	cr := csv.NewReader(src)
+	cr.Comma = cOpts.Comma
+	cr.Comment = cOpts.Comment
+
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return RowError{Row: 0, Err: err}
+	}
+	var row int64
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		row++
+		if err != nil {
+			return RowError{Row: row, Err: err}
+		}
+		if err := fn(header, record); err != nil {
+			return RowError{Row: row, Err: err}
+		}
+	}
+}
+
+// ReadCSV streams the cloud file's CSV rows through fn with the header row,
+// handles BOM & gzip content, stops on the first malformed row or fn error, reported as RowError
+func (cs *cloudStorageClient) ReadCSV(ctx context.Context, cfr CloudFileRequest, fn func(header []string, record []string) error, opts ...CSVOption) error {
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return err
+	}
+	if cfr.bucket == "" {
+		return ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return ErrFileNameMissing
+	}
+	cOpts := csvOptions(opts)
+	op := cs.startOperation(ctx, "ReadCSV", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	or := cs.newObjectReader(WithRequestID(ctx, op.requestID), cfr)
+	if err := or.finish(scanCSV(or, cOpts, fn)); err != nil {
+		if _, ok := err.(StorageError); ok {
+			return err
+		}
+		op.logger.Error(ERROR_READING_CSV, zap.Error(err), zap.String("filepath", op.object))
+		return op.wrapError(err, "%s %s", ERROR_READING_CSV, op.object)
+	}
+	return nil
+}
+
+// WriteCSV uploads header & rows returned by rows as CSV, until rows returns false
+func (cs *cloudStorageClient) WriteCSV(ctx context.Context, cfr CloudFileRequest, header []string, rows func() ([]string, bool), opts ...CSVOption) (UploadResult, error) {
+	if cfr.IsZero() {
+		return UploadResult{}, ErrEmptyRequest
+	}
+	cOpts := csvOptions(opts)
+	if cfr.contentType == "" {
+		cfr.contentType = "text/csv"
+	}
+	return cs.writeObject(ctx, cfr, cOpts.Gzip, func(w io.Writer) error {
+		cw := csv.NewWriter(w)
+		cw.Comma = cOpts.Comma
+		if err := cw.Write(header); err != nil {
+			return RowError{Row: 0, Err: err}
+		}
+		var row int64
+		for {
+			record, ok := rows()
+			if !ok {
+				break
+			}
+			row++
+			if err := cw.Write(record); err != nil {
+				return RowError{Row: row, Err: err}
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+}
@@ -0,0 +1,122 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanCSV(t *testing.T) {
+	collect := func(input string, opts ...CSVOption) ([][]string, error) {
+		rows := [][]string{}
+		err := scanCSV(strings.NewReader(input), csvOptions(opts), func(header, record []string) error {
+			require.Equal(t, []string{"name", "size"}, header)
+			rows = append(rows, record)
+			return nil
+		})
+		return rows, err
+	}
+
+	// BOM is stripped from the header
+	rows, err := collect("\xEF\xBB\xBFname,size\na.json,1\nb.json,2\n")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"a.json", "1"}, {"b.json", "2"}}, rows)
+
+	// comma & comment
+	rows, err = collect("name;size\n# skipped\na.json;1\n", WithComma(';'), WithComment('#'))
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"a.json", "1"}}, rows)
+
+	// gzip content
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("name,size\na.json,1\n"))
+	require.NoError(t, zw.Close())
+	rows, err = collect(gz.String())
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"a.json", "1"}}, rows)
+
+	// malformed row reports row number
+	rows, err = collect("name,size\na.json,1\nb.json\n")
+	var rErr RowError
+	require.True(t, errors.As(err, &rErr))
+	require.Equal(t, int64(2), rErr.Row)
+	require.True(t, errors.Is(err, csv.ErrFieldCount))
+	require.Len(t, rows, 1)
+
+	// empty content
+	rows, err = collect("")
+	require.NoError(t, err)
+	require.Empty(t, rows)
+}
+
+func TestCSVHelpers(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+
+	cfr, err := NewCloudFileRequest("bucket", "rows.csv", "path", 0)
+	require.NoError(t, err)
+	data := [][]string{{"a.json", "1"}, {"b,c.json", "2"}}
+	i := 0
+	_, err = cs.WriteCSV(ctx, cfr, []string{"name", "size"}, func() ([]string, bool) {
+		if i >= len(data) {
+			return nil, false
+		}
+		i++
+		return data[i-1], true
+	})
+	require.NoError(t, err)
+	content, attrs, ok := f.get("bucket", "path/rows.csv")
+	require.True(t, ok)
+	require.Equal(t, "text/csv", attrs.ContentType)
+	require.Equal(t, "name,size\na.json,1\n\"b,c.json\",2\n", string(content))
+
+	rows := [][]string{}
+	err = cs.ReadCSV(ctx, cfr, func(header, record []string) error {
+		require.Equal(t, []string{"name", "size"}, header)
+		rows = append(rows, record)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, data, rows)
+
+	// fn error stops reading at its row
+	stop := errors.New("stop")
+	err = cs.ReadCSV(ctx, cfr, func(header, record []string) error {
+		if record[0] == "b,c.json" {
+			return stop
+		}
+		return nil
+	})
+	require.True(t, errors.Is(err, stop))
+	var rErr RowError
+	require.True(t, errors.As(err, &rErr))
+	require.Equal(t, int64(2), rErr.Row)
+
+	// gzip written content is read back
+	gzCfr, err := NewCloudFileRequest("bucket", "rows.csv.gz", "path", 0)
+	require.NoError(t, err)
+	i = 0
+	_, err = cs.WriteCSV(ctx, gzCfr, []string{"name", "size"}, func() ([]string, bool) {
+		if i >= len(data) {
+			return nil, false
+		}
+		i++
+		return data[i-1], true
+	}, WithCSVGzip())
+	require.NoError(t, err)
+	rows = [][]string{}
+	err = cs.ReadCSV(ctx, gzCfr, func(header, record []string) error {
+		rows = append(rows, record)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, data, rows)
+}
@@ -0,0 +1,113 @@
+package cloudstorage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_DEADLINE_TOO_SHORT string = "context deadline too short for operation"
+)
+
+var (
+	ErrDeadlineTooShort = errors.NewAppError(ERROR_DEADLINE_TOO_SHORT)
+)
+
+// Default deadlines of single object transfers, applied under the caller's context,
+// the earlier of the two deadlines wins
+const (
+	DEFAULT_UPLOAD_TIMEOUT   = 50 * time.Second
+	DEFAULT_DOWNLOAD_TIMEOUT = 50 * time.Second
+)
+
+// DEFAULT_CLEANUP_TIMEOUT is the deadline of best effort temporary object removals,
+// run after the operation's context may be done
+const DEFAULT_CLEANUP_TIMEOUT = 10 * time.Second
+
+// Default deadline budget of composite operations
+const (
+	DEFAULT_DEADLINE_BUDGET_BASE     = 50 * time.Millisecond
+	DEFAULT_DEADLINE_BUDGET_PER_ITEM = 10 * time.Millisecond
+)
+
+// DeadlineBudget estimates the minimum time a composite operation, making many calls under one context,
+// needs to complete: Base plus PerItem for each item of its slowest concurrent worker.
+// Operations whose context deadline leaves less fail with ErrDeadlineTooShort before their first call,
+// rather than doing partial work. Contexts without deadline aren't checked.
+type DeadlineBudget struct {
+	// Base is the fixed part of the estimate, defaults to DEFAULT_DEADLINE_BUDGET_BASE
+	Base time.Duration `json:"base"`
+	// PerItem is the floor time of one item's calls, defaults to DEFAULT_DEADLINE_BUDGET_PER_ITEM
+	PerItem time.Duration `json:"per_item"`
+	// Disabled skips the check for every operation
+	Disabled bool `json:"disabled"`
+}
+
+// estimate returns the minimum time for given number of items run by given number of concurrent workers
+func (b DeadlineBudget) estimate(items, concurrency int) time.Duration {
+	base, perItem := b.Base, b.PerItem
+	if base <= 0 {
+		base = DEFAULT_DEADLINE_BUDGET_BASE
+	}
+	if perItem <= 0 {
+		perItem = DEFAULT_DEADLINE_BUDGET_PER_ITEM
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	rounds := (items + concurrency - 1) / concurrency
+	return base + time.Duration(rounds)*perItem
+}
+
+// DeadlineTooShortError is returned by composite operations whose context deadline is before
+// the budget estimate, matches ErrDeadlineTooShort with errors.Is
+type DeadlineTooShortError struct {
+	Op        string
+	Items     int
+	Remaining time.Duration
+	Estimated time.Duration
+}
+
+func (e DeadlineTooShortError) Error() string {
+	return fmt.Sprintf("%s %s: %s remaining, %s estimated for %d items", ERROR_DEADLINE_TOO_SHORT, e.Op, e.Remaining, e.Estimated, e.Items)
+}
+
+// Is matches ErrDeadlineTooShort
+func (e DeadlineTooShortError) Is(target error) bool {
+	return target == ErrDeadlineTooShort
+}
+
+type forceKey struct{}
+
+// WithForce returns a copy of given context making composite operations start
+// whatever their remaining deadline, skipping the deadline budget check
+func WithForce(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceKey{}, true)
+}
+
+// checkDeadlineBudget fails with DeadlineTooShortError when the context deadline leaves less than
+// the budget estimate of given items & concurrency, before the operation's first call
+func (cs *cloudStorageClient) checkDeadlineBudget(ctx context.Context, op *operation, items, concurrency int) error {
+	budget := cs.config.DeadlineBudget
+	if budget.Disabled {
+		return nil
+	}
+	if force, _ := ctx.Value(forceKey{}).(bool); force {
+		return nil
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	remaining, estimated := deadline.Sub(cs.now()), budget.estimate(items, concurrency)
+	if remaining >= estimated {
+		return nil
+	}
+	err := DeadlineTooShortError{Op: op.name, Items: items, Remaining: remaining, Estimated: estimated}
+	op.logger.Error(ERROR_DEADLINE_TOO_SHORT, zap.Error(err), zap.String("filepath", op.object), zap.Int("items", items))
+	return err
+}
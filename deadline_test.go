@@ -0,0 +1,119 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadlineBudgetEstimate(t *testing.T) {
+	require.Equal(t, 150*time.Millisecond, DeadlineBudget{}.estimate(20, 2))
+	require.Equal(t, 160*time.Millisecond, DeadlineBudget{}.estimate(21, 2))
+	require.Equal(t, 4*time.Second, DeadlineBudget{Base: time.Second, PerItem: time.Second}.estimate(3, 0))
+}
+
+func TestDeadlineBudget(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "data/a.txt", []byte("a"), nil)
+	_, attrs, _ := f.get("bucket", "data/a.txt")
+	cs := newFakeClient(t, f)
+	var requests int64
+	f.fail = func(r *http.Request) int {
+		atomic.AddInt64(&requests, 1)
+		return 0
+	}
+	publish := func(ctx context.Context) error {
+		items := []UploadItem{}
+		for i := 0; i < 20; i++ {
+			items = append(items, UploadItem{Request: CloudFileRequest{bucket: "bucket", path: "set", file: fmt.Sprintf("%d.txt", i)}, Reader: strings.NewReader("data")})
+		}
+		manifest := UploadItem{Request: CloudFileRequest{bucket: "bucket", path: "set", file: "manifest.json"}, Reader: strings.NewReader("{}")}
+		_, err := cs.PublishSet(ctx, items, manifest, WithPublishConcurrency(2))
+		return err
+	}
+	ops := map[string]func(ctx context.Context) error{
+		"PublishSet": publish,
+		"ReconcileBuckets": func(ctx context.Context) error {
+			_, err := cs.ReconcileBuckets(ctx, BucketPrefix{Bucket: "bucket", Prefix: "data"}, BucketPrefix{Bucket: "mirror"}, ReconcileOptions{})
+			return err
+		},
+		"RestoreSnapshot": func(ctx context.Context) error {
+			_, err := cs.RestoreSnapshot(ctx, []ObjectVersion{{Bucket: "bucket", Name: "data/a.txt", Generation: attrs.Generation}}, "restored")
+			return err
+		},
+		"UploadFanOut": func(ctx context.Context) error {
+			_, err := cs.UploadFanOut(ctx, strings.NewReader("data"), CloudFileRequest{bucket: "bucket", file: "a.txt"}, []CloudFileRequest{{bucket: "mirror", file: "a.txt"}})
+			return err
+		},
+		"UploadFile": func(ctx context.Context) error {
+			data := bytes.Repeat([]byte("x"), 1024)
+			_, err := cs.UploadFromReaderAt(ctx, bytes.NewReader(data), int64(len(data)), CloudFileRequest{bucket: "bucket", file: "parts.bin"}, WithChunkSize(256), WithParallelUpload(512, 2))
+			return err
+		},
+	}
+
+	// a deadline clearly too short fails every composite operation before its first call
+	cs.config.DeadlineBudget = DeadlineBudget{Base: time.Hour}
+	for name, run := range ops {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := run(ctx)
+		cancel()
+		require.ErrorIs(t, err, ErrDeadlineTooShort, name)
+		var dErr DeadlineTooShortError
+		require.ErrorAs(t, err, &dErr, name)
+		require.Equal(t, name, dErr.Op)
+		require.Less(t, dErr.Remaining, time.Minute)
+		require.Greater(t, dErr.Estimated, time.Hour)
+		require.Zero(t, atomic.LoadInt64(&requests), name)
+	}
+
+	// the default budget is sized by items, 20 data objects over 2 workers & the manifest need 160ms
+	cs.config.DeadlineBudget = DeadlineBudget{}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	err := publish(ctx)
+	cancel()
+	var dErr DeadlineTooShortError
+	require.ErrorAs(t, err, &dErr)
+	require.Equal(t, 160*time.Millisecond, dErr.Estimated)
+	require.Zero(t, atomic.LoadInt64(&requests))
+
+	// forced, disabled & deadline free runs aren't checked
+	cs.config.DeadlineBudget = DeadlineBudget{Base: time.Hour}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for name, run := range ops {
+		require.NoError(t, run(WithForce(ctx)), name)
+	}
+	require.NotZero(t, atomic.LoadInt64(&requests))
+	require.NoError(t, publish(context.Background()))
+	cs.config.DeadlineBudget.Disabled = true
+	require.NoError(t, publish(ctx))
+}
+
+func TestDeadlineBudgetClientClock(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	clock := newFakeClock()
+	cs.clock = clock
+	cs.config.DeadlineBudget = DeadlineBudget{Base: time.Second}
+
+	// remaining time is measured with the client clock, exactly
+	ctx, cancel := context.WithDeadline(context.Background(), clock.Now().Add(900*time.Millisecond))
+	defer cancel()
+	_, err := cs.ReconcileBuckets(ctx, BucketPrefix{Bucket: "bucket"}, BucketPrefix{Bucket: "mirror"}, ReconcileOptions{})
+	var dErr DeadlineTooShortError
+	require.ErrorAs(t, err, &dErr)
+	require.Equal(t, 900*time.Millisecond, dErr.Remaining)
+
+	clock.Advance(400 * time.Millisecond)
+	_, err = cs.ReconcileBuckets(ctx, BucketPrefix{Bucket: "bucket"}, BucketPrefix{Bucket: "mirror"}, ReconcileOptions{})
+	require.ErrorAs(t, err, &dErr)
+	require.Equal(t, 500*time.Millisecond, dErr.Remaining)
+}
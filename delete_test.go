@@ -0,0 +1,361 @@
+package cloudstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/comfforts/cloudstorage/cloudstoragetest"
+	"github.com/stretchr/testify/require"
+)
+
+func deleteFixture(t *testing.T) *fakeGCS {
+	f := newFakeGCS()
+	cloudstoragetest.NewTree().
+		Dir("data").Marker().File("a.txt", 4).
+		Dir("data/sub").Marker().File("b.txt", 2).
+		Dir("other").File("c.txt", 1).
+		Build(t, f)
+	return f
+}
+
+func TestDeleteObjectsWithReport(t *testing.T) {
+	f := deleteFixture(t)
+	cs := newFakeClient(t, f)
+
+	report, err := cs.DeleteObjectsWithReport(context.Background(), CloudFileRequest{bucket: "bucket", path: "data"})
+	require.NoError(t, err)
+	require.Equal(t, DeleteReport{Deleted: 2, Skipped: 2, BytesFreed: 6}, report)
+	cloudstoragetest.AssertObjectExists(t, f, "bucket", "other/c.txt")
+	cloudstoragetest.AssertObjectExists(t, f, "bucket", "data/sub/")
+
+	// markers removed on request
+	cfr := CloudFileRequest{bucket: "bucket", path: "data"}
+	WithRemoveDirMarkers()(&cfr)
+	report, err = cs.DeleteObjectsWithReport(context.Background(), cfr)
+	require.NoError(t, err)
+	require.Equal(t, DeleteReport{Deleted: 2}, report)
+	cloudstoragetest.AssertPrefixEmpty(t, f, "bucket", "data/")
+
+	// nothing left to delete
+	report, err = cs.DeleteObjectsWithReport(context.Background(), cfr)
+	require.NoError(t, err)
+	require.Equal(t, DeleteReport{}, report)
+}
+
+func TestDeleteObjectsWithReportFailures(t *testing.T) {
+	f := deleteFixture(t)
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "a.txt") {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	cfr := CloudFileRequest{bucket: "bucket", path: "data"}
+	WithRemoveDirMarkers()(&cfr)
+
+	report, err := cs.DeleteObjectsWithReport(context.Background(), cfr)
+	require.Error(t, err)
+	var sErr StorageError
+	require.True(t, errors.As(err, &sErr))
+	require.Equal(t, "DeleteObjects", sErr.Op)
+	// failure doesn't stop the run, markers are kept
+	require.Equal(t, DeleteReport{Deleted: 1, Skipped: 2, Failed: 1, BytesFreed: 2}, report)
+	cloudstoragetest.AssertObjectExists(t, f, "bucket", "data/sub/")
+}
+
+func TestDeleteObjectsWithReportCancel(t *testing.T) {
+	f := deleteFixture(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodDelete {
+			// cancelled after the first delete is handled
+			defer cancel()
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+
+	report, err := cs.DeleteObjectsWithReport(ctx, CloudFileRequest{bucket: "bucket", path: "data"})
+	require.Error(t, err)
+	require.Equal(t, int64(1), report.Deleted+report.Failed)
+	cloudstoragetest.AssertObjectExists(t, f, "bucket", "data/sub/b.txt")
+}
+
+// parentsFixture has objects named like directories, with & without content, the prefix itself included
+func parentsFixture(t *testing.T) (*fakeGCS, cloudstoragetest.Manifest) {
+	f := newFakeGCS()
+	m := cloudstoragetest.NewTree().
+		Dir("data").Marker(cloudstoragetest.WithContent([]byte("prefix named object"))).File("a.txt", 4).
+		Dir("data/sub").Marker().File("b.txt", 2).
+		Dir("data/sub/c").Marker(cloudstoragetest.WithContent([]byte("c"))).File("d.txt", 1).
+		Build(t, f)
+	return f, m
+}
+
+// requireNoOrphans checks every remaining object of the fixture keeps its parents
+func requireNoOrphans(t *testing.T, f *fakeGCS) {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key := range f.objects {
+		name := strings.TrimPrefix(key, "bucket/")
+		for _, parent := range []string{"data/", "data/sub/", "data/sub/c/"} {
+			if parent != name && strings.HasPrefix(name, parent) {
+				_, ok := f.objects[fakeKey("bucket", parent)]
+				require.True(t, ok, "%s orphaned, %s deleted", name, parent)
+			}
+		}
+	}
+}
+
+func TestDeleteObjectsParentsLast(t *testing.T) {
+	f, m := parentsFixture(t)
+	deleted := recordRequests(f, http.MethodDelete, nil)
+	cs := newFakeClient(t, f)
+
+	// parents with content go after their children, the marker is kept
+	report, err := cs.DeleteObjectsWithReport(context.Background(), CloudFileRequest{bucket: "bucket", path: "data"})
+	require.NoError(t, err)
+	require.Equal(t, DeleteReport{Deleted: 5, Skipped: 1, BytesFreed: m.Bytes("data/")}, report)
+	cloudstoragetest.AssertObjectExists(t, f, "bucket", "data/sub/")
+	for i, p := range *deleted {
+		for _, later := range (*deleted)[i+1:] {
+			require.False(t, strings.HasPrefix(later, p), "%s deleted before %s", p, later)
+		}
+	}
+
+	cfr := CloudFileRequest{bucket: "bucket", path: "data"}
+	WithRemoveDirMarkers()(&cfr)
+	report, err = cs.DeleteObjectsWithReport(context.Background(), cfr)
+	require.NoError(t, err)
+	require.Equal(t, DeleteReport{Deleted: 1}, report)
+	cloudstoragetest.AssertPrefixEmpty(t, f, "bucket", "")
+}
+
+func TestDeleteObjectsRerunConverges(t *testing.T) {
+	for _, failing := range []string{"data/sub/c/d.txt", "data/sub/c/", "data/sub/"} {
+		t.Run(failing, func(t *testing.T) {
+			f, _ := parentsFixture(t)
+			failed := false
+			f.fail = func(r *http.Request) int {
+				if !failed && r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/o/"+failing) {
+					failed = true
+					return http.StatusForbidden
+				}
+				return 0
+			}
+			cs := newFakeClient(t, f)
+			cfr := CloudFileRequest{bucket: "bucket", path: "data"}
+			WithRemoveDirMarkers()(&cfr)
+
+			_, err := cs.DeleteObjectsWithReport(context.Background(), cfr)
+			require.Error(t, err)
+			require.True(t, failed)
+			requireNoOrphans(t, f)
+
+			report, err := cs.DeleteObjectsWithReport(context.Background(), cfr)
+			require.NoError(t, err)
+			require.Zero(t, report.Failed)
+			cloudstoragetest.AssertPrefixEmpty(t, f, "bucket", "")
+		})
+	}
+}
+
+func TestDeleteObjectsAlreadyGone(t *testing.T) {
+	f := deleteFixture(t)
+	// another deleter removes the object first
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "a.txt") {
+			return http.StatusNotFound
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	cfr := CloudFileRequest{bucket: "bucket", path: "data"}
+	WithRemoveDirMarkers()(&cfr)
+
+	report, err := cs.DeleteObjectsWithReport(context.Background(), cfr)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), report.Skipped)
+	require.Zero(t, report.Failed)
+	// markers are removed, gone objects aren't failures
+	cloudstoragetest.AssertObjectMissing(t, f, "bucket", "data/")
+}
+
+func TestDeleteObjectsPinnedToListedGeneration(t *testing.T) {
+	f := deleteFixture(t)
+	// a log written while the cleanup runs replaces an object after it was listed
+	replaced := false
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "a.txt") && !replaced {
+			replaced = true
+			f.put("bucket", "data/sub/b.txt", []byte("newer"), nil)
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	cfr := CloudFileRequest{bucket: "bucket", path: "data"}
+	WithRemoveDirMarkers()(&cfr)
+
+	report, err := cs.DeleteObjectsWithReport(context.Background(), cfr)
+	require.NoError(t, err)
+	require.Equal(t, DeleteReport{Deleted: 1, Skipped: 2, Changed: 1, BytesFreed: 4}, report)
+	require.Equal(t, EXIT_OK, report.ExitCode())
+	// the newer generation is kept, with its marker
+	cloudstoragetest.AssertObjectContent(t, f, "bucket", "data/sub/b.txt", []byte("newer"))
+	cloudstoragetest.AssertObjectExists(t, f, "bucket", "data/sub/")
+
+	// unconditional deletes remove the replacement
+	f = deleteFixture(t)
+	replaced = false
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "a.txt") && !replaced {
+			replaced = true
+			f.put("bucket", "data/sub/b.txt", []byte("newer"), nil)
+		}
+		return 0
+	}
+	cs = newFakeClient(t, f)
+	WithUnconditionalDelete()(&cfr)
+	report, err = cs.DeleteObjectsWithReport(context.Background(), cfr)
+	require.NoError(t, err)
+	require.Equal(t, DeleteReport{Deleted: 4, BytesFreed: 6}, report)
+	cloudstoragetest.AssertObjectMissing(t, f, "bucket", "data/sub/b.txt")
+}
+
+// deleteEntryPoints deletes data/a.txt of the parity fixture by name & as the only object under data/
+var deleteEntryPoints = map[string]func(ctx context.Context, cs *cloudStorageClient, opts ...CloudFileRequestOption) error{
+	"DeleteObject": func(ctx context.Context, cs *cloudStorageClient, opts ...CloudFileRequestOption) error {
+		return cs.DeleteObject(ctx, CloudFileRequest{bucket: "bucket", path: "data", file: "a.txt"}, opts...)
+	},
+	"DeleteObjectsWithReport": func(ctx context.Context, cs *cloudStorageClient, opts ...CloudFileRequestOption) error {
+		report, err := cs.DeleteObjectsWithReport(ctx, CloudFileRequest{bucket: "bucket", path: "data"}, opts...)
+		if err == nil && report.Deleted != 1 {
+			return fmt.Errorf("deleted %d, want 1", report.Deleted)
+		}
+		return err
+	},
+}
+
+func TestDeleteOptionParity(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		opts     []CloudFileRequestOption
+		readOnly bool
+		check    func(t *testing.T, f *fakeGCS, m cloudstoragetest.Manifest, requests []*http.Request)
+	}{
+		{name: "default", check: func(t *testing.T, f *fakeGCS, m cloudstoragetest.Manifest, requests []*http.Request) {
+			cloudstoragetest.AssertObjectMissing(t, f, "bucket", "data/a.txt")
+			cloudstoragetest.AssertObjectExists(t, f, "bucket", "other/c.txt")
+		}},
+		{name: "dry run", opts: []CloudFileRequestOption{WithDeleteDryRun()}, readOnly: true,
+			check: func(t *testing.T, f *fakeGCS, m cloudstoragetest.Manifest, requests []*http.Request) {
+				cloudstoragetest.AssertManifest(t, f, m)
+				for _, r := range requests {
+					require.NotEqual(t, http.MethodDelete, r.Method)
+				}
+			}},
+		{name: "trash", opts: []CloudFileRequestOption{WithDeleteToTrash("")},
+			check: func(t *testing.T, f *fakeGCS, m cloudstoragetest.Manifest, requests []*http.Request) {
+				a, _ := m.Get("data/a.txt")
+				cloudstoragetest.AssertObjectMissing(t, f, "bucket", "data/a.txt")
+				cloudstoragetest.AssertObjectContent(t, f, "bucket", DEFAULT_TRASH_PREFIX+"/data/a.txt", a.Content)
+			}},
+		{name: "trash prefix, dry run", opts: []CloudFileRequestOption{WithDeleteToTrash("bin"), WithDeleteDryRun()},
+			check: func(t *testing.T, f *fakeGCS, m cloudstoragetest.Manifest, requests []*http.Request) {
+				cloudstoragetest.AssertManifest(t, f, m)
+				cloudstoragetest.AssertPrefixEmpty(t, f, "bucket", "bin/")
+			}},
+		{name: "user project", opts: []CloudFileRequestOption{WithUserProject("billing")},
+			check: func(t *testing.T, f *fakeGCS, m cloudstoragetest.Manifest, requests []*http.Request) {
+				cloudstoragetest.AssertObjectMissing(t, f, "bucket", "data/a.txt")
+				for _, r := range requests {
+					require.Equal(t, "billing", r.URL.Query().Get("userProject"), "%s %s", r.Method, r.URL.Path)
+				}
+			}},
+	} {
+		for entry, del := range deleteEntryPoints {
+			t.Run(tc.name+"/"+entry, func(t *testing.T) {
+				f := newFakeGCS()
+				m := cloudstoragetest.NewTree().Dir("data").File("a.txt", 4).Dir("other").File("c.txt", 1).Build(t, f)
+				var mu sync.Mutex
+				requests := []*http.Request{}
+				f.fail = func(r *http.Request) int {
+					mu.Lock()
+					defer mu.Unlock()
+					requests = append(requests, r)
+					return 0
+				}
+				cs := newFakeClient(t, f)
+				cs.config.ReadOnly = tc.readOnly
+
+				require.NoError(t, del(context.Background(), cs, tc.opts...))
+				tc.check(t, f, m, requests)
+			})
+		}
+	}
+}
+
+func TestDeleteObjectPreconditions(t *testing.T) {
+	f := newFakeGCS()
+	cloudstoragetest.NewTree().Dir("data").File("a.txt", 4).File("*.txt", 2).File("[a].txt", 2).Build(t, f)
+	_, attrs, _ := f.get("bucket", "data/a.txt")
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr := CloudFileRequest{bucket: "bucket", path: "data", file: "a.txt"}
+
+	for _, opts := range [][]CloudFileRequestOption{
+		{WithIfGenerationMatch(attrs.Generation + 1)},
+		{WithIfGenerationMatch(attrs.Generation + 1), WithDeleteToTrash("")},
+		{WithIfGenerationMatch(attrs.Generation + 1), WithDeleteDryRun()},
+		{WithIfGenerationMatch(0)},
+	} {
+		err := cs.DeleteObject(ctx, cfr, opts...)
+		require.True(t, isPreconditionFailed(err), "%v", err)
+		cloudstoragetest.AssertObjectExists(t, f, "bucket", "data/a.txt")
+	}
+	cloudstoragetest.AssertPrefixEmpty(t, f, "bucket", DEFAULT_TRASH_PREFIX)
+	require.NoError(t, cs.DeleteObject(ctx, cfr, WithIfGenerationMatch(attrs.Generation)))
+	cloudstoragetest.AssertObjectMissing(t, f, "bucket", "data/a.txt")
+
+	// dry runs of missing objects fail like deletes
+	require.ErrorIs(t, cs.DeleteObject(ctx, cfr, WithDeleteDryRun()), ErrObjectNotFound)
+	require.ErrorIs(t, cs.DeleteObject(ctx, cfr), ErrObjectNotFound)
+
+	// names are literal, wildcards aren't expanded
+	require.NoError(t, cs.DeleteObject(ctx, CloudFileRequest{bucket: "bucket", path: "data", file: "*.txt"}))
+	cloudstoragetest.AssertObjectMissing(t, f, "bucket", "data/*.txt")
+	cloudstoragetest.AssertObjectExists(t, f, "bucket", "data/[a].txt")
+}
+
+func TestDeleteObjectsToTrash(t *testing.T) {
+	f := newFakeGCS()
+	m := cloudstoragetest.NewTree().
+		Dir("data").Marker().File("a.txt", 4).
+		Dir("data/sub").File("b.txt", 2).
+		Build(t, f)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	// the whole bucket's trash, kept under the deleted prefix, isn't deleted again
+	scope, err := NewBucketRequest("bucket")
+	require.NoError(t, err)
+	report, err := cs.DeleteObjectsWithReport(ctx, scope, WithDeleteToTrash(""), WithRemoveDirMarkers())
+	require.NoError(t, err)
+	require.Equal(t, DeleteReport{Deleted: 3, BytesFreed: 6}, report)
+	for _, obj := range m.Objects {
+		cloudstoragetest.AssertObjectContent(t, f, "bucket", ".trash/"+obj.Name, obj.Content)
+	}
+	require.Equal(t, []string{".trash/data/", ".trash/data/a.txt", ".trash/data/sub/b.txt"}, f.storedNames("bucket"))
+
+	report, err = cs.DeleteObjectsWithReport(ctx, scope, WithDeleteToTrash(""), WithRemoveDirMarkers())
+	require.NoError(t, err)
+	require.Equal(t, DeleteReport{}, report)
+}
@@ -0,0 +1,76 @@
+package cloudstorage
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_DESTINATION_NOT_EMPTY string = "destination prefix not empty"
+)
+
+var (
+	ErrDestinationNotEmpty = errors.NewAppError(ERROR_DESTINATION_NOT_EMPTY)
+)
+
+// DESTINATION_CONFLICT_SAMPLE is the number of conflicting names reported by DestinationNotEmptyError
+const DESTINATION_CONFLICT_SAMPLE = 10
+
+// DestinationNotEmptyError is returned by bulk copies requiring an empty destination prefix
+// that has objects, matches ErrDestinationNotEmpty with errors.Is
+type DestinationNotEmptyError struct {
+	Bucket string
+	Prefix string
+	// Names are a sample of the objects found under the prefix, up to DESTINATION_CONFLICT_SAMPLE
+	Names []string
+}
+
+func (e DestinationNotEmptyError) Error() string {
+	return fmt.Sprintf("%s %s/%s: %s", ERROR_DESTINATION_NOT_EMPTY, e.Bucket, e.Prefix, strings.Join(e.Names, ", "))
+}
+
+// Is matches ErrDestinationNotEmpty
+func (e DestinationNotEmptyError) Is(target error) bool {
+	return target == ErrDestinationNotEmpty
+}
+
+// destinationPrefix returns given bulk copy destination prefix without leading, trailing & repeated slashes,
+// the one prefix a copy both checks & writes under. Parent segments fail with ErrOutOfScope,
+// they could resolve outside the prefix, scoped or not.
+func destinationPrefix(p string) (string, error) {
+	if hasParentSegment(p) {
+		return "", errors.WrapError(ErrOutOfScope, "%s %q", ERROR_OUT_OF_SCOPE, p)
+	}
+	return strings.Trim(path.Clean("/"+p), "/"), nil
+}
+
+// requireEmptyDestination fails with DestinationNotEmptyError when objects other than directory markers
+// exist under the path of given scoped request, the path the copy writes under.
+// Reported names are relative to the request's scope.
+func (cs *cloudStorageClient) requireEmptyDestination(ctx context.Context, op *operation, dst CloudFileRequest) error {
+	prefix := dirPrefix(dst.path)
+	names := []string{}
+	it := cs.bucketHandle(dst).Objects(ctx, &storage.Query{Prefix: prefix})
+	for len(names) < DESTINATION_CONFLICT_SAMPLE {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return op.wrapError(err, ERROR_LISTING_OBJECTS)
+		}
+		if !isDirMarker(attrs) {
+			names = append(names, dst.unscopedName(attrs.Name))
+		}
+	}
+	if len(names) > 0 {
+		return DestinationNotEmptyError{Bucket: dst.bucket, Prefix: dst.unscopedName(prefix), Names: names}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package cloudstorage
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_CREATING_DIR_MARKER string = "error creating directory marker"
+	ERROR_LISTING_DIR         string = "error listing directory"
+)
+
+// DirListing is the result of listing one directory level
+type DirListing struct {
+	// Files are the object names directly under the directory
+	Files []string
+	// Dirs are the sub directory prefixes, with trailing slash
+	Dirs []string
+}
+
+// dirPrefix normalizes given directory path into an object name prefix,
+// keeps the trailing slash, returns empty prefix for bucket root
+func dirPrefix(dir string) string {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	if dir == "" {
+		return ""
+	}
+	return dir + "/"
+}
+
+// isDirMarker reports whether given object is a directory marker, a zero byte object with trailing slash name
+func isDirMarker(attrs *storage.ObjectAttrs) bool {
+	return strings.HasSuffix(attrs.Name, "/") && attrs.Size == 0
+}
+
+// EnsureDir creates the zero byte directory marker object for given prefix,
+// succeeds without changes if the marker already exists
+func (cs *cloudStorageClient) EnsureDir(ctx context.Context, bucket, prefix string) error {
+	if err := cs.mutation(); err != nil {
+		return err
+	}
+	if dirPrefix(prefix) == "" {
+		return ErrFilePathMissing
+	}
+	cfr, err := cs.scoped(ctx, CloudFileRequest{bucket: bucket, path: prefix})
+	if err != nil {
+		return err
+	}
+	bucket = cfr.bucket
+	if bucket == "" {
+		return ErrBucketNameMissing
+	}
+	marker := dirPrefix(cfr.path)
+	op := cs.startOperation(ctx, "EnsureDir", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	op.object = marker
+
+	obj := cs.bucketHandle(cfr).Object(marker).If(storage.Conditions{DoesNotExist: true})
+	wc := obj.NewWriter(ctx)
+	err = wc.Close()
+	cs.invalidate(bucket, marker)
+	if err != nil {
+		if isPreconditionFailed(err) {
+			op.logger.Debug("directory marker exists", zap.String("filepath", marker))
+			return nil
+		}
+		op.logger.Error(ERROR_CREATING_DIR_MARKER, zap.Error(err), zap.String("filepath", marker))
+		return op.wrapError(err, "%s %s", ERROR_CREATING_DIR_MARKER, marker)
+	}
+	op.logger.Debug("directory marker created", zap.String("filepath", marker))
+	return nil
+}
+
+// ListDir lists files & sub directories directly under request path,
+// the directory's own marker object isn't returned as a file
+func (cs *cloudStorageClient) ListDir(ctx context.Context, cfr CloudFileRequest) (DirListing, error) {
+	cfr, err := cs.prefixRequest(ctx, cfr)
+	if err != nil {
+		return DirListing{}, err
+	}
+	if cfr.bucket == "" {
+		return DirListing{}, ErrBucketNameMissing
+	}
+	op := cs.startOperation(ctx, "ListDir", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	prefix := dirPrefix(cfr.path)
+	op.object = prefix
+
+	listing := DirListing{
+		Files: []string{},
+		Dirs:  []string{},
+	}
+	// shard segments of a sharded layout are listed after the directory, their content mapped to it
+	shardDirs := []string{}
+	list := func(dir string) error {
+		it := cs.bucketHandle(cfr).Objects(ctx, &storage.Query{
+			Prefix:    dir,
+			Delimiter: "/",
+		})
+		for {
+			objAttrs, err := it.Next()
+			if err != nil {
+				if err == iterator.Done {
+					return nil
+				}
+				return err
+			}
+			switch {
+			case objAttrs.Prefix != "" && dir == prefix && cfr.sharded() && cfr.isShardSegment(objAttrs.Prefix):
+				shardDirs = append(shardDirs, objAttrs.Prefix)
+			case objAttrs.Prefix != "":
+				listing.Dirs = append(listing.Dirs, cfr.unscopedName(prefix+strings.TrimPrefix(objAttrs.Prefix, dir)))
+			case objAttrs.Name == prefix:
+				// marker of the listed directory
+			case dir != prefix:
+				listing.Files = append(listing.Files, cfr.logicalName(objAttrs.Name))
+			default:
+				listing.Files = append(listing.Files, cfr.unscopedName(objAttrs.Name))
+			}
+		}
+	}
+	err = list(prefix)
+	for _, dir := range shardDirs {
+		if err != nil {
+			break
+		}
+		err = list(dir)
+	}
+	if err != nil {
+		op.logger.Error(ERROR_LISTING_DIR, zap.Error(err), zap.String("filepath", prefix))
+		listed := append(append([]string{}, listing.Dirs...), listing.Files...)
+		return DirListing{}, op.wrapError(partialList(listed, err), "%s %s", ERROR_LISTING_DIR, prefix)
+	}
+	if len(shardDirs) > 0 {
+		listing.Dirs = sortedUnique(listing.Dirs)
+		sort.Strings(listing.Files)
+	}
+	return listing, nil
+}
+
+// sortedUnique sorts given names & drops duplicates
+func sortedUnique(names []string) []string {
+	sort.Strings(names)
+	unique := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// sortMarkersDeepestFirst orders directory markers so nested markers come before their parents
+func sortMarkersDeepestFirst(markers []string) {
+	sort.SliceStable(markers, func(i, j int) bool {
+		di, dj := strings.Count(markers[i], "/"), strings.Count(markers[j], "/")
+		if di != dj {
+			return di > dj
+		}
+		return markers[i] > markers[j]
+	})
+}
+
+// objectNames returns the names of given objects
+func objectNames(objs []*storage.ObjectAttrs) []string {
+	names := make([]string, len(objs))
+	for i, attrs := range objs {
+		names[i] = attrs.Name
+	}
+	return names
+}
+
+// hasChildIn reports whether any of given names is under parent
+func hasChildIn(parent string, names []string) bool {
+	for _, name := range names {
+		if strings.HasPrefix(name, parent) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package cloudstorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirPrefix(t *testing.T) {
+	for in, want := range map[string]string{
+		"":             "",
+		"/":            "",
+		"reports":      "reports/",
+		"reports/":     "reports/",
+		"/reports//q1": "reports/q1/",
+		"a/./b/../c":   "a/c/",
+	} {
+		require.Equal(t, want, dirPrefix(in), "dirPrefix(%q)", in)
+	}
+}
+
+func TestSortMarkersDeepestFirst(t *testing.T) {
+	markers := []string{"a/", "a/b/", "c/", "a/b/c/", "a/d/"}
+	sortMarkersDeepestFirst(markers)
+	require.Equal(t, []string{"a/b/c/", "a/d/", "a/b/", "c/", "a/"}, markers)
+}
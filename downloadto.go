@@ -0,0 +1,315 @@
+package cloudstorage
+
+import (
+	"context"
+	"hash/crc32"
+	"io"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const ERROR_DOWNLOADING_CHUNK string = "error downloading cloud file chunk"
+
+const (
+	// DEFAULT_DOWNLOAD_CHUNK_SIZE is the default range size of parallel downloads, the initial one when adaptive
+	DEFAULT_DOWNLOAD_CHUNK_SIZE = 8 * 1024 * 1024 // 8MB
+	// DEFAULT_DOWNLOAD_PARALLELISM is the default number of concurrent range reads
+	DEFAULT_DOWNLOAD_PARALLELISM = 4
+	// DEFAULT_MIN_DOWNLOAD_CHUNK_SIZE & DEFAULT_MAX_DOWNLOAD_CHUNK_SIZE bound adaptive chunk sizes
+	DEFAULT_MIN_DOWNLOAD_CHUNK_SIZE = 256 * 1024        // 256KB
+	DEFAULT_MAX_DOWNLOAD_CHUNK_SIZE = 128 * 1024 * 1024 // 128MB
+	// DEFAULT_CHUNK_TARGET_DURATION is the default time an adaptive chunk should take to download
+	DEFAULT_CHUNK_TARGET_DURATION = 1500 * time.Millisecond
+)
+
+// DownloadOptions configure DownloadToWriterAt
+type DownloadOptions struct {
+	// ChunkSize is the range size of each read, the initial one when adaptive,
+	// defaults to DEFAULT_DOWNLOAD_CHUNK_SIZE
+	ChunkSize int64
+	// Parallelism is the number of concurrent range reads, defaults to DEFAULT_DOWNLOAD_PARALLELISM
+	Parallelism int
+	// Adaptive sizes each next chunk from the measured throughput of finished ones,
+	// within MinChunkSize & MaxChunkSize, so that a chunk takes about TargetDuration
+	Adaptive       bool
+	MinChunkSize   int64
+	MaxChunkSize   int64
+	TargetDuration time.Duration
+}
+
+// DownloadOption sets download options
+type DownloadOption func(o *DownloadOptions)
+
+// WithDownloadChunkSize sets the range size of parallel downloads, the initial one when adaptive
+func WithDownloadChunkSize(size int64) DownloadOption {
+	return func(o *DownloadOptions) {
+		o.ChunkSize = size
+	}
+}
+
+// WithDownloadParallelism sets the number of concurrent range reads, DEFAULT_DOWNLOAD_PARALLELISM when not positive
+func WithDownloadParallelism(n int) DownloadOption {
+	return func(o *DownloadOptions) {
+		if n <= 0 {
+			n = DEFAULT_DOWNLOAD_PARALLELISM
+		}
+		o.Parallelism = n
+	}
+}
+
+// WithAdaptiveChunks sizes chunks between min & max from measured throughput, each taking about target,
+// bounds & target default when not positive
+func WithAdaptiveChunks(min, max int64, target time.Duration) DownloadOption {
+	return func(o *DownloadOptions) {
+		o.Adaptive = true
+		o.MinChunkSize, o.MaxChunkSize, o.TargetDuration = min, max, target
+	}
+}
+
+// chunkSizer picks chunk sizes, fixed or adapted to observed chunk throughput
+type chunkSizer struct {
+	size     int64
+	adaptive bool
+	min, max int64
+	target   time.Duration
+}
+
+// newChunkSizer returns the sizer of given options, defaults applied
+func newChunkSizer(dOpts DownloadOptions) *chunkSizer {
+	s := &chunkSizer{size: dOpts.ChunkSize, adaptive: dOpts.Adaptive, min: dOpts.MinChunkSize, max: dOpts.MaxChunkSize, target: dOpts.TargetDuration}
+	if s.size <= 0 {
+		s.size = DEFAULT_DOWNLOAD_CHUNK_SIZE
+	}
+	if !s.adaptive {
+		return s
+	}
+	if s.min <= 0 {
+		s.min = DEFAULT_MIN_DOWNLOAD_CHUNK_SIZE
+	}
+	if s.max <= 0 {
+		s.max = DEFAULT_MAX_DOWNLOAD_CHUNK_SIZE
+	}
+	if s.max < s.min {
+		s.max = s.min
+	}
+	if s.target <= 0 {
+		s.target = DEFAULT_CHUNK_TARGET_DURATION
+	}
+	s.size = s.clamp(s.size)
+	return s
+}
+
+func (s *chunkSizer) clamp(size int64) int64 {
+	if size < s.min {
+		return s.min
+	}
+	if size > s.max {
+		return s.max
+	}
+	return size
+}
+
+// observe adapts the chunk size to a chunk of given bytes that took d, halfway to the size
+// the measured throughput moves in the target duration, damping single slow or fast chunks
+func (s *chunkSizer) observe(bytes int64, d time.Duration) {
+	if !s.adaptive || bytes <= 0 {
+		return
+	}
+	if d <= 0 {
+		// faster than the clock resolves, as fast as allowed
+		s.size = s.max
+		return
+	}
+	ideal := float64(bytes) * float64(s.target) / float64(d)
+	if ideal > float64(s.max) {
+		ideal = float64(s.max)
+	}
+	s.size = s.clamp((s.size + int64(ideal)) / 2)
+}
+
+// sectionWriter writes to a writer at from an offset
+type sectionWriter struct {
+	w   io.WriterAt
+	off int64
+}
+
+func (sw *sectionWriter) Write(p []byte) (int, error) {
+	n, err := sw.w.WriteAt(p, sw.off)
+	sw.off += int64(n)
+	return n, err
+}
+
+// chunkRange is a chunk's offset, length & CRC32C once read
+type chunkRange struct {
+	off, n int64
+	crc    uint32
+}
+
+// DownloadToWriterAt downloads the cloud file into given writer at with concurrent range reads
+// of the generation seen first, each written at its offset. Chunks are cut from the remaining range
+// as readers free up, with adaptive chunks sized by the throughput of the chunks read so far.
+// The chunks' CRC32Cs are combined & verified against the object's. Transcoded gzip objects
+// can't be read by range & are downloaded sequentially like Download. Failed downloads return the bytes
+// written across chunks, at their offsets, not a contiguous prefix of the content.
+func (cs *cloudStorageClient) DownloadToWriterAt(ctx context.Context, w io.WriterAt, cfr CloudFileRequest, opts ...DownloadOption) (DownloadResult, error) {
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return DownloadResult{}, err
+	}
+	if cfr.bucket == "" {
+		return DownloadResult{}, ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return DownloadResult{}, ErrFileNameMissing
+	}
+	dOpts := DownloadOptions{Parallelism: DEFAULT_DOWNLOAD_PARALLELISM}
+	for _, opt := range opts {
+		opt(&dOpts)
+	}
+	if dOpts.Parallelism <= 0 {
+		dOpts.Parallelism = DEFAULT_DOWNLOAD_PARALLELISM
+	}
+	sizer := newChunkSizer(dOpts)
+
+	op := cs.startOperation(ctx, "DownloadToWriterAt", cfr)
+	// transcoded objects are downloaded by Download under the caller's context, its own operation's
+	parent := ctx
+	ctx = op.ctx
+	fPath := op.object
+	attrs, err := cs.fetchAttrs(ctx, op, cfr)
+	if err != nil {
+		defer op.finish()
+		op.logger.Error("cloud file inaccessible", zap.Error(err), zap.String("filepath", fPath))
+		return DownloadResult{}, op.wrapError(err, "cloud file inaccessible %s", fPath)
+	}
+	if attrs.ContentEncoding == "gzip" && !cfr.readCompressed {
+		op.logger.Debug("cloud file will be transcoded, downloading sequentially", zap.String("filepath", fPath))
+		op.finish()
+		return cs.Download(parent, &sectionWriter{w: w}, cfr)
+	}
+	defer op.finish()
+	op.storageClass = attrs.StorageClass
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	obj := cs.retrying(cs.objectHandle(cfr, fPath)).Generation(attrs.Generation).ReadCompressed(cfr.readCompressed)
+
+	op.startTransfer(TransferDownload)
+	// chunks are cut in offset order, the first chunk error is the cause
+	var mu sync.Mutex
+	var chunks []chunkRange
+	var next, written int64
+	var firstErr error
+	var wg sync.WaitGroup
+	for i := 0; i < dOpts.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				if next >= attrs.Size || firstErr != nil {
+					mu.Unlock()
+					return
+				}
+				c := chunkRange{off: next, n: sizer.size}
+				if rem := attrs.Size - next; c.n > rem {
+					c.n = rem
+				}
+				next += c.n
+				idx := len(chunks)
+				chunks = append(chunks, c)
+				mu.Unlock()
+
+				n, crc, d, err := cs.downloadChunk(ctx, op, obj, w, c, func() {
+					mu.Lock()
+					defer mu.Unlock()
+					op.markFirstByte()
+				})
+
+				mu.Lock()
+				// failed chunks count what they wrote before failing
+				written += n
+				if err != nil {
+					if firstErr == nil {
+						op.logger.Error(ERROR_DOWNLOADING_CHUNK, zap.Error(err), zap.String("filepath", fPath), zap.Int64("offset", c.off), zap.Int64("length", c.n))
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+					return
+				}
+				chunks[idx].crc = crc
+				sizer.observe(c.n, d)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	op.bytes = written
+	if err := firstErr; err != nil {
+		err = op.wrapError(err, "%s %s", ERROR_DOWNLOADING_CHUNK, fPath)
+		op.endTransfer(written, err)
+		return DownloadResult{Bytes: written, RequestID: op.requestID, Chunks: len(chunks)}, err
+	}
+
+	var got uint32
+	for _, c := range chunks {
+		got = CombineCRC32C(got, c.crc, c.n)
+	}
+	if got != attrs.CRC32C {
+		op.logger.Error(ERROR_CHECKSUM_MISMATCH, zap.String("filepath", fPath), zap.Uint32("want", attrs.CRC32C), zap.Uint32("got", got))
+		err := op.wrapError(errors.NewAppError(ERROR_CHECKSUM_MISMATCH), "%s %s", ERROR_CHECKSUM_MISMATCH, fPath)
+		op.endTransfer(written, err)
+		return DownloadResult{Bytes: written, RequestID: op.requestID, Chunks: len(chunks)}, err
+	}
+
+	m := op.endTransfer(written, nil)
+	op.logger.Debug("cloud file downloaded in chunks", zap.String("filepath", fPath), zap.Int("chunks", len(chunks)), zap.Int64("chunkSize", sizer.size), zap.Duration("duration", m.Duration))
+	return DownloadResult{
+		Bytes:           written,
+		RequestID:       op.requestID,
+		Verified:        true,
+		Attrs:           cfr.logicalAttrs(newObjectAttrs(attrs)),
+		Duration:        m.Duration,
+		TimeToFirstByte: m.TimeToFirstByte,
+		BytesPerSecond:  m.BytesPerSecond,
+		Chunks:          len(chunks),
+		ChunkSize:       sizer.size,
+	}, nil
+}
+
+// downloadChunk range reads given chunk of the object into the writer at its offset, returns the bytes written,
+// the chunk's CRC32C & how long the read took once a transfer slot was free, firstByte is called once
+// the chunk's reader is open. Failed chunks return the bytes written before failing.
+func (cs *cloudStorageClient) downloadChunk(ctx context.Context, op *operation, obj *storage.ObjectHandle, w io.WriterAt, c chunkRange, firstByte func()) (int64, uint32, time.Duration, error) {
+	release, err := op.acquireTransfer(ctx)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer release()
+	start := cs.now()
+	var rc *storage.Reader
+	err = op.retry(ctx, func() (err error) {
+		rc, err = obj.NewRangeReader(ctx, c.off, c.n)
+		return err
+	})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer rc.Close()
+	firstByte()
+
+	hasher := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	n, err := cs.buffers().copy(io.MultiWriter(&sectionWriter{w: w, off: c.off}, hasher), rc)
+	if err != nil {
+		return n, 0, 0, err
+	}
+	if n != c.n {
+		return n, 0, 0, io.ErrUnexpectedEOF
+	}
+	return n, hasher.Sum32(), cs.since(start), nil
+}
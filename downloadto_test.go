@@ -0,0 +1,160 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memWriterAt is an in memory io.WriterAt, safe for concurrent use
+type memWriterAt struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (m *memWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if end := off + int64(len(p)); end > int64(len(m.buf)) {
+		m.buf = append(m.buf, make([]byte, end-int64(len(m.buf)))...)
+	}
+	return copy(m.buf[off:], p), nil
+}
+
+// simulateLink makes range reads of f take latency plus their length over bandwidth on the clock,
+// with seeded jitter of up to a tenth
+func simulateLink(f *fakeGCS, clock *fakeClock, latency time.Duration, bandwidth int64, seed int64) {
+	rnd := rand.New(rand.NewSource(seed))
+	f.fail = func(r *http.Request) int {
+		rng := r.Header.Get("Range")
+		if r.Method != http.MethodGet || rng == "" {
+			return 0
+		}
+		var start, end int64
+		fmt.Sscanf(rng, "bytes=%d-%d", &start, &end)
+		d := latency + time.Duration(float64(end-start+1)/float64(bandwidth)*float64(time.Second))
+		clock.Advance(d + time.Duration(rnd.Int63n(int64(d)/10+1)))
+		return 0
+	}
+}
+
+func TestChunkSizer(t *testing.T) {
+	// fixed chunks stay as set
+	s := newChunkSizer(DownloadOptions{ChunkSize: 1024})
+	s.observe(1024, time.Hour)
+	require.Equal(t, int64(1024), s.size)
+
+	// chunks settle where a chunk takes the target on a simulated link
+	const latency, bandwidth, target = 100 * time.Millisecond, 8 * 1024 * 1024, time.Second
+	rnd := rand.New(rand.NewSource(1))
+	s = newChunkSizer(DownloadOptions{Adaptive: true, ChunkSize: 256 * 1024, TargetDuration: target})
+	for i := 0; i < 30; i++ {
+		d := latency + time.Duration(float64(s.size)/bandwidth*float64(time.Second))
+		s.observe(s.size, d+time.Duration(rnd.Int63n(int64(d)/20+1)))
+	}
+	want := float64(bandwidth) * (target - latency).Seconds()
+	require.InDelta(t, want, float64(s.size), want/10)
+
+	// latency over the target shrinks chunks to the minimum, instant reads grow them to the maximum
+	s = newChunkSizer(DownloadOptions{Adaptive: true, MinChunkSize: 1024, MaxChunkSize: 4096, TargetDuration: time.Second})
+	require.Equal(t, int64(4096), s.size, "the initial size is clamped")
+	for i := 0; i < 10; i++ {
+		s.observe(s.size, 2*time.Second)
+	}
+	require.Equal(t, int64(1024), s.size)
+	s.observe(s.size, 0)
+	require.Equal(t, int64(4096), s.size)
+}
+
+func TestDownloadToWriterAt(t *testing.T) {
+	content := make([]byte, 1024*1024+123)
+	rand.New(rand.NewSource(7)).Read(content)
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", content, nil)
+	cs := newFakeClient(t, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	var w memWriterAt
+	res, err := cs.DownloadToWriterAt(context.Background(), &w, cfr, WithDownloadChunkSize(256*1024), WithDownloadParallelism(3))
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(content, w.buf))
+	require.True(t, res.Verified)
+	require.Equal(t, int64(len(content)), res.Bytes)
+	require.Equal(t, 5, res.Chunks)
+	require.Equal(t, int64(256*1024), res.ChunkSize)
+	require.Equal(t, "path/file.bin", res.Attrs.Name)
+
+	// empty objects have no chunks
+	f.put("bucket", "path/empty.bin", nil, nil)
+	empty, err := NewCloudFileRequest("bucket", "empty.bin", "path", 0)
+	require.NoError(t, err)
+	res, err = cs.DownloadToWriterAt(context.Background(), &memWriterAt{}, empty)
+	require.NoError(t, err)
+	require.Zero(t, res.Chunks)
+	require.True(t, res.Verified)
+
+	// combined chunk checksums are verified against the object's
+	f.mu.Lock()
+	f.objects[fakeKey("bucket", "path/file.bin")].attrs.Crc32c = "AAAAAA=="
+	f.mu.Unlock()
+	_, err = cs.DownloadToWriterAt(context.Background(), &memWriterAt{}, cfr, WithDownloadChunkSize(256*1024))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ERROR_CHECKSUM_MISMATCH)
+}
+
+func TestDownloadToWriterAtAdaptive(t *testing.T) {
+	content := bytes.Repeat([]byte("adaptive"), 2*1024*1024)
+	download := func() DownloadResult {
+		f := newFakeGCS()
+		f.put("bucket", "path/file.bin", content, nil)
+		clock := newFakeClock()
+		simulateLink(f, clock, 50*time.Millisecond, 1024*1024, 42)
+		cs := newFakeClient(t, f)
+		WithClock(clock)(cs)
+		cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+		require.NoError(t, err)
+		var w memWriterAt
+		res, err := cs.DownloadToWriterAt(context.Background(), &w, cfr, WithDownloadParallelism(1),
+			WithDownloadChunkSize(64*1024), WithAdaptiveChunks(64*1024, 4*1024*1024, time.Second))
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(content, w.buf))
+		return res
+	}
+
+	res := download()
+	// a chunk of about a second moves 950KB on the simulated link
+	require.InDelta(t, 950*1024, float64(res.ChunkSize), 150*1024)
+	require.Less(t, res.Chunks, len(content)/(64*1024))
+	again := download()
+	require.Equal(t, res.ChunkSize, again.ChunkSize, "seeded runs adapt alike")
+	require.Equal(t, res.Chunks, again.Chunks)
+}
+
+func TestDownloadToWriterAtTranscoded(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	var gzBuf bytes.Buffer
+	gzw := gzip.NewWriter(&gzBuf)
+	_, err := gzw.Write([]byte("transcoded content"))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+	cfr, err := NewCloudFileRequest("bucket", "file.txt", "path", 0, WithContentEncoding("gzip"))
+	require.NoError(t, err)
+	_, err = cs.Upload(context.Background(), bytes.NewReader(gzBuf.Bytes()), cfr)
+	require.NoError(t, err)
+
+	// transcoded objects aren't read by range
+	res, err := cs.DownloadToWriterAt(context.Background(), &memWriterAt{}, cfr)
+	require.NoError(t, err)
+	require.True(t, res.Transcoded)
+	require.Zero(t, res.Chunks)
+}
@@ -0,0 +1,214 @@
+package cloudstorage
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"go.uber.org/zap"
+)
+
+type existenceEntry struct {
+	key string
+	// attrs are the found object's attributes, nil for a missing object
+	attrs   *ObjectAttrs
+	expires time.Time
+}
+
+// existenceCache is a size bounded LRU cache of object existence & attributes, safe for concurrent use.
+// Found & missing objects are cached with separate TTLs, a zero TTL doesn't cache that kind.
+type existenceCache struct {
+	mu          sync.Mutex
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+	entries     map[string]*list.Element
+	lru         *list.List
+	// invalidations counts invalidations, lookups started before one aren't cached
+	invalidations uint64
+}
+
+func newExistenceCache(size int, ttl, negativeTTL time.Duration) *existenceCache {
+	return &existenceCache{
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     map[string]*list.Element{},
+		lru:         list.New(),
+	}
+}
+
+// get returns the cached attributes, nil for a missing object, & whether the entry was found & live
+func (c *existenceCache) get(key string) (*ObjectAttrs, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*existenceEntry)
+	if !c.now().Before(entry.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return copyObjectAttrs(entry.attrs), true
+}
+
+// token returns the invalidation count, taken before a lookup & passed to put
+func (c *existenceCache) token() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.invalidations
+}
+
+// put caches given lookup result, nil attrs for a missing object,
+// dropped when any invalidation happened since the token was taken
+func (c *existenceCache) put(key string, attrs *ObjectAttrs, token uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ttl := c.ttl
+	if attrs == nil {
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 || token != c.invalidations {
+		return
+	}
+	entry := &existenceEntry{key: key, attrs: copyObjectAttrs(attrs), expires: c.now().Add(ttl)}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*existenceEntry).key)
+	}
+}
+
+func (c *existenceCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidations++
+	if el, ok := c.entries[key]; ok {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+// copyObjectAttrs returns a copy of given attributes, callers can't change cached metadata
+func copyObjectAttrs(attrs *ObjectAttrs) *ObjectAttrs {
+	if attrs == nil {
+		return nil
+	}
+	cp := *attrs
+	if attrs.Metadata != nil {
+		cp.Metadata = make(map[string]string, len(attrs.Metadata))
+		for k, v := range attrs.Metadata {
+			cp.Metadata[k] = v
+		}
+	}
+	return &cp
+}
+
+// Invalidate drops the cached existence of given object, for changes made outside this client.
+// Uploads, copies, metadata updates & deletes through this client invalidate their objects.
+func (cs *cloudStorageClient) Invalidate(bucket, object string) {
+	cs.invalidate(bucket, object)
+}
+
+// invalidate drops the cached existence of given object, when the cache is enabled
+func (cs *cloudStorageClient) invalidate(bucket, object string) {
+	if cs.existCache != nil {
+		cs.existCache.invalidate(existenceKey(bucket, object))
+	}
+}
+
+// existenceKey returns the cache key of given object
+func existenceKey(bucket, object string) string {
+	return bucket + "/" + object
+}
+
+// statObject returns attributes of the request's object, from the existence cache when enabled.
+// Requests for a generation or with preconditions bypass the cache,
+// missing objects fail with storage.ErrObjectNotExist either way.
+func (cs *cloudStorageClient) statObject(ctx context.Context, op *operation, cfr CloudFileRequest) (*ObjectAttrs, error) {
+	cache := cs.existCache
+	if cache == nil || cfr.generation != 0 || cfr.metagenerationMatch != 0 {
+		attrs, err := cs.fetchAttrs(ctx, op, cfr)
+		if err != nil {
+			return nil, err
+		}
+		return newObjectAttrs(attrs), nil
+	}
+
+	key := existenceKey(cfr.bucket, op.object)
+	if attrs, ok := cache.get(key); ok {
+		op.logger.Debug("cloud file existence cached", zap.String("filepath", op.object), zap.Bool("exists", attrs != nil))
+		if attrs == nil {
+			return nil, storage.ErrObjectNotExist
+		}
+		return attrs, nil
+	}
+	token := cache.token()
+	attrs, err := cs.fetchAttrs(ctx, op, cfr)
+	switch {
+	case err == nil:
+		cache.put(key, newObjectAttrs(attrs), token)
+	case err == storage.ErrObjectNotExist:
+		cache.put(key, nil, token)
+		return nil, err
+	default:
+		return nil, err
+	}
+	return newObjectAttrs(attrs), nil
+}
+
+// fetchAttrs requests attributes of the request's object, retried with the client's retry policy
+func (cs *cloudStorageClient) fetchAttrs(ctx context.Context, op *operation, cfr CloudFileRequest) (*storage.ObjectAttrs, error) {
+	obj := cs.retrying(cs.objectHandle(cfr, op.object))
+	var attrs *storage.ObjectAttrs
+	err := op.retry(ctx, func() (err error) {
+		attrs, err = obj.Attrs(ctx)
+		return err
+	})
+	return attrs, err
+}
+
+// Exists reports whether the cloud file at request's bucket & filepath exists,
+// answered from the existence cache when enabled
+func (cs *cloudStorageClient) Exists(ctx context.Context, cfr CloudFileRequest) (bool, error) {
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return false, err
+	}
+	if cfr.bucket == "" {
+		return false, ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return false, ErrFileNameMissing
+	}
+	op := cs.startOperation(ctx, "Exists", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	_, err = cs.statObject(ctx, op, cfr)
+	if err == storage.ErrObjectNotExist {
+		return false, nil
+	}
+	if err != nil {
+		op.logger.Error(ERROR_GETTING_ATTRS, zap.Error(err), zap.String("filepath", op.object))
+		return false, op.wrapError(err, "%s %s", ERROR_GETTING_ATTRS, op.object)
+	}
+	return true, nil
+}
@@ -0,0 +1,167 @@
+package cloudstorage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// countAttrs counts the fake's object metadata requests
+func countAttrs(f *fakeGCS) *int64 {
+	var gets int64
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/storage/v1/b/bucket/o/") {
+			atomic.AddInt64(&gets, 1)
+		}
+		return 0
+	}
+	return &gets
+}
+
+// newCachedClient returns a fake backed client with existence cache & manually advanced clock
+func newCachedClient(t *testing.T, f *fakeGCS, size int, ttl, negativeTTL time.Duration) (*cloudStorageClient, *fakeClock) {
+	cs := newFakeClient(t, f)
+	clock := newFakeClock()
+	cs.clock = clock
+	cs.existCache = newExistenceCache(size, ttl, negativeTTL)
+	cs.existCache.now = cs.now
+	return cs, clock
+}
+
+func TestExistenceCacheNegativeStaleness(t *testing.T) {
+	f := newFakeGCS()
+	gets := countAttrs(f)
+	cs, clock := newCachedClient(t, f, 10, time.Minute, 10*time.Second)
+	cfr, err := NewCloudFileRequest("bucket", "override.json", "tenant", 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		exists, err := cs.Exists(ctx, cfr)
+		require.NoError(t, err)
+		require.False(t, exists)
+	}
+	require.Equal(t, int64(1), atomic.LoadInt64(gets))
+
+	// created out of band, missing is still reported for the rest of the negative TTL
+	f.put("bucket", "tenant/override.json", []byte("{}"), nil)
+	clock.Advance(9 * time.Second)
+	exists, err := cs.Exists(ctx, cfr)
+	require.NoError(t, err)
+	require.False(t, exists)
+	_, err = cs.GetAttrs(ctx, cfr)
+	require.ErrorIs(t, err, storage.ErrObjectNotExist)
+	require.Equal(t, int64(1), atomic.LoadInt64(gets))
+
+	// expired at exactly the TTL
+	clock.Advance(time.Second)
+	exists, err = cs.Exists(ctx, cfr)
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, int64(2), atomic.LoadInt64(gets))
+}
+
+func TestExistenceCachePositiveStaleness(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "tenant/override.json", []byte("{}"), map[string]string{"k": "v"})
+	gets := countAttrs(f)
+	cs, clock := newCachedClient(t, f, 10, time.Minute, 10*time.Second)
+	cfr, err := NewCloudFileRequest("bucket", "override.json", "tenant", 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	attrs, err := cs.GetAttrs(ctx, cfr)
+	require.NoError(t, err)
+	// cached attributes are copies
+	attrs.Metadata["k"] = "changed"
+
+	// deleted out of band, the cached attributes are returned for the rest of the TTL
+	f.mu.Lock()
+	delete(f.objects, fakeKey("bucket", "tenant/override.json"))
+	f.mu.Unlock()
+	clock.Advance(59 * time.Second)
+	attrs, err = cs.GetAttrs(ctx, cfr)
+	require.NoError(t, err)
+	require.Equal(t, "v", attrs.Metadata["k"])
+	exists, err := cs.Exists(ctx, cfr)
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, int64(1), atomic.LoadInt64(gets))
+
+	clock.Advance(time.Second)
+	exists, err = cs.Exists(ctx, cfr)
+	require.NoError(t, err)
+	require.False(t, exists)
+	require.Equal(t, int64(2), atomic.LoadInt64(gets))
+}
+
+func TestExistenceCacheInvalidation(t *testing.T) {
+	f := newFakeGCS()
+	gets := countAttrs(f)
+	cs, _ := newCachedClient(t, f, 10, time.Minute, time.Minute)
+	cfr, err := NewCloudFileRequest("bucket", "override.json", "tenant", 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	exists, err := cs.Exists(ctx, cfr)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// uploads & deletes through the client are seen immediately
+	_, err = cs.Upload(ctx, strings.NewReader("{}"), cfr)
+	require.NoError(t, err)
+	exists, err = cs.Exists(ctx, cfr)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	require.NoError(t, cs.DeleteObject(ctx, cfr))
+	exists, err = cs.Exists(ctx, cfr)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// out of band changes need an explicit invalidation
+	f.put("bucket", "tenant/override.json", []byte("{}"), nil)
+	exists, err = cs.Exists(ctx, cfr)
+	require.NoError(t, err)
+	require.False(t, exists)
+	cs.Invalidate("bucket", "tenant/override.json")
+	exists, err = cs.Exists(ctx, cfr)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	// generation reads bypass the cache
+	before := atomic.LoadInt64(gets)
+	_, err = cs.GetAttrs(ctx, CloudFileRequest{bucket: "bucket", path: "tenant", file: "override.json", generation: 1})
+	require.Error(t, err)
+	require.Equal(t, before+1, atomic.LoadInt64(gets))
+}
+
+func TestExistenceCacheSizeCap(t *testing.T) {
+	f := newFakeGCS()
+	gets := countAttrs(f)
+	cs, _ := newCachedClient(t, f, 2, time.Minute, time.Minute)
+	ctx := context.Background()
+	exists := func(i int) {
+		_, err := cs.Exists(ctx, CloudFileRequest{bucket: "bucket", path: "tenant", file: fmt.Sprintf("%d.json", i)})
+		require.NoError(t, err)
+	}
+
+	exists(1)
+	exists(2)
+	exists(1)
+	// evicts 2, the least recently used
+	exists(3)
+	require.Equal(t, int64(3), atomic.LoadInt64(gets))
+	exists(1)
+	exists(3)
+	require.Equal(t, int64(3), atomic.LoadInt64(gets))
+	exists(2)
+	require.Equal(t, int64(4), atomic.LoadInt64(gets))
+}
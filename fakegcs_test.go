@@ -0,0 +1,841 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/cloudstorage/cloudstoragetest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	raw "google.golang.org/api/storage/v1"
+)
+
+// newFakeClient returns a storage client backed by given JSON API handler
+func newFakeClient(t testing.TB, handler http.Handler) *cloudStorageClient {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	// clients send requests through the transports NewCloudStorageClient always installs
+	hc := withCallExtrasTransport(srv.Client())
+	client, err := storage.NewClient(
+		context.Background(),
+		option.WithEndpoint(srv.URL+"/storage/v1/"),
+		option.WithHTTPClient(hc),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return &cloudStorageClient{
+		client:  client,
+		jsonAPI: &jsonAPIClient{client: hc, endpoint: srv.URL + "/storage/v1/"},
+		logger:  &recordingLogger{},
+		life:    newLifecycle(),
+	}
+}
+
+// writeAPIError writes a JSON API error response
+func writeAPIError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+type fakeObject struct {
+	attrs raw.Object
+	data  []byte
+}
+
+// fakeGCS is an in memory JSON & XML API backend for unit tests,
+// supports object get, list (with start & end offsets), multipart & resumable upload, download, patch, delete, rewrite, compose
+// & soft deleted object listing & restore
+type fakeGCS struct {
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+	gen     int64
+	// fail, when set, is called before every request, a non zero status fails the request
+	fail func(r *http.Request) int
+	// cutReads is the number of content reads broken off halfway
+	cutReads int
+	// versioned keeps replaced & deleted generations as noncurrent versions
+	versioned bool
+	archived  map[string][]*fakeObject
+	// pageSize, when set, splits listings into pages of that many objects
+	pageSize int
+	// sessions are the open resumable uploads by upload ID
+	sessions    map[string]*fakeSession
+	nextSession int
+	// buckets, when set, are the existing buckets, every bucket exists otherwise
+	buckets map[string]bool
+	// bucketAttrs are the bucket attributes by name, default attributes otherwise
+	bucketAttrs map[string]*raw.Bucket
+	// softDelete are the soft delete policies by bucket name, deleted live objects of those buckets
+	// are kept as soft deleted generations, unless versioned
+	softDelete  map[string]*softDeletePolicy
+	softDeleted map[string][]*fakeObject
+}
+
+// fakeSession is an open resumable upload
+type fakeSession struct {
+	start *http.Request
+	attrs raw.Object
+	data  []byte
+}
+
+func newFakeGCS() *fakeGCS {
+	return &fakeGCS{objects: map[string]*fakeObject{}, archived: map[string][]*fakeObject{}, sessions: map[string]*fakeSession{}}
+}
+
+// archive keeps the live generation of given key as noncurrent version when versioned
+func (f *fakeGCS) archive(key string) {
+	obj, ok := f.objects[key]
+	if !ok || !f.versioned {
+		return
+	}
+	archived := *obj
+	archived.attrs.TimeDeleted = time.Now().UTC().Format(time.RFC3339Nano)
+	f.archived[key] = append(f.archived[key], &archived)
+}
+
+// lookup returns the live object or the given generation, live or noncurrent
+func (f *fakeGCS) lookup(bucket, name string, generation int64) (*fakeObject, bool) {
+	key := fakeKey(bucket, name)
+	obj, ok := f.objects[key]
+	if generation == 0 || (ok && obj.attrs.Generation == generation) {
+		return obj, ok
+	}
+	for _, a := range f.archived[key] {
+		if a.attrs.Generation == generation {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// generationMatches reports whether request's ifGenerationMatch precondition holds for the live object
+func (f *fakeGCS) generationMatches(r *http.Request, bucket, name string) bool {
+	q := r.URL.Query().Get("ifGenerationMatch")
+	if q == "" {
+		return true
+	}
+	want, _ := strconv.ParseInt(q, 10, 64)
+	existing, exists := f.objects[fakeKey(bucket, name)]
+	if want == 0 {
+		return !exists
+	}
+	return exists && existing.attrs.Generation == want
+}
+
+// queryGeneration returns request's generation query parameter, zero when not set
+func queryGeneration(r *http.Request, param string) int64 {
+	gen, _ := strconv.ParseInt(r.URL.Query().Get(param), 10, 64)
+	return gen
+}
+
+func fakeKey(bucket, name string) string {
+	return bucket + "/" + name
+}
+
+// put stores an object directly
+func (f *fakeGCS) put(bucket, name string, data []byte, metadata map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.store(raw.Object{Bucket: bucket, Name: name, Metadata: metadata}, data)
+}
+
+// get returns a stored object's content & attributes
+func (f *fakeGCS) get(bucket, name string) ([]byte, raw.Object, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[fakeKey(bucket, name)]
+	if !ok {
+		return nil, raw.Object{}, false
+	}
+	return obj.data, obj.attrs, true
+}
+
+func (f *fakeGCS) store(attrs raw.Object, data []byte) *raw.Object {
+	f.gen++
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+	sum := md5.Sum(data)
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	attrs.Size = uint64(len(data))
+	attrs.Generation = f.gen
+	attrs.Metageneration = 1
+	attrs.Crc32c = base64.StdEncoding.EncodeToString(crc)
+	// composed objects have no MD5, like the service's
+	attrs.Md5Hash = ""
+	if attrs.ComponentCount == 0 {
+		attrs.Md5Hash = base64.StdEncoding.EncodeToString(sum[:])
+	}
+	attrs.Etag = fmt.Sprintf("etag-%d-1", f.gen)
+	attrs.TimeCreated = now
+	attrs.Updated = now
+	if attrs.StorageClass == "" {
+		attrs.StorageClass = "STANDARD"
+	}
+	attrs.TimeDeleted = ""
+	f.archive(fakeKey(attrs.Bucket, attrs.Name))
+	f.objects[fakeKey(attrs.Bucket, attrs.Name)] = &fakeObject{attrs: attrs, data: data}
+	return &attrs
+}
+
+// the fake is a fixtures backend
+var _ cloudstoragetest.Backend = &fakeGCS{}
+
+// PutObject stores a fixture object, with its update time when set
+func (f *fakeGCS) PutObject(bucket string, obj cloudstoragetest.Object) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.store(raw.Object{Bucket: bucket, Name: obj.Name, ContentType: obj.ContentType, Metadata: obj.Metadata}, obj.Content)
+	if !obj.Updated.IsZero() {
+		f.objects[fakeKey(bucket, obj.Name)].attrs.Updated = obj.Updated.UTC().Format(time.RFC3339Nano)
+	}
+	return nil
+}
+
+// ReadObject returns a stored object's content
+func (f *fakeGCS) ReadObject(bucket, name string) ([]byte, bool, error) {
+	data, _, ok := f.get(bucket, name)
+	return data, ok, nil
+}
+
+// ListNames returns the stored names under given prefix
+func (f *fakeGCS) ListNames(bucket, prefix string) ([]string, error) {
+	names := []string{}
+	for _, name := range f.storedNames(bucket) {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// pathSegments returns the unescaped segments of request's path
+func pathSegments(r *http.Request) []string {
+	segs := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	for i, s := range segs {
+		if u, err := url.PathUnescape(s); err == nil {
+			segs[i] = u
+		}
+	}
+	return segs
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.fail != nil {
+		if code := f.fail(r); code != 0 {
+			writeAPIError(w, code, http.StatusText(code))
+			return
+		}
+	}
+	segs := pathSegments(r)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case len(segs) >= 5 && segs[0] == "upload" && r.URL.Query().Get("uploadType") == "resumable":
+		f.resumable(w, r, segs[4])
+	case len(segs) >= 5 && segs[0] == "upload" && r.Method == http.MethodPost:
+		f.upload(w, r, segs[4])
+	case len(segs) == 4 && segs[0] == "storage" && r.Method == http.MethodGet:
+		if f.buckets != nil && !f.buckets[segs[3]] {
+			writeAPIError(w, http.StatusNotFound, "bucket not found")
+			return
+		}
+		f.writeBucket(w, segs[3])
+	case len(segs) == 4 && segs[0] == "storage" && r.Method == http.MethodPatch:
+		f.patchBucket(w, r, segs[3])
+	case len(segs) == 3 && segs[0] == "storage" && segs[2] == "b" && r.Method == http.MethodPost:
+		f.createBucket(w, r)
+	case len(segs) == 5 && segs[0] == "storage" && r.Method == http.MethodGet:
+		f.list(w, r, segs[3])
+	case len(segs) == 5 && segs[0] == "storage" && segs[4] == "lockRetentionPolicy" && r.Method == http.MethodPost:
+		f.lockRetentionPolicy(w, r, segs[3])
+	case len(segs) >= 6 && segs[0] == "storage" && strings.Contains(r.URL.Path, "/rewriteTo/"):
+		f.rewrite(w, r, segs)
+	case len(segs) >= 7 && segs[0] == "storage" && segs[len(segs)-1] == "compose" && r.Method == http.MethodPost:
+		f.compose(w, r, segs[3], strings.Join(segs[5:len(segs)-1], "/"))
+	case len(segs) >= 7 && segs[0] == "storage" && segs[len(segs)-1] == "restore" && r.Method == http.MethodPost:
+		f.restore(w, r, segs[3], strings.Join(segs[5:len(segs)-1], "/"))
+	case len(segs) >= 6 && segs[0] == "storage":
+		f.object(w, r, segs[3], strings.Join(segs[5:], "/"))
+	case len(segs) >= 2 && (r.Method == http.MethodGet || r.Method == http.MethodHead):
+		f.read(w, r, segs[0], strings.Join(segs[1:], "/"))
+	default:
+		writeAPIError(w, http.StatusNotImplemented, "unsupported fake request "+r.Method+" "+r.URL.Path)
+	}
+}
+
+// bucket returns the attributes of given bucket
+func (f *fakeGCS) bucket(name string) *raw.Bucket {
+	if f.bucketAttrs == nil {
+		f.bucketAttrs = map[string]*raw.Bucket{}
+	}
+	if _, ok := f.bucketAttrs[name]; !ok {
+		f.bucketAttrs[name] = &raw.Bucket{Name: name, Metageneration: 1}
+	}
+	return f.bucketAttrs[name]
+}
+
+// writeBucket writes the attributes of given bucket, with the soft delete policy raw.Bucket doesn't know
+func (f *fakeGCS) writeBucket(w http.ResponseWriter, name string) {
+	resp := map[string]interface{}{}
+	data, _ := json.Marshal(f.bucket(name))
+	json.Unmarshal(data, &resp)
+	if p := f.softDelete[name]; p != nil {
+		resp["softDeletePolicy"] = p
+	}
+	writeJSON(w, resp)
+}
+
+// patchBucket merges versioning, labels, rpo & soft delete policy, null labels are removed
+func (f *fakeGCS) patchBucket(w http.ResponseWriter, r *http.Request, name string) {
+	if f.buckets != nil && !f.buckets[name] {
+		writeAPIError(w, http.StatusNotFound, "bucket not found")
+		return
+	}
+	var patch struct {
+		Versioning       *raw.BucketVersioning `json:"versioning"`
+		Labels           map[string]*string    `json:"labels"`
+		SoftDeletePolicy *softDeletePolicy     `json:"softDeletePolicy"`
+		Rpo              string                `json:"rpo"`
+		// RetentionPolicy is null to remove the policy
+		RetentionPolicy       json.RawMessage `json:"retentionPolicy"`
+		DefaultEventBasedHold *bool           `json:"defaultEventBasedHold"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bucket := f.bucket(name)
+	if v := r.URL.Query().Get("ifMetagenerationMatch"); v != "" && v != strconv.FormatInt(bucket.Metageneration, 10) {
+		writeAPIError(w, http.StatusPreconditionFailed, "metageneration mismatch")
+		return
+	}
+	if patch.Versioning != nil {
+		bucket.Versioning = patch.Versioning
+	}
+	if patch.Rpo != "" {
+		bucket.Rpo = patch.Rpo
+	}
+	if patch.RetentionPolicy != nil {
+		var rp *raw.BucketRetentionPolicy
+		json.Unmarshal(patch.RetentionPolicy, &rp)
+		if locked := bucket.RetentionPolicy; locked != nil && locked.IsLocked && (rp == nil || rp.RetentionPeriod < locked.RetentionPeriod) {
+			writeAPIError(w, http.StatusForbidden, "Cannot reduce retention duration of a locked Retention Policy for bucket '"+name+"'.")
+			return
+		}
+		if rp != nil {
+			rp.EffectiveTime = time.Now().UTC().Format(time.RFC3339Nano)
+		}
+		bucket.RetentionPolicy = rp
+	}
+	if patch.DefaultEventBasedHold != nil {
+		bucket.DefaultEventBasedHold = *patch.DefaultEventBasedHold
+	}
+	if patch.Labels != nil && bucket.Labels == nil {
+		bucket.Labels = map[string]string{}
+	}
+	for k, v := range patch.Labels {
+		if v == nil {
+			delete(bucket.Labels, k)
+		} else {
+			bucket.Labels[k] = *v
+		}
+	}
+	if patch.SoftDeletePolicy != nil {
+		if f.softDelete == nil {
+			f.softDelete = map[string]*softDeletePolicy{}
+		}
+		patch.SoftDeletePolicy.EffectiveTime = time.Now().UTC().Format(time.RFC3339Nano)
+		f.softDelete[name] = patch.SoftDeletePolicy
+	}
+	bucket.Metageneration++
+	f.writeBucket(w, name)
+}
+
+// lockRetentionPolicy locks the bucket's retention policy, the metageneration must match
+func (f *fakeGCS) lockRetentionPolicy(w http.ResponseWriter, r *http.Request, name string) {
+	if f.buckets != nil && !f.buckets[name] {
+		writeAPIError(w, http.StatusNotFound, "bucket not found")
+		return
+	}
+	bucket := f.bucket(name)
+	if r.URL.Query().Get("ifMetagenerationMatch") != strconv.FormatInt(bucket.Metageneration, 10) {
+		writeAPIError(w, http.StatusPreconditionFailed, "metageneration mismatch")
+		return
+	}
+	if bucket.RetentionPolicy == nil {
+		writeAPIError(w, http.StatusBadRequest, "no retention policy to lock")
+		return
+	}
+	bucket.RetentionPolicy.IsLocked = true
+	bucket.Metageneration++
+	f.writeBucket(w, name)
+}
+
+func (f *fakeGCS) createBucket(w http.ResponseWriter, r *http.Request) {
+	var bucket raw.Bucket
+	if err := json.NewDecoder(r.Body).Decode(&bucket); err != nil || r.URL.Query().Get("project") == "" {
+		writeAPIError(w, http.StatusBadRequest, "invalid bucket")
+		return
+	}
+	if f.buckets == nil {
+		f.buckets = map[string]bool{}
+	}
+	if f.buckets[bucket.Name] {
+		writeAPIError(w, http.StatusConflict, "bucket exists")
+		return
+	}
+	f.buckets[bucket.Name] = true
+	writeJSON(w, bucket)
+}
+
+func (f *fakeGCS) upload(w http.ResponseWriter, r *http.Request, bucket string) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	part, err := mr.NextPart()
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var attrs raw.Object
+	if err := json.NewDecoder(part).Decode(&attrs); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	part, err = mr.NextPart()
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data, err := io.ReadAll(part)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if attrs.Name == "" {
+		attrs.Name = r.URL.Query().Get("name")
+	}
+	attrs.Bucket = bucket
+	if attrs.ContentType == "" {
+		attrs.ContentType = part.Header.Get("Content-Type")
+	}
+
+	if !f.generationMatches(r, bucket, attrs.Name) {
+		writeAPIError(w, http.StatusPreconditionFailed, "precondition failed")
+		return
+	}
+	if attrs.Crc32c != "" {
+		crc := make([]byte, 4)
+		binary.BigEndian.PutUint32(crc, crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+		if attrs.Crc32c != base64.StdEncoding.EncodeToString(crc) {
+			writeAPIError(w, http.StatusBadRequest, "crc32c mismatch")
+			return
+		}
+	}
+	writeJSON(w, f.store(attrs, data))
+}
+
+// resumable starts a resumable upload session, then appends chunks posted to the session URL,
+// the chunk with the total size in its content range commits the object
+func (f *fakeGCS) resumable(w http.ResponseWriter, r *http.Request, bucket string) {
+	if r.URL.Query().Get("upload_id") == "" {
+		var attrs raw.Object
+		if err := json.NewDecoder(r.Body).Decode(&attrs); err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if attrs.Name == "" {
+			attrs.Name = r.URL.Query().Get("name")
+		}
+		attrs.Bucket = bucket
+		// IDs aren't reused once sessions complete
+		f.nextSession++
+		id := strconv.Itoa(f.nextSession)
+		f.sessions[id] = &fakeSession{start: &http.Request{URL: r.URL}, attrs: attrs}
+		w.Header().Set("Location", "http://"+r.Host+r.URL.Path+"?uploadType=resumable&upload_id="+id)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	sess, ok := f.sessions[r.URL.Query().Get("upload_id")]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "no such upload")
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// content range is "bytes first-last/total", total is "*" until the last chunk
+	rng := strings.TrimPrefix(r.Header.Get("Content-Range"), "bytes ")
+	total := rng[strings.Index(rng, "/")+1:]
+	// "bytes */total" commits the chunks already sent
+	if !strings.HasPrefix(rng, "*") {
+		var first int
+		fmt.Sscanf(rng, "%d-", &first)
+		sess.data = append(sess.data[:first], data...)
+	}
+	if total == "*" {
+		// clients send X-GUploader-No-308, incomplete is signalled with a 200 override header
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(sess.data)-1))
+		w.Header().Set("X-Http-Status-Code-Override", "308")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	delete(f.sessions, r.URL.Query().Get("upload_id"))
+	if !f.generationMatches(sess.start, bucket, sess.attrs.Name) {
+		writeAPIError(w, http.StatusPreconditionFailed, "precondition failed")
+		return
+	}
+	if sess.attrs.Crc32c != "" {
+		crc := make([]byte, 4)
+		binary.BigEndian.PutUint32(crc, crc32.Checksum(sess.data, crc32.MakeTable(crc32.Castagnoli)))
+		if sess.attrs.Crc32c != base64.StdEncoding.EncodeToString(crc) {
+			writeAPIError(w, http.StatusBadRequest, "crc32c mismatch")
+			return
+		}
+	}
+	writeJSON(w, f.store(sess.attrs, sess.data))
+}
+
+// page returns the bounds of request's page of given listing size, sets the next page token
+func (f *fakeGCS) page(r *http.Request, size int, resp *raw.Objects) (int, int) {
+	if f.pageSize <= 0 {
+		return 0, size
+	}
+	start, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+	if start > size {
+		start = size
+	}
+	end := start + f.pageSize
+	if end >= size {
+		return start, size
+	}
+	resp.NextPageToken = strconv.Itoa(end)
+	return start, end
+}
+
+func (f *fakeGCS) list(w http.ResponseWriter, r *http.Request, bucket string) {
+	prefix := r.URL.Query().Get("prefix")
+	delimiter := r.URL.Query().Get("delimiter")
+	startOffset := r.URL.Query().Get("startOffset")
+	endOffset := r.URL.Query().Get("endOffset")
+
+	names := []string{}
+	for _, obj := range f.objects {
+		if obj.attrs.Bucket == bucket && strings.HasPrefix(obj.attrs.Name, prefix) && obj.attrs.Name >= startOffset && (endOffset == "" || obj.attrs.Name < endOffset) {
+			names = append(names, obj.attrs.Name)
+		}
+	}
+	if r.URL.Query().Get("softDeleted") == "true" {
+		f.listSoftDeleted(w, r, bucket, prefix)
+		return
+	}
+	if r.URL.Query().Get("versions") == "true" {
+		// noncurrent & live generations, by name & generation
+		versionItems := []*raw.Object{}
+		for _, versions := range f.archived {
+			for _, v := range versions {
+				if v.attrs.Bucket == bucket && strings.HasPrefix(v.attrs.Name, prefix) {
+					attrs := v.attrs
+					versionItems = append(versionItems, &attrs)
+				}
+			}
+		}
+		for _, name := range names {
+			attrs := f.objects[fakeKey(bucket, name)].attrs
+			versionItems = append(versionItems, &attrs)
+		}
+		sort.SliceStable(versionItems, func(i, j int) bool {
+			if versionItems[i].Name != versionItems[j].Name {
+				return versionItems[i].Name < versionItems[j].Name
+			}
+			return versionItems[i].Generation < versionItems[j].Generation
+		})
+		resp := raw.Objects{}
+		start, end := f.page(r, len(versionItems), &resp)
+		resp.Items = versionItems[start:end]
+		writeJSON(w, resp)
+		return
+	}
+	sort.Strings(names)
+
+	resp := raw.Objects{}
+	start, end := f.page(r, len(names), &resp)
+	names = names[start:end]
+	seen := map[string]bool{}
+	for _, name := range names {
+		if delimiter != "" {
+			if i := strings.Index(name[len(prefix):], delimiter); i >= 0 {
+				p := name[:len(prefix)+i+len(delimiter)]
+				if !seen[p] {
+					seen[p] = true
+					resp.Prefixes = append(resp.Prefixes, p)
+				}
+				continue
+			}
+		}
+		attrs := f.objects[fakeKey(bucket, name)].attrs
+		resp.Items = append(resp.Items, &attrs)
+	}
+	writeJSON(w, resp)
+}
+
+func (f *fakeGCS) object(w http.ResponseWriter, r *http.Request, bucket, name string) {
+	obj, ok := f.lookup(bucket, name, queryGeneration(r, "generation"))
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "No such object: "+bucket+"/"+name)
+		return
+	}
+	if q := r.URL.Query().Get("ifGenerationMatch"); q != "" {
+		if want, _ := strconv.ParseInt(q, 10, 64); want != obj.attrs.Generation {
+			writeAPIError(w, http.StatusPreconditionFailed, "precondition failed")
+			return
+		}
+	}
+	if q := r.URL.Query().Get("ifMetagenerationMatch"); q != "" {
+		if want, _ := strconv.ParseInt(q, 10, 64); want != obj.attrs.Metageneration {
+			writeAPIError(w, http.StatusPreconditionFailed, "precondition failed")
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, obj.attrs)
+	case http.MethodDelete:
+		f.softDeleteObject(bucket, name)
+		f.archive(fakeKey(bucket, name))
+		delete(f.objects, fakeKey(bucket, name))
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPatch:
+		var patch struct {
+			Metadata    map[string]*string `json:"metadata"`
+			ContentType *string            `json:"contentType"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if patch.Metadata != nil && obj.attrs.Metadata == nil {
+			obj.attrs.Metadata = map[string]string{}
+		}
+		for k, v := range patch.Metadata {
+			if v == nil {
+				delete(obj.attrs.Metadata, k)
+			} else {
+				obj.attrs.Metadata[k] = *v
+			}
+		}
+		if patch.ContentType != nil {
+			obj.attrs.ContentType = *patch.ContentType
+		}
+		obj.attrs.Metageneration++
+		obj.attrs.Etag = fmt.Sprintf("etag-%d-%d", obj.attrs.Generation, obj.attrs.Metageneration)
+		writeJSON(w, obj.attrs)
+	default:
+		writeAPIError(w, http.StatusNotImplemented, "unsupported fake object method "+r.Method)
+	}
+}
+
+// rewrite copies path "storage/v1/b/{src}/o/{srcObj}/rewriteTo/b/{dst}/o/{dstObj}"
+func (f *fakeGCS) rewrite(w http.ResponseWriter, r *http.Request, segs []string) {
+	joined := strings.Join(segs[5:], "/")
+	i := strings.Index(joined, "/rewriteTo/b/")
+	srcName := joined[:i]
+	rest := strings.SplitN(joined[i+len("/rewriteTo/b/"):], "/o/", 2)
+
+	src, ok := f.lookup(segs[3], srcName, queryGeneration(r, "sourceGeneration"))
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "No such object: "+segs[3]+"/"+srcName)
+		return
+	}
+	if !f.generationMatches(r, rest[0], rest[1]) {
+		writeAPIError(w, http.StatusPreconditionFailed, "precondition failed")
+		return
+	}
+	attrs := src.attrs
+	attrs.Bucket, attrs.Name = rest[0], rest[1]
+	dst := f.store(attrs, src.data)
+	writeJSON(w, raw.RewriteResponse{
+		Done:                true,
+		ObjectSize:          int64(dst.Size),
+		TotalBytesRewritten: int64(dst.Size),
+		Resource:            dst,
+	})
+}
+
+// compose concatenates the source objects into the destination object
+func (f *fakeGCS) compose(w http.ResponseWriter, r *http.Request, bucket, name string) {
+	var req raw.ComposeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var data []byte
+	for _, src := range req.SourceObjects {
+		obj, ok := f.lookup(bucket, src.Name, src.Generation)
+		if !ok {
+			writeAPIError(w, http.StatusNotFound, "No such object: "+bucket+"/"+src.Name)
+			return
+		}
+		data = append(data, obj.data...)
+	}
+	if !f.generationMatches(r, bucket, name) {
+		writeAPIError(w, http.StatusPreconditionFailed, "precondition failed")
+		return
+	}
+	attrs := raw.Object{Bucket: bucket, Name: name}
+	if req.Destination != nil {
+		attrs = *req.Destination
+		attrs.Bucket, attrs.Name = bucket, name
+	}
+	attrs.ComponentCount = int64(len(req.SourceObjects))
+	writeJSON(w, f.store(attrs, data))
+}
+
+func (f *fakeGCS) read(w http.ResponseWriter, r *http.Request, bucket, name string) {
+	obj, ok := f.lookup(bucket, name, queryGeneration(r, "generation"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", obj.attrs.ContentType)
+	if obj.attrs.ContentEncoding != "" {
+		w.Header().Set("X-Goog-Stored-Content-Encoding", obj.attrs.ContentEncoding)
+	}
+	w.Header().Set("X-Goog-Generation", strconv.FormatInt(obj.attrs.Generation, 10))
+	w.Header().Set("X-Goog-Metageneration", strconv.FormatInt(obj.attrs.Metageneration, 10))
+	w.Header().Set("X-Goog-Hash", "crc32c="+obj.attrs.Crc32c)
+	if obj.attrs.CacheControl != "" {
+		w.Header().Set("Cache-Control", obj.attrs.CacheControl)
+	}
+	if updated, err := time.Parse(time.RFC3339Nano, obj.attrs.Updated); err == nil {
+		w.Header().Set("Last-Modified", updated.UTC().Format(http.TimeFormat))
+	}
+	if f.cutReads > 0 && r.Method == http.MethodGet {
+		// declare the full length, send half, the client sees a broken stream
+		f.cutReads--
+		data := obj.data
+		if rng := r.Header.Get("Range"); rng != "" {
+			var start int
+			fmt.Sscanf(rng, "bytes=%d-", &start)
+			data = data[start:]
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(obj.data)-1, len(obj.data)))
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		}
+		w.Write(data[:len(data)/2])
+		return
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(obj.data))
+}
+
+// softDeleteObject keeps the live generation of given object as soft deleted, when the bucket is unversioned
+// & has soft delete retention
+func (f *fakeGCS) softDeleteObject(bucket, name string) {
+	key := fakeKey(bucket, name)
+	obj, ok := f.objects[key]
+	p := f.softDelete[bucket]
+	if !ok || f.versioned || p == nil || p.RetentionDurationSeconds == 0 {
+		return
+	}
+	if f.softDeleted == nil {
+		f.softDeleted = map[string][]*fakeObject{}
+	}
+	deleted := *obj
+	deleted.attrs.TimeDeleted = time.Now().UTC().Format(time.RFC3339Nano)
+	f.softDeleted[key] = append(f.softDeleted[key], &deleted)
+}
+
+// listSoftDeleted lists the soft deleted generations under prefix, by name & generation, in one page
+func (f *fakeGCS) listSoftDeleted(w http.ResponseWriter, r *http.Request, bucket, prefix string) {
+	p := f.softDelete[bucket]
+	items := []map[string]interface{}{}
+	for _, deleted := range f.softDeleted {
+		for _, obj := range deleted {
+			if obj.attrs.Bucket != bucket || !strings.HasPrefix(obj.attrs.Name, prefix) {
+				continue
+			}
+			item := map[string]interface{}{}
+			data, _ := obj.attrs.MarshalJSON()
+			json.Unmarshal(data, &item)
+			softDeleted, _ := time.Parse(time.RFC3339Nano, obj.attrs.TimeDeleted)
+			item["softDeleteTime"] = obj.attrs.TimeDeleted
+			if p != nil {
+				item["hardDeleteTime"] = softDeleted.Add(time.Duration(p.RetentionDurationSeconds) * time.Second).Format(time.RFC3339Nano)
+			}
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i]["name"] != items[j]["name"] {
+			return items[i]["name"].(string) < items[j]["name"].(string)
+		}
+		gi, _ := strconv.ParseInt(items[i]["generation"].(string), 10, 64)
+		gj, _ := strconv.ParseInt(items[j]["generation"].(string), 10, 64)
+		return gi < gj
+	})
+	writeJSON(w, map[string]interface{}{"items": items})
+}
+
+// restore makes the soft deleted generation of request the live object, as a new generation
+func (f *fakeGCS) restore(w http.ResponseWriter, r *http.Request, bucket, name string) {
+	key := fakeKey(bucket, name)
+	generation := queryGeneration(r, "generation")
+	for i, obj := range f.softDeleted[key] {
+		if obj.attrs.Generation != generation {
+			continue
+		}
+		if !f.generationMatches(r, bucket, name) {
+			writeAPIError(w, http.StatusPreconditionFailed, "precondition failed")
+			return
+		}
+		f.softDeleted[key] = append(f.softDeleted[key][:i], f.softDeleted[key][i+1:]...)
+		writeJSON(w, f.store(obj.attrs, obj.data))
+		return
+	}
+	writeAPIError(w, http.StatusNotFound, "No such soft deleted object: "+key)
+}
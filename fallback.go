@@ -0,0 +1,145 @@
+package cloudstorage
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"sync/atomic"
+)
+
+// FallbackConfig configures the buckets a FallbackReader falls back to
+type FallbackConfig struct {
+	// Buckets are the fallback buckets of each primary bucket, tried in order, e.g. DR replicas
+	Buckets map[string][]string `json:"buckets"`
+	// TryFallbackOnNotFound also falls back when the object is missing from the primary,
+	// missing objects are returned from the primary immediately otherwise
+	TryFallbackOnNotFound bool `json:"try_fallback_on_not_found"`
+	// Recorder receives fallback activations, optional
+	Recorder FallbackRecorder `json:"-"`
+}
+
+// FallbackMetrics describe one fallback read, the primary's failure & the fallback's outcome
+type FallbackMetrics struct {
+	Op string
+	// Primary is the request's bucket, Bucket the fallback bucket tried
+	Primary string
+	Bucket  string
+	Object  string
+	// Cause is the failure the read fell back on, the previous bucket's
+	Cause error
+	// Err is the fallback read's error, nil when the fallback served the read
+	Err error
+}
+
+// FallbackRecorder receives fallback reads, implemented by metrics recorders watching failovers
+type FallbackRecorder interface {
+	// RecordFallback is called once per fallback bucket tried
+	RecordFallback(FallbackMetrics)
+}
+
+// ReadSource is the bucket that served a FallbackReader read
+type ReadSource struct {
+	Bucket string
+	// Fallback is set when a fallback bucket served the read
+	Fallback bool
+}
+
+// FallbackReader reads through the wrapped client from a request's primary bucket & falls back to the
+// configured buckets, in order, on retryable & unavailable failures, e.g. an open circuit, during a
+// regional incident. Missing objects fall back only with TryFallbackOnNotFound. Failed reads return
+// the primary's error. Requests pinned to a generation, through WithGeneration, WithIfMetagenerationMatch
+// or WithKnownGeneration, aren't fallen back as generations differ between buckets, nor requests without
+// a bucket, e.g. routed ones. Only reads are decorated, writes must go to the client explicitly
+// & never fall back.
+type FallbackReader struct {
+	client      CloudStorage
+	config      FallbackConfig
+	activations int64
+}
+
+// NewFallbackReader returns a reader falling back to the configured buckets of given client's requests
+func NewFallbackReader(client CloudStorage, config FallbackConfig) *FallbackReader {
+	return &FallbackReader{client: client, config: config}
+}
+
+// Activations returns the number of reads that fell back
+func (fr *FallbackReader) Activations() int64 {
+	return atomic.LoadInt64(&fr.activations)
+}
+
+// DownloadFile downloads like the client's DownloadFile, returns the bucket that served it.
+// Downloads failed after writing content aren't fallen back.
+func (fr *FallbackReader) DownloadFile(ctx context.Context, w io.Writer, cfr CloudFileRequest) (int64, ReadSource, error) {
+	var n int64
+	src, err := fr.read("DownloadFile", cfr, func(cfr CloudFileRequest) (bool, error) {
+		var err error
+		n, err = fr.client.DownloadFile(ctx, w, cfr)
+		return n > 0, err
+	})
+	return n, src, err
+}
+
+// ReadAt reads like the client's ReadAt, returns the bucket that served it
+func (fr *FallbackReader) ReadAt(ctx context.Context, cfr CloudFileRequest, p []byte, off int64) (int, ReadSource, error) {
+	var n int
+	src, err := fr.read("ReadAt", cfr, func(cfr CloudFileRequest) (bool, error) {
+		var err error
+		n, err = fr.client.ReadAt(ctx, cfr, p, off)
+		return false, err
+	})
+	return n, src, err
+}
+
+// GetAttrs returns attributes like the client's GetAttrs, returns the bucket that served them
+func (fr *FallbackReader) GetAttrs(ctx context.Context, cfr CloudFileRequest) (*ObjectAttrs, ReadSource, error) {
+	var attrs *ObjectAttrs
+	src, err := fr.read("GetAttrs", cfr, func(cfr CloudFileRequest) (bool, error) {
+		var err error
+		attrs, err = fr.client.GetAttrs(ctx, cfr)
+		return false, err
+	})
+	return attrs, src, err
+}
+
+// read runs given read against the primary, then each fallback while the read fails with a failure
+// falling back & made no progress, returns the bucket that served it or the primary's error
+func (fr *FallbackReader) read(op string, cfr CloudFileRequest, read func(cfr CloudFileRequest) (bool, error)) (ReadSource, error) {
+	primary := ReadSource{Bucket: cfr.bucket}
+	progressed, err := read(cfr)
+	if err == nil {
+		return primary, nil
+	}
+	if progressed || !fr.fallsBack(err) || cfr.generation != 0 || cfr.metagenerationMatch != 0 || cfr.knownGeneration != 0 {
+		return primary, err
+	}
+	fallbacks := fr.config.Buckets[cfr.bucket]
+	if len(fallbacks) == 0 {
+		return primary, err
+	}
+	atomic.AddInt64(&fr.activations, 1)
+	primaryErr, cause := err, err
+	for _, bucket := range fallbacks {
+		fallback := cfr
+		fallback.bucket = bucket
+		progressed, err := read(fallback)
+		if fr.config.Recorder != nil {
+			fr.config.Recorder.RecordFallback(FallbackMetrics{Op: op, Primary: cfr.bucket, Bucket: bucket, Object: cfr.objectPath(), Cause: cause, Err: err})
+		}
+		if err == nil {
+			return ReadSource{Bucket: bucket, Fallback: true}, nil
+		}
+		if progressed || !fr.fallsBack(err) {
+			break
+		}
+		cause = err
+	}
+	return primary, primaryErr
+}
+
+// fallsBack reports whether given read failure falls back to the next bucket
+func (fr *FallbackReader) fallsBack(err error) bool {
+	if isNotFound(err) || stderrors.Is(err, ErrObjectNotFound) || stderrors.Is(err, ErrBucketNotFound) {
+		return fr.config.TryFallbackOnNotFound
+	}
+	return IsRetryable(err) || stderrors.Is(err, ErrCircuitOpen)
+}
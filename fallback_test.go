@@ -0,0 +1,136 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fallbackRecorder records fallback reads
+type fallbackRecorder struct {
+	mu    sync.Mutex
+	reads []FallbackMetrics
+}
+
+func (fr *fallbackRecorder) RecordFallback(m FallbackMetrics) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.reads = append(fr.reads, m)
+}
+
+// failBucket fails the object requests & media reads of given buckets with given status
+func failBucket(status int, buckets ...string) func(r *http.Request) int {
+	return func(r *http.Request) int {
+		for _, bucket := range buckets {
+			if strings.Contains(r.URL.Path, "/b/"+bucket+"/") || strings.HasPrefix(r.URL.Path, "/"+bucket+"/") {
+				return status
+			}
+		}
+		return 0
+	}
+}
+
+func TestFallbackReader(t *testing.T) {
+	f := newFakeGCS()
+	for _, bucket := range []string{"primary", "dr-1", "dr-2"} {
+		f.put(bucket, "path/file.json", []byte(`{"from":"`+bucket+`"}`), nil)
+	}
+	cs := newFakeClient(t, f)
+	cs.config.Retry = &RetryPolicy{MaxAttempts: 1}
+	recorder := &fallbackRecorder{}
+	fr := NewFallbackReader(cs, FallbackConfig{Buckets: map[string][]string{"primary": {"dr-1", "dr-2"}}, Recorder: recorder})
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("primary", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	// healthy primary serves
+	var buf bytes.Buffer
+	_, src, err := fr.DownloadFile(ctx, &buf, cfr)
+	require.NoError(t, err)
+	require.Equal(t, ReadSource{Bucket: "primary"}, src)
+	require.Zero(t, fr.Activations())
+
+	// unavailable buckets are skipped, in order
+	f.fail = failBucket(http.StatusServiceUnavailable, "primary", "dr-1")
+	buf.Reset()
+	n, src, err := fr.DownloadFile(ctx, &buf, cfr)
+	require.NoError(t, err)
+	require.Equal(t, ReadSource{Bucket: "dr-2", Fallback: true}, src)
+	require.Equal(t, `{"from":"dr-2"}`, buf.String())
+	require.Equal(t, int64(buf.Len()), n)
+
+	attrs, src, err := fr.GetAttrs(ctx, cfr)
+	require.NoError(t, err)
+	require.Equal(t, "dr-2", src.Bucket)
+	require.Equal(t, "dr-2", attrs.Bucket)
+	require.Equal(t, int64(2), fr.Activations())
+
+	recorder.mu.Lock()
+	require.Len(t, recorder.reads, 4)
+	require.Equal(t, "DownloadFile", recorder.reads[0].Op)
+	require.Equal(t, "primary", recorder.reads[0].Primary)
+	require.Equal(t, "dr-1", recorder.reads[0].Bucket)
+	require.True(t, IsRetryable(recorder.reads[0].Cause))
+	require.Error(t, recorder.reads[0].Err)
+	require.Equal(t, "dr-2", recorder.reads[1].Bucket)
+	require.NoError(t, recorder.reads[1].Err)
+	recorder.mu.Unlock()
+
+	// every bucket down fails with the primary's error
+	f.fail = failBucket(http.StatusServiceUnavailable, "primary", "dr-1", "dr-2")
+	_, src, err = fr.GetAttrs(ctx, cfr)
+	require.Error(t, err)
+	require.True(t, IsRetryable(err))
+	require.Equal(t, ReadSource{Bucket: "primary"}, src)
+
+	// reads pinned to a generation aren't fallen back
+	pinned := cfr
+	WithGeneration(1)(&pinned)
+	_, src, err = fr.GetAttrs(ctx, pinned)
+	require.Error(t, err)
+	require.Equal(t, "primary", src.Bucket)
+	require.Equal(t, int64(3), fr.Activations())
+}
+
+func TestFallbackReaderNotFound(t *testing.T) {
+	f := newFakeGCS()
+	f.put("dr", "path/file.json", []byte("replicated"), nil)
+	cs := newFakeClient(t, f)
+	cs.config.Retry = &RetryPolicy{MaxAttempts: 1}
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("primary", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	// missing objects are returned from the primary
+	fr := NewFallbackReader(cs, FallbackConfig{Buckets: map[string][]string{"primary": {"dr"}}})
+	_, src, err := fr.GetAttrs(ctx, cfr)
+	require.ErrorIs(t, err, ErrObjectNotFound)
+	require.Equal(t, ReadSource{Bucket: "primary"}, src)
+	require.Zero(t, fr.Activations())
+
+	fr = NewFallbackReader(cs, FallbackConfig{Buckets: map[string][]string{"primary": {"dr"}}, TryFallbackOnNotFound: true})
+	var buf bytes.Buffer
+	_, src, err = fr.DownloadFile(ctx, &buf, cfr)
+	require.NoError(t, err)
+	require.Equal(t, ReadSource{Bucket: "dr", Fallback: true}, src)
+	require.Equal(t, "replicated", buf.String())
+
+	p := make([]byte, 4)
+	n, src, err := fr.ReadAt(ctx, cfr, p, 2)
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, ReadSource{Bucket: "dr", Fallback: true}, src)
+	require.Equal(t, "plic", string(p))
+
+	// forbidden reads don't fall back
+	f.put("primary", "path/file.json", []byte("primary"), nil)
+	f.fail = failBucket(http.StatusForbidden, "primary")
+	_, _, err = fr.GetAttrs(ctx, cfr)
+	require.ErrorIs(t, err, ErrPermissionDenied)
+	require.Equal(t, int64(2), fr.Activations())
+}
@@ -0,0 +1,229 @@
+package cloudstorage
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_FANOUT_PRIMARY  string = "fan out primary upload failed"
+	ERROR_FANOUT_REPLICAS string = "fan out replica uploads failed"
+)
+
+// FanOutStatus is the overall status of a fan out upload
+type FanOutStatus string
+
+const (
+	// FanOutSucceeded primary & all replicas were written
+	FanOutSucceeded FanOutStatus = "succeeded"
+	// FanOutReplicasFailed primary was written, one or more replicas failed
+	FanOutReplicasFailed FanOutStatus = "replicas_failed"
+	// FanOutPrimaryFailed primary wasn't written, replicas may have been
+	FanOutPrimaryFailed FanOutStatus = "primary_failed"
+)
+
+// FanOutOptions configure UploadFanOut
+type FanOutOptions struct {
+	// RequirePrimary makes replica failures non fatal, reported in the result only
+	RequirePrimary bool
+}
+
+// FanOutOption sets fan out upload options
+type FanOutOption func(o *FanOutOptions)
+
+// WithRequirePrimary makes only the primary upload fatal, replica failures are reported in the result
+func WithRequirePrimary() FanOutOption {
+	return func(o *FanOutOptions) {
+		o.RequirePrimary = true
+	}
+}
+
+// FanOutDestination is the upload result of one fan out destination,
+// failed destinations' results carry the bytes sent before failing
+type FanOutDestination struct {
+	Bucket string
+	Object string
+	Result UploadResult
+	Err    error
+}
+
+// FanOutResult is the result of a fan out upload
+type FanOutResult struct {
+	Status   FanOutStatus
+	Primary  FanOutDestination
+	Replicas []FanOutDestination
+	// Duration is the time from starting the destination uploads until all finished, spooling excluded
+	Duration time.Duration
+	// BytesPerSecond is the throughput of all succeeded destinations over Duration
+	BytesPerSecond float64
+}
+
+// fanOutStatus returns the overall status of given destination results
+func fanOutStatus(primary FanOutDestination, replicas []FanOutDestination) FanOutStatus {
+	if primary.Err != nil {
+		return FanOutPrimaryFailed
+	}
+	for _, r := range replicas {
+		if r.Err != nil {
+			return FanOutReplicasFailed
+		}
+	}
+	return FanOutSucceeded
+}
+
+// fanOutSource returns a reader at over given reader's remaining content, of given declared size when positive,
+// readers that aren't seekable reader ats are spooled, returned spool must be closed. Content over the client's
+// max upload bytes fails with MaxUploadExceededError, streams before they're spooled past the limit.
+func (cs *cloudStorageClient) fanOutSource(r io.Reader, size int64) (io.ReaderAt, int64, *spooled, error) {
+	if ra, ok := r.(io.ReaderAt); ok {
+		if s, ok := r.(io.Seeker); ok {
+			cur, err := s.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, 0, nil, err
+			}
+			end, err := s.Seek(0, io.SeekEnd)
+			if err != nil {
+				return nil, 0, nil, err
+			}
+			if _, err := s.Seek(cur, io.SeekStart); err != nil {
+				return nil, 0, nil, err
+			}
+			if err := cs.checkUploadSize(end - cur); err != nil {
+				return nil, 0, nil, err
+			}
+			return io.NewSectionReader(ra, cur, end-cur), end - cur, nil, nil
+		}
+	}
+	r, err := cs.limitUpload(r, size)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	sp, err := spool(r, cs.spoolThreshold(), cs.config.SpoolDir, cs.buffers())
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	// memory & file spools are both reader ats
+	return sp.ReadSeeker.(io.ReaderAt), sp.size, sp, nil
+}
+
+// UploadFanOut reads given stream once & uploads it to the primary & replica destinations concurrently.
+// Any failed destination fails the upload unless WithRequirePrimary is set,
+// a failed primary always fails it. The result reports every destination either way.
+func (cs *cloudStorageClient) UploadFanOut(ctx context.Context, r io.Reader, primary CloudFileRequest, replicas []CloudFileRequest, opts ...FanOutOption) (FanOutResult, error) {
+	if err := cs.mutation(); err != nil {
+		return FanOutResult{}, err
+	}
+	primary, err := cs.request(ctx, primary)
+	if err != nil {
+		return FanOutResult{}, err
+	}
+	scoped := make([]CloudFileRequest, len(replicas))
+	for i, cfr := range replicas {
+		if scoped[i], err = cs.request(ctx, cfr); err != nil {
+			return FanOutResult{}, err
+		}
+	}
+	replicas = scoped
+	for _, cfr := range append([]CloudFileRequest{primary}, replicas...) {
+		if cfr.bucket == "" {
+			return FanOutResult{}, ErrBucketNameMissing
+		}
+		if cfr.file == "" {
+			return FanOutResult{}, ErrFileNameMissing
+		}
+	}
+	fOpts := FanOutOptions{}
+	for _, opt := range opts {
+		opt(&fOpts)
+	}
+
+	op := cs.startOperation(ctx, "UploadFanOut", primary)
+	defer op.finish()
+	ctx = op.ctx
+	if err := cs.checkDeadlineBudget(ctx, op, 1+len(replicas), 1+len(replicas)); err != nil {
+		return FanOutResult{}, err
+	}
+
+	src, size, sp, err := cs.fanOutSource(r, primary.size)
+	if stderrors.Is(err, ErrMaxUploadExceeded) {
+		op.logger.Error(ERROR_MAX_UPLOAD_EXCEEDED, zap.Error(err), zap.String("filepath", op.object))
+		return FanOutResult{Status: FanOutPrimaryFailed}, op.wrapError(err, "%s %s", ERROR_MAX_UPLOAD_EXCEEDED, op.object)
+	}
+	if err != nil {
+		op.logger.Error(ERROR_SPOOLING_UPLOAD, zap.Error(err), zap.String("filepath", op.object))
+		return FanOutResult{Status: FanOutPrimaryFailed}, op.wrapError(err, "%s %s", ERROR_SPOOLING_UPLOAD, op.object)
+	}
+	if sp != nil {
+		defer func() {
+			if err := sp.Close(); err != nil {
+				op.logger.Error("error removing upload spool", zap.Error(err), zap.String("filepath", op.object))
+			}
+		}()
+	}
+
+	// destination uploads share the fan out's request ID unless set on the request
+	ctx = WithRequestID(ctx, op.requestID)
+
+	dests := make([]FanOutDestination, len(replicas)+1)
+	start := cs.now()
+	var wg sync.WaitGroup
+	for i, cfr := range append([]CloudFileRequest{primary}, replicas...) {
+		wg.Add(1)
+		go func(i int, cfr CloudFileRequest) {
+			defer wg.Done()
+			res, err := cs.Upload(ctx, io.NewSectionReader(src, 0, size), cfr)
+			dests[i] = FanOutDestination{
+				Bucket: cfr.bucket,
+				Object: cfr.objectPath(),
+				Result: res,
+				Err:    err,
+			}
+		}(i, cfr)
+	}
+	wg.Wait()
+
+	result := FanOutResult{
+		Primary:  dests[0],
+		Replicas: dests[1:],
+		Duration: cs.since(start),
+	}
+	result.Status = fanOutStatus(result.Primary, result.Replicas)
+	var moved int64
+	for _, d := range dests {
+		if d.Err == nil {
+			moved += d.Result.Bytes
+		}
+	}
+	result.BytesPerSecond = bytesPerSecond(moved, result.Duration)
+
+	switch result.Status {
+	case FanOutPrimaryFailed:
+		op.logger.Error(ERROR_FANOUT_PRIMARY, zap.Error(result.Primary.Err), zap.String("filepath", op.object))
+		return result, op.wrapError(result.Primary.Err, "%s %s", ERROR_FANOUT_PRIMARY, op.object)
+	case FanOutReplicasFailed:
+		for _, d := range result.Replicas {
+			if d.Err != nil {
+				op.logger.Error(ERROR_FANOUT_REPLICAS, zap.Error(d.Err), zap.String("bucket", d.Bucket), zap.String("filepath", d.Object))
+			}
+		}
+		if !fOpts.RequirePrimary {
+			return result, op.wrapError(firstReplicaErr(result.Replicas), "%s %s", ERROR_FANOUT_REPLICAS, op.object)
+		}
+	}
+	op.logger.Debug("fan out upload done", zap.String("filepath", op.object), zap.String("status", string(result.Status)), zap.Int("replicas", len(replicas)))
+	return result, nil
+}
+
+func firstReplicaErr(replicas []FanOutDestination) error {
+	for _, d := range replicas {
+		if d.Err != nil {
+			return d.Err
+		}
+	}
+	return nil
+}
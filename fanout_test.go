@@ -0,0 +1,115 @@
+package cloudstorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failBucketUploads fails uploads to given bucket
+func failBucketUploads(bucket string) func(r *http.Request) int {
+	return func(r *http.Request) int {
+		if strings.HasPrefix(r.URL.Path, "/upload/storage/v1/b/"+bucket+"/") {
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	}
+}
+
+func TestUploadFanOut(t *testing.T) {
+	content := "fan out content"
+	primary, err := NewCloudFileRequest("primary", "file.json", "path", 0)
+	require.NoError(t, err)
+	replica, err := NewCloudFileRequest("dr", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name           string
+		failBucket     string
+		requirePrimary bool
+		status         FanOutStatus
+		fails          bool
+	}{
+		{name: "all succeed", status: FanOutSucceeded},
+		{name: "replica fails", failBucket: "dr", status: FanOutReplicasFailed, fails: true},
+		{name: "replica fails, primary required", failBucket: "dr", requirePrimary: true, status: FanOutReplicasFailed},
+		{name: "primary fails", failBucket: "primary", status: FanOutPrimaryFailed, fails: true},
+		{name: "primary fails, primary required", failBucket: "primary", requirePrimary: true, status: FanOutPrimaryFailed, fails: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeGCS()
+			if tc.failBucket != "" {
+				f.fail = failBucketUploads(tc.failBucket)
+			}
+			cs := newFakeClient(t, f)
+
+			opts := []FanOutOption{}
+			if tc.requirePrimary {
+				opts = append(opts, WithRequirePrimary())
+			}
+			// non seekable stream is spooled once
+			r := io.MultiReader(strings.NewReader(content))
+			res, err := cs.UploadFanOut(context.Background(), r, primary, []CloudFileRequest{replica}, opts...)
+			require.Equal(t, tc.status, res.Status)
+			if tc.fails {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Len(t, res.Replicas, 1)
+			require.Equal(t, "primary", res.Primary.Bucket)
+			require.Equal(t, "path/file.json", res.Primary.Object)
+
+			for bucket, dest := range map[string]FanOutDestination{"primary": res.Primary, "dr": res.Replicas[0]} {
+				data, _, ok := f.get(bucket, "path/file.json")
+				if bucket == tc.failBucket {
+					require.Error(t, dest.Err)
+					require.False(t, ok)
+					continue
+				}
+				require.NoError(t, dest.Err)
+				require.True(t, ok)
+				require.Equal(t, content, string(data))
+				require.Equal(t, int64(len(content)), dest.Result.Bytes)
+			}
+		})
+	}
+}
+
+func TestUploadFanOutReaderAt(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	primary, err := NewCloudFileRequest("primary", "file.json", "path", 0)
+	require.NoError(t, err)
+	replica, err := NewCloudFileRequest("dr", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	// remaining content of a partly read reader at is uploaded
+	r := strings.NewReader("skip:content")
+	_, err = r.Seek(5, io.SeekStart)
+	require.NoError(t, err)
+
+	res, err := cs.UploadFanOut(context.Background(), r, primary, []CloudFileRequest{replica})
+	require.NoError(t, err)
+	require.Equal(t, FanOutSucceeded, res.Status)
+	for _, bucket := range []string{"primary", "dr"} {
+		data, _, ok := f.get(bucket, "path/file.json")
+		require.True(t, ok)
+		require.Equal(t, "content", string(data))
+	}
+}
+
+func TestUploadFanOutValidation(t *testing.T) {
+	cs := &cloudStorageClient{logger: &recordingLogger{}}
+	primary, err := NewCloudFileRequest("primary", "file.json", "path", 0)
+	require.NoError(t, err)
+	replica := CloudFileRequest{file: "file.json", path: "path"}
+
+	_, err = cs.UploadFanOut(context.Background(), strings.NewReader(""), primary, []CloudFileRequest{replica})
+	require.True(t, errors.Is(err, ErrBucketNameMissing))
+}
@@ -0,0 +1,129 @@
+package cloudstorage
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const ERROR_SLOW_START string = "object read didn't start within the first byte deadline"
+
+var (
+	ErrSlowStart = errors.NewAppError(ERROR_SLOW_START)
+)
+
+// WithFirstByteTimeout sets the time-to-first-byte deadline of downloads & opened readers: the reader's open
+// & its first successful read must complete within given duration, the rest of the transfer is bounded by the
+// operation's own deadline. Reads that haven't started are aborted & opened once more, unless the client's
+// retry policy makes a single attempt, before failing with ErrSlowStart. Zero or less sets no deadline.
+func WithFirstByteTimeout(timeout time.Duration) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.firstByteTimeout = timeout
+	}
+}
+
+// startedReader is an object reader whose first read completed, the first read's bytes
+// are replayed before the rest
+type startedReader struct {
+	*storage.Reader
+	first []byte
+	// err is the first read's error, io.EOF of empty reads, returned once the bytes are replayed
+	err error
+	// cancel ends the reader's context, detached from the first byte deadline
+	cancel context.CancelFunc
+}
+
+func (r *startedReader) Read(p []byte) (int, error) {
+	if len(r.first) > 0 {
+		n := copy(p, r.first)
+		r.first = r.first[n:]
+		return n, nil
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.Reader.Read(p)
+}
+
+// Remain returns the number of bytes left to read, the replayed ones included, -1 when unknown
+func (r *startedReader) Remain() int64 {
+	remain := r.Reader.Remain()
+	if remain < 0 {
+		return remain
+	}
+	return remain + int64(len(r.first))
+}
+
+func (r *startedReader) Close() error {
+	defer r.cancel()
+	return r.Reader.Close()
+}
+
+// openStarted opens a reader with given open func, under the request's first byte deadline when set, the open
+// & the first successful read must complete within it. Attempts past the deadline are aborted & retried once
+// when the retry policy allows, then fail with ErrSlowStart, the operation counts them for its metrics.
+func (op *operation) openStarted(ctx context.Context, cfr CloudFileRequest, open func(ctx context.Context) (*storage.Reader, error)) (*startedReader, error) {
+	if cfr.firstByteTimeout <= 0 {
+		rc, err := open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &startedReader{Reader: rc, cancel: func() {}}, nil
+	}
+	attempts := 1
+	if op.cs.config.Retry == nil || op.cs.config.Retry.normalize().MaxAttempts > 1 {
+		attempts = 2
+	}
+	for attempt := 1; ; attempt++ {
+		r, err := op.startRead(ctx, cfr.firstByteTimeout, open)
+		if err == nil || !stderrors.Is(err, ErrSlowStart) {
+			return r, err
+		}
+		op.slowStarts++
+		op.logger.Info(ERROR_SLOW_START, zap.String("filepath", op.object), zap.Int("attempt", attempt), zap.Duration("timeout", cfr.firstByteTimeout))
+		if attempt >= attempts {
+			return nil, err
+		}
+	}
+}
+
+// startRead opens a reader & reads its first byte within given timeout on the client clock, failing with
+// ErrSlowStart past it. The reader's context is cancelled at the timeout only, the caller's context bounds
+// the rest of the read.
+func (op *operation) startRead(ctx context.Context, timeout time.Duration, open func(ctx context.Context) (*storage.Reader, error)) (*startedReader, error) {
+	rctx, cancel := context.WithCancel(ctx)
+	stop := op.cs.afterFunc(timeout, cancel)
+	r := &startedReader{cancel: cancel}
+	rc, err := open(rctx)
+	if err == nil {
+		r.Reader = rc
+		buf := make([]byte, 1)
+		n := 0
+		for n == 0 && err == nil {
+			n, err = rc.Read(buf)
+		}
+		r.first = buf[:n]
+		if n > 0 {
+			op.markFirstByte()
+		}
+		if err == io.EOF {
+			r.err, err = err, nil
+		}
+	}
+	if !stop() && ctx.Err() == nil {
+		err = ErrSlowStart
+	}
+	if err != nil {
+		if rc != nil {
+			rc.Close()
+		}
+		cancel()
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,176 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// slowStarts stalls the first given number of media reads of fake's objects until aborted, advancing
+// given clock past the first byte deadline once stalled, returns the number of media reads
+func slowStarts(f *fakeGCS, clock *fakeClock, slow int64) *int64 {
+	var reads int64
+	f.fail = func(r *http.Request) int {
+		if pathSegments(r)[0] != "bucket" {
+			return 0
+		}
+		if atomic.AddInt64(&reads, 1) <= slow {
+			clock.Advance(time.Second)
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+		return 0
+	}
+	return &reads
+}
+
+func TestFirstByteTimeout(t *testing.T) {
+	f := newFakeGCS()
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	f.put("bucket", "data/blob.bin", content, nil)
+	cs := newFakeClient(t, f)
+	clock := newFakeClock()
+	cs.clock = clock
+	metrics := &recordingMetrics{}
+	cs.config.Metrics = metrics
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "blob.bin", "data", 0, WithFirstByteTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	// a slow start is aborted & the read opened once more
+	reads := slowStarts(f, clock, 1)
+	var buf bytes.Buffer
+	res, err := cs.Download(ctx, &buf, cfr)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), res.Bytes)
+	require.Equal(t, content, buf.Bytes())
+	require.Equal(t, int64(2), atomic.LoadInt64(reads))
+	m := metrics.transfers[len(metrics.transfers)-1]
+	require.Equal(t, 1, m.SlowStarts)
+	require.Equal(t, time.Second, m.TimeToFirstByte, "a clock second passed in the aborted read")
+	require.GreaterOrEqual(t, m.Duration, m.TimeToFirstByte)
+
+	// reads slow twice fail
+	reads = slowStarts(f, clock, 2)
+	_, err = cs.Download(ctx, &bytes.Buffer{}, cfr)
+	require.ErrorIs(t, err, ErrSlowStart)
+	require.Equal(t, int64(2), atomic.LoadInt64(reads))
+	m = metrics.transfers[len(metrics.transfers)-1]
+	require.Equal(t, 2, m.SlowStarts)
+	require.ErrorIs(t, m.Err, ErrSlowStart)
+
+	// single attempt policies don't retry
+	cs.config.Retry = &RetryPolicy{MaxAttempts: 1}
+	reads = slowStarts(f, clock, 1)
+	_, err = cs.Download(ctx, &bytes.Buffer{}, cfr)
+	require.ErrorIs(t, err, ErrSlowStart)
+	require.Equal(t, int64(1), atomic.LoadInt64(reads))
+	cs.config.Retry = nil
+
+	// opened readers replay the first read
+	reads = slowStarts(f, clock, 1)
+	or, err := cs.OpenReader(ctx, cfr)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), or.Remaining())
+	data, err := io.ReadAll(or)
+	require.NoError(t, err)
+	require.Equal(t, content, data)
+	require.NoError(t, or.Close())
+	require.Equal(t, int64(2), atomic.LoadInt64(reads))
+	require.Zero(t, clock.pendingTimers(), "started reads stop their deadline")
+
+	// empty objects start with their end
+	f.fail = nil
+	f.put("bucket", "data/empty.bin", nil, nil)
+	empty, err := NewCloudFileRequest("bucket", "empty.bin", "data", 0, WithFirstByteTimeout(time.Second))
+	require.NoError(t, err)
+	res, err = cs.Download(ctx, &bytes.Buffer{}, empty)
+	require.NoError(t, err)
+	require.Zero(t, res.Bytes)
+}
+
+func TestFirstByteTimeoutSlowTransfer(t *testing.T) {
+	f := newFakeGCS()
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	f.put("bucket", "data/blob.bin", content, nil)
+	// media reads send their first bytes right away & the rest after the first byte deadline
+	cs := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pathSegments(r)[0] != "bucket" {
+			f.ServeHTTP(w, r)
+			return
+		}
+		rec := httptest.NewRecorder()
+		f.ServeHTTP(rec, r)
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		body := rec.Body.Bytes()
+		w.Write(body[:10])
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		w.Write(body[10:])
+	}))
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "blob.bin", "data", 0, WithFirstByteTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	// the first byte deadline doesn't bound the rest of the transfer
+	var buf bytes.Buffer
+	res, err := cs.Download(ctx, &buf, cfr)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), res.Bytes)
+	require.Equal(t, content, buf.Bytes())
+}
+
+func TestFirstByteTimeoutRangeReads(t *testing.T) {
+	f := newFakeGCS()
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	f.put("bucket", "data/blob.bin", content, nil)
+	cs := newFakeClient(t, f)
+	clock := newFakeClock()
+	cs.clock = clock
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "blob.bin", "data", 0, WithFirstByteTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+
+	reads := map[string]func() error{
+		"ReadAt": func() error {
+			_, err := cs.ReadAt(ctx, cfr, make([]byte, 10), 0)
+			return err
+		},
+		"NewReaderAt": func() error {
+			ra, err := cs.NewReaderAt(ctx, cfr, WithReadAhead(0))
+			if err != nil {
+				return err
+			}
+			defer ra.Close()
+			_, err = ra.ReadAt(make([]byte, 10), 0)
+			return err
+		},
+		"DownloadHead": func() error {
+			_, err := cs.DownloadHead(ctx, cfr, 10, &bytes.Buffer{})
+			return err
+		},
+	}
+	for name, read := range reads {
+		// a slow start is read again
+		n := slowStarts(f, clock, 1)
+		require.NoError(t, read(), name)
+		require.Equal(t, int64(2), atomic.LoadInt64(n), name)
+
+		// twice fails
+		n = slowStarts(f, clock, 2)
+		require.ErrorIs(t, read(), ErrSlowStart, name)
+		require.Equal(t, int64(2), atomic.LoadInt64(n), name)
+	}
+}
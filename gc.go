@@ -0,0 +1,209 @@
+package cloudstorage
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_CLEANING_ORPHANS     string = "error cleaning up orphaned temporary objects"
+	ERROR_CHECKING_TEMP_OBJECT string = "error checking temporary object references"
+)
+
+// Well known path prefixes of temporary objects, relative to the context scope.
+// Temporary objects are named <prefix>/<id>/<target>, target being the name, relative to the scope,
+// of the object they're written for.
+const (
+	// DEFAULT_STAGING_PREFIX is the default path prefix of staged uploads
+	DEFAULT_STAGING_PREFIX = ".staging"
+	// DEFAULT_PARTS_PREFIX is the path prefix of parallel upload parts, composed into their target
+	DEFAULT_PARTS_PREFIX = ".parts"
+)
+
+// DEFAULT_ORPHAN_AGE is the default age after which unreferenced temporary objects are orphans
+const DEFAULT_ORPHAN_AGE = 24 * time.Hour
+
+// tempObjectName returns the name of a temporary object under given prefix, for given target
+func tempObjectName(prefix, id, target string) string {
+	return path.Join(prefix, id, target)
+}
+
+// removeTemp deletes a temporary object best effort: once, without retries, under DEFAULT_CLEANUP_TIMEOUT,
+// whatever the operation's context. Leftovers are removed by CleanupOrphans.
+func removeTemp(obj *storage.ObjectHandle) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DEFAULT_CLEANUP_TIMEOUT)
+	defer cancel()
+	return obj.Retryer(storage.WithPolicy(storage.RetryNever)).Delete(ctx)
+}
+
+// TempObject is a temporary object, e.g. a staged upload or an upload part
+type TempObject struct {
+	Bucket string `json:"bucket"`
+	// Name is the object's name, relative to the context scope
+	Name string `json:"name"`
+	// Prefix is the well known prefix the object is under
+	Prefix string `json:"prefix"`
+	// ID identifies the writing upload, the staging ID or the part's request ID & number
+	ID string `json:"id"`
+	// Target is the name, relative to the context scope, of the object it's written for
+	Target     string    `json:"target"`
+	Generation int64     `json:"generation"`
+	Size       int64     `json:"size"`
+	Updated    time.Time `json:"updated"`
+}
+
+// parseTempObject returns the temporary object of given scope relative name under given prefix,
+// false for names not following the temporary object naming
+func parseTempObject(prefix, name string) (TempObject, bool) {
+	rest := strings.TrimPrefix(name, dirPrefix(prefix))
+	if rest == name {
+		return TempObject{}, false
+	}
+	segs := strings.SplitN(rest, "/", 2)
+	if len(segs) != 2 || segs[0] == "" || segs[1] == "" || strings.HasSuffix(segs[1], "/") {
+		return TempObject{}, false
+	}
+	return TempObject{Name: name, Prefix: prefix, ID: segs[0], Target: segs[1]}, true
+}
+
+// TempReferenceCheck reports whether a temporary object is still referenced, e.g. by a live manifest
+// or a running compose, referenced objects are kept whatever their age
+type TempReferenceCheck func(ctx context.Context, obj TempObject) (bool, error)
+
+// GCOptions configure CleanupOrphans
+type GCOptions struct {
+	// Prefixes are the temporary object prefixes scanned, relative to the context scope,
+	// DEFAULT_STAGING_PREFIX & DEFAULT_PARTS_PREFIX when empty
+	Prefixes []string `json:"prefixes"`
+	// OlderThan is the minimum age since last update of orphans, DEFAULT_ORPHAN_AGE when not positive
+	OlderThan time.Duration `json:"older_than"`
+	// Referenced, when set, keeps referenced temporary objects
+	Referenced TempReferenceCheck `json:"-"`
+	// DryRun reports orphans without deleting them
+	DryRun bool `json:"dry_run"`
+}
+
+// GCReport reports the orphans of a cleanup, deleted or, on dry runs, to be deleted
+type GCReport struct {
+	DeleteReport
+	DryRun bool `json:"dry_run"`
+	// Scanned is the number of temporary objects looked at
+	Scanned int64 `json:"scanned"`
+	// Referenced is the number of old enough temporary objects kept as referenced
+	Referenced int64 `json:"referenced"`
+	// Orphans are the temporary objects found orphaned
+	Orphans []TempObject `json:"orphans"`
+}
+
+// CleanupOrphans deletes the bucket's temporary objects, under the well known prefixes, last updated
+// more than the options' age ago & not referenced, left by crashed staged & parallel uploads.
+// Deletes are conditional on the listed generation, objects rewritten since are kept.
+// Failed reference checks & deletes don't stop the run, the first failure is returned with the report.
+func (cs *cloudStorageClient) CleanupOrphans(ctx context.Context, bucket string, opts GCOptions) (GCReport, error) {
+	if !opts.DryRun {
+		if err := cs.mutation(); err != nil {
+			return GCReport{}, err
+		}
+	}
+	if len(opts.Prefixes) == 0 {
+		opts.Prefixes = []string{DEFAULT_STAGING_PREFIX, DEFAULT_PARTS_PREFIX}
+	}
+	if opts.OlderThan <= 0 {
+		opts.OlderThan = DEFAULT_ORPHAN_AGE
+	}
+	return cs.cleanupTemp(ctx, "CleanupOrphans", bucket, opts, false)
+}
+
+// cleanupTemp deletes the orphans under given prefixes, with sweepAll objects not following
+// the temporary object naming too, by age alone
+func (cs *cloudStorageClient) cleanupTemp(ctx context.Context, name, bucket string, opts GCOptions, sweepAll bool) (GCReport, error) {
+	cfr, err := cs.scoped(ctx, CloudFileRequest{bucket: bucket})
+	if err != nil {
+		return GCReport{}, err
+	}
+	if cfr.bucket == "" {
+		return GCReport{}, ErrBucketNameMissing
+	}
+	prefixes := make([]CloudFileRequest, len(opts.Prefixes))
+	for i, prefix := range opts.Prefixes {
+		if prefixes[i], err = cs.scoped(ctx, CloudFileRequest{bucket: bucket, path: prefix}); err != nil {
+			return GCReport{}, err
+		}
+	}
+	op := cs.startOperation(ctx, name, cfr)
+	defer op.finish()
+	ctx = op.ctx
+	op.audited = op.audited && !opts.DryRun
+
+	cutoff := cs.now().Add(-opts.OlderThan)
+	report := GCReport{DryRun: opts.DryRun, Orphans: []TempObject{}}
+	var firstErr error
+	fail := func(object, msg string, err error) {
+		report.Failed++
+		op.logger.Error(msg, zap.Error(err), zap.String("temp", object))
+		if firstErr == nil {
+			firstErr = op.forObject(cfr.bucket, object).wrapError(err, "%s %s", msg, object)
+		}
+	}
+	for i, pcfr := range prefixes {
+		op.object = dirPrefix(pcfr.path)
+		it := cs.bucketHandle(pcfr).Objects(ctx, &storage.Query{Prefix: op.object})
+		for {
+			attrs, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				op.logger.Error(ERROR_LISTING_OBJECTS, zap.Error(err), zap.String("filepath", op.object))
+				return report, op.wrapError(err, ERROR_LISTING_OBJECTS)
+			}
+			obj, ok := parseTempObject(opts.Prefixes[i], pcfr.unscopedName(attrs.Name))
+			if !ok && !sweepAll {
+				continue
+			}
+			if !ok {
+				obj = TempObject{Name: pcfr.unscopedName(attrs.Name), Prefix: opts.Prefixes[i]}
+			}
+			report.Scanned++
+			if !attrs.Updated.Before(cutoff) {
+				continue
+			}
+			obj.Bucket, obj.Generation, obj.Size, obj.Updated = attrs.Bucket, attrs.Generation, attrs.Size, attrs.Updated
+			if opts.Referenced != nil {
+				referenced, err := opts.Referenced(ctx, obj)
+				if err != nil {
+					fail(attrs.Name, ERROR_CHECKING_TEMP_OBJECT, err)
+					continue
+				}
+				if referenced {
+					report.Referenced++
+					continue
+				}
+			}
+			if opts.DryRun {
+				report.Orphans = append(report.Orphans, obj)
+				continue
+			}
+			// a rewritten object is newer than the listed generation, kept
+			err = cs.bucketHandle(pcfr).Object(attrs.Name).If(storage.Conditions{GenerationMatch: attrs.Generation}).Delete(ctx)
+			switch {
+			case err == nil:
+				report.Deleted++
+				report.BytesFreed += attrs.Size
+				report.Orphans = append(report.Orphans, obj)
+			case isNotFound(err) || isPreconditionFailed(err):
+				report.Skipped++
+			default:
+				fail(attrs.Name, ERROR_CLEANING_ORPHANS, err)
+			}
+		}
+	}
+	op.logger.Debug("orphaned temporary objects cleaned up", zap.Strings("prefixes", opts.Prefixes), zap.Bool("dryRun", opts.DryRun), zap.Int("orphans", len(report.Orphans)), zap.Int64("failed", report.Failed))
+	return report, firstErr
+}
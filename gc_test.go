@@ -0,0 +1,138 @@
+package cloudstorage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/comfforts/cloudstorage/cloudstoragetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanupOrphans(t *testing.T) {
+	f := newFakeGCS()
+	old := cloudstoragetest.WithUpdated(time.Now().Add(-48 * time.Hour))
+	m := cloudstoragetest.NewTree().
+		Dir(".staging/s1/reports").File("a.csv", 6, old).
+		Dir(".staging/s2").File("b.csv", 7).
+		Dir(".parts/req1-0000/data").File("big.bin", 5, old).
+		Dir(".parts/req1-0001/data").File("big.bin", 5, old).
+		Dir(".parts/req2-0000/data").File("live.bin", 9, old).
+		Dir(".staging").File("loose", 17, old).
+		Dir("data").File("big.bin", 5, old).
+		Build(t, f)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	checked := []TempObject{}
+	opts := GCOptions{
+		Referenced: func(ctx context.Context, obj TempObject) (bool, error) {
+			checked = append(checked, obj)
+			return obj.Target == "data/live.bin", nil
+		},
+		DryRun: true,
+	}
+
+	report, err := cs.CleanupOrphans(ctx, "bucket", opts)
+	require.NoError(t, err)
+	require.True(t, report.DryRun)
+	require.Equal(t, int64(5), report.Scanned)
+	require.Equal(t, int64(1), report.Referenced)
+	require.Zero(t, report.Deleted)
+	names := []string{}
+	for _, obj := range report.Orphans {
+		names = append(names, obj.Name)
+	}
+	require.ElementsMatch(t, []string{".staging/s1/reports/a.csv", ".parts/req1-0000/data/big.bin", ".parts/req1-0001/data/big.bin"}, names)
+	require.Len(t, checked, 4, "young objects aren't checked")
+	require.Len(t, stagedNames(f, "bucket", "."), 6, "dry runs delete nothing")
+	for _, obj := range checked {
+		if obj.Name == ".staging/s1/reports/a.csv" {
+			require.Equal(t, TempObject{Bucket: "bucket", Name: obj.Name, Prefix: DEFAULT_STAGING_PREFIX, ID: "s1", Target: "reports/a.csv",
+				Generation: obj.Generation, Size: 6, Updated: obj.Updated}, obj)
+		}
+	}
+
+	opts.DryRun = false
+	report, err = cs.CleanupOrphans(ctx, "bucket", opts)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), report.Deleted)
+	require.Equal(t, m.Bytes(".staging/s1/")+m.Bytes(".parts/req1-"), report.BytesFreed)
+	left := stagedNames(f, "bucket", "")
+	sort.Strings(left)
+	require.Equal(t, []string{".parts/req2-0000/data/live.bin", ".staging/loose", ".staging/s2/b.csv", "data/big.bin"}, left)
+
+	_, err = cs.CleanupOrphans(ctx, "", GCOptions{})
+	require.Equal(t, ErrBucketNameMissing, err)
+}
+
+func TestCleanupOrphansReferenceFailure(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", ".parts/r-0000/a.bin", []byte("a"), nil)
+	f.put("bucket", ".parts/r-0001/b.bin", []byte("b"), nil)
+	age(f, "bucket", ".parts/r-0000/a.bin", 48*time.Hour)
+	age(f, "bucket", ".parts/r-0001/b.bin", 48*time.Hour)
+	cs := newFakeClient(t, f)
+
+	unavailable := errors.New("manifest store unavailable")
+	report, err := cs.CleanupOrphans(context.Background(), "bucket", GCOptions{
+		Prefixes: []string{DEFAULT_PARTS_PREFIX},
+		Referenced: func(ctx context.Context, obj TempObject) (bool, error) {
+			if obj.Target == "a.bin" {
+				return false, unavailable
+			}
+			return false, nil
+		},
+	})
+	require.Error(t, err)
+	require.Equal(t, int64(1), report.Failed)
+	require.Equal(t, int64(1), report.Deleted)
+	require.Equal(t, []string{".parts/r-0000/a.bin"}, stagedNames(f, "bucket", ".parts/"), "unchecked objects are kept")
+}
+
+func TestCleanupOrphansLeftParts(t *testing.T) {
+	content := readerAtContent(1024 * 1024)
+	f := newFakeGCS()
+	// parts can't be removed after the failed upload
+	f.fail = func(r *http.Request) int {
+		name := r.URL.Query().Get("name")
+		if strings.Contains(name, "-0002/") {
+			return http.StatusForbidden
+		}
+		if r.Method == http.MethodDelete {
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	ctx := tenantContext()
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+	_, err = cs.UploadFromReaderAt(ctx, strings.NewReader(string(content)), int64(len(content)), cfr,
+		WithChunkSize(256*1024), WithParallelUpload(256*1024, 1))
+	require.Error(t, err)
+	// the parts uploaded are left, named for their target, the failed one isn't
+	left := stagedNames(f, "bucket", "tenant-a/"+DEFAULT_PARTS_PREFIX+"/")
+	sort.Strings(left)
+	require.GreaterOrEqual(t, len(left), 2)
+	for _, name := range left {
+		require.Regexp(t, `^tenant-a/\.parts/[^/]+-000[013]/path/file\.bin$`, name)
+	}
+	for _, name := range left {
+		age(f, "bucket", name, 48*time.Hour)
+	}
+
+	f.fail = nil
+	targets := map[string]bool{}
+	report, err := cs.CleanupOrphans(ctx, "", GCOptions{Referenced: func(ctx context.Context, obj TempObject) (bool, error) {
+		targets[obj.Target] = true
+		return false, nil
+	}})
+	require.NoError(t, err)
+	require.Equal(t, int64(len(left)), report.Deleted)
+	require.Equal(t, map[string]bool{"path/file.bin": true}, targets, "parts name their target relative to the scope")
+	require.Empty(t, stagedNames(f, "bucket", "tenant-a/"+DEFAULT_PARTS_PREFIX))
+}
@@ -0,0 +1,96 @@
+package cloudstorage
+
+import (
+	"context"
+	"io"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_INVALID_RANGE_LENGTH string = "range length must be positive"
+)
+
+var (
+	ErrInvalidRangeLength = errors.NewAppError(ERROR_INVALID_RANGE_LENGTH)
+)
+
+// DownloadHead copies the first n bytes of the cloud file to given writer with a single range read,
+// the whole object when it's smaller, returns the number of bytes written.
+// Ranges are of the stored bytes, gzip encoded objects aren't decompressed, the head of
+// a transcoded object is the head of its compressed content.
+func (cs *cloudStorageClient) DownloadHead(ctx context.Context, cfr CloudFileRequest, n int64, w io.Writer) (int64, error) {
+	return cs.downloadRange(ctx, "DownloadHead", cfr, n, false, w)
+}
+
+// DownloadTail copies the last n bytes of the cloud file to given writer with a single suffix range read,
+// no size lookup needed, the whole object when it's smaller, returns the number of bytes written.
+// Ranges are of the stored bytes, gzip encoded objects aren't decompressed, the tail of
+// a transcoded object is the tail of its compressed content.
+func (cs *cloudStorageClient) DownloadTail(ctx context.Context, cfr CloudFileRequest, n int64, w io.Writer) (int64, error) {
+	return cs.downloadRange(ctx, "DownloadTail", cfr, n, true, w)
+}
+
+// downloadRange copies the first or last n bytes of the stored object
+func (cs *cloudStorageClient) downloadRange(ctx context.Context, name string, cfr CloudFileRequest, n int64, tail bool, w io.Writer) (int64, error) {
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return 0, err
+	}
+	if cfr.bucket == "" {
+		return 0, ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return 0, ErrFileNameMissing
+	}
+	if n <= 0 {
+		return 0, ErrInvalidRangeLength
+	}
+	op := cs.startOperation(ctx, name, cfr)
+	defer op.finish()
+	ctx = op.ctx
+	fPath := op.object
+
+	// a negative offset reads from the end
+	offset, length := int64(0), n
+	if tail {
+		offset, length = -n, -1
+	}
+	release, err := op.acquireTransfer(ctx)
+	if err != nil {
+		op.logger.Error(ERROR_WAITING_TRANSFER_SLOT, zap.Error(err), zap.String("filepath", fPath))
+		return 0, op.wrapError(err, "%s %s", ERROR_WAITING_TRANSFER_SLOT, fPath)
+	}
+	defer release()
+
+	obj := cs.retrying(cs.objectHandle(cfr, fPath)).ReadCompressed(true)
+	op.startTransfer(TransferDownload)
+	rc, err := op.openStarted(ctx, cfr, func(ctx context.Context) (rc *storage.Reader, err error) {
+		err = op.retry(ctx, func() (err error) {
+			rc, err = obj.NewRangeReader(ctx, offset, length)
+			return err
+		})
+		return rc, err
+	})
+	if err != nil {
+		op.logger.Error("error reading cloud file range", zap.Error(err), zap.String("filepath", fPath), zap.Int64("offset", offset), zap.Int64("length", length))
+		err = op.wrapError(err, "error reading cloud file range %s", fPath)
+		op.endTransfer(0, err)
+		return 0, err
+	}
+	defer func() {
+		if err := rc.Close(); err != nil {
+			op.logger.Error("error closing cloud file", zap.Error(err), zap.String("filepath", fPath))
+		}
+	}()
+
+	nBytes, err := cs.buffers().copy(&countingWriter{w: w, op: op}, rc)
+	if err != nil {
+		op.logger.Error("error copying cloud file range", zap.Error(err), zap.String("filepath", fPath))
+		err = op.wrapError(err, "error copying cloud file range %s", fPath)
+	}
+	op.endTransfer(nBytes, err)
+	return nBytes, err
+}
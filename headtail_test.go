@@ -0,0 +1,84 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadHeadTail(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64)
+	f := newFakeGCS()
+	f.put("bucket", "logs/app.log", content, nil)
+	f.put("bucket", "logs/small.log", []byte("tiny"), nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr := CloudFileRequest{bucket: "bucket", path: "logs", file: "app.log"}
+
+	var buf bytes.Buffer
+	n, err := cs.DownloadHead(ctx, cfr, 64, &buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(64), n)
+	require.Equal(t, content[:64], buf.Bytes())
+
+	// the tail is a single suffix range read, no size lookup
+	reads := recordRequests(f, http.MethodGet, nil)
+	buf.Reset()
+	n, err = cs.DownloadTail(ctx, cfr, 100, &buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), n)
+	require.Equal(t, content[len(content)-100:], buf.Bytes())
+	require.Len(t, *reads, 1)
+
+	// objects smaller than n are returned whole
+	small := CloudFileRequest{bucket: "bucket", path: "logs", file: "small.log"}
+	for _, download := range []func(context.Context, CloudFileRequest, int64, *bytes.Buffer) (int64, error){
+		func(ctx context.Context, cfr CloudFileRequest, n int64, w *bytes.Buffer) (int64, error) {
+			return cs.DownloadHead(ctx, cfr, n, w)
+		},
+		func(ctx context.Context, cfr CloudFileRequest, n int64, w *bytes.Buffer) (int64, error) {
+			return cs.DownloadTail(ctx, cfr, n, w)
+		},
+	} {
+		buf.Reset()
+		n, err = download(ctx, small, 64*1024, &buf)
+		require.NoError(t, err)
+		require.Equal(t, int64(4), n)
+		require.Equal(t, "tiny", buf.String())
+	}
+
+	_, err = cs.DownloadTail(ctx, CloudFileRequest{bucket: "bucket", path: "logs", file: "missing.log"}, 10, &buf)
+	require.ErrorIs(t, err, ErrObjectNotFound)
+	_, err = cs.DownloadHead(ctx, cfr, 0, &buf)
+	require.Equal(t, ErrInvalidRangeLength, err)
+	_, err = cs.DownloadTail(ctx, cfr, -1, &buf)
+	require.Equal(t, ErrInvalidRangeLength, err)
+}
+
+func TestDownloadHeadTailStoredBytes(t *testing.T) {
+	var gz bytes.Buffer
+	gzw := gzip.NewWriter(&gz)
+	_, err := gzw.Write(bytes.Repeat([]byte(`{"level":"info"}`+"\n"), 100))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr := CloudFileRequest{bucket: "bucket", path: "logs", file: "app.log.gz", contentEncoding: "gzip"}
+	_, err = cs.Upload(ctx, bytes.NewReader(gz.Bytes()), cfr)
+	require.NoError(t, err)
+
+	// ranges are of the compressed content, not decompressed
+	var buf bytes.Buffer
+	_, err = cs.DownloadHead(ctx, cfr, 10, &buf)
+	require.NoError(t, err)
+	require.Equal(t, gz.Bytes()[:10], buf.Bytes())
+	buf.Reset()
+	_, err = cs.DownloadTail(ctx, cfr, 8, &buf)
+	require.NoError(t, err)
+	require.Equal(t, gz.Bytes()[gz.Len()-8:], buf.Bytes())
+}
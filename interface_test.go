@@ -0,0 +1,57 @@
+package cloudstorage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// the client implements the full interface & every piece it's composed of
+var (
+	_ CloudStorage  = (*cloudStorageClient)(nil)
+	_ Uploader      = (*cloudStorageClient)(nil)
+	_ Downloader    = (*cloudStorageClient)(nil)
+	_ RecordStore   = (*cloudStorageClient)(nil)
+	_ Lister        = (*cloudStorageClient)(nil)
+	_ Deleter       = (*cloudStorageClient)(nil)
+	_ AttrsManager  = (*cloudStorageClient)(nil)
+	_ Replicator    = (*cloudStorageClient)(nil)
+	_ Versioner     = (*cloudStorageClient)(nil)
+	_ PointerStore  = (*cloudStorageClient)(nil)
+	_ BucketManager = (*cloudStorageClient)(nil)
+	_ URLSigner     = (*cloudStorageClient)(nil)
+	_ UsageSampler  = (*cloudStorageClient)(nil)
+)
+
+func TestInterfacePiecesDisjoint(t *testing.T) {
+	pieces := []reflect.Type{
+		reflect.TypeOf((*Uploader)(nil)).Elem(),
+		reflect.TypeOf((*Downloader)(nil)).Elem(),
+		reflect.TypeOf((*RecordStore)(nil)).Elem(),
+		reflect.TypeOf((*Lister)(nil)).Elem(),
+		reflect.TypeOf((*Deleter)(nil)).Elem(),
+		reflect.TypeOf((*AttrsManager)(nil)).Elem(),
+		reflect.TypeOf((*Replicator)(nil)).Elem(),
+		reflect.TypeOf((*Versioner)(nil)).Elem(),
+		reflect.TypeOf((*PointerStore)(nil)).Elem(),
+		reflect.TypeOf((*BucketManager)(nil)).Elem(),
+		reflect.TypeOf((*URLSigner)(nil)).Elem(),
+		reflect.TypeOf((*UsageSampler)(nil)).Elem(),
+	}
+	// every method belongs to one piece at most, pieces are what consumers depend on & mock
+	owners := map[string]string{}
+	for _, piece := range pieces {
+		for i := 0; i < piece.NumMethod(); i++ {
+			name := piece.Method(i).Name
+			owner, ok := owners[name]
+			require.False(t, ok, "%s in %s & %s", name, owner, piece.Name())
+			owners[name] = piece.Name()
+		}
+	}
+	full := reflect.TypeOf((*CloudStorage)(nil)).Elem()
+	for name := range owners {
+		_, ok := full.MethodByName(name)
+		require.True(t, ok, name)
+	}
+}
@@ -0,0 +1,240 @@
+package cloudstorage
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_EXPORTING_INVENTORY      string = "error exporting inventory"
+	ERROR_UNKNOWN_INVENTORY_FORMAT string = "unknown inventory format"
+	ERROR_UNKNOWN_INVENTORY_FIELD  string = "unknown inventory field"
+)
+
+// inventoryFlushRows is the number of rows written between flushes
+const inventoryFlushRows = 1000
+
+// InventoryFormat is the output format of an inventory export
+type InventoryFormat string
+
+const (
+	// InventoryJSONL writes one JSON object per line
+	InventoryJSONL InventoryFormat = "jsonl"
+	// InventoryCSV writes a header row followed by one row per object
+	InventoryCSV InventoryFormat = "csv"
+)
+
+// InventoryField is an object attribute included in an inventory export
+type InventoryField string
+
+const (
+	InventoryName         InventoryField = "name"
+	InventorySize         InventoryField = "size"
+	InventoryCRC32C       InventoryField = "crc32c"
+	InventoryUpdated      InventoryField = "updated"
+	InventoryStorageClass InventoryField = "storage_class"
+)
+
+// DefaultInventoryFields are the fields exported when none are selected
+var DefaultInventoryFields = []InventoryField{
+	InventoryName,
+	InventorySize,
+	InventoryCRC32C,
+	InventoryUpdated,
+	InventoryStorageClass,
+}
+
+// WithInventoryFields selects the fields, in order, written by ExportInventory
+func WithInventoryFields(fields ...InventoryField) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.inventoryFields = fields
+	}
+}
+
+// inventoryValue returns given field's value for object attrs,
+// crc32c is base64 encoded big endian like the storage API, updated is RFC3339 UTC
+func inventoryValue(attrs *storage.ObjectAttrs, field InventoryField) (interface{}, error) {
+	switch field {
+	case InventoryName:
+		return attrs.Name, nil
+	case InventorySize:
+		return attrs.Size, nil
+	case InventoryCRC32C:
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], attrs.CRC32C)
+		return base64.StdEncoding.EncodeToString(b[:]), nil
+	case InventoryUpdated:
+		return attrs.Updated.UTC().Format(time.RFC3339Nano), nil
+	case InventoryStorageClass:
+		return attrs.StorageClass, nil
+	}
+	return nil, errors.NewAppError("%s %s", ERROR_UNKNOWN_INVENTORY_FIELD, field)
+}
+
+// inventoryEncoder writes inventory rows in one of the supported formats
+type inventoryEncoder interface {
+	header(fields []InventoryField) error
+	row(fields []InventoryField, values []interface{}) error
+	flush() error
+}
+
+type jsonlInventoryEncoder struct {
+	w *bufio.Writer
+}
+
+func (e *jsonlInventoryEncoder) header(fields []InventoryField) error {
+	return nil
+}
+
+func (e *jsonlInventoryEncoder) row(fields []InventoryField, values []interface{}) error {
+	// written field by field to keep selected field order
+	e.w.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		val, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(e.w, "%q:", string(f))
+		e.w.Write(val)
+	}
+	e.w.WriteByte('}')
+	return e.w.WriteByte('\n')
+}
+
+func (e *jsonlInventoryEncoder) flush() error {
+	return e.w.Flush()
+}
+
+type csvInventoryEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvInventoryEncoder) header(fields []InventoryField) error {
+	rec := make([]string, len(fields))
+	for i, f := range fields {
+		rec[i] = string(f)
+	}
+	return e.w.Write(rec)
+}
+
+func (e *csvInventoryEncoder) row(fields []InventoryField, values []interface{}) error {
+	rec := make([]string, len(values))
+	for i, v := range values {
+		switch val := v.(type) {
+		case string:
+			rec[i] = val
+		case int64:
+			rec[i] = strconv.FormatInt(val, 10)
+		default:
+			rec[i] = fmt.Sprint(val)
+		}
+	}
+	return e.w.Write(rec)
+}
+
+func (e *csvInventoryEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func newInventoryEncoder(w io.Writer, format InventoryFormat) (inventoryEncoder, error) {
+	switch format {
+	case InventoryJSONL:
+		return &jsonlInventoryEncoder{w: bufio.NewWriter(w)}, nil
+	case InventoryCSV:
+		return &csvInventoryEncoder{w: csv.NewWriter(w)}, nil
+	}
+	return nil, errors.NewAppError("%s %s", ERROR_UNKNOWN_INVENTORY_FORMAT, format)
+}
+
+// ExportInventory streams a manifest of objects under request path to given writer,
+// rows are written as the listing is iterated & flushed periodically, returns the row count
+func (cs *cloudStorageClient) ExportInventory(ctx context.Context, cfr CloudFileRequest, w io.Writer, format InventoryFormat) (int64, error) {
+	cfr, err := cs.prefixRequest(ctx, cfr)
+	if err != nil {
+		return 0, err
+	}
+	if cfr.bucket == "" {
+		return 0, ErrBucketNameMissing
+	}
+	enc, err := newInventoryEncoder(w, format)
+	if err != nil {
+		return 0, err
+	}
+	fields := cfr.inventoryFields
+	if len(fields) == 0 {
+		fields = DefaultInventoryFields
+	}
+	for _, f := range fields {
+		if _, err := inventoryValue(&storage.ObjectAttrs{}, f); err != nil {
+			return 0, err
+		}
+	}
+
+	op := cs.startOperation(ctx, "ExportInventory", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	prefix := dirPrefix(cfr.path)
+	op.object = prefix
+
+	if err := enc.header(fields); err != nil {
+		op.logger.Error(ERROR_EXPORTING_INVENTORY, zap.Error(err))
+		return 0, op.wrapError(err, ERROR_EXPORTING_INVENTORY)
+	}
+
+	var rows int64
+	values := make([]interface{}, len(fields))
+	it := cs.bucketHandle(cfr).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		objAttrs, err := it.Next()
+		if err != nil {
+			if err == iterator.Done {
+				break
+			}
+			op.logger.Error(ERROR_LISTING_OBJECTS, zap.Error(err), zap.Int64("rows", rows))
+			enc.flush()
+			return rows, op.wrapError(err, ERROR_LISTING_OBJECTS)
+		}
+		if cfr.mapsNames() {
+			scoped := *objAttrs
+			scoped.Name = cfr.unscopedName(objAttrs.Name)
+			objAttrs = &scoped
+		}
+		for i, f := range fields {
+			values[i], _ = inventoryValue(objAttrs, f)
+		}
+		if err := enc.row(fields, values); err != nil {
+			op.logger.Error(ERROR_EXPORTING_INVENTORY, zap.Error(err), zap.Int64("rows", rows))
+			return rows, op.wrapError(err, ERROR_EXPORTING_INVENTORY)
+		}
+		rows++
+		if rows%inventoryFlushRows == 0 {
+			if err := enc.flush(); err != nil {
+				op.logger.Error(ERROR_EXPORTING_INVENTORY, zap.Error(err), zap.Int64("rows", rows))
+				return rows, op.wrapError(err, ERROR_EXPORTING_INVENTORY)
+			}
+		}
+	}
+	if err := enc.flush(); err != nil {
+		op.logger.Error(ERROR_EXPORTING_INVENTORY, zap.Error(err), zap.Int64("rows", rows))
+		return rows, op.wrapError(err, ERROR_EXPORTING_INVENTORY)
+	}
+	op.logger.Debug("inventory exported", zap.Int64("rows", rows), zap.String("format", string(format)))
+	return rows, nil
+}
@@ -0,0 +1,58 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventoryEncoders(t *testing.T) {
+	updated := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	objs := []*storage.ObjectAttrs{
+		{Name: "a/one.json", Size: 10, CRC32C: 1, Updated: updated, StorageClass: "STANDARD"},
+		{Name: "a/two,three.csv", Size: 0, CRC32C: 0xffffffff, Updated: updated, StorageClass: "NEARLINE"},
+	}
+
+	for scenario, tc := range map[string]struct {
+		format InventoryFormat
+		fields []InventoryField
+		want   string
+	}{
+		"jsonl default fields": {
+			format: InventoryJSONL,
+			fields: DefaultInventoryFields,
+			want: `{"name":"a/one.json","size":10,"crc32c":"AAAAAQ==","updated":"2024-01-02T03:04:05Z","storage_class":"STANDARD"}` + "\n" +
+				`{"name":"a/two,three.csv","size":0,"crc32c":"/////w==","updated":"2024-01-02T03:04:05Z","storage_class":"NEARLINE"}` + "\n",
+		},
+		"csv selected fields": {
+			format: InventoryCSV,
+			fields: []InventoryField{InventorySize, InventoryName},
+			want:   "size,name\n10,a/one.json\n0,\"a/two,three.csv\"\n",
+		},
+	} {
+		t.Run(scenario, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := newInventoryEncoder(&buf, tc.format)
+			require.NoError(t, err)
+			require.NoError(t, enc.header(tc.fields))
+			values := make([]interface{}, len(tc.fields))
+			for _, o := range objs {
+				for i, f := range tc.fields {
+					values[i], err = inventoryValue(o, f)
+					require.NoError(t, err)
+				}
+				require.NoError(t, enc.row(tc.fields, values))
+			}
+			require.NoError(t, enc.flush())
+			require.Equal(t, tc.want, buf.String())
+		})
+	}
+
+	_, err := newInventoryEncoder(&bytes.Buffer{}, "xml")
+	require.Error(t, err)
+	_, err = inventoryValue(objs[0], "owner")
+	require.Error(t, err)
+}
@@ -0,0 +1,81 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+// DEFAULT_JSON_API_ENDPOINT is the JSON API endpoint of calls the storage client doesn't cover
+const DEFAULT_JSON_API_ENDPOINT = "https://storage.googleapis.com/storage/v1/"
+
+// jsonAPIClient calls JSON API methods newer than the storage client version, e.g. soft delete,
+// authorized like the storage client, failures are *googleapi.Error as the storage client's
+type jsonAPIClient struct {
+	client *http.Client
+	// endpoint is the JSON API base URL, ending with a slash
+	endpoint string
+}
+
+// newJSONAPIClient returns a JSON API client with the default credentials & full control scope
+func newJSONAPIClient(ctx context.Context) (*jsonAPIClient, error) {
+	client, _, err := htransport.NewClient(ctx, option.WithScopes(storage.ScopeFullControl))
+	if err != nil {
+		return nil, err
+	}
+	return &jsonAPIClient{client: client, endpoint: DEFAULT_JSON_API_ENDPOINT}, nil
+}
+
+// jsonAPIPath returns the escaped resource path of given bucket & object, the bucket's path without object
+func jsonAPIPath(bucket, object string) string {
+	p := "b/" + url.PathEscape(bucket)
+	if object != "" {
+		p += "/o/" + url.PathEscape(object)
+	}
+	return p
+}
+
+// do calls given method on resource path, relative to the endpoint, with given query.
+// in, when set, is sent as JSON body, the JSON response is decoded into out when set.
+func (j *jsonAPIClient) do(ctx context.Context, method, resource string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("prettyPrint", "false")
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(j.endpoint, "/")+"/"+resource+"?"+query.Encode(), body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
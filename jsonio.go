@@ -0,0 +1,206 @@
+package cloudstorage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_READING_JSON string = "error reading cloud file json"
+	ERROR_WRITING_JSON string = "error writing cloud file json"
+)
+
+// DEFAULT_MAX_LINE_SIZE is the default maximum NDJSON line size
+const DEFAULT_MAX_LINE_SIZE = 1024 * 1024 // 1MB
+
+// LineError reports the line of a failed record, unwraps to the record's error
+type LineError struct {
+	Line int64
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err.Error())
+}
+
+// Unwrap returns the record's error
+func (e LineError) Unwrap() error {
+	return e.Err
+}
+
+// JSONOptions configure JSON & NDJSON helpers
+type JSONOptions struct {
+	// MaxLineSize is the maximum NDJSON line size, defaults to DEFAULT_MAX_LINE_SIZE
+	MaxLineSize int
+	// Gzip compresses written content & sets gzip content encoding
+	Gzip bool
+}
+
+// JSONOption sets JSON & NDJSON helper options
+type JSONOption func(o *JSONOptions)
+
+// WithMaxLineSize sets the maximum NDJSON line size
+func WithMaxLineSize(n int) JSONOption {
+	return func(o *JSONOptions) {
+		o.MaxLineSize = n
+	}
+}
+
+// WithGzip compresses written content
+func WithGzip() JSONOption {
+	return func(o *JSONOptions) {
+		o.Gzip = true
+	}
+}
+
+func jsonOptions(opts []JSONOption) JSONOptions {
+	jOpts := JSONOptions{}
+	for _, opt := range opts {
+		opt(&jOpts)
+	}
+	if jOpts.MaxLineSize <= 0 {
+		jOpts.MaxLineSize = DEFAULT_MAX_LINE_SIZE
+	}
+	return jOpts
+}
+
+// scanNDJSON calls fn for every non blank line of given reader, stops on the first error
+func scanNDJSON(r io.Reader, maxLineSize int, fn func(json.RawMessage) error) error {
+	scanner := bufio.NewScanner(r)
+	// max token size is the larger of initial buffer capacity & max
+	initial := 64 * 1024
+	if maxLineSize < initial {
+		initial = maxLineSize
+	}
+	scanner.Buffer(make([]byte, 0, initial), maxLineSize)
+	var line int64
+	for scanner.Scan() {
+		line++
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		var rec json.RawMessage
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return LineError{Line: line, Err: err}
+		}
+		if err := fn(rec); err != nil {
+			return LineError{Line: line, Err: err}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			return LineError{Line: line + 1, Err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// ReadJSON decodes the cloud file's JSON content into v
+func (cs *cloudStorageClient) ReadJSON(ctx context.Context, cfr CloudFileRequest, v interface{}) error {
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return err
+	}
+	if cfr.bucket == "" {
+		return ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return ErrFileNameMissing
+	}
+	op := cs.startOperation(ctx, "ReadJSON", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	or := cs.newObjectReader(WithRequestID(ctx, op.requestID), cfr)
+	err = json.NewDecoder(or).Decode(v)
+	if err == nil {
+		// drain trailing whitespace so the download completes
+		_, err = io.Copy(io.Discard, or)
+	}
+	if err := or.finish(err); err != nil {
+		if _, ok := err.(StorageError); ok {
+			return err
+		}
+		op.logger.Error(ERROR_READING_JSON, zap.Error(err), zap.String("filepath", op.object))
+		return op.wrapError(err, "%s %s", ERROR_READING_JSON, op.object)
+	}
+	return nil
+}
+
+// ReadNDJSON streams the cloud file's line delimited JSON records through fn,
+// stops on the first malformed line or fn error, reported with the line number as LineError
+func (cs *cloudStorageClient) ReadNDJSON(ctx context.Context, cfr CloudFileRequest, fn func(json.RawMessage) error, opts ...JSONOption) error {
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return err
+	}
+	if cfr.bucket == "" {
+		return ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return ErrFileNameMissing
+	}
+	jOpts := jsonOptions(opts)
+	op := cs.startOperation(ctx, "ReadNDJSON", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	or := cs.newObjectReader(WithRequestID(ctx, op.requestID), cfr)
+	if err := or.finish(scanNDJSON(or, jOpts.MaxLineSize, fn)); err != nil {
+		if _, ok := err.(StorageError); ok {
+			return err
+		}
+		op.logger.Error(ERROR_READING_JSON, zap.Error(err), zap.String("filepath", op.object))
+		return op.wrapError(err, "%s %s", ERROR_READING_JSON, op.object)
+	}
+	return nil
+}
+
+// WriteJSON uploads v encoded as JSON
+func (cs *cloudStorageClient) WriteJSON(ctx context.Context, cfr CloudFileRequest, v interface{}, opts ...JSONOption) (UploadResult, error) {
+	if cfr.IsZero() {
+		return UploadResult{}, ErrEmptyRequest
+	}
+	jOpts := jsonOptions(opts)
+	if cfr.contentType == "" {
+		cfr.contentType = "application/json"
+	}
+	return cs.writeObject(ctx, cfr, jOpts.Gzip, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(v)
+	})
+}
+
+// WriteNDJSON uploads records returned by next as line delimited JSON, until next returns false
+func (cs *cloudStorageClient) WriteNDJSON(ctx context.Context, cfr CloudFileRequest, next func() (interface{}, bool), opts ...JSONOption) (UploadResult, error) {
+	if cfr.IsZero() {
+		return UploadResult{}, ErrEmptyRequest
+	}
+	jOpts := jsonOptions(opts)
+	if cfr.contentType == "" {
+		cfr.contentType = "application/x-ndjson"
+	}
+	return cs.writeObject(ctx, cfr, jOpts.Gzip, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		var line int64
+		for {
+			rec, ok := next()
+			if !ok {
+				break
+			}
+			line++
+			if err := enc.Encode(rec); err != nil {
+				return LineError{Line: line, Err: err}
+			}
+		}
+		return bw.Flush()
+	})
+}
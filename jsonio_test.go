@@ -0,0 +1,126 @@
+package cloudstorage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanNDJSON(t *testing.T) {
+	input := "{\"id\":1}\n\n{\"id\":2}\n{\"id\":\n{\"id\":4}\n"
+
+	var ids []int
+	err := scanNDJSON(strings.NewReader(input), DEFAULT_MAX_LINE_SIZE, func(rec json.RawMessage) error {
+		var v struct{ ID int }
+		require.NoError(t, json.Unmarshal(rec, &v))
+		ids = append(ids, v.ID)
+		return nil
+	})
+	var lErr LineError
+	require.True(t, errors.As(err, &lErr))
+	require.Equal(t, int64(4), lErr.Line)
+	require.Equal(t, []int{1, 2}, ids)
+
+	// fn error stops the scan
+	stop := errors.New("stop")
+	calls := 0
+	err = scanNDJSON(strings.NewReader(input), DEFAULT_MAX_LINE_SIZE, func(rec json.RawMessage) error {
+		calls++
+		return stop
+	})
+	require.True(t, errors.Is(err, stop))
+	require.True(t, errors.As(err, &lErr))
+	require.Equal(t, int64(1), lErr.Line)
+	require.Equal(t, 1, calls)
+
+	// lines over max size
+	err = scanNDJSON(strings.NewReader("{}\n{\"k\":\""+strings.Repeat("x", 100)+"\"}\n"), 64, func(json.RawMessage) error { return nil })
+	require.True(t, errors.Is(err, bufio.ErrTooLong))
+	require.True(t, errors.As(err, &lErr))
+	require.Equal(t, int64(2), lErr.Line)
+}
+
+func TestJSONHelpers(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+
+	cfr, err := NewCloudFileRequest("bucket", "doc.json", "path", 0)
+	require.NoError(t, err)
+	_, err = cs.WriteJSON(ctx, cfr, map[string]string{"name": "acme"})
+	require.NoError(t, err)
+	_, attrs, ok := f.get("bucket", "path/doc.json")
+	require.True(t, ok)
+	require.Equal(t, "application/json", attrs.ContentType)
+
+	var doc map[string]string
+	require.NoError(t, cs.ReadJSON(ctx, cfr, &doc))
+	require.Equal(t, map[string]string{"name": "acme"}, doc)
+
+	cfr, err = NewCloudFileRequest("bucket", "records.ndjson", "path", 0)
+	require.NoError(t, err)
+	i := 0
+	res, err := cs.WriteNDJSON(ctx, cfr, func() (interface{}, bool) {
+		i++
+		return map[string]int{"id": i}, i <= 3
+	})
+	require.NoError(t, err)
+	require.Equal(t, SpoolMemory, res.Spool)
+
+	ids := []int{}
+	err = cs.ReadNDJSON(ctx, cfr, func(rec json.RawMessage) error {
+		var v struct{ ID int }
+		if err := json.Unmarshal(rec, &v); err != nil {
+			return err
+		}
+		ids = append(ids, v.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3}, ids)
+
+	// stopping early reports fn's error, not the aborted download
+	stop := errors.New("stop")
+	err = cs.ReadNDJSON(ctx, cfr, func(json.RawMessage) error { return stop })
+	require.True(t, errors.Is(err, stop))
+	var lErr LineError
+	require.True(t, errors.As(err, &lErr))
+	require.Equal(t, int64(1), lErr.Line)
+
+	// missing object
+	missing, err := NewCloudFileRequest("bucket", "missing.ndjson", "path", 0)
+	require.NoError(t, err)
+	err = cs.ReadNDJSON(ctx, missing, func(json.RawMessage) error { return nil })
+	require.True(t, errors.Is(err, ErrObjectNotFound))
+}
+
+func TestWriteNDJSONGzip(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+
+	cfr, err := NewCloudFileRequest("bucket", "records.ndjson.gz", "path", 0)
+	require.NoError(t, err)
+	n := 0
+	_, err = cs.WriteNDJSON(context.Background(), cfr, func() (interface{}, bool) {
+		n++
+		return map[string]int{"id": n}, n <= 2
+	}, WithGzip())
+	require.NoError(t, err)
+
+	data, attrs, ok := f.get("bucket", "path/records.ndjson.gz")
+	require.True(t, ok)
+	require.Equal(t, "gzip", attrs.ContentEncoding)
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	content, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	require.Equal(t, "{\"id\":1}\n{\"id\":2}\n", string(content))
+}
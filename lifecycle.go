@@ -0,0 +1,370 @@
+package cloudstorage
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_CLIENT_CLOSED      string = "storage client closed"
+	ERROR_CLOSING_CLIENT     string = "error closing storage client"
+	ERROR_FLUSHING_COMPONENT string = "error flushing client component"
+)
+
+var (
+	ErrClientClosed = errors.NewAppError(ERROR_CLIENT_CLOSED)
+)
+
+// DEFAULT_CLOSE_TIMEOUT bounds Close's wait for in-flight operations & component flushes
+const DEFAULT_CLOSE_TIMEOUT = 30 * time.Second
+
+// lifecycleComponent is a client component holding buffered or asynchronous state, e.g. a batching sink,
+// flushed by CloseWithContext once in-flight operations completed & before the storage client is closed.
+// Client features register theirs with the client's lifecycle, config supplied sinks & recorders participate
+// through configComponent.
+type lifecycleComponent interface {
+	// componentName names the component in close errors & logs
+	componentName() string
+	// flush writes the component's buffered state, giving up when the context is done
+	flush(ctx context.Context) error
+}
+
+// funcComponent is a lifecycle component flushed by a function
+type funcComponent struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c funcComponent) componentName() string {
+	return c.name
+}
+
+func (c funcComponent) flush(ctx context.Context) error {
+	return c.fn(ctx)
+}
+
+// lifecycle tracks a client's in-flight operations & registered components, shared with the scoped
+// clients derived from it. A nil lifecycle admits every operation & tracks none.
+type lifecycle struct {
+	mu sync.Mutex
+	// closing stops admitting operations but the sinks' own writes, closed stops admitting any
+	closing bool
+	closed  bool
+	active  int
+	// drained is closed when the last in-flight operation leaves while closing
+	drained chan struct{}
+	// done is closed with err set once the first close returned
+	done       chan struct{}
+	err        error
+	registered []lifecycleComponent
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{}
+}
+
+// register adds given component, flushed on close before the components registered earlier
+func (l *lifecycle) register(c lifecycleComponent) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.registered = append(l.registered, c)
+}
+
+type lifecycleKey struct{}
+
+// withinOperation returns a context whose operations are issued by an operation in flight with given lifecycle
+func withinOperation(ctx context.Context, l *lifecycle) context.Context {
+	return context.WithValue(ctx, lifecycleKey{}, l)
+}
+
+// admit returns ErrClientClosed for operations started once the client is closing, but for those
+// issued by in-flight operations & the audit sinks' own writes flushing their batches
+func (l *lifecycle) admit(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	nested := ctx.Value(lifecycleKey{}) == l
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed || (l.closing && !nested && !auditSuppressed(ctx)) {
+		return ErrClientClosed
+	}
+	return nil
+}
+
+// enter counts a started operation in flight, reporting whether it's tracked, it isn't once the client closed
+func (l *lifecycle) enter() bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return false
+	}
+	l.active++
+	return true
+}
+
+// leave counts a tracked operation done
+func (l *lifecycle) leave() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	if l.active == 0 && l.drained != nil {
+		close(l.drained)
+		l.drained = nil
+	}
+}
+
+// begin stops admitting operations, returns a channel closed once the in-flight ones are done,
+// first reporting whether this is the first close
+func (l *lifecycle) begin() (bool, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closing {
+		return false, nil
+	}
+	l.closing, l.done = true, make(chan struct{})
+	drained := make(chan struct{})
+	if l.active == 0 {
+		close(drained)
+	} else {
+		l.drained = drained
+	}
+	return true, drained
+}
+
+// inFlight returns the number of operations in flight
+func (l *lifecycle) inFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active
+}
+
+// components returns the registered components, in registration order
+func (l *lifecycle) components() []lifecycleComponent {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]lifecycleComponent{}, l.registered...)
+}
+
+// end stops admitting any operation, before the client is closed
+func (l *lifecycle) end() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+}
+
+// finish records the first close's result, returned by later ones
+func (l *lifecycle) finish(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.err = err
+	close(l.done)
+}
+
+// wait returns the first close's result, or the context's error when it's done first
+func (l *lifecycle) wait(ctx context.Context) error {
+	l.mu.Lock()
+	done := l.done
+	l.mu.Unlock()
+	select {
+	case <-done:
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CloseError is returned by Close & CloseWithContext when shutdown steps failed, the failures are in
+// shutdown order: in-flight operations left when the context expired, component flushes, the client's close
+type CloseError struct {
+	Errors []error
+}
+
+func (e CloseError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%s: %s", ERROR_CLOSING_CLIENT, strings.Join(msgs, "; "))
+}
+
+// Is matches any of the failures
+func (e CloseError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if stderrors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// closeStepError is a failed shutdown step
+type closeStepError struct {
+	step string
+	err  error
+}
+
+func (e closeStepError) Error() string {
+	return fmt.Sprintf("%s: %s", e.step, e.err)
+}
+
+// Unwrap returns the step's failure
+func (e closeStepError) Unwrap() error {
+	return e.err
+}
+
+// Close closes the client like CloseWithContext, within DEFAULT_CLOSE_TIMEOUT
+func (cs *cloudStorageClient) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DEFAULT_CLOSE_TIMEOUT)
+	defer cancel()
+	return cs.CloseWithContext(ctx)
+}
+
+// CloseWithContext shuts the client down: new operations fail with ErrClientClosed, in-flight ones are
+// waited for, then the lifecycle components are flushed in reverse registration order, config supplied
+// sinks & recorders last, closed when they're io.Closers. The client's connections are closed last.
+// The context bounds the wait & the flushes, components still flushing when it's done are given up on,
+// the client closed regardless. Failures are returned together as a CloseError. Later calls wait for the
+// first's result, scoped clients share the connections of the client they were derived from & don't close.
+func (cs *cloudStorageClient) CloseWithContext(ctx context.Context) error {
+	if cs.bound != nil {
+		return nil
+	}
+	if cs.life != nil {
+		first, drained := cs.life.begin()
+		if !first {
+			return cs.life.wait(ctx)
+		}
+		err := cs.shutdown(ctx, drained)
+		cs.life.finish(err)
+		return err
+	}
+	return cs.shutdown(ctx, nil)
+}
+
+// shutdown waits for given drained channel, flushes the components & closes the client
+func (cs *cloudStorageClient) shutdown(ctx context.Context, drained <-chan struct{}) error {
+	var errs []error
+	if drained != nil {
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			n := cs.life.inFlight()
+			cs.logger.Error(ERROR_CLOSING_CLIENT, zap.Error(ctx.Err()), zap.Int("inFlight", n))
+			errs = append(errs, closeStepError{step: fmt.Sprintf("waiting for %d in-flight operations", n), err: ctx.Err()})
+		}
+	}
+
+	comps := cs.components()
+	for i := len(comps) - 1; i >= 0; i-- {
+		if err := flushBounded(ctx, comps[i]); err != nil {
+			cs.logger.Error(ERROR_FLUSHING_COMPONENT, zap.Error(err), zap.String("component", comps[i].componentName()))
+			errs = append(errs, closeStepError{step: "flushing " + comps[i].componentName(), err: err})
+		}
+	}
+
+	if cs.life != nil {
+		cs.life.end()
+	}
+	if err := cs.client.Close(); err != nil {
+		cs.logger.Error(ERROR_CLOSING_CLIENT, zap.Error(err))
+		errs = append(errs, closeStepError{step: "closing connections", err: err})
+	}
+	if len(errs) > 0 {
+		return CloseError{Errors: errs}
+	}
+	return nil
+}
+
+// flushBounded flushes given component, returning the context's error when it's done first,
+// the flush left to complete on its own
+func flushBounded(ctx context.Context, c lifecycleComponent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- c.flush(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// components returns the client's lifecycle components in registration order: the config's audit sink,
+// metrics & access recorders, considered registered with the client, then the components registered by
+// client features
+func (cs *cloudStorageClient) components() []lifecycleComponent {
+	comps := []lifecycleComponent{}
+	seen := []interface{}{}
+	for _, sink := range []struct {
+		name string
+		v    interface{}
+	}{
+		{"audit sink", cs.config.Audit},
+		{"metrics recorder", cs.config.Metrics},
+		{"access recorder", cs.config.AccessRecorder},
+	} {
+		if sink.v == nil || containsSink(seen, sink.v) {
+			continue
+		}
+		seen = append(seen, sink.v)
+		if comp := configComponent(sink.name, sink.v); comp != nil {
+			comps = append(comps, comp)
+		}
+	}
+	return append(comps, cs.life.components()...)
+}
+
+// containsSink reports whether given sink is one of the seen ones, a value configured for
+// more than one role is flushed once
+func containsSink(seen []interface{}, v interface{}) bool {
+	if !reflect.TypeOf(v).Comparable() {
+		return false
+	}
+	for _, s := range seen {
+		if reflect.TypeOf(s) == reflect.TypeOf(v) && s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// configComponent adapts a config supplied sink or recorder to a lifecycle component: closed when it's
+// an io.Closer, flushed when it has a Flush method, nil otherwise
+func configComponent(name string, v interface{}) lifecycleComponent {
+	switch s := v.(type) {
+	case lifecycleComponent:
+		return s
+	case io.Closer:
+		return funcComponent{name: name, fn: func(context.Context) error { return s.Close() }}
+	case interface{ Flush(context.Context) error }:
+		return funcComponent{name: name, fn: s.Flush}
+	case interface{ Flush() error }:
+		return funcComponent{name: name, fn: func(context.Context) error { return s.Flush() }}
+	}
+	return nil
+}
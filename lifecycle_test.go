@@ -0,0 +1,179 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// slowSink is an audit sink buffering events, flushed after a delay regardless of the context
+type slowSink struct {
+	delay   time.Duration
+	mu      sync.Mutex
+	events  int
+	flushed int
+}
+
+func (s *slowSink) Record(AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events++
+	return nil
+}
+
+func (s *slowSink) Flush() error {
+	time.Sleep(s.delay)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushed, s.events = s.flushed+s.events, 0
+	return nil
+}
+
+func (s *slowSink) flushedEvents() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushed
+}
+
+// blockReads blocks downloads of given object until the returned release func is called,
+// entered receives once a read is blocked
+func blockReads(f *fakeGCS, object string) (entered <-chan struct{}, release func()) {
+	in, out := make(chan struct{}, 1), make(chan struct{})
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, object) {
+			in <- struct{}{}
+			<-out
+		}
+		return 0
+	}
+	var once sync.Once
+	return in, func() { once.Do(func() { close(out) }) }
+}
+
+func TestCloseWithContext(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/slow.bin", []byte("slow"), nil)
+	cs := newFakeClient(t, f)
+	sink := &slowSink{delay: 50 * time.Millisecond}
+	cs.config.Audit = sink
+	var mu sync.Mutex
+	order := []string{}
+	for _, name := range []string{"first", "second"} {
+		name := name
+		cs.life.register(funcComponent{name: name, fn: func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}})
+	}
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+	_, err = cs.Upload(ctx, strings.NewReader("content"), cfr)
+	require.NoError(t, err)
+
+	// a download is in flight when the client is closed
+	entered, release := blockReads(f, "slow.bin")
+	defer release()
+	slow, err := NewCloudFileRequest("bucket", "slow.bin", "path", 0)
+	require.NoError(t, err)
+	downloaded := make(chan error, 1)
+	go func() {
+		var buf bytes.Buffer
+		_, err := cs.Download(ctx, &buf, slow)
+		downloaded <- err
+	}()
+	<-entered
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- cs.CloseWithContext(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		_, err := cs.Upload(ctx, strings.NewReader("content"), cfr)
+		return stderrors.Is(err, ErrClientClosed)
+	}, time.Second, time.Millisecond, "new operations are rejected")
+	_, err = cs.Scoped("bucket", "path").Upload(ctx, strings.NewReader("content"), cfr)
+	require.ErrorIs(t, err, ErrClientClosed, "so are the scoped clients'")
+	select {
+	case err := <-closed:
+		t.Fatalf("closed with an operation in flight: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// the in-flight download completes, then the components flush in reverse registration order
+	release()
+	require.NoError(t, <-downloaded)
+	require.NoError(t, <-closed)
+	require.Equal(t, []string{"second", "first"}, order)
+	require.Equal(t, 1, sink.flushedEvents())
+	require.NoError(t, cs.Close(), "later closes return the first's result")
+	require.NoError(t, cs.Scoped("bucket", "").Close())
+	_, err = cs.Download(ctx, &bytes.Buffer{}, slow)
+	require.ErrorIs(t, err, ErrClientClosed)
+}
+
+func TestCloseWithContextDeadline(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	sink := &slowSink{delay: time.Second}
+	cs.config.Audit = sink
+	var flushed int64
+	cs.life.register(funcComponent{name: "fast", fn: func(context.Context) error {
+		atomic.AddInt64(&flushed, 1)
+		return nil
+	}})
+
+	// the slow sink is given up on at the deadline, the client closed regardless
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := cs.CloseWithContext(ctx)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	var closeErr CloseError
+	require.ErrorAs(t, err, &closeErr)
+	require.Len(t, closeErr.Errors, 1)
+	require.Contains(t, closeErr.Error(), "flushing audit sink")
+	require.Equal(t, int64(1), atomic.LoadInt64(&flushed), "components are flushed before the slow one")
+	_, err = cs.GetAttrs(context.Background(), CloudFileRequest{bucket: "bucket", file: "file.bin"})
+	require.ErrorIs(t, err, ErrClientClosed)
+
+	// in-flight operations are given up on too, the flushes left undone
+	f = newFakeGCS()
+	f.put("bucket", "path/slow.bin", []byte("slow"), nil)
+	cs = newFakeClient(t, f)
+	cs.config.Retry = &RetryPolicy{MaxAttempts: 1}
+	sink = &slowSink{}
+	cs.config.Audit = sink
+	entered, release := blockReads(f, "slow.bin")
+	defer release()
+	slow, err := NewCloudFileRequest("bucket", "slow.bin", "path", 0)
+	require.NoError(t, err)
+	downloaded := make(chan error, 1)
+	go func() {
+		_, err := cs.Download(context.Background(), &bytes.Buffer{}, slow)
+		downloaded <- err
+	}()
+	<-entered
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = cs.CloseWithContext(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorAs(t, err, &closeErr)
+	require.Len(t, closeErr.Errors, 2)
+	require.Contains(t, closeErr.Errors[0].Error(), "waiting for 1 in-flight operations")
+	require.Contains(t, closeErr.Errors[1].Error(), "flushing audit sink")
+	release()
+	<-downloaded
+}
@@ -0,0 +1,525 @@
+package cloudstorage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_READING_MANIFEST        string = "error reading manifest"
+	ERROR_WRITING_MANIFEST        string = "error writing manifest report"
+	ERROR_UNKNOWN_MANIFEST_ACTION string = "unknown manifest action"
+	ERROR_MISSING_MANIFEST_DEST   string = "manifest copy destination missing"
+	ERROR_INVALID_MANIFEST_NAME   string = "manifest name must be relative, without parent segments"
+	ERROR_MANIFEST_LINE_TOO_LONG  string = "manifest line exceeds max line size"
+)
+
+// DEFAULT_MANIFEST_CONCURRENCY is the default number of manifest rows processed concurrently
+const DEFAULT_MANIFEST_CONCURRENCY = 8
+
+// ManifestAction is the batch operation run for every manifest row
+type ManifestAction string
+
+const (
+	// ManifestVerifyExists checks the object exists
+	ManifestVerifyExists ManifestAction = "verify_exists"
+	// ManifestVerifyChecksum checks the object's CRC32C matches the row's crc32c
+	ManifestVerifyChecksum ManifestAction = "verify_checksum"
+	// ManifestDelete deletes the object
+	ManifestDelete ManifestAction = "delete"
+	// ManifestCopy copies the object under the destination prefix
+	ManifestCopy ManifestAction = "copy"
+)
+
+// ManifestOutcome is the result of processing one manifest row
+type ManifestOutcome string
+
+const (
+	ManifestOK        ManifestOutcome = "ok"
+	ManifestMissing   ManifestOutcome = "missing"
+	ManifestMismatch  ManifestOutcome = "checksum_mismatch"
+	ManifestFailed    ManifestOutcome = "failed"
+	ManifestMalformed ManifestOutcome = "malformed"
+)
+
+// ManifestOptions configure ProcessManifest
+type ManifestOptions struct {
+	// Bucket holds the manifest's objects, required
+	Bucket string
+	// Format of the input manifest, defaults to JSON Lines
+	Format InventoryFormat
+	// Concurrency is the number of rows processed concurrently
+	Concurrency int
+	// DestBucket is the copy destination bucket, defaults to Bucket
+	DestBucket string
+	// DestPrefix is the copy destination prefix, required for copies
+	DestPrefix string
+	// Output receives one JSON line result per row, optional
+	Output io.Writer
+	// RequireEmptyDestination fails copies with DestinationNotEmptyError, before any row is processed,
+	// when the destination prefix has objects, copies overwrite existing objects otherwise
+	RequireEmptyDestination bool
+	// MaxLineSize is the maximum JSON Lines row size, longer rows are malformed,
+	// defaults to DEFAULT_MAX_LINE_SIZE
+	MaxLineSize int
+	// DestKMSKeyName is the Cloud KMS key copies are encrypted with, the bucket's default key when empty
+	DestKMSKeyName string
+}
+
+// ManifestOption sets manifest processing options
+type ManifestOption func(o *ManifestOptions)
+
+// WithManifestBucket sets the bucket holding the manifest's objects
+func WithManifestBucket(bucket string) ManifestOption {
+	return func(o *ManifestOptions) {
+		o.Bucket = bucket
+	}
+}
+
+// WithManifestFormat sets the input manifest format
+func WithManifestFormat(format InventoryFormat) ManifestOption {
+	return func(o *ManifestOptions) {
+		o.Format = format
+	}
+}
+
+// WithManifestConcurrency sets the number of rows processed concurrently
+func WithManifestConcurrency(n int) ManifestOption {
+	return func(o *ManifestOptions) {
+		o.Concurrency = n
+	}
+}
+
+// WithManifestDestination sets the bucket & prefix objects are copied to
+func WithManifestDestination(bucket, prefix string) ManifestOption {
+	return func(o *ManifestOptions) {
+		o.DestBucket = bucket
+		o.DestPrefix = prefix
+	}
+}
+
+// WithManifestDestinationKMSKey encrypts copies with given Cloud KMS key name
+func WithManifestDestinationKMSKey(keyName string) ManifestOption {
+	return func(o *ManifestOptions) {
+		o.DestKMSKeyName = keyName
+	}
+}
+
+// WithManifestOutput sets the writer per row results are written to
+func WithManifestOutput(w io.Writer) ManifestOption {
+	return func(o *ManifestOptions) {
+		o.Output = w
+	}
+}
+
+// WithManifestRequireEmptyDestination fails copies when the destination prefix isn't empty,
+// directory markers aside, the explicit alternative to overwriting
+func WithManifestRequireEmptyDestination() ManifestOption {
+	return func(o *ManifestOptions) {
+		o.RequireEmptyDestination = true
+	}
+}
+
+// WithManifestMaxLineSize sets the maximum JSON Lines row size
+func WithManifestMaxLineSize(n int) ManifestOption {
+	return func(o *ManifestOptions) {
+		o.MaxLineSize = n
+	}
+}
+
+// ManifestRowResult is the result of processing one manifest row
+type ManifestRowResult struct {
+	Row     int64           `json:"row"`
+	Name    string          `json:"name,omitempty"`
+	Outcome ManifestOutcome `json:"outcome"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// ManifestReport summarizes a manifest run
+type ManifestReport struct {
+	// Rows is the number of rows read
+	Rows int64 `json:"rows"`
+	// Counts are the number of rows per outcome
+	Counts map[ManifestOutcome]int64 `json:"counts"`
+}
+
+// Failures returns the number of rows that didn't succeed
+func (r ManifestReport) Failures() int64 {
+	return r.Rows - r.Counts[ManifestOK]
+}
+
+type manifestRow struct {
+	row    int64
+	name   string
+	crc    uint32
+	hasCRC bool
+	err    error
+}
+
+// parseCRC32C decodes base64 encoded big endian CRC32C, storage API's format
+func parseCRC32C(s string) (uint32, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) != 4 {
+		return 0, errors.NewAppError("invalid crc32c %s", s)
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+func newManifestRow(row int64, name, crc string) manifestRow {
+	mr := manifestRow{row: row, name: name}
+	if name == "" {
+		mr.err = errors.NewAppError("row %d: name missing", row)
+		return mr
+	}
+	// names are joined to prefixes, absolute names & parent segments could resolve outside them
+	if strings.HasPrefix(name, "/") || hasParentSegment(name) {
+		mr.err = errors.NewAppError("row %d: %s %q", row, ERROR_INVALID_MANIFEST_NAME, name)
+		return mr
+	}
+	if crc != "" {
+		c, err := parseCRC32C(crc)
+		if err != nil {
+			mr.err = errors.WrapError(err, "row %d: invalid crc32c %s", row, crc)
+			return mr
+		}
+		mr.crc, mr.hasCRC = c, true
+	}
+	return mr
+}
+
+// hasParentSegment reports whether given name has a ".." segment
+func hasParentSegment(name string) bool {
+	for _, seg := range strings.Split(name, "/") {
+		if seg == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// readLine returns the next line of given reader without line ending, lines longer than
+// given max size are consumed & reported too long, without content. Returns io.EOF after the last line.
+func readLine(br *bufio.Reader, maxSize int) ([]byte, bool, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, isPrefix, err := br.ReadLine()
+		if err != nil {
+			return nil, false, err
+		}
+		if !tooLong && len(line)+len(chunk) > maxSize {
+			tooLong, line = true, nil
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		if !isPrefix {
+			return line, tooLong, nil
+		}
+	}
+}
+
+// readManifest parses manifest rows onto returned channel, malformed rows carry the parse error.
+// JSON Lines rows longer than given max size are malformed. Read errors stop parsing
+// & are reported on the error channel.
+func readManifest(ctx context.Context, r io.Reader, format InventoryFormat, maxLineSize int) (<-chan manifestRow, <-chan error) {
+	rows := make(chan manifestRow)
+	errc := make(chan error, 1)
+
+	send := func(mr manifestRow) bool {
+		select {
+		case rows <- mr:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(rows)
+		defer close(errc)
+
+		switch format {
+		case InventoryCSV:
+			cr := csv.NewReader(r)
+			cr.FieldsPerRecord = -1
+			header, err := cr.Read()
+			if err != nil {
+				if err != io.EOF {
+					errc <- errors.WrapError(err, ERROR_READING_MANIFEST)
+				}
+				return
+			}
+			nameCol, crcCol := -1, -1
+			for i, h := range header {
+				switch InventoryField(strings.TrimSpace(h)) {
+				case InventoryName:
+					nameCol = i
+				case InventoryCRC32C:
+					crcCol = i
+				}
+			}
+			if nameCol < 0 {
+				errc <- errors.NewAppError("%s: name column missing", ERROR_READING_MANIFEST)
+				return
+			}
+			var row int64
+			for {
+				rec, err := cr.Read()
+				if err == io.EOF {
+					return
+				}
+				row++
+				if err != nil {
+					if _, ok := err.(*csv.ParseError); !ok {
+						errc <- errors.WrapError(err, ERROR_READING_MANIFEST)
+						return
+					}
+					if !send(manifestRow{row: row, err: err}) {
+						return
+					}
+					continue
+				}
+				var name, crc string
+				if nameCol < len(rec) {
+					name = rec[nameCol]
+				}
+				if crcCol >= 0 && crcCol < len(rec) {
+					crc = rec[crcCol]
+				}
+				if !send(newManifestRow(row, name, crc)) {
+					return
+				}
+			}
+		default:
+			br := bufio.NewReader(r)
+			var row int64
+			for {
+				b, tooLong, err := readLine(br, maxLineSize)
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					errc <- errors.WrapError(err, ERROR_READING_MANIFEST)
+					return
+				}
+				line := bytes.TrimSpace(b)
+				if len(line) == 0 && !tooLong {
+					continue
+				}
+				row++
+				var rec struct {
+					Name   string `json:"name"`
+					CRC32C string `json:"crc32c"`
+				}
+				mr := manifestRow{row: row}
+				if tooLong {
+					mr.err = errors.NewAppError("row %d: %s %d", row, ERROR_MANIFEST_LINE_TOO_LONG, maxLineSize)
+				} else if err := json.Unmarshal(line, &rec); err != nil {
+					mr.err = errors.WrapError(err, "row %d: invalid json", row)
+				} else {
+					mr = newManifestRow(row, rec.Name, rec.CRC32C)
+				}
+				if !send(mr) {
+					return
+				}
+			}
+		}
+	}()
+	return rows, errc
+}
+
+// ProcessManifest runs given action for every object named in the manifest with bounded concurrency,
+// malformed rows & per object failures are reported, not fatal,
+// returned error is for manifest read, report write or context failures
+func (cs *cloudStorageClient) ProcessManifest(ctx context.Context, r io.Reader, action ManifestAction, opts ...ManifestOption) (ManifestReport, error) {
+	mOpts := ManifestOptions{
+		Format:      InventoryJSONL,
+		Concurrency: DEFAULT_MANIFEST_CONCURRENCY,
+	}
+	for _, opt := range opts {
+		opt(&mOpts)
+	}
+	src, err := cs.scoped(ctx, CloudFileRequest{bucket: mOpts.Bucket})
+	if err != nil {
+		return ManifestReport{}, err
+	}
+	mOpts.Bucket = src.bucket
+	if mOpts.Bucket == "" {
+		return ManifestReport{}, ErrBucketNameMissing
+	}
+	if action == ManifestDelete || action == ManifestCopy {
+		if err := cs.mutation(); err != nil {
+			return ManifestReport{}, err
+		}
+	}
+	var dst CloudFileRequest
+	switch action {
+	case ManifestVerifyExists, ManifestVerifyChecksum, ManifestDelete:
+	case ManifestCopy:
+		if mOpts.DestPrefix == "" {
+			return ManifestReport{}, errors.NewAppError(ERROR_MISSING_MANIFEST_DEST)
+		}
+		if mOpts.DestBucket == "" {
+			mOpts.DestBucket = mOpts.Bucket
+		}
+		// the normalized prefix is both checked & written under
+		prefix, err := destinationPrefix(mOpts.DestPrefix)
+		if err != nil {
+			return ManifestReport{}, err
+		}
+		if dst, err = cs.scoped(ctx, CloudFileRequest{bucket: mOpts.DestBucket, path: prefix}); err != nil {
+			return ManifestReport{}, err
+		}
+		mOpts.DestBucket, mOpts.DestPrefix = dst.bucket, dst.path
+		dst.kmsKeyName = mOpts.DestKMSKeyName
+	default:
+		return ManifestReport{}, errors.NewAppError("%s %s", ERROR_UNKNOWN_MANIFEST_ACTION, action)
+	}
+	if mOpts.Concurrency < 1 {
+		mOpts.Concurrency = 1
+	}
+	if mOpts.MaxLineSize <= 0 {
+		mOpts.MaxLineSize = DEFAULT_MAX_LINE_SIZE
+	}
+
+	op := cs.startOperation(ctx, "ProcessManifest", CloudFileRequest{bucket: mOpts.Bucket})
+	defer op.finish()
+	ctx = op.ctx
+	// verifications don't change bucket content
+	op.audited = op.audited && (action == ManifestDelete || action == ManifestCopy)
+	if action == ManifestCopy && mOpts.RequireEmptyDestination {
+		if err := cs.requireEmptyDestination(ctx, op, dst); err != nil {
+			op.logger.Error("manifest copy destination not empty", zap.Error(err), zap.String("prefix", mOpts.DestPrefix))
+			return ManifestReport{Counts: map[ManifestOutcome]int64{}}, err
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rows, readErrc := readManifest(ctx, r, mOpts.Format, mOpts.MaxLineSize)
+	results := make(chan ManifestRowResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < mOpts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for mr := range rows {
+				res := cs.processManifestRow(ctx, op, action, mOpts, dst, mr)
+				select {
+				case results <- res:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	report := ManifestReport{Counts: map[ManifestOutcome]int64{}}
+	var out *json.Encoder
+	var bw *bufio.Writer
+	if mOpts.Output != nil {
+		bw = bufio.NewWriter(mOpts.Output)
+		out = json.NewEncoder(bw)
+	}
+	var writeErr error
+	for res := range results {
+		report.Rows++
+		report.Counts[res.Outcome]++
+		if out != nil && writeErr == nil {
+			if err := out.Encode(res); err != nil {
+				writeErr = err
+				cancel()
+			}
+		}
+	}
+	if bw != nil && writeErr == nil {
+		writeErr = bw.Flush()
+	}
+
+	if err := <-readErrc; err != nil {
+		op.logger.Error(ERROR_READING_MANIFEST, zap.Error(err), zap.Int64("rows", report.Rows))
+		return report, op.wrapError(err, ERROR_READING_MANIFEST)
+	}
+	if writeErr != nil {
+		op.logger.Error(ERROR_WRITING_MANIFEST, zap.Error(writeErr), zap.Int64("rows", report.Rows))
+		return report, op.wrapError(writeErr, ERROR_WRITING_MANIFEST)
+	}
+	if err := ctx.Err(); err != nil {
+		op.logger.Error("manifest processing cancelled", zap.Error(err), zap.Int64("rows", report.Rows))
+		return report, op.wrapError(err, "manifest processing cancelled")
+	}
+	op.logger.Debug("manifest processed", zap.String("action", string(action)), zap.Int64("rows", report.Rows), zap.Int64("failures", report.Failures()))
+	return report, nil
+}
+
+func (cs *cloudStorageClient) processManifestRow(ctx context.Context, op *operation, action ManifestAction, mOpts ManifestOptions, dst CloudFileRequest, mr manifestRow) ManifestRowResult {
+	res := ManifestRowResult{Row: mr.row, Name: mr.name}
+	if mr.err != nil {
+		res.Outcome, res.Error = ManifestMalformed, mr.err.Error()
+		return res
+	}
+	if action == ManifestVerifyChecksum && !mr.hasCRC {
+		res.Outcome, res.Error = ManifestMalformed, "crc32c missing"
+		return res
+	}
+
+	// manifest names are relative to the context scope, if any
+	cfr, err := cs.scoped(ctx, CloudFileRequest{bucket: mOpts.Bucket, file: mr.name})
+	if err != nil {
+		res.Outcome, res.Error = ManifestMalformed, err.Error()
+		return res
+	}
+	name := cfr.objectPath()
+	obj := cs.bucketHandle(cfr).Object(name)
+	switch action {
+	case ManifestVerifyExists, ManifestVerifyChecksum:
+		var attrs *storage.ObjectAttrs
+		attrs, err = obj.Attrs(ctx)
+		if err == nil && action == ManifestVerifyChecksum && attrs.CRC32C != mr.crc {
+			res.Outcome = ManifestMismatch
+			return res
+		}
+	case ManifestDelete:
+		err = obj.Delete(ctx)
+		cs.invalidate(cfr.bucket, name)
+	case ManifestCopy:
+		dstObj := cs.bucketHandle(dst).Object(path.Join(dst.path, dst.encodedName(mr.name)))
+		copier := dstObj.CopierFrom(obj)
+		copier.DestinationKMSKeyName = dst.kmsKeyName
+		_, err = copier.Run(ctx)
+		cs.invalidate(dst.bucket, dstObj.ObjectName())
+	}
+
+	switch {
+	case err == nil:
+		res.Outcome = ManifestOK
+	case err == storage.ErrObjectNotExist:
+		res.Outcome = ManifestMissing
+	default:
+		op.logger.Error("error processing manifest row", zap.Error(err), zap.Int64("row", mr.row), zap.String("filepath", mr.name))
+		res.Outcome, res.Error = ManifestFailed, err.Error()
+	}
+	return res
+}
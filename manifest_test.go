@@ -0,0 +1,170 @@
+package cloudstorage
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func collectManifest(t *testing.T, input string, format InventoryFormat) []manifestRow {
+	t.Helper()
+	rows, errc := readManifest(context.Background(), strings.NewReader(input), format, DEFAULT_MAX_LINE_SIZE)
+	got := []manifestRow{}
+	for mr := range rows {
+		got = append(got, mr)
+	}
+	require.NoError(t, <-errc)
+	return got
+}
+
+func TestReadManifest(t *testing.T) {
+	crc, err := inventoryValue(&storage.ObjectAttrs{CRC32C: 0xdeadbeef}, InventoryCRC32C)
+	require.NoError(t, err)
+
+	jsonl := `{"name":"a.json","crc32c":"` + crc.(string) + `"}
+not json
+
+{"name":"b.json"}
+{"crc32c":"AAAAAQ=="}
+{"name":"c.json","crc32c":"bad"}
+`
+	rows := collectManifest(t, jsonl, InventoryJSONL)
+	require.Equal(t, 5, len(rows))
+	require.Equal(t, "a.json", rows[0].name)
+	require.Equal(t, true, rows[0].hasCRC)
+	require.Equal(t, uint32(0xdeadbeef), rows[0].crc)
+	require.Error(t, rows[1].err)
+	require.Equal(t, int64(2), rows[1].row)
+	require.NoError(t, rows[2].err)
+	require.Equal(t, false, rows[2].hasCRC)
+	require.Error(t, rows[3].err)
+	require.Error(t, rows[4].err)
+
+	csvInput := "size,name,crc32c\n10,a.json," + crc.(string) + "\n0,,\n1,\"b.json\n"
+	rows = collectManifest(t, csvInput, InventoryCSV)
+	require.Equal(t, 3, len(rows))
+	require.Equal(t, uint32(0xdeadbeef), rows[0].crc)
+	require.Error(t, rows[1].err)
+	require.Error(t, rows[2].err)
+
+	_, errc := readManifest(context.Background(), strings.NewReader("size,crc32c\n"), InventoryCSV, DEFAULT_MAX_LINE_SIZE)
+	require.Error(t, <-errc)
+}
+
+func TestReadManifestNames(t *testing.T) {
+	rows := collectManifest(t, `{"name":"../escape.txt"}
+{"name":"/abs.txt"}
+{"name":"a/../../b.txt"}
+{"name":"a/..b.txt"}
+`, InventoryJSONL)
+	require.Len(t, rows, 4)
+	for _, mr := range rows[:3] {
+		require.Error(t, mr.err, mr.name)
+		require.Contains(t, mr.err.Error(), ERROR_INVALID_MANIFEST_NAME)
+	}
+	require.NoError(t, rows[3].err)
+}
+
+func TestReadManifestLongLines(t *testing.T) {
+	long := `{"name":"` + strings.Repeat("x", 200*1024) + `"}`
+	rows := collectManifest(t, `{"name":"a.json"}`+"\n"+long+"\n"+`{"name":"b.json"}`, InventoryJSONL)
+	require.Len(t, rows, 3)
+	require.Equal(t, strings.Repeat("x", 200*1024), rows[1].name, "rows over 64KiB are read")
+
+	// rows past the max size are malformed, the rows after are read
+	ch, errc := readManifest(context.Background(), strings.NewReader(`{"name":"a.json"}`+"\n"+long+"\n"+`{"name":"b.json"}`), InventoryJSONL, 1024)
+	rows = []manifestRow{}
+	for mr := range ch {
+		rows = append(rows, mr)
+	}
+	require.NoError(t, <-errc)
+	require.Len(t, rows, 3)
+	require.Equal(t, "a.json", rows[0].name)
+	require.Error(t, rows[1].err)
+	require.Contains(t, rows[1].err.Error(), ERROR_MANIFEST_LINE_TOO_LONG)
+	require.Equal(t, int64(2), rows[1].row)
+	require.Equal(t, "b.json", rows[2].name)
+}
+
+func TestManifestCopyRejectsEscapingNames(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "data/a.txt", []byte("a"), nil)
+	f.put("bucket", "secret.txt", []byte("s"), nil)
+	cs := newFakeClient(t, f)
+
+	manifest := `{"name":"data/a.txt"}` + "\n" + `{"name":"data/../../secret.txt"}` + "\n"
+	report, err := cs.ProcessManifest(context.Background(), strings.NewReader(manifest), ManifestCopy, WithManifestBucket("bucket"), WithManifestDestination("bucket", "copy"))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), report.Counts[ManifestOK])
+	require.Equal(t, int64(1), report.Counts[ManifestMalformed])
+	_, _, ok := f.get("bucket", "copy/data/a.txt")
+	require.True(t, ok)
+	require.Equal(t, []string{"copy/data/a.txt"}, stagedNames(f, "bucket", "copy"))
+	_, _, ok = f.get("bucket", "secret.txt")
+	require.True(t, ok)
+}
+
+func TestManifestReportFailures(t *testing.T) {
+	report := ManifestReport{
+		Rows: 5,
+		Counts: map[ManifestOutcome]int64{
+			ManifestOK:        3,
+			ManifestMissing:   1,
+			ManifestMalformed: 1,
+		},
+	}
+	require.Equal(t, int64(2), report.Failures())
+}
+
+func TestManifestCopyRequireEmptyDestination(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "data/a.txt", []byte("a"), nil)
+	f.put("bucket", "release/", nil, nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	manifest := `{"name":"data/a.txt"}` + "\n"
+
+	// directory markers don't conflict, the normalized prefix is written
+	report, err := cs.ProcessManifest(ctx, strings.NewReader(manifest), ManifestCopy, WithManifestBucket("bucket"),
+		WithManifestDestination("bucket", "/release/"), WithManifestRequireEmptyDestination())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), report.Counts[ManifestOK])
+	_, _, ok := f.get("bucket", "release/data/a.txt")
+	require.True(t, ok)
+
+	copies := recordRequests(f, http.MethodPost, nil)
+	_, err = cs.ProcessManifest(ctx, strings.NewReader(manifest), ManifestCopy, WithManifestBucket("bucket"),
+		WithManifestDestination("bucket", "release"), WithManifestRequireEmptyDestination())
+	var nErr DestinationNotEmptyError
+	require.ErrorAs(t, err, &nErr)
+	require.Equal(t, "release/", nErr.Prefix)
+	require.Equal(t, []string{"release/data/a.txt"}, nErr.Names)
+	require.Empty(t, *copies, "nothing is copied into a non empty destination")
+
+	// overwriting stays the default
+	report, err = cs.ProcessManifest(ctx, strings.NewReader(manifest), ManifestCopy, WithManifestBucket("bucket"), WithManifestDestination("bucket", "release"))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), report.Counts[ManifestOK])
+
+	_, err = cs.ProcessManifest(ctx, strings.NewReader(manifest), ManifestCopy, WithManifestBucket("bucket"), WithManifestDestination("bucket", "../release"))
+	requireOutOfScope(t, err)
+}
+
+func TestManifestCopyKMSKey(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "data/a.txt", []byte("a"), nil)
+	f.put("bucket", "data/b.txt", []byte("b"), nil)
+	keys := recordKMSKeys(f)
+	cs := newFakeClient(t, f)
+	manifest := `{"name":"data/a.txt"}` + "\n" + `{"name":"data/b.txt"}` + "\n"
+
+	report, err := cs.ProcessManifest(context.Background(), strings.NewReader(manifest), ManifestCopy, WithManifestBucket("bucket"),
+		WithManifestDestination("bucket", "release"), WithManifestDestinationKMSKey(testKMSKey))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), report.Counts[ManifestOK])
+	require.Equal(t, []string{testKMSKey, testKMSKey}, keys["rewrite"])
+}
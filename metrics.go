@@ -0,0 +1,86 @@
+package cloudstorage
+
+import (
+	"time"
+)
+
+// TransferMetrics are the measurements of one upload or download,
+// timed around the byte movement, pre-flight attribute lookups & upload spooling excluded
+type TransferMetrics struct {
+	Op        string
+	Bucket    string
+	Object    string
+	RequestID string
+	Bytes     int64
+	Duration  time.Duration
+	// TimeToFirstByte is the time until the first content byte was received, downloads only,
+	// including the reads aborted by the first byte deadline
+	TimeToFirstByte time.Duration
+	// SlowStarts is the number of reads aborted by the request's first byte deadline, see WithFirstByteTimeout
+	SlowStarts     int
+	BytesPerSecond float64
+	// Direction is whether content was uploaded or downloaded
+	Direction TransferDirection
+	// StorageClass is the object's storage class, empty when not known, e.g. uploads failed before commit
+	StorageClass string
+	// Label is the object's cost attribution label, by the configured CostLabel
+	Label string
+	// Err is the transfer's error, nil on success
+	Err error
+}
+
+// MetricsRecorder receives client metrics, must be safe for concurrent use
+type MetricsRecorder interface {
+	// RecordTransfer is called once per upload & download, failed ones included
+	RecordTransfer(TransferMetrics)
+}
+
+// bytesPerSecond returns the throughput of given bytes moved in given duration
+func bytesPerSecond(bytes int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) / d.Seconds()
+}
+
+// startTransfer marks the start of the operation's byte movement in given direction
+func (op *operation) startTransfer(direction TransferDirection) {
+	op.transferStart, op.direction = op.cs.now(), direction
+	op.firstByte = time.Time{}
+}
+
+// markFirstByte records the first content byte of a started transfer
+func (op *operation) markFirstByte() {
+	if op.firstByte.IsZero() && !op.transferStart.IsZero() {
+		op.firstByte = op.cs.now()
+	}
+}
+
+// endTransfer returns the measurements of the operation's transfer of given bytes,
+// reported to the configured metrics recorder
+func (op *operation) endTransfer(bytes int64, err error) TransferMetrics {
+	m := TransferMetrics{
+		Op:         op.name,
+		Bucket:     op.bucket,
+		Object:     op.object,
+		RequestID:  op.requestID,
+		Bytes:      bytes,
+		Direction:  op.direction,
+		SlowStarts: op.slowStarts,
+		Err:        err,
+	}
+	if op.cs != nil {
+		m.StorageClass, m.Label = op.storageClass, op.cs.costLabel(op.bucket, op.object)
+	}
+	if !op.transferStart.IsZero() {
+		m.Duration = op.cs.since(op.transferStart)
+		if !op.firstByte.IsZero() {
+			m.TimeToFirstByte = op.firstByte.Sub(op.transferStart)
+		}
+	}
+	m.BytesPerSecond = bytesPerSecond(bytes, m.Duration)
+	if op.cs != nil && op.cs.config.Metrics != nil {
+		op.cs.config.Metrics.RecordTransfer(m)
+	}
+	return m
+}
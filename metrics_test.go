@@ -0,0 +1,125 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMetrics records transfer metrics for assertions
+type recordingMetrics struct {
+	mu        sync.Mutex
+	transfers []TransferMetrics
+}
+
+func (m *recordingMetrics) RecordTransfer(tm TransferMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.transfers = append(m.transfers, tm)
+}
+
+func (m *recordingMetrics) all() []TransferMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]TransferMetrics{}, m.transfers...)
+}
+
+func TestBytesPerSecond(t *testing.T) {
+	require.Equal(t, float64(0), bytesPerSecond(100, 0))
+	require.Equal(t, float64(200), bytesPerSecond(100, 500*time.Millisecond))
+}
+
+func TestTransferMetrics(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 10*1024)
+	f := newFakeGCS()
+	delay := 20 * time.Millisecond
+	f.fail = func(r *http.Request) int {
+		// content reads are served by the XML endpoint, outside the JSON API path
+		if r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/storage/v1/") {
+			time.Sleep(delay)
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	metrics := &recordingMetrics{}
+	cs.config.Metrics = metrics
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	up, err := cs.Upload(context.Background(), bytes.NewReader(content), cfr)
+	require.NoError(t, err)
+	require.Greater(t, up.Duration, time.Duration(0))
+	require.Greater(t, up.BytesPerSecond, float64(0))
+
+	var buf bytes.Buffer
+	down, err := cs.Download(context.Background(), &buf, cfr)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, down.TimeToFirstByte, delay)
+	require.GreaterOrEqual(t, down.Duration, down.TimeToFirstByte)
+	require.Equal(t, bytesPerSecond(down.Bytes, down.Duration), down.BytesPerSecond)
+
+	// recorded metrics match the results
+	recorded := metrics.all()
+	require.Len(t, recorded, 2)
+	require.Equal(t, "UploadFile", recorded[0].Op)
+	require.Equal(t, up.Bytes, recorded[0].Bytes)
+	require.Equal(t, up.Duration, recorded[0].Duration)
+	require.Equal(t, up.BytesPerSecond, recorded[0].BytesPerSecond)
+	require.Equal(t, up.RequestID, recorded[0].RequestID)
+	require.Equal(t, "DownloadFile", recorded[1].Op)
+	require.Equal(t, "path/file.bin", recorded[1].Object)
+	require.Equal(t, down.Duration, recorded[1].Duration)
+	require.Equal(t, down.TimeToFirstByte, recorded[1].TimeToFirstByte)
+	require.Equal(t, down.BytesPerSecond, recorded[1].BytesPerSecond)
+	require.NoError(t, recorded[1].Err)
+}
+
+func TestTransferMetricsFailure(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", []byte("content"), nil)
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/storage/v1/") {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	metrics := &recordingMetrics{}
+	cs.config.Metrics = metrics
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = cs.Download(context.Background(), &buf, cfr)
+	require.Error(t, err)
+
+	recorded := metrics.all()
+	require.Len(t, recorded, 1)
+	require.Equal(t, err, recorded[0].Err)
+	require.Equal(t, int64(0), recorded[0].Bytes)
+}
+
+func TestFanOutTransferMetrics(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 4096)
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	metrics := &recordingMetrics{}
+	cs.config.Metrics = metrics
+	primary, err := NewCloudFileRequest("bucket", "file.bin", "primary", 0)
+	require.NoError(t, err)
+	replica, err := NewCloudFileRequest("bucket", "file.bin", "replica", 0)
+	require.NoError(t, err)
+
+	res, err := cs.UploadFanOut(context.Background(), bytes.NewReader(content), primary, []CloudFileRequest{replica})
+	require.NoError(t, err)
+	require.Greater(t, res.Duration, time.Duration(0))
+	require.Equal(t, bytesPerSecond(int64(2*len(content)), res.Duration), res.BytesPerSecond)
+	// destination uploads are recorded individually
+	require.Len(t, metrics.all(), 2)
+}
@@ -0,0 +1,212 @@
+package cloudstorage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+
+	"github.com/comfforts/errors"
+)
+
+const (
+	ERROR_INVALID_NAME_KEY  string = "name codec key must be 32, 48 or 64 bytes"
+	ERROR_DECODING_NAME     string = "error decoding object name"
+	ERROR_NAME_SEGMENT_SIZE string = "encoded name segment too short"
+)
+
+var (
+	ErrInvalidNameKey = errors.NewAppError(ERROR_INVALID_NAME_KEY)
+	ErrDecodingName   = errors.NewAppError(ERROR_DECODING_NAME)
+)
+
+// NameCodec maps the object paths callers use to the names stored in the bucket & back,
+// e.g. to keep identifiers in paths from anyone with list permission, must be safe for concurrent use.
+// Encode must be deterministic & map every slash separated segment on its own, empty segments kept,
+// so the encoded path prefix of whole segments lists the encoded names under it.
+// Decode fails for stored names the codec didn't encode.
+type NameCodec interface {
+	Encode(plaintext string) string
+	Decode(stored string) (string, error)
+}
+
+// PassthroughNameCodec stores names as given
+type PassthroughNameCodec struct{}
+
+// Encode returns the path as is
+func (PassthroughNameCodec) Encode(plaintext string) string {
+	return plaintext
+}
+
+// Decode returns the stored name as is
+func (PassthroughNameCodec) Decode(stored string) (string, error) {
+	return stored, nil
+}
+
+// SIVNameCodec encrypts every path segment with deterministic AES-SIV (RFC 5297),
+// stored segments are the URL safe unpadded base64 of the synthetic IV & ciphertext.
+// Equal segments encrypt to equal stored segments, prefix listings work on whole segments only.
+// A segment grows by 16 bytes & a third, stored names are limited to 1024 bytes by the service.
+type SIVNameCodec struct {
+	mac cipher.Block
+	ctr cipher.Block
+}
+
+// NewSIVNameCodec returns an AES-SIV name codec of given key, 32, 48 or 64 bytes for AES-128, 192 or 256,
+// the first half of the key authenticates, the second encrypts
+func NewSIVNameCodec(key []byte) (*SIVNameCodec, error) {
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, ErrInvalidNameKey
+	}
+	mac, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := aes.NewCipher(key[len(key)/2:])
+	if err != nil {
+		return nil, err
+	}
+	return &SIVNameCodec{mac: mac, ctr: ctr}, nil
+}
+
+// Encode encrypts every non empty segment of the path
+func (c *SIVNameCodec) Encode(plaintext string) string {
+	segs := strings.Split(plaintext, "/")
+	for i, seg := range segs {
+		if seg != "" {
+			segs[i] = base64.RawURLEncoding.EncodeToString(c.seal([]byte(seg)))
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// Decode decrypts every non empty segment of the stored name, fails with ErrDecodingName
+// when a segment isn't one the codec's key encrypted
+func (c *SIVNameCodec) Decode(stored string) (string, error) {
+	segs := strings.Split(stored, "/")
+	for i, seg := range segs {
+		if seg == "" {
+			continue
+		}
+		sealed, err := base64.RawURLEncoding.DecodeString(seg)
+		if err != nil {
+			return "", errors.WrapError(err, "%s %q", ERROR_DECODING_NAME, stored)
+		}
+		plain, err := c.open(sealed)
+		if err != nil {
+			return "", errors.WrapError(err, "%s %q", ERROR_DECODING_NAME, stored)
+		}
+		segs[i] = string(plain)
+	}
+	return strings.Join(segs, "/"), nil
+}
+
+// seal returns the synthetic IV followed by the ciphertext of given plaintext & associated data
+func (c *SIVNameCodec) seal(plaintext []byte, ad ...[]byte) []byte {
+	v := c.s2v(plaintext, ad...)
+	out := make([]byte, aes.BlockSize+len(plaintext))
+	copy(out, v)
+	c.xorKeyStream(out[aes.BlockSize:], plaintext, v)
+	return out
+}
+
+// open decrypts sealed IV & ciphertext, fails unless the IV authenticates the plaintext & associated data
+func (c *SIVNameCodec) open(sealed []byte, ad ...[]byte) ([]byte, error) {
+	if len(sealed) < aes.BlockSize {
+		return nil, errors.NewAppError(ERROR_NAME_SEGMENT_SIZE)
+	}
+	v := sealed[:aes.BlockSize]
+	plain := make([]byte, len(sealed)-aes.BlockSize)
+	c.xorKeyStream(plain, sealed[aes.BlockSize:], v)
+	if subtle.ConstantTimeCompare(c.s2v(plain, ad...), v) != 1 {
+		return nil, ErrDecodingName
+	}
+	return plain, nil
+}
+
+// xorKeyStream applies the CTR key stream of the synthetic IV, its 31st & 63rd bits from the right cleared
+func (c *SIVNameCodec) xorKeyStream(dst, src, v []byte) {
+	q := make([]byte, aes.BlockSize)
+	copy(q, v)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	cipher.NewCTR(c.ctr, q).XORKeyStream(dst, src)
+}
+
+// s2v is the CMAC based pseudo random function of the associated data strings & the plaintext
+func (c *SIVNameCodec) s2v(plaintext []byte, ad ...[]byte) []byte {
+	d := c.cmac(make([]byte, aes.BlockSize))
+	for _, s := range ad {
+		d = sivDouble(d)
+		sivXor(d, c.cmac(s))
+	}
+	var t []byte
+	if len(plaintext) >= aes.BlockSize {
+		t = append([]byte{}, plaintext...)
+		sivXor(t[len(t)-aes.BlockSize:], d)
+	} else {
+		t = sivDouble(d)
+		sivXor(t, sivPad(plaintext))
+	}
+	return c.cmac(t)
+}
+
+// cmac is AES-CMAC (RFC 4493) of given message with the codec's authentication key
+func (c *SIVNameCodec) cmac(msg []byte) []byte {
+	l := make([]byte, aes.BlockSize)
+	c.mac.Encrypt(l, l)
+	k1 := sivDouble(l)
+	last := make([]byte, aes.BlockSize)
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	if n > 0 && len(msg)%aes.BlockSize == 0 {
+		copy(last, msg[(n-1)*aes.BlockSize:])
+		sivXor(last, k1)
+	} else {
+		// an empty message is one padded block
+		if n == 0 {
+			n = 1
+		}
+		copy(last, sivPad(msg[(n-1)*aes.BlockSize:]))
+		sivXor(last, sivDouble(k1))
+	}
+	x := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		sivXor(x, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		c.mac.Encrypt(x, x)
+	}
+	sivXor(x, last)
+	c.mac.Encrypt(x, x)
+	return x
+}
+
+// sivDouble multiplies given block by x in GF(2^128)
+func sivDouble(b []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	var carry byte
+	for i := aes.BlockSize - 1; i >= 0; i-- {
+		out[i] = b[i]<<1 | carry
+		carry = b[i] >> 7
+	}
+	if carry != 0 {
+		out[aes.BlockSize-1] ^= 0x87
+	}
+	return out
+}
+
+// sivPad returns given partial block followed by a one bit & zeros
+func sivPad(b []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	copy(out, b)
+	out[len(b)] = 0x80
+	return out
+}
+
+// sivXor xors src into dst
+func sivXor(dst, src []byte) {
+	for i := range src {
+		dst[i] ^= src[i]
+	}
+}
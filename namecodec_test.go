@@ -0,0 +1,185 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sivTestCodec(t *testing.T) *SIVNameCodec {
+	c, err := NewSIVNameCodec(bytes.Repeat([]byte{7}, 32))
+	require.NoError(t, err)
+	return c
+}
+
+// storedNames returns the names of the fake's objects in given bucket
+func (f *fakeGCS) storedNames(bucket string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := []string{}
+	for key := range f.objects {
+		if strings.HasPrefix(key, bucket+"/") {
+			names = append(names, strings.TrimPrefix(key, bucket+"/"))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSIVTestVector(t *testing.T) {
+	// RFC 5297 A.1, deterministic authenticated encryption
+	key, _ := hex.DecodeString("fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	ad, _ := hex.DecodeString("101112131415161718191a1b1c1d1e1f2021222324252627")
+	plaintext, _ := hex.DecodeString("112233445566778899aabbccddee")
+	c, err := NewSIVNameCodec(key)
+	require.NoError(t, err)
+	sealed := c.seal(plaintext, ad)
+	require.Equal(t, "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c", hex.EncodeToString(sealed))
+	opened, err := c.open(sealed, ad)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, opened)
+}
+
+func TestSIVNameCodec(t *testing.T) {
+	c := sivTestCodec(t)
+	for _, name := range []string{"", "users", "users/alice/report.csv", "users/alice/", "a//b", "a long segment of more than sixteen bytes/x"} {
+		encoded := c.Encode(name)
+		require.Equal(t, strings.Count(name, "/"), strings.Count(encoded, "/"), "segments are encoded one by one")
+		require.Equal(t, encoded, c.Encode(name), "encoding is deterministic")
+		decoded, err := c.Decode(encoded)
+		require.NoError(t, err)
+		require.Equal(t, name, decoded)
+	}
+	require.NotContains(t, c.Encode("users/alice"), "alice")
+	// encoded prefixes of whole segments prefix encoded names
+	require.True(t, strings.HasPrefix(c.Encode("users/alice/report.csv"), c.Encode("users/alice/")))
+
+	// names the codec didn't encode fail to decode
+	other, err := NewSIVNameCodec(bytes.Repeat([]byte{8}, 64))
+	require.NoError(t, err)
+	for _, stored := range []string{"users/alice", "abcdefghijklmnopqrstuvwxyz", other.Encode("users"), c.Encode("users")[1:]} {
+		_, err := c.Decode(stored)
+		require.Error(t, err, stored)
+	}
+
+	_, err = NewSIVNameCodec([]byte("short"))
+	require.Equal(t, ErrInvalidNameKey, err)
+}
+
+func TestNameCodecClient(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	codec := sivTestCodec(t)
+	cs.config.NameCodec = codec
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "report.csv", "users/alice", 0)
+	require.NoError(t, err)
+
+	res, err := cs.Upload(ctx, strings.NewReader("content"), cfr)
+	require.NoError(t, err)
+	require.Equal(t, "users/alice/report.csv", res.Attrs.Name)
+	require.False(t, res.Attrs.NameNotDecoded)
+	require.Equal(t, []string{codec.Encode("users/alice/report.csv")}, f.storedNames("bucket"))
+
+	// callers keep using plaintext names
+	var buf bytes.Buffer
+	_, err = cs.Download(ctx, &buf, cfr)
+	require.NoError(t, err)
+	require.Equal(t, "content", buf.String())
+	attrs, err := cs.GetAttrs(ctx, cfr)
+	require.NoError(t, err)
+	require.Equal(t, "users/alice/report.csv", attrs.Name)
+
+	notes, err := NewCloudFileRequest("bucket", "notes.txt", "users/alice", 0)
+	require.NoError(t, err)
+	_, err = cs.Upload(ctx, strings.NewReader("notes"), notes)
+	require.NoError(t, err)
+	dir, err := NewCloudFileRequest("bucket", "", "users/alice", 0)
+	require.NoError(t, err)
+	names, err := cs.ListObjects(ctx, dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"users/alice/notes.txt", "users/alice/report.csv"}, names)
+	root, err := NewCloudFileRequest("bucket", "", "users", 0)
+	require.NoError(t, err)
+	listing, err := cs.ListDir(ctx, root)
+	require.NoError(t, err)
+	require.Equal(t, []string{"users/alice/"}, listing.Dirs)
+	infos, err := cs.ListObjectsInfo(ctx, dir)
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	require.Equal(t, "users/alice/notes.txt", infos[0].Name)
+
+	// parallel upload parts are encoded too
+	parts, err := NewCloudFileRequest("bucket", "parts.bin", "users/alice", 0)
+	require.NoError(t, err)
+	data := bytes.Repeat([]byte("p"), 3*256*1024)
+	_, err = cs.UploadFromReaderAt(ctx, bytes.NewReader(data), int64(len(data)), parts, WithChunkSize(256*1024), WithParallelUpload(1, 3))
+	require.NoError(t, err)
+	for _, name := range f.storedNames("bucket") {
+		require.NotContains(t, name, "alice")
+	}
+
+	require.NoError(t, cs.DeleteObject(ctx, cfr))
+	names, err = cs.ListObjects(ctx, dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"users/alice/notes.txt", "users/alice/parts.bin"}, names)
+}
+
+func TestNameCodecScoped(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	codec := sivTestCodec(t)
+	cs.config.NameCodec = codec
+	cfr := CloudFileRequest{file: "d.txt", path: "docs"}
+
+	_, err := cs.Upload(tenantContext(), strings.NewReader("d"), cfr)
+	require.NoError(t, err)
+	require.Equal(t, []string{codec.Encode("tenant-a/docs/d.txt")}, f.storedNames("bucket"))
+	attrs, err := cs.GetAttrs(tenantContext(), cfr)
+	require.NoError(t, err)
+	require.Equal(t, "docs/d.txt", attrs.Name)
+	names, err := cs.ListObjects(tenantContext(), CloudFileRequest{bucket: "bucket", wholeBucket: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"docs/d.txt"}, names)
+}
+
+func TestNameCodecMixedBucket(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	cs.config.NameCodec = sivTestCodec(t)
+	f.put("bucket", "users/bob/legacy.txt", []byte("legacy"), nil)
+	cfr, err := NewCloudFileRequest("bucket", "new.txt", "users/bob", 0)
+	require.NoError(t, err)
+	_, err = cs.Upload(context.Background(), strings.NewReader("new"), cfr)
+	require.NoError(t, err)
+
+	// names stored before the codec are listed as stored & flagged
+	infos, err := cs.ListObjectsInfo(context.Background(), CloudFileRequest{bucket: "bucket", wholeBucket: true})
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	flagged := map[string]bool{}
+	for _, info := range infos {
+		flagged[info.Name] = info.NameNotDecoded
+	}
+	require.Equal(t, map[string]bool{"users/bob/new.txt": false, "users/bob/legacy.txt": true}, flagged)
+	names, err := cs.ListObjects(context.Background(), CloudFileRequest{bucket: "bucket", wholeBucket: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"users/bob/legacy.txt", "users/bob/new.txt"}, names)
+}
+
+func TestPassthroughNameCodec(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	cs.config.NameCodec = PassthroughNameCodec{}
+	cfr, err := NewCloudFileRequest("bucket", "a.txt", "path", 0)
+	require.NoError(t, err)
+	res, err := cs.Upload(context.Background(), strings.NewReader("a"), cfr)
+	require.NoError(t, err)
+	require.Equal(t, "path/a.txt", res.Attrs.Name)
+	require.Equal(t, []string{"path/a.txt"}, f.storedNames("bucket"))
+}
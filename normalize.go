@@ -0,0 +1,167 @@
+package cloudstorage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_EMPTY_FILE_NAME      string = "file name normalizes to an empty name"
+	ERROR_FINDING_STRAY_OBJECT string = "error finding stray objects"
+	ERROR_INVALID_OBJECT_NAME  string = "invalid object name"
+)
+
+// MAX_OBJECT_NAME_BYTES is the service's limit of object name length, in UTF-8 encoded bytes
+const MAX_OBJECT_NAME_BYTES = 1024
+
+// nameHashLen is the length of the hash suffix of truncated names
+const nameHashLen = 8
+
+var (
+	ErrEmptyFileName     = errors.NewAppError(ERROR_EMPTY_FILE_NAME)
+	ErrInvalidObjectName = errors.NewAppError(ERROR_INVALID_OBJECT_NAME)
+)
+
+// InvalidObjectNameError is returned for object names the service would reject,
+// over MAX_OBJECT_NAME_BYTES or not valid UTF-8, matches ErrInvalidObjectName with errors.Is
+type InvalidObjectNameError struct {
+	Name string
+	// Bytes is the name's length in bytes
+	Bytes int
+	// InvalidUTF8 is set for names that aren't valid UTF-8
+	InvalidUTF8 bool
+}
+
+func (e InvalidObjectNameError) Error() string {
+	if e.InvalidUTF8 {
+		return fmt.Sprintf("%s %q: invalid UTF-8", ERROR_INVALID_OBJECT_NAME, e.Name)
+	}
+	return fmt.Sprintf("%s %.64q...: %d bytes, over the %d bytes limit", ERROR_INVALID_OBJECT_NAME, e.Name, e.Bytes, MAX_OBJECT_NAME_BYTES)
+}
+
+// Is matches ErrInvalidObjectName
+func (e InvalidObjectNameError) Is(target error) bool {
+	return target == ErrInvalidObjectName
+}
+
+// ValidateObjectName fails names the service would reject with InvalidObjectNameError,
+// names over MAX_OBJECT_NAME_BYTES or not valid UTF-8
+func ValidateObjectName(name string) error {
+	switch {
+	case !utf8.ValidString(name):
+		return InvalidObjectNameError{Name: name, Bytes: len(name), InvalidUTF8: true}
+	case len(name) > MAX_OBJECT_NAME_BYTES:
+		return InvalidObjectNameError{Name: name, Bytes: len(name)}
+	}
+	return nil
+}
+
+// TruncateNameSafely returns given name shortened to at most max bytes, MAX_OBJECT_NAME_BYTES when not positive,
+// cut on a rune boundary & suffixed with a short hash of the whole name, so names sharing a long prefix stay unique.
+// Names within the limit are returned as is.
+func TruncateNameSafely(name string, max int) string {
+	if max <= 0 {
+		max = MAX_OBJECT_NAME_BYTES
+	}
+	if len(name) <= max {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	suffix := "~" + hex.EncodeToString(sum[:])[:nameHashLen]
+	if max <= len(suffix) {
+		return suffix[len(suffix)-max:]
+	}
+	cut := max - len(suffix)
+	for cut > 0 && !utf8.RuneStart(name[cut]) {
+		cut--
+	}
+	return name[:cut] + suffix
+}
+
+// StrayObject is a stored object whose name changes under normalization,
+// unreachable through requests naming it, which are normalized before use
+type StrayObject struct {
+	// Name is the stored object name
+	Name string
+	// Normalized is the name requests for the object resolve to, empty for names normalizing to nothing
+	Normalized string
+}
+
+// NormalizeName returns given object name or path with leading slashes stripped
+// & doubled slashes collapsed, a trailing slash is kept
+func NormalizeName(name string) string {
+	segs := strings.Split(name, "/")
+	kept := segs[:0]
+	for _, seg := range segs {
+		if seg != "" {
+			kept = append(kept, seg)
+		}
+	}
+	normalized := strings.Join(kept, "/")
+	if normalized != "" && strings.HasSuffix(name, "/") {
+		normalized += "/"
+	}
+	return normalized
+}
+
+// normalized returns request with normalized file & path, without trailing slash, so uploads, listings,
+// downloads & deletes build the same object names, fails with ErrEmptyFileName for file names normalizing to nothing
+// & InvalidObjectNameError for object names the service would reject
+func (cfr CloudFileRequest) normalized() (CloudFileRequest, error) {
+	if cfr.file != "" {
+		file := NormalizeName(cfr.file)
+		if file == "" {
+			return CloudFileRequest{}, errors.WrapError(ErrEmptyFileName, "%s %q", ERROR_EMPTY_FILE_NAME, cfr.file)
+		}
+		cfr.file = file
+	}
+	cfr.path = strings.TrimSuffix(NormalizeName(cfr.path), "/")
+	if err := ValidateObjectName(cfr.objectPath()); err != nil {
+		return CloudFileRequest{}, err
+	}
+	return cfr, nil
+}
+
+// FindStrayObjects lists the stored objects of given bucket, under the scope prefix, whose names change
+// under normalization, e.g. stored with a leading or doubled slash before names were normalized,
+// for repairs with the stored names. Objects are streamed, memory grows with the number of strays.
+func (cs *cloudStorageClient) FindStrayObjects(ctx context.Context, bucket string) ([]StrayObject, error) {
+	cfr, err := cs.scopedBucket(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	op := cs.startOperation(ctx, "FindStrayObjects", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	prefix := dirPrefix(cfr.path)
+	op.object = prefix
+
+	strays := []StrayObject{}
+	it := cs.bucketHandle(cfr).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err != nil {
+			if err == iterator.Done {
+				break
+			}
+			op.logger.Error(ERROR_FINDING_STRAY_OBJECT, zap.Error(err), zap.Int("found", len(strays)))
+			return strays, op.wrapError(err, ERROR_LISTING_OBJECTS)
+		}
+		if normalized := NormalizeName(attrs.Name); normalized != attrs.Name {
+			strays = append(strays, StrayObject{Name: attrs.Name, Normalized: normalized})
+		}
+	}
+	if len(strays) > 0 {
+		op.logger.Info("stray objects found", zap.Int("strays", len(strays)))
+	}
+	return strays, nil
+}
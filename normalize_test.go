@@ -0,0 +1,182 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/comfforts/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeName(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                 "",
+		"/":                "",
+		"//":               "",
+		"reports":          "reports",
+		"/reports":         "reports",
+		"//reports//q1":    "reports/q1",
+		"reports/q1/":      "reports/q1/",
+		"/reports//q1//":   "reports/q1/",
+		"a.json":           "a.json",
+		"reports/./a.json": "reports/./a.json",
+	} {
+		require.Equal(t, want, NormalizeName(in), "NormalizeName(%q)", in)
+	}
+}
+
+func TestNormalizedNamesRoundTrip(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		path, file, name string
+	}{
+		{path: "/reports", file: "a.json", name: "reports/a.json"},
+		{path: "reports//daily/", file: "b.json", name: "reports/daily/b.json"},
+		{path: "//", file: "/c.json", name: "c.json"},
+		{path: "/reports/", file: "d//e.json", name: "reports/d/e.json"},
+	} {
+		cfr, err := NewCloudFileRequest("bucket", tc.file, tc.path, 0)
+		require.NoError(t, err)
+		_, err = cs.Upload(ctx, strings.NewReader(tc.name), cfr)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, []string{tc.name}, f.storedNames("bucket"))
+
+		// listings & downloads find the object under the same name
+		names, err := cs.ListObjects(ctx, cfr)
+		require.NoError(t, err)
+		require.Equal(t, []string{tc.name}, names)
+		infos, err := cs.ListObjectsInfo(ctx, CloudFileRequest{bucket: "bucket", path: tc.path, wholeBucket: true})
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+		require.Equal(t, tc.name, infos[0].Name)
+		var buf bytes.Buffer
+		_, err = cs.Download(ctx, &buf, cfr)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.name, buf.String())
+		ok, err := cs.Exists(ctx, cfr)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		require.NoError(t, cs.DeleteObject(ctx, cfr))
+		require.Empty(t, f.storedNames("bucket"))
+	}
+
+	// file names normalizing to nothing are rejected
+	for _, file := range []string{"/", "//"} {
+		cfr, err := NewCloudFileRequest("bucket", file, "reports", 0)
+		require.NoError(t, err)
+		_, err = cs.Upload(ctx, strings.NewReader("x"), cfr)
+		appErr, ok := err.(errors.AppError)
+		require.True(t, ok, "%T %v", err, err)
+		require.Equal(t, ErrEmptyFileName, appErr.Inner)
+		_, err = cs.Download(ctx, &bytes.Buffer{}, cfr)
+		require.Error(t, err)
+	}
+}
+
+func TestFindStrayObjects(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "/reports/a.json", []byte("{}"), nil)
+	f.put("bucket", "reports//b.json", []byte("{}"), nil)
+	f.put("bucket", "reports/c.json", []byte("{}"), nil)
+	f.put("bucket", "reports/", nil, nil)
+	f.put("bucket", "/", nil, nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	strays, err := cs.FindStrayObjects(ctx, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, []StrayObject{
+		{Name: "/", Normalized: ""},
+		{Name: "/reports/a.json", Normalized: "reports/a.json"},
+		{Name: "reports//b.json", Normalized: "reports/b.json"},
+	}, strays)
+
+	// strays under the scope prefix
+	putTenants(f)
+	f.put("bucket", "tenant-a//x.json", []byte("{}"), nil)
+	strays, err = cs.FindStrayObjects(tenantContext(), "bucket")
+	require.NoError(t, err)
+	require.Equal(t, []StrayObject{{Name: "tenant-a//x.json", Normalized: "tenant-a/x.json"}}, strays)
+
+	_, err = cs.FindStrayObjects(ctx, "")
+	require.ErrorIs(t, err, ErrBucketNameMissing)
+}
+
+// unreadReader fails the test when read
+type unreadReader struct {
+	t *testing.T
+}
+
+func (r unreadReader) Read(p []byte) (int, error) {
+	r.t.Error("caller bytes read before the name was validated")
+	return 0, io.EOF
+}
+
+func TestValidateObjectName(t *testing.T) {
+	require.NoError(t, ValidateObjectName("reports/ä.json"))
+	require.NoError(t, ValidateObjectName(strings.Repeat("a", MAX_OBJECT_NAME_BYTES)))
+
+	long := strings.Repeat("ä", MAX_OBJECT_NAME_BYTES/2+1)
+	err := ValidateObjectName(long)
+	require.ErrorIs(t, err, ErrInvalidObjectName)
+	var nameErr InvalidObjectNameError
+	require.True(t, stderrors.As(err, &nameErr))
+	require.Equal(t, MAX_OBJECT_NAME_BYTES+2, nameErr.Bytes, "measured in UTF-8 bytes, not runes")
+	require.False(t, nameErr.InvalidUTF8)
+
+	err = ValidateObjectName("reports/\xff.json")
+	require.True(t, stderrors.As(err, &nameErr))
+	require.True(t, nameErr.InvalidUTF8)
+
+	// requests are validated when built & once scoped
+	_, err = NewCloudFileRequest("bucket", long, "", 0)
+	require.ErrorIs(t, err, ErrInvalidObjectName)
+	_, err = NewCloudFileRequest("bucket", "x.json", "bad\xfe", 0)
+	require.ErrorIs(t, err, ErrInvalidObjectName)
+	_, err = NewCloudFileRequest("bucket", "x.json", strings.Repeat("a//", 400), 0)
+	require.NoError(t, err, "doubled slashes are normalized before measuring")
+
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	fits := strings.Repeat("a", MAX_OBJECT_NAME_BYTES-len("x.json")-1)
+	cfr, err := NewCloudFileRequest("bucket", "x.json", fits, 0, WithoutSpooling())
+	require.NoError(t, err)
+	_, err = cs.Upload(tenantContext(), unreadReader{t}, cfr)
+	require.ErrorIs(t, err, ErrInvalidObjectName, "the scope prefix takes the name over the limit")
+	_, err = cs.UploadFromReaderAt(context.Background(), strings.NewReader("x"), 1, CloudFileRequest{bucket: "bucket", file: long})
+	require.ErrorIs(t, err, ErrInvalidObjectName)
+	_, err = cs.TransformObject(context.Background(), cfr, CloudFileRequest{bucket: "bucket", file: "\xff"}, func(io.Writer, io.Reader) error {
+		t.Error("transform ran")
+		return nil
+	})
+	require.ErrorIs(t, err, ErrInvalidObjectName)
+	require.Empty(t, f.storedNames("bucket"))
+}
+
+func TestTruncateNameSafely(t *testing.T) {
+	require.Equal(t, "short.json", TruncateNameSafely("short.json", 0))
+
+	long := strings.Repeat("ä", MAX_OBJECT_NAME_BYTES)
+	truncated := TruncateNameSafely(long, 0)
+	require.LessOrEqual(t, len(truncated), MAX_OBJECT_NAME_BYTES)
+	require.NoError(t, ValidateObjectName(truncated), "cut on a rune boundary")
+	require.True(t, strings.HasPrefix(truncated, strings.Repeat("ä", 100)))
+
+	// names sharing the kept prefix stay unique, truncation is stable
+	other := TruncateNameSafely(long+"b", 0)
+	require.NotEqual(t, truncated, other)
+	require.Equal(t, truncated, TruncateNameSafely(long, 0))
+	require.Len(t, other, MAX_OBJECT_NAME_BYTES-1, "the last rune didn't fit")
+
+	require.Equal(t, "abc~", TruncateNameSafely("abcdefghijklmnop", 12)[:4])
+	require.Len(t, TruncateNameSafely("abcdefghijklmnop", 12), 12)
+	require.Len(t, TruncateNameSafely("abcdefghijklmnop", 4), 4)
+}
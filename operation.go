@@ -0,0 +1,216 @@
+package cloudstorage
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"github.com/comfforts/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// operation holds the details of a single storage call,
+// shared by the call's logs & errors
+type operation struct {
+	name      string
+	bucket    string
+	object    string
+	requestID string
+	logger    logger.AppLogger
+	// ctx carries the request's call extras to the operation's calls, ctx & cs classify not found failures
+	ctx context.Context
+	cs  *cloudStorageClient
+	// bucketListed is set once a listing found the bucket, not found failures are then missing objects
+	bucketListed bool
+	// start & bytes are reported for slow operations
+	start time.Time
+	bytes int64
+	// transferStart & firstByte time the operation's byte movement
+	transferStart time.Time
+	firstByte     time.Time
+	// direction & storageClass are reported with the transfer's metrics
+	direction    TransferDirection
+	storageClass string
+	// slowStarts counts the reads aborted by the request's first byte deadline
+	slowStarts int
+	// audited operations are recorded by finish with their outcome & written generation
+	audited    bool
+	outcome    *opOutcome
+	generation int64
+	// tracked operations are counted in flight by the client's lifecycle until finished
+	tracked bool
+}
+
+// startOperation resolves the request ID & returns operation scoped logger & error details
+func (cs *cloudStorageClient) startOperation(ctx context.Context, name string, cfr CloudFileRequest) *operation {
+	op := &operation{
+		name:      name,
+		bucket:    cfr.bucket,
+		requestID: resolveRequestID(ctx, cfr),
+		ctx:       withCallExtras(ctx, cfr.callExtras),
+		cs:        cs,
+		start:     cs.now(),
+		audited:   cs.audited(ctx, name),
+		outcome:   &opOutcome{},
+		tracked:   cs.life.enter(),
+	}
+	if op.tracked {
+		op.ctx = withinOperation(op.ctx, cs.life)
+	}
+	if cfr.file != "" {
+		op.object = cfr.objectPath()
+	}
+	op.logger = &fieldsLogger{
+		AppLogger: cs.logger,
+		fields: []zapcore.Field{
+			zap.String("requestId", op.requestID),
+			zap.String("op", op.name),
+		},
+	}
+	return op
+}
+
+// forObject returns a copy of the operation for one object of a batch call,
+// sharing the batch's logger & request ID
+func (op *operation) forObject(bucket, object string) *operation {
+	item := *op
+	item.bucket, item.object = bucket, object
+	return &item
+}
+
+// finish audits the operation, counts it done if it exceeded the configured slow operation threshold,
+// deferred by every public method
+func (op *operation) finish() {
+	if op.cs == nil {
+		return
+	}
+	if op.tracked {
+		defer op.cs.life.leave()
+	}
+	op.audit()
+	if op.cs.config.SlowOpThreshold <= 0 {
+		return
+	}
+	elapsed := op.cs.since(op.start)
+	if elapsed <= op.cs.config.SlowOpThreshold {
+		return
+	}
+	op.logger.Info(
+		"slow storage operation",
+		zap.String("bucket", op.bucket),
+		zap.String("filepath", op.object),
+		zap.Int64("bytes", op.bytes),
+		zap.Duration("elapsed", elapsed),
+		zap.Duration("threshold", op.cs.config.SlowOpThreshold),
+	)
+}
+
+// countingReader counts bytes read into the operation
+type countingReader struct {
+	r  io.Reader
+	op *operation
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.op.bytes += int64(n)
+	return n, err
+}
+
+// countingWriter counts bytes written into the operation
+type countingWriter struct {
+	w  io.Writer
+	op *operation
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		cw.op.markFirstByte()
+	}
+	n, err := cw.w.Write(p)
+	cw.op.bytes += int64(n)
+	return n, err
+}
+
+// wrapError wraps given error with message & operation details
+func (op *operation) wrapError(err error, msgf string, msgArgs ...interface{}) error {
+	if op.outcome != nil {
+		op.outcome.fail(err)
+	}
+	_, reason, domain, details := apiErrorDetails(err)
+	return StorageError{
+		AppError:   errors.WrapError(err, msgf, msgArgs...),
+		Op:         op.name,
+		Bucket:     op.bucket,
+		Object:     op.object,
+		RequestID:  op.requestID,
+		Class:      op.errorClass(err),
+		Permission: missingPermission(err),
+		Reason:     reason,
+		Domain:     domain,
+		Details:    details,
+	}
+}
+
+// errorClass classifies not found failures as ErrBucketNotFound or ErrObjectNotFound,
+// forbidden ones as ErrPermissionDenied, e.g. listing by a caller allowed only object reads,
+// rejected changes of a locked retention policy as ErrRetentionPolicyLocked.
+// Object calls report a missing bucket as a missing object,
+// the bucket is looked up to tell them apart, only on the failure path.
+func (op *operation) errorClass(err error) error {
+	if isRetentionLocked(err) {
+		return ErrRetentionPolicyLocked
+	}
+	if isPermissionDenied(err) {
+		return ErrPermissionDenied
+	}
+	if stderrors.Is(err, storage.ErrBucketNotExist) {
+		return ErrBucketNotFound
+	}
+	if !isNotFound(err) {
+		return nil
+	}
+	if op.bucketMissing() {
+		return ErrBucketNotFound
+	}
+	return ErrObjectNotFound
+}
+
+// bucketMissing reports whether operation's bucket is known not to exist
+func (op *operation) bucketMissing() bool {
+	if op.bucketListed || op.cs == nil || op.cs.client == nil || op.bucket == "" {
+		return false
+	}
+	_, err := op.cs.client.Bucket(op.bucket).Attrs(op.ctx)
+	return stderrors.Is(err, storage.ErrBucketNotExist)
+}
+
+// fieldsLogger adds given fields to every log entry
+type fieldsLogger struct {
+	logger.AppLogger
+	fields []zapcore.Field
+}
+
+func (l *fieldsLogger) with(fields []zapcore.Field) []zapcore.Field {
+	return append(append(make([]zapcore.Field, 0, len(l.fields)+len(fields)), l.fields...), fields...)
+}
+
+func (l *fieldsLogger) Info(msg string, fields ...zapcore.Field) {
+	l.AppLogger.Info(msg, l.with(fields)...)
+}
+
+func (l *fieldsLogger) Error(msg string, fields ...zapcore.Field) {
+	l.AppLogger.Error(msg, l.with(fields)...)
+}
+
+func (l *fieldsLogger) Debug(msg string, fields ...zapcore.Field) {
+	l.AppLogger.Debug(msg, l.with(fields)...)
+}
+
+func (l *fieldsLogger) Fatal(msg string, fields ...zapcore.Field) {
+	l.AppLogger.Fatal(msg, l.with(fields)...)
+}
@@ -0,0 +1,132 @@
+package cloudstorage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+type logEntry struct {
+	level  string
+	msg    string
+	fields map[string]interface{}
+}
+
+// recordingLogger records log entries for assertions
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (l *recordingLogger) record(level, msg string, fields []zapcore.Field) {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, logEntry{level: level, msg: msg, fields: enc.Fields})
+}
+
+func (l *recordingLogger) Info(msg string, fields ...zapcore.Field)  { l.record("info", msg, fields) }
+func (l *recordingLogger) Error(msg string, fields ...zapcore.Field) { l.record("error", msg, fields) }
+func (l *recordingLogger) Debug(msg string, fields ...zapcore.Field) { l.record("debug", msg, fields) }
+func (l *recordingLogger) Fatal(msg string, fields ...zapcore.Field) { l.record("fatal", msg, fields) }
+
+func (l *recordingLogger) all() []logEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]logEntry{}, l.entries...)
+}
+
+func TestOperationRequestID(t *testing.T) {
+	rl := &recordingLogger{}
+	cs := &cloudStorageClient{logger: rl}
+
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	// generated when not supplied
+	op := cs.startOperation(context.Background(), "UploadFile", cfr)
+	require.NotEmpty(t, op.requestID)
+	require.Equal(t, "path/file.json", op.object)
+
+	// context value when supplied
+	ctx := WithRequestID(context.Background(), "ctx-id")
+	op = cs.startOperation(ctx, "UploadFile", cfr)
+	require.Equal(t, "ctx-id", op.requestID)
+
+	// request option wins over context value
+	cfr, err = NewCloudFileRequest("bucket", "file.json", "path", 0, WithFileRequestID("cfr-id"))
+	require.NoError(t, err)
+	op = cs.startOperation(ctx, "UploadFile", cfr)
+	require.Equal(t, "cfr-id", op.requestID)
+
+	op.logger.Debug("test entry")
+	entries := rl.all()
+	require.Equal(t, 1, len(entries))
+	require.Equal(t, "cfr-id", entries[0].fields["requestId"])
+	require.Equal(t, "UploadFile", entries[0].fields["op"])
+
+	cause := errors.New("cause")
+	err = op.wrapError(cause, "error uploading file %s", op.object)
+	var se StorageError
+	require.True(t, errors.As(err, &se))
+	require.Equal(t, "cfr-id", se.RequestID)
+	require.Equal(t, "bucket", se.Bucket)
+	require.Equal(t, "path/file.json", se.Object)
+	require.Equal(t, "error uploading file path/file.json", se.Error())
+	require.True(t, errors.Is(err, cause))
+}
+
+func TestOperationSlowLog(t *testing.T) {
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	slowLogs := func(rl *recordingLogger) []logEntry {
+		entries := []logEntry{}
+		for _, e := range rl.all() {
+			if e.msg == "slow storage operation" {
+				entries = append(entries, e)
+			}
+		}
+		return entries
+	}
+
+	// zero threshold disables
+	rl := &recordingLogger{}
+	cs := &cloudStorageClient{logger: rl}
+	op := cs.startOperation(context.Background(), "DownloadFile", cfr)
+	op.start = op.start.Add(-time.Hour)
+	op.finish()
+	require.Empty(t, slowLogs(rl))
+
+	// fast operation isn't logged
+	cs.config.SlowOpThreshold = time.Minute
+	op = cs.startOperation(context.Background(), "DownloadFile", cfr)
+	op.finish()
+	require.Empty(t, slowLogs(rl))
+
+	// slow operation logs op, object & bytes moved
+	op = cs.startOperation(context.Background(), "DownloadFile", cfr)
+	op.start = op.start.Add(-2 * time.Minute)
+	var buf strings.Builder
+	_, err = io.Copy(&countingWriter{w: &buf, op: op}, &countingReader{r: strings.NewReader("hello"), op: op})
+	require.NoError(t, err)
+	op.finish()
+
+	entries := slowLogs(rl)
+	require.Len(t, entries, 1)
+	require.Equal(t, "info", entries[0].level)
+	require.Equal(t, "DownloadFile", entries[0].fields["op"])
+	require.Equal(t, "path/file.json", entries[0].fields["filepath"])
+	require.Equal(t, int64(10), entries[0].fields["bytes"])
+	require.GreaterOrEqual(t, entries[0].fields["elapsed"], 2*time.Minute)
+}
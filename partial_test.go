@@ -0,0 +1,109 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errCut = errors.New("device full")
+
+// cutWriter accepts the first n bytes written, at any offset, failing every write past them
+type cutWriter struct {
+	mu sync.Mutex
+	n  int64
+}
+
+func (cw *cutWriter) accept(p []byte) (int, error) {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	if int64(len(p)) <= cw.n {
+		cw.n -= int64(len(p))
+		return len(p), nil
+	}
+	n := int(cw.n)
+	cw.n = 0
+	return n, errCut
+}
+
+func (cw *cutWriter) Write(p []byte) (int, error) {
+	return cw.accept(p)
+}
+
+func (cw *cutWriter) WriteAt(p []byte, _ int64) (int, error) {
+	return cw.accept(p)
+}
+
+// cutReader yields the first n bytes of r, failing reads past them
+type cutReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *cutReader) Read(p []byte) (int, error) {
+	if cr.n <= 0 {
+		return 0, errCut
+	}
+	if int64(len(p)) > cr.n {
+		p = p[:cr.n]
+	}
+	n, err := cr.r.Read(p)
+	cr.n -= int64(n)
+	return n, err
+}
+
+func TestPartialDownloadCounts(t *testing.T) {
+	content := bytes.Repeat([]byte("partial transfer "), 64*1024)
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", content, nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+	const cut = 300*1000 + 7
+
+	n, err := cs.DownloadFile(ctx, &cutWriter{n: cut}, cfr)
+	require.ErrorIs(t, err, errCut)
+	require.Equal(t, int64(cut), n)
+
+	res, err := cs.Download(ctx, &cutWriter{n: cut}, cfr)
+	require.ErrorIs(t, err, errCut)
+	require.Equal(t, int64(cut), res.Bytes)
+	require.NotEmpty(t, res.RequestID)
+
+	n, err = cs.DownloadHead(ctx, cfr, int64(len(content)), &cutWriter{n: cut})
+	require.ErrorIs(t, err, errCut)
+	require.Equal(t, int64(cut), n)
+
+	// chunks failing midway count what they wrote
+	res, err = cs.DownloadToWriterAt(ctx, &cutWriter{n: cut}, cfr, WithDownloadChunkSize(128*1024), WithDownloadParallelism(3))
+	require.ErrorIs(t, err, errCut)
+	require.Equal(t, int64(cut), res.Bytes)
+}
+
+func TestPartialUploadCounts(t *testing.T) {
+	content := bytes.Repeat([]byte("partial transfer "), 64*1024)
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0, WithoutSpooling())
+	require.NoError(t, err)
+	cfr.chunkSize = 256 * 1024
+	const cut = 300*1000 + 7
+
+	n, err := cs.UploadFile(ctx, &cutReader{r: bytes.NewReader(content), n: cut}, cfr)
+	require.ErrorIs(t, err, errCut)
+	require.Equal(t, int64(cut), n)
+
+	res, err := cs.Upload(ctx, &cutReader{r: bytes.NewReader(content), n: cut}, cfr)
+	require.ErrorIs(t, err, errCut)
+	require.Equal(t, int64(cut), res.Bytes)
+	require.NotEmpty(t, res.RequestID)
+	_, _, ok := f.get("bucket", "path/file.bin")
+	require.False(t, ok, "partial uploads aren't committed")
+}
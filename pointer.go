@@ -0,0 +1,139 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_READING_POINTER    string = "error reading cloud file pointer"
+	ERROR_PUBLISHING_POINTER string = "error publishing cloud file pointer"
+)
+
+// DEFAULT_PUBLISH_ATTEMPTS is the default number of pointer read-modify-write attempts
+const DEFAULT_PUBLISH_ATTEMPTS = 5
+
+// PointerOptions configure PublishPointer
+type PointerOptions struct {
+	// Validate, when set, is called with the current payload, nil when the pointer doesn't exist,
+	// & the new payload before every write attempt, an error stops the publish
+	Validate func(old, new []byte) error
+	// Attempts is the number of read-modify-write attempts on concurrent updates
+	Attempts int
+}
+
+// PointerOption sets pointer publish options
+type PointerOption func(o *PointerOptions)
+
+// WithPointerValidator sets the callback validating the new payload against the current one
+func WithPointerValidator(fn func(old, new []byte) error) PointerOption {
+	return func(o *PointerOptions) {
+		o.Validate = fn
+	}
+}
+
+// WithPublishAttempts sets the number of read-modify-write attempts on concurrent updates
+func WithPublishAttempts(n int) PointerOption {
+	return func(o *PointerOptions) {
+		o.Attempts = n
+	}
+}
+
+// ReadPointer returns the pointer object's payload & generation,
+// readers compare generations to detect changes
+func (cs *cloudStorageClient) ReadPointer(ctx context.Context, pointer CloudFileRequest) ([]byte, int64, error) {
+	pointer, err := cs.request(ctx, pointer)
+	if err != nil {
+		return nil, 0, err
+	}
+	if pointer.bucket == "" {
+		return nil, 0, ErrBucketNameMissing
+	}
+	if pointer.file == "" {
+		return nil, 0, ErrFileNameMissing
+	}
+	op := cs.startOperation(ctx, "ReadPointer", pointer)
+	defer op.finish()
+	ctx = op.ctx
+
+	payload, generation, err := cs.readPointer(WithRequestID(ctx, op.requestID), pointer)
+	if err != nil {
+		op.logger.Error(ERROR_READING_POINTER, zap.Error(err), zap.String("filepath", op.object))
+		return nil, 0, err
+	}
+	return payload, generation, nil
+}
+
+func (cs *cloudStorageClient) readPointer(ctx context.Context, pointer CloudFileRequest) ([]byte, int64, error) {
+	var buf bytes.Buffer
+	res, err := cs.Download(ctx, &buf, pointer)
+	if err != nil {
+		return nil, 0, err
+	}
+	// writes are conditional on the generation read, a missing one would match missing objects only
+	if err := requireAttrs(res.Attrs, AttrGeneration, "generation precondition"); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), res.Attrs.Generation, nil
+}
+
+// PublishPointer replaces the pointer object's payload, conditional on the generation read,
+// so concurrent publishers can't overwrite an update they haven't seen.
+// A publisher losing the race re-reads & re-validates, up to the configured attempts.
+func (cs *cloudStorageClient) PublishPointer(ctx context.Context, pointer CloudFileRequest, payload []byte, opts ...PointerOption) error {
+	if err := cs.mutation(); err != nil {
+		return err
+	}
+	pointer, err := cs.request(ctx, pointer)
+	if err != nil {
+		return err
+	}
+	if pointer.bucket == "" {
+		return ErrBucketNameMissing
+	}
+	if pointer.file == "" {
+		return ErrFileNameMissing
+	}
+	pOpts := PointerOptions{Attempts: DEFAULT_PUBLISH_ATTEMPTS}
+	for _, opt := range opts {
+		opt(&pOpts)
+	}
+	op := cs.startOperation(ctx, "PublishPointer", pointer)
+	defer op.finish()
+	ctx = op.ctx
+	ctx = WithRequestID(ctx, op.requestID)
+
+	for attempt := 1; ; attempt++ {
+		old, generation, err := cs.readPointer(ctx, pointer)
+		if err != nil {
+			if !isNotFound(err) {
+				op.logger.Error(ERROR_READING_POINTER, zap.Error(err), zap.String("filepath", op.object))
+				return err
+			}
+			// first publish, pointer must still be missing on write
+			old, generation = nil, 0
+		}
+		if pOpts.Validate != nil {
+			if err := pOpts.Validate(old, payload); err != nil {
+				op.logger.Error(ERROR_PUBLISHING_POINTER, zap.Error(err), zap.String("filepath", op.object), zap.Int64("generation", generation))
+				return op.wrapError(err, "%s %s", ERROR_PUBLISHING_POINTER, op.object)
+			}
+		}
+
+		cfr := pointer
+		WithIfGenerationMatch(generation)(&cfr)
+		res, err := cs.Upload(ctx, bytes.NewReader(payload), cfr)
+		if err == nil {
+			op.generation = res.Attrs.Generation
+			op.logger.Debug("cloud file pointer published", zap.String("filepath", op.object), zap.Int64("generation", res.Attrs.Generation), zap.Int("attempt", attempt))
+			return nil
+		}
+		if !isPreconditionFailed(err) || attempt >= pOpts.Attempts {
+			op.logger.Error(ERROR_PUBLISHING_POINTER, zap.Error(err), zap.String("filepath", op.object), zap.Int("attempt", attempt))
+			return op.wrapError(err, "%s %s", ERROR_PUBLISHING_POINTER, op.object)
+		}
+		op.logger.Info("cloud file pointer changed, retrying publish", zap.String("filepath", op.object), zap.Int("attempt", attempt))
+	}
+}
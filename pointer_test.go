@@ -0,0 +1,129 @@
+package cloudstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errStaleVersion = errors.New("stale pointer version")
+
+// monotonic rejects payloads whose version isn't greater than the current one
+func monotonic(old, new []byte) error {
+	if old == nil {
+		return nil
+	}
+	o, _ := strconv.Atoi(string(old))
+	n, _ := strconv.Atoi(string(new))
+	if n <= o {
+		return errStaleVersion
+	}
+	return nil
+}
+
+func TestPublishPointer(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	pointer, err := NewCloudFileRequest("bucket", "latest.json", "datasets", 0)
+	require.NoError(t, err)
+
+	_, _, err = cs.ReadPointer(context.Background(), pointer)
+	require.ErrorIs(t, err, ErrObjectNotFound)
+
+	require.NoError(t, cs.PublishPointer(context.Background(), pointer, []byte("1"), WithPointerValidator(monotonic)))
+	payload, gen1, err := cs.ReadPointer(context.Background(), pointer)
+	require.NoError(t, err)
+	require.Equal(t, "1", string(payload))
+
+	require.NoError(t, cs.PublishPointer(context.Background(), pointer, []byte("2"), WithPointerValidator(monotonic)))
+	payload, gen2, err := cs.ReadPointer(context.Background(), pointer)
+	require.NoError(t, err)
+	require.Equal(t, "2", string(payload))
+	require.NotEqual(t, gen1, gen2)
+
+	// validation failure isn't retried & leaves the pointer unchanged
+	err = cs.PublishPointer(context.Background(), pointer, []byte("1"), WithPointerValidator(monotonic))
+	require.ErrorIs(t, err, errStaleVersion)
+	_, gen, err := cs.ReadPointer(context.Background(), pointer)
+	require.NoError(t, err)
+	require.Equal(t, gen2, gen)
+
+	_, _, err = cs.ReadPointer(context.Background(), CloudFileRequest{bucket: "bucket"})
+	require.Equal(t, ErrFileNameMissing, err)
+}
+
+func TestPublishPointerAttemptsExhausted(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "datasets/latest.json", []byte("1"), nil)
+	// every pointer write races with another publisher
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodPost {
+			f.put("bucket", "datasets/latest.json", []byte("1"), nil)
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	pointer, err := NewCloudFileRequest("bucket", "latest.json", "datasets", 0)
+	require.NoError(t, err)
+
+	err = cs.PublishPointer(context.Background(), pointer, []byte("2"), WithPublishAttempts(3))
+	require.Error(t, err)
+	require.True(t, isPreconditionFailed(err))
+
+	uploads := 0
+	for _, e := range cs.logger.(*recordingLogger).all() {
+		if e.msg == "cloud file pointer changed, retrying publish" {
+			uploads++
+		}
+	}
+	require.Equal(t, 2, uploads)
+}
+
+func TestPublishPointerConcurrent(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	pointer, err := NewCloudFileRequest("bucket", "latest.json", "datasets", 0)
+	require.NoError(t, err)
+
+	const publishers = 8
+	for round := 1; round <= 5; round++ {
+		payload := []byte(fmt.Sprint(round))
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		winners, stale := 0, 0
+		for i := 0; i < publishers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := cs.PublishPointer(context.Background(), pointer, payload, WithPointerValidator(monotonic), WithPublishAttempts(publishers))
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					winners++
+				case errors.Is(err, errStaleVersion):
+					stale++
+				default:
+					t.Errorf("round %d: %v", round, err)
+				}
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, 1, winners, "round %d", round)
+		require.Equal(t, publishers-1, stale, "round %d", round)
+
+		got, _, err := cs.ReadPointer(context.Background(), pointer)
+		require.NoError(t, err)
+		require.Equal(t, payload, got)
+	}
+	// one write per round, no lost updates
+	_, attrs, ok := f.get("bucket", "datasets/latest.json")
+	require.True(t, ok)
+	require.Equal(t, int64(5), attrs.Generation)
+}
@@ -0,0 +1,203 @@
+package cloudstorage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/comfforts/errors"
+)
+
+const (
+	ERROR_POLICY_VIOLATION string = "upload policy violation"
+)
+
+var (
+	ErrPolicyViolation = errors.NewAppError(ERROR_POLICY_VIOLATION)
+)
+
+// sniffLen is the number of leading bytes used for content type detection
+const sniffLen = 512
+
+// PolicyRule names an upload policy rule
+type PolicyRule string
+
+const (
+	PolicyMaxObjectSize       PolicyRule = "max_object_size"
+	PolicyAllowedSuffixes     PolicyRule = "allowed_suffixes"
+	PolicyDeniedSuffixes      PolicyRule = "denied_suffixes"
+	PolicyAllowedContentTypes PolicyRule = "allowed_content_types"
+	PolicyDeniedContentTypes  PolicyRule = "denied_content_types"
+	PolicyRequiredMetadata    PolicyRule = "required_metadata"
+	PolicyOverride            PolicyRule = "override"
+)
+
+// PolicyError reports the violated upload policy rule, unwraps to ErrPolicyViolation
+type PolicyError struct {
+	Rule   PolicyRule
+	Detail string
+}
+
+func (e PolicyError) Error() string {
+	return fmt.Sprintf("%s: %s, %s", ERROR_POLICY_VIOLATION, e.Rule, e.Detail)
+}
+
+// Unwrap returns the sentinel error
+func (e PolicyError) Unwrap() error {
+	return ErrPolicyViolation
+}
+
+// UploadPolicy are upload guardrails evaluated before any bytes are written
+type UploadPolicy struct {
+	// MaxObjectSize is the upload size limit in bytes, zero for no limit
+	MaxObjectSize int64 `json:"max_object_size"`
+	// AllowedSuffixes, when set, are the only accepted object name suffixes, e.g. .csv
+	AllowedSuffixes []string `json:"allowed_suffixes"`
+	// DeniedSuffixes are rejected object name suffixes
+	DeniedSuffixes []string `json:"denied_suffixes"`
+	// AllowedContentTypes, when set, are the only accepted content type prefixes, e.g. image/
+	AllowedContentTypes []string `json:"allowed_content_types"`
+	// DeniedContentTypes are rejected content type prefixes
+	DeniedContentTypes []string `json:"denied_content_types"`
+	// RequiredMetadata are metadata keys every upload must set
+	RequiredMetadata []string `json:"required_metadata"`
+	// AllowOverride permits requests to replace the client policy with WithUploadPolicy
+	AllowOverride bool `json:"allow_override"`
+}
+
+// WithUploadPolicy sets request's upload policy,
+// replaces client policy only when the client policy allows override
+func WithUploadPolicy(policy *UploadPolicy) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.policy = policy
+	}
+}
+
+// uploadPolicy returns the policy in effect for given request, nil when none
+func (cs *cloudStorageClient) uploadPolicy(cfr CloudFileRequest) (*UploadPolicy, error) {
+	clientPolicy := cs.config.UploadPolicy
+	if cfr.policy == nil {
+		return clientPolicy, nil
+	}
+	if clientPolicy != nil && !clientPolicy.AllowOverride {
+		return nil, PolicyError{Rule: PolicyOverride, Detail: "client policy doesn't allow request override"}
+	}
+	return cfr.policy, nil
+}
+
+func hasSuffixFold(name string, suffixes []string) bool {
+	name = strings.ToLower(name)
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefixFold(contentType string, prefixes []string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, p := range prefixes {
+		if strings.HasPrefix(contentType, strings.ToLower(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRequest evaluates name, metadata & declared size rules
+func (p *UploadPolicy) checkRequest(name string, metadata map[string]string, size int64) error {
+	if p.MaxObjectSize > 0 && size > p.MaxObjectSize {
+		return PolicyError{Rule: PolicyMaxObjectSize, Detail: fmt.Sprintf("declared size %d exceeds %d", size, p.MaxObjectSize)}
+	}
+	if hasSuffixFold(name, p.DeniedSuffixes) {
+		return PolicyError{Rule: PolicyDeniedSuffixes, Detail: fmt.Sprintf("name %s has denied suffix", name)}
+	}
+	if len(p.AllowedSuffixes) > 0 && !hasSuffixFold(name, p.AllowedSuffixes) {
+		return PolicyError{Rule: PolicyAllowedSuffixes, Detail: fmt.Sprintf("name %s has no allowed suffix", name)}
+	}
+	for _, key := range p.RequiredMetadata {
+		if _, ok := metadata[key]; !ok {
+			return PolicyError{Rule: PolicyRequiredMetadata, Detail: fmt.Sprintf("metadata %s missing", key)}
+		}
+	}
+	return nil
+}
+
+// checkContentType evaluates content type rules
+func (p *UploadPolicy) checkContentType(contentType string) error {
+	if hasPrefixFold(contentType, p.DeniedContentTypes) {
+		return PolicyError{Rule: PolicyDeniedContentTypes, Detail: fmt.Sprintf("content type %s denied", contentType)}
+	}
+	if len(p.AllowedContentTypes) > 0 && !hasPrefixFold(contentType, p.AllowedContentTypes) {
+		return PolicyError{Rule: PolicyAllowedContentTypes, Detail: fmt.Sprintf("content type %s not allowed", contentType)}
+	}
+	return nil
+}
+
+func (p *UploadPolicy) hasContentTypeRules() bool {
+	return len(p.AllowedContentTypes) > 0 || len(p.DeniedContentTypes) > 0
+}
+
+// suspectContentType reports whether declared content type can't be trusted for policy checks
+func suspectContentType(contentType string) bool {
+	ct := strings.ToLower(strings.TrimSpace(contentType))
+	return ct == "" || strings.HasPrefix(ct, "application/octet-stream")
+}
+
+// sniffContentType detects reader's content type from its leading bytes,
+// returns a reader replaying the sniffed bytes
+func sniffContentType(r io.Reader) (string, io.Reader, error) {
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", nil, err
+	}
+	return http.DetectContentType(head), br, nil
+}
+
+// maxSizeReader fails reads once the policy's size limit is exceeded
+type maxSizeReader struct {
+	r   io.Reader
+	max int64
+	n   int64
+}
+
+func (mr *maxSizeReader) Read(p []byte) (int, error) {
+	if limit := mr.max - mr.n + 1; int64(len(p)) > limit {
+		p = p[:limit]
+	}
+	n, err := mr.r.Read(p)
+	mr.n += int64(n)
+	if mr.n > mr.max {
+		return n - int(mr.n-mr.max), PolicyError{Rule: PolicyMaxObjectSize, Detail: fmt.Sprintf("upload exceeds %d bytes", mr.max)}
+	}
+	return n, err
+}
+
+// applyUploadPolicy evaluates policy for given upload request,
+// returns the reader to upload & the detected content type when sniffed
+func applyUploadPolicy(p *UploadPolicy, cfr CloudFileRequest, name string, r io.Reader) (io.Reader, string, error) {
+	if err := p.checkRequest(name, cfr.metadata, cfr.size); err != nil {
+		return nil, "", err
+	}
+	contentType := cfr.contentType
+	if p.hasContentTypeRules() {
+		if suspectContentType(contentType) {
+			sniffed, sr, err := sniffContentType(r)
+			if err != nil {
+				return nil, "", err
+			}
+			contentType, r = sniffed, sr
+		}
+		if err := p.checkContentType(contentType); err != nil {
+			return nil, "", err
+		}
+	}
+	if p.MaxObjectSize > 0 {
+		r = &maxSizeReader{r: r, max: p.MaxObjectSize}
+	}
+	return r, contentType, nil
+}
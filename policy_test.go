@@ -0,0 +1,104 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var pngHeader = []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR")
+
+func TestApplyUploadPolicy(t *testing.T) {
+	policy := &UploadPolicy{
+		MaxObjectSize:       64,
+		AllowedSuffixes:     []string{".png", ".csv"},
+		DeniedSuffixes:      []string{".exe.png"},
+		AllowedContentTypes: []string{"image/", "text/"},
+		DeniedContentTypes:  []string{"image/gif"},
+		RequiredMetadata:    []string{"tenant"},
+	}
+	tenant := map[string]string{"tenant": "acme"}
+
+	for scenario, tc := range map[string]struct {
+		name     string
+		cfr      CloudFileRequest
+		data     []byte
+		wantRule PolicyRule
+		wantType string
+	}{
+		"allowed upload, sniffed type": {
+			name: "img/a.PNG", cfr: CloudFileRequest{metadata: tenant}, data: pngHeader, wantType: "image/png",
+		},
+		"declared type trusted": {
+			name: "a.csv", cfr: CloudFileRequest{metadata: tenant, contentType: "text/csv"}, data: pngHeader, wantType: "text/csv",
+		},
+		"declared size too large": {
+			name: "a.png", cfr: CloudFileRequest{metadata: tenant, size: 65}, wantRule: PolicyMaxObjectSize,
+		},
+		"denied suffix": {
+			name: "a.exe.png", cfr: CloudFileRequest{metadata: tenant}, wantRule: PolicyDeniedSuffixes,
+		},
+		"suffix not allowed": {
+			name: "a.txt", cfr: CloudFileRequest{metadata: tenant}, wantRule: PolicyAllowedSuffixes,
+		},
+		"metadata missing": {
+			name: "a.png", wantRule: PolicyRequiredMetadata,
+		},
+		"suspect type sniffed & denied": {
+			name: "a.png", cfr: CloudFileRequest{metadata: tenant, contentType: "application/octet-stream"}, data: []byte("GIF89a..."), wantRule: PolicyDeniedContentTypes,
+		},
+		"content type not allowed": {
+			name: "a.png", cfr: CloudFileRequest{metadata: tenant, contentType: "application/pdf"}, wantRule: PolicyAllowedContentTypes,
+		},
+	} {
+		t.Run(scenario, func(t *testing.T) {
+			r, contentType, err := applyUploadPolicy(policy, tc.cfr, tc.name, bytes.NewReader(tc.data))
+			if tc.wantRule != "" {
+				var pErr PolicyError
+				require.True(t, errors.As(err, &pErr))
+				require.Equal(t, tc.wantRule, pErr.Rule)
+				require.True(t, errors.Is(err, ErrPolicyViolation))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantType, contentType)
+			// sniffed bytes are replayed
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, tc.data, got)
+		})
+	}
+}
+
+func TestUploadPolicyStreamLimit(t *testing.T) {
+	policy := &UploadPolicy{MaxObjectSize: 16}
+	r, _, err := applyUploadPolicy(policy, CloudFileRequest{}, "a.bin", bytes.NewReader(make([]byte, 32)))
+	require.NoError(t, err)
+	n, err := io.Copy(io.Discard, r)
+	require.Equal(t, int64(16), n)
+	var pErr PolicyError
+	require.True(t, errors.As(err, &pErr))
+	require.Equal(t, PolicyMaxObjectSize, pErr.Rule)
+}
+
+func TestUploadPolicyOverride(t *testing.T) {
+	override := &UploadPolicy{MaxObjectSize: 1}
+
+	cs := &cloudStorageClient{config: CloudStorageClientConfig{UploadPolicy: &UploadPolicy{}}}
+	_, err := cs.uploadPolicy(CloudFileRequest{policy: override})
+	var pErr PolicyError
+	require.True(t, errors.As(err, &pErr))
+	require.Equal(t, PolicyOverride, pErr.Rule)
+
+	cs.config.UploadPolicy.AllowOverride = true
+	p, err := cs.uploadPolicy(CloudFileRequest{policy: override})
+	require.NoError(t, err)
+	require.Equal(t, override, p)
+
+	p, err = cs.uploadPolicy(CloudFileRequest{})
+	require.NoError(t, err)
+	require.Equal(t, cs.config.UploadPolicy, p)
+}
@@ -0,0 +1,94 @@
+package cloudstorage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// untouchedReader fails the test when read
+type untouchedReader struct {
+	t *testing.T
+}
+
+func (r untouchedReader) Read(p []byte) (int, error) {
+	r.t.Error("caller's reader read by a doomed upload")
+	return 0, errors.New("unexpected read")
+}
+
+// failLookups fails the fake's object metadata lookups with given status, counts uploads
+func failLookups(f *fakeGCS, code int) *int32 {
+	var uploads int32
+	f.fail = func(r *http.Request) int {
+		if strings.HasPrefix(r.URL.Path, "/upload/") {
+			atomic.AddInt32(&uploads, 1)
+		}
+		if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/storage/v1/b/bucket/o/") {
+			return code
+		}
+		return 0
+	}
+	return &uploads
+}
+
+func TestUploadStrictPreflight(t *testing.T) {
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	t.Run("permission denied", func(t *testing.T) {
+		f := newFakeGCS()
+		uploads := failLookups(f, http.StatusForbidden)
+		cs := newFakeClient(t, f)
+
+		_, err := cs.Upload(context.Background(), untouchedReader{t}, cfr)
+		require.ErrorIs(t, err, ErrPermissionDenied)
+		require.Contains(t, err.Error(), ERROR_UPLOAD_PREFLIGHT)
+		require.Equal(t, int32(0), atomic.LoadInt32(uploads))
+		_, _, ok := f.get("bucket", "path/file.bin")
+		require.False(t, ok)
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		// every connection is dropped without a response
+		cs := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+				conn.Close()
+			}
+		}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		_, err := cs.Upload(ctx, untouchedReader{t}, cfr)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), ERROR_UPLOAD_PREFLIGHT)
+	})
+
+	t.Run("not found proceeds", func(t *testing.T) {
+		f := newFakeGCS()
+		cs := newFakeClient(t, f)
+		res, err := cs.Upload(context.Background(), strings.NewReader("content"), cfr)
+		require.NoError(t, err)
+		require.Equal(t, int64(len("content")), res.Bytes)
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		f := newFakeGCS()
+		failLookups(f, http.StatusForbidden)
+		cs := newFakeClient(t, f)
+		strict := false
+		cs.config.StrictPreflight = &strict
+
+		res, err := cs.Upload(context.Background(), strings.NewReader("content"), cfr)
+		require.NoError(t, err)
+		require.Equal(t, int64(len("content")), res.Bytes)
+		data, _, ok := f.get("bucket", "path/file.bin")
+		require.True(t, ok)
+		require.Equal(t, "content", string(data))
+	})
+}
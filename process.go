@@ -0,0 +1,288 @@
+package cloudstorage
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_PROCESSING_OBJECT          string = "error processing object"
+	ERROR_PROCESS_HANDLER_REQUIRED   string = "prefix process handler missing"
+	ERROR_RECORDING_PROCESSED_OBJECT string = "error recording processed object"
+)
+
+var (
+	ErrProcessHandlerRequired = errors.NewAppError(ERROR_PROCESS_HANDLER_REQUIRED)
+)
+
+const (
+	// DEFAULT_PROCESSED_MARKER_PREFIX is the path prefix of processed object markers
+	DEFAULT_PROCESSED_MARKER_PREFIX = ".processed"
+	// DEFAULT_PROCESSED_DONE_PREFIX is the path prefix processed objects are moved under with WithProcessMoveTo
+	DEFAULT_PROCESSED_DONE_PREFIX = "done"
+	// processedGenerationKey is the marker metadata key of the processed generation
+	processedGenerationKey = "processed-generation"
+)
+
+// ProcessMode selects how ProcessPrefix records processed objects
+type ProcessMode int
+
+const (
+	// ProcessMarkers writes a marker object per processed object, recording the processed generation
+	ProcessMarkers ProcessMode = iota
+	// ProcessMoveDone moves processed objects under the done prefix
+	ProcessMoveDone
+)
+
+// ProcessHandler processes one object's content, streamed from the generation listed,
+// returned errors stop the run before the object is recorded as processed
+type ProcessHandler func(ctx context.Context, attrs *ObjectAttrs, r io.Reader) error
+
+// ProcessOptions configure ProcessPrefix
+type ProcessOptions struct {
+	// Mode is how processed objects are recorded, defaults to ProcessMarkers
+	Mode ProcessMode
+	// MarkerPrefix is the path prefix markers are written under, the processed object's name appended,
+	// defaults to DEFAULT_PROCESSED_MARKER_PREFIX
+	MarkerPrefix string
+	// DonePrefix is the path prefix processed objects are moved under, their names appended,
+	// defaults to DEFAULT_PROCESSED_DONE_PREFIX
+	DonePrefix string
+}
+
+// ProcessOption sets prefix process options
+type ProcessOption func(o *ProcessOptions)
+
+// WithProcessMarkerPrefix sets the path prefix of processed object markers
+func WithProcessMarkerPrefix(prefix string) ProcessOption {
+	return func(o *ProcessOptions) {
+		o.MarkerPrefix = prefix
+	}
+}
+
+// WithProcessMoveTo moves processed objects under given path prefix instead of writing markers
+func WithProcessMoveTo(prefix string) ProcessOption {
+	return func(o *ProcessOptions) {
+		o.Mode = ProcessMoveDone
+		o.DonePrefix = prefix
+	}
+}
+
+// processedMarker is the content of a processed object marker
+type processedMarker struct {
+	Bucket     string    `json:"bucket"`
+	Object     string    `json:"object"`
+	Generation int64     `json:"generation"`
+	Processed  time.Time `json:"processed"`
+	RequestID  string    `json:"request_id"`
+}
+
+// ProcessReport reports a prefix process run, in name order
+type ProcessReport struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix"`
+	// Listed is the number of objects listed, Skipped the ones recorded as processed by earlier runs
+	Listed    int64 `json:"listed"`
+	Skipped   int64 `json:"skipped"`
+	Processed int64 `json:"processed"`
+	Bytes     int64 `json:"bytes"`
+	// Last is the last object processed
+	Last string `json:"last,omitempty"`
+	// Failed is the object the run stopped at, its handler or record failed, Error is the failure's message
+	Failed string `json:"failed,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ProcessPrefix streams the objects under request's path to given handler one at a time, in name order,
+// & records each as processed once the handler succeeded: a marker object under the marker prefix holds
+// the processed generation, or with WithProcessMoveTo the object is moved under the done prefix.
+// Later runs skip objects recorded as processed, a replaced object's new generation is processed again.
+// The run stops at the first failed handler or record, later objects aren't processed out of order,
+// a re-run resumes at the failed object.
+//
+// Delivery is at least once: a crash between a handler's success & the object's record processes
+// the object again on the next run, handlers must tolerate duplicates. Markers are written conditional
+// on the marker seen, so concurrent or repeated runs recording one generation keep a single marker,
+// interrupted moves are completed like RenameByRule's.
+func (cs *cloudStorageClient) ProcessPrefix(ctx context.Context, cfr CloudFileRequest, handler ProcessHandler, opts ...ProcessOption) (ProcessReport, error) {
+	if err := cs.mutation(); err != nil {
+		return ProcessReport{}, err
+	}
+	cfr, err := cs.prefixRequest(ctx, cfr)
+	if err != nil {
+		return ProcessReport{}, err
+	}
+	if cfr.bucket == "" {
+		return ProcessReport{}, ErrBucketNameMissing
+	}
+	if handler == nil {
+		return ProcessReport{}, ErrProcessHandlerRequired
+	}
+	pOpts := ProcessOptions{MarkerPrefix: DEFAULT_PROCESSED_MARKER_PREFIX, DonePrefix: DEFAULT_PROCESSED_DONE_PREFIX}
+	for _, opt := range opts {
+		opt(&pOpts)
+	}
+	recordRoot := pOpts.MarkerPrefix
+	if pOpts.Mode == ProcessMoveDone {
+		recordRoot = pOpts.DonePrefix
+	}
+	if dirPrefix(recordRoot) == "" {
+		return ProcessReport{}, ErrFilePathMissing
+	}
+	op := cs.startOperation(ctx, "ProcessPrefix", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	prefix := dirPrefix(cfr.path)
+	op.object = prefix
+	// markers & moved objects are named by the processed object's name under the record root
+	recordRoot = cfr.scopePrefix + dirPrefix(recordRoot)
+	recordName := func(name string) string {
+		return recordRoot + strings.TrimPrefix(name, cfr.scopePrefix)
+	}
+
+	report := ProcessReport{Bucket: cfr.bucket, Prefix: cfr.unscopedName(prefix)}
+	bh := cs.bucketHandle(cfr)
+	var markers *processedMarkers
+	if pOpts.Mode == ProcessMarkers {
+		markers = &processedMarkers{it: bh.Objects(ctx, &storage.Query{Prefix: recordName(prefix)})}
+	}
+	fail := func(name string, err error, msg string) (ProcessReport, error) {
+		op.logger.Error(msg, zap.Error(err), zap.String("filepath", name))
+		err = op.wrapError(err, "%s %s", msg, name)
+		report.Failed, report.Error = cfr.unscopedName(name), err.Error()
+		return report, err
+	}
+
+	it := bh.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fail(prefix, err, ERROR_LISTING_OBJECTS)
+		}
+		// records under the processed prefix aren't processed
+		if isDirMarker(attrs) || strings.HasPrefix(attrs.Name, recordRoot) {
+			continue
+		}
+		report.Listed++
+		dstName := recordName(attrs.Name)
+		var marker *storage.ObjectAttrs
+		if markers != nil {
+			if marker, err = markers.find(dstName); err != nil {
+				return fail(attrs.Name, err, ERROR_LISTING_OBJECTS)
+			}
+			if marker != nil && marker.Metadata[processedGenerationKey] == strconv.FormatInt(attrs.Generation, 10) {
+				report.Skipped++
+				continue
+			}
+		}
+		if err := cs.processObject(ctx, op, cfr, attrs, handler); err != nil {
+			return fail(attrs.Name, err, ERROR_PROCESSING_OBJECT)
+		}
+		if markers != nil {
+			err = cs.writeProcessedMarker(ctx, op, bh, attrs, dstName, marker)
+		} else {
+			var taken bool
+			taken, err = cs.renameObject(ctx, bh, attrs, dstName, false)
+			cs.invalidate(cfr.bucket, attrs.Name)
+			cs.invalidate(cfr.bucket, dstName)
+			if err == nil && taken {
+				err = ErrRenameCollision
+			}
+		}
+		if err != nil {
+			return fail(attrs.Name, err, ERROR_RECORDING_PROCESSED_OBJECT)
+		}
+		report.Processed++
+		report.Bytes += attrs.Size
+		report.Last = cfr.unscopedName(attrs.Name)
+	}
+	op.bytes = report.Bytes
+	op.logger.Debug("prefix processed", zap.String("prefix", prefix), zap.Int64("processed", report.Processed), zap.Int64("skipped", report.Skipped))
+	return report, nil
+}
+
+// processObject streams the listed generation of the object to the handler
+func (cs *cloudStorageClient) processObject(ctx context.Context, op *operation, cfr CloudFileRequest, attrs *storage.ObjectAttrs, handler ProcessHandler) error {
+	obj := cs.retrying(cs.bucketHandle(cfr).Object(attrs.Name)).Generation(attrs.Generation).ReadCompressed(cfr.readCompressed)
+	var rc *storage.Reader
+	err := op.retry(ctx, func() (err error) {
+		rc, err = obj.NewReader(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return handler(ctx, cfr.logicalAttrs(newObjectAttrs(attrs)), rc)
+}
+
+// writeProcessedMarker records the processed generation, conditional on the marker seen, none when nil.
+// A failed precondition is another run's record of the object, kept.
+func (cs *cloudStorageClient) writeProcessedMarker(ctx context.Context, op *operation, bh *storage.BucketHandle, attrs *storage.ObjectAttrs, name string, seen *storage.ObjectAttrs) error {
+	cond := storage.Conditions{DoesNotExist: true}
+	if seen != nil {
+		cond = storage.Conditions{GenerationMatch: seen.Generation}
+	}
+	content, err := json.Marshal(processedMarker{
+		Bucket:     attrs.Bucket,
+		Object:     attrs.Name,
+		Generation: attrs.Generation,
+		Processed:  cs.now().UTC(),
+		RequestID:  op.requestID,
+	})
+	if err != nil {
+		return err
+	}
+	wc := bh.Object(name).If(cond).NewWriter(ctx)
+	wc.ContentType = "application/json"
+	wc.Metadata = map[string]string{processedGenerationKey: strconv.FormatInt(attrs.Generation, 10)}
+	if _, err := wc.Write(content); err != nil {
+		wc.Close()
+		return err
+	}
+	err = wc.Close()
+	cs.invalidate(attrs.Bucket, name)
+	if isPreconditionFailed(err) {
+		op.logger.Info("processed object recorded by another run", zap.String("filepath", attrs.Name), zap.String("marker", name))
+		return nil
+	}
+	return err
+}
+
+// processedMarkers walks the markers of a prefix in name order alongside the processed objects' listing
+type processedMarkers struct {
+	it   *storage.ObjectIterator
+	next *storage.ObjectAttrs
+	done bool
+}
+
+// find returns the marker of given name, nil when missing, names must be asked for in ascending order
+func (pm *processedMarkers) find(name string) (*storage.ObjectAttrs, error) {
+	for !pm.done && (pm.next == nil || pm.next.Name < name) {
+		attrs, err := pm.it.Next()
+		if err == iterator.Done {
+			pm.done, pm.next = true, nil
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		pm.next = attrs
+	}
+	if pm.next != nil && pm.next.Name == name {
+		return pm.next, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,149 @@
+package cloudstorage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHandler returns a process handler recording the processed names & content,
+// failing names in fail
+func recordingHandler(processed map[string]string, order *[]string, fail map[string]error) ProcessHandler {
+	return func(ctx context.Context, attrs *ObjectAttrs, r io.Reader) error {
+		if err := fail[attrs.Name]; err != nil {
+			return err
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		processed[attrs.Name] = string(data)
+		*order = append(*order, attrs.Name)
+		return nil
+	}
+}
+
+func TestProcessPrefixMarkers(t *testing.T) {
+	f := newFakeGCS()
+	for _, name := range []string{"incoming/03.csv", "incoming/01.csv", "incoming/02.csv"} {
+		f.put("bucket", "tenant-a/"+name, []byte("rows of "+name), nil)
+	}
+	f.put("bucket", "tenant-a/other/01.csv", []byte("x"), nil)
+	cs := newFakeClient(t, f)
+	ctx := tenantContext()
+	cfr := CloudFileRequest{bucket: "bucket", path: "incoming"}
+
+	processed, order := map[string]string{}, []string{}
+	failed := errors.New("malformed row")
+	report, err := cs.ProcessPrefix(ctx, cfr, recordingHandler(processed, &order, map[string]error{"incoming/02.csv": failed}))
+	require.ErrorIs(t, err, failed)
+	require.Equal(t, []string{"incoming/01.csv"}, order, "objects after the failed one aren't processed")
+	require.Equal(t, "incoming/02.csv", report.Failed)
+	require.Equal(t, "incoming/01.csv", report.Last)
+	require.Equal(t, EXIT_PARTIAL_FAILURE, ExitCode(report, err))
+
+	// the re-run resumes at the failed object, in name order
+	report, err = cs.ProcessPrefix(ctx, cfr, recordingHandler(processed, &order, nil))
+	require.NoError(t, err)
+	require.Equal(t, []string{"incoming/01.csv", "incoming/02.csv", "incoming/03.csv"}, order)
+	require.Equal(t, "rows of incoming/03.csv", processed["incoming/03.csv"])
+	require.Equal(t, ProcessReport{Bucket: "bucket", Prefix: "incoming/", Listed: 3, Skipped: 1, Processed: 2, Bytes: 46, Last: "incoming/03.csv"}, report)
+
+	data, attrs, ok := f.get("bucket", "tenant-a/.processed/incoming/02.csv")
+	require.True(t, ok)
+	_, src, _ := f.get("bucket", "tenant-a/incoming/02.csv")
+	var marker processedMarker
+	require.NoError(t, json.Unmarshal(data, &marker))
+	require.Equal(t, src.Generation, marker.Generation)
+	require.Equal(t, "tenant-a/incoming/02.csv", marker.Object)
+	require.NotEmpty(t, attrs.Metadata[processedGenerationKey])
+
+	// processed objects are skipped, replaced ones processed again
+	order = order[:0]
+	f.put("bucket", "tenant-a/incoming/02.csv", []byte("replaced"), nil)
+	report, err = cs.ProcessPrefix(ctx, cfr, recordingHandler(processed, &order, nil))
+	require.NoError(t, err)
+	require.Equal(t, []string{"incoming/02.csv"}, order)
+	require.Equal(t, int64(2), report.Skipped)
+	data, _, _ = f.get("bucket", "tenant-a/.processed/incoming/02.csv")
+	_, src, _ = f.get("bucket", "tenant-a/incoming/02.csv")
+	require.NoError(t, json.Unmarshal(data, &marker))
+	require.Equal(t, src.Generation, marker.Generation)
+
+	// whole scope runs don't process the markers
+	order = order[:0]
+	scope, err := NewBucketRequest("bucket")
+	require.NoError(t, err)
+	report, err = cs.ProcessPrefix(ctx, scope, recordingHandler(processed, &order, nil))
+	require.NoError(t, err)
+	require.Equal(t, []string{"other/01.csv"}, order)
+	require.Equal(t, int64(4), report.Listed)
+
+	_, err = cs.ProcessPrefix(ctx, cfr, nil)
+	require.Equal(t, ErrProcessHandlerRequired, err)
+}
+
+func TestProcessedMarkerDuplicates(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "incoming/01.csv", []byte("rows"), nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr := CloudFileRequest{bucket: "bucket", path: "incoming"}
+	_, src, _ := f.get("bucket", "incoming/01.csv")
+
+	// a run crashed after its handler, another run recorded the object meanwhile
+	op := cs.startOperation(ctx, "ProcessPrefix", cfr)
+	bh := cs.bucketHandle(cfr)
+	attrs, err := bh.Object("incoming/01.csv").Attrs(ctx)
+	require.NoError(t, err)
+	require.NoError(t, cs.writeProcessedMarker(ctx, op, bh, attrs, ".processed/incoming/01.csv", nil))
+	_, first, _ := f.get("bucket", ".processed/incoming/01.csv")
+	require.NoError(t, cs.writeProcessedMarker(ctx, op, bh, attrs, ".processed/incoming/01.csv", nil))
+	_, second, _ := f.get("bucket", ".processed/incoming/01.csv")
+	require.Equal(t, first.Generation, second.Generation, "the duplicate marker isn't written")
+	require.Equal(t, []string{".processed/incoming/01.csv", "incoming/01.csv"}, f.storedNames("bucket"))
+
+	processed, order := map[string]string{}, []string{}
+	report, err := cs.ProcessPrefix(ctx, cfr, recordingHandler(processed, &order, nil))
+	require.NoError(t, err)
+	require.Empty(t, order)
+	require.Equal(t, int64(1), report.Skipped)
+	require.NotZero(t, src.Generation)
+}
+
+func TestProcessPrefixMoveDone(t *testing.T) {
+	f := newFakeGCS()
+	for _, name := range []string{"incoming/01.csv", "incoming/02.csv", "incoming/03.csv"} {
+		f.put("bucket", name, []byte("rows of "+name), nil)
+	}
+	// a run crashed after moving 02's content, before deleting it
+	data, _, _ := f.get("bucket", "incoming/02.csv")
+	f.put("bucket", "archive/incoming/02.csv", data, nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr := CloudFileRequest{bucket: "bucket", path: "incoming"}
+
+	processed, order := map[string]string{}, []string{}
+	report, err := cs.ProcessPrefix(ctx, cfr, recordingHandler(processed, &order, nil), WithProcessMoveTo("archive"))
+	require.NoError(t, err)
+	// at least once, the interrupted object is processed again
+	require.Equal(t, []string{"incoming/01.csv", "incoming/02.csv", "incoming/03.csv"}, order)
+	require.Equal(t, int64(3), report.Processed)
+	require.Equal(t, []string{"archive/incoming/01.csv", "archive/incoming/02.csv", "archive/incoming/03.csv"}, f.storedNames("bucket"))
+
+	report, err = cs.ProcessPrefix(ctx, cfr, recordingHandler(processed, &order, nil), WithProcessMoveTo("archive"))
+	require.NoError(t, err)
+	require.Zero(t, report.Listed)
+
+	// done names holding other content aren't overwritten
+	f.put("bucket", "incoming/01.csv", []byte("new rows"), nil)
+	report, err = cs.ProcessPrefix(ctx, cfr, recordingHandler(processed, &order, nil), WithProcessMoveTo("archive"))
+	require.ErrorIs(t, err, ErrRenameCollision)
+	require.Equal(t, "incoming/01.csv", report.Failed)
+	data, _, _ = f.get("bucket", "archive/incoming/01.csv")
+	require.Equal(t, "rows of incoming/01.csv", string(data))
+}
@@ -0,0 +1,116 @@
+package cloudstorage
+
+import (
+	"github.com/comfforts/errors"
+)
+
+const (
+	ERROR_UNKNOWN_PROFILE string = "unknown upload profile"
+)
+
+var (
+	ErrUnknownProfile = errors.NewAppError(ERROR_UNKNOWN_PROFILE)
+)
+
+const (
+	PROFILE_IMMUTABLE_ASSET = "immutable-asset"
+	PROFILE_HTML_PAGE       = "html-page"
+	PROFILE_DOWNLOAD        = "download"
+)
+
+// ObjectAttrsTemplate are the object attributes an upload profile sets,
+// empty values are left to the request
+type ObjectAttrsTemplate struct {
+	ContentType        string            `json:"content_type"`
+	ContentEncoding    string            `json:"content_encoding"`
+	ContentLanguage    string            `json:"content_language"`
+	ContentDisposition string            `json:"content_disposition"`
+	CacheControl       string            `json:"cache_control"`
+	Metadata           map[string]string `json:"metadata"`
+}
+
+// StandardUploadProfiles returns the immutable asset, html page & download profiles,
+// a starting point for the client's UploadProfiles
+func StandardUploadProfiles() map[string]ObjectAttrsTemplate {
+	return map[string]ObjectAttrsTemplate{
+		PROFILE_IMMUTABLE_ASSET: {CacheControl: "public, max-age=31536000, immutable"},
+		PROFILE_HTML_PAGE:       {CacheControl: "no-cache"},
+		PROFILE_DOWNLOAD:        {ContentDisposition: "attachment"},
+	}
+}
+
+// WithProfile applies the client's named upload profile to uploaded object's attributes,
+// under the request's own content type, encoding, language, disposition, cache control & metadata.
+// Requests built with the client's NewFileRequest fail on unknown names, others when uploaded.
+func WithProfile(name string) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.profile = name
+	}
+}
+
+// WithContentLanguage sets the content language of uploaded object, e.g. en
+func WithContentLanguage(language string) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.contentLanguage = language
+	}
+}
+
+// WithContentDisposition sets the content disposition of uploaded object, e.g. attachment
+func WithContentDisposition(disposition string) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.contentDisposition = disposition
+	}
+}
+
+// WithCacheControl sets the cache control of uploaded object, e.g. no-cache
+func WithCacheControl(cacheControl string) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.cacheControl = cacheControl
+	}
+}
+
+// NewFileRequest returns cloud storage request like NewCloudFileRequest,
+// failing with ErrUnknownProfile when the request's profile isn't registered with the client
+func (cs *cloudStorageClient) NewFileRequest(bucketName, fileName, path string, modTime int64, opts ...CloudFileRequestOption) (CloudFileRequest, error) {
+	cfr, err := NewCloudFileRequest(bucketName, fileName, path, modTime, opts...)
+	if err != nil {
+		return CloudFileRequest{}, err
+	}
+	if _, err := cs.applyProfile(cfr); err != nil {
+		return CloudFileRequest{}, err
+	}
+	return cfr, nil
+}
+
+// applyProfile returns the request with its profile's attributes filled in,
+// request's values win, metadata is merged
+func (cs *cloudStorageClient) applyProfile(cfr CloudFileRequest) (CloudFileRequest, error) {
+	if cfr.profile == "" {
+		return cfr, nil
+	}
+	tmpl, ok := cs.config.UploadProfiles[cfr.profile]
+	if !ok {
+		return cfr, errors.WrapError(ErrUnknownProfile, "%s %q", ERROR_UNKNOWN_PROFILE, cfr.profile)
+	}
+	fill := func(value *string, profile string) {
+		if *value == "" {
+			*value = profile
+		}
+	}
+	fill(&cfr.contentType, tmpl.ContentType)
+	fill(&cfr.contentEncoding, tmpl.ContentEncoding)
+	fill(&cfr.contentLanguage, tmpl.ContentLanguage)
+	fill(&cfr.contentDisposition, tmpl.ContentDisposition)
+	fill(&cfr.cacheControl, tmpl.CacheControl)
+	if len(tmpl.Metadata) > 0 {
+		metadata := make(map[string]string, len(tmpl.Metadata)+len(cfr.metadata))
+		for k, v := range tmpl.Metadata {
+			metadata[k] = v
+		}
+		for k, v := range cfr.metadata {
+			metadata[k] = v
+		}
+		cfr.metadata = metadata
+	}
+	return cfr, nil
+}
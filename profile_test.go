@@ -0,0 +1,145 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/comfforts/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// newProfileClient returns a fake backed client with the standard profiles & a tagged html profile
+func newProfileClient(t *testing.T, f *fakeGCS) *cloudStorageClient {
+	cs := newFakeClient(t, f)
+	cs.config.UploadProfiles = StandardUploadProfiles()
+	cs.config.UploadProfiles["localized-page"] = ObjectAttrsTemplate{
+		ContentType:     "text/html",
+		ContentLanguage: "en",
+		CacheControl:    "no-cache",
+		Metadata:        map[string]string{"pipeline": "static", "tier": "web"},
+	}
+	return cs
+}
+
+func TestNewFileRequestUnknownProfile(t *testing.T) {
+	cs := newProfileClient(t, newFakeGCS())
+
+	_, err := cs.NewFileRequest("bucket", "app.js", "assets", 0, WithProfile("immutable-assets"))
+	require.Error(t, err)
+	appErr, ok := err.(errors.AppError)
+	require.True(t, ok)
+	require.Equal(t, ErrUnknownProfile, appErr.Inner)
+	require.Contains(t, err.Error(), `"immutable-assets"`)
+
+	cfr, err := cs.NewFileRequest("bucket", "app.js", "assets", 0, WithProfile(PROFILE_IMMUTABLE_ASSET))
+	require.NoError(t, err)
+	require.Equal(t, PROFILE_IMMUTABLE_ASSET, cfr.profile)
+
+	_, err = cs.NewFileRequest("", "app.js", "assets", 0)
+	require.Equal(t, ErrBucketNameMissing, err)
+}
+
+func TestUploadProfiles(t *testing.T) {
+	f := newFakeGCS()
+	cs := newProfileClient(t, f)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		opts []CloudFileRequestOption
+		want ObjectAttrs
+	}{
+		{
+			name: "app.js",
+			opts: []CloudFileRequestOption{WithProfile(PROFILE_IMMUTABLE_ASSET), WithContentType("text/javascript")},
+			want: ObjectAttrs{ContentType: "text/javascript", CacheControl: "public, max-age=31536000, immutable"},
+		},
+		{
+			name: "report.pdf",
+			opts: []CloudFileRequestOption{WithProfile(PROFILE_DOWNLOAD)},
+			want: ObjectAttrs{ContentDisposition: "attachment"},
+		},
+		{
+			// explicit values win over the profile, metadata is merged
+			name: "index.html",
+			opts: []CloudFileRequestOption{
+				WithProfile("localized-page"),
+				WithContentLanguage("de"),
+				WithCacheControl("public, max-age=60"),
+				WithMetadata(map[string]string{"tier": "edge", "build": "42"}),
+			},
+			want: ObjectAttrs{
+				ContentType:     "text/html",
+				ContentLanguage: "de",
+				CacheControl:    "public, max-age=60",
+				Metadata:        map[string]string{"pipeline": "static", "tier": "edge", "build": "42"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfr, err := cs.NewFileRequest("bucket", tc.name, "site", 0, tc.opts...)
+			require.NoError(t, err)
+			res, err := cs.Upload(ctx, strings.NewReader("content"), cfr)
+			require.NoError(t, err)
+
+			for _, attrs := range []*ObjectAttrs{res.Attrs, mustGetAttrs(t, cs, cfr)} {
+				if tc.want.ContentType != "" {
+					require.Equal(t, tc.want.ContentType, attrs.ContentType)
+				}
+				require.Equal(t, tc.want.ContentLanguage, attrs.ContentLanguage)
+				require.Equal(t, tc.want.ContentDisposition, attrs.ContentDisposition)
+				require.Equal(t, tc.want.CacheControl, attrs.CacheControl)
+				if tc.want.Metadata != nil {
+					require.Equal(t, tc.want.Metadata, attrs.Metadata)
+				}
+			}
+		})
+	}
+}
+
+func TestUploadFromReaderAtProfile(t *testing.T) {
+	f := newFakeGCS()
+	cs := newProfileClient(t, f)
+	content := bytes.Repeat([]byte("a"), 2048)
+	cfr, err := cs.NewFileRequest("bucket", "bundle.js", "assets", 0, WithProfile(PROFILE_IMMUTABLE_ASSET))
+	require.NoError(t, err)
+
+	// composed from parallel parts
+	res, err := cs.UploadFromReaderAt(context.Background(), bytes.NewReader(content), int64(len(content)), cfr, WithChunkSize(512), WithParallelUpload(1024, 2))
+	require.NoError(t, err)
+	require.Equal(t, "public, max-age=31536000, immutable", res.Attrs.CacheControl)
+}
+
+func TestUploadUnknownProfileSendsNothing(t *testing.T) {
+	f := newFakeGCS()
+	var requests int64
+	f.fail = func(r *http.Request) int {
+		atomic.AddInt64(&requests, 1)
+		return 0
+	}
+	cs := newProfileClient(t, f)
+	// built without the client, checked before the upload starts
+	cfr, err := NewCloudFileRequest("bucket", "app.js", "assets", 0, WithProfile("missing"))
+	require.NoError(t, err)
+
+	_, err = cs.Upload(context.Background(), strings.NewReader("content"), cfr)
+	require.Error(t, err)
+	appErr, ok := err.(errors.AppError)
+	require.True(t, ok)
+	require.Equal(t, ErrUnknownProfile, appErr.Inner)
+
+	_, err = cs.UploadFromReaderAt(context.Background(), strings.NewReader("content"), 7, cfr)
+	require.Error(t, err)
+	require.Equal(t, int64(0), atomic.LoadInt64(&requests))
+}
+
+func mustGetAttrs(t *testing.T, cs *cloudStorageClient, cfr CloudFileRequest) *ObjectAttrs {
+	t.Helper()
+	attrs, err := cs.GetAttrs(context.Background(), cfr)
+	require.NoError(t, err)
+	return attrs
+}
@@ -0,0 +1,250 @@
+package cloudstorage
+
+import (
+	"context"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_PUBLISHING_SET        string = "error publishing object set"
+	ERROR_PUBLISHING_MANIFEST   string = "error publishing set manifest"
+	ERROR_PUBLISH_CHECKSUM      string = "published object checksum mismatch"
+	ERROR_MISSING_UPLOAD_SOURCE string = "upload item content missing"
+)
+
+var (
+	ErrUploadSourceMissing = errors.NewAppError(ERROR_MISSING_UPLOAD_SOURCE)
+)
+
+// DEFAULT_PUBLISH_CONCURRENCY is the default number of concurrent data object uploads of a publish set
+const DEFAULT_PUBLISH_CONCURRENCY = 8
+
+// UploadItem is one object of a publish set, its content read from Reader,
+// or for the manifest returned by Content once the data objects are uploaded
+type UploadItem struct {
+	Request CloudFileRequest
+	Reader  io.Reader
+	// Content returns the manifest's content given the data objects' report, e.g. to embed their generations
+	Content func(report PublishReport) (io.Reader, error)
+}
+
+// PublishedObject is the outcome of one publish set object
+type PublishedObject struct {
+	Bucket     string `json:"bucket"`
+	Object     string `json:"object"`
+	Generation int64  `json:"generation"`
+	// Size is the stored size, the bytes sent before the upload failed when Err is set
+	Size   int64  `json:"size"`
+	CRC32C uint32 `json:"crc32c"`
+	// Err is the upload or verification error, Error is its message
+	Err   error  `json:"-"`
+	Error string `json:"error,omitempty"`
+	// Removed is set when the object was deleted by cleanup
+	Removed bool `json:"removed"`
+	// CleanupErr is the error deleting the object on cleanup, CleanupError is its message
+	CleanupErr   error  `json:"-"`
+	CleanupError string `json:"cleanup_error,omitempty"`
+}
+
+// PublishReport reports a publish set, data objects in input order
+type PublishReport struct {
+	Items    []PublishedObject `json:"items"`
+	Manifest PublishedObject   `json:"manifest"`
+	// Published is set once the manifest was written
+	Published bool `json:"published"`
+}
+
+// PublishOptions configure PublishSet
+type PublishOptions struct {
+	// Concurrency is the number of concurrent data object uploads, defaults to DEFAULT_PUBLISH_CONCURRENCY
+	Concurrency int
+	// VerifyChecksums compares the CRC32C of uploaded content with the stored object's
+	VerifyChecksums bool
+	// Cleanup deletes uploaded data objects when the set isn't published
+	Cleanup bool
+}
+
+// PublishOption sets publish set options
+type PublishOption func(o *PublishOptions)
+
+// WithPublishConcurrency sets the number of concurrent data object uploads
+func WithPublishConcurrency(n int) PublishOption {
+	return func(o *PublishOptions) {
+		o.Concurrency = n
+	}
+}
+
+// WithPublishChecksums verifies every data object's stored CRC32C against the uploaded content's,
+// content is hashed as it's read, readers are spooled
+func WithPublishChecksums() PublishOption {
+	return func(o *PublishOptions) {
+		o.VerifyChecksums = true
+	}
+}
+
+// WithPublishCleanup deletes the uploaded data objects when a data upload or the manifest write fails,
+// each delete conditional on the uploaded generation, so newer writes are kept
+func WithPublishCleanup() PublishOption {
+	return func(o *PublishOptions) {
+		o.Cleanup = true
+	}
+}
+
+// PublishSet uploads the data objects concurrently & only once all succeeded, & verified when requested,
+// uploads the manifest, so consumers never see a manifest referencing missing objects.
+// The report has every data object's generation, a failed data upload fails the set without writing the manifest.
+func (cs *cloudStorageClient) PublishSet(ctx context.Context, items []UploadItem, manifest UploadItem, opts ...PublishOption) (PublishReport, error) {
+	if err := cs.mutation(); err != nil {
+		return PublishReport{}, err
+	}
+	pOpts := PublishOptions{Concurrency: DEFAULT_PUBLISH_CONCURRENCY}
+	for _, opt := range opts {
+		opt(&pOpts)
+	}
+	if pOpts.Concurrency < 1 {
+		pOpts.Concurrency = 1
+	}
+	// requests are scoped up front, cleanup deletes the stored names
+	reqs := make([]CloudFileRequest, len(items))
+	for i, item := range append(items[:len(items):len(items)], manifest) {
+		cfr, err := cs.request(ctx, item.Request)
+		if err != nil {
+			return PublishReport{}, err
+		}
+		if cfr.bucket == "" {
+			return PublishReport{}, ErrBucketNameMissing
+		}
+		if cfr.file == "" {
+			return PublishReport{}, ErrFileNameMissing
+		}
+		if item.Reader == nil && (i < len(items) || item.Content == nil) {
+			return PublishReport{}, ErrUploadSourceMissing
+		}
+		if i < len(items) {
+			reqs[i] = cfr
+		} else {
+			manifest.Request = cfr
+		}
+	}
+	op := cs.startOperation(ctx, "PublishSet", manifest.Request)
+	defer op.finish()
+	ctx = op.ctx
+	// data objects upload concurrently, the manifest after them, as one more round
+	if err := cs.checkDeadlineBudget(ctx, op, len(items)+pOpts.Concurrency, pOpts.Concurrency); err != nil {
+		return PublishReport{}, err
+	}
+	// uploads share the set's request ID unless set on the request
+	ctx = WithRequestID(ctx, op.requestID)
+
+	report := PublishReport{Items: make([]PublishedObject, len(items))}
+	sem := make(chan struct{}, pOpts.Concurrency)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, r io.Reader) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			report.Items[i] = cs.publishObject(ctx, op, reqs[i], r, pOpts.VerifyChecksums)
+		}(i, item.Reader)
+	}
+	wg.Wait()
+
+	var err error
+	for i, item := range report.Items {
+		if item.Err != nil {
+			op.logger.Error(ERROR_PUBLISHING_SET, zap.Error(item.Err), zap.String("filepath", reqs[i].objectPath()))
+			if err == nil {
+				err = op.wrapError(item.Err, "%s %s", ERROR_PUBLISHING_SET, reqs[i].objectPath())
+			}
+		}
+	}
+	if err == nil {
+		r := manifest.Reader
+		if r == nil {
+			r, err = manifest.Content(report)
+		}
+		if err == nil {
+			report.Manifest = cs.publishObject(ctx, op, manifest.Request, r, false)
+			err = report.Manifest.Err
+		} else {
+			report.Manifest = PublishedObject{Bucket: manifest.Request.bucket, Object: manifest.Request.unscopedName(manifest.Request.objectPath()), Err: err, Error: err.Error()}
+		}
+		if err != nil {
+			op.logger.Error(ERROR_PUBLISHING_MANIFEST, zap.Error(err), zap.String("filepath", op.object))
+			err = op.wrapError(err, "%s %s", ERROR_PUBLISHING_MANIFEST, op.object)
+		}
+	}
+	if err == nil {
+		report.Published = true
+		op.logger.Debug("object set published", zap.String("manifest", op.object), zap.Int("objects", len(items)))
+		return report, nil
+	}
+	if pOpts.Cleanup {
+		// cleanup runs even if the caller's context is done, removal shouldn't be abandoned half way
+		cs.cleanupPublishSet(context.Background(), op, reqs, report.Items)
+	}
+	return report, err
+}
+
+// publishObject uploads one object of a publish set, optionally verifying the stored CRC32C
+func (cs *cloudStorageClient) publishObject(ctx context.Context, op *operation, cfr CloudFileRequest, r io.Reader, verify bool) PublishedObject {
+	obj := PublishedObject{Bucket: cfr.bucket, Object: cfr.unscopedName(cfr.objectPath())}
+	var hasher hash.Hash32
+	if verify {
+		hasher = crc32.New(crc32.MakeTable(crc32.Castagnoli))
+		r = io.TeeReader(r, hasher)
+	}
+	res, err := cs.Upload(ctx, r, cfr)
+	if err != nil {
+		obj.Size, obj.Err, obj.Error = res.Bytes, err, err.Error()
+		return obj
+	}
+	obj.Generation, obj.Size, obj.CRC32C = res.Attrs.Generation, res.Attrs.Size, res.Attrs.CRC32C
+	if verify && hasher.Sum32() != res.Attrs.CRC32C {
+		op.logger.Error(ERROR_PUBLISH_CHECKSUM, zap.String("filepath", cfr.objectPath()), zap.Uint32("want", hasher.Sum32()), zap.Uint32("got", res.Attrs.CRC32C))
+		obj.Err = errors.NewAppError("%s %s", ERROR_PUBLISH_CHECKSUM, cfr.objectPath())
+		obj.Error = obj.Err.Error()
+	}
+	return obj
+}
+
+// cleanupPublishSet deletes the uploaded data objects of an unpublished set, at their uploaded generation
+func (cs *cloudStorageClient) cleanupPublishSet(ctx context.Context, op *operation, reqs []CloudFileRequest, items []PublishedObject) {
+	sem := make(chan struct{}, DEFAULT_PUBLISH_CONCURRENCY)
+	var wg sync.WaitGroup
+	for i := range items {
+		if items[i].Generation == 0 {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			name := reqs[i].objectPath()
+			err := cs.bucketHandle(reqs[i]).Object(name).If(storage.Conditions{GenerationMatch: items[i].Generation}).Delete(ctx)
+			cs.invalidate(reqs[i].bucket, name)
+			if isPreconditionFailed(err) {
+				op.logger.Info("unpublished object replaced, kept", zap.String("filepath", name))
+				return
+			}
+			if err != nil && err != storage.ErrObjectNotExist {
+				op.logger.Error("error removing unpublished object", zap.Error(err), zap.String("filepath", name))
+				items[i].CleanupErr = op.forObject(reqs[i].bucket, name).wrapError(err, "%s %s", ERROR_DELETING_OBJECT, name)
+				items[i].CleanupError = items[i].CleanupErr.Error()
+				return
+			}
+			items[i].Removed = true
+		}(i)
+	}
+	wg.Wait()
+	op.logger.Info("unpublished objects removed", zap.String("manifest", op.object), zap.Int("objects", len(items)))
+}
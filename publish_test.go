@@ -0,0 +1,283 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/comfforts/errors"
+	"github.com/stretchr/testify/require"
+	raw "google.golang.org/api/storage/v1"
+)
+
+// publishItems returns n data items under the data path
+func publishItems(n int) []UploadItem {
+	items := make([]UploadItem, n)
+	for i := range items {
+		items[i] = UploadItem{
+			Request: CloudFileRequest{bucket: "bucket", path: "data", file: fmt.Sprintf("%02d.json", i)},
+			Reader:  strings.NewReader(fmt.Sprintf(`{"part":%d}`, i)),
+		}
+	}
+	return items
+}
+
+// recordUploads records the names of uploaded objects, failing uploads of given names with code
+func recordUploads(f *fakeGCS, code int, failing ...string) *[]string {
+	var mu sync.Mutex
+	names := []string{}
+	f.fail = func(r *http.Request) int {
+		if !strings.HasPrefix(r.URL.Path, "/upload/") {
+			return 0
+		}
+		name := r.URL.Query().Get("name")
+		mu.Lock()
+		names = append(names, name)
+		mu.Unlock()
+		for _, n := range failing {
+			if n == name {
+				return code
+			}
+		}
+		return 0
+	}
+	return &names
+}
+
+func TestPublishSet(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	uploads := recordUploads(f, 0)
+
+	manifest := UploadItem{
+		Request: CloudFileRequest{bucket: "bucket", path: "data", file: "_MANIFEST"},
+		Content: func(report PublishReport) (io.Reader, error) {
+			for _, item := range report.Items {
+				_, attrs, ok := f.get(item.Bucket, item.Object)
+				require.True(t, ok, "data objects are uploaded before the manifest")
+				require.Equal(t, attrs.Generation, item.Generation)
+			}
+			data, err := json.Marshal(report.Items)
+			return bytes.NewReader(data), err
+		},
+	}
+	report, err := cs.PublishSet(context.Background(), publishItems(5), manifest, WithPublishConcurrency(2))
+	require.NoError(t, err)
+	require.True(t, report.Published)
+	require.Len(t, report.Items, 5)
+	for i, item := range report.Items {
+		require.Equal(t, fmt.Sprintf("data/%02d.json", i), item.Object)
+		require.NotZero(t, item.Generation)
+		require.Equal(t, int64(len(fmt.Sprintf(`{"part":%d}`, i))), item.Size)
+	}
+	require.Equal(t, "data/_MANIFEST", report.Manifest.Object)
+	require.NotZero(t, report.Manifest.Generation)
+	require.Len(t, *uploads, 6)
+	require.Equal(t, "data/_MANIFEST", (*uploads)[5])
+
+	data, _, ok := f.get("bucket", "data/_MANIFEST")
+	require.True(t, ok)
+	var listed []PublishedObject
+	require.NoError(t, json.Unmarshal(data, &listed))
+	require.Equal(t, report.Items[3].Generation, listed[3].Generation)
+}
+
+func TestPublishSetDataFailure(t *testing.T) {
+	manifest := UploadItem{
+		Request: CloudFileRequest{bucket: "bucket", path: "data", file: "_MANIFEST"},
+		Reader:  strings.NewReader("{}"),
+	}
+
+	t.Run("kept", func(t *testing.T) {
+		f := newFakeGCS()
+		cs := newFakeClient(t, f)
+		uploads := recordUploads(f, http.StatusForbidden, "data/02.json")
+
+		report, err := cs.PublishSet(context.Background(), publishItems(4), manifest)
+		require.ErrorIs(t, err, ErrPermissionDenied)
+		require.Contains(t, err.Error(), ERROR_PUBLISHING_SET)
+		require.False(t, report.Published)
+		require.NotContains(t, *uploads, "data/_MANIFEST")
+		require.Error(t, report.Items[2].Err)
+		require.Zero(t, report.Items[2].Generation)
+		for _, i := range []int{0, 1, 3} {
+			require.NoError(t, report.Items[i].Err)
+			require.False(t, report.Items[i].Removed)
+			_, _, ok := f.get("bucket", report.Items[i].Object)
+			require.True(t, ok, "objects are kept without cleanup")
+		}
+	})
+
+	t.Run("cleanup", func(t *testing.T) {
+		f := newFakeGCS()
+		cs := newFakeClient(t, f)
+		recordUploads(f, http.StatusForbidden, "data/02.json")
+
+		report, err := cs.PublishSet(context.Background(), publishItems(4), manifest, WithPublishCleanup())
+		require.ErrorIs(t, err, ErrPermissionDenied)
+		require.False(t, report.Items[2].Removed, "failed uploads have nothing to remove")
+		for _, i := range []int{0, 1, 3} {
+			require.True(t, report.Items[i].Removed)
+			require.NoError(t, report.Items[i].CleanupErr)
+		}
+		f.mu.Lock()
+		require.Empty(t, f.objects)
+		f.mu.Unlock()
+	})
+}
+
+func TestPublishSetManifestFailure(t *testing.T) {
+	t.Run("upload", func(t *testing.T) {
+		f := newFakeGCS()
+		cs := newFakeClient(t, f)
+		recordUploads(f, http.StatusForbidden, "data/_MANIFEST")
+
+		manifest := UploadItem{
+			Request: CloudFileRequest{bucket: "bucket", path: "data", file: "_MANIFEST"},
+			Reader:  strings.NewReader("{}"),
+		}
+		report, err := cs.PublishSet(context.Background(), publishItems(3), manifest, WithPublishCleanup())
+		require.ErrorIs(t, err, ErrPermissionDenied)
+		require.Contains(t, err.Error(), ERROR_PUBLISHING_MANIFEST)
+		require.False(t, report.Published)
+		require.Error(t, report.Manifest.Err)
+		for _, item := range report.Items {
+			require.NotZero(t, item.Generation, "generations are reported for removed objects")
+			require.True(t, item.Removed)
+		}
+		f.mu.Lock()
+		require.Empty(t, f.objects)
+		f.mu.Unlock()
+	})
+
+	t.Run("content", func(t *testing.T) {
+		f := newFakeGCS()
+		cs := newFakeClient(t, f)
+		uploads := recordUploads(f, 0)
+
+		manifest := UploadItem{
+			Request: CloudFileRequest{bucket: "bucket", path: "data", file: "_MANIFEST"},
+			Content: func(PublishReport) (io.Reader, error) {
+				return nil, errors.NewAppError("manifest unavailable")
+			},
+		}
+		report, err := cs.PublishSet(context.Background(), publishItems(3), manifest)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), ERROR_PUBLISHING_MANIFEST)
+		require.Equal(t, "data/_MANIFEST", report.Manifest.Object)
+		require.Len(t, *uploads, 3)
+		for _, item := range report.Items {
+			require.False(t, item.Removed)
+			_, _, ok := f.get("bucket", item.Object)
+			require.True(t, ok, "objects are kept without cleanup")
+		}
+	})
+}
+
+func TestPublishSetCleanupKeepsReplaced(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+
+	// another writer replaces an object before the manifest fails
+	manifest := UploadItem{
+		Request: CloudFileRequest{bucket: "bucket", path: "data", file: "_MANIFEST"},
+		Content: func(PublishReport) (io.Reader, error) {
+			f.put("bucket", "data/01.json", []byte(`{"part":"newer"}`), nil)
+			return nil, errors.NewAppError("manifest unavailable")
+		},
+	}
+	report, err := cs.PublishSet(context.Background(), publishItems(3), manifest, WithPublishCleanup())
+	require.Error(t, err)
+	require.True(t, report.Items[0].Removed)
+	require.True(t, report.Items[2].Removed)
+	require.False(t, report.Items[1].Removed)
+	require.NoError(t, report.Items[1].CleanupErr)
+	data, _, ok := f.get("bucket", "data/01.json")
+	require.True(t, ok)
+	require.Equal(t, `{"part":"newer"}`, string(data))
+}
+
+// corruptChecksums reports a wrong CRC32C for uploads of given name
+func corruptChecksums(f *fakeGCS, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/upload/") || r.URL.Query().Get("name") != name {
+			f.ServeHTTP(w, r)
+			return
+		}
+		rec := httptest.NewRecorder()
+		f.ServeHTTP(rec, r)
+		var attrs raw.Object
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &attrs) != nil {
+			w.WriteHeader(rec.Code)
+			_, _ = w.Write(rec.Body.Bytes())
+			return
+		}
+		attrs.Crc32c = "AAAAAA=="
+		writeJSON(w, attrs)
+	})
+}
+
+func TestPublishSetChecksums(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, corruptChecksums(f, "data/01.json"))
+	manifest := UploadItem{
+		Request: CloudFileRequest{bucket: "bucket", path: "data", file: "_MANIFEST"},
+		Reader:  strings.NewReader("{}"),
+	}
+
+	report, err := cs.PublishSet(context.Background(), publishItems(3), manifest, WithPublishChecksums(), WithPublishCleanup())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ERROR_PUBLISHING_SET)
+	require.False(t, report.Published)
+	require.NoError(t, report.Items[0].Err)
+	require.NotZero(t, report.Items[0].CRC32C)
+	require.Error(t, report.Items[1].Err)
+	require.Contains(t, report.Items[1].Err.Error(), ERROR_PUBLISH_CHECKSUM)
+	// the mismatched object was stored, cleanup removes it too
+	for _, item := range report.Items {
+		require.True(t, item.Removed, item.Object)
+	}
+	_, _, ok := f.get("bucket", "data/_MANIFEST")
+	require.False(t, ok)
+
+	report, err = cs.PublishSet(context.Background(), publishItems(3)[:1], UploadItem{
+		Request: manifest.Request,
+		Reader:  strings.NewReader("{}"),
+	}, WithPublishChecksums())
+	require.NoError(t, err)
+	require.True(t, report.Published)
+}
+
+func TestPublishSetValidation(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	requests := recordRequests(f, http.MethodPost, nil)
+	manifest := UploadItem{
+		Request: CloudFileRequest{bucket: "bucket", path: "data", file: "_MANIFEST"},
+		Reader:  strings.NewReader("{}"),
+	}
+
+	items := publishItems(2)
+	items[1].Reader = nil
+	_, err := cs.PublishSet(context.Background(), items, manifest)
+	require.ErrorIs(t, err, ErrUploadSourceMissing)
+
+	_, err = cs.PublishSet(context.Background(), publishItems(2), UploadItem{Request: manifest.Request})
+	require.ErrorIs(t, err, ErrUploadSourceMissing)
+
+	items = publishItems(2)
+	items[0].Request.file = ""
+	_, err = cs.PublishSet(context.Background(), items, manifest)
+	require.ErrorIs(t, err, ErrFileNameMissing)
+
+	_, err = cs.PublishSet(context.Background(), publishItems(2), UploadItem{Request: CloudFileRequest{path: "data", file: "_MANIFEST"}, Reader: manifest.Reader})
+	require.ErrorIs(t, err, ErrBucketNameMissing)
+	require.Empty(t, *requests, "invalid sets upload nothing")
+}
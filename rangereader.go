@@ -0,0 +1,56 @@
+package cloudstorage
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_INVALID_RANGE         string = "range offset must not be negative, length must be -1 or more"
+	ERROR_RANGE_NOT_SATISFIABLE string = "range offset past the end of the cloud file"
+)
+
+var (
+	ErrInvalidRange        = errors.NewAppError(ERROR_INVALID_RANGE)
+	ErrRangeNotSatisfiable = errors.NewAppError(ERROR_RANGE_NOT_SATISFIABLE)
+)
+
+// RangeNotSatisfiableError is returned by OpenRangeReader for offsets at or past the object's end,
+// matches ErrRangeNotSatisfiable with errors.Is
+type RangeNotSatisfiableError struct {
+	Bucket string
+	Object string
+	Offset int64
+	Size   int64
+}
+
+func (e RangeNotSatisfiableError) Error() string {
+	return fmt.Sprintf("%s %s/%s: offset %d, size %d", ERROR_RANGE_NOT_SATISFIABLE, e.Bucket, e.Object, e.Offset, e.Size)
+}
+
+// Is matches ErrRangeNotSatisfiable
+func (e RangeNotSatisfiableError) Is(target error) bool {
+	return target == ErrRangeNotSatisfiable
+}
+
+// OpenRangeReader returns a live reader of length bytes of the cloud file from given offset,
+// to the end when length is -1, without buffering the range. Ranges past the end stop at the end,
+// Remaining reports the bytes left of the range actually read.
+// Missing objects match ErrObjectNotFound, offsets at or past the end fail with RangeNotSatisfiableError.
+// Raw downloads & generations are honoured as by OpenReader, the reader must be closed.
+func (cs *cloudStorageClient) OpenRangeReader(ctx context.Context, cfr CloudFileRequest, offset, length int64) (*ObjectReader, error) {
+	if offset < 0 || length < -1 {
+		return nil, ErrInvalidRange
+	}
+	return cs.openReader(ctx, "OpenRangeReader", cfr, offset, length, func(op *operation, cfr CloudFileRequest, attrs *storage.ObjectAttrs) error {
+		if offset < attrs.Size {
+			return nil
+		}
+		op.logger.Error(ERROR_RANGE_NOT_SATISFIABLE, zap.String("filepath", op.object), zap.Int64("offset", offset), zap.Int64("size", attrs.Size))
+		return RangeNotSatisfiableError{Bucket: attrs.Bucket, Object: cfr.unscopedName(attrs.Name), Offset: offset, Size: attrs.Size}
+	})
+}
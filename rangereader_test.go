@@ -0,0 +1,81 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenRangeReader(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1024)
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", content, nil)
+	cs := newFakeClient(t, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		offset, length int64
+		want           []byte
+	}{
+		{"middle", 100, 50, content[100:150]},
+		{"to end", 10000, -1, content[10000:]},
+		{"past end", 10200, 100, content[10200:]},
+		{"empty", 5, 0, []byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := cs.OpenRangeReader(ctx, cfr, tt.offset, tt.length)
+			require.NoError(t, err)
+			defer r.Close()
+			require.Equal(t, int64(len(content)), r.Size())
+			require.Equal(t, int64(len(tt.want)), r.Remaining())
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, data)
+			require.Equal(t, int64(0), r.Remaining())
+		})
+	}
+
+	_, err = cs.OpenRangeReader(ctx, cfr, -1, 10)
+	require.Equal(t, ErrInvalidRange, err)
+	_, err = cs.OpenRangeReader(ctx, cfr, 0, -2)
+	require.Equal(t, ErrInvalidRange, err)
+
+	_, err = cs.OpenRangeReader(ctx, cfr, int64(len(content)), 10)
+	var rErr RangeNotSatisfiableError
+	require.ErrorAs(t, err, &rErr)
+	require.True(t, errors.Is(err, ErrRangeNotSatisfiable))
+	require.Equal(t, RangeNotSatisfiableError{Bucket: "bucket", Object: "path/file.bin", Offset: int64(len(content)), Size: int64(len(content))}, rErr)
+
+	missing, err := NewCloudFileRequest("bucket", "missing.bin", "path", 0)
+	require.NoError(t, err)
+	_, err = cs.OpenRangeReader(ctx, missing, 0, 10)
+	require.True(t, errors.Is(err, ErrObjectNotFound))
+}
+
+func TestOpenRangeReaderScoped(t *testing.T) {
+	content := []byte("scoped range content")
+	f := newFakeGCS()
+	f.put("bucket", "tenant-a/path/file.bin", content, nil)
+	cs := newFakeClient(t, f)
+
+	r, err := cs.OpenRangeReader(tenantContext(), CloudFileRequest{file: "file.bin", path: "path"}, 7, 5)
+	require.NoError(t, err)
+	defer r.Close()
+	require.Equal(t, "path/file.bin", r.Attrs.Name)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "range", string(data))
+
+	_, err = cs.OpenRangeReader(tenantContext(), CloudFileRequest{file: "file.bin", path: "path"}, 100, -1)
+	var rErr RangeNotSatisfiableError
+	require.ErrorAs(t, err, &rErr)
+	require.Equal(t, "path/file.bin", rErr.Object)
+}
@@ -0,0 +1,209 @@
+package cloudstorage
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"go.uber.org/zap"
+)
+
+// DEFAULT_READ_AHEAD_WINDOW is the default read-ahead window size
+const DEFAULT_READ_AHEAD_WINDOW = 4 * 1024 * 1024 // 4MB
+
+// ReaderAtOptions configure NewReaderAt
+type ReaderAtOptions struct {
+	// ReadAhead is the window fetched when sequential access is detected, zero disables read-ahead
+	ReadAhead int
+}
+
+// ReaderAtOption sets reader at options
+type ReaderAtOption func(o *ReaderAtOptions)
+
+// WithReadAhead enables read-ahead with given window size, DEFAULT_READ_AHEAD_WINDOW when not positive
+func WithReadAhead(window int) ReaderAtOption {
+	return func(o *ReaderAtOptions) {
+		if window <= 0 {
+			window = DEFAULT_READ_AHEAD_WINDOW
+		}
+		o.ReadAhead = window
+	}
+}
+
+// ObjectReaderAt is an io.ReaderAt over a cloud file, every miss is a range read.
+// With read-ahead, a read starting where the previous one ended fetches a whole window
+// & following sequential reads are served from it, random reads stay direct range reads.
+// A fetch seeing a new object generation drops the window, reads served from the window
+// may lag a replaced object until the next fetch. Safe for concurrent use, must be closed.
+type ObjectReaderAt struct {
+	cs        *cloudStorageClient
+	ctx       context.Context
+	cfr       CloudFileRequest
+	op        *operation
+	readAhead int
+
+	mu         sync.Mutex
+	size       int64
+	generation int64
+	lastEnd    int64
+	window     []byte
+	windowOff  int64
+	fetches    int64
+}
+
+// NewReaderAt returns a reader at over the cloud file at request's bucket & filepath,
+// reads use given context, the reader lives until closed or the context is done
+func (cs *cloudStorageClient) NewReaderAt(ctx context.Context, cfr CloudFileRequest, opts ...ReaderAtOption) (*ObjectReaderAt, error) {
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return nil, err
+	}
+	if cfr.bucket == "" {
+		return nil, ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return nil, ErrFileNameMissing
+	}
+	rOpts := ReaderAtOptions{}
+	for _, opt := range opts {
+		opt(&rOpts)
+	}
+	op := cs.startOperation(ctx, "ReaderAt", cfr)
+	ctx = op.ctx
+
+	attrs, err := cs.fetchAttrs(ctx, op, cfr)
+	if err != nil {
+		op.logger.Error("cloud file inaccessible", zap.Error(err), zap.String("filepath", op.object))
+		defer op.finish()
+		return nil, op.wrapError(err, "cloud file inaccessible %s", op.object)
+	}
+	return &ObjectReaderAt{
+		cs:         cs,
+		ctx:        ctx,
+		cfr:        cfr,
+		op:         op,
+		readAhead:  rOpts.ReadAhead,
+		size:       attrs.Size,
+		generation: attrs.Generation,
+		lastEnd:    -1,
+	}, nil
+}
+
+// Size returns the object size seen by the last read
+func (ra *ObjectReaderAt) Size() int64 {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	return ra.size
+}
+
+// ReadAt reads len(p) bytes at given offset, returns io.EOF when fewer bytes remain
+func (ra *ObjectReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	start, hit := ra.cs.accessStart(), false
+	defer func() {
+		ra.cs.recordAccess(ra.op, off, len(p), n, start, hit, err)
+	}()
+
+	if off >= ra.size {
+		return 0, io.EOF
+	}
+	if n, ok := ra.fromWindow(p, off); ok {
+		ra.lastEnd = off + int64(n)
+		hit = true
+		return n, eof(n, len(p))
+	}
+
+	length := int64(len(p))
+	sequential := off == ra.lastEnd
+	if ra.readAhead > len(p) && sequential {
+		length = int64(ra.readAhead)
+	}
+	if rem := ra.size - off; length > rem {
+		length = rem
+	}
+	buf := make([]byte, length)
+	n, err = ra.fetch(buf, off)
+	if err != nil {
+		return 0, err
+	}
+	buf = buf[:n]
+	if length > int64(len(p)) {
+		ra.window, ra.windowOff = buf, off
+	}
+	n = copy(p, buf)
+	ra.lastEnd = off + int64(n)
+	return n, eof(n, len(p))
+}
+
+// fromWindow copies the window's bytes at given offset, when the window covers the read
+func (ra *ObjectReaderAt) fromWindow(p []byte, off int64) (int, bool) {
+	if ra.window == nil || off < ra.windowOff {
+		return 0, false
+	}
+	start := off - ra.windowOff
+	end := start + int64(len(p))
+	windowEnd := ra.windowOff + int64(len(ra.window))
+	// reads past the window are covered only when the window ends at the object's end
+	if end > int64(len(ra.window)) && windowEnd < ra.size {
+		return 0, false
+	}
+	if start >= int64(len(ra.window)) {
+		return 0, false
+	}
+	return copy(p, ra.window[start:]), true
+}
+
+// eof returns io.EOF for reads short of given length
+func eof(n, want int) error {
+	if n < want {
+		return io.EOF
+	}
+	return nil
+}
+
+// fetch range reads the object into given buffer, drops the window when the object's generation changed
+func (ra *ObjectReaderAt) fetch(buf []byte, off int64) (int, error) {
+	ra.fetches++
+	release, err := ra.op.acquireTransfer(ra.ctx)
+	if err != nil {
+		ra.op.logger.Error(ERROR_WAITING_TRANSFER_SLOT, zap.Error(err), zap.String("filepath", ra.op.object))
+		return 0, ra.op.wrapError(err, "%s %s", ERROR_WAITING_TRANSFER_SLOT, ra.op.object)
+	}
+	defer release()
+	obj := ra.cs.bucketHandle(ra.cfr).Object(ra.op.object)
+	if ra.cfr.generation != 0 {
+		obj = obj.Generation(ra.cfr.generation)
+	}
+	rc, err := ra.op.openStarted(ra.ctx, ra.cfr, func(ctx context.Context) (*storage.Reader, error) {
+		return obj.NewRangeReader(ctx, off, int64(len(buf)))
+	})
+	if err != nil {
+		ra.op.logger.Error("error reading cloud file", zap.Error(err), zap.String("filepath", ra.op.object), zap.Int64("offset", off))
+		return 0, ra.op.wrapError(err, "error reading cloud file %s", ra.op.object)
+	}
+	defer rc.Close()
+
+	if gen := rc.Attrs.Generation; gen != ra.generation {
+		ra.op.logger.Debug("cloud file generation changed, dropping read-ahead window", zap.String("filepath", ra.op.object), zap.Int64("generation", gen))
+		ra.generation, ra.size = gen, rc.Attrs.Size
+		ra.window = nil
+	}
+	n, err := io.ReadFull(&countingReader{r: rc, op: ra.op}, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		ra.op.logger.Error("error reading cloud file", zap.Error(err), zap.String("filepath", ra.op.object), zap.Int64("offset", off))
+		return 0, ra.op.wrapError(err, "error reading cloud file %s", ra.op.object)
+	}
+	return n, nil
+}
+
+// Close drops the read-ahead window & ends the reader's operation
+func (ra *ObjectReaderAt) Close() error {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	ra.window = nil
+	ra.op.logger.Debug("cloud file reader at closed", zap.String("filepath", ra.op.object), zap.Int64("fetches", ra.fetches), zap.Int64("bytes", ra.op.bytes))
+	ra.op.finish()
+	return nil
+}
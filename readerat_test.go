@@ -0,0 +1,167 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countRangeReads counts the fake's content reads
+func countRangeReads(f *fakeGCS) *int64 {
+	var reads int64
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/storage/v1/") {
+			atomic.AddInt64(&reads, 1)
+		}
+		return 0
+	}
+	return &reads
+}
+
+func readerAtContent(size int) []byte {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(content)
+	return content
+}
+
+func TestReaderAtSequentialReadAhead(t *testing.T) {
+	content := readerAtContent(1024*1024 + 100)
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", content, nil)
+	reads := countRangeReads(f)
+	cs := newFakeClient(t, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	ra, err := cs.NewReaderAt(context.Background(), cfr, WithReadAhead(256*1024))
+	require.NoError(t, err)
+	defer ra.Close()
+	require.Equal(t, int64(len(content)), ra.Size())
+
+	got := make([]byte, 0, len(content))
+	chunk := make([]byte, 64*1024)
+	var off int64
+	for {
+		n, err := ra.ReadAt(chunk, off)
+		got = append(got, chunk[:n]...)
+		off += int64(n)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	require.Equal(t, content, got)
+	// the first read has no previous read & is direct, then 4 windows, the last one clamped to the end
+	require.Equal(t, int64(1+4), atomic.LoadInt64(reads))
+}
+
+func TestReaderAtRandomAccess(t *testing.T) {
+	content := readerAtContent(512 * 1024)
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", content, nil)
+	reads := countRangeReads(f)
+	cs := newFakeClient(t, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	ra, err := cs.NewReaderAt(context.Background(), cfr, WithReadAhead(256*1024))
+	require.NoError(t, err)
+	defer ra.Close()
+
+	offsets := []int64{300 * 1024, 10 * 1024, 450 * 1024, 100 * 1024}
+	for _, off := range offsets {
+		p := make([]byte, 4096)
+		n, err := ra.ReadAt(p, off)
+		require.NoError(t, err)
+		require.Equal(t, content[off:off+int64(n)], p[:n])
+	}
+	// random reads are direct range reads of the requested length only
+	require.Equal(t, int64(len(offsets)), atomic.LoadInt64(reads))
+
+	// past the end
+	n, err := ra.ReadAt(make([]byte, 16), int64(len(content)))
+	require.Equal(t, 0, n)
+	require.Equal(t, io.EOF, err)
+	n, err = ra.ReadAt(make([]byte, 16), int64(len(content)-4))
+	require.Equal(t, 4, n)
+	require.Equal(t, io.EOF, err)
+}
+
+func TestReaderAtGenerationChange(t *testing.T) {
+	v1 := bytes.Repeat([]byte("1"), 64*1024)
+	v2 := bytes.Repeat([]byte("2"), 64*1024)
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", v1, nil)
+	cs := newFakeClient(t, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	ra, err := cs.NewReaderAt(context.Background(), cfr, WithReadAhead(16*1024))
+	require.NoError(t, err)
+	defer ra.Close()
+
+	p := make([]byte, 1024)
+	_, err = ra.ReadAt(p, 0)
+	require.NoError(t, err)
+	// window of v1 fetched
+	_, err = ra.ReadAt(p, 1024)
+	require.NoError(t, err)
+	require.Equal(t, v1[:1024], p)
+
+	f.put("bucket", "path/file.bin", v2, nil)
+	// past the window, the fetch sees the new generation & drops the v1 window
+	_, err = ra.ReadAt(p, 32*1024)
+	require.NoError(t, err)
+	require.Equal(t, v2[:1024], p)
+	_, err = ra.ReadAt(p, 2048)
+	require.NoError(t, err)
+	require.Equal(t, v2[:1024], p)
+}
+
+func BenchmarkReaderAt(b *testing.B) {
+	content := readerAtContent(8 * 1024 * 1024)
+	chunk := 64 * 1024
+	patterns := map[string]func(i int) int64{
+		"sequential": func(i int) int64 { return int64(i*chunk) % int64(len(content)-chunk) },
+		"random": func(i int) int64 {
+			return rand.New(rand.NewSource(int64(i))).Int63n(int64(len(content) - chunk))
+		},
+	}
+	for _, name := range []string{"sequential", "random"} {
+		for _, window := range []int{0, DEFAULT_READ_AHEAD_WINDOW} {
+			b.Run(fmt.Sprintf("%s/window=%d", name, window), func(b *testing.B) {
+				f := newFakeGCS()
+				f.put("bucket", "path/file.bin", content, nil)
+				reads := countRangeReads(f)
+				cs := newFakeClient(b, f)
+				cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+				require.NoError(b, err)
+				opts := []ReaderAtOption{}
+				if window > 0 {
+					opts = append(opts, WithReadAhead(window))
+				}
+				ra, err := cs.NewReaderAt(context.Background(), cfr, opts...)
+				require.NoError(b, err)
+				defer ra.Close()
+
+				p := make([]byte, chunk)
+				b.SetBytes(int64(chunk))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := ra.ReadAt(p, patterns[name](i)); err != nil && err != io.EOF {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(atomic.LoadInt64(reads))/float64(b.N), "roundtrips/op")
+			})
+		}
+	}
+}
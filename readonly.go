@@ -0,0 +1,22 @@
+package cloudstorage
+
+import (
+	"github.com/comfforts/errors"
+)
+
+const (
+	ERROR_READ_ONLY_CLIENT string = "storage client is read only"
+)
+
+var (
+	ErrReadOnlyClient = errors.NewAppError(ERROR_READ_ONLY_CLIENT)
+)
+
+// mutation returns ErrReadOnlyClient when the client is configured read only,
+// checked first by every method that can change bucket content, before any request is issued
+func (cs *cloudStorageClient) mutation() error {
+	if cs.config.ReadOnly {
+		return ErrReadOnlyClient
+	}
+	return nil
+}
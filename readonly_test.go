@@ -0,0 +1,240 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyClient(t *testing.T) {
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+	snapshot := []ObjectVersion{{Bucket: "bucket", Name: "path/file.json", Generation: 1}}
+
+	mutating := map[string]func(cs *cloudStorageClient) error{
+		"UploadFile": func(cs *cloudStorageClient) error {
+			_, err := cs.UploadFile(ctx, strings.NewReader("{}"), cfr)
+			return err
+		},
+		"Upload": func(cs *cloudStorageClient) error {
+			_, err := cs.Upload(ctx, strings.NewReader("{}"), cfr)
+			return err
+		},
+		"UploadBytes": func(cs *cloudStorageClient) error {
+			_, err := cs.UploadBytes(ctx, []byte("{}"), cfr)
+			return err
+		},
+		"UploadFromReaderAt": func(cs *cloudStorageClient) error {
+			_, err := cs.UploadFromReaderAt(ctx, strings.NewReader("{}"), 2, cfr)
+			return err
+		},
+		"UploadFromFile": func(cs *cloudStorageClient) error {
+			_, err := cs.UploadFromFile(ctx, "file.json", cfr)
+			return err
+		},
+		"UploadFanOut": func(cs *cloudStorageClient) error {
+			_, err := cs.UploadFanOut(ctx, strings.NewReader("{}"), cfr, nil)
+			return err
+		},
+		"PublishSet": func(cs *cloudStorageClient) error {
+			_, err := cs.PublishSet(ctx, []UploadItem{{Request: cfr, Reader: strings.NewReader("{}")}}, UploadItem{Request: cfr, Reader: strings.NewReader("{}")})
+			return err
+		},
+		"StagedUpload": func(cs *cloudStorageClient) error {
+			_, err := cs.StagedUpload(ctx, strings.NewReader("{}"), cfr, func(context.Context, CloudFileRequest) error { return nil })
+			return err
+		},
+		"UploadUnique": func(cs *cloudStorageClient) error {
+			_, err := cs.UploadUnique(ctx, strings.NewReader("{}"), "bucket", "uploads", "file.json")
+			return err
+		},
+		"CleanupStaging": func(cs *cloudStorageClient) error {
+			_, err := cs.CleanupStaging(ctx, "bucket", time.Hour)
+			return err
+		},
+		"CleanupOrphans": func(cs *cloudStorageClient) error {
+			_, err := cs.CleanupOrphans(ctx, "bucket", GCOptions{})
+			return err
+		},
+		"EnsureRoutedBuckets": func(cs *cloudStorageClient) error {
+			_, err := cs.EnsureRoutedBuckets(ctx, []string{"tenant"})
+			return err
+		},
+		"SetBucketVersioning": func(cs *cloudStorageClient) error {
+			return cs.SetBucketVersioning(ctx, "bucket", true)
+		},
+		"SetBucketLabels": func(cs *cloudStorageClient) error {
+			return cs.SetBucketLabels(ctx, "bucket", map[string]string{"env": "test"})
+		},
+		"SetBucketRPO": func(cs *cloudStorageClient) error {
+			return cs.SetBucketRPO(ctx, "bucket", RPOAsyncTurbo)
+		},
+		"SetBucketRetentionPolicy": func(cs *cloudStorageClient) error {
+			return cs.SetBucketRetentionPolicy(ctx, "bucket", time.Hour)
+		},
+		"LockBucketRetentionPolicy": func(cs *cloudStorageClient) error {
+			return cs.LockBucketRetentionPolicy(ctx, "bucket", "bucket")
+		},
+		"SetDefaultEventBasedHold": func(cs *cloudStorageClient) error {
+			return cs.SetDefaultEventBasedHold(ctx, "bucket", true)
+		},
+		"SetBucketSoftDelete": func(cs *cloudStorageClient) error {
+			return cs.SetBucketSoftDelete(ctx, "bucket", MIN_SOFT_DELETE_RETENTION)
+		},
+		"RestoreSoftDeleted": func(cs *cloudStorageClient) error {
+			_, err := cs.RestoreSoftDeleted(ctx, cfr, 1)
+			return err
+		},
+		"WriteJSON": func(cs *cloudStorageClient) error {
+			_, err := cs.WriteJSON(ctx, cfr, map[string]string{})
+			return err
+		},
+		"WriteNDJSON": func(cs *cloudStorageClient) error {
+			_, err := cs.WriteNDJSON(ctx, cfr, func() (interface{}, bool) { return nil, false })
+			return err
+		},
+		"WriteCSV": func(cs *cloudStorageClient) error {
+			_, err := cs.WriteCSV(ctx, cfr, []string{"a"}, func() ([]string, bool) { return nil, false })
+			return err
+		},
+		"CopyFrom": func(cs *cloudStorageClient) error {
+			_, err := cs.CopyFrom(ctx, cs, cfr, cfr)
+			return err
+		},
+		"TransformObject": func(cs *cloudStorageClient) error {
+			_, err := cs.TransformObject(ctx, cfr, cfr, func(dst io.Writer, src io.Reader) error {
+				_, err := io.Copy(dst, src)
+				return err
+			})
+			return err
+		},
+		"RestoreSnapshot": func(cs *cloudStorageClient) error {
+			_, err := cs.RestoreSnapshot(ctx, snapshot, "restore")
+			return err
+		},
+		"BackupPrefixIncremental": func(cs *cloudStorageClient) error {
+			_, err := cs.BackupPrefixIncremental(ctx, cs.Bucket("bucket"), cs.Bucket("mirror"), "path", time.Time{})
+			return err
+		},
+		"PutCAS": func(cs *cloudStorageClient) error {
+			_, _, err := cs.PutCAS(ctx, "bucket", "objects", strings.NewReader("content"))
+			return err
+		},
+		"RenameByRule": func(cs *cloudStorageClient) error {
+			_, err := cs.RenameByRule(ctx, "bucket", func(name string) (string, bool) { return name + ".moved", false })
+			return err
+		},
+		"ReconcileBuckets": func(cs *cloudStorageClient) error {
+			_, err := cs.ReconcileBuckets(ctx, BucketPrefix{Bucket: "bucket"}, BucketPrefix{Bucket: "mirror"}, ReconcileOptions{})
+			return err
+		},
+		"PublishPointer": func(cs *cloudStorageClient) error {
+			return cs.PublishPointer(ctx, cfr, []byte("1"))
+		},
+		"IncrementCounter": func(cs *cloudStorageClient) error {
+			_, err := cs.IncrementCounter(ctx, cfr, 1)
+			return err
+		},
+		"DeleteObject": func(cs *cloudStorageClient) error {
+			return cs.DeleteObject(ctx, cfr)
+		},
+		"DeleteObjects": func(cs *cloudStorageClient) error {
+			return cs.DeleteObjects(ctx, cfr)
+		},
+		"DeleteObjectsWithReport": func(cs *cloudStorageClient) error {
+			_, err := cs.DeleteObjectsWithReport(ctx, cfr)
+			return err
+		},
+		"EnsureDir": func(cs *cloudStorageClient) error {
+			return cs.EnsureDir(ctx, "bucket", "path")
+		},
+		"ProcessManifest": func(cs *cloudStorageClient) error {
+			_, err := cs.ProcessManifest(ctx, strings.NewReader(`{"name":"a"}`), ManifestDelete, WithManifestBucket("bucket"))
+			return err
+		},
+		"ProcessPrefix": func(cs *cloudStorageClient) error {
+			_, err := cs.ProcessPrefix(ctx, cfr, func(context.Context, *ObjectAttrs, io.Reader) error { return nil })
+			return err
+		},
+		"UpdateMetadata": func(cs *cloudStorageClient) error {
+			_, err := cs.UpdateMetadata(ctx, cfr, map[string]string{"k": "v"})
+			return err
+		},
+		"SignedURL": func(cs *cloudStorageClient) error {
+			_, err := cs.SignedURL(ctx, cfr, SignedURLOptions{Method: http.MethodPut})
+			return err
+		},
+		"SignedURLs": func(cs *cloudStorageClient) error {
+			_, err := cs.SignedURLs(ctx, []CloudFileRequest{cfr}, SignedURLOptions{Method: http.MethodPut})
+			return err
+		},
+		"SetObjectTags": func(cs *cloudStorageClient) error {
+			_, err := cs.SetObjectTags(ctx, cfr, map[string]string{"k": "v"})
+			return err
+		},
+		"WriteUsageSnapshot": func(cs *cloudStorageClient) error {
+			_, err := cs.WriteUsageSnapshot(ctx, UsageSnapshot{Bucket: "bucket"}, cfr)
+			return err
+		},
+	}
+	// methods that never change bucket content
+	reads := map[string]bool{
+		"DownloadFile": true, "Download": true, "DownloadBytes": true, "DownloadToWriterAt": true, "DownloadHead": true, "DownloadTail": true, "ReadJSON": true, "ReadNDJSON": true, "ReadCSV": true,
+		"ReadAt": true, "OpenReader": true, "OpenRangeReader": true, "NewReaderAt": true, "SnapshotPrefix": true, "ReadPointer": true, "ReadCounter": true,
+		"ListObjects": true, "ListDir": true, "ListTree": true, "WalkObjects": true, "ExportInventory": true, "GetAttrs": true, "GetAttrsBatch": true,
+		"ListObjectsInfo": true, "GetObjectTags": true, "FindObjectsByTag": true, "FindStrayObjects": true, "Close": true, "CloseWithContext": true,
+		"Exists": true, "NewFileRequest": true, "Invalidate": true, "Bucket": true, "Scoped": true, "SampleUsage": true, "ReadUsageHistory": true, "WaitVisible": true, "GetBucketAttrs": true, "AssertBucketPolicy": true, "ListLatestVersions": true,
+		"AuditFailures": true, "ListSoftDeleted": true, "VerifyObject": true, "ReadLastBackupMarker": true, "GetCAS": true,
+	}
+
+	// every interface method is classified, new mutating methods must be guarded & listed
+	it := reflect.TypeOf((*CloudStorage)(nil)).Elem()
+	for i := 0; i < it.NumMethod(); i++ {
+		name := it.Method(i).Name
+		_, isMutating := mutating[name]
+		require.True(t, isMutating != reads[name], "method %s must be classified", name)
+	}
+
+	for name, call := range mutating {
+		t.Run(name, func(t *testing.T) {
+			cs := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("%s issued request %s %s", name, r.Method, r.URL.Path)
+				writeAPIError(w, http.StatusInternalServerError, "unexpected request")
+			}))
+			cs.config.ReadOnly = true
+			require.ErrorIs(t, call(cs), ErrReadOnlyClient)
+		})
+	}
+}
+
+func TestReadOnlyClientReads(t *testing.T) {
+	f := newFakeGCS()
+	f.versioned = true
+	f.put("bucket", "path/file.json", []byte(`{"a":1}`), nil)
+	cs := newFakeClient(t, f)
+	cs.config.ReadOnly = true
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = cs.Download(context.Background(), &buf, cfr)
+	require.NoError(t, err)
+	_, err = cs.ListObjects(context.Background(), cfr)
+	require.NoError(t, err)
+
+	// dry runs & verifications don't mutate
+	snapshot, err := cs.SnapshotPrefix(context.Background(), CloudFileRequest{bucket: "bucket", path: "path"}, time.Now())
+	require.NoError(t, err)
+	_, err = cs.RestoreSnapshot(context.Background(), snapshot, "restore", WithDryRun())
+	require.NoError(t, err)
+	_, err = cs.ProcessManifest(context.Background(), strings.NewReader(`{"name":"path/file.json"}`+"\n"), ManifestVerifyExists, WithManifestBucket("bucket"))
+	require.NoError(t, err)
+}
@@ -0,0 +1,357 @@
+package cloudstorage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_RECONCILING_BUCKETS string = "error reconciling buckets"
+)
+
+// DEFAULT_RECONCILE_CONCURRENCY is the default number of concurrent reconcile copies & deletes
+const DEFAULT_RECONCILE_CONCURRENCY = 8
+
+// BucketPrefix is a bucket & object prefix of a reconcile, objects are matched by name relative to the prefix
+type BucketPrefix struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix"`
+}
+
+// objectName returns the object name of given relative name under the ref's prefix
+func (b BucketPrefix) objectName(rel string) string {
+	return dirPrefix(b.Prefix) + rel
+}
+
+// ReconcileOptions configure ReconcileBuckets
+type ReconcileOptions struct {
+	// DryRun reports planned actions without changing any object
+	DryRun bool
+	// DeleteExtraneous deletes destination objects missing from the source, filters don't apply
+	DeleteExtraneous bool
+	// Prefix limits the reconcile to relative names with given prefix, on both sides
+	Prefix string
+	// UpdatedAfter, when set, limits copies to source objects updated after it
+	UpdatedAfter time.Time
+	// Concurrency is the number of concurrent copies & deletes, defaults to DEFAULT_RECONCILE_CONCURRENCY
+	Concurrency int
+	// Checkpointer, when set, saves progress every CheckpointEvery names, defaults to DEFAULT_CHECKPOINT_EVERY,
+	// a later run resumes after the saved name. Progress isn't saved past a failed action, a resumed run retries it.
+	Checkpointer    Checkpointer
+	CheckpointEvery int
+	// Trace, when set, receives a record of every compared object's decision, action & outcome
+	Trace OperationTrace
+}
+
+// ReconcileActionKind is what reconcile does, or would do on a dry run, to a destination object
+type ReconcileActionKind string
+
+const (
+	// ReconcileCopy copies a source object missing from the destination
+	ReconcileCopy ReconcileActionKind = "copy"
+	// ReconcileUpdate copies a source object whose destination content differs
+	ReconcileUpdate ReconcileActionKind = "update"
+	// ReconcileDelete deletes a destination object missing from the source
+	ReconcileDelete ReconcileActionKind = "delete"
+)
+
+// ReconcileAction is the action on one destination object
+type ReconcileAction struct {
+	Action ReconcileActionKind `json:"action"`
+	// Name is the object name relative to both prefixes
+	Name string `json:"name"`
+	// Generation is the copied source generation, the deleted destination generation for deletes
+	Generation int64 `json:"generation"`
+	Size       int64 `json:"size"`
+	// Err is the action's error, nil on success & on dry runs, Error is its message
+	Err   error  `json:"-"`
+	Error string `json:"error,omitempty"`
+}
+
+// ReconcileReport reports the actions of a reconcile in name order, or the planned actions of a dry run
+type ReconcileReport struct {
+	Source BucketPrefix `json:"source"`
+	Dest   BucketPrefix `json:"dest"`
+	DryRun bool         `json:"dry_run"`
+	// SourceObjects & DestObjects are the objects listed on each side
+	SourceObjects int64 `json:"source_objects"`
+	DestObjects   int64 `json:"dest_objects"`
+	// InSync is the number of objects with the same size & CRC32C on both sides
+	InSync int64 `json:"in_sync"`
+	// Filtered is the number of source objects left alone by UpdatedAfter
+	Filtered int64             `json:"filtered"`
+	Actions  []ReconcileAction `json:"actions"`
+}
+
+// Failures returns the number of failed actions
+func (r ReconcileReport) Failures() int {
+	n := 0
+	for _, a := range r.Actions {
+		if a.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// traceObject returns the trace input of a listed object
+func traceObject(attrs *storage.ObjectAttrs) *TraceObject {
+	return &TraceObject{Bucket: attrs.Bucket, Name: attrs.Name, Generation: attrs.Generation, Size: attrs.Size, CRC32C: attrs.CRC32C}
+}
+
+// traceCondition describes the precondition of a traced action
+func traceCondition(conds storage.Conditions) string {
+	if conds.DoesNotExist {
+		return "if_does_not_exist"
+	}
+	return fmt.Sprintf("if_generation_match=%d", conds.GenerationMatch)
+}
+
+// reconcileCursor walks one side's listing in name order, directory markers skipped
+type reconcileCursor struct {
+	it     *storage.ObjectIterator
+	prefix string
+	// after skips names up to a resumed cursor
+	after  string
+	attrs  *storage.ObjectAttrs
+	name   string
+	done   bool
+	listed int64
+}
+
+func (c *reconcileCursor) next() error {
+	for {
+		attrs, err := c.it.Next()
+		if err == iterator.Done {
+			c.attrs, c.done = nil, true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(attrs.Name, c.prefix)
+		if isDirMarker(attrs) || (c.after != "" && name <= c.after) {
+			continue
+		}
+		c.attrs, c.name = attrs, name
+		c.listed++
+		return nil
+	}
+}
+
+// ReconcileBuckets makes the destination's objects match the source's, both listed in name order & merged,
+// copying missing & changed objects server side, conditional on the destination generation seen,
+// & deleting extraneous destination objects when requested. Objects with equal size & CRC32C are in sync,
+// a run right after a successful one reports no actions. Actions run concurrently while listing,
+// failed actions are reported, the first failure is returned after all actions ran.
+// With a checkpointer the run resumes after the saved name, the report covers this run only.
+// A trace receives every compared object's decision in name order, planned actions of dry runs included.
+func (cs *cloudStorageClient) ReconcileBuckets(ctx context.Context, src, dst BucketPrefix, opts ReconcileOptions) (ReconcileReport, error) {
+	if !opts.DryRun {
+		if err := cs.mutation(); err != nil {
+			return ReconcileReport{}, err
+		}
+	}
+	src, err := cs.scopedRef(ctx, src)
+	if err != nil {
+		return ReconcileReport{}, err
+	}
+	if dst, err = cs.scopedRef(ctx, dst); err != nil {
+		return ReconcileReport{}, err
+	}
+	if src.Bucket == "" || dst.Bucket == "" {
+		return ReconcileReport{}, ErrBucketNameMissing
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DEFAULT_RECONCILE_CONCURRENCY
+	}
+	op := cs.startOperation(ctx, "ReconcileBuckets", CloudFileRequest{bucket: dst.Bucket})
+	defer op.finish()
+	ctx = op.ctx
+	op.audited = op.audited && !opts.DryRun
+	op.object = dirPrefix(dst.Prefix)
+	// the item count is unknown before listing, the listings of both sides are budgeted
+	if err := cs.checkDeadlineBudget(ctx, op, 2, 2); err != nil {
+		return ReconcileReport{}, err
+	}
+
+	scope := fmt.Sprintf("%s/%s -> %s/%s %s", src.Bucket, dirPrefix(src.Prefix), dst.Bucket, dirPrefix(dst.Prefix), opts.Prefix)
+	ckpt, err := loadCheckpoint(opts.Checkpointer, opts.CheckpointEvery, "ReconcileBuckets", scope)
+	if err != nil {
+		op.logger.Error(ERROR_LOADING_CHECKPOINT, zap.Error(err))
+		return ReconcileReport{}, op.wrapError(err, "%s %s", ERROR_RECONCILING_BUCKETS, op.object)
+	}
+	resume := ckpt.cursor()
+	if resume != "" {
+		op.logger.Info("resuming reconcile from checkpoint", zap.String("cursor", resume))
+	}
+
+	report := ReconcileReport{Source: src, Dest: dst, DryRun: opts.DryRun}
+	tr := newTracer(opts.Trace, op, cs.now)
+	cursor := func(ref BucketPrefix) *reconcileCursor {
+		prefix := dirPrefix(ref.Prefix)
+		q := &storage.Query{Prefix: prefix + opts.Prefix}
+		if resume != "" {
+			q.StartOffset = prefix + resume
+		}
+		return &reconcileCursor{it: cs.bucketHandle(CloudFileRequest{bucket: ref.Bucket}).Objects(ctx, q), prefix: prefix, after: resume}
+	}
+	srcC, dstC := cursor(src), cursor(dst)
+
+	var mu sync.Mutex
+	var firstErr error
+	fail := func(a *ReconcileAction, err error) {
+		op.logger.Error(ERROR_RECONCILING_BUCKETS, zap.Error(err), zap.String("action", string(a.Action)), zap.String("name", a.Name))
+		err = op.wrapError(err, "%s %s", ERROR_RECONCILING_BUCKETS, a.Name)
+		mu.Lock()
+		defer mu.Unlock()
+		a.Err, a.Error = err, err.Error()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	actions := []*ReconcileAction{}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	run := func(a *ReconcileAction, conds storage.Conditions, rec TraceRecord) {
+		actions = append(actions, a)
+		rec.Action, rec.Condition = "copy", traceCondition(conds)
+		if a.Action == ReconcileDelete {
+			rec.Action = "delete"
+		}
+		rec = tr.decide(rec)
+		if opts.DryRun {
+			tr.done(rec, TracePlanned, nil)
+			return
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			dstName := dst.objectName(a.Name)
+			obj := cs.bucketHandle(CloudFileRequest{bucket: dst.Bucket}).Object(dstName).If(conds)
+			var err error
+			if a.Action == ReconcileDelete {
+				err = obj.Delete(ctx)
+			} else {
+				srcObj := cs.bucketHandle(CloudFileRequest{bucket: src.Bucket}).Object(src.objectName(a.Name)).Generation(a.Generation)
+				_, err = obj.CopierFrom(srcObj).Run(ctx)
+			}
+			cs.invalidate(dst.Bucket, dstName)
+			if err != nil {
+				fail(a, err)
+			}
+			tr.done(rec, TraceOK, a.Err)
+		}()
+	}
+	copyAction := func(kind ReconcileActionKind, conds storage.Conditions, rec TraceRecord) {
+		if !opts.UpdatedAfter.IsZero() && !srcC.attrs.Updated.After(opts.UpdatedAfter) {
+			report.Filtered++
+			rec.Decision, rec.Reason = TraceFiltered, "not updated after "+opts.UpdatedAfter.UTC().Format(time.RFC3339)
+			tr.record(rec, TraceNoAction)
+			return
+		}
+		run(&ReconcileAction{Action: kind, Name: srcC.name, Generation: srcC.attrs.Generation, Size: srcC.attrs.Size}, conds, rec)
+	}
+
+	// progress is saved once every action up to the processed name ran, never past a failure
+	processed := resume
+	saveCheckpoint := func() {
+		if opts.DryRun {
+			return
+		}
+		wg.Wait()
+		if firstErr != nil {
+			return
+		}
+		if err := ckpt.save(processed, nil, nil); err != nil {
+			op.logger.Error(ERROR_SAVING_CHECKPOINT, zap.Error(err), zap.String("cursor", processed))
+			firstErr = op.wrapError(err, "%s %s", ERROR_RECONCILING_BUCKETS, op.object)
+		}
+	}
+
+	err = srcC.next()
+	if err == nil {
+		err = dstC.next()
+	}
+	for err == nil && ctx.Err() == nil && (!srcC.done || !dstC.done) {
+		switch {
+		case dstC.done || (!srcC.done && srcC.name < dstC.name):
+			processed = srcC.name
+			copyAction(ReconcileCopy, storage.Conditions{DoesNotExist: true},
+				TraceRecord{Name: srcC.name, Source: traceObject(srcC.attrs), Decision: TraceCopy, Reason: "missing from destination"})
+			err = srcC.next()
+		case srcC.done || dstC.name < srcC.name:
+			processed = dstC.name
+			rec := TraceRecord{Name: dstC.name, Dest: traceObject(dstC.attrs), Decision: TraceExtraneous, Reason: "missing from source"}
+			if opts.DeleteExtraneous {
+				rec.Decision = TraceDelete
+				run(&ReconcileAction{Action: ReconcileDelete, Name: dstC.name, Generation: dstC.attrs.Generation, Size: dstC.attrs.Size},
+					storage.Conditions{GenerationMatch: dstC.attrs.Generation}, rec)
+			} else {
+				tr.record(rec, TraceNoAction)
+			}
+			err = dstC.next()
+		default:
+			processed = srcC.name
+			rec := TraceRecord{Name: srcC.name, Source: traceObject(srcC.attrs), Dest: traceObject(dstC.attrs)}
+			switch {
+			case srcC.attrs.Size != dstC.attrs.Size:
+				rec.Decision, rec.Reason = TraceUpdate, "size differs"
+			case srcC.attrs.CRC32C != dstC.attrs.CRC32C:
+				rec.Decision, rec.Reason = TraceUpdate, "crc32c differs"
+			default:
+				rec.Decision, rec.Reason = TraceInSync, "size & crc32c match"
+			}
+			if rec.Decision == TraceInSync {
+				report.InSync++
+				tr.record(rec, TraceNoAction)
+			} else {
+				copyAction(ReconcileUpdate, storage.Conditions{GenerationMatch: dstC.attrs.Generation}, rec)
+			}
+			if err = srcC.next(); err == nil {
+				err = dstC.next()
+			}
+		}
+		if ckpt.due() {
+			saveCheckpoint()
+		}
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		saveCheckpoint()
+	}
+	wg.Wait()
+	if err == nil && firstErr == nil && !opts.DryRun {
+		if err := ckpt.finish(); err != nil {
+			op.logger.Error(ERROR_SAVING_CHECKPOINT, zap.Error(err))
+			firstErr = op.wrapError(err, "%s %s", ERROR_RECONCILING_BUCKETS, op.object)
+		}
+	}
+
+	report.SourceObjects, report.DestObjects = srcC.listed, dstC.listed
+	report.Actions = make([]ReconcileAction, len(actions))
+	for i, a := range actions {
+		report.Actions[i] = *a
+	}
+	if err != nil {
+		// actions planned before the listing failed ran, nothing after it was compared
+		op.logger.Error(ERROR_LISTING_OBJECTS, zap.Error(err), zap.Int64("source", srcC.listed), zap.Int64("dest", dstC.listed))
+		return report, op.wrapError(err, "%s %s", ERROR_RECONCILING_BUCKETS, op.object)
+	}
+	op.logger.Debug("buckets reconciled", zap.String("source", src.Bucket), zap.String("dest", dst.Bucket), zap.Bool("dryRun", opts.DryRun), zap.Int("actions", len(report.Actions)), zap.Int("failures", report.Failures()))
+	return report, firstErr
+}
@@ -0,0 +1,137 @@
+package cloudstorage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// reconcileActions returns report's actions as kind:name
+func reconcileActions(report ReconcileReport) []string {
+	actions := []string{}
+	for _, a := range report.Actions {
+		actions = append(actions, string(a.Action)+":"+a.Name)
+	}
+	return actions
+}
+
+func TestReconcileBuckets(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "data/a.txt", []byte("a"), nil)
+	f.put("bucket", "data/b.txt", []byte("b"), nil)
+	f.put("bucket", "data/nested/c.txt", []byte("c"), nil)
+	f.put("bucket", "data/d.txt", []byte("d"), nil)
+	f.put("bucket", "data/dir/", nil, nil)
+	f.put("mirror", "copy/b.txt", []byte("b"), nil)
+	f.put("mirror", "copy/d.txt", []byte("stale"), nil)
+	f.put("mirror", "copy/extra.txt", []byte("x"), nil)
+	f.put("mirror", "other/a.txt", []byte("a"), nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	src, dst := BucketPrefix{Bucket: "bucket", Prefix: "data"}, BucketPrefix{Bucket: "mirror", Prefix: "copy/"}
+	opts := ReconcileOptions{DeleteExtraneous: true, Concurrency: 2}
+
+	// dry run plans without changes
+	dryOpts := opts
+	dryOpts.DryRun = true
+	report, err := cs.ReconcileBuckets(ctx, src, dst, dryOpts)
+	require.NoError(t, err)
+	want := []string{"copy:a.txt", "update:d.txt", "delete:extra.txt", "copy:nested/c.txt"}
+	require.Equal(t, want, reconcileActions(report))
+	_, _, ok := f.get("mirror", "copy/a.txt")
+	require.False(t, ok)
+
+	report, err = cs.ReconcileBuckets(ctx, src, dst, opts)
+	require.NoError(t, err)
+	require.Equal(t, want, reconcileActions(report))
+	require.Equal(t, int64(4), report.SourceObjects)
+	require.Equal(t, int64(3), report.DestObjects)
+	require.Equal(t, int64(1), report.InSync)
+	require.Zero(t, report.Failures())
+	for name, content := range map[string]string{"a.txt": "a", "d.txt": "d", "nested/c.txt": "c"} {
+		data, _, ok := f.get("mirror", "copy/"+name)
+		require.True(t, ok, name)
+		require.Equal(t, content, string(data))
+	}
+	_, _, ok = f.get("mirror", "copy/extra.txt")
+	require.False(t, ok)
+	_, _, ok = f.get("mirror", "other/a.txt")
+	require.True(t, ok, "objects outside the destination prefix are kept")
+
+	// no source changes, nothing to do
+	report, err = cs.ReconcileBuckets(ctx, src, dst, opts)
+	require.NoError(t, err)
+	require.Empty(t, report.Actions)
+	require.Equal(t, int64(4), report.InSync)
+
+	// machine readable
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"in_sync":4`)
+}
+
+func TestReconcileBucketsFilters(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "logs/2023/old.txt", []byte("old"), nil)
+	f.put("bucket", "logs/2024/kept.txt", []byte("kept"), nil)
+	f.put("mirror", "logs/2024/extra.txt", []byte("x"), nil)
+	f.put("mirror", "logs/2024/kept.txt", []byte("stale"), nil)
+	cutoff := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	f.put("bucket", "logs/2024/new.txt", []byte("new"), nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	ref := func(bucket string) BucketPrefix { return BucketPrefix{Bucket: bucket, Prefix: "logs"} }
+
+	// only names under the filter prefix, only recent source objects are copied,
+	// older ones still count as present at the source
+	report, err := cs.ReconcileBuckets(ctx, ref("bucket"), ref("mirror"), ReconcileOptions{
+		Prefix:           "2024/",
+		UpdatedAfter:     cutoff,
+		DeleteExtraneous: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"delete:2024/extra.txt", "copy:2024/new.txt"}, reconcileActions(report))
+	require.Equal(t, int64(1), report.Filtered)
+	_, _, ok := f.get("mirror", "logs/2023/old.txt")
+	require.False(t, ok)
+	data, _, _ := f.get("mirror", "logs/2024/kept.txt")
+	require.Equal(t, "stale", string(data))
+}
+
+func TestReconcileBucketsFailures(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "a.txt", []byte("a"), nil)
+	f.put("bucket", "b.txt", []byte("b"), nil)
+	f.fail = func(r *http.Request) int {
+		if strings.Contains(r.URL.Path, "/o/a.txt/rewriteTo/") {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+
+	report, err := cs.ReconcileBuckets(context.Background(), BucketPrefix{Bucket: "bucket"}, BucketPrefix{Bucket: "mirror"}, ReconcileOptions{})
+	require.ErrorIs(t, err, ErrPermissionDenied)
+	require.Equal(t, 1, report.Failures())
+	require.Error(t, report.Actions[0].Err)
+	require.NotEmpty(t, report.Actions[0].Error)
+	require.NoError(t, report.Actions[1].Err)
+	_, _, ok := f.get("mirror", "b.txt")
+	require.True(t, ok)
+
+	// listing failures stop the reconcile
+	f.fail = func(r *http.Request) int {
+		if r.URL.Path == "/storage/v1/b/mirror/o" {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	_, err = cs.ReconcileBuckets(context.Background(), BucketPrefix{Bucket: "bucket"}, BucketPrefix{Bucket: "mirror"}, ReconcileOptions{DeleteExtraneous: true})
+	require.ErrorIs(t, err, ErrPermissionDenied)
+}
@@ -0,0 +1,336 @@
+package cloudstorage
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_RENAMING_OBJECT  string = "error renaming object"
+	ERROR_RENAME_COLLISION string = "rename target claimed by another object"
+	ERROR_RENAME_VERIFY    string = "renamed object doesn't match its source"
+)
+
+var (
+	ErrRenameCollision = errors.NewAppError(ERROR_RENAME_COLLISION)
+)
+
+// DEFAULT_RENAME_CONCURRENCY is the default number of concurrent renames
+const DEFAULT_RENAME_CONCURRENCY = 8
+
+// RenameRule returns the new name of given object name, or skip to leave it alone,
+// names are relative to the context's scope
+type RenameRule func(oldName string) (newName string, skip bool)
+
+// RenameOptions configure RenameByRule
+type RenameOptions struct {
+	// DryRun reports planned renames & collisions without changing any object
+	DryRun bool
+	// Prefix limits the renames to names with given prefix
+	Prefix string
+	// Concurrency is the number of concurrent renames, defaults to DEFAULT_RENAME_CONCURRENCY
+	Concurrency int
+	// Checkpointer, when set, saves progress every CheckpointEvery names, defaults to DEFAULT_CHECKPOINT_EVERY,
+	// a later run resumes after the saved name. Progress isn't saved past a failed rename, a resumed run retries it.
+	Checkpointer    Checkpointer
+	CheckpointEvery int
+}
+
+// RenameOption sets rename options
+type RenameOption func(o *RenameOptions)
+
+// WithRenameDryRun makes the rename report what it would do
+func WithRenameDryRun() RenameOption {
+	return func(o *RenameOptions) {
+		o.DryRun = true
+	}
+}
+
+// WithRenamePrefix limits the rename to names with given prefix
+func WithRenamePrefix(prefix string) RenameOption {
+	return func(o *RenameOptions) {
+		o.Prefix = prefix
+	}
+}
+
+// WithRenameConcurrency sets the number of concurrent renames
+func WithRenameConcurrency(n int) RenameOption {
+	return func(o *RenameOptions) {
+		o.Concurrency = n
+	}
+}
+
+// WithRenameCheckpointer saves the rename's progress with given checkpointer every given number of names
+func WithRenameCheckpointer(cp Checkpointer, every int) RenameOption {
+	return func(o *RenameOptions) {
+		o.Checkpointer = cp
+		o.CheckpointEvery = every
+	}
+}
+
+// RenameAction is the rename of one object
+type RenameAction struct {
+	OldName string `json:"old_name"`
+	NewName string `json:"new_name"`
+	// Generation is the renamed source generation
+	Generation int64 `json:"generation"`
+	Size       int64 `json:"size"`
+	// Err is the rename's error, nil on success & on dry runs, Error is its message
+	Err   error  `json:"-"`
+	Error string `json:"error,omitempty"`
+}
+
+// RenameCollision is an object left alone because its new name is taken
+type RenameCollision struct {
+	OldName string `json:"old_name"`
+	NewName string `json:"new_name"`
+	// With is the earlier listed object renamed to the same name, empty when the name was already
+	// taken by an object with other content
+	With string `json:"with,omitempty"`
+}
+
+// RenameReport reports the renames of a run in name order, or the planned renames of a dry run
+type RenameReport struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix"`
+	DryRun bool   `json:"dry_run"`
+	// Listed is the number of objects listed, Skipped the ones the rule left alone or kept the name of
+	Listed  int64 `json:"listed"`
+	Skipped int64 `json:"skipped"`
+	Renamed int64 `json:"renamed"`
+	Bytes   int64 `json:"bytes"`
+	Failed  int64 `json:"failed"`
+	// Actions are the renames, collisions aren't renamed
+	Actions    []RenameAction    `json:"actions"`
+	Collisions []RenameCollision `json:"collisions"`
+}
+
+// RenameByRule renames the bucket's objects by given rule, streaming the listing in name order:
+// each object is copied server side to its new name, conditional on the name being free, verified
+// by size & CRC32C, & its old name deleted, conditional on the copied generation. Two objects mapped
+// to one name are collisions, the first listed is renamed & the later ones reported & left alone,
+// as are objects whose new name holds other content. A taken name holding the same content is
+// taken for an interrupted rename of the object & its old name deleted. Names renamed to aren't
+// renamed again in the same run. Renames run concurrently while listing, failures & collisions
+// don't stop the run, the first failure is returned after all renames ran, ErrRenameCollision
+// when there were only collisions. With a checkpointer the run resumes after the saved name,
+// the report covers this run only.
+func (cs *cloudStorageClient) RenameByRule(ctx context.Context, bucket string, rule RenameRule, opts ...RenameOption) (RenameReport, error) {
+	rOpts := RenameOptions{Concurrency: DEFAULT_RENAME_CONCURRENCY}
+	for _, opt := range opts {
+		opt(&rOpts)
+	}
+	if !rOpts.DryRun {
+		if err := cs.mutation(); err != nil {
+			return RenameReport{}, err
+		}
+	}
+	if rOpts.Concurrency <= 0 {
+		rOpts.Concurrency = DEFAULT_RENAME_CONCURRENCY
+	}
+	cfr, err := cs.scoped(ctx, CloudFileRequest{bucket: bucket})
+	if err != nil {
+		return RenameReport{}, err
+	}
+	if cfr.bucket == "" {
+		return RenameReport{}, ErrBucketNameMissing
+	}
+	op := cs.startOperation(ctx, "RenameByRule", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	op.audited = op.audited && !rOpts.DryRun
+	prefix := cfr.scopePrefix + cfr.encodedName(rOpts.Prefix)
+	op.object = prefix
+
+	ckpt, err := loadCheckpoint(rOpts.Checkpointer, rOpts.CheckpointEvery, "RenameByRule", cfr.bucket+"/"+prefix)
+	if err != nil {
+		op.logger.Error(ERROR_LOADING_CHECKPOINT, zap.Error(err))
+		return RenameReport{}, op.wrapError(err, "%s %s", ERROR_RENAMING_OBJECT, prefix)
+	}
+	resume := ckpt.cursor()
+	if resume != "" {
+		op.logger.Info("resuming rename from checkpoint", zap.String("cursor", resume))
+	}
+
+	report := RenameReport{Bucket: cfr.bucket, Prefix: rOpts.Prefix, DryRun: rOpts.DryRun}
+	bh := cs.bucketHandle(cfr)
+	var mu sync.Mutex
+	var firstErr error
+	fail := func(a *RenameAction, err error) {
+		op.logger.Error(ERROR_RENAMING_OBJECT, zap.Error(err), zap.String("filepath", a.OldName), zap.String("newName", a.NewName))
+		err = op.wrapError(err, "%s %s", ERROR_RENAMING_OBJECT, a.OldName)
+		mu.Lock()
+		defer mu.Unlock()
+		a.Err, a.Error = err, err.Error()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	collide := func(c RenameCollision) {
+		op.logger.Info(ERROR_RENAME_COLLISION, zap.String("filepath", c.OldName), zap.String("newName", c.NewName), zap.String("with", c.With))
+		mu.Lock()
+		defer mu.Unlock()
+		report.Collisions = append(report.Collisions, c)
+	}
+
+	// claimed maps new names to the old name renamed to them, first listed wins
+	claimed := map[string]string{}
+	actions := []*RenameAction{}
+	sem := make(chan struct{}, rOpts.Concurrency)
+	var wg sync.WaitGroup
+	run := func(a *RenameAction, src *storage.ObjectAttrs, dstName string) {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			taken, err := cs.renameObject(ctx, bh, src, dstName, rOpts.DryRun)
+			cs.invalidate(cfr.bucket, src.Name)
+			cs.invalidate(cfr.bucket, dstName)
+			if taken {
+				collide(RenameCollision{OldName: a.OldName, NewName: a.NewName})
+				return
+			}
+			if err != nil {
+				fail(a, err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			actions = append(actions, a)
+		}()
+	}
+
+	// progress is saved once every rename up to the processed name ran, never past a failure
+	processed := resume
+	saveCheckpoint := func() {
+		if rOpts.DryRun {
+			return
+		}
+		wg.Wait()
+		if firstErr != nil {
+			return
+		}
+		if err := ckpt.save(processed, nil, nil); err != nil {
+			op.logger.Error(ERROR_SAVING_CHECKPOINT, zap.Error(err), zap.String("cursor", processed))
+			firstErr = op.wrapError(err, "%s %s", ERROR_RENAMING_OBJECT, prefix)
+		}
+	}
+
+	it := bh.Objects(ctx, &storage.Query{Prefix: prefix, StartOffset: resume})
+	for ctx.Err() == nil {
+		var attrs *storage.ObjectAttrs
+		if attrs, err = it.Next(); err != nil {
+			break
+		}
+		if attrs.Name <= resume || isDirMarker(attrs) {
+			continue
+		}
+		report.Listed++
+		processed = attrs.Name
+		oldName := cfr.unscopedName(attrs.Name)
+		newName, skip := rule(oldName)
+		_, renamedTo := claimed[attrs.Name]
+		if skip || newName == "" || newName == oldName || renamedTo {
+			report.Skipped++
+		} else {
+			dstName := cfr.scopePrefix + cfr.encodedName(newName)
+			a := &RenameAction{OldName: oldName, NewName: newName, Generation: attrs.Generation, Size: attrs.Size}
+			if with, ok := claimed[dstName]; ok {
+				collide(RenameCollision{OldName: oldName, NewName: newName, With: cfr.unscopedName(with)})
+			} else {
+				claimed[dstName] = attrs.Name
+				run(a, attrs, dstName)
+			}
+		}
+		if ckpt.due() {
+			saveCheckpoint()
+		}
+	}
+	if err == iterator.Done {
+		err = nil
+	}
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		saveCheckpoint()
+	}
+	wg.Wait()
+	if err == nil && firstErr == nil && !rOpts.DryRun {
+		if err := ckpt.finish(); err != nil {
+			op.logger.Error(ERROR_SAVING_CHECKPOINT, zap.Error(err))
+			firstErr = op.wrapError(err, "%s %s", ERROR_RENAMING_OBJECT, prefix)
+		}
+	}
+
+	// renames finish out of order, reports are in name order
+	sort.Slice(actions, func(i, j int) bool { return actions[i].OldName < actions[j].OldName })
+	sort.Slice(report.Collisions, func(i, j int) bool { return report.Collisions[i].OldName < report.Collisions[j].OldName })
+	report.Actions = make([]RenameAction, len(actions))
+	for i, a := range actions {
+		report.Actions[i] = *a
+		if a.Err != nil {
+			report.Failed++
+		} else if !rOpts.DryRun {
+			report.Renamed++
+			report.Bytes += a.Size
+		}
+	}
+	op.bytes = report.Bytes
+	if err != nil {
+		// renames started before the listing failed ran, nothing after it was listed
+		op.logger.Error(ERROR_LISTING_OBJECTS, zap.Error(err), zap.Int64("listed", report.Listed))
+		return report, op.wrapError(err, "%s %s", ERROR_RENAMING_OBJECT, prefix)
+	}
+	op.logger.Debug("objects renamed", zap.String("bucket", cfr.bucket), zap.Bool("dryRun", rOpts.DryRun), zap.Int64("renamed", report.Renamed), zap.Int("collisions", len(report.Collisions)), zap.Int64("failed", report.Failed))
+	if firstErr == nil && len(report.Collisions) > 0 {
+		return report, ErrRenameCollision
+	}
+	return report, firstErr
+}
+
+// renameObject copies the source generation to given name when free & deletes the source, reports whether
+// the name was taken by other content. Dry runs only look the name up.
+func (cs *cloudStorageClient) renameObject(ctx context.Context, bh *storage.BucketHandle, src *storage.ObjectAttrs, dstName string, dryRun bool) (bool, error) {
+	dst := bh.Object(dstName)
+	sameContent := func(attrs *storage.ObjectAttrs) bool {
+		return attrs.Size == src.Size && attrs.CRC32C == src.CRC32C
+	}
+	if dryRun {
+		attrs, err := dst.Attrs(ctx)
+		if err == storage.ErrObjectNotExist {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return !sameContent(attrs), nil
+	}
+
+	attrs, err := dst.If(storage.Conditions{DoesNotExist: true}).CopierFrom(bh.Object(src.Name).Generation(src.Generation)).Run(ctx)
+	if isPreconditionFailed(err) {
+		// taken, by an interrupted rename of the same object when holding its content
+		if attrs, err = dst.Attrs(ctx); err != nil {
+			return false, err
+		}
+		if !sameContent(attrs) {
+			return true, nil
+		}
+	} else if err != nil {
+		return false, err
+	}
+	if !sameContent(attrs) {
+		return false, errors.NewAppError("%s %s", ERROR_RENAME_VERIFY, dstName)
+	}
+	err = bh.Object(src.Name).If(storage.Conditions{GenerationMatch: src.Generation}).Delete(ctx)
+	if err == storage.ErrObjectNotExist {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,133 @@
+package cloudstorage
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// flatToPrefixes renames flat __ separated keys to prefixes
+func flatToPrefixes(name string) (string, bool) {
+	if !strings.Contains(name, "__") {
+		return "", true
+	}
+	return strings.ReplaceAll(name, "__", "/"), false
+}
+
+func TestRenameByRule(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "tenant__2024__report.json", []byte("report"), nil)
+	f.put("bucket", "tenant__2024__summary.json", []byte("summary"), nil)
+	f.put("bucket", "readme.txt", []byte("readme"), nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	// dry runs change nothing
+	report, err := cs.RenameByRule(ctx, "bucket", flatToPrefixes, WithRenameDryRun())
+	require.NoError(t, err)
+	require.True(t, report.DryRun)
+	require.Len(t, report.Actions, 2)
+	require.Zero(t, report.Renamed)
+	require.Equal(t, []string{"readme.txt", "tenant__2024__report.json", "tenant__2024__summary.json"}, f.storedNames("bucket"))
+
+	report, err = cs.RenameByRule(ctx, "bucket", flatToPrefixes, WithRenameConcurrency(2))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), report.Listed)
+	require.Equal(t, int64(1), report.Skipped)
+	require.Equal(t, int64(2), report.Renamed)
+	require.Equal(t, int64(13), report.Bytes)
+	require.Equal(t, RenameAction{OldName: "tenant__2024__report.json", NewName: "tenant/2024/report.json", Generation: report.Actions[0].Generation, Size: 6}, report.Actions[0])
+	require.Equal(t, []string{"readme.txt", "tenant/2024/report.json", "tenant/2024/summary.json"}, f.storedNames("bucket"))
+	data, _, ok := f.get("bucket", "tenant/2024/report.json")
+	require.True(t, ok)
+	require.Equal(t, "report", string(data))
+
+	// a re-run finds nothing to rename
+	report, err = cs.RenameByRule(ctx, "bucket", flatToPrefixes)
+	require.NoError(t, err)
+	require.Empty(t, report.Actions)
+	require.Equal(t, int64(3), report.Skipped)
+}
+
+func TestRenameByRuleCollisions(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "a__x.txt", []byte("first"), nil)
+	f.put("bucket", "b__x.txt", []byte("second"), nil)
+	f.put("bucket", "c__y.txt", []byte("mine"), nil)
+	f.put("bucket", "dir/y.txt", []byte("theirs"), nil)
+	cs := newFakeClient(t, f)
+	rules := map[string]string{"a__x.txt": "dir/x.txt", "b__x.txt": "dir/x.txt", "c__y.txt": "dir/y.txt"}
+	rule := func(name string) (string, bool) {
+		newName, ok := rules[name]
+		return newName, !ok
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		opts := []RenameOption{}
+		if dryRun {
+			opts = append(opts, WithRenameDryRun())
+		}
+		report, err := cs.RenameByRule(context.Background(), "bucket", rule, opts...)
+		require.Equal(t, ErrRenameCollision, err, "dry run %v", dryRun)
+		require.Equal(t, []RenameCollision{
+			{OldName: "b__x.txt", NewName: "dir/x.txt", With: "a__x.txt"},
+			{OldName: "c__y.txt", NewName: "dir/y.txt"},
+		}, report.Collisions)
+		require.Len(t, report.Actions, 1)
+		require.Equal(t, "a__x.txt", report.Actions[0].OldName)
+	}
+	// collisions are left alone, the taken name keeps its content
+	require.Equal(t, []string{"b__x.txt", "c__y.txt", "dir/x.txt", "dir/y.txt"}, f.storedNames("bucket"))
+	data, _, _ := f.get("bucket", "dir/x.txt")
+	require.Equal(t, "first", string(data))
+	data, _, _ = f.get("bucket", "dir/y.txt")
+	require.Equal(t, "theirs", string(data))
+}
+
+func TestRenameByRuleInterrupted(t *testing.T) {
+	f := newFakeGCS()
+	// copied by an interrupted run, the old name wasn't deleted
+	f.put("bucket", "t__a.txt", []byte("a"), nil)
+	f.put("bucket", "t/a.txt", []byte("a"), nil)
+	f.put("bucket", "t__b.txt", []byte("b"), nil)
+	cs := newFakeClient(t, f)
+
+	report, err := cs.RenameByRule(context.Background(), "bucket", flatToPrefixes)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), report.Renamed)
+	require.Empty(t, report.Collisions)
+	require.Equal(t, []string{"t/a.txt", "t/b.txt"}, f.storedNames("bucket"))
+}
+
+func TestRenameByRuleCheckpoint(t *testing.T) {
+	f := newFakeGCS()
+	for _, name := range []string{"p__1", "p__2", "p__3", "p__4"} {
+		f.put("bucket", name, []byte(name), nil)
+	}
+	f.fail = func(r *http.Request) int {
+		if strings.Contains(r.URL.Path, "/o/p__3/rewriteTo/") {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	cp := &memCheckpointer{}
+
+	report, err := cs.RenameByRule(context.Background(), "bucket", flatToPrefixes, WithRenameConcurrency(1), WithRenameCheckpointer(cp, 1))
+	require.ErrorIs(t, err, ErrPermissionDenied)
+	require.Equal(t, int64(1), report.Failed)
+	require.Equal(t, int64(3), report.Renamed)
+	require.Equal(t, "p__2", cp.state(t).Cursor, "progress isn't saved past the failed rename")
+
+	// the resumed run retries the failed rename only
+	f.fail = nil
+	report, err = cs.RenameByRule(context.Background(), "bucket", flatToPrefixes, WithRenameCheckpointer(cp, 1))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), report.Renamed)
+	require.Equal(t, int64(1), report.Listed)
+	require.True(t, cp.state(t).Done)
+	require.Equal(t, []string{"p/1", "p/2", "p/3", "p/4"}, f.storedNames("bucket"))
+}
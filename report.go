@@ -0,0 +1,185 @@
+package cloudstorage
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// EXIT_OK is the exit code of a run whose every action succeeded, or that had nothing to do
+	EXIT_OK = 0
+	// EXIT_FAILURE is the exit code of a run where no action succeeded
+	EXIT_FAILURE = 1
+	// EXIT_PARTIAL_FAILURE is the exit code of a run where some actions failed & others succeeded
+	EXIT_PARTIAL_FAILURE = 2
+)
+
+// Report is implemented by the reports of bulk operations, for scripts & CI pipelines.
+// Reports marshal to JSON with snake case field names, RFC 3339 times & error messages as strings,
+// fields are only ever added, never renamed or removed.
+type Report interface {
+	// Summary returns a one line human readable summary
+	Summary() string
+	// ExitCode returns EXIT_OK, EXIT_PARTIAL_FAILURE or EXIT_FAILURE by the reported actions' outcomes
+	ExitCode() int
+}
+
+// ExitCode returns the exit code of a bulk operation's report & error, EXIT_FAILURE when the operation
+// failed without reporting a failed action, e.g. on a listing failure or cancellation
+func ExitCode(r Report, err error) int {
+	code := r.ExitCode()
+	if err != nil && code == EXIT_OK {
+		return EXIT_FAILURE
+	}
+	return code
+}
+
+// exitCode returns the exit code of given succeeded & failed action counts
+func exitCode(succeeded, failed int64) int {
+	switch {
+	case failed == 0:
+		return EXIT_OK
+	case succeeded == 0:
+		return EXIT_FAILURE
+	default:
+		return EXIT_PARTIAL_FAILURE
+	}
+}
+
+// dryRunPrefix prefixes summaries of dry runs
+func dryRunPrefix(dryRun bool) string {
+	if dryRun {
+		return "dry run: "
+	}
+	return ""
+}
+
+// formatSince formats a since time of a report, zero for a full run
+func formatSince(since time.Time) string {
+	if since.IsZero() {
+		return "the beginning"
+	}
+	return since.UTC().Format(time.RFC3339)
+}
+
+// Summary returns the deleted, skipped, changed & failed counts
+func (r DeleteReport) Summary() string {
+	return fmt.Sprintf("deleted %d, skipped %d, changed %d, failed %d, %d bytes freed", r.Deleted, r.Skipped, r.Changed, r.Failed, r.BytesFreed)
+}
+
+// ExitCode returns the exit code of the deletes
+func (r DeleteReport) ExitCode() int {
+	return exitCode(r.Deleted, r.Failed)
+}
+
+// Summary returns the scanned, orphan & deleted counts
+func (r GCReport) Summary() string {
+	return fmt.Sprintf("%sscanned %d, referenced %d, orphans %d, %s", dryRunPrefix(r.DryRun), r.Scanned, r.Referenced, len(r.Orphans), r.DeleteReport.Summary())
+}
+
+// Summary returns the row counts per outcome
+func (r ManifestReport) Summary() string {
+	return fmt.Sprintf("rows %d, ok %d, missing %d, checksum mismatch %d, failed %d, malformed %d", r.Rows,
+		r.Counts[ManifestOK], r.Counts[ManifestMissing], r.Counts[ManifestMismatch], r.Counts[ManifestFailed], r.Counts[ManifestMalformed])
+}
+
+// ExitCode returns the exit code of the rows
+func (r ManifestReport) ExitCode() int {
+	return exitCode(r.Counts[ManifestOK], r.Failures())
+}
+
+// Summary returns whether the set was published & the failed objects count
+func (r PublishReport) Summary() string {
+	failed := 0
+	for _, item := range r.Items {
+		if item.Err != nil {
+			failed++
+		}
+	}
+	if r.Published {
+		return fmt.Sprintf("published %d objects & manifest %s", len(r.Items), r.Manifest.Object)
+	}
+	return fmt.Sprintf("not published, %d of %d objects failed", failed, len(r.Items))
+}
+
+// ExitCode returns the exit code of the publish, a set is published as a whole or not at all
+func (r PublishReport) ExitCode() int {
+	if r.Published {
+		return EXIT_OK
+	}
+	return EXIT_FAILURE
+}
+
+// Summary returns the action counts
+func (r ReconcileReport) Summary() string {
+	counts := map[ReconcileActionKind]int{}
+	for _, a := range r.Actions {
+		counts[a.Action]++
+	}
+	return fmt.Sprintf("%ssource %d, dest %d, in sync %d, filtered %d, copy %d, update %d, delete %d, failed %d", dryRunPrefix(r.DryRun),
+		r.SourceObjects, r.DestObjects, r.InSync, r.Filtered, counts[ReconcileCopy], counts[ReconcileUpdate], counts[ReconcileDelete], r.Failures())
+}
+
+// ExitCode returns the exit code of the actions
+func (r ReconcileReport) ExitCode() int {
+	failed := int64(r.Failures())
+	return exitCode(int64(len(r.Actions))-failed, failed)
+}
+
+// Summary returns the action counts
+func (r RestoreReport) Summary() string {
+	counts := map[RestoreActionKind]int{}
+	for _, a := range r.Actions {
+		counts[a.Action]++
+	}
+	return fmt.Sprintf("%scopy %d, skip %d, delete %d, failed %d", dryRunPrefix(r.DryRun), counts[RestoreCopy], counts[RestoreSkip], counts[RestoreDelete], r.Failures())
+}
+
+// ExitCode returns the exit code of the actions
+func (r RestoreReport) ExitCode() int {
+	failed := int64(r.Failures())
+	return exitCode(int64(len(r.Actions))-failed, failed)
+}
+
+// Summary returns the copied, deleted & failed counts
+func (r BackupReport) Summary() string {
+	return fmt.Sprintf("since %s, unchanged %d, copied %d (%d bytes), deleted %d, failed %d",
+		formatSince(r.Since), r.Unchanged, r.Copied, r.CopiedBytes, r.Deleted, r.Failed)
+}
+
+// ExitCode returns the exit code of the copies & deletes
+func (r BackupReport) ExitCode() int {
+	return exitCode(r.Copied+r.Deleted, r.Failed)
+}
+
+// Summary returns the renamed, collision & failed counts
+func (r RenameReport) Summary() string {
+	return fmt.Sprintf("%slisted %d, skipped %d, renamed %d (%d bytes), collisions %d, failed %d", dryRunPrefix(r.DryRun),
+		r.Listed, r.Skipped, r.Renamed, r.Bytes, len(r.Collisions), r.Failed)
+}
+
+// ExitCode returns the exit code of the renames, collisions are failures
+func (r RenameReport) ExitCode() int {
+	succeeded := r.Renamed
+	if r.DryRun {
+		succeeded = int64(len(r.Actions)) - r.Failed
+	}
+	return exitCode(succeeded, r.Failed+int64(len(r.Collisions)))
+}
+
+// Summary returns the listed, skipped, processed & failed counts
+func (r ProcessReport) Summary() string {
+	failed := 0
+	if r.Failed != "" {
+		failed = 1
+	}
+	return fmt.Sprintf("listed %d, skipped %d, processed %d (%d bytes), failed %d", r.Listed, r.Skipped, r.Processed, r.Bytes, failed)
+}
+
+// ExitCode returns the exit code of the run, which stops at its failed object
+func (r ProcessReport) ExitCode() int {
+	if r.Failed == "" {
+		return EXIT_OK
+	}
+	return exitCode(r.Processed, 1)
+}
@@ -0,0 +1,117 @@
+package cloudstorage
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden report files")
+
+var (
+	_ Report = DeleteReport{}
+	_ Report = GCReport{}
+	_ Report = ManifestReport{}
+	_ Report = PublishReport{}
+	_ Report = ReconcileReport{}
+	_ Report = RestoreReport{}
+	_ Report = BackupReport{}
+	_ Report = RenameReport{}
+	_ Report = ProcessReport{}
+)
+
+// goldenReports are fixed reports of every kind, their JSON locked by testdata/reports
+func goldenReports() map[string]Report {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	failed := errors.New("forbidden")
+	reconcile := ReconcileReport{
+		Source: BucketPrefix{Bucket: "src", Prefix: "data"}, Dest: BucketPrefix{Bucket: "dst", Prefix: "data"},
+		SourceObjects: 3, DestObjects: 2, InSync: 1,
+		Actions: []ReconcileAction{
+			{Action: ReconcileCopy, Name: "a.txt", Generation: 1, Size: 10},
+			{Action: ReconcileDelete, Name: "b.txt", Generation: 2, Size: 20, Err: failed, Error: failed.Error()},
+		},
+	}
+	return map[string]Report{
+		"delete": DeleteReport{Deleted: 3, Skipped: 1, Failed: 1, BytesFreed: 300},
+		"gc": GCReport{
+			DeleteReport: DeleteReport{Deleted: 1, BytesFreed: 5},
+			Scanned:      4, Referenced: 1,
+			Orphans: []TempObject{{Bucket: "bucket", Name: ".staging/id/a.txt", Prefix: ".staging", ID: "id", Target: "a.txt", Generation: 7, Size: 5, Updated: at}},
+		},
+		"manifest": ManifestReport{Rows: 3, Counts: map[ManifestOutcome]int64{ManifestOK: 2, ManifestMissing: 1}},
+		"publish": PublishReport{
+			Items:    []PublishedObject{{Bucket: "bucket", Object: "data/01.json", Generation: 3, Size: 2, CRC32C: 42}, {Bucket: "bucket", Object: "data/02.json", Err: failed, Error: failed.Error()}},
+			Manifest: PublishedObject{Bucket: "bucket", Object: "data/_MANIFEST"},
+		},
+		"reconcile": reconcile,
+		"restore": RestoreReport{Actions: []RestoreAction{
+			{Action: RestoreCopy, Source: "snap/a.txt", Generation: 5, Dest: "live/a.txt"},
+			{Action: RestoreSkip, Source: "snap/b.txt", Generation: 6, Dest: "live/b.txt"},
+		}},
+		"backup": BackupReport{
+			Source: reconcile.Source, Dest: reconcile.Dest, Since: at.Add(-time.Hour), FromMarker: true, HighWater: at,
+			SourceObjects: 3, DestObjects: 2, Unchanged: 1, Copied: 1, CopiedBytes: 10, Failed: 1,
+			Actions: reconcile.Actions,
+		},
+		"rename": RenameReport{
+			Bucket: "bucket", Listed: 3, Skipped: 1, Renamed: 1, Bytes: 6,
+			Actions:    []RenameAction{{OldName: "t__a.txt", NewName: "t/a.txt", Generation: 1, Size: 6}},
+			Collisions: []RenameCollision{{OldName: "t__b.txt", NewName: "t/a.txt", With: "t__a.txt"}},
+		},
+		"process": ProcessReport{
+			Bucket: "bucket", Prefix: "incoming/", Listed: 4, Skipped: 1, Processed: 1, Bytes: 12,
+			Last: "incoming/01.csv", Failed: "incoming/02.csv", Error: failed.Error(),
+		},
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	for name, r := range goldenReports() {
+		got, err := json.MarshalIndent(r, "", "  ")
+		require.NoError(t, err)
+		got = append(got, '\n')
+		path := filepath.Join("testdata", "reports", name+".json")
+		if *updateGolden {
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			require.NoError(t, os.WriteFile(path, got, 0o644))
+		}
+		want, err := os.ReadFile(path)
+		require.NoError(t, err, "run go test -run TestReportJSON -update to create %s", path)
+		require.Equal(t, string(want), string(got), "%s report JSON changed, fields may only be added", name)
+	}
+}
+
+func TestReportExitCodes(t *testing.T) {
+	reports := goldenReports()
+	codes := map[string]int{
+		"delete": EXIT_PARTIAL_FAILURE, "gc": EXIT_OK, "manifest": EXIT_PARTIAL_FAILURE, "publish": EXIT_FAILURE,
+		"reconcile": EXIT_PARTIAL_FAILURE, "restore": EXIT_OK, "backup": EXIT_PARTIAL_FAILURE, "rename": EXIT_PARTIAL_FAILURE,
+		"process": EXIT_PARTIAL_FAILURE,
+	}
+	for name, code := range codes {
+		require.Equal(t, code, reports[name].ExitCode(), name)
+	}
+	require.Equal(t, EXIT_FAILURE, DeleteReport{Failed: 2}.ExitCode())
+	require.Equal(t, EXIT_OK, ReconcileReport{}.ExitCode())
+
+	// run errors without failed actions fail the run
+	require.Equal(t, EXIT_FAILURE, ExitCode(ReconcileReport{}, errors.New("listing failed")))
+	require.Equal(t, EXIT_PARTIAL_FAILURE, ExitCode(reports["reconcile"], errors.New("forbidden")))
+	require.Equal(t, EXIT_OK, ExitCode(reports["restore"], nil))
+}
+
+func TestReportSummaries(t *testing.T) {
+	reports := goldenReports()
+	require.Equal(t, "deleted 3, skipped 1, changed 0, failed 1, 300 bytes freed", reports["delete"].Summary())
+	require.Equal(t, "source 3, dest 2, in sync 1, filtered 0, copy 1, update 0, delete 1, failed 1", reports["reconcile"].Summary())
+	require.Equal(t, "since 2024-05-01T11:30:00Z, unchanged 1, copied 1 (10 bytes), deleted 0, failed 1", reports["backup"].Summary())
+	require.Equal(t, "not published, 1 of 2 objects failed", reports["publish"].Summary())
+	require.Equal(t, "dry run: copy 0, skip 0, delete 0, failed 0", RestoreReport{DryRun: true}.Summary())
+}
@@ -0,0 +1,66 @@
+package cloudstorage
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/comfforts/errors"
+)
+
+const (
+	ERROR_EMPTY_REQUEST       string = "empty cloud file request"
+	ERROR_BUCKET_WIDE_REQUEST string = "request names no file or path, bucket wide requests need NewBucketRequest"
+)
+
+var (
+	ErrEmptyRequest      = errors.NewAppError(ERROR_EMPTY_REQUEST)
+	ErrBucketWideRequest = errors.NewAppError(ERROR_BUCKET_WIDE_REQUEST)
+)
+
+// IsZero reports whether the request is the zero value, e.g. a missed map lookup,
+// every operation fails zero requests with ErrEmptyRequest
+func (cfr CloudFileRequest) IsZero() bool {
+	return reflect.ValueOf(cfr).IsZero()
+}
+
+// NewBucketRequest takes bucket name & options, returns the request of every object in the bucket,
+// under the scope prefix of scoped clients & contexts. Listings & deletes of requests naming
+// no file or path fail with ErrBucketWideRequest unless built with it. The bucket name can be
+// empty with WithRoutingKey.
+func NewBucketRequest(bucketName string, opts ...CloudFileRequestOption) (CloudFileRequest, error) {
+	cfr := CloudFileRequest{bucket: bucketName}
+	for _, opt := range opts {
+		opt(&cfr)
+	}
+	if cfr.bucket == "" && cfr.routingKey == "" {
+		return CloudFileRequest{}, ErrBucketNameMissing
+	}
+	cfr.wholeBucket = true
+	return cfr, nil
+}
+
+// bucketWide reports whether request names neither file nor path, addressing the whole bucket
+func (cfr CloudFileRequest) bucketWide() bool {
+	return NormalizeName(cfr.file) == "" && NormalizeName(cfr.path) == ""
+}
+
+// request returns caller's request scoped, failing zero requests with ErrEmptyRequest
+func (cs *cloudStorageClient) request(ctx context.Context, cfr CloudFileRequest) (CloudFileRequest, error) {
+	if cfr.IsZero() {
+		return CloudFileRequest{}, ErrEmptyRequest
+	}
+	return cs.scoped(ctx, cfr)
+}
+
+// prefixRequest returns caller's request of a listing or delete scoped like request,
+// requests naming no file or path fail with ErrBucketWideRequest unless built with NewBucketRequest
+func (cs *cloudStorageClient) prefixRequest(ctx context.Context, cfr CloudFileRequest) (CloudFileRequest, error) {
+	scoped, err := cs.request(ctx, cfr)
+	if err != nil {
+		return CloudFileRequest{}, err
+	}
+	if !cfr.scoped && !cfr.wholeBucket && cfr.bucketWide() {
+		return CloudFileRequest{}, ErrBucketWideRequest
+	}
+	return scoped, nil
+}
@@ -0,0 +1,147 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudFileRequestIsZero(t *testing.T) {
+	require.True(t, CloudFileRequest{}.IsZero())
+	requests := map[string]CloudFileRequest{}
+	require.True(t, requests["missing"].IsZero())
+
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+	require.False(t, cfr.IsZero())
+	require.False(t, CloudFileRequest{modTime: time.Unix(1, 0)}.IsZero())
+	bucket, err := NewBucketRequest("bucket")
+	require.NoError(t, err)
+	require.False(t, bucket.IsZero())
+}
+
+func TestNewBucketRequest(t *testing.T) {
+	cfr, err := NewBucketRequest("bucket", WithUserProject("billing"))
+	require.NoError(t, err)
+	require.Equal(t, CloudFileRequest{bucket: "bucket", userProject: "billing", wholeBucket: true}, cfr)
+	_, err = NewBucketRequest("")
+	require.ErrorIs(t, err, ErrBucketNameMissing)
+	_, err = NewBucketRequest("", WithRoutingKey("tenant-a"))
+	require.NoError(t, err)
+
+	// object requests name a file or path
+	_, err = NewCloudFileRequest("bucket", "", "", 0)
+	require.ErrorIs(t, err, ErrBucketWideRequest)
+	_, err = NewCloudFileRequest("bucket", "", "/", 0)
+	require.ErrorIs(t, err, ErrBucketWideRequest)
+	_, err = NewCloudFileRequest("bucket", "", "path", 0)
+	require.NoError(t, err)
+}
+
+// zeroValueArgs returns the arguments of a call of given method with zero requests & harmless other values
+func zeroValueArgs(m reflect.Method) []reflect.Value {
+	args := []reflect.Value{}
+	for i := 0; i < m.Type.NumIn(); i++ {
+		in := m.Type.In(i)
+		if m.Type.IsVariadic() && i == m.Type.NumIn()-1 {
+			break
+		}
+		switch {
+		case in == reflect.TypeOf((*context.Context)(nil)).Elem():
+			args = append(args, reflect.ValueOf(context.Background()))
+		case in == reflect.TypeOf((*io.Reader)(nil)).Elem():
+			args = append(args, reflect.ValueOf(io.Reader(strings.NewReader("x"))))
+		case in == reflect.TypeOf((*io.Writer)(nil)).Elem():
+			args = append(args, reflect.ValueOf(io.Writer(&bytes.Buffer{})))
+		case in == reflect.TypeOf([]CloudFileRequest{}):
+			args = append(args, reflect.ValueOf([]CloudFileRequest{{}}))
+		case in == reflect.TypeOf([]byte{}):
+			args = append(args, reflect.ValueOf([]byte("x")))
+		case in.Kind() == reflect.Func:
+			args = append(args, reflect.MakeFunc(in, func([]reflect.Value) []reflect.Value {
+				out := []reflect.Value{}
+				for j := 0; j < in.NumOut(); j++ {
+					out = append(out, reflect.Zero(in.Out(j)))
+				}
+				return out
+			}))
+		default:
+			args = append(args, reflect.Zero(in))
+		}
+	}
+	return args
+}
+
+func TestZeroValueRequests(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/file.json", []byte("{}"), nil)
+	cs := newFakeClient(t, f)
+	cfrType := reflect.TypeOf(CloudFileRequest{})
+	errType := reflect.TypeOf((*error)(nil)).Elem()
+
+	api := reflect.TypeOf((*CloudStorage)(nil)).Elem()
+	checked := 0
+	for i := 0; i < api.NumMethod(); i++ {
+		m := api.Method(i)
+		takesRequest := false
+		for j := 0; j < m.Type.NumIn(); j++ {
+			if in := m.Type.In(j); in == cfrType || in == reflect.SliceOf(cfrType) {
+				takesRequest = true
+			}
+		}
+		if !takesRequest || m.Name == "AuditFailures" {
+			continue
+		}
+		out := reflect.ValueOf(CloudStorage(cs)).MethodByName(m.Name).Call(zeroValueArgs(m))
+		last := out[len(out)-1]
+		require.Equal(t, errType, last.Type(), m.Name)
+		err, _ := last.Interface().(error)
+		require.ErrorIs(t, err, ErrEmptyRequest, m.Name)
+		checked++
+	}
+	require.Greater(t, checked, 30)
+	require.Equal(t, []string{"path/file.json"}, f.storedNames("bucket"), "zero requests never reach the service")
+}
+
+func TestBucketWideRequests(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/file.json", []byte("{}"), nil)
+	f.put("bucket", "other/file.json", []byte("{}"), nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	halfFilled := CloudFileRequest{bucket: "bucket"}
+
+	// listings & deletes of half filled object requests never reach the whole bucket
+	_, err := cs.ListObjects(ctx, halfFilled)
+	require.ErrorIs(t, err, ErrBucketWideRequest)
+	_, err = cs.ListObjectsInfo(ctx, CloudFileRequest{bucket: "bucket", path: "/"})
+	require.ErrorIs(t, err, ErrBucketWideRequest)
+	_, err = cs.ListDir(ctx, halfFilled)
+	require.ErrorIs(t, err, ErrBucketWideRequest)
+	_, err = cs.ExportInventory(ctx, halfFilled, &bytes.Buffer{}, InventoryCSV)
+	require.ErrorIs(t, err, ErrBucketWideRequest)
+	require.ErrorIs(t, cs.DeleteObjects(ctx, halfFilled), ErrBucketWideRequest)
+	require.Equal(t, []string{"other/file.json", "path/file.json"}, f.storedNames("bucket"))
+
+	// bucket requests do
+	all, err := NewBucketRequest("bucket")
+	require.NoError(t, err)
+	names, err := cs.ListObjects(ctx, all)
+	require.NoError(t, err)
+	require.Equal(t, []string{"other/file.json", "path/file.json"}, names)
+	prefix, err := NewCloudFileRequest("bucket", "", "path", 0)
+	require.NoError(t, err)
+	infos, err := cs.ListObjectsInfo(ctx, prefix)
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	report, err := cs.DeleteObjectsWithReport(ctx, all)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), report.Deleted)
+	require.Empty(t, f.storedNames("bucket"))
+}
@@ -0,0 +1,35 @@
+package cloudstorage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of given context carrying the request ID,
+// storage operations started with the returned context log & report the ID.
+// The ID isn't sent to the service, it correlates through logs, errors & results,
+// set a header with WithContextHeader or WithCustomHeader to send it as well
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by given context, if any
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// resolveRequestID picks the request ID for an operation,
+// request option wins over context value, a new UUID is generated when neither is set
+func resolveRequestID(ctx context.Context, cfr CloudFileRequest) string {
+	if cfr.requestID != "" {
+		return cfr.requestID
+	}
+	if id, ok := RequestIDFromContext(ctx); ok {
+		return id
+	}
+	return uuid.NewString()
+}
@@ -0,0 +1,162 @@
+package cloudstorage
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	ERROR_INVALID_RETENTION_PERIOD   string = "invalid bucket retention period"
+	ERROR_RETENTION_POLICY_LOCKED    string = "bucket retention policy is locked"
+	ERROR_RETENTION_LOCK_UNCONFIRMED string = "retention policy lock not confirmed, the confirm token must be the bucket name"
+	ERROR_NO_RETENTION_POLICY        string = "bucket has no retention policy"
+	ERROR_UPDATING_RETENTION_POLICY  string = "error updating bucket retention policy"
+	ERROR_LOCKING_RETENTION_POLICY   string = "error locking bucket retention policy"
+)
+
+var (
+	ErrInvalidRetentionPeriod   = errors.NewAppError(ERROR_INVALID_RETENTION_PERIOD)
+	ErrRetentionPolicyLocked    = errors.NewAppError(ERROR_RETENTION_POLICY_LOCKED)
+	ErrRetentionLockUnconfirmed = errors.NewAppError(ERROR_RETENTION_LOCK_UNCONFIRMED)
+	ErrNoRetentionPolicy        = errors.NewAppError(ERROR_NO_RETENTION_POLICY)
+)
+
+// MAX_RETENTION_PERIOD is the longest bucket retention period the service allows, 100 years
+const MAX_RETENTION_PERIOD = 3155760000 * time.Second
+
+// SetBucketRetentionPolicy sets the retention period of given bucket, the minimum age of its objects
+// before they can be deleted or replaced, in whole seconds, zero removes the policy. Locked policies
+// can only be lengthened, shortening or removing one fails with ErrRetentionPolicyLocked, checked
+// before the update & classified from the service's rejection. The update is conditional on the
+// metageneration read.
+func (cs *cloudStorageClient) SetBucketRetentionPolicy(ctx context.Context, bucket string, period time.Duration) error {
+	if err := cs.mutation(); err != nil {
+		return err
+	}
+	if period < 0 || period > MAX_RETENTION_PERIOD || period%time.Second != 0 {
+		return ErrInvalidRetentionPeriod
+	}
+	if err := cs.bucketMutation(bucket); err != nil {
+		return err
+	}
+	cfr, err := cs.scopedBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	op := cs.startOperation(ctx, "SetBucketRetentionPolicy", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	bkt := cs.bucketHandle(cfr)
+	attrs, err := bkt.Attrs(ctx)
+	if err != nil {
+		op.logger.Error(ERROR_GETTING_BUCKET_ATTRS, zap.Error(err), zap.String("bucket", cfr.bucket))
+		return op.wrapError(err, "%s %s", ERROR_GETTING_BUCKET_ATTRS, cfr.bucket)
+	}
+	if rp := attrs.RetentionPolicy; rp != nil && rp.IsLocked && period < rp.RetentionPeriod {
+		op.logger.Error(ERROR_RETENTION_POLICY_LOCKED, zap.String("bucket", cfr.bucket), zap.Duration("locked", rp.RetentionPeriod), zap.Duration("period", period))
+		return op.wrapError(ErrRetentionPolicyLocked, "%s %s, %s can't be shortened to %s", ERROR_RETENTION_POLICY_LOCKED, cfr.bucket, rp.RetentionPeriod, period)
+	}
+
+	update := storage.BucketAttrsToUpdate{RetentionPolicy: &storage.RetentionPolicy{RetentionPeriod: period}}
+	if _, err := bkt.If(storage.BucketConditions{MetagenerationMatch: attrs.MetaGeneration}).Update(ctx, update); err != nil {
+		op.logger.Error(ERROR_UPDATING_RETENTION_POLICY, zap.Error(err), zap.String("bucket", cfr.bucket), zap.Duration("period", period))
+		return op.wrapError(err, "%s %s", ERROR_UPDATING_RETENTION_POLICY, cfr.bucket)
+	}
+	op.logger.Info("bucket retention policy updated", zap.String("bucket", cfr.bucket), zap.Duration("period", period))
+	return nil
+}
+
+// LockBucketRetentionPolicy locks the retention policy of given bucket, irreversibly: a locked policy
+// can't be removed or shortened, nor the bucket deleted while it holds objects. The confirm token must
+// be the bucket's name, the scope's bucket when unnamed, or the lock fails with ErrRetentionLockUnconfirmed.
+// Buckets without policy fail with ErrNoRetentionPolicy, locked ones are left alone. The lock is
+// conditional on the metageneration read, so the policy locked is the one looked up.
+func (cs *cloudStorageClient) LockBucketRetentionPolicy(ctx context.Context, bucket, confirm string) error {
+	if err := cs.mutation(); err != nil {
+		return err
+	}
+	if err := cs.bucketMutation(bucket); err != nil {
+		return err
+	}
+	cfr, err := cs.scopedBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	if confirm != cfr.bucket {
+		return ErrRetentionLockUnconfirmed
+	}
+	op := cs.startOperation(ctx, "LockBucketRetentionPolicy", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	bkt := cs.bucketHandle(cfr)
+	attrs, err := bkt.Attrs(ctx)
+	if err != nil {
+		op.logger.Error(ERROR_GETTING_BUCKET_ATTRS, zap.Error(err), zap.String("bucket", cfr.bucket))
+		return op.wrapError(err, "%s %s", ERROR_GETTING_BUCKET_ATTRS, cfr.bucket)
+	}
+	rp := attrs.RetentionPolicy
+	if rp == nil || rp.RetentionPeriod == 0 {
+		op.logger.Error(ERROR_NO_RETENTION_POLICY, zap.String("bucket", cfr.bucket))
+		return op.wrapError(ErrNoRetentionPolicy, "%s %s", ERROR_NO_RETENTION_POLICY, cfr.bucket)
+	}
+	if rp.IsLocked {
+		op.logger.Debug("bucket retention policy already locked", zap.String("bucket", cfr.bucket), zap.Duration("period", rp.RetentionPeriod))
+		return nil
+	}
+
+	if err := bkt.If(storage.BucketConditions{MetagenerationMatch: attrs.MetaGeneration}).LockRetentionPolicy(ctx); err != nil {
+		op.logger.Error(ERROR_LOCKING_RETENTION_POLICY, zap.Error(err), zap.String("bucket", cfr.bucket), zap.Duration("period", rp.RetentionPeriod))
+		return op.wrapError(err, "%s %s", ERROR_LOCKING_RETENTION_POLICY, cfr.bucket)
+	}
+	op.logger.Info("bucket retention policy locked", zap.String("bucket", cfr.bucket), zap.Duration("period", rp.RetentionPeriod))
+	return nil
+}
+
+// SetDefaultEventBasedHold sets whether objects created in given bucket are placed under event-based hold,
+// held objects can't be deleted or replaced until released, their retention period counted from the release
+func (cs *cloudStorageClient) SetDefaultEventBasedHold(ctx context.Context, bucket string, enabled bool) error {
+	if err := cs.mutation(); err != nil {
+		return err
+	}
+	if err := cs.bucketMutation(bucket); err != nil {
+		return err
+	}
+	cfr, err := cs.scopedBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	op := cs.startOperation(ctx, "SetDefaultEventBasedHold", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	if _, err := cs.bucketHandle(cfr).Update(ctx, storage.BucketAttrsToUpdate{DefaultEventBasedHold: enabled}); err != nil {
+		op.logger.Error(ERROR_UPDATING_BUCKET, zap.Error(err), zap.String("bucket", cfr.bucket), zap.Bool("defaultEventBasedHold", enabled))
+		return op.wrapError(err, "%s %s", ERROR_UPDATING_BUCKET, cfr.bucket)
+	}
+	op.logger.Info("bucket default event based hold updated", zap.String("bucket", cfr.bucket), zap.Bool("defaultEventBasedHold", enabled))
+	return nil
+}
+
+// isRetentionLocked reports whether given failure is a locked retention policy's, the service rejects
+// reducing or removing one as a bad or forbidden request naming the lock
+func isRetentionLocked(err error) bool {
+	if stderrors.Is(err, ErrRetentionPolicyLocked) {
+		return true
+	}
+	var gErr *googleapi.Error
+	if !stderrors.As(err, &gErr) || (gErr.Code != http.StatusBadRequest && gErr.Code != http.StatusForbidden) {
+		return false
+	}
+	msg := strings.ToLower(gErr.Message)
+	return strings.Contains(msg, "locked") && strings.Contains(msg, "retention")
+}
@@ -0,0 +1,276 @@
+package cloudstorage
+
+import (
+	"context"
+	stderrors "errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"go.uber.org/zap"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	DEFAULT_RETRY_MAX_ATTEMPTS    = 4
+	DEFAULT_RETRY_INITIAL_BACKOFF = time.Second
+	DEFAULT_RETRY_MAX_BACKOFF     = 30 * time.Second
+	DEFAULT_RETRY_MULTIPLIER      = 2.0
+)
+
+// RetryPolicy configures the client's retries of object metadata lookups & download reads,
+// replacing the storage client's own retries for those calls. Server retry hints,
+// RetryInfo error details or the Retry-After header, are preferred over the computed backoff.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first, defaults to DEFAULT_RETRY_MAX_ATTEMPTS
+	MaxAttempts int `json:"max_attempts"`
+	// InitialBackoff is the computed backoff ceiling of the first retry, defaults to DEFAULT_RETRY_INITIAL_BACKOFF
+	InitialBackoff time.Duration `json:"initial_backoff"`
+	// MaxBackoff caps computed & server hinted waits, defaults to DEFAULT_RETRY_MAX_BACKOFF
+	MaxBackoff time.Duration `json:"max_backoff"`
+	// Multiplier grows the backoff ceiling per retry, defaults to DEFAULT_RETRY_MULTIPLIER
+	Multiplier float64 `json:"multiplier"`
+}
+
+// normalize fills defaults
+func (p RetryPolicy) normalize() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DEFAULT_RETRY_MAX_ATTEMPTS
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DEFAULT_RETRY_INITIAL_BACKOFF
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DEFAULT_RETRY_MAX_BACKOFF
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = DEFAULT_RETRY_MULTIPLIER
+	}
+	return p
+}
+
+// Backoff returns a backoff of the normalized policy, with full jitter
+func (p RetryPolicy) Backoff() *Backoff {
+	p = p.normalize()
+	return &Backoff{Initial: p.InitialBackoff, Max: p.MaxBackoff, Multiplier: p.Multiplier, Jitter: 1}
+}
+
+// Backoff computes exponentially growing waits between retries, the wait ceiling starts at Initial,
+// grows by Multiplier per wait & is capped at Max. Jitter, between 0 & 1, is the fraction of the ceiling
+// randomized, waits are random between (1 - Jitter) * ceiling & the ceiling. Not safe for concurrent use.
+type Backoff struct {
+	// Initial is the first wait's ceiling, defaults to DEFAULT_RETRY_INITIAL_BACKOFF
+	Initial time.Duration
+	// Max caps the ceiling, defaults to DEFAULT_RETRY_MAX_BACKOFF
+	Max time.Duration
+	// Multiplier grows the ceiling per wait, defaults to DEFAULT_RETRY_MULTIPLIER
+	Multiplier float64
+	// Jitter is the randomized fraction of the ceiling, zero for no jitter, one for full jitter
+	Jitter float64
+	waits  int
+}
+
+// Next returns the next wait, at least one nanosecond
+func (b *Backoff) Next() time.Duration {
+	b.waits++
+	return b.wait(b.waits)
+}
+
+// Reset restarts the waits at the initial ceiling
+func (b *Backoff) Reset() {
+	b.waits = 0
+}
+
+// wait returns the wait before given retry, starting at 1
+func (b *Backoff) wait(retry int) time.Duration {
+	initial, max, multiplier := b.Initial, b.Max, b.Multiplier
+	if initial <= 0 {
+		initial = DEFAULT_RETRY_INITIAL_BACKOFF
+	}
+	if max <= 0 {
+		max = DEFAULT_RETRY_MAX_BACKOFF
+	}
+	if multiplier < 1 {
+		multiplier = DEFAULT_RETRY_MULTIPLIER
+	}
+	ceiling := float64(initial) * math.Pow(multiplier, float64(retry-1))
+	if ceiling > float64(max) {
+		ceiling = float64(max)
+	}
+	jitter := math.Min(math.Max(b.Jitter, 0), 1)
+	wait := int64(ceiling)
+	if span := int64(ceiling * jitter); span > 0 {
+		wait -= rand.Int63n(span + 1)
+	}
+	if wait < 1 {
+		wait = 1
+	}
+	return time.Duration(wait)
+}
+
+// IsRetryable reports whether given error is transient, retried by the storage client & Do,
+// e.g. rate limiting, server errors & connection resets
+func IsRetryable(err error) bool {
+	return err != nil && storage.ShouldRetry(err)
+}
+
+// RetryMetrics describe one retry wait of an operation
+type RetryMetrics struct {
+	Op        string
+	Bucket    string
+	Object    string
+	RequestID string
+	// Attempt is the failed attempt, starting at 1
+	Attempt int
+	// Wait is the chosen wait before the next attempt
+	Wait time.Duration
+	// Hinted is set when Wait is the server's retry hint, capped at the policy's MaxBackoff
+	Hinted bool
+	// Err is the failed attempt's error
+	Err error
+}
+
+// RetryRecorder receives retry waits, implemented by metrics recorders interested in throttle pressure
+type RetryRecorder interface {
+	// RecordRetry is called before every retry wait
+	RecordRetry(RetryMetrics)
+}
+
+// retryHint returns the server provided wait of given error, from RetryInfo error details,
+// HTTP & gRPC, or the Retry-After header, in seconds or as HTTP date. False when there's none,
+// or it's malformed.
+func retryHint(err error, now time.Time) (time.Duration, bool) {
+	if ae, ok := apierror.FromError(err); ok {
+		if info := ae.Details().RetryInfo; info != nil && info.GetRetryDelay() != nil {
+			if d := info.GetRetryDelay().AsDuration(); d >= 0 {
+				return d, true
+			}
+		}
+	}
+	var gerr *googleapi.Error
+	if !stderrors.As(err, &gerr) {
+		return 0, false
+	}
+	value := strings.TrimSpace(gerr.Header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := at.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// retrying returns given handle without the storage client's own retries, when the client retries itself
+func (cs *cloudStorageClient) retrying(obj *storage.ObjectHandle) *storage.ObjectHandle {
+	if cs.config.Retry == nil {
+		return obj
+	}
+	return obj.Retryer(storage.WithPolicy(storage.RetryNever))
+}
+
+// sleep waits given duration or until the context is done
+func (cs *cloudStorageClient) sleep(ctx context.Context, d time.Duration) error {
+	if cs.sleeper != nil {
+		return cs.sleeper(ctx, d)
+	}
+	return sleepContext(ctx, d)
+}
+
+// Do runs given call with given retry policy, retrying while IsRetryable reports its error transient,
+// the same retries the client makes with a configured policy. Server retry hints are preferred
+// over the backoff, waits that would end past the context deadline aren't started & the call's last error
+// is returned, a context done during a wait returns the context error. Deadlines are compared
+// with the wall clock, there's no client clock.
+func Do(ctx context.Context, policy RetryPolicy, call func() error) error {
+	return retryLoop(ctx, policy, realClock{}.Now, sleepContext, call, nil)
+}
+
+// sleepContext waits given duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryLoop runs given call with given policy, waiting with given sleep. Before each wait
+// onRetry, when set, is told the failed attempt & the chosen wait, or that the deadline ends first.
+func retryLoop(ctx context.Context, policy RetryPolicy, now func() time.Time, sleep func(ctx context.Context, d time.Duration) error, call func() error, onRetry func(attempt int, wait time.Duration, hinted, pastDeadline bool, err error)) error {
+	policy = policy.normalize()
+	backoff := policy.Backoff()
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || attempt >= policy.MaxAttempts || !IsRetryable(err) {
+			return err
+		}
+
+		wait, hinted := retryHint(err, now())
+		if hinted {
+			if wait > policy.MaxBackoff {
+				wait = policy.MaxBackoff
+			}
+		} else {
+			wait = backoff.wait(attempt)
+		}
+		pastDeadline := false
+		if deadline, ok := ctx.Deadline(); ok && deadline.Sub(now()) < wait {
+			pastDeadline = true
+		}
+		if onRetry != nil {
+			onRetry(attempt, wait, hinted, pastDeadline, err)
+		}
+		if pastDeadline {
+			return err
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// retry runs given call with the client's retry policy, once when none is configured.
+// Waits that would end past the context deadline aren't started, the last error is returned.
+func (op *operation) retry(ctx context.Context, call func() error) error {
+	if op.cs == nil || op.cs.config.Retry == nil {
+		return call()
+	}
+	return retryLoop(ctx, *op.cs.config.Retry, op.cs.now, op.cs.sleep, call, func(attempt int, wait time.Duration, hinted, pastDeadline bool, err error) {
+		if pastDeadline {
+			op.logger.Debug("retry wait exceeds deadline, giving up", zap.String("filepath", op.object), zap.Int("attempt", attempt), zap.Duration("wait", wait))
+			return
+		}
+		op.logger.Debug("retrying storage call", zap.String("filepath", op.object), zap.Int("attempt", attempt), zap.Duration("wait", wait), zap.Bool("hinted", hinted), zap.Error(err))
+		if rec, ok := op.cs.config.Metrics.(RetryRecorder); ok {
+			rec.RecordRetry(RetryMetrics{
+				Op:        op.name,
+				Bucket:    op.bucket,
+				Object:    op.object,
+				RequestID: op.requestID,
+				Attempt:   attempt,
+				Wait:      wait,
+				Hinted:    hinted,
+				Err:       err,
+			})
+		}
+	})
+}
@@ -0,0 +1,324 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+)
+
+// retryMetrics records transfer & retry metrics for assertions
+type retryMetrics struct {
+	recordingMetrics
+	mu      sync.Mutex
+	retries []RetryMetrics
+}
+
+func (m *retryMetrics) RecordRetry(rm RetryMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries = append(m.retries, rm)
+}
+
+// throttled serves given responses to JSON API object requests, one per request, then the fake
+func throttled(f *fakeGCS, responses ...func(w http.ResponseWriter)) http.Handler {
+	var mu sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		var next func(w http.ResponseWriter)
+		if len(responses) > 0 && strings.HasPrefix(r.URL.Path, "/storage/v1/b/bucket/o/") {
+			next, responses = responses[0], responses[1:]
+		}
+		mu.Unlock()
+		if next != nil {
+			next(w)
+			return
+		}
+		f.ServeHTTP(w, r)
+	})
+}
+
+func retryAfter(code int, value string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", value)
+		writeAPIError(w, code, http.StatusText(code))
+	}
+}
+
+func retryInfo(code int, delay string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{
+				"code":    code,
+				"message": http.StatusText(code),
+				"details": []interface{}{
+					map[string]string{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": delay},
+				},
+			},
+		})
+	}
+}
+
+// newRetryClient returns a fake backed client with given retry policy, recording waits instead of sleeping
+func newRetryClient(t *testing.T, h http.Handler, policy RetryPolicy) (*cloudStorageClient, *retryMetrics, *[]time.Duration) {
+	cs := newFakeClient(t, h)
+	metrics := &retryMetrics{}
+	cs.config.Metrics = metrics
+	cs.config.Retry = &policy
+	slept := []time.Duration{}
+	cs.sleeper = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return cs, metrics, &slept
+}
+
+func TestRetryPrefersServerHints(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/file.json", []byte("{}"), nil)
+	h := throttled(f, retryAfter(http.StatusTooManyRequests, "3"), retryInfo(http.StatusServiceUnavailable, "1.500s"))
+	cs, metrics, slept := newRetryClient(t, h, RetryPolicy{MaxBackoff: 10 * time.Second})
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	_, err = cs.GetAttrs(context.Background(), cfr)
+	require.NoError(t, err)
+	require.Equal(t, []time.Duration{3 * time.Second, 1500 * time.Millisecond}, *slept)
+
+	require.Len(t, metrics.retries, 2)
+	for i, rm := range metrics.retries {
+		require.Equal(t, "GetAttrs", rm.Op)
+		require.Equal(t, "path/file.json", rm.Object)
+		require.Equal(t, i+1, rm.Attempt)
+		require.True(t, rm.Hinted)
+		require.Equal(t, (*slept)[i], rm.Wait)
+	}
+}
+
+func TestRetryHintCappedAtMaxBackoff(t *testing.T) {
+	f := newFakeGCS()
+	content := []byte(`{"a":1}`)
+	f.put("bucket", "path/file.json", content, nil)
+	h := throttled(f, retryAfter(http.StatusServiceUnavailable, "120"))
+	cs, metrics, slept := newRetryClient(t, h, RetryPolicy{MaxBackoff: 5 * time.Second})
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = cs.Download(context.Background(), &buf, cfr)
+	require.NoError(t, err)
+	require.Equal(t, content, buf.Bytes())
+	require.Equal(t, []time.Duration{5 * time.Second}, *slept)
+	require.True(t, metrics.retries[0].Hinted)
+}
+
+func TestRetryMalformedHintFallsBack(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/file.json", []byte("{}"), nil)
+	h := throttled(f, retryAfter(http.StatusTooManyRequests, "soon"), retryAfter(http.StatusTooManyRequests, "-3"))
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 2, MaxBackoff: time.Minute}
+	cs, metrics, slept := newRetryClient(t, h, policy)
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	_, err = cs.GetAttrs(context.Background(), cfr)
+	require.NoError(t, err)
+	require.Len(t, *slept, 2)
+	require.LessOrEqual(t, (*slept)[0], 100*time.Millisecond)
+	require.LessOrEqual(t, (*slept)[1], 200*time.Millisecond)
+	for _, rm := range metrics.retries {
+		require.False(t, rm.Hinted)
+	}
+}
+
+func TestRetryBoundedByDeadline(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/file.json", []byte("{}"), nil)
+	h := throttled(f, retryAfter(http.StatusTooManyRequests, "30"))
+	cs, metrics, slept := newRetryClient(t, h, RetryPolicy{MaxBackoff: time.Minute})
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = cs.GetAttrs(ctx, cfr)
+	require.Error(t, err)
+	var gerr *googleapi.Error
+	require.ErrorAs(t, err, &gerr)
+	require.Equal(t, http.StatusTooManyRequests, gerr.Code)
+	require.Empty(t, *slept)
+	require.Empty(t, metrics.retries)
+}
+
+func TestRetryStopsAtMaxAttempts(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/file.json", []byte("{}"), nil)
+	throttle := retryAfter(http.StatusServiceUnavailable, "1")
+	h := throttled(f, throttle, throttle)
+	cs, _, slept := newRetryClient(t, h, RetryPolicy{MaxAttempts: 2})
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	_, err = cs.GetAttrs(context.Background(), cfr)
+	require.Error(t, err)
+	require.Equal(t, []time.Duration{time.Second}, *slept)
+
+	// missing objects aren't retried
+	*slept = nil
+	_, err = cs.GetAttrs(context.Background(), CloudFileRequest{bucket: "bucket", path: "path", file: "missing.json"})
+	require.ErrorIs(t, err, ErrObjectNotFound)
+	require.Empty(t, *slept)
+}
+
+func TestRetryHint(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		value  string
+		want   time.Duration
+		hinted bool
+	}{
+		{value: "7", want: 7 * time.Second, hinted: true},
+		{value: " 0 ", want: 0, hinted: true},
+		{value: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second, hinted: true},
+		{value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0, hinted: true},
+		{value: "-1"},
+		{value: "1.5"},
+		{value: "tomorrow"},
+		{value: ""},
+	} {
+		err := &googleapi.Error{Code: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {tc.value}}}
+		wait, hinted := retryHint(err, now)
+		require.Equal(t, tc.hinted, hinted, tc.value)
+		require.Equal(t, tc.want, wait, tc.value)
+	}
+
+	_, hinted := retryHint(context.DeadlineExceeded, now)
+	require.False(t, hinted)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{}.normalize()
+	require.Equal(t, DEFAULT_RETRY_MAX_ATTEMPTS, p.MaxAttempts)
+	for retry := 1; retry <= 10; retry++ {
+		wait := p.Backoff().wait(retry)
+		require.Greater(t, wait, time.Duration(0))
+		require.LessOrEqual(t, wait, p.MaxBackoff)
+	}
+}
+
+func TestBackoffJitterBounds(t *testing.T) {
+	b := &Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	waits := []time.Duration{}
+	for i := 0; i < 6; i++ {
+		waits = append(waits, b.Next())
+	}
+	// no jitter, the ceiling grows & is capped
+	require.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}, waits)
+	b.Reset()
+	require.Equal(t, 100*time.Millisecond, b.Next())
+
+	for _, jitter := range []float64{0.25, 0.5, 1} {
+		b := &Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2, Jitter: jitter}
+		for i := 0; i < 200; i++ {
+			if i%5 == 0 {
+				b.Reset()
+			}
+			wait := b.Next()
+			ceiling := 100 * time.Millisecond << (i % 5)
+			if ceiling > time.Second {
+				ceiling = time.Second
+			}
+			require.LessOrEqual(t, wait, ceiling, jitter)
+			require.GreaterOrEqual(t, wait, time.Duration(float64(ceiling)*(1-jitter)), jitter)
+			require.Greater(t, wait, time.Duration(0))
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	transient := &googleapi.Error{Code: http.StatusServiceUnavailable}
+	require.True(t, IsRetryable(transient))
+	require.False(t, IsRetryable(&googleapi.Error{Code: http.StatusNotFound}))
+	require.False(t, IsRetryable(nil))
+
+	calls := 0
+	policy := RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	err := Do(context.Background(), policy, func() error {
+		if calls++; calls < 3 {
+			return transient
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	// failures stop at max attempts, permanent ones aren't retried
+	calls = 0
+	err = Do(context.Background(), policy, func() error {
+		calls++
+		return transient
+	})
+	require.Equal(t, transient, err)
+	require.Equal(t, DEFAULT_RETRY_MAX_ATTEMPTS, calls)
+	calls = 0
+	err = Do(context.Background(), policy, func() error {
+		calls++
+		return ErrObjectNotFound
+	})
+	require.Equal(t, ErrObjectNotFound, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestDoCancelledDuringSleep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	calls := 0
+	start := time.Now()
+	err := Do(ctx, RetryPolicy{InitialBackoff: time.Hour, MaxBackoff: time.Hour}, func() error {
+		calls++
+		return &googleapi.Error{Code: http.StatusTooManyRequests}
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, calls)
+	require.Less(t, time.Since(start), time.Minute)
+}
+
+func TestRetryDeadlineUsesClientClock(t *testing.T) {
+	clock := newFakeClock()
+	ctx, cancel := context.WithDeadline(context.Background(), clock.Now().Add(time.Minute))
+	defer cancel()
+	transient := &googleapi.Error{Code: http.StatusServiceUnavailable}
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: time.Second, Multiplier: 2}
+	sleep := func(ctx context.Context, d time.Duration) error {
+		clock.Advance(d)
+		return nil
+	}
+
+	// the deadline is compared with the clock, not wall time, the fake clock is far behind it
+	calls := 0
+	err := retryLoop(ctx, policy, clock.Now, sleep, func() error {
+		calls++
+		return transient
+	}, nil)
+	require.Equal(t, transient, err)
+	require.Equal(t, 3, calls)
+
+	// past the deadline by the clock, no wait is started
+	clock.Advance(time.Hour)
+	calls = 0
+	err = retryLoop(ctx, policy, clock.Now, sleep, func() error {
+		calls++
+		return transient
+	}, nil)
+	require.Equal(t, transient, err)
+	require.Equal(t, 1, calls)
+}
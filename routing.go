@@ -0,0 +1,206 @@
+package cloudstorage
+
+import (
+	"context"
+	stderrors "errors"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	ERROR_ROUTER_MISSING     string = "routing key set without bucket router"
+	ERROR_NO_ROUTE           string = "no bucket route for key"
+	ERROR_ROUTE_CONFLICT     string = "request bucket differs from routed bucket"
+	ERROR_ROUTING_BUCKET     string = "error routing bucket"
+	ERROR_PROJECT_ID_MISSING string = "project id missing for bucket creation"
+	ERROR_CREATING_BUCKET    string = "error creating storage bucket"
+)
+
+var (
+	ErrRouterMissing    = errors.NewAppError(ERROR_ROUTER_MISSING)
+	ErrNoRoute          = errors.NewAppError(ERROR_NO_ROUTE)
+	ErrRouteConflict    = errors.NewAppError(ERROR_ROUTE_CONFLICT)
+	ErrProjectIDMissing = errors.NewAppError(ERROR_PROJECT_ID_MISSING)
+)
+
+// DEFAULT_HASH_RING_REPLICAS is the default number of ring points per bucket of a hash ring router
+const DEFAULT_HASH_RING_REPLICAS = 64
+
+// BucketRouter resolves the bucket of a routing key, e.g. a tenant id, must be safe for concurrent use
+type BucketRouter interface {
+	Route(ctx context.Context, key string) (string, error)
+}
+
+// WithRoutingKey resolves request's bucket with the client's bucket router,
+// requests naming a bucket must name the routed one
+func WithRoutingKey(key string) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.routingKey = key
+	}
+}
+
+// routed returns request with its routing key's bucket, requests without routing key are returned as is
+func (cs *cloudStorageClient) routed(ctx context.Context, cfr CloudFileRequest) (CloudFileRequest, error) {
+	if cfr.routingKey == "" {
+		return cfr, nil
+	}
+	bucket, err := cs.route(ctx, cfr.routingKey)
+	if err != nil {
+		return CloudFileRequest{}, err
+	}
+	if cfr.bucket != "" && cfr.bucket != bucket {
+		return CloudFileRequest{}, errors.WrapError(ErrRouteConflict, "%s %q, routed %q", ERROR_ROUTE_CONFLICT, cfr.bucket, bucket)
+	}
+	// resolved once, requests passed on aren't routed again
+	cfr.bucket, cfr.routingKey = bucket, ""
+	return cfr, nil
+}
+
+// route resolves given routing key with the client's router
+func (cs *cloudStorageClient) route(ctx context.Context, key string) (string, error) {
+	if cs.config.Router == nil {
+		return "", ErrRouterMissing
+	}
+	bucket, err := cs.config.Router.Route(ctx, key)
+	if err != nil {
+		cs.logger.Error(ERROR_ROUTING_BUCKET, zap.Error(err), zap.String("key", key))
+		return "", err
+	}
+	if bucket == "" {
+		return "", errors.WrapError(ErrNoRoute, "%s %q", ERROR_NO_ROUTE, key)
+	}
+	return bucket, nil
+}
+
+// EnsureRoutedBuckets routes given keys & creates the missing buckets in the configured ProjectID,
+// returns the created bucket names, in routing order. Buckets created concurrently by another
+// caller count as existing.
+func (cs *cloudStorageClient) EnsureRoutedBuckets(ctx context.Context, keys []string) ([]string, error) {
+	if err := cs.mutation(); err != nil {
+		return nil, err
+	}
+	buckets := []string{}
+	seen := map[string]bool{}
+	for _, key := range keys {
+		bucket, err := cs.route(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if err := cs.bucketMutation(bucket); err != nil {
+			return nil, err
+		}
+		if !seen[bucket] {
+			seen[bucket] = true
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	op := cs.startOperation(ctx, "EnsureRoutedBuckets", CloudFileRequest{})
+	defer op.finish()
+	ctx = op.ctx
+	created := []string{}
+	for _, bucket := range buckets {
+		bop := op.forObject(bucket, "")
+		_, err := cs.client.Bucket(bucket).Attrs(ctx)
+		if err == nil {
+			continue
+		}
+		if !stderrors.Is(err, storage.ErrBucketNotExist) {
+			bop.logger.Error(ERROR_ROUTING_BUCKET, zap.Error(err), zap.String("bucket", bucket))
+			return created, bop.wrapError(err, "%s %s", ERROR_ROUTING_BUCKET, bucket)
+		}
+		if cs.config.ProjectID == "" {
+			return created, ErrProjectIDMissing
+		}
+		err = cs.client.Bucket(bucket).Create(ctx, cs.config.ProjectID, nil)
+		var gErr *googleapi.Error
+		if stderrors.As(err, &gErr) && gErr.Code == http.StatusConflict {
+			continue
+		}
+		if err != nil {
+			bop.logger.Error(ERROR_CREATING_BUCKET, zap.Error(err), zap.String("bucket", bucket))
+			return created, bop.wrapError(err, "%s %s", ERROR_CREATING_BUCKET, bucket)
+		}
+		bop.logger.Info("routed bucket created", zap.String("bucket", bucket))
+		created = append(created, bucket)
+	}
+	return created, nil
+}
+
+// StaticRouter routes keys with a fixed map, keys without route go to the default bucket when set
+type StaticRouter struct {
+	Routes  map[string]string `json:"routes"`
+	Default string            `json:"default"`
+}
+
+// Route returns the key's mapped bucket, the default otherwise, fails with ErrNoRoute when neither is set
+func (r StaticRouter) Route(ctx context.Context, key string) (string, error) {
+	if bucket, ok := r.Routes[key]; ok && bucket != "" {
+		return bucket, nil
+	}
+	if r.Default != "" {
+		return r.Default, nil
+	}
+	return "", errors.WrapError(ErrNoRoute, "%s %q", ERROR_NO_ROUTE, key)
+}
+
+// HashRingRouter routes keys by consistent hashing over a set of buckets, each bucket owning
+// a number of points on a hash ring. A key routes to the bucket of the first point after its hash,
+// so adding or removing a bucket only moves the keys of that bucket's ring segments.
+type HashRingRouter struct {
+	points []uint64
+	owners map[uint64]string
+}
+
+// NewHashRingRouter returns a hash ring router over given buckets, with given number of ring points
+// per bucket, DEFAULT_HASH_RING_REPLICAS when not positive. Assignment depends on bucket names only,
+// not their order.
+func NewHashRingRouter(buckets []string, replicas int) *HashRingRouter {
+	if replicas <= 0 {
+		replicas = DEFAULT_HASH_RING_REPLICAS
+	}
+	r := &HashRingRouter{owners: map[uint64]string{}}
+	for _, bucket := range buckets {
+		for i := 0; i < replicas; i++ {
+			point := ringHash(bucket + "#" + strconv.Itoa(i))
+			// colliding points go to the smallest name, whatever the bucket order
+			if owner, ok := r.owners[point]; ok {
+				if bucket < owner {
+					r.owners[point] = bucket
+				}
+				continue
+			}
+			r.owners[point] = bucket
+			r.points = append(r.points, point)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// Route returns the bucket owning the key's ring segment, fails with ErrNoRoute for an empty ring
+func (r *HashRingRouter) Route(ctx context.Context, key string) (string, error) {
+	if len(r.points) == 0 {
+		return "", errors.WrapError(ErrNoRoute, "%s %q", ERROR_NO_ROUTE, key)
+	}
+	h := ringHash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]], nil
+}
+
+// ringHash returns the ring position of given value
+func ringHash(v string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(v))
+	return h.Sum64()
+}
@@ -0,0 +1,146 @@
+package cloudstorage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutedRequests(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	cs.config.Router = StaticRouter{Routes: map[string]string{"tenant-1": "bucket-a", "tenant-2": "bucket-b"}}
+	ctx := context.Background()
+
+	for key, bucket := range map[string]string{"tenant-1": "bucket-a", "tenant-2": "bucket-b"} {
+		cfr, err := NewCloudFileRequest("", "doc.txt", "docs", 0, WithRoutingKey(key))
+		require.NoError(t, err)
+		_, err = cs.Upload(ctx, strings.NewReader(key), cfr)
+		require.NoError(t, err)
+		data, _, ok := f.get(bucket, "docs/doc.txt")
+		require.True(t, ok, key)
+		require.Equal(t, key, string(data))
+		ok, err = cs.Exists(ctx, cfr)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	// routes apply before the scope, a routed bucket must be the scope's under strict scope
+	scoped := WithScope(ctx, Scope{Bucket: "bucket-a", PathPrefix: "tenant-1"})
+	_, err := cs.Upload(scoped, strings.NewReader("v"), CloudFileRequest{file: "x.txt", routingKey: "tenant-1"})
+	require.NoError(t, err)
+	_, _, ok := f.get("bucket-a", "tenant-1/x.txt")
+	require.True(t, ok)
+	cs.config.StrictScope = true
+	_, err = cs.Upload(scoped, strings.NewReader("v"), CloudFileRequest{file: "x.txt", routingKey: "tenant-2"})
+	require.Equal(t, ErrOutOfScope, errorInner(err))
+	cs.config.StrictScope = false
+
+	_, err = cs.Upload(ctx, strings.NewReader("v"), CloudFileRequest{bucket: "bucket-b", file: "x.txt", routingKey: "tenant-1"})
+	require.Equal(t, ErrRouteConflict, errorInner(err))
+	_, err = cs.Upload(ctx, strings.NewReader("v"), CloudFileRequest{file: "x.txt", routingKey: "unknown"})
+	require.Equal(t, ErrNoRoute, errorInner(err))
+	cs.config.Router = nil
+	_, err = cs.Upload(ctx, strings.NewReader("v"), CloudFileRequest{file: "x.txt", routingKey: "tenant-1"})
+	require.Equal(t, ErrRouterMissing, err)
+
+	_, err = NewCloudFileRequest("", "doc.txt", "docs", 0)
+	require.Equal(t, ErrBucketNameMissing, err)
+}
+
+func TestStaticRouterDefault(t *testing.T) {
+	r := StaticRouter{Routes: map[string]string{"vip": "bucket-vip"}, Default: "bucket-shared"}
+	bucket, err := r.Route(context.Background(), "vip")
+	require.NoError(t, err)
+	require.Equal(t, "bucket-vip", bucket)
+	bucket, err = r.Route(context.Background(), "other")
+	require.NoError(t, err)
+	require.Equal(t, "bucket-shared", bucket)
+}
+
+// routeKeys returns the bucket of every key
+func routeKeys(t *testing.T, r BucketRouter, keys []string) map[string]string {
+	routes := map[string]string{}
+	for _, key := range keys {
+		bucket, err := r.Route(context.Background(), key)
+		require.NoError(t, err)
+		routes[key] = bucket
+	}
+	return routes
+}
+
+func TestHashRingRouterStable(t *testing.T) {
+	keys := []string{}
+	for i := 0; i < 2000; i++ {
+		keys = append(keys, fmt.Sprintf("tenant-%d", i))
+	}
+	base := []string{"shard-0", "shard-1", "shard-2", "shard-3"}
+	routes := routeKeys(t, NewHashRingRouter(base, 0), keys)
+
+	// assignment depends on bucket names only
+	require.Equal(t, routes, routeKeys(t, NewHashRingRouter([]string{"shard-3", "shard-1", "shard-0", "shard-2"}, 0), keys))
+	counts := map[string]int{}
+	for _, bucket := range routes {
+		counts[bucket]++
+	}
+	require.Len(t, counts, 4)
+	for bucket, n := range counts {
+		require.Greater(t, n, 250, bucket)
+	}
+
+	// an added bucket only takes keys, about its share
+	grown := routeKeys(t, NewHashRingRouter(append(base, "shard-4"), 0), keys)
+	moved := 0
+	for key, bucket := range grown {
+		if bucket != routes[key] {
+			require.Equal(t, "shard-4", bucket, key)
+			moved++
+		}
+	}
+	require.Greater(t, moved, 0)
+	require.Less(t, moved, len(keys)/3)
+
+	// a removed bucket's keys move, the others stay
+	shrunk := routeKeys(t, NewHashRingRouter(base[:3], 0), keys)
+	for key, bucket := range shrunk {
+		if routes[key] != "shard-3" {
+			require.Equal(t, routes[key], bucket, key)
+		} else {
+			require.NotEqual(t, "shard-3", bucket)
+		}
+	}
+
+	_, err := NewHashRingRouter(nil, 0).Route(context.Background(), "tenant-1")
+	require.Equal(t, ErrNoRoute, errorInner(err))
+}
+
+func TestEnsureRoutedBuckets(t *testing.T) {
+	f := newFakeGCS()
+	f.buckets = map[string]bool{"bucket-a": true}
+	cs := newFakeClient(t, f)
+	cs.config.Router = StaticRouter{Routes: map[string]string{"tenant-1": "bucket-a", "tenant-2": "bucket-b", "tenant-3": "bucket-c", "tenant-4": "bucket-b"}}
+	ctx := context.Background()
+	keys := []string{"tenant-1", "tenant-2", "tenant-3", "tenant-4"}
+
+	_, err := cs.EnsureRoutedBuckets(ctx, keys)
+	require.Equal(t, ErrProjectIDMissing, err)
+
+	cs.config.ProjectID = "project"
+	creates := recordRequests(f, http.MethodPost, nil)
+	created, err := cs.EnsureRoutedBuckets(ctx, keys)
+	require.NoError(t, err)
+	require.Equal(t, []string{"bucket-b", "bucket-c"}, created)
+	require.Len(t, *creates, 2)
+	require.Equal(t, map[string]bool{"bucket-a": true, "bucket-b": true, "bucket-c": true}, f.buckets)
+
+	created, err = cs.EnsureRoutedBuckets(ctx, keys)
+	require.NoError(t, err)
+	require.Empty(t, created)
+
+	_, err = cs.EnsureRoutedBuckets(ctx, []string{"unknown"})
+	require.Equal(t, ErrNoRoute, errorInner(err))
+}
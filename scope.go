@@ -0,0 +1,181 @@
+package cloudstorage
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/comfforts/errors"
+)
+
+const (
+	ERROR_OUT_OF_SCOPE string = "request is outside the context scope"
+)
+
+var (
+	ErrOutOfScope = errors.NewAppError(ERROR_OUT_OF_SCOPE)
+)
+
+// Scope is the default bucket & path prefix of storage operations started with a scoped context,
+// e.g. a tenant's bucket & tenant id prefix
+type Scope struct {
+	// Bucket is used by requests without bucket, requests naming another bucket
+	// fail with ErrOutOfScope when the client is configured with StrictScope
+	Bucket string `json:"bucket"`
+	// PathPrefix is prepended to request paths & listing prefixes, listed names are returned relative to it
+	PathPrefix string `json:"path_prefix"`
+}
+
+type scopeKey struct{}
+
+// WithScope returns a copy of given context carrying the scope, every client method called
+// with the returned context defaults the bucket & prefixes paths, so a scoped caller
+// never reads, lists or changes objects outside the prefix
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFromContext returns the scope carried by given context, if any
+func ScopeFromContext(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeKey{}).(Scope)
+	return scope, ok
+}
+
+// NewScopedFileRequest builds a cloud file request in the bucket of given context's scope,
+// fails with ErrBucketNameMissing when the context has no scope bucket
+func NewScopedFileRequest(ctx context.Context, fileName, path string, modTime int64, opts ...CloudFileRequestOption) (CloudFileRequest, error) {
+	scope, _ := ScopeFromContext(ctx)
+	return NewCloudFileRequest(scope.Bucket, fileName, path, modTime, opts...)
+}
+
+// prefix returns the scope's path prefix as listing prefix, empty or with trailing slash
+func (s Scope) prefix() string {
+	return dirPrefix(s.PathPrefix)
+}
+
+// scopeBucket returns the bucket of a request in given scope, the scope bucket when none is named
+func (cs *cloudStorageClient) scopeBucket(scope Scope, bucket string) (string, error) {
+	if bucket == "" {
+		return scope.Bucket, nil
+	}
+	if cs.config.StrictScope && scope.Bucket != "" && bucket != scope.Bucket {
+		return "", errors.WrapError(ErrOutOfScope, "%s %q", ERROR_OUT_OF_SCOPE, bucket)
+	}
+	return bucket, nil
+}
+
+// scopePath returns given path under scope prefix, fails with ErrOutOfScope
+// for paths with parent segments, which could resolve outside the prefix
+func scopePath(scope Scope, p string) (string, error) {
+	for _, seg := range strings.Split(p, "/") {
+		if seg == ".." {
+			return "", errors.WrapError(ErrOutOfScope, "%s %q", ERROR_OUT_OF_SCOPE, p)
+		}
+	}
+	prefix := scope.prefix()
+	if prefix == "" {
+		return p, nil
+	}
+	if p == "" {
+		return strings.TrimSuffix(prefix, "/"), nil
+	}
+	joined := path.Join(prefix, p)
+	if strings.HasSuffix(p, "/") {
+		joined += "/"
+	}
+	return joined, nil
+}
+
+// scoped returns request in the scope of given context, bucket routed or defaulted, path & file normalized & path prefixed,
+// within the boundary of a scoped client, path & file encoded with the client's name codec, requests already scoped,
+// e.g. passed on by another method, are returned as is. Fails with ErrClientClosed once the client is closing.
+func (cs *cloudStorageClient) scoped(ctx context.Context, cfr CloudFileRequest) (CloudFileRequest, error) {
+	if err := cs.life.admit(ctx); err != nil {
+		return CloudFileRequest{}, err
+	}
+	cfr, err := cs.routed(ctx, cfr)
+	if err != nil {
+		return CloudFileRequest{}, err
+	}
+	if cfr.scoped {
+		return cfr, nil
+	}
+	if cs.bound != nil {
+		if err := cs.bound.checkNames(cfr); err != nil {
+			return CloudFileRequest{}, err
+		}
+	}
+	if cfr, err = cfr.normalized(); err != nil {
+		return CloudFileRequest{}, err
+	}
+	scope, ok := ScopeFromContext(ctx)
+	codec := cs.config.NameCodec
+	if !ok && codec == nil && cs.bound == nil {
+		return cfr, nil
+	}
+	if ok {
+		bucket, err := cs.scopeBucket(scope, cfr.bucket)
+		if err != nil {
+			return CloudFileRequest{}, err
+		}
+		if _, err := scopePath(Scope{}, cfr.file); err != nil {
+			return CloudFileRequest{}, err
+		}
+		p, err := scopePath(scope, cfr.path)
+		if err != nil {
+			return CloudFileRequest{}, err
+		}
+		cfr.bucket, cfr.path = bucket, p
+	}
+	cfr.scoped, cfr.scopePrefix = true, scope.prefix()
+	if cs.bound != nil {
+		if cfr, err = cs.bound.apply(cfr); err != nil {
+			return CloudFileRequest{}, err
+		}
+	}
+	if codec != nil {
+		cfr.path, cfr.file, cfr.scopePrefix = codec.Encode(cfr.path), codec.Encode(cfr.file), codec.Encode(cfr.scopePrefix)
+		cfr.names = codec
+	}
+	// scope prefixes & encoding lengthen the stored name
+	if err := ValidateObjectName(cfr.objectPath()); err != nil {
+		return CloudFileRequest{}, err
+	}
+	return cfr, nil
+}
+
+// scopedRef returns given bucket & prefix in the scope of given context
+func (cs *cloudStorageClient) scopedRef(ctx context.Context, ref BucketPrefix) (BucketPrefix, error) {
+	cfr, err := cs.scoped(ctx, CloudFileRequest{bucket: ref.Bucket, path: ref.Prefix})
+	if err != nil {
+		return BucketPrefix{}, err
+	}
+	return BucketPrefix{Bucket: cfr.bucket, Prefix: cfr.path}, nil
+}
+
+// unscopedName returns given object name relative to request's scope prefix, decoded with the name codec
+func (cfr CloudFileRequest) unscopedName(name string) string {
+	name, _ = cfr.decodedName(strings.TrimPrefix(name, cfr.scopePrefix))
+	return name
+}
+
+// decodedName returns given stored name decoded with request's name codec,
+// names the codec fails to decode, e.g. stored before it was set, are returned as is with false
+func (cfr CloudFileRequest) decodedName(stored string) (string, bool) {
+	if cfr.names == nil {
+		return stored, true
+	}
+	name, err := cfr.names.Decode(stored)
+	if err != nil {
+		return stored, false
+	}
+	return name, true
+}
+
+// encodedName returns given name relative to request's scope encoded with request's name codec
+func (cfr CloudFileRequest) encodedName(name string) string {
+	if cfr.names == nil {
+		return name
+	}
+	return cfr.names.Encode(name)
+}
@@ -0,0 +1,447 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/comfforts/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// requireOutOfScope checks err is an ErrOutOfScope validation error
+func requireOutOfScope(t *testing.T, err error) {
+	t.Helper()
+	require.Error(t, err)
+	appErr, ok := err.(errors.AppError)
+	require.True(t, ok, "%T %v", err, err)
+	require.Equal(t, ErrOutOfScope, appErr.Inner)
+}
+
+// putTenants stores objects of two tenants & one outside both
+func putTenants(f *fakeGCS) {
+	f.put("bucket", "tenant-a/x.txt", []byte("a-x"), nil)
+	f.put("bucket", "tenant-a/docs/y.txt", []byte("a-y"), map[string]string{tagMetadataKey("kind"): "doc"})
+	f.put("bucket", "tenant-b/x.txt", []byte("b-x"), nil)
+	f.put("bucket", "tenant-b/docs/y.txt", []byte("b-y"), map[string]string{tagMetadataKey("kind"): "doc"})
+	f.put("bucket", "root.txt", []byte("root"), nil)
+}
+
+func tenantContext() context.Context {
+	return WithScope(context.Background(), Scope{Bucket: "bucket", PathPrefix: "tenant-a"})
+}
+
+func TestScopeFromContext(t *testing.T) {
+	_, ok := ScopeFromContext(context.Background())
+	require.False(t, ok)
+
+	scope, ok := ScopeFromContext(tenantContext())
+	require.True(t, ok)
+	require.Equal(t, Scope{Bucket: "bucket", PathPrefix: "tenant-a"}, scope)
+
+	cfr, err := NewScopedFileRequest(tenantContext(), "a.txt", "docs", 0)
+	require.NoError(t, err)
+	require.Equal(t, "bucket", cfr.bucket)
+	_, err = NewScopedFileRequest(context.Background(), "a.txt", "docs", 0)
+	require.Equal(t, ErrBucketNameMissing, err)
+}
+
+func TestScopePrefixesRequests(t *testing.T) {
+	f := newFakeGCS()
+	putTenants(f)
+	cs := newFakeClient(t, f)
+	ctx := tenantContext()
+
+	cfr, err := NewScopedFileRequest(ctx, "a.txt", "docs", 0)
+	require.NoError(t, err)
+	res, err := cs.Upload(ctx, strings.NewReader("new"), cfr)
+	require.NoError(t, err)
+	require.Equal(t, "docs/a.txt", res.Attrs.Name)
+	data, _, ok := f.get("bucket", "tenant-a/docs/a.txt")
+	require.True(t, ok)
+	require.Equal(t, "new", string(data))
+
+	// requests are resolved under the prefix, without it nothing is prefixed
+	x := CloudFileRequest{bucket: "bucket", file: "x.txt"}
+	var buf bytes.Buffer
+	_, err = cs.Download(ctx, &buf, x)
+	require.NoError(t, err)
+	require.Equal(t, "a-x", buf.String())
+	buf.Reset()
+	_, err = cs.Download(context.Background(), &buf, CloudFileRequest{bucket: "bucket", file: "x.txt", path: "tenant-b"})
+	require.NoError(t, err)
+	require.Equal(t, "b-x", buf.String())
+
+	attrs, err := cs.GetAttrs(ctx, x)
+	require.NoError(t, err)
+	require.Equal(t, "x.txt", attrs.Name)
+	exists, err := cs.Exists(ctx, CloudFileRequest{file: "root.txt"})
+	require.NoError(t, err)
+	require.False(t, exists, "objects outside the prefix aren't visible")
+
+	// the bucket defaults to the scope's
+	_, err = cs.UpdateMetadata(ctx, CloudFileRequest{file: "x.txt"}, map[string]string{"k": "v"})
+	require.NoError(t, err)
+	_, meta, _ := f.get("bucket", "tenant-a/x.txt")
+	require.Equal(t, "v", meta.Metadata["k"])
+
+	// names passed on between methods are prefixed once
+	_, err = cs.WriteJSON(ctx, CloudFileRequest{file: "v.json", path: "docs"}, map[string]int{"v": 1})
+	require.NoError(t, err)
+	var v map[string]int
+	require.NoError(t, cs.ReadJSON(ctx, CloudFileRequest{file: "v.json", path: "docs"}, &v))
+	require.Equal(t, 1, v["v"])
+	_, _, ok = f.get("bucket", "tenant-a/docs/v.json")
+	require.True(t, ok)
+
+	tags, err := cs.SetObjectTags(ctx, CloudFileRequest{file: "v.json", path: "docs"}, map[string]string{"kind": "doc"})
+	require.NoError(t, err)
+	require.Equal(t, "doc", tags["kind"])
+	found, err := cs.FindObjectsByTag(ctx, "", "", "kind", "doc")
+	require.NoError(t, err)
+	names := []string{}
+	for _, attrs := range found {
+		names = append(names, attrs.Name)
+	}
+	require.Equal(t, []string{"docs/v.json", "docs/y.txt"}, names)
+}
+
+func TestScopeListings(t *testing.T) {
+	f := newFakeGCS()
+	putTenants(f)
+	cs := newFakeClient(t, f)
+	ctx := tenantContext()
+	all := CloudFileRequest{bucket: "bucket", wholeBucket: true}
+
+	names, err := cs.ListObjects(ctx, all)
+	require.NoError(t, err)
+	require.Equal(t, []string{"docs/y.txt", "x.txt"}, names)
+
+	infos, err := cs.ListObjectsInfo(ctx, all)
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	require.Equal(t, "docs/y.txt", infos[0].Name)
+
+	listing, err := cs.ListDir(ctx, all)
+	require.NoError(t, err)
+	require.Equal(t, []string{"docs/"}, listing.Dirs)
+	require.Equal(t, []string{"x.txt"}, listing.Files)
+
+	var inv bytes.Buffer
+	rows, err := cs.ExportInventory(ctx, all, &inv, InventoryJSONL)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), rows)
+	require.NotContains(t, inv.String(), "tenant-")
+
+	// listed names round trip into requests
+	for _, name := range names {
+		_, err := cs.GetAttrs(ctx, CloudFileRequest{bucket: "bucket", file: name})
+		require.NoError(t, err, name)
+	}
+}
+
+func TestScopeDeletes(t *testing.T) {
+	f := newFakeGCS()
+	putTenants(f)
+	cs := newFakeClient(t, f)
+	ctx := tenantContext()
+
+	require.NoError(t, cs.DeleteObject(ctx, CloudFileRequest{file: "x.txt"}))
+	_, _, ok := f.get("bucket", "tenant-a/x.txt")
+	require.False(t, ok)
+
+	// an empty path deletes the scope's objects, not the bucket's
+	report, err := cs.DeleteObjectsWithReport(ctx, CloudFileRequest{wholeBucket: true})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), report.Deleted)
+	for _, name := range []string{"tenant-b/x.txt", "tenant-b/docs/y.txt", "root.txt"} {
+		_, _, ok := f.get("bucket", name)
+		require.True(t, ok, name)
+	}
+
+	manifest := `{"name":"../tenant-b/x.txt"}` + "\n"
+	mr, err := cs.ProcessManifest(ctx, strings.NewReader(manifest), ManifestDelete)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), mr.Counts[ManifestMalformed])
+	_, _, ok = f.get("bucket", "tenant-b/x.txt")
+	require.True(t, ok)
+}
+
+func TestScopeNonStrictAllowsOtherBuckets(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := tenantContext()
+
+	// the prefix still applies in the named bucket
+	_, err := cs.Upload(ctx, strings.NewReader("archived"), CloudFileRequest{bucket: "archive", file: "x.txt"})
+	require.NoError(t, err)
+	_, _, ok := f.get("archive", "tenant-a/x.txt")
+	require.True(t, ok)
+	names, err := cs.ListObjects(ctx, CloudFileRequest{bucket: "archive", wholeBucket: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"x.txt"}, names)
+}
+
+func TestStrictScope(t *testing.T) {
+	f := newFakeGCS()
+	putTenants(f)
+	f.put("other", "tenant-a/x.txt", []byte("other"), nil)
+	var requests int64
+	f.fail = func(r *http.Request) int {
+		atomic.AddInt64(&requests, 1)
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	cs.config.StrictScope = true
+	ctx := tenantContext()
+	other := CloudFileRequest{bucket: "other", file: "x.txt"}
+	own := CloudFileRequest{bucket: "bucket", file: "x.txt"}
+
+	for name, call := range map[string]func() error{
+		"Upload": func() error {
+			_, err := cs.Upload(ctx, strings.NewReader("x"), other)
+			return err
+		},
+		"UploadFile": func() error {
+			_, err := cs.UploadFile(ctx, strings.NewReader("x"), other)
+			return err
+		},
+		"UploadFromReaderAt": func() error {
+			_, err := cs.UploadFromReaderAt(ctx, strings.NewReader("x"), 1, other)
+			return err
+		},
+		"UploadFanOut replica": func() error {
+			_, err := cs.UploadFanOut(ctx, strings.NewReader("x"), own, []CloudFileRequest{other})
+			return err
+		},
+		"Download": func() error {
+			_, err := cs.Download(ctx, &bytes.Buffer{}, other)
+			return err
+		},
+		"DownloadFile": func() error {
+			_, err := cs.DownloadFile(ctx, &bytes.Buffer{}, other)
+			return err
+		},
+		"ReadAt": func() error {
+			_, err := cs.ReadAt(ctx, other, make([]byte, 1), 0)
+			return err
+		},
+		"OpenReader": func() error {
+			_, err := cs.OpenReader(ctx, other)
+			return err
+		},
+		"NewReaderAt": func() error {
+			_, err := cs.NewReaderAt(ctx, other)
+			return err
+		},
+		"ReadJSON": func() error {
+			return cs.ReadJSON(ctx, other, &map[string]interface{}{})
+		},
+		"ReadNDJSON": func() error {
+			return cs.ReadNDJSON(ctx, other, func(json.RawMessage) error { return nil })
+		},
+		"WriteJSON": func() error {
+			_, err := cs.WriteJSON(ctx, other, 1)
+			return err
+		},
+		"ReadCSV": func() error {
+			return cs.ReadCSV(ctx, other, func([]string, []string) error { return nil })
+		},
+		"WriteCSV": func() error {
+			_, err := cs.WriteCSV(ctx, other, []string{"a"}, func() ([]string, bool) { return nil, false })
+			return err
+		},
+		"CopyFrom source": func() error {
+			_, err := cs.CopyFrom(ctx, cs, other, own)
+			return err
+		},
+		"CopyFrom destination": func() error {
+			_, err := cs.CopyFrom(ctx, cs, own, other)
+			return err
+		},
+		"GetAttrs": func() error {
+			_, err := cs.GetAttrs(ctx, other)
+			return err
+		},
+		"Exists": func() error {
+			_, err := cs.Exists(ctx, other)
+			return err
+		},
+		"UpdateMetadata": func() error {
+			_, err := cs.UpdateMetadata(ctx, other, map[string]string{"k": "v"})
+			return err
+		},
+		"SetObjectTags": func() error {
+			_, err := cs.SetObjectTags(ctx, other, map[string]string{"k": "v"})
+			return err
+		},
+		"GetObjectTags": func() error {
+			_, err := cs.GetObjectTags(ctx, other)
+			return err
+		},
+		"FindObjectsByTag": func() error {
+			_, err := cs.FindObjectsByTag(ctx, "other", "", "k", "v")
+			return err
+		},
+		"SignedURL": func() error {
+			_, err := cs.SignedURL(ctx, other, SignedURLOptions{})
+			return err
+		},
+		"ReadPointer": func() error {
+			_, _, err := cs.ReadPointer(ctx, other)
+			return err
+		},
+		"PublishPointer": func() error {
+			return cs.PublishPointer(ctx, other, []byte("x"))
+		},
+		"ListObjects": func() error {
+			_, err := cs.ListObjects(ctx, other)
+			return err
+		},
+		"ListObjectsInfo": func() error {
+			_, err := cs.ListObjectsInfo(ctx, other)
+			return err
+		},
+		"ListDir": func() error {
+			_, err := cs.ListDir(ctx, other)
+			return err
+		},
+		"ExportInventory": func() error {
+			_, err := cs.ExportInventory(ctx, other, &bytes.Buffer{}, InventoryJSONL)
+			return err
+		},
+		"SnapshotPrefix": func() error {
+			_, err := cs.SnapshotPrefix(ctx, other, time.Now())
+			return err
+		},
+		"RestoreSnapshot": func() error {
+			_, err := cs.RestoreSnapshot(ctx, []ObjectVersion{{Bucket: "other", Name: "x.txt", Generation: 1}}, "")
+			return err
+		},
+		"DeleteObject": func() error {
+			return cs.DeleteObject(ctx, other)
+		},
+		"DeleteObjects": func() error {
+			return cs.DeleteObjects(ctx, CloudFileRequest{bucket: "other", wholeBucket: true})
+		},
+		"EnsureDir": func() error {
+			return cs.EnsureDir(ctx, "other", "dir")
+		},
+		"ReconcileBuckets source": func() error {
+			_, err := cs.ReconcileBuckets(ctx, BucketPrefix{Bucket: "other"}, BucketPrefix{Bucket: "bucket", Prefix: "copy"}, ReconcileOptions{})
+			return err
+		},
+		"ReconcileBuckets destination": func() error {
+			_, err := cs.ReconcileBuckets(ctx, BucketPrefix{Bucket: "bucket"}, BucketPrefix{Bucket: "other"}, ReconcileOptions{})
+			return err
+		},
+		"ProcessManifest": func() error {
+			_, err := cs.ProcessManifest(ctx, strings.NewReader(`{"name":"x.txt"}`), ManifestDelete, WithManifestBucket("other"))
+			return err
+		},
+		"ProcessManifest destination": func() error {
+			_, err := cs.ProcessManifest(ctx, strings.NewReader(`{"name":"x.txt"}`), ManifestCopy, WithManifestDestination("other", "copy"))
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			requireOutOfScope(t, call())
+		})
+	}
+	require.Equal(t, int64(0), atomic.LoadInt64(&requests), "rejected before any request")
+	data, _, _ := f.get("other", "tenant-a/x.txt")
+	require.Equal(t, "other", string(data))
+
+	// batches fail the out of scope item only
+	results, err := cs.SignedURLs(ctx, []CloudFileRequest{other, own}, SignedURLOptions{})
+	requireOutOfScope(t, err)
+	requireOutOfScope(t, results[0].Err)
+	require.NotEqual(t, ErrOutOfScope, errorInner(results[1].Err))
+
+	// the scope's bucket & requests without bucket are allowed
+	_, err = cs.GetAttrs(ctx, own)
+	require.NoError(t, err)
+	_, err = cs.GetAttrs(ctx, CloudFileRequest{file: "x.txt"})
+	require.NoError(t, err)
+
+	// a scope without bucket doesn't restrict buckets
+	_, err = cs.GetAttrs(WithScope(context.Background(), Scope{PathPrefix: "tenant-a"}), other)
+	require.NoError(t, err)
+}
+
+func TestScopeParentSegments(t *testing.T) {
+	f := newFakeGCS()
+	putTenants(f)
+	var requests int64
+	f.fail = func(r *http.Request) int {
+		atomic.AddInt64(&requests, 1)
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	cs.config.StrictScope = true
+	ctx := tenantContext()
+
+	for _, cfr := range []CloudFileRequest{
+		{bucket: "bucket", path: "../tenant-b", file: "x.txt"},
+		{bucket: "bucket", path: "docs/../../tenant-b", file: "x.txt"},
+		{bucket: "bucket", file: "../tenant-b/x.txt"},
+		{bucket: "bucket", path: "docs", file: "../../root.txt"},
+	} {
+		_, err := cs.Download(ctx, &bytes.Buffer{}, cfr)
+		requireOutOfScope(t, err)
+		requireOutOfScope(t, cs.DeleteObject(ctx, cfr))
+	}
+	requireOutOfScope(t, cs.DeleteObjects(ctx, CloudFileRequest{path: ".."}))
+	requireOutOfScope(t, cs.EnsureDir(ctx, "", "../tenant-b/dir"))
+	_, err := cs.ReconcileBuckets(ctx, BucketPrefix{Prefix: "../tenant-b"}, BucketPrefix{Prefix: "copy"}, ReconcileOptions{})
+	requireOutOfScope(t, err)
+	_, err = cs.RestoreSnapshot(ctx, []ObjectVersion{{Name: "x.txt", Generation: 1}}, "../tenant-b")
+	requireOutOfScope(t, err)
+	require.Equal(t, int64(0), atomic.LoadInt64(&requests))
+
+	// dots within names are fine
+	_, err = cs.Upload(ctx, strings.NewReader("v"), CloudFileRequest{path: "docs..v2", file: "x..txt"})
+	require.NoError(t, err)
+	_, _, ok := f.get("bucket", "tenant-a/docs..v2/x..txt")
+	require.True(t, ok)
+}
+
+func TestScopeReconcileAndRestore(t *testing.T) {
+	f := newFakeGCS()
+	putTenants(f)
+	cs := newFakeClient(t, f)
+	cs.config.StrictScope = true
+	ctx := tenantContext()
+
+	report, err := cs.ReconcileBuckets(ctx, BucketPrefix{Prefix: "docs"}, BucketPrefix{Prefix: "backup"}, ReconcileOptions{DeleteExtraneous: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"copy:y.txt"}, reconcileActions(report))
+	data, _, ok := f.get("bucket", "tenant-a/backup/y.txt")
+	require.True(t, ok)
+	require.Equal(t, "a-y", string(data))
+
+	snapshot, err := cs.SnapshotPrefix(ctx, CloudFileRequest{path: "docs"}, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	require.Len(t, snapshot, 1)
+	require.Equal(t, "docs/y.txt", snapshot[0].Name)
+
+	restore, err := cs.RestoreSnapshot(ctx, snapshot, "restored")
+	require.NoError(t, err)
+	require.Len(t, restore.Actions, 1)
+	require.Equal(t, "restored/docs/y.txt", restore.Actions[0].Dest)
+	_, _, ok = f.get("bucket", "tenant-a/restored/docs/y.txt")
+	require.True(t, ok)
+}
+
+// errorInner returns the inner error of an AppError, err otherwise
+func errorInner(err error) error {
+	if appErr, ok := err.(errors.AppError); ok {
+		return appErr.Inner
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package cloudstorage
+
+import (
+	"strings"
+
+	"github.com/comfforts/errors"
+)
+
+const (
+	ERROR_SCOPE_VIOLATION string = "request is outside the scoped client's boundary"
+)
+
+var (
+	ErrScopeViolation = errors.NewAppError(ERROR_SCOPE_VIOLATION)
+)
+
+// boundary is the bucket & prefix a scoped client is restricted to
+type boundary struct {
+	bucket string
+	// prefix is empty or ends with a slash
+	prefix string
+	// err is set when the boundary couldn't be narrowed, every operation fails with it
+	err error
+}
+
+// Scoped returns a client restricted to given bucket & path prefix, sharing this client's connections,
+// caches & limits. Requests naming another bucket, absolute paths or paths with parent segments fail
+// with ErrScopeViolation, paths & listings are under the prefix & listed names relative to it,
+// bucket level changes are denied unless the whole bucket is in scope. Scoping a scoped client narrows
+// its boundary, an empty bucket keeps the bucket, another bucket yields a client failing every call.
+// Context scopes apply within the boundary. Closing a scoped client leaves the shared connections open.
+func (cs *cloudStorageClient) Scoped(bucket, prefix string) CloudStorage {
+	sub := *cs
+	sub.accessSeq, sub.auditFailures = 0, 0
+	sub.bound = cs.bound.narrow(bucket, prefix)
+	return &sub
+}
+
+// narrow returns the boundary within given bucket & prefix, a nil boundary allows any bucket
+func (b *boundary) narrow(bucket, prefix string) *boundary {
+	nb := &boundary{bucket: bucket}
+	if b != nil {
+		nb.prefix, nb.err = b.prefix, b.err
+		if bucket == "" {
+			nb.bucket = b.bucket
+		}
+	}
+	switch {
+	case nb.err != nil:
+	case nb.bucket == "":
+		nb.err = ErrBucketNameMissing
+	case b != nil && nb.bucket != b.bucket:
+		nb.err = errors.WrapError(ErrScopeViolation, "%s %q", ERROR_SCOPE_VIOLATION, bucket)
+	case escapes(prefix):
+		nb.err = errors.WrapError(ErrScopeViolation, "%s %q", ERROR_SCOPE_VIOLATION, prefix)
+	default:
+		nb.prefix += boundaryPrefix(prefix)
+	}
+	return nb
+}
+
+// boundaryPrefix returns given scope prefix normalized like object names, leading slashes stripped &
+// doubled ones collapsed, with a trailing slash, so normalized names under it match
+func boundaryPrefix(prefix string) string {
+	prefix = strings.TrimSuffix(NormalizeName(prefix), "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// escapes reports whether given path has parent segments, which could resolve outside a prefix
+func escapes(p string) bool {
+	for _, seg := range strings.Split(p, "/") {
+		if seg == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// violation returns ErrScopeViolation for given bucket & name
+func (b *boundary) violation(bucket, name string) error {
+	return errors.WrapError(ErrScopeViolation, "%s %s/%s", ERROR_SCOPE_VIOLATION, bucket, name)
+}
+
+// checkNames fails requests naming another bucket, absolute paths & paths with parent segments
+func (b *boundary) checkNames(cfr CloudFileRequest) error {
+	if b.err != nil {
+		return b.err
+	}
+	if cfr.bucket != "" && cfr.bucket != b.bucket {
+		return b.violation(cfr.bucket, cfr.objectPath())
+	}
+	for _, p := range []string{cfr.path, cfr.file} {
+		if strings.HasPrefix(p, "/") || escapes(p) {
+			return b.violation(b.bucket, cfr.objectPath())
+		}
+	}
+	return nil
+}
+
+// apply returns given request, already in its context scope, in the boundary's bucket & under its prefix
+func (b *boundary) apply(cfr CloudFileRequest) (CloudFileRequest, error) {
+	if cfr.bucket != "" && cfr.bucket != b.bucket {
+		// defaulted by the context scope
+		return CloudFileRequest{}, b.violation(cfr.bucket, cfr.objectPath())
+	}
+	p, err := scopePath(Scope{PathPrefix: b.prefix}, cfr.path)
+	if err != nil {
+		return CloudFileRequest{}, err
+	}
+	cfr.bucket, cfr.path, cfr.scopePrefix = b.bucket, p, b.prefix+cfr.scopePrefix
+	// names resolving to the prefix itself, e.g. a "." file name, are outside
+	if cfr.file != "" && !strings.HasPrefix(cfr.objectPath(), b.prefix) {
+		return CloudFileRequest{}, b.violation(b.bucket, cfr.objectPath())
+	}
+	return cfr, nil
+}
+
+// contains reports whether given object is within the boundary
+func (b *boundary) contains(bucket, object string) bool {
+	return b.err == nil && bucket == b.bucket && strings.HasPrefix(object, b.prefix) && !escapes(object)
+}
+
+// bucketMutation fails bucket level changes of scoped clients, unless the whole bucket is in scope
+func (cs *cloudStorageClient) bucketMutation(bucket string) error {
+	b := cs.bound
+	if b == nil {
+		return nil
+	}
+	if b.err != nil {
+		return b.err
+	}
+	if b.prefix != "" || (bucket != "" && bucket != b.bucket) {
+		return b.violation(bucket, b.prefix)
+	}
+	return nil
+}
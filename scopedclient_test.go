@@ -0,0 +1,182 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/comfforts/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// requireScopeViolation checks err is an ErrScopeViolation validation error
+func requireScopeViolation(t *testing.T, err error) {
+	t.Helper()
+	require.Error(t, err)
+	appErr, ok := err.(errors.AppError)
+	require.True(t, ok, "%T %v", err, err)
+	require.Equal(t, ErrScopeViolation, appErr.Inner)
+}
+
+func TestScopedClient(t *testing.T) {
+	f := newFakeGCS()
+	putTenants(f)
+	f.put("other", "tenant-a/x.txt", []byte("other-x"), nil)
+	cs := newFakeClient(t, f)
+	sub := cs.Scoped("bucket", "tenant-a")
+	ctx := context.Background()
+
+	// requests are resolved under the prefix, the bucket defaults to the boundary's
+	var buf bytes.Buffer
+	_, err := sub.Download(ctx, &buf, CloudFileRequest{file: "x.txt"})
+	require.NoError(t, err)
+	require.Equal(t, "a-x", buf.String())
+	res, err := sub.Upload(ctx, strings.NewReader("new"), CloudFileRequest{bucket: "bucket", file: "a.txt", path: "docs"})
+	require.NoError(t, err)
+	require.Equal(t, "docs/a.txt", res.Attrs.Name)
+	_, _, ok := f.get("bucket", "tenant-a/docs/a.txt")
+	require.True(t, ok)
+
+	// listings are prefixed, names relative to the prefix
+	names, err := sub.ListObjects(ctx, CloudFileRequest{wholeBucket: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"docs/a.txt", "docs/y.txt", "x.txt"}, names)
+	infos, err := sub.ListObjectsInfo(ctx, CloudFileRequest{path: "docs"})
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+	require.Equal(t, "docs/a.txt", infos[0].Name)
+
+	// bulk deletes stay under the prefix
+	report, err := sub.DeleteObjectsWithReport(ctx, CloudFileRequest{wholeBucket: true})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), report.Deleted)
+	require.Equal(t, []string{"root.txt", "tenant-b/docs/y.txt", "tenant-b/x.txt"}, f.storedNames("bucket"))
+	require.Equal(t, []string{"tenant-a/x.txt"}, f.storedNames("other"))
+
+	// the parent client is unrestricted & stays open
+	require.NoError(t, sub.Close())
+	exists, err := cs.Exists(ctx, CloudFileRequest{bucket: "other", file: "x.txt", path: "tenant-a"})
+	require.NoError(t, err)
+	require.True(t, exists)
+}
+
+func TestScopedClientEscapes(t *testing.T) {
+	f := newFakeGCS()
+	putTenants(f)
+	f.put("other", "tenant-a/x.txt", []byte("other-x"), nil)
+	cs := newFakeClient(t, f)
+	sub := cs.Scoped("bucket", "tenant-a")
+	ctx := context.Background()
+
+	escapes := []CloudFileRequest{
+		{bucket: "other", file: "x.txt"},
+		{file: "x.txt", path: "/tenant-b"},
+		{file: "/root.txt"},
+		{file: "x.txt", path: "../tenant-b"},
+		{file: "x.txt", path: "docs/../../tenant-b"},
+		{file: "../root.txt"},
+		{file: "..", path: "docs"},
+		{file: "."},
+	}
+	for _, cfr := range escapes {
+		_, err := sub.Download(ctx, &bytes.Buffer{}, cfr)
+		requireScopeViolation(t, err)
+		_, err = sub.Upload(ctx, strings.NewReader("x"), cfr)
+		requireScopeViolation(t, err)
+		requireScopeViolation(t, sub.DeleteObject(ctx, cfr))
+		_, err = sub.DeleteObjectsWithReport(ctx, CloudFileRequest{bucket: cfr.bucket, path: cfr.path + "/" + cfr.file})
+		requireScopeViolation(t, err)
+	}
+
+	// empty file names don't address the prefix itself
+	require.ErrorIs(t, sub.DeleteObject(ctx, CloudFileRequest{bucket: "bucket"}), ErrFileNameMissing)
+	_, err := sub.DeleteObjectsWithReport(ctx, CloudFileRequest{path: "/"})
+	requireScopeViolation(t, err)
+
+	// context scopes apply within the boundary, another scope bucket is outside
+	_, err = sub.Download(WithScope(ctx, Scope{Bucket: "other"}), &bytes.Buffer{}, CloudFileRequest{file: "x.txt"})
+	requireScopeViolation(t, err)
+	buf := bytes.Buffer{}
+	_, err = sub.Download(WithScope(ctx, Scope{PathPrefix: ".."}), &buf, CloudFileRequest{file: "x.txt"})
+	require.NoError(t, err)
+	require.Equal(t, "a-x", buf.String(), "scope prefixes are cleaned")
+
+	// sources of copies are within the boundary too, whatever the source client
+	for _, source := range []CloudStorage{cs, sub} {
+		_, err = sub.CopyFrom(ctx, source, CloudFileRequest{bucket: "other", file: "x.txt"}, CloudFileRequest{file: "copy.txt"})
+		requireScopeViolation(t, err)
+		_, err = sub.CopyFrom(ctx, source, CloudFileRequest{file: "../root.txt"}, CloudFileRequest{file: "copy.txt"})
+		requireScopeViolation(t, err)
+	}
+
+	// bucket level changes reach outside the prefix
+	requireScopeViolation(t, sub.SetBucketVersioning(ctx, "bucket", true))
+	requireScopeViolation(t, sub.SetBucketLabels(ctx, "", map[string]string{"k": "v"}))
+	_, err = sub.WaitVisible(ctx, VisibilityToken{Bucket: "bucket", Object: "root.txt", Generation: 1}, 0)
+	requireScopeViolation(t, err)
+
+	// nothing outside the prefix changed
+	require.Equal(t, []string{"root.txt", "tenant-a/docs/y.txt", "tenant-a/x.txt", "tenant-b/docs/y.txt", "tenant-b/x.txt"}, f.storedNames("bucket"))
+	require.Equal(t, []string{"tenant-a/x.txt"}, f.storedNames("other"))
+	data, _, _ := f.get("bucket", "root.txt")
+	require.Equal(t, "root", string(data))
+}
+
+func TestScopedClientNarrows(t *testing.T) {
+	f := newFakeGCS()
+	putTenants(f)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	docs := cs.Scoped("bucket", "tenant-a").Scoped("", "docs")
+	names, err := docs.ListObjects(ctx, CloudFileRequest{wholeBucket: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"y.txt"}, names)
+	_, err = docs.Download(ctx, &bytes.Buffer{}, CloudFileRequest{file: "x.txt", path: ".."})
+	requireScopeViolation(t, err)
+
+	// narrowing never widens, other buckets & parent prefixes fail every call
+	for _, wider := range []CloudStorage{
+		docs.Scoped("other", ""),
+		docs.Scoped("bucket", ".."),
+		docs.Scoped("", "../../tenant-b"),
+	} {
+		_, err := wider.ListObjects(ctx, CloudFileRequest{wholeBucket: true})
+		requireScopeViolation(t, err)
+		_, err = wider.Download(ctx, &bytes.Buffer{}, CloudFileRequest{bucket: "bucket", file: "x.txt", path: "tenant-b"})
+		requireScopeViolation(t, err)
+	}
+	_, err = cs.Scoped("", "tenant-a").ListObjects(ctx, CloudFileRequest{bucket: "bucket", wholeBucket: true})
+	require.ErrorIs(t, err, ErrBucketNameMissing)
+
+	// clients scoped to a whole bucket may change it
+	whole := cs.Scoped("bucket", "")
+	require.NoError(t, whole.SetBucketVersioning(ctx, "", true))
+	requireScopeViolation(t, whole.SetBucketVersioning(ctx, "other", true))
+}
+
+func TestScopedClientPrefixNormalized(t *testing.T) {
+	f := newFakeGCS()
+	putTenants(f)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	// prefixes are normalized like object names, with leading or doubled slashes they resolve names as without
+	for _, prefix := range []string{"/tenant-a", "//tenant-a/", "tenant-a//"} {
+		sub := cs.Scoped("bucket", prefix)
+		var buf bytes.Buffer
+		_, err := sub.Download(ctx, &buf, CloudFileRequest{file: "x.txt"})
+		require.NoError(t, err, prefix)
+		require.Equal(t, "a-x", buf.String(), prefix)
+		names, err := sub.ListObjects(ctx, CloudFileRequest{wholeBucket: true})
+		require.NoError(t, err, prefix)
+		require.Equal(t, []string{"docs/y.txt", "x.txt"}, names, prefix)
+	}
+	names, err := cs.Scoped("bucket", "/tenant-a").Scoped("", "//docs").ListObjects(ctx, CloudFileRequest{wholeBucket: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"y.txt"}, names)
+
+	_, err = cs.Scoped("bucket", "/tenant-a/../tenant-b").ListObjects(ctx, CloudFileRequest{wholeBucket: true})
+	requireScopeViolation(t, err)
+}
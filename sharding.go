@@ -0,0 +1,121 @@
+package cloudstorage
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// shardSegment returns the stable shard segment of given name, the FNV-1a 32 hash of the name
+// modulo shards, lower case hex padded to the width of shards-1, empty for fewer than 2 shards.
+// The segment is part of the stored layout, it must never change for a given name & shard count.
+func shardSegment(name string, shards int) string {
+	if shards < 2 {
+		return ""
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	width := len(strconv.FormatInt(int64(shards-1), 16))
+	return fmt.Sprintf("%0*x", width, h.Sum32()%uint32(shards))
+}
+
+// ShardedName returns the physical object name of given logical name under prefix,
+// with a hash shard segment inserted before the name, e.g. prefix/3f/name,
+// so sequential names are spread over shards lexically apart. Fewer than 2 shards insert no segment.
+func ShardedName(prefix, name string, shards int) string {
+	return filepath.Join(prefix, shardSegment(name, shards), name)
+}
+
+// LogicalName returns the logical name of given physical object name under prefix,
+// false when the name isn't in the sharded layout of given shard count
+func LogicalName(prefix, physical string, shards int) (string, bool) {
+	if shards < 2 {
+		return physical, false
+	}
+	dir := dirPrefix(prefix)
+	if !strings.HasPrefix(physical, dir) {
+		return physical, false
+	}
+	rest := physical[len(dir):]
+	i := strings.Index(rest, "/")
+	if i < 0 {
+		return physical, false
+	}
+	seg, name := rest[:i], rest[i+1:]
+	if seg != shardSegment(name, shards) {
+		return physical, false
+	}
+	return filepath.Join(prefix, name), true
+}
+
+// WithShardedLayout addresses request's file by its logical name in a layout of given shard count,
+// uploads, downloads & single object calls use the physical ShardedName of path & file,
+// listings under path map physical names back, objects outside the layout are listed as stored.
+// Shard segment names are reserved directly under a sharded path.
+func WithShardedLayout(shards int) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.shards = shards
+	}
+}
+
+// sharded reports whether request uses a sharded layout
+func (cfr CloudFileRequest) sharded() bool {
+	return cfr.shards > 1
+}
+
+// logicalName maps given physical name under request path to its logical name,
+// relative to the request's scope prefix, names outside the request's sharded layout aren't unsharded
+func (cfr CloudFileRequest) logicalName(physical string) string {
+	name, _ := cfr.decodedLogicalName(physical)
+	return name
+}
+
+// decodedLogicalName returns the logical name of given physical name, false when the name codec failed to decode it
+func (cfr CloudFileRequest) decodedLogicalName(physical string) (string, bool) {
+	name, _ := LogicalName(cfr.path, physical, cfr.shards)
+	return cfr.decodedName(strings.TrimPrefix(name, cfr.scopePrefix))
+}
+
+// mapsNames reports whether request's physical names differ from the names returned to callers
+func (cfr CloudFileRequest) mapsNames() bool {
+	return cfr.sharded() || cfr.scopePrefix != "" || cfr.names != nil
+}
+
+// logicalAttrs returns given attributes with the logical object name
+func (cfr CloudFileRequest) logicalAttrs(attrs *ObjectAttrs) *ObjectAttrs {
+	if attrs != nil && cfr.mapsNames() {
+		// attributes may be shared with the existence cache
+		logical := *attrs
+		var decoded bool
+		logical.Name, decoded = cfr.decodedLogicalName(attrs.Name)
+		logical.NameNotDecoded = !decoded
+		return &logical
+	}
+	return attrs
+}
+
+// logicalNames maps given physical names to logical names, sorted as listings are
+func (cfr CloudFileRequest) logicalNames(names []string) []string {
+	if !cfr.mapsNames() {
+		return names
+	}
+	for i, name := range names {
+		names[i] = cfr.logicalName(name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isShardSegment reports whether given directory prefix directly under request path is a shard segment
+func (cfr CloudFileRequest) isShardSegment(dir string) bool {
+	seg := strings.TrimSuffix(strings.TrimPrefix(dir, dirPrefix(cfr.path)), "/")
+	width := len(strconv.FormatInt(int64(cfr.shards-1), 16))
+	if len(seg) != width {
+		return false
+	}
+	n, err := strconv.ParseUint(seg, 16, 32)
+	return err == nil && n < uint64(cfr.shards) && seg == strings.ToLower(seg)
+}
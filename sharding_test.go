@@ -0,0 +1,118 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestShardedNameStable pins the physical layout, stored objects become unreachable if these change
+func TestShardedNameStable(t *testing.T) {
+	for _, tc := range []struct {
+		prefix, name string
+		shards       int
+		want         string
+	}{
+		{prefix: "events/2024-01-01", name: "000001.json", shards: 256, want: "events/2024-01-01/ae/000001.json"},
+		{prefix: "events/2024-01-01", name: "000002.json", shards: 256, want: "events/2024-01-01/19/000002.json"},
+		{prefix: "events/2024-01-01", name: "000003.json", shards: 256, want: "events/2024-01-01/08/000003.json"},
+		{prefix: "logs", name: "a.txt", shards: 16, want: "logs/a/a.txt"},
+		{prefix: "logs", name: "a.txt", shards: 1000, want: "logs/232/a.txt"},
+		{prefix: "", name: "x/y.bin", shards: 64, want: "24/x/y.bin"},
+		{prefix: "logs", name: "a.txt", shards: 1, want: "logs/a.txt"},
+		{prefix: "logs", name: "a.txt", shards: 0, want: "logs/a.txt"},
+	} {
+		t.Run(tc.want, func(t *testing.T) {
+			physical := ShardedName(tc.prefix, tc.name, tc.shards)
+			require.Equal(t, tc.want, physical)
+
+			logical, ok := LogicalName(tc.prefix, physical, tc.shards)
+			require.Equal(t, tc.shards > 1, ok)
+			if ok {
+				require.Equal(t, strings.TrimPrefix(tc.prefix+"/"+tc.name, "/"), logical)
+			}
+		})
+	}
+
+	// names outside the layout
+	for _, physical := range []string{"logs/a.txt", "logs/b/a.txt", "other/a/a.txt", "logs/A/a.txt"} {
+		_, ok := LogicalName("logs", physical, 16)
+		require.False(t, ok, physical)
+	}
+}
+
+func TestShardedLayoutTransfers(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "000001.json", "events/2024-01-01", 0, WithShardedLayout(256))
+	require.NoError(t, err)
+
+	res, err := cs.Upload(ctx, strings.NewReader(`{"a":1}`), cfr)
+	require.NoError(t, err)
+	require.Equal(t, "events/2024-01-01/000001.json", res.Attrs.Name)
+	_, _, ok := f.get("bucket", "events/2024-01-01/ae/000001.json")
+	require.True(t, ok)
+
+	var buf bytes.Buffer
+	dres, err := cs.Download(ctx, &buf, cfr)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1}`, buf.String())
+	require.Equal(t, "events/2024-01-01/000001.json", dres.Attrs.Name)
+
+	attrs, err := cs.GetAttrs(ctx, cfr)
+	require.NoError(t, err)
+	require.Equal(t, "events/2024-01-01/000001.json", attrs.Name)
+
+	require.NoError(t, cs.DeleteObject(ctx, cfr))
+	_, _, ok = f.get("bucket", "events/2024-01-01/ae/000001.json")
+	require.False(t, ok)
+}
+
+func TestShardedLayoutListings(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	prefix := "events/2024-01-01"
+
+	logical := []string{}
+	for i := 0; i < 20; i++ {
+		cfr, err := NewCloudFileRequest("bucket", fmt.Sprintf("%06d.json", i), prefix, 0, WithShardedLayout(16))
+		require.NoError(t, err)
+		_, err = cs.Upload(ctx, strings.NewReader("{}"), cfr)
+		require.NoError(t, err)
+		logical = append(logical, prefix+"/"+fmt.Sprintf("%06d.json", i))
+	}
+	// nested names & objects outside the layout
+	nested, err := NewCloudFileRequest("bucket", "late/000100.json", prefix, 0, WithShardedLayout(16))
+	require.NoError(t, err)
+	_, err = cs.Upload(ctx, strings.NewReader("{}"), nested)
+	require.NoError(t, err)
+	f.put("bucket", prefix+"/legacy.json", []byte("{}"), nil)
+	f.put("bucket", prefix+"/raw/old.json", []byte("{}"), nil)
+
+	listReq, err := NewCloudFileRequest("bucket", "", prefix, 0, WithShardedLayout(16))
+	require.NoError(t, err)
+
+	names, err := cs.ListObjects(ctx, listReq)
+	require.NoError(t, err)
+	want := append(append([]string{}, logical...), prefix+"/late/000100.json", prefix+"/legacy.json", prefix+"/raw/old.json")
+	require.ElementsMatch(t, want, names)
+	require.IsIncreasing(t, names)
+
+	infos, err := cs.ListObjectsInfo(ctx, listReq)
+	require.NoError(t, err)
+	require.Len(t, infos, len(want))
+	for i, info := range infos {
+		require.Equal(t, names[i], info.Name)
+	}
+
+	listing, err := cs.ListDir(ctx, listReq)
+	require.NoError(t, err)
+	require.Equal(t, append(append([]string{}, logical...), prefix+"/legacy.json"), listing.Files)
+	require.Equal(t, []string{prefix + "/late/", prefix + "/raw/"}, listing.Dirs)
+}
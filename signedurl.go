@@ -0,0 +1,219 @@
+package cloudstorage
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_SIGNING_URL string = "error signing url"
+)
+
+const (
+	// DEFAULT_SIGNED_URL_TTL is the default signed URL validity
+	DEFAULT_SIGNED_URL_TTL = 15 * time.Minute
+	// DEFAULT_SIGNED_URL_MIN_REMAINING is the default fraction of TTL a cached URL must still be valid for
+	DEFAULT_SIGNED_URL_MIN_REMAINING = 0.2
+)
+
+// SignedURLOptions configure a signed URL
+type SignedURLOptions struct {
+	// Method is the HTTP method the URL is signed for, defaults to GET
+	Method string
+	// TTL is the URL validity, defaults to DEFAULT_SIGNED_URL_TTL
+	TTL time.Duration
+	// ContentType the request must send, for PUT URLs
+	ContentType string
+	// Headers the request must send
+	Headers []string
+	// QueryParameters signed into the URL
+	QueryParameters url.Values
+	// NoCache bypasses the signed URL cache for this call
+	NoCache bool
+	// VerifyExists checks the object exists before signing, missing objects fail with ErrObjectNotFound
+	VerifyExists bool
+	// SkipMissing makes SignedURLs mark missing objects skipped instead of failed, with VerifyExists
+	SkipMissing bool
+}
+
+// normalize fills defaults & orders headers and parameters
+func (o SignedURLOptions) normalize() SignedURLOptions {
+	if o.Method == "" {
+		o.Method = http.MethodGet
+	}
+	o.Method = strings.ToUpper(o.Method)
+	if o.TTL <= 0 {
+		o.TTL = DEFAULT_SIGNED_URL_TTL
+	}
+	if len(o.Headers) > 0 {
+		headers := make([]string, len(o.Headers))
+		for i, h := range o.Headers {
+			headers[i] = strings.TrimSpace(h)
+		}
+		sort.Strings(headers)
+		o.Headers = headers
+	}
+	return o
+}
+
+// cacheKey returns the cache key of given object & normalized options
+func (o SignedURLOptions) cacheKey(bucket, object string) string {
+	return strings.Join([]string{
+		bucket,
+		object,
+		o.Method,
+		o.TTL.String(),
+		o.ContentType,
+		strings.Join(o.Headers, "\n"),
+		o.QueryParameters.Encode(),
+	}, "\x00")
+}
+
+// SignedURL returns a V4 signed URL for request's object,
+// served from the signed URL cache when configured & a cached URL is still valid long enough
+func (cs *cloudStorageClient) SignedURL(ctx context.Context, cfr CloudFileRequest, opts SignedURLOptions) (string, error) {
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return "", err
+	}
+	if cfr.bucket == "" {
+		return "", ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return "", ErrFileNameMissing
+	}
+	opts = opts.normalize()
+	// signed writes would bypass the read only guard
+	if opts.Method != http.MethodGet && opts.Method != http.MethodHead {
+		if err := cs.mutation(); err != nil {
+			return "", err
+		}
+	}
+	op := cs.startOperation(ctx, "SignedURL", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	if opts.VerifyExists {
+		if _, err := cs.statObject(ctx, op, cfr); err != nil {
+			op.logger.Error(ERROR_SIGNING_URL, zap.Error(err), zap.String("filepath", op.object))
+			return "", op.wrapError(err, "%s %s", ERROR_SIGNING_URL, op.object)
+		}
+	}
+	u, err := cs.signURL(op, cfr.bucket, op.object, opts, nil)
+	if err != nil {
+		op.logger.Error(ERROR_SIGNING_URL, zap.Error(err), zap.String("filepath", op.object))
+		return "", op.wrapError(err, "%s %s", ERROR_SIGNING_URL, op.object)
+	}
+	return u, nil
+}
+
+// signURL signs given object's URL with normalized options, served from the cache when enabled & still valid.
+// A nil signer leaves credential detection to the storage client, on every call.
+func (cs *cloudStorageClient) signURL(op *operation, bucket, object string, opts SignedURLOptions, signer *urlSigner) (string, error) {
+	var key string
+	if cs.urlCache != nil && !opts.NoCache {
+		key = opts.cacheKey(bucket, object)
+		if u, ok := cs.urlCache.get(key); ok {
+			op.logger.Debug("signed url cache hit", zap.String("filepath", object), zap.String("method", opts.Method))
+			return u, nil
+		}
+	}
+
+	expires := cs.now().Add(opts.TTL)
+	sOpts := &storage.SignedURLOptions{
+		Method:          opts.Method,
+		Expires:         expires,
+		ContentType:     opts.ContentType,
+		Headers:         opts.Headers,
+		QueryParameters: opts.QueryParameters,
+		Scheme:          storage.SigningSchemeV4,
+	}
+	if signer != nil {
+		sOpts.GoogleAccessID, sOpts.SignBytes = signer.accessID, signer.signBytes
+	}
+	u, err := cs.client.Bucket(bucket).SignedURL(object, sOpts)
+	if err != nil {
+		return "", err
+	}
+	if key != "" {
+		cs.urlCache.put(key, u, opts.TTL, expires)
+	}
+	return u, nil
+}
+
+type signedURLEntry struct {
+	key     string
+	url     string
+	ttl     time.Duration
+	expires time.Time
+}
+
+// signedURLCache is a size bounded LRU cache of signed URLs, safe for concurrent use
+type signedURLCache struct {
+	mu           sync.Mutex
+	size         int
+	minRemaining float64
+	now          func() time.Time
+	entries      map[string]*list.Element
+	lru          *list.List
+}
+
+// newSignedURLCache returns a cache of given size, expiring URLs by given clock
+func newSignedURLCache(size int, minRemaining float64, now func() time.Time) *signedURLCache {
+	if minRemaining <= 0 || minRemaining >= 1 {
+		minRemaining = DEFAULT_SIGNED_URL_MIN_REMAINING
+	}
+	return &signedURLCache{
+		size:         size,
+		minRemaining: minRemaining,
+		now:          now,
+		entries:      map[string]*list.Element{},
+		lru:          list.New(),
+	}
+}
+
+// get returns cached URL if its remaining validity exceeds the threshold fraction of its TTL
+func (c *signedURLCache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	entry := el.Value.(*signedURLEntry)
+	remaining := entry.expires.Sub(c.now())
+	if remaining <= time.Duration(float64(entry.ttl)*c.minRemaining) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return "", false
+	}
+	c.lru.MoveToFront(el)
+	return entry.url, true
+}
+
+func (c *signedURLCache) put(key, u string, ttl time.Duration, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = &signedURLEntry{key: key, url: u, ttl: ttl, expires: expires}
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&signedURLEntry{key: key, url: u, ttl: ttl, expires: expires})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*signedURLEntry).key)
+	}
+}
@@ -0,0 +1,92 @@
+package cloudstorage
+
+import (
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedURLCacheExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newSignedURLCache(10, 0.2, func() time.Time { return now })
+
+	ttl := 10 * time.Minute
+	c.put("k", "https://signed/1", ttl, now.Add(ttl))
+
+	// plenty of validity left
+	now = now.Add(7 * time.Minute)
+	u, ok := c.get("k")
+	require.True(t, ok)
+	require.Equal(t, "https://signed/1", u)
+
+	// remaining 2m isn't more than 20% of TTL, re-sign
+	now = now.Add(time.Minute)
+	_, ok = c.get("k")
+	require.False(t, ok)
+	_, ok = c.get("k")
+	require.False(t, ok)
+}
+
+func TestSignedURLCacheLRU(t *testing.T) {
+	now := time.Now()
+	c := newSignedURLCache(2, 0, func() time.Time { return now })
+
+	c.put("a", "ua", time.Hour, now.Add(time.Hour))
+	c.put("b", "ub", time.Hour, now.Add(time.Hour))
+	_, ok := c.get("a")
+	require.True(t, ok)
+	c.put("c", "uc", time.Hour, now.Add(time.Hour))
+
+	_, ok = c.get("b")
+	require.False(t, ok, "least recently used evicted")
+	_, ok = c.get("a")
+	require.True(t, ok)
+	_, ok = c.get("c")
+	require.True(t, ok)
+}
+
+func TestSignedURLCacheKey(t *testing.T) {
+	base := SignedURLOptions{}.normalize()
+	require.Equal(t, "GET", base.Method)
+	require.Equal(t, DEFAULT_SIGNED_URL_TTL, base.TTL)
+
+	key := base.cacheKey("bucket", "a.json")
+	require.Equal(t, key, SignedURLOptions{Method: "get", TTL: DEFAULT_SIGNED_URL_TTL}.normalize().cacheKey("bucket", "a.json"))
+
+	// header order doesn't matter
+	h1 := SignedURLOptions{Headers: []string{"x-goog-a:1", "x-goog-b:2"}}.normalize().cacheKey("bucket", "a.json")
+	h2 := SignedURLOptions{Headers: []string{"x-goog-b:2", " x-goog-a:1"}}.normalize().cacheKey("bucket", "a.json")
+	require.Equal(t, h1, h2)
+
+	for name, opts := range map[string]SignedURLOptions{
+		"method":       {Method: "PUT"},
+		"ttl":          {TTL: time.Hour},
+		"content type": {ContentType: "text/csv"},
+		"headers":      {Headers: []string{"x-goog-a:1"}},
+		"query":        {QueryParameters: url.Values{"response-content-disposition": {"attachment"}}},
+	} {
+		require.NotEqual(t, key, opts.normalize().cacheKey("bucket", "a.json"), name)
+	}
+	require.NotEqual(t, key, base.cacheKey("bucket", "b.json"))
+	require.NotEqual(t, key, base.cacheKey("other", "a.json"))
+}
+
+func TestSignedURLCacheConcurrent(t *testing.T) {
+	c := newSignedURLCache(8, 0, time.Now)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := string(rune('a' + i%10))
+			c.put(key, key, time.Hour, time.Now().Add(time.Hour))
+			c.get(key)
+		}(i)
+	}
+	wg.Wait()
+	require.LessOrEqual(t, c.lru.Len(), 8)
+	require.Equal(t, c.lru.Len(), len(c.entries))
+}
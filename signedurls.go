@@ -0,0 +1,232 @@
+package cloudstorage
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+	iamcredentials "google.golang.org/api/iamcredentials/v1"
+	"google.golang.org/api/option"
+)
+
+const (
+	ERROR_PREPARING_SIGNER string = "error preparing url signing credentials"
+)
+
+// DEFAULT_SIGNED_URL_VERIFY_CONCURRENCY is the number of concurrent existence checks of SignedURLs
+const DEFAULT_SIGNED_URL_VERIFY_CONCURRENCY = 8
+
+// SignedURLResult is the signed URL of one SignedURLs request
+type SignedURLResult struct {
+	Bucket string
+	Object string
+	URL    string
+	// Skipped is set for missing objects with VerifyExists & SkipMissing, no URL is signed
+	Skipped bool
+	Err     error
+}
+
+// urlSigner holds signing credentials resolved once, for many signatures
+type urlSigner struct {
+	accessID  string
+	signBytes func([]byte) ([]byte, error)
+}
+
+// credentialsFile returns configured credentials path, or the application default credentials file
+func (cs *cloudStorageClient) credentialsFile() string {
+	if cs.config.CredsPath != "" {
+		return cs.config.CredsPath
+	}
+	return os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+}
+
+// prepareSigner resolves the signing credentials of the client's credentials file once:
+// service account keys are parsed for local signing, impersonated service accounts
+// sign through one IAM credentials client. Returns nil for other credentials,
+// e.g. on GCE, leaving detection to the storage client.
+func (cs *cloudStorageClient) prepareSigner(ctx context.Context) (*urlSigner, error) {
+	credsPath := cs.credentialsFile()
+	if credsPath == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(credsPath)
+	if err != nil {
+		return nil, err
+	}
+	var creds struct {
+		Type             string `json:"type"`
+		ClientEmail      string `json:"client_email"`
+		PrivateKey       string `json:"private_key"`
+		ImpersonationURL string `json:"service_account_impersonation_url"`
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, err
+	}
+
+	switch creds.Type {
+	case "service_account":
+		key, err := parsePrivateKey([]byte(creds.PrivateKey))
+		if err != nil {
+			return nil, err
+		}
+		return &urlSigner{
+			accessID: creds.ClientEmail,
+			signBytes: func(b []byte) ([]byte, error) {
+				sum := sha256.Sum256(b)
+				return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+			},
+		}, nil
+	case "impersonated_service_account":
+		// ".../serviceAccounts/{email}:generateAccessToken"
+		start, end := strings.LastIndex(creds.ImpersonationURL, "/"), strings.LastIndex(creds.ImpersonationURL, ":")
+		if end <= start {
+			return nil, errors.NewAppError("invalid impersonation url %s", creds.ImpersonationURL)
+		}
+		email := creds.ImpersonationURL[start+1 : end]
+		svc, err := iamcredentials.NewService(ctx, option.WithCredentialsFile(credsPath))
+		if err != nil {
+			return nil, err
+		}
+		return &urlSigner{
+			accessID: email,
+			signBytes: func(b []byte) ([]byte, error) {
+				resp, err := svc.Projects.ServiceAccounts.SignBlob(fmt.Sprintf("projects/-/serviceAccounts/%s", email), &iamcredentials.SignBlobRequest{
+					Payload: base64.StdEncoding.EncodeToString(b),
+				}).Context(ctx).Do()
+				if err != nil {
+					return nil, err
+				}
+				return base64.StdEncoding.DecodeString(resp.SignedBlob)
+			},
+		}, nil
+	}
+	return nil, nil
+}
+
+// parsePrivateKey parses a PEM or DER, PKCS8 or PKCS1 RSA private key
+func parsePrivateKey(key []byte) (*rsa.PrivateKey, error) {
+	if block, _ := pem.Decode(key); block != nil {
+		key = block.Bytes
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(key)
+	if err != nil {
+		return x509.ParsePKCS1PrivateKey(key)
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.NewAppError("private key isn't an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// SignedURLs returns V4 signed URLs for given requests in input order, preparing the signing credentials once.
+// With VerifyExists objects are checked first, concurrently, missing objects fail their result,
+// or are skipped with SkipMissing. Every request is signed, the first failure is returned with the results.
+func (cs *cloudStorageClient) SignedURLs(ctx context.Context, cfrs []CloudFileRequest, opts SignedURLOptions) ([]SignedURLResult, error) {
+	opts = opts.normalize()
+	// signed writes would bypass the read only guard
+	if opts.Method != http.MethodGet && opts.Method != http.MethodHead {
+		if err := cs.mutation(); err != nil {
+			return nil, err
+		}
+	}
+	var batch CloudFileRequest
+	if len(cfrs) > 0 {
+		batch.bucket = cfrs[0].bucket
+	}
+	op := cs.startOperation(ctx, "SignedURLs", batch)
+	defer op.finish()
+	ctx = op.ctx
+
+	results := make([]SignedURLResult, len(cfrs))
+	if len(cfrs) == 0 {
+		return results, nil
+	}
+	signer, err := cs.prepareSigner(ctx)
+	if err != nil {
+		op.logger.Error(ERROR_PREPARING_SIGNER, zap.Error(err))
+		return nil, op.wrapError(err, ERROR_PREPARING_SIGNER)
+	}
+
+	scoped := make([]CloudFileRequest, len(cfrs))
+	for i, cfr := range cfrs {
+		results[i] = SignedURLResult{Bucket: cfr.bucket, Object: cfr.objectPath()}
+		if scoped[i], err = cs.request(ctx, cfr); err != nil {
+			results[i].Err = err
+			continue
+		}
+		cfr = scoped[i]
+		results[i] = SignedURLResult{Bucket: cfr.bucket, Object: cfr.objectPath()}
+		switch {
+		case cfr.bucket == "":
+			results[i].Err = ErrBucketNameMissing
+		case cfr.file == "":
+			results[i].Err = ErrFileNameMissing
+		}
+	}
+	if opts.VerifyExists {
+		cs.verifySignedURLObjects(ctx, op, scoped, results, opts.SkipMissing)
+	}
+
+	var firstErr error
+	for i := range results {
+		res := &results[i]
+		if res.Err == nil && !res.Skipped {
+			itemOp := op.forObject(res.Bucket, res.Object)
+			u, err := cs.signURL(itemOp, res.Bucket, res.Object, opts, signer)
+			if err != nil {
+				op.logger.Error(ERROR_SIGNING_URL, zap.Error(err), zap.String("filepath", res.Object))
+				res.Err = itemOp.wrapError(err, "%s %s", ERROR_SIGNING_URL, res.Object)
+			}
+			res.URL = u
+		}
+		if res.Err != nil && firstErr == nil {
+			firstErr = res.Err
+		}
+	}
+	op.logger.Debug("signed urls", zap.Int("requests", len(cfrs)), zap.Bool("preparedSigner", signer != nil))
+	return results, firstErr
+}
+
+// verifySignedURLObjects checks given requests' objects exist, concurrently, sets missing & failed results
+func (cs *cloudStorageClient) verifySignedURLObjects(ctx context.Context, op *operation, cfrs []CloudFileRequest, results []SignedURLResult, skipMissing bool) {
+	sem := make(chan struct{}, DEFAULT_SIGNED_URL_VERIFY_CONCURRENCY)
+	var wg sync.WaitGroup
+	for i, cfr := range cfrs {
+		if results[i].Err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, cfr CloudFileRequest) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			itemOp := op.forObject(cfr.bucket, results[i].Object)
+			_, err := cs.statObject(ctx, itemOp, cfr)
+			switch {
+			case err == nil:
+			case err == storage.ErrObjectNotExist && skipMissing:
+				results[i].Skipped = true
+			default:
+				op.logger.Error(ERROR_SIGNING_URL, zap.Error(err), zap.String("filepath", itemOp.object))
+				results[i].Err = itemOp.wrapError(err, "%s %s", ERROR_SIGNING_URL, itemOp.object)
+			}
+		}(i, cfr)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,168 @@
+package cloudstorage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+const testSignerEmail = "signer@project.iam.gserviceaccount.com"
+
+// writeServiceAccountKey writes a service account credentials file with a new RSA key
+func writeServiceAccountKey(t testing.TB) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "project",
+		"client_email": testSignerEmail,
+		"client_id":    "1",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    "https://oauth2.googleapis.com/token",
+	})
+	require.NoError(t, err)
+	credsPath := filepath.Join(t.TempDir(), "creds.json")
+	require.NoError(t, os.WriteFile(credsPath, data, 0600))
+	return credsPath
+}
+
+func TestSignedURLsInputOrder(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	cs.config.CredsPath = writeServiceAccountKey(t)
+	ctx := context.Background()
+
+	cfrs := []CloudFileRequest{
+		{bucket: "bucket", path: "gallery", file: "3.jpg"},
+		{bucket: "bucket", path: "gallery", file: "1.jpg"},
+		{bucket: "bucket", path: "gallery"},
+		{bucket: "other", file: "2.jpg"},
+	}
+	results, err := cs.SignedURLs(ctx, cfrs, SignedURLOptions{})
+	require.ErrorIs(t, err, ErrFileNameMissing)
+	require.Len(t, results, len(cfrs))
+
+	for i, want := range []string{"/bucket/gallery/3.jpg", "/bucket/gallery/1.jpg", "", "/other/2.jpg"} {
+		res := results[i]
+		if want == "" {
+			require.ErrorIs(t, res.Err, ErrFileNameMissing)
+			require.Empty(t, res.URL)
+			continue
+		}
+		require.NoError(t, res.Err)
+		u, err := url.Parse(res.URL)
+		require.NoError(t, err)
+		require.Equal(t, want, u.Path)
+		require.True(t, strings.HasPrefix(u.Query().Get("X-Goog-Credential"), testSignerEmail+"/"), res.URL)
+		require.NotEmpty(t, u.Query().Get("X-Goog-Signature"))
+	}
+}
+
+func TestSignedURLsVerifyExists(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "gallery/1.jpg", []byte("jpg"), nil)
+	cs := newFakeClient(t, f)
+	cs.config.CredsPath = writeServiceAccountKey(t)
+	ctx := context.Background()
+
+	cfrs := []CloudFileRequest{
+		{bucket: "bucket", path: "gallery", file: "1.jpg"},
+		{bucket: "bucket", path: "gallery", file: "missing.jpg"},
+	}
+	results, err := cs.SignedURLs(ctx, cfrs, SignedURLOptions{VerifyExists: true})
+	require.ErrorIs(t, err, ErrObjectNotFound)
+	require.NoError(t, results[0].Err)
+	require.NotEmpty(t, results[0].URL)
+	require.ErrorIs(t, results[1].Err, ErrObjectNotFound)
+	require.Empty(t, results[1].URL)
+
+	results, err = cs.SignedURLs(ctx, cfrs, SignedURLOptions{VerifyExists: true, SkipMissing: true})
+	require.NoError(t, err)
+	require.NotEmpty(t, results[0].URL)
+	require.False(t, results[0].Skipped)
+	require.True(t, results[1].Skipped)
+	require.NoError(t, results[1].Err)
+	require.Empty(t, results[1].URL)
+}
+
+func TestSignedURLsCache(t *testing.T) {
+	cs := newFakeClient(t, newFakeGCS())
+	cs.config.CredsPath = writeServiceAccountKey(t)
+	cs.urlCache = newSignedURLCache(10, 0.2, cs.now)
+	ctx := context.Background()
+
+	cfrs := []CloudFileRequest{{bucket: "bucket", file: "a.jpg"}, {bucket: "bucket", file: "b.jpg"}}
+	first, err := cs.SignedURLs(ctx, cfrs, SignedURLOptions{})
+	require.NoError(t, err)
+	second, err := cs.SignedURLs(ctx, cfrs, SignedURLOptions{})
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	require.NotEqual(t, first[0].URL, first[1].URL)
+}
+
+func TestSignedURLsReadOnlyWrites(t *testing.T) {
+	cs := newFakeClient(t, newFakeGCS())
+	cs.config.CredsPath = writeServiceAccountKey(t)
+	cs.config.ReadOnly = true
+	ctx := context.Background()
+	cfrs := []CloudFileRequest{{bucket: "bucket", file: "a.jpg"}}
+
+	_, err := cs.SignedURLs(ctx, cfrs, SignedURLOptions{Method: "PUT"})
+	require.ErrorIs(t, err, ErrReadOnlyClient)
+	_, err = cs.SignedURLs(ctx, cfrs, SignedURLOptions{})
+	require.NoError(t, err)
+}
+
+// BenchmarkSignedURLs compares signing a gallery page of URLs one SignedURL call at a time,
+// each detecting & parsing the credentials, with one SignedURLs batch preparing them once.
+func BenchmarkSignedURLs(b *testing.B) {
+	const page = 200
+	credsPath := writeServiceAccountKey(b)
+	client, err := storage.NewClient(context.Background(), option.WithCredentialsFile(credsPath))
+	require.NoError(b, err)
+	b.Cleanup(func() { client.Close() })
+	cs := &cloudStorageClient{
+		client: client,
+		logger: &recordingLogger{},
+		config: CloudStorageClientConfig{CredsPath: credsPath},
+	}
+	ctx := context.Background()
+	cfrs := make([]CloudFileRequest, page)
+	for i := range cfrs {
+		cfrs[i] = CloudFileRequest{bucket: "bucket", path: "gallery", file: fmt.Sprintf("%03d.jpg", i)}
+	}
+
+	b.Run("loop", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			for _, cfr := range cfrs {
+				if _, err := cs.SignedURL(ctx, cfr, SignedURLOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := cs.SignedURLs(ctx, cfrs, SignedURLOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
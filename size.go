@@ -0,0 +1,66 @@
+package cloudstorage
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/comfforts/errors"
+)
+
+const (
+	ERROR_SIZE_EXCEEDED    string = "upload exceeds declared size"
+	ERROR_TRUNCATED_UPLOAD string = "upload shorter than declared size"
+)
+
+var (
+	ErrSizeExceeded    = errors.NewAppError(ERROR_SIZE_EXCEEDED)
+	ErrTruncatedUpload = errors.NewAppError(ERROR_TRUNCATED_UPLOAD)
+)
+
+// UploadSizeError reports declared vs actual upload size,
+// unwraps to ErrSizeExceeded or ErrTruncatedUpload
+type UploadSizeError struct {
+	Err      error
+	Expected int64
+	Actual   int64
+}
+
+func (e UploadSizeError) Error() string {
+	return fmt.Sprintf("%s, expected %d bytes, got %d", e.Err.Error(), e.Expected, e.Actual)
+}
+
+// Unwrap returns the sentinel error
+func (e UploadSizeError) Unwrap() error {
+	return e.Err
+}
+
+// WithSize declares the upload's size, uploads producing more bytes fail
+// as soon as the size is exceeded, uploads ending early fail as truncated
+func WithSize(size int64) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.size = size
+	}
+}
+
+// sizeCheckReader enforces the declared size of the underlying reader
+type sizeCheckReader struct {
+	r    io.Reader
+	size int64
+	n    int64
+}
+
+func (sr *sizeCheckReader) Read(p []byte) (int, error) {
+	// read at most one byte past declared size to detect overflow
+	if limit := sr.size - sr.n + 1; int64(len(p)) > limit {
+		p = p[:limit]
+	}
+	n, err := sr.r.Read(p)
+	sr.n += int64(n)
+	if sr.n > sr.size {
+		return n - int(sr.n-sr.size), UploadSizeError{Err: ErrSizeExceeded, Expected: sr.size, Actual: sr.n}
+	}
+	if err == io.EOF && sr.n < sr.size {
+		return n, UploadSizeError{Err: ErrTruncatedUpload, Expected: sr.size, Actual: sr.n}
+	}
+	return n, err
+}
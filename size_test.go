@@ -0,0 +1,52 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSizeCheckReader(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 100)
+
+	for scenario, tc := range map[string]struct {
+		size    int64
+		wantErr error
+		wantN   int64
+	}{
+		"exact size":     {size: 100, wantN: 100},
+		"size exceeded":  {size: 40, wantErr: ErrSizeExceeded, wantN: 40},
+		"truncated read": {size: 120, wantErr: ErrTruncatedUpload, wantN: 100},
+	} {
+		t.Run(scenario, func(t *testing.T) {
+			var buf bytes.Buffer
+			sr := &sizeCheckReader{r: iotest.HalfReader(bytes.NewReader(data)), size: tc.size}
+			n, err := io.Copy(&buf, sr)
+			require.Equal(t, tc.wantN, n)
+			require.Equal(t, tc.wantN, int64(buf.Len()))
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, errors.Is(err, tc.wantErr))
+			var se UploadSizeError
+			require.True(t, errors.As(err, &se))
+			require.Equal(t, tc.size, se.Expected)
+			require.NotEqual(t, se.Expected, se.Actual)
+		})
+	}
+}
+
+func TestSizeCheckSpooledOverflow(t *testing.T) {
+	sr := &sizeCheckReader{r: bytes.NewReader(make([]byte, 64)), size: 32}
+	_, err := spool(sr, 16, t.TempDir(), defaultBufferPool)
+	require.True(t, errors.Is(err, ErrSizeExceeded))
+
+	// matched through operation error wrapping
+	op := &operation{name: "UploadFile"}
+	require.True(t, errors.Is(op.wrapError(err, ERROR_SPOOLING_UPLOAD), ErrSizeExceeded))
+}
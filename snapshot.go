@@ -0,0 +1,364 @@
+package cloudstorage
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_LISTING_VERSIONS   string = "error listing cloud file versions"
+	ERROR_RESTORING_SNAPSHOT string = "error restoring cloud file snapshot"
+)
+
+// ObjectVersion is a generation of a cloud file
+type ObjectVersion struct {
+	Bucket     string
+	Name       string
+	Generation int64
+	Size       int64
+	CRC32C     uint32
+	Created    time.Time
+	Updated    time.Time
+	// Deleted is when the generation became noncurrent or soft deleted, zero for the live generation
+	Deleted time.Time
+	// HardDeleted is when a soft deleted generation is permanently removed, zero otherwise
+	HardDeleted time.Time
+}
+
+// newObjectVersion returns the version of given listed generation, named relative to request's scope
+func newObjectVersion(cfr CloudFileRequest, attrs *storage.ObjectAttrs) ObjectVersion {
+	return ObjectVersion{
+		Bucket:     attrs.Bucket,
+		Name:       cfr.unscopedName(attrs.Name),
+		Generation: attrs.Generation,
+		Size:       attrs.Size,
+		CRC32C:     attrs.CRC32C,
+		Created:    attrs.Created,
+		Updated:    attrs.Updated,
+		Deleted:    attrs.Deleted,
+	}
+}
+
+// liveAt reports whether the version was the object's content at given time
+func (v ObjectVersion) liveAt(at time.Time) bool {
+	return !v.Created.After(at) && (v.Deleted.IsZero() || v.Deleted.After(at))
+}
+
+// RestoreActionKind is what restore does, or would do on a dry run, to a destination object
+type RestoreActionKind string
+
+const (
+	// RestoreCopy copies the snapshot generation to the destination
+	RestoreCopy RestoreActionKind = "copy"
+	// RestoreSkip leaves a destination whose content matches the snapshot generation
+	RestoreSkip RestoreActionKind = "skip"
+	// RestoreDelete deletes a destination object that isn't in the snapshot
+	RestoreDelete RestoreActionKind = "delete"
+)
+
+// RestoreAction is the action on one destination object
+type RestoreAction struct {
+	Action RestoreActionKind `json:"action"`
+	// Source is the snapshot object name, empty for deletes
+	Source     string `json:"source,omitempty"`
+	Generation int64  `json:"generation"`
+	Dest       string `json:"dest"`
+	// Err is the action's error, nil on success & on dry runs, Error is its message
+	Err   error  `json:"-"`
+	Error string `json:"error,omitempty"`
+}
+
+// RestoreReport reports the actions of a restore, or the planned actions of a dry run
+type RestoreReport struct {
+	DryRun  bool            `json:"dry_run"`
+	Actions []RestoreAction `json:"actions"`
+}
+
+// Failures returns the number of failed actions
+func (r RestoreReport) Failures() int {
+	n := 0
+	for _, a := range r.Actions {
+		if a.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// RestoreOptions configure RestoreSnapshot
+type RestoreOptions struct {
+	// DryRun reports planned actions without changing any object
+	DryRun bool
+	// DeleteExtra, when set, is the snapshot's request, destination objects under its path
+	// that aren't in the snapshot are deleted
+	DeleteExtra *CloudFileRequest
+	// RequireEmptyDestination fails the restore with DestinationNotEmptyError, before any copy,
+	// when the destination prefix has objects, restores overwrite differing objects otherwise
+	RequireEmptyDestination bool
+	// KMSKeyName is the Cloud KMS key restored objects are encrypted with, the bucket's default key when empty
+	KMSKeyName string
+}
+
+// RestoreOption sets restore options
+type RestoreOption func(o *RestoreOptions)
+
+// WithDryRun makes restore report planned actions only
+func WithDryRun() RestoreOption {
+	return func(o *RestoreOptions) {
+		o.DryRun = true
+	}
+}
+
+// WithRequireEmptyDestination fails the restore when the destination prefix isn't empty,
+// directory markers aside, the explicit alternative to overwriting
+func WithRequireEmptyDestination() RestoreOption {
+	return func(o *RestoreOptions) {
+		o.RequireEmptyDestination = true
+	}
+}
+
+// WithRestoreKMSKey encrypts restored objects with given Cloud KMS key name
+func WithRestoreKMSKey(keyName string) RestoreOption {
+	return func(o *RestoreOptions) {
+		o.KMSKeyName = keyName
+	}
+}
+
+// WithDeleteExtra deletes destination objects under the snapshot request's path that aren't in the snapshot,
+// objects created after the snapshot time are omitted otherwise
+func WithDeleteExtra(snapshot CloudFileRequest) RestoreOption {
+	return func(o *RestoreOptions) {
+		o.DeleteExtra = &snapshot
+	}
+}
+
+// SnapshotPrefix returns, for every object under request path, the generation that was live at given time.
+// A generation is live from its creation until it became noncurrent, metadata updates don't change it.
+// Objects created after, or deleted before, given time are omitted. Requires bucket object versioning.
+func (cs *cloudStorageClient) SnapshotPrefix(ctx context.Context, cfr CloudFileRequest, at time.Time) ([]ObjectVersion, error) {
+	cfr, err := cs.prefixRequest(ctx, cfr)
+	if err != nil {
+		return nil, err
+	}
+	if cfr.bucket == "" {
+		return nil, ErrBucketNameMissing
+	}
+	op := cs.startOperation(ctx, "SnapshotPrefix", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	prefix := dirPrefix(cfr.path)
+	op.object = prefix
+
+	snapshot := []ObjectVersion{}
+	latest := map[string]int{}
+	it := cs.bucketHandle(cfr).Objects(ctx, &storage.Query{Prefix: prefix, Versions: true})
+	for {
+		attrs, err := it.Next()
+		if err != nil {
+			if err == iterator.Done {
+				break
+			}
+			op.logger.Error(ERROR_LISTING_VERSIONS, zap.Error(err), zap.String("prefix", prefix))
+			names := make([]string, len(snapshot))
+			for i, v := range snapshot {
+				names[i] = v.Name
+			}
+			return nil, op.wrapError(partialList(names, err), "%s %s", ERROR_LISTING_VERSIONS, prefix)
+		}
+		v := newObjectVersion(cfr, attrs)
+		if !v.liveAt(at) {
+			continue
+		}
+		if i, ok := latest[v.Name]; ok {
+			if snapshot[i].Generation < v.Generation {
+				snapshot[i] = v
+			}
+			continue
+		}
+		latest[v.Name] = len(snapshot)
+		snapshot = append(snapshot, v)
+	}
+	op.logger.Debug("cloud file snapshot listed", zap.String("prefix", prefix), zap.Time("at", at), zap.Int("objects", len(snapshot)))
+	return snapshot, nil
+}
+
+// RestoreSnapshot copies the snapshot's generations, server side, under given destination prefix
+// in the snapshot's bucket, or over the live objects when the prefix is empty.
+// Copies are conditional on the destination generation seen when planning,
+// destinations with the snapshot's size & CRC32C are skipped.
+// Failed actions are reported, the first failure is returned after all actions ran.
+func (cs *cloudStorageClient) RestoreSnapshot(ctx context.Context, snapshot []ObjectVersion, dstPrefix string, opts ...RestoreOption) (RestoreReport, error) {
+	rOpts := RestoreOptions{}
+	for _, opt := range opts {
+		opt(&rOpts)
+	}
+	if !rOpts.DryRun {
+		if err := cs.mutation(); err != nil {
+			return RestoreReport{}, err
+		}
+	}
+	dstPrefix, err := destinationPrefix(dstPrefix)
+	if err != nil {
+		return RestoreReport{}, err
+	}
+	// snapshot names & destinations are relative to the context scope, if any
+	scope, err := cs.scoped(ctx, CloudFileRequest{})
+	if err != nil {
+		return RestoreReport{}, err
+	}
+	versions := make([]ObjectVersion, len(snapshot))
+	srcs := make([]CloudFileRequest, len(snapshot))
+	dests := make([]CloudFileRequest, len(snapshot))
+	for i, v := range snapshot {
+		src, err := cs.scoped(ctx, CloudFileRequest{bucket: v.Bucket, file: v.Name})
+		if err != nil {
+			return RestoreReport{}, err
+		}
+		if src.bucket == "" {
+			return RestoreReport{}, ErrBucketNameMissing
+		}
+		if v.Name == "" {
+			return RestoreReport{}, ErrFileNameMissing
+		}
+		dst, err := cs.scoped(ctx, CloudFileRequest{bucket: v.Bucket, file: path.Join(dstPrefix, v.Name)})
+		if err != nil {
+			return RestoreReport{}, err
+		}
+		dst.kmsKeyName = rOpts.KMSKeyName
+		v.Bucket, v.Name = src.bucket, src.objectPath()
+		versions[i], srcs[i], dests[i] = v, src, dst
+	}
+	snapshot = versions
+	if rOpts.DeleteExtra != nil {
+		// extra objects are listed under the snapshot's path in the destination prefix
+		extra, err := cs.scoped(ctx, CloudFileRequest{bucket: rOpts.DeleteExtra.bucket, path: path.Join(dstPrefix, rOpts.DeleteExtra.path)})
+		if err != nil {
+			return RestoreReport{}, err
+		}
+		rOpts.DeleteExtra = &extra
+	}
+	bucket := ""
+	if len(snapshot) > 0 {
+		bucket = snapshot[0].Bucket
+	}
+	if rOpts.DeleteExtra != nil {
+		if bucket == "" {
+			bucket = rOpts.DeleteExtra.bucket
+		}
+		if rOpts.DeleteExtra.bucket == "" {
+			return RestoreReport{}, ErrBucketNameMissing
+		}
+	}
+	op := cs.startOperation(ctx, "RestoreSnapshot", CloudFileRequest{bucket: bucket})
+	defer op.finish()
+	ctx = op.ctx
+	op.audited = op.audited && !rOpts.DryRun
+	op.object = dstPrefix
+	if !rOpts.DryRun {
+		if err := cs.checkDeadlineBudget(ctx, op, len(snapshot), 1); err != nil {
+			return RestoreReport{DryRun: rOpts.DryRun}, err
+		}
+	}
+
+	if rOpts.RequireEmptyDestination {
+		checked := map[string]bool{}
+		for _, v := range snapshot {
+			if checked[v.Bucket] {
+				continue
+			}
+			checked[v.Bucket] = true
+			dst, err := cs.scoped(ctx, CloudFileRequest{bucket: v.Bucket, path: dstPrefix})
+			if err != nil {
+				return RestoreReport{}, err
+			}
+			if err := cs.requireEmptyDestination(ctx, op, dst); err != nil {
+				op.logger.Error(ERROR_RESTORING_SNAPSHOT, zap.Error(err), zap.String("prefix", dstPrefix))
+				return RestoreReport{DryRun: rOpts.DryRun}, err
+			}
+		}
+	}
+
+	report := RestoreReport{DryRun: rOpts.DryRun}
+	restored := map[string]bool{}
+	var firstErr error
+	fail := func(a *RestoreAction, err error) {
+		op.logger.Error(ERROR_RESTORING_SNAPSHOT, zap.Error(err), zap.String("action", string(a.Action)), zap.String("source", a.Source), zap.String("filepath", a.Dest))
+		a.Err = op.wrapError(err, "%s %s", ERROR_RESTORING_SNAPSHOT, a.Dest)
+		a.Error = a.Err.Error()
+		if firstErr == nil {
+			firstErr = a.Err
+		}
+	}
+
+	for i, v := range snapshot {
+		a := RestoreAction{Action: RestoreCopy, Source: v.Name, Generation: v.Generation, Dest: dests[i].objectPath()}
+		restored[a.Dest] = true
+
+		dst := cs.bucketHandle(dests[i]).Object(a.Dest)
+		conds := storage.Conditions{DoesNotExist: true}
+		attrs, err := dst.Attrs(ctx)
+		switch {
+		case err == nil:
+			if attrs.Size == v.Size && attrs.CRC32C == v.CRC32C {
+				a.Action = RestoreSkip
+			}
+			conds = storage.Conditions{GenerationMatch: attrs.Generation}
+		case err != storage.ErrObjectNotExist:
+			fail(&a, err)
+			report.Actions = append(report.Actions, a)
+			continue
+		}
+		if a.Action == RestoreCopy && !rOpts.DryRun {
+			src := cs.bucketHandle(srcs[i]).Object(v.Name).Generation(v.Generation)
+			copier := dst.If(conds).CopierFrom(src)
+			copier.DestinationKMSKeyName = dests[i].kmsKeyName
+			_, err := copier.Run(ctx)
+			cs.invalidate(v.Bucket, a.Dest)
+			if err != nil {
+				fail(&a, err)
+			}
+		}
+		report.Actions = append(report.Actions, a)
+	}
+
+	if rOpts.DeleteExtra != nil {
+		prefix := dirPrefix(rOpts.DeleteExtra.path)
+		it := cs.bucketHandle(*rOpts.DeleteExtra).Objects(ctx, &storage.Query{Prefix: prefix})
+		for {
+			attrs, err := it.Next()
+			if err != nil {
+				if err == iterator.Done {
+					break
+				}
+				a := RestoreAction{Action: RestoreDelete, Dest: prefix}
+				fail(&a, err)
+				report.Actions = append(report.Actions, a)
+				break
+			}
+			if restored[attrs.Name] || isDirMarker(attrs) {
+				continue
+			}
+			a := RestoreAction{Action: RestoreDelete, Generation: attrs.Generation, Dest: attrs.Name}
+			if !rOpts.DryRun {
+				obj := cs.bucketHandle(*rOpts.DeleteExtra).Object(attrs.Name)
+				err := obj.If(storage.Conditions{GenerationMatch: attrs.Generation}).Delete(ctx)
+				cs.invalidate(attrs.Bucket, attrs.Name)
+				if err != nil {
+					fail(&a, err)
+				}
+			}
+			report.Actions = append(report.Actions, a)
+		}
+	}
+
+	for i, a := range report.Actions {
+		report.Actions[i].Source, report.Actions[i].Dest = scope.unscopedName(a.Source), scope.unscopedName(a.Dest)
+	}
+	op.logger.Debug("cloud file snapshot restored", zap.String("prefix", dstPrefix), zap.Bool("dryRun", rOpts.DryRun), zap.Int("actions", len(report.Actions)), zap.Int("failures", report.Failures()))
+	return report, firstErr
+}
@@ -0,0 +1,228 @@
+package cloudstorage
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// versionedHistory writes a & b, returns the time after, then replaces a, deletes b & creates c
+func versionedHistory(t *testing.T, f *fakeGCS) time.Time {
+	t.Helper()
+	f.put("bucket", "data/a.txt", []byte("a v1"), nil)
+	f.put("bucket", "data/b.txt", []byte("b v1"), nil)
+	f.put("bucket", "other/x.txt", []byte("x"), nil)
+	time.Sleep(5 * time.Millisecond)
+	at := time.Now()
+	time.Sleep(5 * time.Millisecond)
+
+	f.put("bucket", "data/a.txt", []byte("a v2"), nil)
+	f.mu.Lock()
+	f.archive(fakeKey("bucket", "data/b.txt"))
+	delete(f.objects, fakeKey("bucket", "data/b.txt"))
+	f.mu.Unlock()
+	f.put("bucket", "data/c.txt", []byte("c v1"), nil)
+	return at
+}
+
+func TestSnapshotPrefix(t *testing.T) {
+	f := newFakeGCS()
+	f.versioned = true
+	at := versionedHistory(t, f)
+	cs := newFakeClient(t, f)
+	cfr := CloudFileRequest{bucket: "bucket", path: "data"}
+
+	snapshot, err := cs.SnapshotPrefix(context.Background(), cfr, at)
+	require.NoError(t, err)
+	require.Len(t, snapshot, 2)
+	require.Equal(t, "data/a.txt", snapshot[0].Name)
+	require.Equal(t, int64(1), snapshot[0].Generation)
+	require.Equal(t, "data/b.txt", snapshot[1].Name)
+	require.Equal(t, int64(2), snapshot[1].Generation)
+
+	// before anything was written
+	snapshot, err = cs.SnapshotPrefix(context.Background(), cfr, at.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, snapshot)
+
+	// now, the live generations
+	snapshot, err = cs.SnapshotPrefix(context.Background(), cfr, time.Now())
+	require.NoError(t, err)
+	require.Len(t, snapshot, 2)
+	require.Equal(t, "data/a.txt", snapshot[0].Name)
+	require.Equal(t, int64(4), snapshot[0].Generation)
+	require.Equal(t, "data/c.txt", snapshot[1].Name)
+
+	_, err = cs.SnapshotPrefix(context.Background(), CloudFileRequest{path: "data"}, at)
+	require.Equal(t, ErrBucketNameMissing, err)
+}
+
+func TestRestoreSnapshotDryRun(t *testing.T) {
+	f := newFakeGCS()
+	f.versioned = true
+	at := versionedHistory(t, f)
+	cs := newFakeClient(t, f)
+	cfr := CloudFileRequest{bucket: "bucket", path: "data"}
+
+	snapshot, err := cs.SnapshotPrefix(context.Background(), cfr, at)
+	require.NoError(t, err)
+
+	report, err := cs.RestoreSnapshot(context.Background(), snapshot, "", WithDryRun(), WithDeleteExtra(cfr))
+	require.NoError(t, err)
+	require.True(t, report.DryRun)
+	require.Equal(t, []RestoreAction{
+		{Action: RestoreCopy, Source: "data/a.txt", Generation: 1, Dest: "data/a.txt"},
+		{Action: RestoreCopy, Source: "data/b.txt", Generation: 2, Dest: "data/b.txt"},
+		{Action: RestoreDelete, Generation: 5, Dest: "data/c.txt"},
+	}, report.Actions)
+
+	// nothing changed
+	data, _, ok := f.get("bucket", "data/a.txt")
+	require.True(t, ok)
+	require.Equal(t, "a v2", string(data))
+	_, _, ok = f.get("bucket", "data/b.txt")
+	require.False(t, ok)
+	_, _, ok = f.get("bucket", "data/c.txt")
+	require.True(t, ok)
+}
+
+func TestRestoreSnapshotInPlace(t *testing.T) {
+	f := newFakeGCS()
+	f.versioned = true
+	at := versionedHistory(t, f)
+	cs := newFakeClient(t, f)
+	cfr := CloudFileRequest{bucket: "bucket", path: "data"}
+
+	snapshot, err := cs.SnapshotPrefix(context.Background(), cfr, at)
+	require.NoError(t, err)
+
+	report, err := cs.RestoreSnapshot(context.Background(), snapshot, "", WithDeleteExtra(cfr))
+	require.NoError(t, err)
+	require.Equal(t, 0, report.Failures())
+	require.Len(t, report.Actions, 3)
+
+	for name, want := range map[string]string{"data/a.txt": "a v1", "data/b.txt": "b v1", "other/x.txt": "x"} {
+		data, _, ok := f.get("bucket", name)
+		require.True(t, ok, name)
+		require.Equal(t, want, string(data), name)
+	}
+	_, _, ok := f.get("bucket", "data/c.txt")
+	require.False(t, ok)
+
+	// restored content is skipped
+	report, err = cs.RestoreSnapshot(context.Background(), snapshot, "")
+	require.NoError(t, err)
+	for _, a := range report.Actions {
+		require.Equal(t, RestoreSkip, a.Action, a.Dest)
+	}
+}
+
+func TestRestoreSnapshotToPrefix(t *testing.T) {
+	f := newFakeGCS()
+	f.versioned = true
+	at := versionedHistory(t, f)
+	f.put("bucket", "restore/data/stale.txt", []byte("stale"), nil)
+	cs := newFakeClient(t, f)
+	cfr := CloudFileRequest{bucket: "bucket", path: "data"}
+
+	snapshot, err := cs.SnapshotPrefix(context.Background(), cfr, at)
+	require.NoError(t, err)
+
+	report, err := cs.RestoreSnapshot(context.Background(), snapshot, "restore", WithDeleteExtra(cfr))
+	require.NoError(t, err)
+	require.Equal(t, []RestoreAction{
+		{Action: RestoreCopy, Source: "data/a.txt", Generation: 1, Dest: "restore/data/a.txt"},
+		{Action: RestoreCopy, Source: "data/b.txt", Generation: 2, Dest: "restore/data/b.txt"},
+		{Action: RestoreDelete, Generation: 6, Dest: "restore/data/stale.txt"},
+	}, report.Actions)
+
+	data, _, ok := f.get("bucket", "restore/data/a.txt")
+	require.True(t, ok)
+	require.Equal(t, "a v1", string(data))
+	// live objects untouched
+	data, _, ok = f.get("bucket", "data/a.txt")
+	require.True(t, ok)
+	require.Equal(t, "a v2", string(data))
+}
+
+func TestRestoreSnapshotPreconditionFailure(t *testing.T) {
+	f := newFakeGCS()
+	f.versioned = true
+	at := versionedHistory(t, f)
+	cs := newFakeClient(t, f)
+	cfr := CloudFileRequest{bucket: "bucket", path: "data"}
+
+	snapshot, err := cs.SnapshotPrefix(context.Background(), cfr, at)
+	require.NoError(t, err)
+
+	// a.txt is replaced between planning & copying
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodPost {
+			f.store(f.objects[fakeKey("bucket", "data/a.txt")].attrs, []byte("a v3"))
+			f.fail = nil
+		}
+		return 0
+	}
+	report, err := cs.RestoreSnapshot(context.Background(), snapshot, "")
+	require.Error(t, err)
+	require.Equal(t, 1, report.Failures())
+	require.Error(t, report.Actions[0].Err)
+	require.NoError(t, report.Actions[1].Err)
+
+	data, _, ok := f.get("bucket", "data/a.txt")
+	require.True(t, ok)
+	require.Equal(t, "a v3", string(data))
+}
+
+func TestRestoreSnapshotRequireEmptyDestination(t *testing.T) {
+	f := newFakeGCS()
+	f.versioned = true
+	at := versionedHistory(t, f)
+	f.put("bucket", "release/", nil, nil)
+	cs := newFakeClient(t, f)
+	snapshot, err := cs.SnapshotPrefix(context.Background(), CloudFileRequest{bucket: "bucket", path: "data"}, at)
+	require.NoError(t, err)
+
+	// directory markers don't conflict
+	report, err := cs.RestoreSnapshot(context.Background(), snapshot, "/release/", WithRequireEmptyDestination())
+	require.NoError(t, err)
+	require.Len(t, report.Actions, 2)
+	require.Equal(t, "release/data/a.txt", report.Actions[0].Dest)
+	_, _, ok := f.get("bucket", "release/data/a.txt")
+	require.True(t, ok, "the checked prefix is the one written")
+	_, _, ok = f.get("bucket", "/release/data/a.txt")
+	require.False(t, ok)
+
+	copies := recordRequests(f, http.MethodPost, nil)
+	_, err = cs.RestoreSnapshot(context.Background(), snapshot, "//release", WithRequireEmptyDestination())
+	require.ErrorIs(t, err, ErrDestinationNotEmpty)
+	var nErr DestinationNotEmptyError
+	require.ErrorAs(t, err, &nErr)
+	require.Equal(t, "release/", nErr.Prefix)
+	require.Equal(t, []string{"release/data/a.txt", "release/data/b.txt"}, nErr.Names)
+	require.Empty(t, *copies, "nothing is copied into a non empty destination")
+
+	// overwriting stays the default
+	_, err = cs.RestoreSnapshot(context.Background(), snapshot, "release")
+	require.NoError(t, err)
+
+	_, err = cs.RestoreSnapshot(context.Background(), snapshot, "release/../data", WithRequireEmptyDestination())
+	requireOutOfScope(t, err)
+}
+
+func TestRestoreSnapshotKMSKey(t *testing.T) {
+	f := newFakeGCS()
+	f.versioned = true
+	at := versionedHistory(t, f)
+	cs := newFakeClient(t, f)
+
+	snapshot, err := cs.SnapshotPrefix(context.Background(), CloudFileRequest{bucket: "bucket", path: "data"}, at)
+	require.NoError(t, err)
+	keys := recordKMSKeys(f)
+	_, err = cs.RestoreSnapshot(context.Background(), snapshot, "restore", WithRestoreKMSKey(testKMSKey))
+	require.NoError(t, err)
+	require.Equal(t, []string{testKMSKey, testKMSKey}, keys["rewrite"])
+}
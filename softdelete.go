@@ -0,0 +1,280 @@
+package cloudstorage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_SOFT_DELETE_NOT_ENABLED        string = "soft delete not enabled on bucket"
+	ERROR_INVALID_SOFT_DELETE_RETENTION  string = "invalid soft delete retention"
+	ERROR_LISTING_SOFT_DELETED           string = "error listing soft deleted objects"
+	ERROR_RESTORING_SOFT_DELETED         string = "error restoring soft deleted object"
+	ERROR_GETTING_SOFT_DELETE_POLICY     string = "error getting bucket soft delete policy"
+	ERROR_UPDATING_SOFT_DELETE_POLICY    string = "error updating bucket soft delete policy"
+	ERROR_SOFT_DELETED_GENERATION_NEEDED string = "soft deleted generation required"
+)
+
+var (
+	ErrSoftDeleteNotEnabled        = errors.NewAppError(ERROR_SOFT_DELETE_NOT_ENABLED)
+	ErrInvalidSoftDeleteRetention  = errors.NewAppError(ERROR_INVALID_SOFT_DELETE_RETENTION)
+	ErrSoftDeletedGenerationNeeded = errors.NewAppError(ERROR_SOFT_DELETED_GENERATION_NEEDED)
+)
+
+const (
+	// MIN_SOFT_DELETE_RETENTION & MAX_SOFT_DELETE_RETENTION bound the soft delete retention the service accepts
+	MIN_SOFT_DELETE_RETENTION = 7 * 24 * time.Hour
+	MAX_SOFT_DELETE_RETENTION = 90 * 24 * time.Hour
+)
+
+// SoftDeleteNotEnabledError is returned by soft delete listings & restores of buckets without
+// soft delete retention, matches ErrSoftDeleteNotEnabled with errors.Is
+type SoftDeleteNotEnabledError struct {
+	Bucket string
+}
+
+func (e SoftDeleteNotEnabledError) Error() string {
+	return fmt.Sprintf("%s %s", ERROR_SOFT_DELETE_NOT_ENABLED, e.Bucket)
+}
+
+// Is matches ErrSoftDeleteNotEnabled
+func (e SoftDeleteNotEnabledError) Is(target error) bool {
+	return target == ErrSoftDeleteNotEnabled
+}
+
+// softDeletePolicy is the JSON API bucket soft delete policy, not known to the storage client version
+type softDeletePolicy struct {
+	RetentionDurationSeconds int64  `json:"retentionDurationSeconds,string"`
+	EffectiveTime            string `json:"effectiveTime,omitempty"`
+}
+
+// softDeleteBucket is the soft delete part of the JSON API bucket resource
+type softDeleteBucket struct {
+	SoftDeletePolicy *softDeletePolicy `json:"softDeletePolicy,omitempty"`
+}
+
+// softDeletedObject is the part of the JSON API object resource soft delete listings & restores use
+type softDeletedObject struct {
+	Bucket         string `json:"bucket"`
+	Name           string `json:"name"`
+	Generation     int64  `json:"generation,string"`
+	Size           int64  `json:"size,string"`
+	Crc32c         string `json:"crc32c"`
+	TimeCreated    string `json:"timeCreated"`
+	Updated        string `json:"updated"`
+	SoftDeleteTime string `json:"softDeleteTime"`
+	HardDeleteTime string `json:"hardDeleteTime"`
+}
+
+type softDeletedObjects struct {
+	Items         []softDeletedObject `json:"items"`
+	NextPageToken string              `json:"nextPageToken"`
+}
+
+// parseAPITime returns given JSON API timestamp, zero when empty or malformed
+func parseAPITime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
+
+// newSoftDeletedVersion returns the version of given soft deleted generation, named relative to request's scope
+func newSoftDeletedVersion(cfr CloudFileRequest, obj softDeletedObject) ObjectVersion {
+	v := ObjectVersion{
+		Bucket:      obj.Bucket,
+		Name:        cfr.unscopedName(obj.Name),
+		Generation:  obj.Generation,
+		Size:        obj.Size,
+		Created:     parseAPITime(obj.TimeCreated),
+		Updated:     parseAPITime(obj.Updated),
+		Deleted:     parseAPITime(obj.SoftDeleteTime),
+		HardDeleted: parseAPITime(obj.HardDeleteTime),
+	}
+	if crc, err := base64.StdEncoding.DecodeString(obj.Crc32c); err == nil && len(crc) == 4 {
+		v.CRC32C = binary.BigEndian.Uint32(crc)
+	}
+	return v
+}
+
+// jsonAPIQuery returns the query of request's JSON API calls, billed to the user project when set
+func jsonAPIQuery(cfr CloudFileRequest) url.Values {
+	query := url.Values{}
+	if cfr.userProject != "" {
+		query.Set("userProject", cfr.userProject)
+	}
+	return query
+}
+
+// softDeleteRetention returns the soft delete retention of request's bucket, zero when disabled
+func (cs *cloudStorageClient) softDeleteRetention(ctx context.Context, op *operation, cfr CloudFileRequest) (time.Duration, time.Time, error) {
+	query := jsonAPIQuery(cfr)
+	query.Set("fields", "softDeletePolicy")
+	var bucket softDeleteBucket
+	err := op.retry(ctx, func() error {
+		return cs.jsonAPI.do(ctx, http.MethodGet, jsonAPIPath(cfr.bucket, ""), query, nil, &bucket)
+	})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if bucket.SoftDeletePolicy == nil {
+		return 0, time.Time{}, nil
+	}
+	return time.Duration(bucket.SoftDeletePolicy.RetentionDurationSeconds) * time.Second, parseAPITime(bucket.SoftDeletePolicy.EffectiveTime), nil
+}
+
+// requireSoftDelete fails with SoftDeleteNotEnabledError when request's bucket has no soft delete retention
+func (cs *cloudStorageClient) requireSoftDelete(ctx context.Context, op *operation, cfr CloudFileRequest) error {
+	retention, _, err := cs.softDeleteRetention(ctx, op, cfr)
+	if err != nil {
+		op.logger.Error(ERROR_GETTING_SOFT_DELETE_POLICY, zap.Error(err), zap.String("bucket", cfr.bucket))
+		return op.wrapError(err, "%s %s", ERROR_GETTING_SOFT_DELETE_POLICY, cfr.bucket)
+	}
+	if retention == 0 {
+		return SoftDeleteNotEnabledError{Bucket: cfr.bucket}
+	}
+	return nil
+}
+
+// ListSoftDeleted returns the soft deleted generations of request's file, or of the objects under request path
+// without file name, ordered by name & generation. Deleted is the soft delete time, HardDeleted when the
+// generation is permanently removed. Buckets without soft delete retention fail with SoftDeleteNotEnabledError.
+func (cs *cloudStorageClient) ListSoftDeleted(ctx context.Context, cfr CloudFileRequest) ([]ObjectVersion, error) {
+	cfr, err := cs.prefixRequest(ctx, cfr)
+	if err != nil {
+		return nil, err
+	}
+	if cfr.bucket == "" {
+		return nil, ErrBucketNameMissing
+	}
+	op := cs.startOperation(ctx, "ListSoftDeleted", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	prefix := dirPrefix(cfr.path)
+	if cfr.file != "" {
+		prefix = cfr.objectPath()
+	}
+	op.object = prefix
+	if err := cs.requireSoftDelete(ctx, op, cfr); err != nil {
+		return nil, err
+	}
+
+	query := jsonAPIQuery(cfr)
+	query.Set("softDeleted", "true")
+	query.Set("prefix", prefix)
+	versions := []ObjectVersion{}
+	for {
+		var page softDeletedObjects
+		err := op.retry(ctx, func() error {
+			return cs.jsonAPI.do(ctx, http.MethodGet, jsonAPIPath(cfr.bucket, "")+"/o", query, nil, &page)
+		})
+		if err != nil {
+			op.logger.Error(ERROR_LISTING_SOFT_DELETED, zap.Error(err), zap.String("prefix", prefix))
+			return nil, op.wrapError(err, "%s %s", ERROR_LISTING_SOFT_DELETED, prefix)
+		}
+		for _, obj := range page.Items {
+			// the file's prefix also lists longer names
+			if cfr.file != "" && obj.Name != prefix {
+				continue
+			}
+			versions = append(versions, newSoftDeletedVersion(cfr, obj))
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+	op.logger.Debug("soft deleted objects listed", zap.String("prefix", prefix), zap.Int("versions", len(versions)))
+	return versions, nil
+}
+
+// RestoreSoftDeleted restores given soft deleted generation of request's file as its live object,
+// a new generation, returns its attributes. A live object is replaced, unless the request's
+// generation precondition fails. Buckets without soft delete retention fail with SoftDeleteNotEnabledError,
+// generations that aren't soft deleted match ErrObjectNotFound.
+func (cs *cloudStorageClient) RestoreSoftDeleted(ctx context.Context, cfr CloudFileRequest, generation int64) (*ObjectAttrs, error) {
+	if err := cs.mutation(); err != nil {
+		return nil, err
+	}
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return nil, err
+	}
+	if cfr.bucket == "" {
+		return nil, ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return nil, ErrFileNameMissing
+	}
+	if generation <= 0 {
+		return nil, ErrSoftDeletedGenerationNeeded
+	}
+	op := cs.startOperation(ctx, "RestoreSoftDeleted", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	objName := cfr.objectPath()
+	op.object, op.generation = objName, generation
+	defer cs.invalidate(cfr.bucket, objName)
+	if err := cs.requireSoftDelete(ctx, op, cfr); err != nil {
+		return nil, err
+	}
+
+	query := jsonAPIQuery(cfr)
+	query.Set("generation", strconv.FormatInt(generation, 10))
+	if cfr.hasGenerationMatch {
+		query.Set("ifGenerationMatch", strconv.FormatInt(cfr.generationMatch, 10))
+	}
+	var restored softDeletedObject
+	if err := cs.jsonAPI.do(ctx, http.MethodPost, jsonAPIPath(cfr.bucket, objName)+"/restore", query, nil, &restored); err != nil {
+		op.logger.Error(ERROR_RESTORING_SOFT_DELETED, zap.Error(err), zap.String("filepath", objName), zap.Int64("generation", generation))
+		return nil, op.wrapError(err, "%s %s#%d", ERROR_RESTORING_SOFT_DELETED, objName, generation)
+	}
+	op.generation = restored.Generation
+
+	// the restore response isn't decoded by the storage client version, the restored generation is read back
+	attrs, err := cs.bucketHandle(cfr).Object(objName).Generation(restored.Generation).Attrs(ctx)
+	if err != nil {
+		op.logger.Error(ERROR_GETTING_ATTRS, zap.Error(err), zap.String("filepath", objName))
+		return nil, op.wrapError(err, "%s %s", ERROR_GETTING_ATTRS, objName)
+	}
+	op.logger.Info("soft deleted object restored", zap.String("filepath", objName), zap.Int64("generation", generation), zap.Int64("restored", restored.Generation))
+	return cfr.logicalAttrs(newObjectAttrs(attrs)), nil
+}
+
+// SetBucketSoftDelete sets the soft delete retention of given bucket, zero disables soft delete.
+// Retentions are whole seconds between MIN_SOFT_DELETE_RETENTION & MAX_SOFT_DELETE_RETENTION.
+func (cs *cloudStorageClient) SetBucketSoftDelete(ctx context.Context, bucket string, retention time.Duration) error {
+	if err := cs.mutation(); err != nil {
+		return err
+	}
+	if retention != 0 && (retention < MIN_SOFT_DELETE_RETENTION || retention > MAX_SOFT_DELETE_RETENTION || retention%time.Second != 0) {
+		return ErrInvalidSoftDeleteRetention
+	}
+	if err := cs.bucketMutation(bucket); err != nil {
+		return err
+	}
+	cfr, err := cs.scopedBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	op := cs.startOperation(ctx, "SetBucketSoftDelete", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	query := jsonAPIQuery(cfr)
+	query.Set("fields", "softDeletePolicy")
+	patch := softDeleteBucket{SoftDeletePolicy: &softDeletePolicy{RetentionDurationSeconds: int64(retention / time.Second)}}
+	if err := cs.jsonAPI.do(ctx, http.MethodPatch, jsonAPIPath(cfr.bucket, ""), query, patch, nil); err != nil {
+		op.logger.Error(ERROR_UPDATING_SOFT_DELETE_POLICY, zap.Error(err), zap.String("bucket", cfr.bucket), zap.Duration("retention", retention))
+		return op.wrapError(err, "%s %s", ERROR_UPDATING_SOFT_DELETE_POLICY, cfr.bucket)
+	}
+	op.logger.Info("bucket soft delete policy updated", zap.String("bucket", cfr.bucket), zap.Duration("retention", retention))
+	return nil
+}
@@ -0,0 +1,176 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// softDeleteClient returns a client of a fake with soft delete enabled on bucket "bucket"
+func softDeleteClient(t *testing.T) (*fakeGCS, *cloudStorageClient) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	require.NoError(t, cs.SetBucketSoftDelete(context.Background(), "bucket", MIN_SOFT_DELETE_RETENTION))
+	return f, cs
+}
+
+func TestSetBucketSoftDelete(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	attrs, err := cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.Zero(t, attrs.SoftDeleteRetention)
+	require.True(t, attrs.SoftDeleteEffective.IsZero())
+
+	require.NoError(t, cs.SetBucketSoftDelete(ctx, "bucket", 30*24*time.Hour))
+	attrs, err = cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.Equal(t, 30*24*time.Hour, attrs.SoftDeleteRetention)
+	require.False(t, attrs.SoftDeleteEffective.IsZero())
+
+	// zero disables soft delete
+	require.NoError(t, cs.SetBucketSoftDelete(ctx, "bucket", 0))
+	attrs, err = cs.GetBucketAttrs(ctx, "bucket")
+	require.NoError(t, err)
+	require.Zero(t, attrs.SoftDeleteRetention)
+
+	for _, retention := range []time.Duration{time.Hour, 91 * 24 * time.Hour, MIN_SOFT_DELETE_RETENTION + time.Millisecond, -time.Hour} {
+		require.Equal(t, ErrInvalidSoftDeleteRetention, cs.SetBucketSoftDelete(ctx, "bucket", retention), retention)
+	}
+
+	f.buckets = map[string]bool{"bucket": true}
+	require.ErrorIs(t, cs.SetBucketSoftDelete(ctx, "missing", MIN_SOFT_DELETE_RETENTION), ErrBucketNotFound)
+}
+
+func TestListSoftDeleted(t *testing.T) {
+	f, cs := softDeleteClient(t)
+	ctx := context.Background()
+	f.put("bucket", "path/a.txt", []byte("a1"), nil)
+	f.put("bucket", "path/b.txt", []byte("b"), nil)
+	f.put("bucket", "path/a.txt.bak", []byte("bak"), nil)
+	f.put("bucket", "other/c.txt", []byte("c"), nil)
+	_, first, _ := f.get("bucket", "path/a.txt")
+
+	for _, name := range []string{"path/a.txt", "path/b.txt", "path/a.txt.bak", "other/c.txt"} {
+		cfr, err := NewCloudFileRequest("bucket", strings.TrimPrefix(strings.TrimPrefix(name, "path/"), "other/"), strings.Split(name, "/")[0], 0)
+		require.NoError(t, err)
+		require.NoError(t, cs.DeleteObject(ctx, cfr))
+	}
+	f.put("bucket", "path/a.txt", []byte("a2"), nil)
+	cfr, err := NewCloudFileRequest("bucket", "a.txt", "path", 0)
+	require.NoError(t, err)
+	require.NoError(t, cs.DeleteObject(ctx, cfr))
+
+	// the path's soft deleted generations, by name & generation
+	dir, err := NewCloudFileRequest("bucket", "", "path", 0)
+	require.NoError(t, err)
+	versions, err := cs.ListSoftDeleted(ctx, dir)
+	require.NoError(t, err)
+	names := []string{}
+	for _, v := range versions {
+		names = append(names, v.Name)
+	}
+	require.Equal(t, []string{"path/a.txt", "path/a.txt", "path/a.txt.bak", "path/b.txt"}, names)
+	require.Less(t, versions[0].Generation, versions[1].Generation)
+
+	// the file's generations only
+	versions, err = cs.ListSoftDeleted(ctx, cfr)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	v := versions[0]
+	require.Equal(t, "bucket", v.Bucket)
+	require.Equal(t, first.Generation, v.Generation)
+	require.Equal(t, int64(2), v.Size)
+	require.NotZero(t, v.CRC32C)
+	require.False(t, v.Created.IsZero())
+	require.False(t, v.Deleted.IsZero())
+	require.Equal(t, v.Deleted.Add(MIN_SOFT_DELETE_RETENTION), v.HardDeleted)
+
+	// scoped listings name objects relative to the scope
+	f.put("bucket", "tenant-a/docs/d.txt", []byte("d"), nil)
+	scoped := CloudFileRequest{file: "d.txt", path: "docs"}
+	require.NoError(t, cs.DeleteObject(tenantContext(), scoped))
+	versions, err = cs.ListSoftDeleted(tenantContext(), scoped)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	require.Equal(t, "docs/d.txt", versions[0].Name)
+}
+
+func TestSoftDeleteNotEnabled(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	calls := recordRequests(f, http.MethodGet, nil)
+	cfr, err := NewCloudFileRequest("bucket", "a.txt", "path", 0)
+	require.NoError(t, err)
+
+	_, err = cs.ListSoftDeleted(ctx, cfr)
+	require.ErrorIs(t, err, ErrSoftDeleteNotEnabled)
+	var notEnabled SoftDeleteNotEnabledError
+	require.True(t, errors.As(err, &notEnabled))
+	require.Equal(t, "bucket", notEnabled.Bucket)
+
+	_, err = cs.RestoreSoftDeleted(ctx, cfr, 1)
+	require.ErrorIs(t, err, ErrSoftDeleteNotEnabled)
+	// the policy is checked before listing or restoring
+	require.Len(t, *calls, 2)
+
+	_, err = cs.ListSoftDeleted(ctx, CloudFileRequest{path: "data"})
+	require.Equal(t, ErrBucketNameMissing, err)
+}
+
+func TestRestoreSoftDeleted(t *testing.T) {
+	f, cs := softDeleteClient(t)
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "a.txt", "path", 0)
+	require.NoError(t, err)
+	_, err = cs.Upload(ctx, strings.NewReader("restore me"), cfr)
+	require.NoError(t, err)
+	require.NoError(t, cs.DeleteObject(ctx, cfr))
+	versions, err := cs.ListSoftDeleted(ctx, cfr)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+
+	attrs, err := cs.RestoreSoftDeleted(ctx, cfr, versions[0].Generation)
+	require.NoError(t, err)
+	require.Equal(t, "path/a.txt", attrs.Name)
+	require.Greater(t, attrs.Generation, versions[0].Generation, "restores are new generations")
+	data, live, ok := f.get("bucket", "path/a.txt")
+	require.True(t, ok)
+	require.Equal(t, "restore me", string(data))
+	require.Equal(t, live.Generation, attrs.Generation)
+
+	versions, err = cs.ListSoftDeleted(ctx, cfr)
+	require.NoError(t, err)
+	require.Empty(t, versions)
+
+	// generations that aren't soft deleted are missing
+	_, err = cs.RestoreSoftDeleted(ctx, cfr, live.Generation)
+	require.ErrorIs(t, err, ErrObjectNotFound)
+	_, err = cs.RestoreSoftDeleted(ctx, cfr, 0)
+	require.Equal(t, ErrSoftDeletedGenerationNeeded, err)
+
+	// restores can be conditional on the live object
+	require.NoError(t, cs.DeleteObject(ctx, cfr))
+	_, err = cs.Upload(ctx, strings.NewReader("replacement"), cfr)
+	require.NoError(t, err)
+	versions, err = cs.ListSoftDeleted(ctx, cfr)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	conditional, err := NewCloudFileRequest("bucket", "a.txt", "path", 0, WithIfGenerationMatch(0))
+	require.NoError(t, err)
+	_, err = cs.RestoreSoftDeleted(ctx, conditional, versions[0].Generation)
+	require.True(t, isPreconditionFailed(err))
+	var buf bytes.Buffer
+	_, err = cs.Download(ctx, &buf, cfr)
+	require.NoError(t, err)
+	require.Equal(t, "replacement", buf.String())
+}
@@ -0,0 +1,100 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+const (
+	ERROR_SPOOLING_UPLOAD string = "error spooling upload stream"
+)
+
+// DEFAULT_SPOOL_THRESHOLD is the default in memory spool size limit
+const DEFAULT_SPOOL_THRESHOLD int64 = 8 * 1024 * 1024 // 8MB
+
+// SpoolMode describes how an upload's reader was consumed
+type SpoolMode string
+
+const (
+	// SpoolNone reader was uploaded directly
+	SpoolNone SpoolMode = "direct"
+	// SpoolMemory reader was spooled to memory before upload
+	SpoolMemory SpoolMode = "memory"
+	// SpoolFile reader was spooled to a temp file before upload
+	SpoolFile SpoolMode = "file"
+)
+
+// spooled is a rewindable copy of a consumed reader
+type spooled struct {
+	io.ReadSeeker
+	mode SpoolMode
+	size int64
+	crc  uint32
+	file *os.File
+}
+
+// spool consumes given reader into memory if it fits the threshold,
+// into a temp file in given directory otherwise, computes CRC32C while spooling.
+// Returned spool must be closed to remove the temp file,
+// errors are returned unwrapped so reader errors can be matched by the caller.
+func spool(r io.Reader, threshold int64, dir string, bufs *bufferPool) (*spooled, error) {
+	hasher := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	tr := io.TeeReader(r, hasher)
+
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, tr, threshold+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n <= threshold {
+		return &spooled{
+			ReadSeeker: bytes.NewReader(buf.Bytes()),
+			mode:       SpoolMemory,
+			size:       n,
+			crc:        hasher.Sum32(),
+		}, nil
+	}
+
+	f, err := os.CreateTemp(dir, "cloudstorage-spool-*")
+	if err != nil {
+		return nil, err
+	}
+	sp := &spooled{
+		ReadSeeker: f,
+		mode:       SpoolFile,
+		file:       f,
+	}
+	if _, err := buf.WriteTo(f); err != nil {
+		sp.Close()
+		return nil, err
+	}
+	// hide the file's ReadFrom, it allocates a copy buffer per call for non file readers
+	rest, err := bufs.copy(struct{ io.Writer }{f}, tr)
+	if err != nil {
+		sp.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		sp.Close()
+		return nil, err
+	}
+	sp.size = n + rest
+	sp.crc = hasher.Sum32()
+	return sp, nil
+}
+
+// Close removes the spool's temp file, if any
+func (sp *spooled) Close() error {
+	if sp.file == nil {
+		return nil
+	}
+	f := sp.file
+	sp.file = nil
+	closeErr := f.Close()
+	if err := os.Remove(f.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return closeErr
+}
@@ -0,0 +1,61 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"os"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpool(t *testing.T) {
+	data := bytes.Repeat([]byte("spool-data|"), 100)
+	wantCRC := crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli))
+
+	for scenario, tc := range map[string]struct {
+		threshold int64
+		mode      SpoolMode
+	}{
+		"fits memory threshold":    {threshold: int64(len(data)), mode: SpoolMemory},
+		"exceeds memory threshold": {threshold: int64(len(data)) - 1, mode: SpoolFile},
+	} {
+		t.Run(scenario, func(t *testing.T) {
+			dir := t.TempDir()
+			sp, err := spool(bytes.NewBuffer(data), tc.threshold, dir, defaultBufferPool)
+			require.NoError(t, err)
+			require.Equal(t, tc.mode, sp.mode)
+			require.Equal(t, int64(len(data)), sp.size)
+			require.Equal(t, wantCRC, sp.crc)
+
+			got, err := io.ReadAll(sp)
+			require.NoError(t, err)
+			require.Equal(t, data, got)
+
+			// rewindable for retries
+			_, err = sp.Seek(0, io.SeekStart)
+			require.NoError(t, err)
+			got, err = io.ReadAll(sp)
+			require.NoError(t, err)
+			require.Equal(t, data, got)
+
+			require.NoError(t, sp.Close())
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			require.Equal(t, 0, len(entries))
+		})
+	}
+}
+
+func TestSpoolCleanupOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	r := io.MultiReader(bytes.NewReader(make([]byte, 64)), iotest.ErrReader(io.ErrUnexpectedEOF))
+	_, err := spool(r, 16, dir, defaultBufferPool)
+	require.Error(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(entries))
+}
@@ -0,0 +1,177 @@
+package cloudstorage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_VALIDATION_FAILED  string = "staged upload validation failed"
+	ERROR_PROMOTING_UPLOAD   string = "error promoting staged upload"
+	ERROR_REMOVING_STAGED    string = "error removing staged upload"
+	ERROR_VALIDATOR_REQUIRED string = "staged upload validator missing"
+)
+
+var (
+	ErrValidationFailed  = errors.NewAppError(ERROR_VALIDATION_FAILED)
+	ErrValidatorRequired = errors.NewAppError(ERROR_VALIDATOR_REQUIRED)
+)
+
+// StagingOptions configure StagedUpload & CleanupStaging
+type StagingOptions struct {
+	// Prefix is the path prefix staged objects are uploaded under, as <prefix>/<uuid>/<final name>,
+	// defaults to DEFAULT_STAGING_PREFIX, cleanups must use the uploads' prefix
+	Prefix string
+}
+
+// StagingOption sets staging options
+type StagingOption func(o *StagingOptions)
+
+// WithStagingPrefix sets the path prefix of staged objects
+func WithStagingPrefix(prefix string) StagingOption {
+	return func(o *StagingOptions) {
+		o.Prefix = prefix
+	}
+}
+
+func stagingOptions(opts []StagingOption) StagingOptions {
+	sOpts := StagingOptions{}
+	for _, opt := range opts {
+		opt(&sOpts)
+	}
+	if sOpts.Prefix == "" {
+		sOpts.Prefix = DEFAULT_STAGING_PREFIX
+	}
+	return sOpts
+}
+
+// StagedValidator validates a staged upload's object before it's promoted, e.g. scans or parses it,
+// with the client's methods & given staged request
+type StagedValidator func(ctx context.Context, staged CloudFileRequest) error
+
+// ValidationFailedError is returned by StagedUpload when the validator rejects the staged object,
+// matches ErrValidationFailed with errors.Is & unwraps to the validator's error
+type ValidationFailedError struct {
+	Bucket string
+	Object string
+	Err    error
+}
+
+func (e ValidationFailedError) Error() string {
+	return fmt.Sprintf("%s %s/%s: %s", ERROR_VALIDATION_FAILED, e.Bucket, e.Object, e.Err)
+}
+
+// Is matches ErrValidationFailed
+func (e ValidationFailedError) Is(target error) bool {
+	return target == ErrValidationFailed
+}
+
+// Unwrap returns the validator's error
+func (e ValidationFailedError) Unwrap() error {
+	return e.Err
+}
+
+// StagedUpload uploads given content to a staging object in the final request's bucket, validates it
+// with given validator & promotes it to the final object with a server side copy, so only validated
+// content is ever visible at the final name. The staged object is deleted either way,
+// rejected content fails with ValidationFailedError. The final request's generation precondition
+// applies to the promotion. Staged objects left by a crash are removed with CleanupStaging.
+func (cs *cloudStorageClient) StagedUpload(ctx context.Context, r io.Reader, final CloudFileRequest, validate StagedValidator, opts ...StagingOption) (UploadResult, error) {
+	if err := cs.mutation(); err != nil {
+		return UploadResult{}, err
+	}
+	if validate == nil {
+		return UploadResult{}, ErrValidatorRequired
+	}
+	scopedFinal, err := cs.request(ctx, final)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	if scopedFinal.bucket == "" {
+		return UploadResult{}, ErrBucketNameMissing
+	}
+	if scopedFinal.file == "" {
+		return UploadResult{}, ErrFileNameMissing
+	}
+	sOpts := stagingOptions(opts)
+	op := cs.startOperation(ctx, "StagedUpload", scopedFinal)
+	defer op.finish()
+	ctx = op.ctx
+	fPath := op.object
+
+	// the staged request keeps the final request's attributes, in a unique staging path
+	// relative to the same scope, without preconditions & sharding
+	rel := final.objectPath()
+	staged := final
+	stagedRel := tempObjectName(sOpts.Prefix, uuid.NewString(), rel)
+	staged.path, staged.file = path.Dir(stagedRel), path.Base(stagedRel)
+	staged.shards = 0
+	staged.generationMatch, staged.hasGenerationMatch = 0, false
+	staged.requestID = op.requestID
+
+	res, err := cs.Upload(ctx, r, staged)
+	if err != nil {
+		return res, err
+	}
+	scopedStaged, err := cs.scoped(ctx, staged)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	stagedName := scopedStaged.objectPath()
+	op.logger.Debug("upload staged", zap.String("filepath", fPath), zap.String("staged", stagedName), zap.Int64("generation", res.Attrs.Generation))
+
+	if err := validate(ctx, staged); err != nil {
+		op.logger.Error(ERROR_VALIDATION_FAILED, zap.Error(err), zap.String("filepath", fPath), zap.String("staged", stagedName))
+		cs.removeStaged(op, scopedStaged, stagedName, res.Attrs.Generation)
+		return UploadResult{}, ValidationFailedError{Bucket: scopedFinal.bucket, Object: scopedFinal.unscopedName(fPath), Err: err}
+	}
+
+	// the validated generation is promoted, whatever happened to the staged object since
+	src := cs.bucketHandle(scopedStaged).Object(stagedName).Generation(res.Attrs.Generation)
+	dst := cs.bucketHandle(scopedFinal).Object(fPath)
+	if scopedFinal.hasGenerationMatch {
+		dst = dst.If(generationConditions(scopedFinal.generationMatch))
+	}
+	copier := dst.CopierFrom(src)
+	copier.DestinationKMSKeyName = scopedFinal.kmsKeyName
+	attrs, err := copier.Run(ctx)
+	cs.invalidate(scopedFinal.bucket, fPath)
+	cs.removeStaged(op, scopedStaged, stagedName, res.Attrs.Generation)
+	if err != nil {
+		op.logger.Error(ERROR_PROMOTING_UPLOAD, zap.Error(err), zap.String("filepath", fPath), zap.String("staged", stagedName))
+		return UploadResult{}, op.wrapError(err, "%s %s", ERROR_PROMOTING_UPLOAD, fPath)
+	}
+	op.generation = attrs.Generation
+	res.Attrs, res.Token = scopedFinal.logicalAttrs(newObjectAttrs(attrs)), visibilityToken(attrs)
+	return res, nil
+}
+
+// removeStaged deletes given generation of a staged object, failures are logged, CleanupStaging removes leftovers
+func (cs *cloudStorageClient) removeStaged(op *operation, staged CloudFileRequest, name string, generation int64) {
+	obj := cs.bucketHandle(staged).Object(name).If(storage.Conditions{GenerationMatch: generation})
+	if err := removeTemp(obj); err != nil && !isNotFound(err) {
+		op.logger.Error(ERROR_REMOVING_STAGED, zap.Error(err), zap.String("filepath", op.object), zap.String("staged", name))
+	}
+}
+
+// CleanupStaging deletes the bucket's staged objects last updated more than given age ago,
+// left by uploads that crashed before promotion or removal. Younger objects, possibly of running
+// uploads, are left alone. Failed deletes don't stop the run, the first failure is returned with the report.
+// CleanupOrphans cleans up staged objects along with the other temporary objects.
+func (cs *cloudStorageClient) CleanupStaging(ctx context.Context, bucket string, olderThan time.Duration, opts ...StagingOption) (DeleteReport, error) {
+	if err := cs.mutation(); err != nil {
+		return DeleteReport{}, err
+	}
+	sOpts := stagingOptions(opts)
+	// every object under the staging prefix is swept, whatever its name
+	report, err := cs.cleanupTemp(ctx, "CleanupStaging", bucket, GCOptions{Prefixes: []string{sOpts.Prefix}, OlderThan: olderThan}, true)
+	return report.DeleteReport, err
+}
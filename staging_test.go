@@ -0,0 +1,142 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// stagedNames returns the names of fake's objects under given prefix
+func stagedNames(f *fakeGCS, bucket, prefix string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := []string{}
+	for _, obj := range f.objects {
+		if obj.attrs.Bucket == bucket && strings.HasPrefix(obj.attrs.Name, prefix) {
+			names = append(names, obj.attrs.Name)
+		}
+	}
+	return names
+}
+
+// age sets a fake object's update time given duration in the past
+func age(f *fakeGCS, bucket, name string, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[fakeKey(bucket, name)].attrs.Updated = time.Now().Add(-d).UTC().Format(time.RFC3339Nano)
+}
+
+func TestStagedUpload(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	final := CloudFileRequest{bucket: "bucket", path: "reports", file: "q1.csv", contentType: "text/csv"}
+
+	validated := ""
+	res, err := cs.StagedUpload(ctx, strings.NewReader("a,b\n1,2\n"), final, func(ctx context.Context, staged CloudFileRequest) error {
+		// the content is staged, not visible at the final name yet
+		var buf bytes.Buffer
+		if _, err := cs.Download(ctx, &buf, staged); err != nil {
+			return err
+		}
+		validated = buf.String()
+		ok, err := cs.Exists(ctx, final)
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.True(t, strings.HasPrefix(staged.objectPath(), DEFAULT_STAGING_PREFIX+"/"))
+		require.True(t, strings.HasSuffix(staged.objectPath(), "/reports/q1.csv"))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "a,b\n1,2\n", validated)
+	require.Equal(t, "reports/q1.csv", res.Attrs.Name)
+	require.Equal(t, "text/csv", res.Attrs.ContentType)
+	data, attrs, ok := f.get("bucket", "reports/q1.csv")
+	require.True(t, ok)
+	require.Equal(t, "a,b\n1,2\n", string(data))
+	require.Equal(t, res.Attrs.Generation, attrs.Generation)
+	require.Empty(t, stagedNames(f, "bucket", DEFAULT_STAGING_PREFIX))
+}
+
+func TestStagedUploadRejected(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "reports/q1.csv", []byte("previous"), nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	final := CloudFileRequest{bucket: "bucket", path: "reports", file: "q1.csv"}
+
+	infected := errors.New("infected")
+	_, err := cs.StagedUpload(ctx, strings.NewReader("virus"), final, func(context.Context, CloudFileRequest) error {
+		return infected
+	}, WithStagingPrefix("_scan"))
+	require.ErrorIs(t, err, ErrValidationFailed)
+	require.ErrorIs(t, err, infected)
+	var vErr ValidationFailedError
+	require.ErrorAs(t, err, &vErr)
+	require.Equal(t, "reports/q1.csv", vErr.Object)
+	data, _, _ := f.get("bucket", "reports/q1.csv")
+	require.Equal(t, "previous", string(data), "rejected content isn't promoted")
+	require.Empty(t, stagedNames(f, "bucket", "_scan/"))
+
+	// a failed promotion removes the staged object too
+	_, err = cs.StagedUpload(ctx, strings.NewReader("new"), CloudFileRequest{bucket: "bucket", path: "reports", file: "q1.csv", generationMatch: 0, hasGenerationMatch: true}, func(context.Context, CloudFileRequest) error {
+		return nil
+	})
+	require.True(t, isPreconditionFailed(err), err)
+	data, _, _ = f.get("bucket", "reports/q1.csv")
+	require.Equal(t, "previous", string(data))
+	require.Empty(t, stagedNames(f, "bucket", DEFAULT_STAGING_PREFIX))
+
+	_, err = cs.StagedUpload(ctx, strings.NewReader("new"), final, nil)
+	require.Equal(t, ErrValidatorRequired, err)
+	_, err = cs.StagedUpload(ctx, strings.NewReader("new"), CloudFileRequest{bucket: "bucket"}, func(context.Context, CloudFileRequest) error { return nil })
+	require.Equal(t, ErrFileNameMissing, err)
+}
+
+func TestStagedUploadScoped(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	ctx := tenantContext()
+
+	_, err := cs.StagedUpload(ctx, strings.NewReader("data"), CloudFileRequest{path: "docs", file: "a.txt"}, func(ctx context.Context, staged CloudFileRequest) error {
+		require.Len(t, stagedNames(f, "bucket", "tenant-a/"+DEFAULT_STAGING_PREFIX+"/"), 1)
+		ok, err := cs.Exists(ctx, staged)
+		require.True(t, ok)
+		return err
+	})
+	require.NoError(t, err)
+	_, _, ok := f.get("bucket", "tenant-a/docs/a.txt")
+	require.True(t, ok)
+	require.Empty(t, stagedNames(f, "bucket", "tenant-a/"+DEFAULT_STAGING_PREFIX))
+}
+
+func TestCleanupStaging(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", ".staging/1/reports/a.csv", []byte("stale"), nil)
+	f.put("bucket", ".staging/2/b.csv", []byte("stale too"), nil)
+	f.put("bucket", ".staging/3/c.csv", []byte("running"), nil)
+	f.put("bucket", "reports/old.csv", []byte("final"), nil)
+	f.put("bucket", ".staging/loose.csv", []byte("unnamed"), nil)
+	age(f, "bucket", ".staging/loose.csv", 2*time.Hour)
+	age(f, "bucket", ".staging/1/reports/a.csv", 2*time.Hour)
+	age(f, "bucket", ".staging/2/b.csv", 3*time.Hour)
+	age(f, "bucket", "reports/old.csv", 3*time.Hour)
+	cs := newFakeClient(t, f)
+
+	report, err := cs.CleanupStaging(context.Background(), "bucket", time.Hour)
+	require.NoError(t, err)
+	// staged objects are swept by age, whether or not they follow the temporary object naming
+	require.Equal(t, int64(3), report.Deleted)
+	require.Equal(t, int64(len("stale")+len("stale too")+len("unnamed")), report.BytesFreed)
+	require.Equal(t, []string{".staging/3/c.csv"}, stagedNames(f, "bucket", ".staging/"))
+	_, _, ok := f.get("bucket", "reports/old.csv")
+	require.True(t, ok, "objects outside the staging prefix are left alone")
+
+	_, err = cs.CleanupStaging(context.Background(), "", time.Hour)
+	require.Equal(t, ErrBucketNameMissing, err)
+}
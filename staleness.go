@@ -0,0 +1,141 @@
+package cloudstorage
+
+import (
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+)
+
+// DEFAULT_MOD_TIME_SKEW is the clock skew tolerated between a request's modTime & the object's update time
+const DEFAULT_MOD_TIME_SKEW = 2 * time.Second
+
+// MAX_MOD_TIME_SECONDS is the largest modTime argument taken for unix seconds, the start of year 3000,
+// larger ones are present times in milliseconds or finer units
+const MAX_MOD_TIME_SECONDS int64 = 32503680000
+
+const (
+	ERROR_IMPLAUSIBLE_MOD_TIME string = "modTime isn't in unix seconds"
+)
+
+var (
+	ErrImplausibleModTime = errors.NewAppError(ERROR_IMPLAUSIBLE_MOD_TIME)
+)
+
+// ModTimeUnitError is returned by NewCloudFileRequest for modTime arguments past MAX_MOD_TIME_SECONDS,
+// matches ErrImplausibleModTime with errors.Is
+type ModTimeUnitError struct {
+	ModTime int64
+	// Unit is the coarsest unit the modTime is a time before year 3000 in, e.g. milliseconds of time.UnixMilli
+	Unit string
+}
+
+func (e ModTimeUnitError) Error() string {
+	return fmt.Sprintf("%s: %d looks like %s", ERROR_IMPLAUSIBLE_MOD_TIME, e.ModTime, e.Unit)
+}
+
+// Is matches ErrImplausibleModTime
+func (e ModTimeUnitError) Is(target error) bool {
+	return target == ErrImplausibleModTime
+}
+
+// checkModTime returns a ModTimeUnitError for modTime arguments that aren't plausible unix seconds,
+// int64 nanoseconds end before year 3000
+func checkModTime(modTime int64) error {
+	switch {
+	case modTime <= MAX_MOD_TIME_SECONDS:
+		return nil
+	case modTime/1e3 <= MAX_MOD_TIME_SECONDS:
+		return ModTimeUnitError{ModTime: modTime, Unit: "milliseconds"}
+	case modTime/1e6 <= MAX_MOD_TIME_SECONDS:
+		return ModTimeUnitError{ModTime: modTime, Unit: "microseconds"}
+	}
+	return ModTimeUnitError{ModTime: modTime, Unit: "nanoseconds"}
+}
+
+// WithModTime sets the modification time of the caller's copy, see NewCloudFileRequest, taking
+// precedence over the modTime argument. Preferred to the argument, sub second times compare exactly
+// & there's no unit to get wrong. The zero time sets none.
+func WithModTime(modTime time.Time) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.modTime = modTime
+	}
+}
+
+// WithUncheckedModTime takes the request's modTime argument for unix seconds whatever its value,
+// for callers with modification times past year 3000
+func WithUncheckedModTime() CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.modTimeUnchecked = true
+	}
+}
+
+// freshness is how the stored object compares with the caller's copy
+type freshness int
+
+const (
+	// freshnessUnknown is returned when the request carries neither a known generation nor a modTime
+	freshnessUnknown freshness = iota
+	// freshnessSame is returned when the copies match, or their times are within the skew tolerance
+	freshnessSame
+	// freshnessObjectNewer is returned when the object has updates the caller's copy lacks
+	freshnessObjectNewer
+	// freshnessCallerNewer is returned when the caller's copy has updates the object lacks
+	freshnessCallerNewer
+)
+
+// WithKnownGeneration sets the generation & metageneration of the object the caller's copy was read from,
+// uploads fail with ErrStaleUpload when the object changed since, downloads with ErrStaleDownload
+// when the caller saw a later object. Known generations take precedence over the request's modTime,
+// a zero metageneration compares generations only.
+func WithKnownGeneration(generation, metageneration int64) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.knownGeneration, cfr.knownMetageneration = generation, metageneration
+	}
+}
+
+// modTimeSkew returns the clock skew tolerated in modTime comparisons, none when configured negative
+func (cs *cloudStorageClient) modTimeSkew() time.Duration {
+	switch skew := cs.config.ModTimeSkew; {
+	case skew < 0:
+		return 0
+	case skew == 0:
+		return DEFAULT_MOD_TIME_SKEW
+	default:
+		return skew
+	}
+}
+
+// freshness compares given object attributes, nil for a missing object, with the request's known
+// generation, else its modTime, times within the skew tolerance compare the same
+func (cs *cloudStorageClient) freshness(cfr CloudFileRequest, attrs *storage.ObjectAttrs) freshness {
+	if cfr.knownGeneration > 0 {
+		switch {
+		case attrs == nil:
+			// deleted since the caller read it
+			return freshnessObjectNewer
+		case attrs.Generation != cfr.knownGeneration:
+			if attrs.Generation > cfr.knownGeneration {
+				return freshnessObjectNewer
+			}
+			return freshnessCallerNewer
+		case cfr.knownMetageneration > 0 && attrs.Metageneration > cfr.knownMetageneration:
+			return freshnessObjectNewer
+		case cfr.knownMetageneration > 0 && attrs.Metageneration < cfr.knownMetageneration:
+			return freshnessCallerNewer
+		}
+		return freshnessSame
+	}
+	if cfr.modTime.IsZero() || attrs == nil {
+		return freshnessUnknown
+	}
+	skew := cs.modTimeSkew()
+	switch d := attrs.Updated.Sub(cfr.modTime); {
+	case d > skew:
+		return freshnessObjectNewer
+	case d < -skew:
+		return freshnessCallerNewer
+	}
+	return freshnessSame
+}
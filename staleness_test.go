@@ -0,0 +1,161 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreshnessSkewTolerance(t *testing.T) {
+	cs := &cloudStorageClient{}
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	attrs := &storage.ObjectAttrs{Generation: 5, Metageneration: 2, Updated: updated}
+	at := func(d time.Duration) CloudFileRequest {
+		return CloudFileRequest{modTime: updated.Add(d)}
+	}
+
+	// the default tolerance is inclusive on both sides
+	require.Equal(t, freshnessSame, cs.freshness(at(0), attrs))
+	require.Equal(t, freshnessSame, cs.freshness(at(-DEFAULT_MOD_TIME_SKEW), attrs))
+	require.Equal(t, freshnessSame, cs.freshness(at(DEFAULT_MOD_TIME_SKEW), attrs))
+	require.Equal(t, freshnessObjectNewer, cs.freshness(at(-DEFAULT_MOD_TIME_SKEW-time.Second), attrs))
+	require.Equal(t, freshnessCallerNewer, cs.freshness(at(DEFAULT_MOD_TIME_SKEW+time.Second), attrs))
+
+	// sub second update times count
+	attrs.Updated = updated.Add(500 * time.Millisecond)
+	require.Equal(t, freshnessObjectNewer, cs.freshness(at(-DEFAULT_MOD_TIME_SKEW), attrs))
+	require.Equal(t, freshnessSame, cs.freshness(at(DEFAULT_MOD_TIME_SKEW), attrs))
+	attrs.Updated = updated
+
+	cs.config.ModTimeSkew = time.Minute
+	require.Equal(t, freshnessSame, cs.freshness(at(-time.Minute), attrs))
+	require.Equal(t, freshnessObjectNewer, cs.freshness(at(-time.Minute-time.Second), attrs))
+	cs.config.ModTimeSkew = -1
+	require.Equal(t, freshnessSame, cs.freshness(at(0), attrs))
+	require.Equal(t, freshnessObjectNewer, cs.freshness(at(-time.Second), attrs))
+	require.Equal(t, freshnessCallerNewer, cs.freshness(at(time.Second), attrs))
+
+	// without modTime or object nothing is known
+	require.Equal(t, freshnessUnknown, cs.freshness(CloudFileRequest{}, attrs))
+	require.Equal(t, freshnessUnknown, cs.freshness(at(0), nil))
+}
+
+func TestFreshnessPrecedence(t *testing.T) {
+	cs := &cloudStorageClient{}
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	attrs := &storage.ObjectAttrs{Generation: 5, Metageneration: 2, Updated: updated}
+	known := func(gen, metagen int64, modTime time.Time) CloudFileRequest {
+		cfr := CloudFileRequest{modTime: modTime}
+		WithKnownGeneration(gen, metagen)(&cfr)
+		return cfr
+	}
+	stale, fresh := updated.Add(-time.Hour), updated.Add(time.Hour)
+
+	// generations beat modTimes both ways
+	require.Equal(t, freshnessSame, cs.freshness(known(5, 0, stale), attrs))
+	require.Equal(t, freshnessSame, cs.freshness(known(5, 2, fresh), attrs))
+	require.Equal(t, freshnessObjectNewer, cs.freshness(known(4, 0, fresh), attrs))
+	require.Equal(t, freshnessCallerNewer, cs.freshness(known(6, 0, stale), attrs))
+	// metagenerations of the same generation
+	require.Equal(t, freshnessObjectNewer, cs.freshness(known(5, 1, fresh), attrs))
+	require.Equal(t, freshnessCallerNewer, cs.freshness(known(5, 3, stale), attrs))
+	// a deleted object changed since it was read
+	require.Equal(t, freshnessObjectNewer, cs.freshness(known(5, 0, time.Time{}), nil))
+	// modTimes beat nothing
+	require.Equal(t, freshnessObjectNewer, cs.freshness(known(0, 0, stale), attrs))
+}
+
+func TestStaleUploadDownload(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/file.txt", []byte("stored"), nil)
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f.mu.Lock()
+	f.objects[fakeKey("bucket", "path/file.txt")].attrs.Updated = updated.Format(time.RFC3339Nano)
+	f.mu.Unlock()
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	request := func(modTime time.Time, opts ...CloudFileRequestOption) CloudFileRequest {
+		cfr, err := NewCloudFileRequest("bucket", "file.txt", "path", modTime.Unix(), opts...)
+		require.NoError(t, err)
+		return cfr
+	}
+
+	// copies older than the object aren't uploaded over it, skewed clocks are tolerated
+	_, err := cs.Upload(ctx, strings.NewReader("old"), request(updated.Add(-time.Minute)))
+	require.ErrorIs(t, err, ErrStaleUpload)
+	_, err = cs.Download(ctx, &bytes.Buffer{}, request(updated.Add(time.Minute)))
+	require.ErrorIs(t, err, ErrStaleDownload)
+	_, err = cs.Download(ctx, &bytes.Buffer{}, request(updated.Add(DEFAULT_MOD_TIME_SKEW)))
+	require.NoError(t, err)
+	data, attrs, _ := f.get("bucket", "path/file.txt")
+	require.Equal(t, "stored", string(data))
+
+	// the known generation takes precedence over the stale modTime
+	res, err := cs.Upload(ctx, strings.NewReader("new"), request(updated.Add(-time.Minute), WithKnownGeneration(attrs.Generation, 0)))
+	require.NoError(t, err)
+	_, err = cs.Upload(ctx, strings.NewReader("newer"), request(time.Time{}, WithKnownGeneration(attrs.Generation, 0)))
+	require.ErrorIs(t, err, ErrStaleUpload, "the object changed since the known generation")
+	_, err = cs.Download(ctx, &bytes.Buffer{}, request(time.Time{}, WithKnownGeneration(res.Attrs.Generation+1, 0)))
+	require.ErrorIs(t, err, ErrStaleDownload)
+	data, _, _ = f.get("bucket", "path/file.txt")
+	require.Equal(t, "new", string(data))
+}
+
+func TestModTimeUnits(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	request := func(modTime int64, opts ...CloudFileRequestOption) (CloudFileRequest, error) {
+		return NewCloudFileRequest("bucket", "file.txt", "path", modTime, opts...)
+	}
+
+	cfr, err := request(now.Unix())
+	require.NoError(t, err)
+	require.True(t, now.Equal(cfr.modTime))
+	cfr, err = request(MAX_MOD_TIME_SECONDS)
+	require.NoError(t, err)
+	require.Equal(t, 3000, cfr.modTime.UTC().Year())
+
+	// finer units of present times are rejected, naming the unit
+	for unit, modTime := range map[string]int64{
+		"milliseconds": now.UnixMilli(),
+		"microseconds": now.UnixMicro(),
+		"nanoseconds":  now.UnixNano(),
+	} {
+		_, err := request(modTime)
+		require.ErrorIs(t, err, ErrImplausibleModTime, unit)
+		var mte ModTimeUnitError
+		require.ErrorAs(t, err, &mte)
+		require.Equal(t, unit, mte.Unit)
+		require.Equal(t, modTime, mte.ModTime)
+	}
+	// year 3000 is the edge of each unit
+	_, err = request(MAX_MOD_TIME_SECONDS + 1)
+	require.EqualError(t, err, "modTime isn't in unix seconds: 32503680001 looks like milliseconds")
+	_, err = request(MAX_MOD_TIME_SECONDS*1e3 + 1e3)
+	require.EqualError(t, err, "modTime isn't in unix seconds: 32503680001000 looks like microseconds")
+	_, err = request(math.MaxInt64)
+	require.ErrorIs(t, err, ErrImplausibleModTime)
+
+	// unless unchecked
+	cfr, err = request(now.UnixMilli(), WithUncheckedModTime())
+	require.NoError(t, err)
+	require.True(t, time.Unix(now.UnixMilli(), 0).Equal(cfr.modTime))
+
+	// WithModTime takes precedence & keeps sub second times
+	sub := now.Add(250 * time.Millisecond)
+	cfr, err = request(now.UnixMilli(), WithModTime(sub))
+	require.NoError(t, err)
+	require.True(t, sub.Equal(cfr.modTime))
+	cs := &cloudStorageClient{}
+	cs.config.ModTimeSkew = -1
+	attrs := &storage.ObjectAttrs{Updated: sub}
+	require.Equal(t, freshnessSame, cs.freshness(cfr, attrs))
+	cfr, err = request(0, WithModTime(now))
+	require.NoError(t, err)
+	require.Equal(t, freshnessObjectNewer, cs.freshness(cfr, attrs))
+}
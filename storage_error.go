@@ -0,0 +1,317 @@
+package cloudstorage
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	ERROR_BUCKET_NOT_FOUND  string = "storage bucket not found"
+	ERROR_OBJECT_NOT_FOUND  string = "storage bucket object not found"
+	ERROR_PERMISSION_DENIED string = "storage permission denied"
+)
+
+var (
+	ErrBucketNotFound   = errors.NewAppError(ERROR_BUCKET_NOT_FOUND)
+	ErrObjectNotFound   = errors.NewAppError(ERROR_OBJECT_NOT_FOUND)
+	ErrPermissionDenied = errors.NewAppError(ERROR_PERMISSION_DENIED)
+)
+
+// StorageError is returned when a storage operation fails,
+// carries the operation details & request ID for log correlation
+type StorageError struct {
+	errors.AppError
+	// Op is the failed operation, e.g. UploadFile
+	Op string
+	// Bucket is the operation's bucket name
+	Bucket string
+	// Object is the operation's object name, empty for bucket level operations
+	Object string
+	// RequestID is the ID included in the operation's logs
+	RequestID string
+	// Class is the failure's classification, ErrBucketNotFound, ErrObjectNotFound,
+	// ErrPermissionDenied or ErrRetentionPolicyLocked, nil otherwise
+	Class error
+	// Permission is the missing permission of ErrPermissionDenied failures, e.g. storage.objects.list,
+	// empty when the service didn't name it
+	Permission string
+	// Reason & Domain are the service's reason for the failure, e.g. rateLimitExceeded in usageLimits,
+	// from the JSON API's error items or the error info of JSON & gRPC errors, empty when none
+	Reason string
+	Domain string
+	// Details are the error info's metadata, e.g. quota_metric, & the first quota violation's
+	// quota_subject & quota_description, nil when none
+	Details map[string]string
+}
+
+// Unwrap returns the underlying error
+func (e StorageError) Unwrap() error {
+	return e.Inner
+}
+
+// Is matches the failure's classification, errors.Is(err, ErrBucketNotFound)
+func (e StorageError) Is(target error) bool {
+	return e.Class != nil && target == e.Class
+}
+
+// PartialListError is returned by listings that failed after collecting some results,
+// listing methods return no results on error, the names listed before the failure are carried here
+type PartialListError struct {
+	// Names are the object names, or prefixes, listed before the failure
+	Names []string
+	Err   error
+}
+
+func (e PartialListError) Error() string {
+	return fmt.Sprintf("listing failed after %d results: %s", len(e.Names), e.Err.Error())
+}
+
+// Unwrap returns the listing error
+func (e PartialListError) Unwrap() error {
+	return e.Err
+}
+
+// partialList returns given listing error, as PartialListError when names were collected
+func partialList(names []string, err error) error {
+	if len(names) == 0 {
+		return err
+	}
+	return PartialListError{Names: names, Err: err}
+}
+
+// isNotFound reports whether given error is a missing bucket or object
+func isNotFound(err error) bool {
+	if stderrors.Is(err, storage.ErrBucketNotExist) || stderrors.Is(err, storage.ErrObjectNotExist) {
+		return true
+	}
+	var gErr *googleapi.Error
+	if stderrors.As(err, &gErr) {
+		return gErr.Code == http.StatusNotFound
+	}
+	return false
+}
+
+// isPermissionDenied reports whether given error is a 403 from the service
+func isPermissionDenied(err error) bool {
+	var gErr *googleapi.Error
+	if stderrors.As(err, &gErr) {
+		return gErr.Code == http.StatusForbidden
+	}
+	return false
+}
+
+// preflightFatal reports whether a failed pre-flight lookup dooms the request that follows,
+// denied or unauthenticated callers, unreachable service & done contexts
+func preflightFatal(err error) bool {
+	if isPermissionDenied(err) || stderrors.Is(err, context.Canceled) || stderrors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var gErr *googleapi.Error
+	if stderrors.As(err, &gErr) {
+		return gErr.Code == http.StatusUnauthorized
+	}
+	var netErr net.Error
+	return stderrors.As(err, &netErr)
+}
+
+// permissionPattern matches IAM storage permission names in service error messages
+var permissionPattern = regexp.MustCompile(`\bstorage\.[a-zA-Z]+\.[a-zA-Z]+\b`)
+
+// missingPermission returns the permission named by given 403 error, from the error info details
+// or the messages, e.g. "... does not have storage.objects.list access to ...", empty when none
+func missingPermission(err error) string {
+	var gErr *googleapi.Error
+	if !stderrors.As(err, &gErr) || gErr.Code != http.StatusForbidden {
+		return ""
+	}
+	for _, d := range gErr.Details {
+		detail, _ := d.(map[string]interface{})
+		metadata, _ := detail["metadata"].(map[string]interface{})
+		if p, ok := metadata["permission"].(string); ok && p != "" {
+			return p
+		}
+	}
+	messages := []string{gErr.Message}
+	for _, item := range gErr.Errors {
+		messages = append(messages, item.Message)
+	}
+	for _, m := range messages {
+		if p := permissionPattern.FindString(m); p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// isPreconditionFailed reports whether given error is a failed request precondition
+func isPreconditionFailed(err error) bool {
+	var gErr *googleapi.Error
+	if stderrors.As(err, &gErr) {
+		return gErr.Code == http.StatusPreconditionFailed
+	}
+	return false
+}
+
+// quota & rate limit reasons of the JSON API's error items & of error infos
+var (
+	rateLimitReasons = map[string]bool{"rateLimitExceeded": true, "userRateLimitExceeded": true, "RATE_LIMIT_EXCEEDED": true}
+	quotaReasons     = map[string]bool{"quotaExceeded": true, "dailyLimitExceeded": true, "RESOURCE_EXHAUSTED": true}
+)
+
+// IsRateLimited reports whether given error is a rate limit failure, e.g. rateLimitExceeded or
+// userRateLimitExceeded, or a 429 the service gave no reason for
+func IsRateLimited(err error) bool {
+	code, reason, _, _ := errorDetails(err)
+	if reason != "" {
+		return rateLimitReasons[reason]
+	}
+	return code == http.StatusTooManyRequests
+}
+
+// IsQuotaExceeded reports whether given error is an exhausted quota, e.g. quotaExceeded,
+// or a failure naming a quota metric or violation
+func IsQuotaExceeded(err error) bool {
+	_, reason, _, details := errorDetails(err)
+	if quotaReasons[reason] {
+		return true
+	}
+	return !rateLimitReasons[reason] && (details["quota_metric"] != "" || details["quota_subject"] != "")
+}
+
+// errorDetails returns the HTTP status, the reason, domain & details of given error, from the
+// StorageError in its chain or the underlying JSON or gRPC error, gRPC codes mapped to HTTP statuses
+func errorDetails(err error) (int, string, string, map[string]string) {
+	var sErr StorageError
+	if stderrors.As(err, &sErr) && (sErr.Reason != "" || sErr.Details != nil) {
+		code, _, _, _ := apiErrorDetails(sErr.Inner)
+		return code, sErr.Reason, sErr.Domain, sErr.Details
+	}
+	return apiErrorDetails(err)
+}
+
+// apiErrorDetails parses the JSON API or gRPC error in given error's chain
+func apiErrorDetails(err error) (int, string, string, map[string]string) {
+	var gErr *googleapi.Error
+	if stderrors.As(err, &gErr) {
+		reason, domain, details := httpErrorInfo(gErr)
+		return gErr.Code, reason, domain, details
+	}
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if stderrors.As(err, &grpcErr) {
+		st := grpcErr.GRPCStatus()
+		reason, domain, details := grpcErrorInfo(st)
+		return grpcHTTPStatus(st.Code()), reason, domain, details
+	}
+	return 0, "", "", nil
+}
+
+// httpErrorInfo returns the reason, domain & metadata of given JSON API error's error info detail,
+// the first error item's reason & domain otherwise, with its first quota violation
+func httpErrorInfo(gErr *googleapi.Error) (string, string, map[string]string) {
+	var reason, domain string
+	var details map[string]string
+	for _, d := range gErr.Details {
+		detail, _ := d.(map[string]interface{})
+		typ, _ := detail["@type"].(string)
+		switch {
+		case strings.HasSuffix(typ, "google.rpc.ErrorInfo"):
+			reason, _ = detail["reason"].(string)
+			domain, _ = detail["domain"].(string)
+			metadata, _ := detail["metadata"].(map[string]interface{})
+			for k, v := range metadata {
+				if v, ok := v.(string); ok {
+					details = addDetail(details, k, v)
+				}
+			}
+		case strings.HasSuffix(typ, "google.rpc.QuotaFailure"):
+			violations, _ := detail["violations"].([]interface{})
+			if len(violations) > 0 {
+				v, _ := violations[0].(map[string]interface{})
+				subject, _ := v["subject"].(string)
+				description, _ := v["description"].(string)
+				details = addDetail(addDetail(details, "quota_subject", subject), "quota_description", description)
+			}
+		}
+	}
+	if reason == "" && len(gErr.Errors) > 0 {
+		reason = gErr.Errors[0].Reason
+	}
+	// googleapi doesn't parse the error items' domain, read from the response body
+	if domain == "" && gErr.Body != "" {
+		var body struct {
+			Error struct {
+				Errors []struct {
+					Domain string `json:"domain"`
+				} `json:"errors"`
+			} `json:"error"`
+		}
+		if json.Unmarshal([]byte(gErr.Body), &body) == nil && len(body.Error.Errors) > 0 {
+			domain = body.Error.Errors[0].Domain
+		}
+	}
+	return reason, domain, details
+}
+
+// grpcErrorInfo returns the reason, domain & metadata of given status's error info detail,
+// with its first quota violation
+func grpcErrorInfo(st *status.Status) (string, string, map[string]string) {
+	var reason, domain string
+	var details map[string]string
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			reason, domain = d.GetReason(), d.GetDomain()
+			for k, v := range d.GetMetadata() {
+				details = addDetail(details, k, v)
+			}
+		case *errdetails.QuotaFailure:
+			if violations := d.GetViolations(); len(violations) > 0 {
+				details = addDetail(addDetail(details, "quota_subject", violations[0].GetSubject()), "quota_description", violations[0].GetDescription())
+			}
+		}
+	}
+	return reason, domain, details
+}
+
+// addDetail sets the detail, when not empty, creating the details
+func addDetail(details map[string]string, key, value string) map[string]string {
+	if value == "" {
+		return details
+	}
+	if details == nil {
+		details = map[string]string{}
+	}
+	details[key] = value
+	return details
+}
+
+// grpcHTTPStatus maps gRPC codes of classified failures to the JSON API's HTTP statuses
+func grpcHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return 0
+	}
+}
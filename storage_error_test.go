@@ -0,0 +1,360 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// notFoundHandler fails every request with 404,
+// serves bucket metadata unless the bucket is missing
+func notFoundHandler(bucketMissing bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !bucketMissing && r.Method == http.MethodGet && r.URL.Path == "/storage/v1/b/bucket" {
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"name":"bucket"}`)
+			return
+		}
+		if bucketMissing {
+			writeAPIError(w, http.StatusNotFound, "The specified bucket does not exist.")
+			return
+		}
+		writeAPIError(w, http.StatusNotFound, "No such object: bucket/path/file.json")
+	})
+}
+
+func TestNotFoundClassification(t *testing.T) {
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	methods := map[string]func(ctx context.Context, cs *cloudStorageClient) error{
+		"Download": func(ctx context.Context, cs *cloudStorageClient) error {
+			_, err := cs.Download(ctx, &bytes.Buffer{}, cfr)
+			return err
+		},
+		"ReadAt": func(ctx context.Context, cs *cloudStorageClient) error {
+			_, err := cs.ReadAt(ctx, cfr, make([]byte, 8), 0)
+			return err
+		},
+		"GetAttrs": func(ctx context.Context, cs *cloudStorageClient) error {
+			_, err := cs.GetAttrs(ctx, cfr)
+			return err
+		},
+		"UpdateMetadata": func(ctx context.Context, cs *cloudStorageClient) error {
+			_, err := cs.UpdateMetadata(ctx, cfr, map[string]string{"k": "v"})
+			return err
+		},
+		"DeleteObject": func(ctx context.Context, cs *cloudStorageClient) error {
+			return cs.DeleteObject(ctx, cfr)
+		},
+	}
+	// bucket level failures only
+	bucketMethods := map[string]func(ctx context.Context, cs *cloudStorageClient) error{
+		"Upload": func(ctx context.Context, cs *cloudStorageClient) error {
+			_, err := cs.Upload(ctx, strings.NewReader("{}"), cfr)
+			return err
+		},
+		"ListObjects": func(ctx context.Context, cs *cloudStorageClient) error {
+			names, err := cs.ListObjects(ctx, cfr)
+			require.Nil(t, names)
+			return err
+		},
+		"ListObjectsInfo": func(ctx context.Context, cs *cloudStorageClient) error {
+			_, err := cs.ListObjectsInfo(ctx, cfr)
+			return err
+		},
+		"DeleteObjects": func(ctx context.Context, cs *cloudStorageClient) error {
+			return cs.DeleteObjects(ctx, cfr)
+		},
+		"ListDir": func(ctx context.Context, cs *cloudStorageClient) error {
+			_, err := cs.ListDir(ctx, cfr)
+			return err
+		},
+	}
+
+	for _, tc := range []struct {
+		name          string
+		bucketMissing bool
+		methods       map[string]func(ctx context.Context, cs *cloudStorageClient) error
+		want          error
+		notWant       error
+	}{
+		{name: "missing bucket", bucketMissing: true, methods: methods, want: ErrBucketNotFound, notWant: ErrObjectNotFound},
+		{name: "missing object", bucketMissing: false, methods: methods, want: ErrObjectNotFound, notWant: ErrBucketNotFound},
+		{name: "missing bucket only", bucketMissing: true, methods: bucketMethods, want: ErrBucketNotFound, notWant: ErrObjectNotFound},
+	} {
+		for method, fn := range tc.methods {
+			t.Run(tc.name+" "+method, func(t *testing.T) {
+				cs := newFakeClient(t, notFoundHandler(tc.bucketMissing))
+				err := fn(context.Background(), cs)
+				require.Error(t, err)
+				require.True(t, errors.Is(err, tc.want), "%s: %v", method, err)
+				require.False(t, errors.Is(err, tc.notWant), "%s: %v", method, err)
+
+				var sErr StorageError
+				require.True(t, errors.As(err, &sErr))
+				require.Equal(t, "bucket", sErr.Bucket)
+				require.NotEmpty(t, sErr.Op)
+			})
+		}
+	}
+}
+
+func TestErrorClassUnclassified(t *testing.T) {
+	op := &operation{name: "GetAttrs", bucket: "bucket"}
+	err := op.wrapError(errors.New("boom"), "failed")
+	require.False(t, errors.Is(err, ErrBucketNotFound))
+	require.False(t, errors.Is(err, ErrObjectNotFound))
+}
+
+func TestListingPartialFailure(t *testing.T) {
+	listings := map[string]func(ctx context.Context, cs *cloudStorageClient) (interface{}, error){
+		"ListObjects": func(ctx context.Context, cs *cloudStorageClient) (interface{}, error) {
+			return cs.ListObjects(ctx, CloudFileRequest{bucket: "bucket", wholeBucket: true})
+		},
+		"ListObjectsInfo": func(ctx context.Context, cs *cloudStorageClient) (interface{}, error) {
+			return cs.ListObjectsInfo(ctx, CloudFileRequest{bucket: "bucket", path: "data"})
+		},
+		"ListDir": func(ctx context.Context, cs *cloudStorageClient) (interface{}, error) {
+			return cs.ListDir(ctx, CloudFileRequest{bucket: "bucket", path: "data"})
+		},
+		"FindObjectsByTag": func(ctx context.Context, cs *cloudStorageClient) (interface{}, error) {
+			return cs.FindObjectsByTag(ctx, "bucket", "data", "team", "a")
+		},
+		"SnapshotPrefix": func(ctx context.Context, cs *cloudStorageClient) (interface{}, error) {
+			return cs.SnapshotPrefix(ctx, CloudFileRequest{bucket: "bucket", path: "data"}, time.Now())
+		},
+	}
+	empty := map[string]interface{}{
+		"ListObjects":      []string{},
+		"ListObjectsInfo":  []*ObjectAttrs{},
+		"ListDir":          DirListing{Files: []string{}, Dirs: []string{}},
+		"FindObjectsByTag": []*ObjectAttrs{},
+		"SnapshotPrefix":   []ObjectVersion{},
+	}
+	for name, list := range listings {
+		t.Run(name, func(t *testing.T) {
+			// empty listing is a non nil empty result
+			cs := newFakeClient(t, newFakeGCS())
+			res, err := list(context.Background(), cs)
+			require.NoError(t, err)
+			require.Equal(t, empty[name], res)
+
+			f := newFakeGCS()
+			for _, n := range []string{"data/a.txt", "data/b.txt", "data/c.txt"} {
+				f.put("bucket", n, []byte(n), map[string]string{tagMetadataKey("team"): "a"})
+			}
+			f.pageSize = 2
+			f.fail = func(r *http.Request) int {
+				if r.URL.Query().Get("pageToken") != "" {
+					return http.StatusForbidden
+				}
+				return 0
+			}
+			cs = newFakeClient(t, f)
+
+			res, err = list(context.Background(), cs)
+			require.Error(t, err)
+			// no results on error
+			require.True(t, reflect.ValueOf(res).IsZero(), "%v", res)
+
+			var pErr PartialListError
+			require.True(t, errors.As(err, &pErr))
+			require.Equal(t, []string{"data/a.txt", "data/b.txt"}, pErr.Names)
+			var sErr StorageError
+			require.True(t, errors.As(err, &sErr))
+			require.Equal(t, name, sErr.Op)
+		})
+	}
+}
+
+func TestListingFirstPageFailure(t *testing.T) {
+	f := newFakeGCS()
+	f.fail = func(r *http.Request) int {
+		return http.StatusForbidden
+	}
+	cs := newFakeClient(t, f)
+
+	names, err := cs.ListObjects(context.Background(), CloudFileRequest{bucket: "bucket", wholeBucket: true})
+	require.Error(t, err)
+	require.Nil(t, names)
+	// nothing collected, no partial results
+	var pErr PartialListError
+	require.False(t, errors.As(err, &pErr))
+}
+
+func TestListPermissionDenied(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/file.json", []byte("{}"), nil)
+	denyList := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// objects can be read by name, not listed
+		if r.Method == http.MethodGet && r.URL.Path == "/storage/v1/b/bucket/o" {
+			writeAPIError(w, http.StatusForbidden, "sa@project.iam.gserviceaccount.com does not have storage.objects.list access to the Google Cloud Storage bucket.")
+			return
+		}
+		f.ServeHTTP(w, r)
+	})
+	cs := newFakeClient(t, denyList)
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	listings := map[string]func() error{
+		"ListObjects": func() error {
+			_, err := cs.ListObjects(ctx, cfr)
+			return err
+		},
+		"ListObjectsInfo": func() error {
+			_, err := cs.ListObjectsInfo(ctx, cfr)
+			return err
+		},
+		"ListDir": func() error {
+			_, err := cs.ListDir(ctx, cfr)
+			return err
+		},
+		"DeleteObjects": func() error {
+			return cs.DeleteObjects(ctx, cfr)
+		},
+		"ExportInventory": func() error {
+			_, err := cs.ExportInventory(ctx, cfr, io.Discard, InventoryJSONL)
+			return err
+		},
+		"FindObjectsByTag": func() error {
+			_, err := cs.FindObjectsByTag(ctx, "bucket", "path", "k", "v")
+			return err
+		},
+	}
+	for name, list := range listings {
+		t.Run(name, func(t *testing.T) {
+			err := list()
+			require.ErrorIs(t, err, ErrPermissionDenied)
+			require.False(t, errors.Is(err, ErrBucketNotFound))
+			var sErr StorageError
+			require.True(t, errors.As(err, &sErr))
+			require.Equal(t, "storage.objects.list", sErr.Permission)
+		})
+	}
+
+	// callers degrade to access by name
+	_, err = cs.GetAttrs(ctx, cfr)
+	require.NoError(t, err)
+}
+
+func TestMissingPermission(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "error info", err: &googleapi.Error{Code: http.StatusForbidden, Details: []interface{}{
+			map[string]interface{}{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "metadata": map[string]interface{}{"permission": "storage.objects.delete"}},
+		}}, want: "storage.objects.delete"},
+		{name: "error item", err: &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{
+			{Reason: "forbidden", Message: "caller does not have storage.buckets.get access"},
+		}}, want: "storage.buckets.get"},
+		{name: "unnamed", err: &googleapi.Error{Code: http.StatusForbidden, Message: "Forbidden"}},
+		{name: "not forbidden", err: &googleapi.Error{Code: http.StatusNotFound, Message: "no storage.objects.get"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, missingPermission(tc.err))
+		})
+	}
+}
+
+func TestErrorDetails(t *testing.T) {
+	quotaStatus, err := status.New(codes.ResourceExhausted, "quota exceeded").WithDetails(
+		&errdetails.ErrorInfo{Reason: "RESOURCE_EXHAUSTED", Domain: "storage.googleapis.com", Metadata: map[string]string{"quota_metric": "storage.googleapis.com/egress"}},
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{Subject: "project:123", Description: "egress bandwidth"}}},
+	)
+	require.NoError(t, err)
+	rateStatus, err := status.New(codes.ResourceExhausted, "slow down").WithDetails(
+		&errdetails.ErrorInfo{Reason: "RATE_LIMIT_EXCEEDED", Domain: "googleapis.com"},
+	)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name          string
+		err           error
+		reason        string
+		domain        string
+		details       map[string]string
+		rateLimited   bool
+		quotaExceeded bool
+	}{
+		{name: "rate limit item", err: &googleapi.Error{Code: http.StatusTooManyRequests,
+			Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded", Message: "The project exceeded the rate limit"}},
+			Body:   `{"error":{"errors":[{"domain":"usageLimits","reason":"rateLimitExceeded"}]}}`,
+		}, reason: "rateLimitExceeded", domain: "usageLimits", rateLimited: true},
+		{name: "user rate limit item", err: &googleapi.Error{Code: http.StatusForbidden,
+			Errors: []googleapi.ErrorItem{{Reason: "userRateLimitExceeded"}},
+		}, reason: "userRateLimitExceeded", rateLimited: true},
+		{name: "quota error info", err: &googleapi.Error{Code: http.StatusTooManyRequests, Details: []interface{}{
+			map[string]interface{}{"@type": "type.googleapis.com/google.rpc.ErrorInfo", "reason": "quotaExceeded", "domain": "storage.googleapis.com",
+				"metadata": map[string]interface{}{"quota_metric": "storage.googleapis.com/requests", "quota_limit": "RequestsPerMinute"}},
+			map[string]interface{}{"@type": "type.googleapis.com/google.rpc.QuotaFailure", "violations": []interface{}{
+				map[string]interface{}{"subject": "project:123", "description": "requests per minute"},
+			}},
+		}}, reason: "quotaExceeded", domain: "storage.googleapis.com", details: map[string]string{
+			"quota_metric": "storage.googleapis.com/requests", "quota_limit": "RequestsPerMinute",
+			"quota_subject": "project:123", "quota_description": "requests per minute",
+		}, quotaExceeded: true},
+		{name: "unexplained 429", err: &googleapi.Error{Code: http.StatusTooManyRequests}, rateLimited: true},
+		{name: "forbidden", err: &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "forbidden"}}}, reason: "forbidden"},
+		{name: "grpc quota", err: quotaStatus.Err(), reason: "RESOURCE_EXHAUSTED", domain: "storage.googleapis.com", details: map[string]string{
+			"quota_metric": "storage.googleapis.com/egress", "quota_subject": "project:123", "quota_description": "egress bandwidth",
+		}, quotaExceeded: true},
+		{name: "grpc rate limit", err: rateStatus.Err(), reason: "RATE_LIMIT_EXCEEDED", domain: "googleapis.com", rateLimited: true},
+		{name: "grpc unexplained", err: status.Error(codes.ResourceExhausted, "exhausted"), rateLimited: true},
+		{name: "grpc not found", err: status.Error(codes.NotFound, "no such object")},
+		{name: "wrapped", err: fmt.Errorf("reading: %w", &googleapi.Error{Code: http.StatusForbidden,
+			Errors: []googleapi.ErrorItem{{Reason: "dailyLimitExceeded"}},
+		}), reason: "dailyLimitExceeded", quotaExceeded: true},
+		{name: "other", err: errors.New("boom")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			op := &operation{name: "GetAttrs", bucket: "bucket", object: "file.json"}
+			err := op.wrapError(tc.err, "%s %s", ERROR_GETTING_ATTRS, "file.json")
+			var sErr StorageError
+			require.True(t, errors.As(err, &sErr))
+			require.Equal(t, tc.reason, sErr.Reason)
+			require.Equal(t, tc.domain, sErr.Domain)
+			require.Equal(t, tc.details, sErr.Details)
+			for _, err := range []error{tc.err, err} {
+				require.Equal(t, tc.rateLimited, IsRateLimited(err))
+				require.Equal(t, tc.quotaExceeded, IsQuotaExceeded(err))
+			}
+		})
+	}
+}
+
+func TestQuotaExceededPropagation(t *testing.T) {
+	quota := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `{"error":{"code":403,"message":"Daily Limit Exceeded","errors":[{"domain":"usageLimits","reason":"dailyLimitExceeded","message":"Daily Limit Exceeded"}]}}`)
+	})
+	cs := newFakeClient(t, quota)
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0)
+	require.NoError(t, err)
+
+	_, err = cs.GetAttrs(context.Background(), cfr)
+	require.True(t, IsQuotaExceeded(err))
+	require.False(t, IsRateLimited(err))
+	var sErr StorageError
+	require.True(t, errors.As(err, &sErr))
+	require.Equal(t, "dailyLimitExceeded", sErr.Reason)
+	require.Equal(t, "usageLimits", sErr.Domain)
+}
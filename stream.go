@@ -0,0 +1,208 @@
+package cloudstorage
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"go.uber.org/zap"
+)
+
+// Methods returning live readers or writers tie their lifetime to the caller's context only,
+// internal contexts are never cancelled before the returned resource is closed.
+
+// ObjectReader is a live reader of a cloud file's content, must be closed.
+// Its accessors return the attributes the read response carried, e.g. to set HTTP headers before copying.
+type ObjectReader struct {
+	// Attrs are the attributes of the generation being read
+	Attrs *ObjectAttrs
+	r     *startedReader
+	cr    *countingReader
+	op    *operation
+	// release frees the reader's transfer slot
+	release func()
+}
+
+// Read reads the cloud file's content
+func (or *ObjectReader) Read(p []byte) (int, error) {
+	return or.cr.Read(p)
+}
+
+// Size returns the object's size, of the whole object for range reads
+func (or *ObjectReader) Size() int64 {
+	return or.r.Attrs.Size
+}
+
+// ContentType returns the object's content type
+func (or *ObjectReader) ContentType() string {
+	return or.r.Attrs.ContentType
+}
+
+// ContentEncoding returns the object's stored content encoding
+func (or *ObjectReader) ContentEncoding() string {
+	return or.r.Attrs.ContentEncoding
+}
+
+// CacheControl returns the object's cache control
+func (or *ObjectReader) CacheControl() string {
+	return or.r.Attrs.CacheControl
+}
+
+// Generation returns the generation being read
+func (or *ObjectReader) Generation() int64 {
+	return or.r.Attrs.Generation
+}
+
+// LastModified returns the object's last modification time, at second precision
+func (or *ObjectReader) LastModified() time.Time {
+	return or.r.Attrs.LastModified
+}
+
+// Remaining returns the number of bytes left to read, from the read offset on,
+// -1 when unknown, e.g. for transcoded reads
+func (or *ObjectReader) Remaining() int64 {
+	return or.r.Remain()
+}
+
+// Close closes the reader & ends the read operation
+func (or *ObjectReader) Close() error {
+	defer or.op.finish()
+	defer or.release()
+	if err := or.r.Close(); err != nil {
+		or.op.logger.Error("error closing cloud file reader", zap.Error(err), zap.String("filepath", or.op.object))
+		return or.op.wrapError(err, "error closing cloud file reader %s", or.op.object)
+	}
+	return nil
+}
+
+// WithReadOffset makes OpenReader start reading at given offset
+func WithReadOffset(offset int64) CloudFileRequestOption {
+	return func(cfr *CloudFileRequest) {
+		cfr.readOffset = offset
+	}
+}
+
+// OpenReader returns a live reader of the cloud file at request's bucket & filepath,
+// pinned to the current generation unless WithGeneration is set, from WithReadOffset when set.
+// The reader lives until closed or the caller's context is done.
+func (cs *cloudStorageClient) OpenReader(ctx context.Context, cfr CloudFileRequest) (*ObjectReader, error) {
+	return cs.openReader(ctx, "OpenReader", cfr, cfr.readOffset, -1, nil)
+}
+
+// openReader returns a live reader of length bytes from given offset of the request's object,
+// to the end when length is negative, given check validates the scoped request & read generation's attributes first
+func (cs *cloudStorageClient) openReader(ctx context.Context, name string, cfr CloudFileRequest, offset, length int64, check func(op *operation, cfr CloudFileRequest, attrs *storage.ObjectAttrs) error) (*ObjectReader, error) {
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return nil, err
+	}
+	if cfr.bucket == "" {
+		return nil, ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return nil, ErrFileNameMissing
+	}
+	op := cs.startOperation(ctx, name, cfr)
+	ctx = op.ctx
+
+	obj := cs.objectHandle(cfr, op.object)
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		op.logger.Error("cloud file inaccessible", zap.Error(err), zap.String("filepath", op.object))
+		defer op.finish()
+		return nil, op.wrapError(err, "cloud file inaccessible %s", op.object)
+	}
+	if check != nil {
+		if err := check(op, cfr, attrs); err != nil {
+			defer op.finish()
+			return nil, err
+		}
+	}
+	// the slot is held until the reader is closed
+	release, err := op.acquireTransfer(ctx)
+	if err != nil {
+		op.logger.Error(ERROR_WAITING_TRANSFER_SLOT, zap.Error(err), zap.String("filepath", op.object))
+		defer op.finish()
+		return nil, op.wrapError(err, "%s %s", ERROR_WAITING_TRANSFER_SLOT, op.object)
+	}
+	rc, err := op.openStarted(ctx, cfr, func(ctx context.Context) (*storage.Reader, error) {
+		return obj.Generation(attrs.Generation).ReadCompressed(cfr.readCompressed).NewRangeReader(ctx, offset, length)
+	})
+	if err != nil {
+		release()
+		op.logger.Error("error reading cloud file", zap.Error(err), zap.String("filepath", op.object))
+		defer op.finish()
+		return nil, op.wrapError(err, "error reading cloud file %s", op.object)
+	}
+	return &ObjectReader{
+		Attrs:   cfr.logicalAttrs(newObjectAttrs(attrs)),
+		r:       rc,
+		cr:      &countingReader{r: rc, op: op},
+		op:      op,
+		release: release,
+	}, nil
+}
+
+// objectReader streams request's object content through Download,
+// the download's checksum verification & preconditions apply
+type objectReader struct {
+	*io.PipeReader
+	done chan error
+}
+
+func (cs *cloudStorageClient) newObjectReader(ctx context.Context, cfr CloudFileRequest) *objectReader {
+	pr, pw := io.Pipe()
+	or := &objectReader{PipeReader: pr, done: make(chan error, 1)}
+	go func() {
+		_, err := cs.Download(ctx, pw, cfr)
+		pw.CloseWithError(err)
+		or.done <- err
+	}()
+	return or
+}
+
+// finish stops the download & returns the first of given consumer error & the download error.
+// The download error is ignored when the consumer stopped early.
+func (or *objectReader) finish(err error) error {
+	or.PipeReader.Close()
+	dErr := <-or.done
+	if err != nil {
+		return err
+	}
+	return dErr
+}
+
+// writeObject uploads the content produced by given write func,
+// write errors fail the upload, gzip compresses the content
+func (cs *cloudStorageClient) writeObject(ctx context.Context, cfr CloudFileRequest, gz bool, write func(w io.Writer) error) (UploadResult, error) {
+	if err := cs.mutation(); err != nil {
+		return UploadResult{}, err
+	}
+	if gz {
+		cfr.contentEncoding = "gzip"
+	}
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var w io.Writer = pw
+		var zw *gzip.Writer
+		if gz {
+			zw = gzip.NewWriter(pw)
+			w = zw
+		}
+		err := write(w)
+		if err == nil && zw != nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	res, err := cs.Upload(ctx, pr, cfr)
+	// unblock the writer when the upload stopped reading
+	pr.CloseWithError(io.ErrClosedPipe)
+	<-done
+	return res, err
+}
@@ -0,0 +1,128 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenReaderOutlivesCall(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100*1024)
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", content, nil)
+	cs := newFakeClient(t, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r, err := cs.OpenReader(ctx, cfr)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), r.Attrs.Size)
+
+	// read well after the originating call returned
+	time.Sleep(50 * time.Millisecond)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, content, data)
+	require.NoError(t, r.Close())
+}
+
+func TestOpenReaderMetadata(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1024)
+	cs := newFakeClient(t, newFakeGCS())
+	cfr, err := NewCloudFileRequest("bucket", "file.json", "path", 0, WithContentType("application/json"), WithCacheControl("public, max-age=60"))
+	require.NoError(t, err)
+	_, err = cs.Upload(context.Background(), bytes.NewReader(content), cfr)
+	require.NoError(t, err)
+	attrs, err := cs.GetAttrs(context.Background(), cfr)
+	require.NoError(t, err)
+
+	var rc io.ReadCloser
+	r, err := cs.OpenReader(context.Background(), cfr)
+	require.NoError(t, err)
+	rc = r
+	defer rc.Close()
+	require.Equal(t, attrs.Size, r.Size())
+	require.Equal(t, attrs.ContentType, r.ContentType())
+	require.Equal(t, attrs.ContentEncoding, r.ContentEncoding())
+	require.Equal(t, attrs.CacheControl, r.CacheControl())
+	require.Equal(t, attrs.Generation, r.Generation())
+	require.Equal(t, attrs.Updated.Truncate(time.Second), r.LastModified().UTC())
+	require.Equal(t, attrs.Size, r.Remaining())
+
+	// range readers report the object size & what's left from the offset
+	WithReadOffset(4000)(&cfr)
+	rr, err := cs.OpenReader(context.Background(), cfr)
+	require.NoError(t, err)
+	defer rr.Close()
+	require.Equal(t, attrs.Size, rr.Size())
+	require.Equal(t, attrs.Size-4000, rr.Remaining())
+	_, err = io.ReadFull(rr, make([]byte, 1000))
+	require.NoError(t, err)
+	require.Equal(t, attrs.Size-5000, rr.Remaining())
+}
+
+func TestOpenReaderCallerCancel(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1024*1024)
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", content, nil)
+	cs := newFakeClient(t, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, err := cs.OpenReader(ctx, cfr)
+	require.NoError(t, err)
+	defer r.Close()
+
+	buf := make([]byte, 1024)
+	_, err = io.ReadFull(r, buf)
+	require.NoError(t, err)
+
+	// caller's context ends the reader
+	cancel()
+	_, err = io.Copy(io.Discard, r)
+	require.Error(t, err)
+}
+
+func TestReadAtChunks(t *testing.T) {
+	content := []byte("0123456789abcdef")
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", content, nil)
+	cs := newFakeClient(t, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	p := make([]byte, 6)
+	n, err := cs.ReadAt(ctx, cfr, p, 0)
+	require.NoError(t, err)
+	require.Equal(t, "012345", string(p[:n]))
+
+	n, err = cs.ReadAt(ctx, cfr, p, 6)
+	require.NoError(t, err)
+	require.Equal(t, "6789ab", string(p[:n]))
+
+	// short final chunk
+	n, err = cs.ReadAt(ctx, cfr, p, 12)
+	require.True(t, errors.Is(err, io.EOF))
+	require.Equal(t, "cdef", string(p[:n]))
+
+	// chunk ending at end of file
+	p = make([]byte, 4)
+	n, err = cs.ReadAt(ctx, cfr, p, 12)
+	require.True(t, errors.Is(err, io.EOF))
+	require.Equal(t, "cdef", string(p[:n]))
+
+	// past end of file
+	n, err = cs.ReadAt(ctx, cfr, p, 16)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 0, n)
+}
@@ -0,0 +1,165 @@
+package cloudstorage
+
+import (
+	"context"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_INVALID_TAG_KEY string = "invalid object tag key"
+	ERROR_SETTING_TAGS    string = "error setting cloud file tags"
+	ERROR_FINDING_TAGGED  string = "error finding tagged cloud files"
+)
+
+var (
+	ErrInvalidTagKey = errors.NewAppError(ERROR_INVALID_TAG_KEY)
+)
+
+// TAG_METADATA_PREFIX is the reserved metadata key prefix for object tags,
+// user metadata keys must not start with it
+const TAG_METADATA_PREFIX = "x-cs-tag-"
+
+// tagUpdateAttempts is the number of tag read-modify-write attempts on concurrent metadata updates
+const tagUpdateAttempts = 3
+
+// tagMetadataKey returns the metadata key of given tag key
+func tagMetadataKey(key string) string {
+	return TAG_METADATA_PREFIX + key
+}
+
+// tagsFromMetadata returns the tags in given metadata, without the reserved prefix
+func tagsFromMetadata(metadata map[string]string) map[string]string {
+	tags := map[string]string{}
+	for k, v := range metadata {
+		if strings.HasPrefix(k, TAG_METADATA_PREFIX) {
+			tags[strings.TrimPrefix(k, TAG_METADATA_PREFIX)] = v
+		}
+	}
+	return tags
+}
+
+// tagsMetadataUpdate returns the metadata update setting given tags,
+// tags with empty values are removed
+func tagsMetadataUpdate(tags map[string]string) (map[string]string, error) {
+	update := make(map[string]string, len(tags))
+	for k, v := range tags {
+		if strings.TrimSpace(k) == "" || strings.HasPrefix(k, TAG_METADATA_PREFIX) {
+			return nil, errors.WrapError(ErrInvalidTagKey, "%s %q", ERROR_INVALID_TAG_KEY, k)
+		}
+		update[tagMetadataKey(k)] = v
+	}
+	return update, nil
+}
+
+// SetObjectTags merges given tags into the cloud file's tags, tags with empty values are removed.
+// Unrelated metadata keys are preserved, the update is conditional on the metageneration read,
+// retried on concurrent updates unless the request sets WithIfMetagenerationMatch.
+func (cs *cloudStorageClient) SetObjectTags(ctx context.Context, cfr CloudFileRequest, tags map[string]string) (map[string]string, error) {
+	if err := cs.mutation(); err != nil {
+		return nil, err
+	}
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return nil, err
+	}
+	if cfr.bucket == "" {
+		return nil, ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return nil, ErrFileNameMissing
+	}
+	update, err := tagsMetadataUpdate(tags)
+	if err != nil {
+		return nil, err
+	}
+	op := cs.startOperation(ctx, "SetObjectTags", cfr)
+	defer op.finish()
+	ctx = op.ctx
+
+	obj := cs.bucketHandle(cfr).Object(op.object)
+	attempts := tagUpdateAttempts
+	if cfr.metagenerationMatch != 0 {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		metageneration := cfr.metagenerationMatch
+		if metageneration == 0 {
+			attrs, err := obj.Attrs(ctx)
+			if err != nil {
+				op.logger.Error(ERROR_GETTING_ATTRS, zap.Error(err), zap.String("filepath", op.object))
+				return nil, op.wrapError(err, "%s %s", ERROR_GETTING_ATTRS, op.object)
+			}
+			metageneration = attrs.Metageneration
+		}
+
+		attrs, err := obj.If(storage.Conditions{MetagenerationMatch: metageneration}).Update(ctx, storage.ObjectAttrsToUpdate{Metadata: update})
+		cs.invalidate(cfr.bucket, op.object)
+		if err == nil {
+			op.generation = attrs.Generation
+			op.logger.Debug("cloud file tags updated", zap.String("filepath", op.object), zap.Int64("metageneration", attrs.Metageneration))
+			return tagsFromMetadata(attrs.Metadata), nil
+		}
+		if !isPreconditionFailed(err) || attempt >= attempts {
+			op.logger.Error(ERROR_SETTING_TAGS, zap.Error(err), zap.String("filepath", op.object), zap.Int("attempt", attempt))
+			return nil, op.wrapError(err, "%s %s", ERROR_SETTING_TAGS, op.object)
+		}
+		op.logger.Info("cloud file metadata changed, retrying tag update", zap.String("filepath", op.object), zap.Int("attempt", attempt))
+	}
+}
+
+// GetObjectTags returns the cloud file's tags
+func (cs *cloudStorageClient) GetObjectTags(ctx context.Context, cfr CloudFileRequest) (map[string]string, error) {
+	attrs, err := cs.GetAttrs(ctx, cfr)
+	if err != nil {
+		return nil, err
+	}
+	return tagsFromMetadata(attrs.Metadata), nil
+}
+
+// FindObjectsByTag returns attributes of objects under given prefix tagged with given key & value.
+// GCS can't filter on metadata, the listing is streamed & filtered client side,
+// cost is proportional to the number of objects under prefix.
+func (cs *cloudStorageClient) FindObjectsByTag(ctx context.Context, bucket, prefix, key, value string) ([]*ObjectAttrs, error) {
+	cfr, err := cs.scoped(ctx, CloudFileRequest{bucket: bucket, path: prefix})
+	if err != nil {
+		return nil, err
+	}
+	bucket = cfr.bucket
+	if bucket == "" {
+		return nil, ErrBucketNameMissing
+	}
+	if strings.TrimSpace(key) == "" {
+		return nil, ErrInvalidTagKey
+	}
+	op := cs.startOperation(ctx, "FindObjectsByTag", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	op.object = dirPrefix(cfr.path)
+
+	metaKey := tagMetadataKey(key)
+	found := []*ObjectAttrs{}
+	it := cs.bucketHandle(cfr).Objects(ctx, &storage.Query{Prefix: op.object})
+	for {
+		objAttrs, err := it.Next()
+		if err != nil {
+			if err == iterator.Done {
+				break
+			}
+			op.logger.Error(ERROR_FINDING_TAGGED, zap.Error(err), zap.String("tag", key))
+			names := make([]string, len(found))
+			for i, attrs := range found {
+				names[i] = attrs.Name
+			}
+			return nil, op.wrapError(partialList(names, err), "%s %s", ERROR_FINDING_TAGGED, key)
+		}
+		if v, ok := objAttrs.Metadata[metaKey]; ok && v == value {
+			found = append(found, cfr.logicalAttrs(newObjectAttrs(objAttrs)))
+		}
+	}
+	return found, nil
+}
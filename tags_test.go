@@ -0,0 +1,35 @@
+package cloudstorage
+
+import (
+	"testing"
+
+	"github.com/comfforts/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagsMetadataUpdate(t *testing.T) {
+	update, err := tagsMetadataUpdate(map[string]string{"tenant": "acme", "retention": ""})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		TAG_METADATA_PREFIX + "tenant":    "acme",
+		TAG_METADATA_PREFIX + "retention": "",
+	}, update)
+
+	for _, key := range []string{"", " ", TAG_METADATA_PREFIX + "tenant"} {
+		_, err := tagsMetadataUpdate(map[string]string{key: "v"})
+		require.Error(t, err)
+		var appErr errors.AppError
+		require.ErrorAs(t, err, &appErr)
+		require.Equal(t, ErrInvalidTagKey, appErr.Inner)
+	}
+}
+
+func TestTagsFromMetadata(t *testing.T) {
+	tags := tagsFromMetadata(map[string]string{
+		"state":                           "reviewed",
+		TAG_METADATA_PREFIX + "tenant":    "acme",
+		TAG_METADATA_PREFIX + "retention": "7y",
+	})
+	require.Equal(t, map[string]string{"tenant": "acme", "retention": "7y"}, tags)
+	require.Empty(t, tagsFromMetadata(nil))
+}
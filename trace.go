@@ -0,0 +1,242 @@
+package cloudstorage
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// TraceDecision is what a composite operation decided for one object
+type TraceDecision string
+
+const (
+	// TraceCopy copies an object missing from the destination
+	TraceCopy TraceDecision = "copy"
+	// TraceUpdate copies an object whose destination differs
+	TraceUpdate TraceDecision = "update"
+	// TraceDelete deletes a destination object missing from the source
+	TraceDelete TraceDecision = "delete"
+	// TraceInSync leaves an object equal on both sides alone
+	TraceInSync TraceDecision = "in_sync"
+	// TraceFiltered leaves an object excluded by the operation's filters alone
+	TraceFiltered TraceDecision = "filtered"
+	// TraceExtraneous leaves a destination object missing from the source alone, deletes weren't requested
+	TraceExtraneous TraceDecision = "extraneous"
+)
+
+// TraceOutcome is the outcome of a traced decision's action
+type TraceOutcome string
+
+const (
+	// TraceOK is an action that succeeded
+	TraceOK TraceOutcome = "ok"
+	// TraceFailed is an action that failed, the record's Error is the failure's message
+	TraceFailed TraceOutcome = "failed"
+	// TracePlanned is an action of a dry run, not taken
+	TracePlanned TraceOutcome = "planned"
+	// TraceNoAction is a decision without action
+	TraceNoAction TraceOutcome = "none"
+)
+
+// TraceObject is an object a decision was made on, as listed
+type TraceObject struct {
+	Bucket     string `json:"bucket"`
+	Name       string `json:"name"`
+	Generation int64  `json:"generation"`
+	Size       int64  `json:"size"`
+	CRC32C     uint32 `json:"crc32c"`
+}
+
+// TraceRecord is one decision of a composite operation, with its inputs, the action taken & its outcome
+type TraceRecord struct {
+	// Seq numbers the operation's decisions from 1, in the order they were made
+	Seq       int64     `json:"seq"`
+	Time      time.Time `json:"time"`
+	Op        string    `json:"op"`
+	RequestID string    `json:"request_id"`
+	// Name is the object name the decision is about, relative to the operation's prefixes
+	Name string `json:"name"`
+	// Source & Dest are the compared objects, nil on the side missing it
+	Source   *TraceObject  `json:"source,omitempty"`
+	Dest     *TraceObject  `json:"dest,omitempty"`
+	Decision TraceDecision `json:"decision"`
+	// Reason explains the decision, e.g. the differing attribute
+	Reason string `json:"reason,omitempty"`
+	// Action is the call the decision made, with its precondition, empty without action
+	Action    string       `json:"action,omitempty"`
+	Condition string       `json:"condition,omitempty"`
+	Outcome   TraceOutcome `json:"outcome"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// OperationTrace receives the decision records of composite operations, e.g. ReconcileBuckets,
+// in Seq order, one call per record, never concurrently for one operation
+type OperationTrace interface {
+	Trace(TraceRecord)
+}
+
+// NDJSONTrace writes trace records to a writer, one JSON object per line
+type NDJSONTrace struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewNDJSONTrace returns a trace writing records to given writer
+func NewNDJSONTrace(w io.Writer) *NDJSONTrace {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &NDJSONTrace{enc: enc}
+}
+
+func (t *NDJSONTrace) Trace(rec TraceRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return
+	}
+	t.err = t.enc.Encode(rec)
+}
+
+// Err returns the first write error, records after it are dropped
+func (t *NDJSONTrace) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// RingTrace retains the last records of traced operations in memory, e.g. for error reports
+type RingTrace struct {
+	mu      sync.Mutex
+	records []TraceRecord
+	next    int
+	full    bool
+}
+
+// NewRingTrace returns a trace retaining the last n records, at least one
+func NewRingTrace(n int) *RingTrace {
+	if n < 1 {
+		n = 1
+	}
+	return &RingTrace{records: make([]TraceRecord, n)}
+}
+
+func (t *RingTrace) Trace(rec TraceRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.records[t.next] = rec
+	t.next = (t.next + 1) % len(t.records)
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+// Records returns the retained records, oldest first
+func (t *RingTrace) Records() []TraceRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.full {
+		return append([]TraceRecord{}, t.records[:t.next]...)
+	}
+	return append(append([]TraceRecord{}, t.records[t.next:]...), t.records[:t.next]...)
+}
+
+// FilterTrace returns a trace passing records of given decisions to the trace, failed actions always
+func FilterTrace(trace OperationTrace, decisions ...TraceDecision) OperationTrace {
+	keep := map[TraceDecision]bool{}
+	for _, d := range decisions {
+		keep[d] = true
+	}
+	return traceFunc(func(rec TraceRecord) {
+		if keep[rec.Decision] || rec.Outcome == TraceFailed {
+			trace.Trace(rec)
+		}
+	})
+}
+
+// SampleTrace returns a trace passing every nth record of each decision to the trace, the first included,
+// failed actions always. Sampling by decision keeps rare decisions of huge runs, e.g. deletes among
+// millions of objects in sync.
+func SampleTrace(trace OperationTrace, n int) OperationTrace {
+	var mu sync.Mutex
+	seen := map[TraceDecision]int{}
+	return traceFunc(func(rec TraceRecord) {
+		mu.Lock()
+		i := seen[rec.Decision]
+		seen[rec.Decision]++
+		mu.Unlock()
+		if n <= 1 || i%n == 0 || rec.Outcome == TraceFailed {
+			trace.Trace(rec)
+		}
+	})
+}
+
+// traceFunc is a trace calling a func
+type traceFunc func(TraceRecord)
+
+func (f traceFunc) Trace(rec TraceRecord) {
+	f(rec)
+}
+
+// tracer numbers an operation's decisions & delivers their records in Seq order once complete,
+// records of concurrent actions complete out of order. A nil tracer traces nothing.
+type tracer struct {
+	trace     OperationTrace
+	op        string
+	requestID string
+	now       func() time.Time
+
+	mu      sync.Mutex
+	seq     int64
+	next    int64
+	pending map[int64]TraceRecord
+}
+
+// newTracer returns the tracer of given operation, nil without trace
+func newTracer(trace OperationTrace, op *operation, now func() time.Time) *tracer {
+	if trace == nil {
+		return nil
+	}
+	return &tracer{trace: trace, op: op.name, requestID: op.requestID, now: now, next: 1, pending: map[int64]TraceRecord{}}
+}
+
+// decide numbers given decision's record, completed with done
+func (t *tracer) decide(rec TraceRecord) TraceRecord {
+	if t == nil {
+		return rec
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seq++
+	rec.Seq, rec.Time, rec.Op, rec.RequestID = t.seq, t.now().UTC(), t.op, t.requestID
+	return rec
+}
+
+// done completes given decided record with its outcome, delivering the records complete in Seq order
+func (t *tracer) done(rec TraceRecord, outcome TraceOutcome, err error) {
+	if t == nil {
+		return
+	}
+	rec.Outcome = outcome
+	if err != nil {
+		rec.Outcome, rec.Error = TraceFailed, err.Error()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[rec.Seq] = rec
+	for {
+		next, ok := t.pending[t.next]
+		if !ok {
+			return
+		}
+		delete(t.pending, t.next)
+		t.next++
+		t.trace.Trace(next)
+	}
+}
+
+// record decides given record & completes it with given outcome
+func (t *tracer) record(rec TraceRecord, outcome TraceOutcome) {
+	t.done(t.decide(rec), outcome, nil)
+}
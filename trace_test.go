@@ -0,0 +1,117 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// traceDecisions returns the records' decisions as decision:name
+func traceDecisions(records []TraceRecord) []string {
+	decisions := []string{}
+	for _, rec := range records {
+		decisions = append(decisions, string(rec.Decision)+":"+rec.Name)
+	}
+	return decisions
+}
+
+// traceFixture is a reconcile with a decision of every kind, the delete of extra.txt forbidden
+func traceFixture(t *testing.T) (*cloudStorageClient, BucketPrefix, BucketPrefix) {
+	f := newFakeGCS()
+	f.put("bucket", "data/a.txt", []byte("a"), nil)
+	f.put("bucket", "data/b.txt", []byte("b"), nil)
+	f.put("bucket", "data/c.txt", []byte("c"), nil)
+	f.put("bucket", "data/d.txt", []byte("d"), nil)
+	f.put("mirror", "copy/b.txt", []byte("b"), nil)
+	f.put("mirror", "copy/c.txt", []byte("C"), nil)
+	f.put("mirror", "copy/d.txt", []byte("stale"), nil)
+	f.put("mirror", "copy/extra.txt", []byte("x"), nil)
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "extra.txt") {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	WithClock(newFakeClock())(cs)
+	return cs, BucketPrefix{Bucket: "bucket", Prefix: "data"}, BucketPrefix{Bucket: "mirror", Prefix: "copy"}
+}
+
+func TestReconcileTraceGolden(t *testing.T) {
+	for name, dryRun := range map[string]bool{"reconcile": false, "reconcile_dry_run": true} {
+		cs, src, dst := traceFixture(t)
+		var buf bytes.Buffer
+		trace := NewNDJSONTrace(&buf)
+		ctx := WithRequestID(context.Background(), "trace-request")
+		_, err := cs.ReconcileBuckets(ctx, src, dst, ReconcileOptions{DryRun: dryRun, DeleteExtraneous: true, Concurrency: 4, Trace: trace})
+		require.Equal(t, dryRun, err == nil, name)
+		require.NoError(t, trace.Err())
+
+		path := filepath.Join("testdata", "traces", name+".ndjson")
+		if *updateGolden {
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+		}
+		want, err := os.ReadFile(path)
+		require.NoError(t, err, "run go test -run TestReconcileTraceGolden -update to create %s", path)
+		require.Equal(t, string(want), buf.String(), "%s trace changed, fields may only be added", name)
+	}
+}
+
+func TestReconcileTrace(t *testing.T) {
+	cs, src, dst := traceFixture(t)
+	ctx := context.Background()
+
+	ring := NewRingTrace(3)
+	_, err := cs.ReconcileBuckets(ctx, src, dst, ReconcileOptions{DeleteExtraneous: true, Concurrency: 4, Trace: ring})
+	require.Error(t, err)
+	records := ring.Records()
+	require.Equal(t, []string{"update:c.txt", "update:d.txt", "delete:extra.txt"}, traceDecisions(records))
+	require.Equal(t, []int64{3, 4, 5}, []int64{records[0].Seq, records[1].Seq, records[2].Seq})
+	require.Equal(t, "crc32c differs", records[0].Reason)
+	require.Equal(t, "size differs", records[1].Reason)
+	require.Equal(t, TraceFailed, records[2].Outcome)
+	require.Contains(t, records[2].Error, "extra.txt")
+
+	// the rerun finds everything but the forbidden delete in sync, extraneous objects kept
+	filtered := NewRingTrace(10)
+	_, err = cs.ReconcileBuckets(ctx, src, dst, ReconcileOptions{Trace: FilterTrace(filtered, TraceExtraneous)})
+	require.NoError(t, err)
+	require.Equal(t, []string{"extraneous:extra.txt"}, traceDecisions(filtered.Records()))
+	require.Equal(t, TraceNoAction, filtered.Records()[0].Outcome)
+
+	sampled := NewRingTrace(10)
+	_, err = cs.ReconcileBuckets(ctx, src, dst, ReconcileOptions{Trace: SampleTrace(sampled, 2), UpdatedAfter: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, []string{"in_sync:a.txt", "in_sync:c.txt", "extraneous:extra.txt"}, traceDecisions(sampled.Records()))
+
+	// backups trace their reconcile
+	backup := NewRingTrace(10)
+	_, err = cs.BackupPrefixIncremental(ctx, cs.Bucket("bucket"), cs.Bucket("mirror"), "data", time.Time{}, WithBackupTrace(backup))
+	require.NoError(t, err)
+	require.Equal(t, []string{"copy:a.txt", "copy:b.txt", "copy:c.txt", "copy:d.txt"}, traceDecisions(backup.Records()))
+}
+
+func TestTracerOrder(t *testing.T) {
+	ring := NewRingTrace(4)
+	tr := newTracer(ring, &operation{name: "Op", requestID: "id"}, newFakeClock().Now)
+	first := tr.decide(TraceRecord{Name: "a", Decision: TraceCopy})
+	second := tr.decide(TraceRecord{Name: "b", Decision: TraceCopy})
+	tr.record(TraceRecord{Name: "c", Decision: TraceInSync}, TraceNoAction)
+	tr.done(second, TraceOK, nil)
+	require.Empty(t, ring.Records(), "records wait for earlier decisions")
+	tr.done(first, TraceOK, nil)
+	require.Equal(t, []string{"copy:a", "copy:b", "in_sync:c"}, traceDecisions(ring.Records()))
+	require.Equal(t, "Op", ring.Records()[0].Op)
+
+	var none *tracer
+	none.record(TraceRecord{}, TraceOK)
+	require.Nil(t, newTracer(nil, &operation{}, time.Now))
+}
@@ -0,0 +1,79 @@
+package cloudstorage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const ERROR_WAITING_TRANSFER_SLOT string = "error waiting for a transfer slot"
+
+// TransferSlotMetrics describe one transfer's wait for a MaxConcurrentTransfers slot
+type TransferSlotMetrics struct {
+	Op        string
+	Bucket    string
+	Object    string
+	RequestID string
+	// Wait is the time spent waiting for the slot, zero when one was free
+	Wait time.Duration
+	// InFlight is the number of transfers holding a slot, this one included once acquired
+	InFlight int
+	// Err is the context error of a wait given up, nil once acquired
+	Err error
+}
+
+// TransferSlotRecorder receives transfer slot waits, implemented by metrics recorders watching client concurrency
+type TransferSlotRecorder interface {
+	RecordTransferSlot(TransferSlotMetrics)
+}
+
+// transferLimiter bounds the number of the client's concurrent transfers, a slot per open stream
+type transferLimiter struct {
+	slots chan struct{}
+}
+
+// newTransferLimiter returns a limiter of given number of slots, nil for unlimited transfers
+func newTransferLimiter(max int) *transferLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &transferLimiter{slots: make(chan struct{}, max)}
+}
+
+// acquireTransfer waits for a transfer slot of the client, returns the func releasing it, safe to call twice.
+// Nothing is waited for without MaxConcurrentTransfers, slots are only taken by the calls opening streams,
+// composite operations never hold one while waiting for another.
+// A context done while waiting returns the context error, the caller wraps it.
+func (op *operation) acquireTransfer(ctx context.Context) (func(), error) {
+	if op.cs == nil || op.cs.transfers == nil {
+		return func() {}, nil
+	}
+	l := op.cs.transfers
+	start := op.cs.now()
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		op.recordTransferSlot(op.cs.since(start), len(l.slots), ctx.Err())
+		return nil, ctx.Err()
+	}
+	op.recordTransferSlot(op.cs.since(start), len(l.slots), nil)
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-l.slots })
+	}, nil
+}
+
+// recordTransferSlot reports a slot wait to the configured metrics recorder, when it records slots
+func (op *operation) recordTransferSlot(wait time.Duration, inFlight int, err error) {
+	if rec, ok := op.cs.config.Metrics.(TransferSlotRecorder); ok {
+		rec.RecordTransferSlot(TransferSlotMetrics{
+			Op:        op.name,
+			Bucket:    op.bucket,
+			Object:    op.object,
+			RequestID: op.requestID,
+			Wait:      wait,
+			InFlight:  inFlight,
+			Err:       err,
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// slotMetrics records transfer slot waits
+type slotMetrics struct {
+	recordingMetrics
+	slotsMu sync.Mutex
+	slots   []TransferSlotMetrics
+}
+
+func (m *slotMetrics) RecordTransferSlot(sm TransferSlotMetrics) {
+	m.slotsMu.Lock()
+	defer m.slotsMu.Unlock()
+	m.slots = append(m.slots, sm)
+}
+
+func (m *slotMetrics) recorded() []TransferSlotMetrics {
+	m.slotsMu.Lock()
+	defer m.slotsMu.Unlock()
+	return append([]TransferSlotMetrics{}, m.slots...)
+}
+
+func TestMaxConcurrentTransfers(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/a.txt", []byte("content a"), nil)
+	f.put("bucket", "path/b.txt", []byte("content b"), nil)
+	metrics := &slotMetrics{}
+	cs := newFakeClient(t, f)
+	cs.config.Metrics = metrics
+	cs.transfers = newTransferLimiter(1)
+	ctx := context.Background()
+	a, err := NewCloudFileRequest("bucket", "a.txt", "path", 0)
+	require.NoError(t, err)
+	b, err := NewCloudFileRequest("bucket", "b.txt", "path", 0)
+	require.NoError(t, err)
+
+	// an open reader holds the only slot
+	r, err := cs.OpenReader(ctx, a)
+	require.NoError(t, err)
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = cs.Download(waitCtx, io.Discard, b)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Contains(t, err.Error(), ERROR_WAITING_TRANSFER_SLOT)
+	waitCtx, cancel = context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = cs.Upload(waitCtx, strings.NewReader("new"), b)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Contains(t, err.Error(), ERROR_WAITING_TRANSFER_SLOT)
+	data, _, _ := f.get("bucket", "path/b.txt")
+	require.Equal(t, "content b", string(data))
+
+	// waiting transfers proceed once the slot is released
+	done := make(chan error, 1)
+	go func() {
+		var buf bytes.Buffer
+		_, err := cs.Download(ctx, &buf, b)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("download didn't wait for the slot: %v", err)
+	default:
+	}
+	_, err = io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	require.NoError(t, <-done)
+	require.NoError(t, r.Close(), "closing twice releases once")
+
+	slots := metrics.recorded()
+	require.Len(t, slots, 4)
+	require.Equal(t, "OpenReader", slots[0].Op)
+	require.Equal(t, 1, slots[0].InFlight)
+	require.NoError(t, slots[0].Err)
+	require.ErrorIs(t, slots[1].Err, context.DeadlineExceeded)
+	require.Equal(t, "UploadFile", slots[2].Op)
+	require.ErrorIs(t, slots[2].Err, context.DeadlineExceeded)
+	require.Equal(t, "DownloadFile", slots[3].Op)
+	require.Equal(t, "path/b.txt", slots[3].Object)
+	require.GreaterOrEqual(t, slots[3].Wait, 20*time.Millisecond)
+	require.NoError(t, slots[3].Err)
+}
+
+func TestMaxConcurrentTransfersParallelParts(t *testing.T) {
+	f := newFakeGCS()
+	metrics := &slotMetrics{}
+	cs := newFakeClient(t, f)
+	cs.config.Metrics = metrics
+	cs.transfers = newTransferLimiter(2)
+	cfr, err := NewCloudFileRequest("bucket", "parts.bin", "path", 0)
+	require.NoError(t, err)
+
+	content := bytes.Repeat([]byte("x"), 6*256*1024)
+	_, err = cs.UploadFromReaderAt(context.Background(), bytes.NewReader(content), int64(len(content)), cfr, WithChunkSize(256*1024), WithParallelUpload(1, 6))
+	require.NoError(t, err)
+	data, _, ok := f.get("bucket", "path/parts.bin")
+	require.True(t, ok)
+	require.Equal(t, content, data)
+
+	// every part took a slot, never more than the limit at once
+	slots := metrics.recorded()
+	require.Len(t, slots, 6)
+	for _, s := range slots {
+		require.LessOrEqual(t, s.InFlight, 2)
+	}
+	require.Empty(t, cs.transfers.slots, "slots are released")
+}
+
+func TestUnlimitedTransfers(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/a.txt", []byte("content"), nil)
+	cs := newFakeClient(t, f)
+	require.Nil(t, newTransferLimiter(0))
+	require.Nil(t, cs.transfers)
+	cfr, err := NewCloudFileRequest("bucket", "a.txt", "path", 0)
+	require.NoError(t, err)
+
+	readers := []*ObjectReader{}
+	for i := 0; i < 5; i++ {
+		r, err := cs.OpenReader(context.Background(), cfr)
+		require.NoError(t, err)
+		readers = append(readers, r)
+	}
+	for _, r := range readers {
+		require.NoError(t, r.Close())
+	}
+}
@@ -0,0 +1,176 @@
+package cloudstorage
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_TRANSFORMING_OBJECT  string = "error transforming cloud file"
+	ERROR_TRANSFORM_REQUIRED   string = "object transform missing"
+	ERROR_TRANSFORM_UPLOAD_END string = "transform destination upload ended"
+)
+
+var (
+	ErrTransformRequired = errors.NewAppError(ERROR_TRANSFORM_REQUIRED)
+	// errUploadEnded fails the transform's writes once the destination upload ended
+	errUploadEnded = errors.NewAppError(ERROR_TRANSFORM_UPLOAD_END)
+)
+
+// TransformFunc writes the transformed content of src to dst, e.g. decompressed, re-encoded or redacted,
+// returned errors fail the transform before the destination is committed
+type TransformFunc func(dst io.Writer, src io.Reader) error
+
+// TransformOptions configure TransformObject
+type TransformOptions struct {
+	// Progress, when set, is called with bytes read from the source & written to the destination
+	// as the transformed content is uploaded
+	Progress func(in, out int64)
+}
+
+// TransformOption sets transform options
+type TransformOption func(o *TransformOptions)
+
+// WithTransformProgress sets the transform progress callback
+func WithTransformProgress(fn func(in, out int64)) TransformOption {
+	return func(o *TransformOptions) {
+		o.Progress = fn
+	}
+}
+
+// TransformResult is the result of a successful transform, failed transforms return the bytes read & sent
+type TransformResult struct {
+	// BytesIn is the number of source bytes the transform read
+	BytesIn int64
+	// BytesOut is the number of transformed bytes uploaded
+	BytesOut int64
+	// Source are the attributes of the source generation read
+	Source *ObjectAttrs
+	// Attrs are the destination object's attributes
+	Attrs *ObjectAttrs
+	// Duration is the time from opening the source to the destination's commit
+	Duration time.Duration
+}
+
+// transformSource counts the source bytes the transform read, read by the upload's progress concurrently
+type transformSource struct {
+	r io.Reader
+	n int64
+}
+
+func (ts *transformSource) Read(p []byte) (int, error) {
+	n, err := ts.r.Read(p)
+	atomic.AddInt64(&ts.n, int64(n))
+	return n, err
+}
+
+// progressReader reports the transform's progress as transformed content is uploaded
+type progressReader struct {
+	r        io.Reader
+	out      int64
+	in       *transformSource
+	progress func(in, out int64)
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	if n > 0 {
+		pr.out += int64(n)
+		pr.progress(atomic.LoadInt64(&pr.in.n), pr.out)
+	}
+	return n, err
+}
+
+// TransformObject streams the src object through given transform into the dst object, without spooling,
+// the transform's writes are backed by the upload. The source is pinned to the generation opened.
+// Either side failing stops the other: transform errors abort the upload, so the destination
+// is never committed with partial content, & upload failures fail the transform's writes & source reads.
+// A failed transform is returned with its error in the chain.
+func (cs *cloudStorageClient) TransformObject(ctx context.Context, src, dst CloudFileRequest, transform TransformFunc, opts ...TransformOption) (TransformResult, error) {
+	if err := cs.mutation(); err != nil {
+		return TransformResult{}, err
+	}
+	src, err := cs.request(ctx, src)
+	if err != nil {
+		return TransformResult{}, err
+	}
+	dst, err = cs.request(ctx, dst)
+	if err != nil {
+		return TransformResult{}, err
+	}
+	for _, cfr := range []CloudFileRequest{src, dst} {
+		if cfr.bucket == "" {
+			return TransformResult{}, ErrBucketNameMissing
+		}
+		if cfr.file == "" {
+			return TransformResult{}, ErrFileNameMissing
+		}
+	}
+	if transform == nil {
+		return TransformResult{}, ErrTransformRequired
+	}
+	tOpts := TransformOptions{}
+	for _, opt := range opts {
+		opt(&tOpts)
+	}
+	op := cs.startOperation(ctx, "TransformObject", dst)
+	defer op.finish()
+	ctx = op.ctx
+	srcPath := src.objectPath()
+	start := cs.now()
+
+	// cancelled once the upload ended, unblocking source reads
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r, err := cs.OpenReader(ctx, src)
+	if err != nil {
+		op.logger.Error(ERROR_TRANSFORMING_OBJECT, zap.Error(err), zap.String("source", srcPath), zap.String("filepath", op.object))
+		return TransformResult{}, op.wrapError(err, "%s %s", ERROR_TRANSFORMING_OBJECT, srcPath)
+	}
+	defer r.Close()
+
+	in := &transformSource{r: r}
+	pr, pw := io.Pipe()
+	transformed := make(chan error, 1)
+	go func() {
+		err := transform(pw, in)
+		// a nil error ends the upload's content, any other aborts it
+		pw.CloseWithError(err)
+		transformed <- err
+	}()
+
+	var body io.Reader = pr
+	if tOpts.Progress != nil {
+		body = &progressReader{r: pr, in: in, progress: tOpts.Progress}
+	}
+	// streamed, spooling would hold the whole transformed content
+	WithoutSpooling()(&dst)
+	res, upErr := cs.Upload(WithRequestID(ctx, op.requestID), body, dst)
+	pr.CloseWithError(errUploadEnded)
+	cancel()
+	tErr := <-transformed
+	if upErr != nil {
+		if tErr != nil && !stderrors.Is(tErr, errUploadEnded) {
+			// the transform's failure aborted the upload
+			upErr = tErr
+		}
+		op.logger.Error(ERROR_TRANSFORMING_OBJECT, zap.Error(upErr), zap.String("source", srcPath), zap.String("filepath", op.object), zap.Int64("in", in.n))
+		return TransformResult{BytesIn: in.n, BytesOut: res.Bytes}, op.wrapError(upErr, "%s %s", ERROR_TRANSFORMING_OBJECT, srcPath)
+	}
+	// committed uploads read the content's end, the transform returned nil
+	op.bytes, op.generation = res.Bytes, res.Attrs.Generation
+	op.logger.Debug("cloud file transformed", zap.String("source", srcPath), zap.String("filepath", op.object), zap.Int64("in", in.n), zap.Int64("out", res.Bytes))
+	return TransformResult{
+		BytesIn:  in.n,
+		BytesOut: res.Bytes,
+		Source:   r.Attrs,
+		Attrs:    res.Attrs,
+		Duration: cs.since(start),
+	}, nil
+}
@@ -0,0 +1,117 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformObject(t *testing.T) {
+	content := bytes.Repeat([]byte("transform in transit "), 50*1024)
+	f := newFakeGCS()
+	f.put("bucket", "raw/data.txt", content, nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	src, err := NewCloudFileRequest("bucket", "data.txt", "raw", 0)
+	require.NoError(t, err)
+	dst, err := NewCloudFileRequest("bucket", "data.txt", "upper", 0, WithContentType("text/plain"))
+	require.NoError(t, err)
+
+	var lastIn, lastOut int64
+	res, err := cs.TransformObject(ctx, src, dst, func(w io.Writer, r io.Reader) error {
+		buf := make([]byte, 4096)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				if _, err := w.Write(bytes.ToUpper(buf[:n])); err != nil {
+					return err
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}, WithTransformProgress(func(in, out int64) {
+		lastIn, lastOut = in, out
+	}))
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), res.BytesIn)
+	require.Equal(t, int64(len(content)), res.BytesOut)
+	require.Equal(t, int64(len(content)), lastIn)
+	require.Equal(t, int64(len(content)), lastOut)
+	require.Equal(t, "raw/data.txt", res.Source.Name)
+	require.Equal(t, "upper/data.txt", res.Attrs.Name)
+
+	data, attrs, ok := f.get("bucket", "upper/data.txt")
+	require.True(t, ok)
+	require.Equal(t, bytes.ToUpper(content), data)
+	require.Equal(t, "text/plain", attrs.ContentType)
+
+	_, err = cs.TransformObject(ctx, src, dst, nil)
+	require.Equal(t, ErrTransformRequired, err)
+}
+
+func TestTransformObjectFailsHalfway(t *testing.T) {
+	content := bytes.Repeat([]byte("redact me "), 100*1024)
+	f := newFakeGCS()
+	f.put("bucket", "raw/data.txt", content, nil)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	src, err := NewCloudFileRequest("bucket", "data.txt", "raw", 0)
+	require.NoError(t, err)
+	dst, err := NewCloudFileRequest("bucket", "data.txt", "redacted", 0)
+	require.NoError(t, err)
+
+	failed := errors.New("malformed record")
+	_, err = cs.TransformObject(ctx, src, dst, func(w io.Writer, r io.Reader) error {
+		if _, err := io.CopyN(w, r, int64(len(content)/2)); err != nil {
+			return err
+		}
+		return failed
+	})
+	require.ErrorIs(t, err, failed)
+	_, _, ok := f.get("bucket", "redacted/data.txt")
+	require.False(t, ok, "the destination isn't committed")
+	require.Equal(t, []string{"raw/data.txt"}, f.storedNames("bucket"))
+}
+
+func TestTransformObjectUploadFailure(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "raw/data.txt", bytes.Repeat([]byte("x"), 1024*1024), nil)
+	f.fail = func(r *http.Request) int {
+		if strings.HasPrefix(r.URL.Path, "/upload/") {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	src, err := NewCloudFileRequest("bucket", "data.txt", "raw", 0)
+	require.NoError(t, err)
+	dst, err := NewCloudFileRequest("bucket", "data.txt", "copy", 0)
+	require.NoError(t, err)
+	// chunks smaller than the content are sent while the transform writes
+	dst.chunkSize = 256 * 1024
+
+	// the transform's writes fail once the upload ended, it doesn't block on the pipe
+	var writeErr error
+	_, err = cs.TransformObject(ctx, src, dst, func(w io.Writer, r io.Reader) error {
+		_, writeErr = io.Copy(w, r)
+		return writeErr
+	})
+	require.Error(t, err)
+	require.Error(t, writeErr)
+	require.NotErrorIs(t, err, writeErr)
+	var sErr StorageError
+	require.True(t, errors.As(err, &sErr))
+	require.Equal(t, []string{"raw/data.txt"}, f.storedNames("bucket"))
+}
@@ -0,0 +1,298 @@
+package cloudstorage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_INVALID_TREE_DEPTH string = "tree depth can't be negative"
+	ERROR_LISTING_TREE       string = "error listing prefix tree"
+)
+
+var (
+	ErrInvalidTreeDepth = errors.NewAppError(ERROR_INVALID_TREE_DEPTH)
+)
+
+const (
+	// DEFAULT_TREE_CONCURRENCY is the default number of concurrent prefix listings of ListTree
+	DEFAULT_TREE_CONCURRENCY = 8
+	// DEFAULT_TREE_MAX_NODES is the default number of prefixes ListTree lists before truncating the tree
+	DEFAULT_TREE_MAX_NODES = 10000
+)
+
+// TreeOptions configure ListTree
+type TreeOptions struct {
+	// Concurrency is the number of concurrent sibling prefix listings, defaults to DEFAULT_TREE_CONCURRENCY
+	Concurrency int
+	// MaxNodes caps the number of prefixes listed, the root included, defaults to DEFAULT_TREE_MAX_NODES
+	MaxNodes int
+	// Sizes aggregates the object counts & sizes of whole subtrees, listing the prefixes below the tree fully
+	Sizes bool
+}
+
+// TreeOption sets prefix tree options
+type TreeOption func(o *TreeOptions)
+
+// WithTreeConcurrency sets the number of concurrent sibling prefix listings
+func WithTreeConcurrency(n int) TreeOption {
+	return func(o *TreeOptions) {
+		o.Concurrency = n
+	}
+}
+
+// WithTreeMaxNodes sets the number of prefixes listed before the tree is truncated
+func WithTreeMaxNodes(n int) TreeOption {
+	return func(o *TreeOptions) {
+		o.MaxNodes = n
+	}
+}
+
+// WithTreeSizes aggregates subtree object counts & sizes, every object under the tree's prefixes is listed
+func WithTreeSizes() TreeOption {
+	return func(o *TreeOptions) {
+		o.Sizes = true
+	}
+}
+
+// PrefixNode is a listed prefix of a PrefixTree
+type PrefixNode struct {
+	// Prefix is empty or ends with a slash, relative to the scope prefix
+	Prefix string `json:"prefix"`
+	// Depth is the number of segments below the tree's root prefix
+	Depth int `json:"depth"`
+	// Objects & Bytes are the number & total size of the objects directly under the prefix
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+	// Prefixes is the number of sub prefixes directly under the prefix, listed or not
+	Prefixes int `json:"prefixes"`
+	// Children are the listed sub prefixes in prefix order, none at the tree's maximum depth,
+	// fewer than Prefixes when the tree is truncated
+	Children []*PrefixNode `json:"children,omitempty"`
+	// TotalObjects & TotalBytes are the number & total size of every object under the prefix, WithTreeSizes only
+	TotalObjects int64 `json:"total_objects,omitempty"`
+	TotalBytes   int64 `json:"total_bytes,omitempty"`
+}
+
+// Collapsed reports whether the prefix has sub prefixes that weren't listed, expanded on demand
+// with a ListTree of the node's prefix
+func (n *PrefixNode) Collapsed() bool {
+	return n.Prefixes > len(n.Children)
+}
+
+// PrefixTree is the prefix hierarchy listed by ListTree
+type PrefixTree struct {
+	Bucket   string      `json:"bucket"`
+	MaxDepth int         `json:"max_depth"`
+	Root     *PrefixNode `json:"root"`
+	// Nodes is the number of prefixes listed
+	Nodes int `json:"nodes"`
+	// Truncated is set when the node cap stopped prefixes within the maximum depth from being listed
+	Truncated bool `json:"truncated"`
+}
+
+// ListTree lists the prefix hierarchy under given prefix with delimiter listings, breadth first down to
+// maxDepth segments below the prefix, zero listing the prefix only. Each node counts the objects directly
+// under it & its sub prefixes, sub prefixes below maxDepth are counted but not listed. Sibling prefixes are
+// listed concurrently, a level at a time in prefix order, & listing stops once MaxNodes prefixes are listed,
+// the tree is returned Truncated. Directory markers aren't counted as objects. WithTreeSizes also totals
+// each node's subtree, listing everything under the collapsed prefixes, as costly as an object listing.
+func (cs *cloudStorageClient) ListTree(ctx context.Context, bucket, prefix string, maxDepth int, opts ...TreeOption) (*PrefixTree, error) {
+	if maxDepth < 0 {
+		return nil, ErrInvalidTreeDepth
+	}
+	tOpts := TreeOptions{Concurrency: DEFAULT_TREE_CONCURRENCY, MaxNodes: DEFAULT_TREE_MAX_NODES}
+	for _, opt := range opts {
+		opt(&tOpts)
+	}
+	if tOpts.Concurrency <= 0 {
+		tOpts.Concurrency = DEFAULT_TREE_CONCURRENCY
+	}
+	if tOpts.MaxNodes <= 0 {
+		tOpts.MaxNodes = DEFAULT_TREE_MAX_NODES
+	}
+	cfr, err := cs.scoped(ctx, CloudFileRequest{bucket: bucket, path: prefix})
+	if err != nil {
+		return nil, err
+	}
+	if cfr.bucket == "" {
+		return nil, ErrBucketNameMissing
+	}
+	op := cs.startOperation(ctx, "ListTree", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	root := dirPrefix(cfr.path)
+	op.object = root
+
+	tree := &PrefixTree{Bucket: cfr.bucket, MaxDepth: maxDepth, Root: &PrefixNode{Prefix: cfr.unscopedName(root)}, Nodes: 1}
+	level := []*PrefixNode{tree.Root}
+	for len(level) > 0 {
+		subs := make([][]string, len(level))
+		err := cs.eachTreeNode(ctx, level, tOpts.Concurrency, func(i int, node *PrefixNode) (err error) {
+			subs[i], err = cs.listTreeNode(ctx, cfr, node)
+			return err
+		})
+		if err != nil {
+			op.logger.Error(ERROR_LISTING_TREE, zap.Error(err), zap.String("filepath", root), zap.Int("nodes", tree.Nodes))
+			return nil, op.wrapError(err, "%s %s", ERROR_LISTING_TREE, root)
+		}
+		next := []*PrefixNode{}
+		for i, node := range level {
+			if node.Depth >= maxDepth {
+				continue
+			}
+			for _, sub := range subs[i] {
+				if tree.Nodes >= tOpts.MaxNodes {
+					tree.Truncated = true
+					break
+				}
+				child := &PrefixNode{Prefix: sub, Depth: node.Depth + 1}
+				node.Children = append(node.Children, child)
+				next = append(next, child)
+				tree.Nodes++
+			}
+		}
+		level = next
+	}
+
+	if tOpts.Sizes {
+		collapsed := []*PrefixNode{}
+		tree.walk(func(node *PrefixNode) {
+			if node.Collapsed() {
+				collapsed = append(collapsed, node)
+			}
+		})
+		err := cs.eachTreeNode(ctx, collapsed, tOpts.Concurrency, func(_ int, node *PrefixNode) error {
+			return cs.sizeTreeNode(ctx, cfr, node)
+		})
+		if err != nil {
+			op.logger.Error(ERROR_LISTING_TREE, zap.Error(err), zap.String("filepath", root), zap.Bool("sizes", true))
+			return nil, op.wrapError(err, "%s %s", ERROR_LISTING_TREE, root)
+		}
+		tree.Root.total()
+	}
+	op.logger.Debug("prefix tree listed", zap.String("prefix", root), zap.Int("nodes", tree.Nodes), zap.Bool("truncated", tree.Truncated))
+	return tree, nil
+}
+
+// eachTreeNode runs given call for each node, concurrently, returns the first error.
+// Nodes not started once the context is done or a call failed aren't called.
+func (cs *cloudStorageClient) eachTreeNode(ctx context.Context, nodes []*PrefixNode, concurrency int, call func(i int, node *PrefixNode) error) error {
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	failed := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr
+	}
+	for i, node := range nodes {
+		sem <- struct{}{}
+		if err := ctx.Err(); err != nil || failed() != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(i int, node *PrefixNode) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := call(i, node); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(i, node)
+	}
+	wg.Wait()
+	if err := failed(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// listTreeNode delimiter lists the node's prefix, counts its objects & returns its sub prefixes in prefix order
+func (cs *cloudStorageClient) listTreeNode(ctx context.Context, cfr CloudFileRequest, node *PrefixNode) ([]string, error) {
+	subs := []string{}
+	it := cs.bucketHandle(cfr).Objects(ctx, &storage.Query{Prefix: cfr.scopePrefix + node.Prefix, Delimiter: "/"})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case attrs.Prefix != "":
+			subs = append(subs, cfr.unscopedName(attrs.Prefix))
+		case !isDirMarker(attrs):
+			node.Objects++
+			node.Bytes += attrs.Size
+		}
+	}
+	node.Prefixes = len(subs)
+	sort.Strings(subs)
+	return subs, nil
+}
+
+// sizeTreeNode lists everything under the node's sub prefixes that weren't listed, adding their objects
+// to the node's totals
+func (cs *cloudStorageClient) sizeTreeNode(ctx context.Context, cfr CloudFileRequest, node *PrefixNode) error {
+	listed := make(map[string]bool, len(node.Children))
+	for _, child := range node.Children {
+		listed[child.Prefix] = true
+	}
+	it := cs.bucketHandle(cfr).Objects(ctx, &storage.Query{Prefix: cfr.scopePrefix + node.Prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := cfr.unscopedName(attrs.Name)
+		rest := strings.TrimPrefix(name, node.Prefix)
+		slash := strings.IndexByte(rest, '/')
+		// objects directly under the node are counted already, listed children count their own
+		if isDirMarker(attrs) || slash < 0 || listed[node.Prefix+rest[:slash+1]] {
+			continue
+		}
+		node.TotalObjects++
+		node.TotalBytes += attrs.Size
+	}
+}
+
+// total adds the node's objects & its children's totals to the node's totals, returned
+func (n *PrefixNode) total() (int64, int64) {
+	n.TotalObjects += n.Objects
+	n.TotalBytes += n.Bytes
+	for _, child := range n.Children {
+		objects, bytes := child.total()
+		n.TotalObjects += objects
+		n.TotalBytes += bytes
+	}
+	return n.TotalObjects, n.TotalBytes
+}
+
+// walk calls given func for each node of the tree, depth first in prefix order
+func (t *PrefixTree) walk(fn func(node *PrefixNode)) {
+	var visit func(node *PrefixNode)
+	visit = func(node *PrefixNode) {
+		fn(node)
+		for _, child := range node.Children {
+			visit(child)
+		}
+	}
+	visit(t.Root)
+}
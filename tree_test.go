@@ -0,0 +1,142 @@
+package cloudstorage
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/comfforts/cloudstorage/cloudstoragetest"
+	"github.com/stretchr/testify/require"
+)
+
+// treeFixture is a tree of uneven depth, with directory markers
+func treeFixture(t *testing.T, root string) (*fakeGCS, cloudstoragetest.Manifest) {
+	f := newFakeGCS()
+	m := cloudstoragetest.NewTree().
+		Dir(root).File("readme.txt", 3).
+		Dir(root+"/data").Marker().File("a.csv", 10).File("b.csv", 20).
+		Dir(root+"/data/2024/01").File("x.csv", 5).File("y.csv", 5).
+		Dir(root+"/data/2024/02").File("z.csv", 7).
+		Dir(root+"/data/2024/02/deep/er").File("w.csv", 1).
+		Dir(root+"/data/2025").File("q.csv", 4).
+		Dir(root+"/logs").File("app.log", 8).
+		Dir(root+"/tmp").Marker().
+		Build(t, f)
+	return f, m
+}
+
+// treePrefixes returns the prefixes of the tree's nodes, depth first
+func treePrefixes(tree *PrefixTree) []string {
+	prefixes := []string{}
+	tree.walk(func(node *PrefixNode) {
+		prefixes = append(prefixes, node.Prefix)
+	})
+	return prefixes
+}
+
+func TestListTree(t *testing.T) {
+	f, _ := treeFixture(t, "")
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	tree, err := cs.ListTree(ctx, "bucket", "", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"", "data/", "data/2024/", "data/2025/", "logs/", "tmp/"}, treePrefixes(tree))
+	require.Equal(t, 6, tree.Nodes)
+	require.False(t, tree.Truncated)
+
+	root := tree.Root
+	require.Equal(t, PrefixNode{Objects: 1, Bytes: 3, Prefixes: 3}, PrefixNode{Objects: root.Objects, Bytes: root.Bytes, Prefixes: root.Prefixes})
+	data := root.Children[0]
+	require.Equal(t, 1, data.Depth)
+	require.Equal(t, int64(2), data.Objects, "the directory marker isn't counted")
+	require.Equal(t, int64(30), data.Bytes)
+	y2024 := data.Children[0]
+	require.Equal(t, 2, y2024.Depth)
+	require.Zero(t, y2024.Objects)
+	require.Equal(t, 2, y2024.Prefixes)
+	require.True(t, y2024.Collapsed(), "sub prefixes below the maximum depth aren't listed")
+	require.Empty(t, y2024.Children)
+	require.False(t, data.Children[1].Collapsed())
+	tmp := root.Children[2]
+	require.Zero(t, tmp.Objects)
+	require.Zero(t, tmp.Prefixes)
+	require.Zero(t, root.TotalBytes, "totals aren't aggregated unless asked")
+
+	// concurrency doesn't change the tree
+	sequential, err := cs.ListTree(ctx, "bucket", "", 2, WithTreeConcurrency(1))
+	require.NoError(t, err)
+	require.Equal(t, tree, sequential)
+
+	// collapsed prefixes expand on demand
+	expanded, err := cs.ListTree(ctx, "bucket", "data/2024", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"data/2024/", "data/2024/01/", "data/2024/02/"}, treePrefixes(expanded))
+	require.Equal(t, int64(1), expanded.Root.Children[1].Objects)
+	require.True(t, expanded.Root.Children[1].Collapsed())
+
+	// depth zero lists the prefix only
+	tree, err = cs.ListTree(ctx, "bucket", "", 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, tree.Nodes)
+	require.Equal(t, 3, tree.Root.Prefixes)
+	require.True(t, tree.Root.Collapsed())
+
+	_, err = cs.ListTree(ctx, "bucket", "", -1)
+	require.Equal(t, ErrInvalidTreeDepth, err)
+}
+
+func TestListTreeSizes(t *testing.T) {
+	f, m := treeFixture(t, "")
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	tree, err := cs.ListTree(ctx, "bucket", "", 2, WithTreeSizes())
+	require.NoError(t, err)
+	root := tree.Root
+	require.Equal(t, int64(9), root.TotalObjects)
+	require.Equal(t, m.Bytes(""), root.TotalBytes)
+	data := root.Children[0]
+	require.Equal(t, m.Bytes("data/"), data.TotalBytes)
+	require.Equal(t, int64(7), data.TotalObjects)
+	y2024 := data.Children[0]
+	require.Equal(t, m.Bytes("data/2024/"), y2024.TotalBytes, "collapsed prefixes are listed fully")
+	require.Equal(t, int64(4), y2024.TotalObjects)
+
+	// truncated trees total their collapsed prefixes too
+	tree, err = cs.ListTree(ctx, "bucket", "", 5, WithTreeSizes(), WithTreeMaxNodes(3))
+	require.NoError(t, err)
+	require.True(t, tree.Truncated)
+	require.Equal(t, 3, tree.Nodes)
+	require.Equal(t, []string{"", "data/", "logs/"}, treePrefixes(tree))
+	require.True(t, tree.Root.Collapsed())
+	require.True(t, tree.Root.Children[0].Collapsed())
+	require.Equal(t, 2, tree.Root.Children[0].Prefixes)
+	require.Equal(t, m.Bytes(""), tree.Root.TotalBytes)
+	require.Equal(t, m.Bytes("data/"), tree.Root.Children[0].TotalBytes)
+}
+
+func TestListTreeScoped(t *testing.T) {
+	f, _ := treeFixture(t, "tenant-a")
+	cs := newFakeClient(t, f)
+	ctx := tenantContext()
+
+	tree, err := cs.ListTree(ctx, "bucket", "data", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"data/", "data/2024/", "data/2025/"}, treePrefixes(tree))
+	require.Equal(t, int64(2), tree.Root.Objects)
+
+	tree, err = cs.ListTree(ctx, "bucket", "", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"", "data/", "logs/", "tmp/"}, treePrefixes(tree))
+
+	f.fail = func(r *http.Request) int {
+		if r.URL.Query().Get("prefix") == "tenant-a/logs/" {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	tree, err = cs.ListTree(ctx, "bucket", "", 1)
+	require.ErrorIs(t, err, ErrPermissionDenied)
+	require.Nil(t, tree)
+}
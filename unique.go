@@ -0,0 +1,164 @@
+package cloudstorage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	stderrors "errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+// DEFAULT_UNIQUE_NAME_ATTEMPTS is the number of names UploadUnique tries before failing on collisions
+const DEFAULT_UNIQUE_NAME_ATTEMPTS = 3
+
+// crockfordAlphabet is the Crockford base32 alphabet, in ascending byte order so ids sort as their time
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// UniqueUploadResult is the result of UploadUnique
+type UniqueUploadResult struct {
+	UploadResult
+	// Name is the final object name, relative to the context scope
+	Name string
+	// Attempts is the number of names tried, more than one after collisions
+	Attempts int
+}
+
+// newSortableID returns a 26 character ULID style id, the millisecond time followed by 80 random bits,
+// Crockford base32 encoded, ids of different milliseconds sort lexically in time order
+func newSortableID(t time.Time) (string, error) {
+	var id [16]byte
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(id[:6], ms[2:])
+	if _, err := rand.Read(id[6:]); err != nil {
+		return "", err
+	}
+	// 128 bits as 26 5 bit characters, the 2 leading bits zero
+	out := make([]byte, 26)
+	for i := range out {
+		var v byte
+		for b := 0; b < 5; b++ {
+			v <<= 1
+			if bit := i*5 + b - 2; bit >= 0 && id[bit/8]&(0x80>>(bit%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = crockfordAlphabet[v]
+	}
+	return string(out), nil
+}
+
+// sanitizeObjectName returns given name without control characters & invalid UTF-8,
+// path separators replaced so the name stays one path segment
+func sanitizeObjectName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r == '/' || r == '\\':
+			b.WriteByte('_')
+		case r == utf8.RuneError || unicode.IsControl(r):
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// uniqueObjectName returns the unique object name of given time, see NewUniqueObjectName
+func uniqueObjectName(t time.Time, prefix, originalName string) (string, error) {
+	id, err := newSortableID(t)
+	if err != nil {
+		return "", err
+	}
+	if name := sanitizeObjectName(originalName); name != "" {
+		id += "-" + name
+	}
+	return path.Join(prefix, id), nil
+}
+
+// NewUniqueObjectName returns a collision resistant object name under given prefix,
+// a time ordered id followed by the sanitized original name, e.g. uploads/01GQ3Z6W8K2X5V7N9P4R6T8Y0B-report.csv.
+// Names created in different milliseconds sort lexically in creation order.
+// Path separators & control characters are stripped from the original name.
+func NewUniqueObjectName(prefix, originalName string) string {
+	name, err := uniqueObjectName(time.Now(), prefix, originalName)
+	if err != nil {
+		// like uuid.New, a failing system random source isn't recoverable
+		panic(err)
+	}
+	return name
+}
+
+// UploadUnique uploads given content under a new unique name in given bucket & prefix, see NewUniqueObjectName.
+// The upload is conditional on the name not existing, a collision retries under a new name,
+// non seekable readers are spooled so the content can be uploaded again.
+func (cs *cloudStorageClient) UploadUnique(ctx context.Context, r io.Reader, bucket, prefix, originalName string, opts ...CloudFileRequestOption) (UniqueUploadResult, error) {
+	if err := cs.mutation(); err != nil {
+		return UniqueUploadResult{}, err
+	}
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		// the limit applies to spooling, an unbounded stream never fills the spool
+		var declared CloudFileRequest
+		for _, opt := range opts {
+			opt(&declared)
+		}
+		limited, err := cs.limitUpload(r, declared.size)
+		if err != nil {
+			cs.logger.Error(ERROR_MAX_UPLOAD_EXCEEDED, zap.Error(err), zap.String("bucket", bucket), zap.String("prefix", prefix))
+			return UniqueUploadResult{}, err
+		}
+		sp, err := spool(limited, cs.spoolThreshold(), cs.config.SpoolDir, cs.buffers())
+		if stderrors.Is(err, ErrMaxUploadExceeded) {
+			cs.logger.Error(ERROR_MAX_UPLOAD_EXCEEDED, zap.Error(err), zap.String("bucket", bucket), zap.String("prefix", prefix))
+			return UniqueUploadResult{}, err
+		}
+		if err != nil {
+			cs.logger.Error(ERROR_SPOOLING_UPLOAD, zap.Error(err), zap.String("bucket", bucket), zap.String("prefix", prefix))
+			return UniqueUploadResult{}, errors.WrapError(err, "%s %s", ERROR_SPOOLING_UPLOAD, prefix)
+		}
+		defer sp.Close()
+		rs = sp
+	}
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return UniqueUploadResult{}, err
+	}
+
+	// the precondition is appended to a copy, the caller's options stay untouched
+	opts = append(append([]CloudFileRequestOption{}, opts...), WithIfGenerationMatch(0))
+	for attempt := 1; ; attempt++ {
+		name, err := uniqueObjectName(cs.now(), prefix, originalName)
+		if err != nil {
+			return UniqueUploadResult{}, err
+		}
+		dir := path.Dir(name)
+		if dir == "." {
+			dir = ""
+		}
+		// the bucket may come from the context scope, it's checked once scoped by Upload
+		cfr := CloudFileRequest{bucket: bucket, file: path.Base(name), path: dir}
+		for _, opt := range opts {
+			opt(&cfr)
+		}
+		res, err := cs.Upload(ctx, rs, cfr)
+		if err == nil {
+			return UniqueUploadResult{UploadResult: res, Name: name, Attempts: attempt}, nil
+		}
+		if !isPreconditionFailed(err) || attempt >= DEFAULT_UNIQUE_NAME_ATTEMPTS {
+			return UniqueUploadResult{UploadResult: res, Name: name, Attempts: attempt}, err
+		}
+		cs.logger.Info("unique object name exists, retrying with a new name", zap.String("bucket", bucket), zap.String("filepath", name), zap.Int("attempt", attempt))
+		if _, err := rs.Seek(start, io.SeekStart); err != nil {
+			return UniqueUploadResult{}, err
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package cloudstorage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUniqueObjectName(t *testing.T) {
+	name := NewUniqueObjectName("uploads/", "../q1\\report\x00\n.csv")
+	require.Regexp(t, regexp.MustCompile(`^uploads/[0-9A-HJKMNP-TV-Z]{26}-\.\._q1_report\.csv$`), name)
+
+	require.Regexp(t, regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`), NewUniqueObjectName("", " \t"))
+	require.NotEqual(t, NewUniqueObjectName("p", "f"), NewUniqueObjectName("p", "f"))
+
+	// names of later milliseconds sort after
+	at := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	prev := ""
+	for _, d := range []time.Duration{0, time.Millisecond, time.Second, 24 * time.Hour, 365 * 24 * time.Hour} {
+		name, err := uniqueObjectName(at.Add(d), "p", "z")
+		require.NoError(t, err)
+		require.Greater(t, name, prev)
+		prev = name
+	}
+}
+
+func TestUploadUnique(t *testing.T) {
+	f := newFakeGCS()
+	var uploads int32
+	collisions := int32(1)
+	f.fail = func(r *http.Request) int {
+		if !strings.HasPrefix(r.URL.Path, "/upload/") {
+			return 0
+		}
+		atomic.AddInt32(&uploads, 1)
+		if r.URL.Query().Get("ifGenerationMatch") != "0" {
+			return http.StatusBadRequest
+		}
+		if atomic.AddInt32(&collisions, -1) >= 0 {
+			return http.StatusPreconditionFailed
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	// the non seekable reader is uploaded again under a new name after a collision
+	res, err := cs.UploadUnique(ctx, io.MultiReader(strings.NewReader("unique content")), "bucket", "uploads", "report.csv", WithContentType("text/csv"))
+	require.NoError(t, err)
+	require.Equal(t, 2, res.Attempts)
+	require.Regexp(t, regexp.MustCompile(`^uploads/[0-9A-HJKMNP-TV-Z]{26}-report\.csv$`), res.Name)
+	require.Equal(t, res.Name, res.Attrs.Name)
+	data, attrs, ok := f.get("bucket", res.Name)
+	require.True(t, ok)
+	require.Equal(t, "unique content", string(data))
+	require.Equal(t, "text/csv", attrs.ContentType)
+	require.Equal(t, int32(2), atomic.LoadInt32(&uploads))
+
+	// collisions fail once the attempts are used up
+	atomic.StoreInt32(&uploads, 0)
+	atomic.StoreInt32(&collisions, DEFAULT_UNIQUE_NAME_ATTEMPTS)
+	_, err = cs.UploadUnique(ctx, strings.NewReader("unique content"), "bucket", "uploads", "report.csv")
+	require.True(t, isPreconditionFailed(err))
+	require.Equal(t, int32(DEFAULT_UNIQUE_NAME_ATTEMPTS), atomic.LoadInt32(&uploads))
+
+	// names are relative to the context scope
+	atomic.StoreInt32(&collisions, 0)
+	res, err = cs.UploadUnique(tenantContext(), strings.NewReader("scoped"), "", "uploads", "report.csv")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(res.Name, "uploads/"))
+	_, _, ok = f.get("bucket", "tenant-a/"+res.Name)
+	require.True(t, ok)
+}
@@ -0,0 +1,96 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// cancellingReader yields chunks of content, cancelling after given number of chunks
+// & then ending as a disconnected client's body would, with err or a clean EOF
+type cancellingReader struct {
+	chunk  []byte
+	after  int
+	cancel context.CancelFunc
+	err    error
+	reads  int
+}
+
+func (cr *cancellingReader) Read(p []byte) (int, error) {
+	if cr.reads == cr.after {
+		cr.cancel()
+		if cr.err != nil {
+			return 0, cr.err
+		}
+		return 0, io.EOF
+	}
+	cr.reads++
+	return copy(p, cr.chunk), nil
+}
+
+func TestUploadCancelledMidCopy(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts []CloudFileRequestOption
+		err  error
+	}{
+		"spooled, clean EOF":   {},
+		"spooled, read error":  {err: io.ErrUnexpectedEOF},
+		"streamed, clean EOF":  {opts: []CloudFileRequestOption{WithoutSpooling()}},
+		"streamed, read error": {opts: []CloudFileRequestOption{WithoutSpooling()}, err: io.ErrUnexpectedEOF},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := newFakeGCS()
+			cs := newFakeClient(t, f)
+			cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0, tc.opts...)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := &cancellingReader{chunk: bytes.Repeat([]byte("x"), 32*1024), after: 4, cancel: cancel, err: tc.err}
+			_, err = cs.Upload(ctx, r, cfr)
+			require.ErrorIs(t, err, context.Canceled)
+			require.Contains(t, err.Error(), ERROR_UPLOAD_CANCELLED)
+			_, _, ok := f.get("bucket", "path/file.bin")
+			require.False(t, ok, "truncated content isn't committed")
+		})
+	}
+}
+
+func TestUploadCancelledCommitRemoved(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "path/keep.bin", []byte("keep"), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the upload commits, the caller cancels before the response arrives
+	cs := newFakeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/upload/") {
+			f.ServeHTTP(w, r)
+			return
+		}
+		f.ServeHTTP(httptest.NewRecorder(), r)
+		cancel()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	_, err = cs.Upload(ctx, bytes.NewReader([]byte("content")), cfr)
+	require.True(t, errors.Is(err, context.Canceled), err)
+	require.Contains(t, err.Error(), "removed")
+	_, _, ok := f.get("bucket", "path/file.bin")
+	require.False(t, ok, "the committed object is removed")
+	_, _, ok = f.get("bucket", "path/keep.bin")
+	require.True(t, ok)
+}
@@ -0,0 +1,325 @@
+package cloudstorage
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_OPENING_UPLOAD_FILE string = "error opening upload file"
+	ERROR_UPLOADING_PART      string = "error uploading cloud file part"
+	ERROR_COMPOSING_PARTS     string = "error composing cloud file parts"
+)
+
+const (
+	// DEFAULT_UPLOAD_CHUNK_SIZE is the default resumable upload chunk size, the storage client's default
+	DEFAULT_UPLOAD_CHUNK_SIZE = 16 * 1024 * 1024 // 16MB
+	// DEFAULT_UPLOAD_PARALLELISM is the default number of concurrent part uploads
+	DEFAULT_UPLOAD_PARALLELISM = 4
+	// MAX_COMPOSE_PARTS is the service's limit of source objects per compose
+	MAX_COMPOSE_PARTS = 32
+)
+
+// UploadOptions configure UploadFromReaderAt & UploadFromFile
+type UploadOptions struct {
+	// ChunkSize is the resumable upload chunk size, a failed chunk is retried on its own,
+	// also the part size of parallel uploads, defaults to DEFAULT_UPLOAD_CHUNK_SIZE
+	ChunkSize int
+	// ParallelThreshold is the size from which content is uploaded as parallel parts
+	// composed into the object, zero uploads everything like Upload
+	ParallelThreshold int64
+	// Parallelism is the number of concurrent part uploads, defaults to DEFAULT_UPLOAD_PARALLELISM
+	Parallelism int
+}
+
+// UploadOption sets reader at upload options
+type UploadOption func(o *UploadOptions)
+
+// WithChunkSize sets the upload chunk size, rounded up to a multiple of 256KB by the storage client
+func WithChunkSize(size int) UploadOption {
+	return func(o *UploadOptions) {
+		o.ChunkSize = size
+	}
+}
+
+// WithParallelUpload uploads content of at least threshold bytes as chunk size parts,
+// with given number of concurrent part uploads, DEFAULT_UPLOAD_PARALLELISM when not positive
+func WithParallelUpload(threshold int64, parallelism int) UploadOption {
+	return func(o *UploadOptions) {
+		if parallelism <= 0 {
+			parallelism = DEFAULT_UPLOAD_PARALLELISM
+		}
+		o.ParallelThreshold = threshold
+		o.Parallelism = parallelism
+	}
+}
+
+// UploadFromFile opens local file at given path & uploads it with UploadFromReaderAt
+func (cs *cloudStorageClient) UploadFromFile(ctx context.Context, filePath string, cfr CloudFileRequest, opts ...UploadOption) (UploadResult, error) {
+	if err := cs.mutation(); err != nil {
+		return UploadResult{}, err
+	}
+	if cfr.IsZero() {
+		return UploadResult{}, ErrEmptyRequest
+	}
+	if filePath == "" {
+		return UploadResult{}, ErrFilePathMissing
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		cs.logger.Error(ERROR_OPENING_UPLOAD_FILE, zap.Error(err), zap.String("path", filePath))
+		return UploadResult{}, errors.WrapError(err, "%s %s", ERROR_OPENING_UPLOAD_FILE, filePath)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		cs.logger.Error(ERROR_OPENING_UPLOAD_FILE, zap.Error(err), zap.String("path", filePath))
+		return UploadResult{}, errors.WrapError(err, "%s %s", ERROR_OPENING_UPLOAD_FILE, filePath)
+	}
+	return cs.UploadFromReaderAt(ctx, f, fi.Size(), cfr, opts...)
+}
+
+// UploadFromReaderAt uploads size bytes of given reader at, nothing is spooled since any range can be re-read.
+// Content under the parallel threshold is uploaded exactly like Upload, with the configured chunk size.
+// Larger content is uploaded as concurrent chunk size parts, each verified by its CRC32C,
+// composed into the object & removed, the upload fails as a whole when any part fails.
+func (cs *cloudStorageClient) UploadFromReaderAt(ctx context.Context, r io.ReaderAt, size int64, cfr CloudFileRequest, opts ...UploadOption) (UploadResult, error) {
+	if err := cs.mutation(); err != nil {
+		return UploadResult{}, err
+	}
+	cfr, err := cs.request(ctx, cfr)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	if cfr.bucket == "" {
+		return UploadResult{}, ErrBucketNameMissing
+	}
+	if cfr.file == "" {
+		return UploadResult{}, ErrFileNameMissing
+	}
+	cfr, err = cs.applyProfile(cfr)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	uOpts := UploadOptions{ChunkSize: DEFAULT_UPLOAD_CHUNK_SIZE, Parallelism: DEFAULT_UPLOAD_PARALLELISM}
+	for _, opt := range opts {
+		opt(&uOpts)
+	}
+	if uOpts.ChunkSize <= 0 {
+		uOpts.ChunkSize = DEFAULT_UPLOAD_CHUNK_SIZE
+	}
+	cfr.size, cfr.chunkSize = size, uOpts.ChunkSize
+	if err := cs.checkUploadSize(size); err != nil {
+		cs.logger.Error(ERROR_MAX_UPLOAD_EXCEEDED, zap.Error(err), zap.String("bucket", cfr.bucket), zap.Int64("size", size))
+		return UploadResult{}, err
+	}
+
+	if uOpts.ParallelThreshold <= 0 || size < uOpts.ParallelThreshold {
+		return cs.Upload(ctx, io.NewSectionReader(r, 0, size), cfr)
+	}
+	return cs.uploadParts(ctx, r, size, cfr, uOpts)
+}
+
+// uploadParts uploads content as parallel parts composed into the request's object,
+// failed uploads return the bytes of the parts uploaded, nothing is committed
+func (cs *cloudStorageClient) uploadParts(ctx context.Context, r io.ReaderAt, size int64, cfr CloudFileRequest, uOpts UploadOptions) (UploadResult, error) {
+	op := cs.startOperation(ctx, "UploadFile", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	fPath := op.object
+	defer cs.invalidate(cfr.bucket, fPath)
+
+	policy, err := cs.uploadPolicy(cfr)
+	if err != nil {
+		op.logger.Error(ERROR_POLICY_VIOLATION, zap.Error(err), zap.String("filepath", fPath))
+		return UploadResult{}, op.wrapError(err, "%s %s", ERROR_POLICY_VIOLATION, fPath)
+	}
+	contentType := cfr.contentType
+	if policy != nil {
+		// the size is declared, only the sniffed content type is needed from the policy reader
+		_, contentType, err = applyUploadPolicy(policy, cfr, fPath, io.NewSectionReader(r, 0, size))
+		if err != nil {
+			op.logger.Error(ERROR_POLICY_VIOLATION, zap.Error(err), zap.String("filepath", fPath))
+			return UploadResult{}, op.wrapError(err, "%s %s", ERROR_POLICY_VIOLATION, fPath)
+		}
+	}
+
+	partSize := int64(uOpts.ChunkSize)
+	if parts := (size + partSize - 1) / partSize; parts > MAX_COMPOSE_PARTS {
+		partSize = (size + MAX_COMPOSE_PARTS - 1) / MAX_COMPOSE_PARTS
+	}
+	parts := make([]*storage.ObjectHandle, (size+partSize-1)/partSize)
+	crcs := make([]uint32, len(parts))
+	// parts upload concurrently, then compose, as one more round
+	if err := cs.checkDeadlineBudget(ctx, op, len(parts)+uOpts.Parallelism, uOpts.Parallelism); err != nil {
+		return UploadResult{}, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	bucket := cs.bucketHandle(cfr)
+
+	// parts are removed whether or not the compose succeeded, parts left by failed removals
+	// are named for CleanupOrphans
+	defer func() {
+		for _, part := range parts {
+			if part == nil {
+				continue
+			}
+			if err := removeTemp(part); err != nil {
+				op.logger.Error("error removing cloud file part", zap.Error(err), zap.String("filepath", fPath), zap.String("part", part.ObjectName()))
+			}
+		}
+	}()
+
+	op.startTransfer(TransferUpload)
+	// the first part error is the cause, later parts fail on the cancelled context,
+	// failed uploads return the bytes of the parts uploaded
+	var firstErr error
+	var uploaded int64
+	var mu sync.Mutex
+	sem := make(chan struct{}, uOpts.Parallelism)
+	var wg sync.WaitGroup
+	for i := range parts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// parts given up waiting are failed by the context error after the wait group
+			release, err := op.acquireTransfer(ctx)
+			if err != nil {
+				return
+			}
+			defer release()
+			if ctx.Err() != nil {
+				return
+			}
+			off := int64(i) * partSize
+			n := partSize
+			if off+n > size {
+				n = size - off
+			}
+			name := cfr.scopePrefix + cfr.encodedName(tempObjectName(DEFAULT_PARTS_PREFIX, fmt.Sprintf("%s-%04d", op.requestID, i), cfr.unscopedName(fPath)))
+			part, crc, err := cs.uploadPart(ctx, bucket.Object(name), io.NewSectionReader(r, off, n), uOpts.ChunkSize, cfr.kmsKeyName)
+			parts[i], crcs[i] = part, crc
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					op.logger.Error(ERROR_UPLOADING_PART, zap.Error(err), zap.String("filepath", fPath), zap.String("part", name))
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			uploaded += n
+		}(i)
+	}
+	wg.Wait()
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if err := firstErr; err != nil {
+		err = op.wrapError(err, "%s %s", ERROR_UPLOADING_PART, fPath)
+		op.endTransfer(uploaded, err)
+		return UploadResult{Bytes: uploaded, RequestID: op.requestID}, err
+	}
+
+	dst := bucket.Object(fPath)
+	if cfr.hasGenerationMatch {
+		dst = dst.If(generationConditions(cfr.generationMatch))
+	}
+	composer := dst.ComposerFrom(parts...)
+	composer.ContentType = contentType
+	composer.ContentEncoding = cfr.contentEncoding
+	composer.ContentLanguage = cfr.contentLanguage
+	composer.ContentDisposition = cfr.contentDisposition
+	composer.CacheControl = cfr.cacheControl
+	composer.Metadata = cfr.metadata
+	composer.KMSKeyName = cfr.kmsKeyName
+	attrs, err := composer.Run(ctx)
+	if err != nil {
+		op.logger.Error(ERROR_COMPOSING_PARTS, zap.Error(err), zap.String("filepath", fPath), zap.Int("parts", len(parts)))
+		err = op.wrapError(err, "%s %s", ERROR_COMPOSING_PARTS, fPath)
+		op.endTransfer(size, err)
+		return UploadResult{Bytes: size, RequestID: op.requestID}, err
+	}
+	// the storage library drops the composer's key on JSON API composes,
+	// a composed object stored under another key is rewritten under the request's
+	if cfr.kmsKeyName != "" && !strings.HasPrefix(attrs.KMSKeyName, cfr.kmsKeyName) {
+		composed := bucket.Object(fPath)
+		copier := composed.If(storage.Conditions{GenerationMatch: attrs.Generation}).CopierFrom(composed.Generation(attrs.Generation))
+		copier.DestinationKMSKeyName = cfr.kmsKeyName
+		if attrs, err = copier.Run(ctx); err != nil {
+			op.logger.Error(ERROR_COMPOSING_PARTS, zap.Error(err), zap.String("filepath", fPath), zap.String("kmsKey", cfr.kmsKeyName))
+			err = op.wrapError(err, "%s %s", ERROR_COMPOSING_PARTS, fPath)
+			op.endTransfer(size, err)
+			return UploadResult{Bytes: size, RequestID: op.requestID}, err
+		}
+	}
+	// the composed object has no MD5, its CRC32C is the parts' combined
+	var want uint32
+	for i, crc := range crcs {
+		n := partSize
+		if i == len(crcs)-1 {
+			n = size - int64(i)*partSize
+		}
+		want = CombineCRC32C(want, crc, n)
+	}
+	op.storageClass = attrs.StorageClass
+	if attrs.CRC32C != want {
+		op.logger.Error(ERROR_COMPOSE_CHECKSUM, zap.String("filepath", fPath), zap.Uint32("want", want), zap.Uint32("got", attrs.CRC32C))
+		err := op.wrapError(errors.NewAppError(ERROR_COMPOSE_CHECKSUM), "%s %s", ERROR_COMPOSE_CHECKSUM, fPath)
+		op.endTransfer(size, err)
+		return UploadResult{Bytes: size, RequestID: op.requestID}, err
+	}
+	op.bytes += size
+	op.generation = attrs.Generation
+	m := op.endTransfer(size, nil)
+	op.logger.Debug("cloud file created/updated from parts", zap.String("filepath", fPath), zap.Int("parts", len(parts)), zap.Duration("duration", m.Duration))
+	return UploadResult{
+		Bytes:          size,
+		RequestID:      op.requestID,
+		Spool:          SpoolNone,
+		Attrs:          cfr.logicalAttrs(newObjectAttrs(attrs)),
+		Duration:       m.Duration,
+		BytesPerSecond: m.BytesPerSecond,
+		Token:          visibilityToken(attrs),
+	}, nil
+}
+
+// uploadPart uploads given section to a new part object, its CRC32C computed from the section,
+// encrypted with given KMS key when set, returns the part's handle at the uploaded generation & its CRC32C
+func (cs *cloudStorageClient) uploadPart(ctx context.Context, obj *storage.ObjectHandle, section *io.SectionReader, chunkSize int, kmsKeyName string) (*storage.ObjectHandle, uint32, error) {
+	hasher := crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	if _, err := cs.buffers().copy(hasher, section); err != nil {
+		return nil, 0, err
+	}
+	if _, err := section.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	wc := obj.If(storage.Conditions{DoesNotExist: true}).NewWriter(ctx)
+	wc.ChunkSize = chunkSize
+	wc.CRC32C = hasher.Sum32()
+	wc.SendCRC32C = true
+	wc.KMSKeyName = kmsKeyName
+	// on error the caller cancels the context, which aborts the upload uncommitted
+	if _, err := cs.buffers().copy(wc, section); err != nil {
+		return nil, 0, err
+	}
+	if err := wc.Close(); err != nil {
+		return nil, 0, err
+	}
+	return obj.Generation(wc.Attrs().Generation), wc.CRC32C, nil
+}
@@ -0,0 +1,132 @@
+package cloudstorage
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countComposes counts the fake's compose requests
+func countComposes(f *fakeGCS) *int64 {
+	var composes int64
+	f.fail = func(r *http.Request) int {
+		if strings.HasSuffix(r.URL.Path, "/compose") {
+			atomic.AddInt64(&composes, 1)
+		}
+		return 0
+	}
+	return &composes
+}
+
+func TestUploadFromFileSmall(t *testing.T) {
+	content := readerAtContent(64 * 1024)
+	local := filepath.Join(t.TempDir(), "file.bin")
+	require.NoError(t, os.WriteFile(local, content, 0o600))
+
+	f := newFakeGCS()
+	composes := countComposes(f)
+	cs := newFakeClient(t, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0, WithContentType("application/x-test"))
+	require.NoError(t, err)
+
+	res, err := cs.UploadFromFile(context.Background(), local, cfr, WithParallelUpload(1024*1024, 2))
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), res.Bytes)
+	require.Equal(t, SpoolNone, res.Spool)
+	require.Equal(t, int64(0), atomic.LoadInt64(composes))
+
+	data, attrs, ok := f.get("bucket", "path/file.bin")
+	require.True(t, ok)
+	require.Equal(t, content, data)
+	require.Equal(t, "application/x-test", attrs.ContentType)
+	require.Equal(t, int64(0), attrs.ComponentCount)
+}
+
+func TestUploadFromReaderAtParallel(t *testing.T) {
+	content := readerAtContent(1024*1024 + 100)
+	f := newFakeGCS()
+	composes := countComposes(f)
+	cs := newFakeClient(t, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0, WithMetadata(map[string]string{"k": "v"}))
+	require.NoError(t, err)
+
+	res, err := cs.UploadFromReaderAt(
+		context.Background(),
+		strings.NewReader(string(content)),
+		int64(len(content)),
+		cfr,
+		WithChunkSize(256*1024),
+		WithParallelUpload(512*1024, 3),
+	)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(content)), res.Bytes)
+	require.Equal(t, int64(1), atomic.LoadInt64(composes))
+
+	data, attrs, ok := f.get("bucket", "path/file.bin")
+	require.True(t, ok)
+	require.Equal(t, content, data)
+	require.Equal(t, int64(5), attrs.ComponentCount)
+	require.Equal(t, "v", attrs.Metadata["k"])
+	require.Equal(t, res.Attrs.Generation, attrs.Generation)
+
+	// parts are removed after compose
+	names, err := cs.ListObjects(context.Background(), CloudFileRequest{bucket: "bucket", path: "path"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"path/file.bin"}, names)
+}
+
+func TestUploadFromReaderAtPartFailure(t *testing.T) {
+	content := readerAtContent(1024 * 1024)
+	f := newFakeGCS()
+	f.put("bucket", "path/file.bin", []byte("old"), nil)
+	f.fail = func(r *http.Request) int {
+		if strings.Contains(r.URL.Query().Get("name"), "-0002/") {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	cs := newFakeClient(t, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	res, err := cs.UploadFromReaderAt(context.Background(), strings.NewReader(string(content)), int64(len(content)), cfr,
+		WithChunkSize(256*1024), WithParallelUpload(256*1024, 1))
+	require.Error(t, err)
+	// the bytes of the parts uploaded before the failure
+	require.Less(t, res.Bytes, int64(len(content)))
+	require.Zero(t, res.Bytes%(256*1024))
+
+	// the object is untouched & uploaded parts are removed
+	data, _, ok := f.get("bucket", "path/file.bin")
+	require.True(t, ok)
+	require.Equal(t, []byte("old"), data)
+	names, err := cs.ListObjects(context.Background(), CloudFileRequest{bucket: "bucket", path: "path"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"path/file.bin"}, names)
+}
+
+func TestUploadFromReaderAtKMSKey(t *testing.T) {
+	content := readerAtContent(1024*1024 + 100)
+	f := newFakeGCS()
+	keys := recordKMSKeys(f)
+	cs := newFakeClient(t, f)
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0, WithKMSKey(testKMSKey))
+	require.NoError(t, err)
+
+	_, err = cs.UploadFromReaderAt(context.Background(), strings.NewReader(string(content)), int64(len(content)), cfr,
+		WithChunkSize(256*1024), WithParallelUpload(512*1024, 3))
+	require.NoError(t, err)
+	// parts are encrypted with the request's key
+	require.Equal(t, []string{testKMSKey, testKMSKey, testKMSKey, testKMSKey, testKMSKey}, keys["upload"])
+	// the composed object is rewritten under the key
+	require.Equal(t, []string{testKMSKey}, keys["rewrite"])
+	data, _, ok := f.get("bucket", "path/file.bin")
+	require.True(t, ok)
+	require.Equal(t, content, data)
+}
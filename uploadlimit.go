@@ -0,0 +1,72 @@
+package cloudstorage
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/comfforts/errors"
+)
+
+const ERROR_MAX_UPLOAD_EXCEEDED string = "upload exceeds the client's max upload bytes"
+
+var (
+	ErrMaxUploadExceeded = errors.NewAppError(ERROR_MAX_UPLOAD_EXCEEDED)
+)
+
+// MaxUploadExceededError is returned when an upload crosses the client's MaxUploadBytes,
+// nothing is committed, matches ErrMaxUploadExceeded with errors.Is
+type MaxUploadExceededError struct {
+	Limit int64
+	// Consumed is the number of bytes read from the caller's reader, zero when the declared size
+	// was over the limit
+	Consumed int64
+}
+
+func (e MaxUploadExceededError) Error() string {
+	return fmt.Sprintf("%s %d: consumed %d bytes", ERROR_MAX_UPLOAD_EXCEEDED, e.Limit, e.Consumed)
+}
+
+// Is matches ErrMaxUploadExceeded
+func (e MaxUploadExceededError) Is(target error) bool {
+	return target == ErrMaxUploadExceeded
+}
+
+// maxUploadReader fails reads once more than the client's max upload bytes were read,
+// reading at most one byte past the limit
+type maxUploadReader struct {
+	r   io.Reader
+	max int64
+	n   int64
+}
+
+func (mr *maxUploadReader) Read(p []byte) (int, error) {
+	if limit := mr.max - mr.n + 1; int64(len(p)) > limit {
+		p = p[:limit]
+	}
+	n, err := mr.r.Read(p)
+	mr.n += int64(n)
+	if mr.n > mr.max {
+		return n - int(mr.n-mr.max), MaxUploadExceededError{Limit: mr.max, Consumed: mr.n}
+	}
+	return n, err
+}
+
+// checkUploadSize fails uploads of a declared size over the client's max upload bytes
+func (cs *cloudStorageClient) checkUploadSize(size int64) error {
+	if limit := cs.config.MaxUploadBytes; limit > 0 && size > limit {
+		return MaxUploadExceededError{Limit: limit}
+	}
+	return nil
+}
+
+// limitUpload returns given reader limited to the client's max upload bytes, fails uploads
+// of a declared size over the limit before anything is read
+func (cs *cloudStorageClient) limitUpload(r io.Reader, size int64) (io.Reader, error) {
+	if err := cs.checkUploadSize(size); err != nil {
+		return nil, err
+	}
+	if cs.config.MaxUploadBytes <= 0 {
+		return r, nil
+	}
+	return &maxUploadReader{r: r, max: cs.config.MaxUploadBytes}, nil
+}
@@ -0,0 +1,147 @@
+package cloudstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// endlessReader is an unbounded stream
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+// consumed returns the bytes consumed reported by err's MaxUploadExceededError
+func consumed(t *testing.T, err error) int64 {
+	var exceeded MaxUploadExceededError
+	require.True(t, errors.As(err, &exceeded), err)
+	return exceeded.Consumed
+}
+
+func TestMaxUploadBytes(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	limit := int64(2 * DEFAULT_COPY_BUFFER_SIZE)
+	cs.config.MaxUploadBytes = limit
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	// content ending exactly at a buffer boundary on the limit is uploaded
+	res, err := cs.Upload(ctx, bytes.NewReader(bytes.Repeat([]byte("a"), int(limit))), cfr)
+	require.NoError(t, err)
+	require.Equal(t, limit, res.Bytes)
+
+	// one byte more fails & keeps the committed content
+	_, err = cs.Upload(ctx, bytes.NewReader(bytes.Repeat([]byte("b"), int(limit)+1)), cfr)
+	require.ErrorIs(t, err, ErrMaxUploadExceeded)
+	require.Equal(t, limit+1, consumed(t, err))
+	data, _, ok := f.get("bucket", "path/file.bin")
+	require.True(t, ok)
+	require.Equal(t, byte('a'), data[0], "the partial upload isn't committed")
+
+	// unbounded streams stop at the limit, spooled or not
+	for _, opts := range [][]CloudFileRequestOption{nil, {WithoutSpooling()}} {
+		cfr, err := NewCloudFileRequest("bucket", "other.bin", "path", 0, opts...)
+		require.NoError(t, err)
+		_, err = cs.Upload(ctx, endlessReader{}, cfr)
+		require.ErrorIs(t, err, ErrMaxUploadExceeded)
+		require.Equal(t, limit+1, consumed(t, err))
+		_, _, ok = f.get("bucket", "path/other.bin")
+		require.False(t, ok)
+	}
+
+	// declared sizes over the limit fail before anything is read
+	sized, err := NewCloudFileRequest("bucket", "sized.bin", "path", 0, WithSize(limit+1))
+	require.NoError(t, err)
+	_, err = cs.Upload(ctx, endlessReader{}, sized)
+	require.ErrorIs(t, err, ErrMaxUploadExceeded)
+	require.Zero(t, consumed(t, err))
+}
+
+func TestMaxUploadBytesPaths(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	cs.config.MaxUploadBytes = 1024
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	// parallel uploads check the size up front, nothing is uploaded
+	calls := recordRequests(f, http.MethodPost, nil)
+	data := bytes.Repeat([]byte("p"), 2048)
+	_, err = cs.UploadFromReaderAt(ctx, bytes.NewReader(data), int64(len(data)), cfr, WithChunkSize(256*1024), WithParallelUpload(1, 2))
+	require.ErrorIs(t, err, ErrMaxUploadExceeded)
+	require.Zero(t, consumed(t, err))
+	require.Empty(t, *calls)
+
+	// staged uploads stop before staging
+	_, err = cs.StagedUpload(ctx, io.LimitReader(endlessReader{}, 4096), cfr, func(context.Context, CloudFileRequest) error { return nil })
+	require.ErrorIs(t, err, ErrMaxUploadExceeded)
+	require.Empty(t, f.storedNames("bucket"))
+
+	// streamed writes fail like uploads
+	_, err = cs.WriteJSON(ctx, cfr, strings.Repeat("j", 2048))
+	require.ErrorIs(t, err, ErrMaxUploadExceeded)
+}
+
+func TestMaxUploadBytesSpooled(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	limit := int64(1024)
+	cs.config.MaxUploadBytes = limit
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "file.bin", "path", 0)
+	require.NoError(t, err)
+	replica, err := NewCloudFileRequest("replica", "file.bin", "path", 0)
+	require.NoError(t, err)
+
+	// unbounded streams stop at the limit while spooled, nothing is uploaded
+	_, err = cs.UploadFanOut(ctx, endlessReader{}, cfr, []CloudFileRequest{replica})
+	require.ErrorIs(t, err, ErrMaxUploadExceeded)
+	require.Equal(t, limit+1, consumed(t, err))
+	_, err = cs.UploadUnique(ctx, endlessReader{}, "bucket", "unique", "file.bin")
+	require.ErrorIs(t, err, ErrMaxUploadExceeded)
+	require.Equal(t, limit+1, consumed(t, err))
+	_, _, err = cs.PutCAS(ctx, "bucket", "cas", endlessReader{})
+	require.ErrorIs(t, err, ErrMaxUploadExceeded)
+	require.Equal(t, limit+1, consumed(t, err))
+	require.Empty(t, f.storedNames("bucket"))
+	require.Empty(t, f.storedNames("replica"))
+
+	// declared sizes over the limit fail before anything is read
+	sized, err := NewCloudFileRequest("bucket", "sized.bin", "path", 0, WithSize(limit+1))
+	require.NoError(t, err)
+	_, err = cs.UploadFanOut(ctx, endlessReader{}, sized, []CloudFileRequest{replica})
+	require.ErrorIs(t, err, ErrMaxUploadExceeded)
+	require.Zero(t, consumed(t, err))
+	_, err = cs.UploadUnique(ctx, endlessReader{}, "bucket", "unique", "file.bin", WithSize(limit+1))
+	require.ErrorIs(t, err, ErrMaxUploadExceeded)
+	require.Zero(t, consumed(t, err))
+
+	// so do seekable fan out sources
+	_, err = cs.UploadFanOut(ctx, bytes.NewReader(bytes.Repeat([]byte("s"), int(limit)+1)), cfr, []CloudFileRequest{replica})
+	require.ErrorIs(t, err, ErrMaxUploadExceeded)
+	require.Zero(t, consumed(t, err))
+	require.Empty(t, f.storedNames("bucket"))
+
+	// content within the limit is spooled & uploaded
+	_, err = cs.UploadFanOut(ctx, io.LimitReader(endlessReader{}, limit), cfr, []CloudFileRequest{replica})
+	require.NoError(t, err)
+	_, err = cs.UploadUnique(ctx, io.LimitReader(endlessReader{}, limit), "bucket", "unique", "file.bin")
+	require.NoError(t, err)
+	_, created, err := cs.PutCAS(ctx, "bucket", "cas", io.LimitReader(endlessReader{}, limit))
+	require.NoError(t, err)
+	require.True(t, created)
+}
@@ -0,0 +1,240 @@
+package cloudstorage
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_INVALID_USAGE_DEPTH   string = "usage depth can't be negative"
+	ERROR_WRITING_USAGE         string = "error writing usage snapshot"
+	ERROR_READING_USAGE_HISTORY string = "error reading usage history"
+)
+
+var (
+	ErrInvalidUsageDepth = errors.NewAppError(ERROR_INVALID_USAGE_DEPTH)
+)
+
+const (
+	// DEFAULT_USAGE_PREFIX is the path prefix usage snapshots are written under when the request has no path
+	DEFAULT_USAGE_PREFIX = ".usage"
+	// usageTimeLayout names snapshot objects by their sample time, so names sort chronologically
+	usageTimeLayout = "20060102T150405Z"
+)
+
+// PrefixUsage is the number & total size of objects under a prefix
+type PrefixUsage struct {
+	// Prefix is empty or ends with a slash, relative to the scope prefix
+	Prefix  string `json:"prefix"`
+	Objects int64  `json:"objects"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// UsageSnapshot is the storage usage of a bucket at a point in time, grouped by prefix
+type UsageSnapshot struct {
+	Bucket string    `json:"bucket"`
+	Depth  int       `json:"depth"`
+	Taken  time.Time `json:"taken"`
+	// Objects & Bytes are the totals of every prefix
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+	// Prefixes are the usages of the prefixes up to depth segments deep, in prefix order.
+	// Objects not nested as deep are counted in their deepest prefix, root objects in the empty prefix.
+	Prefixes []PrefixUsage `json:"prefixes"`
+}
+
+// UsageRecord is a line of a written usage snapshot, one per prefix, so the series loads into
+// tables as is. Snapshots without any prefix are written as a single record with zero objects
+// & the empty prefix.
+type UsageRecord struct {
+	Taken  time.Time `json:"taken"`
+	Bucket string    `json:"bucket"`
+	Depth  int       `json:"depth"`
+	PrefixUsage
+}
+
+// usagePrefix returns the prefix given object name is counted in, at most depth segments deep
+func usagePrefix(name string, depth int) string {
+	end := 0
+	for i := 0; i < depth; i++ {
+		next := strings.IndexByte(name[end:], '/')
+		if next < 0 {
+			break
+		}
+		end += next + 1
+	}
+	return name[:end]
+}
+
+// usageObjectName returns the object name of a snapshot taken at given time
+func usageObjectName(taken time.Time) string {
+	return taken.UTC().Format(usageTimeLayout) + ".ndjson"
+}
+
+// SampleUsage lists given bucket's live objects & sums their sizes grouped by prefix, up to depth
+// segments deep, zero for the bucket's totals only. Directory markers aren't counted, snapshots written
+// to the sampled bucket are. Objects are streamed, memory grows with the number of prefixes, not objects.
+func (cs *cloudStorageClient) SampleUsage(ctx context.Context, bucket string, depth int) (UsageSnapshot, error) {
+	if depth < 0 {
+		return UsageSnapshot{}, ErrInvalidUsageDepth
+	}
+	cfr, err := cs.scopedBucket(ctx, bucket)
+	if err != nil {
+		return UsageSnapshot{}, err
+	}
+	op := cs.startOperation(ctx, "SampleUsage", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	prefix := dirPrefix(cfr.path)
+	op.object = prefix
+
+	snapshot := UsageSnapshot{Bucket: cfr.bucket, Depth: depth, Taken: cs.now(), Prefixes: []PrefixUsage{}}
+	groups := map[string]*PrefixUsage{}
+	it := cs.bucketHandle(cfr).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err != nil {
+			if err == iterator.Done {
+				break
+			}
+			op.logger.Error(ERROR_LISTING_OBJECTS, zap.Error(err), zap.Int64("listed", snapshot.Objects))
+			return UsageSnapshot{}, op.wrapError(err, ERROR_LISTING_OBJECTS)
+		}
+		if isDirMarker(attrs) {
+			continue
+		}
+		p := usagePrefix(cfr.unscopedName(attrs.Name), depth)
+		g, ok := groups[p]
+		if !ok {
+			g = &PrefixUsage{Prefix: p}
+			groups[p] = g
+		}
+		g.Objects++
+		g.Bytes += attrs.Size
+		snapshot.Objects++
+		snapshot.Bytes += attrs.Size
+	}
+	for _, g := range groups {
+		snapshot.Prefixes = append(snapshot.Prefixes, *g)
+	}
+	sort.Slice(snapshot.Prefixes, func(i, j int) bool {
+		return snapshot.Prefixes[i].Prefix < snapshot.Prefixes[j].Prefix
+	})
+	op.logger.Debug("usage sampled", zap.Int64("objects", snapshot.Objects), zap.Int64("bytes", snapshot.Bytes), zap.Int("prefixes", len(snapshot.Prefixes)))
+	return snapshot, nil
+}
+
+// WriteUsageSnapshot writes given snapshot as line delimited UsageRecords, in an object under
+// the request's path, DEFAULT_USAGE_PREFIX when empty, named by the snapshot's time, so every
+// sample adds an object to the series. Snapshots without time are dated now.
+func (cs *cloudStorageClient) WriteUsageSnapshot(ctx context.Context, snapshot UsageSnapshot, cfr CloudFileRequest) (UploadResult, error) {
+	if cfr.IsZero() {
+		return UploadResult{}, ErrEmptyRequest
+	}
+	if snapshot.Taken.IsZero() {
+		snapshot.Taken = cs.now()
+	}
+	if cfr.path == "" {
+		cfr.path = DEFAULT_USAGE_PREFIX
+	}
+	cfr.file = usageObjectName(snapshot.Taken)
+	records := snapshot.Prefixes
+	if len(records) == 0 {
+		records = []PrefixUsage{{}}
+	}
+	i := 0
+	res, err := cs.WriteNDJSON(ctx, cfr, func() (interface{}, bool) {
+		if i == len(records) {
+			return nil, false
+		}
+		i++
+		return UsageRecord{Taken: snapshot.Taken, Bucket: snapshot.Bucket, Depth: snapshot.Depth, PrefixUsage: records[i-1]}, true
+	})
+	if err != nil {
+		cs.logger.Error(ERROR_WRITING_USAGE, zap.Error(err), zap.String("bucket", cfr.bucket), zap.String("filepath", cfr.objectPath()))
+		return res, err
+	}
+	return res, nil
+}
+
+// ReadUsageHistory reads the usage snapshots written under the request's path, DEFAULT_USAGE_PREFIX when
+// empty, taken at or after since, in time order, zero since reads the whole series
+func (cs *cloudStorageClient) ReadUsageHistory(ctx context.Context, cfr CloudFileRequest, since time.Time) ([]UsageSnapshot, error) {
+	if cfr.IsZero() {
+		return nil, ErrEmptyRequest
+	}
+	if cfr.path == "" {
+		cfr.path = DEFAULT_USAGE_PREFIX
+	}
+	cfr, err := cs.scoped(ctx, cfr)
+	if err != nil {
+		return nil, err
+	}
+	if cfr.bucket == "" {
+		return nil, ErrBucketNameMissing
+	}
+	op := cs.startOperation(ctx, "ReadUsageHistory", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	prefix := dirPrefix(cfr.path)
+	op.object = prefix
+
+	q := &storage.Query{Prefix: prefix}
+	if !since.IsZero() {
+		q.StartOffset = prefix + usageObjectName(since.Truncate(time.Second))
+	}
+	history := []UsageSnapshot{}
+	it := cs.bucketHandle(cfr).Objects(ctx, q)
+	for {
+		attrs, err := it.Next()
+		if err != nil {
+			if err == iterator.Done {
+				break
+			}
+			op.logger.Error(ERROR_LISTING_OBJECTS, zap.Error(err), zap.Int("read", len(history)))
+			return history, op.wrapError(err, ERROR_LISTING_OBJECTS)
+		}
+		// only snapshots directly under the prefix are part of the series
+		if _, err := time.Parse(usageTimeLayout+".ndjson", strings.TrimPrefix(attrs.Name, prefix)); err != nil {
+			continue
+		}
+		snapshot, err := cs.readUsageSnapshot(ctx, cfr, attrs.Name)
+		if err != nil {
+			op.logger.Error(ERROR_READING_USAGE_HISTORY, zap.Error(err), zap.String("filepath", attrs.Name))
+			return history, err
+		}
+		if !snapshot.Taken.Before(since) {
+			history = append(history, snapshot)
+		}
+	}
+	return history, nil
+}
+
+// readUsageSnapshot reads the snapshot written to given stored object name
+func (cs *cloudStorageClient) readUsageSnapshot(ctx context.Context, cfr CloudFileRequest, name string) (UsageSnapshot, error) {
+	cfr.path, cfr.file = path.Split(name)
+	snapshot := UsageSnapshot{Prefixes: []PrefixUsage{}}
+	err := cs.ReadNDJSON(ctx, cfr, func(line json.RawMessage) error {
+		var rec UsageRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return err
+		}
+		snapshot.Bucket, snapshot.Depth, snapshot.Taken = rec.Bucket, rec.Depth, rec.Taken
+		if rec.Objects > 0 {
+			snapshot.Prefixes = append(snapshot.Prefixes, rec.PrefixUsage)
+			snapshot.Objects += rec.Objects
+			snapshot.Bytes += rec.Bytes
+		}
+		return nil
+	})
+	return snapshot, err
+}
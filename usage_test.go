@@ -0,0 +1,110 @@
+package cloudstorage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/comfforts/cloudstorage/cloudstoragetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsagePrefix(t *testing.T) {
+	require.Equal(t, "", usagePrefix("a/b/c.txt", 0))
+	require.Equal(t, "a/", usagePrefix("a/b/c.txt", 1))
+	require.Equal(t, "a/b/", usagePrefix("a/b/c.txt", 2))
+	require.Equal(t, "a/b/", usagePrefix("a/b/c.txt", 5))
+	require.Equal(t, "", usagePrefix("c.txt", 2))
+}
+
+func TestSampleUsage(t *testing.T) {
+	f := newFakeGCS()
+	m := cloudstoragetest.NewTree().
+		Dir("logs/2024").File("a.log", 4).File("b.log", 2).
+		Dir("logs/2023").File("c.log", 1).
+		Dir("logs").File("top.log", 3).
+		Dir("data").Marker().
+		Dir("").File("readme.txt", 6).
+		Build(t, f)
+	cs := newFakeClient(t, f)
+	clock := newFakeClock()
+	WithClock(clock)(cs)
+	ctx := context.Background()
+
+	snapshot, err := cs.SampleUsage(ctx, "bucket", 2)
+	require.NoError(t, err)
+	require.Equal(t, UsageSnapshot{
+		Bucket: "bucket", Depth: 2, Taken: clock.Now(), Objects: 5, Bytes: m.Bytes(""),
+		Prefixes: []PrefixUsage{
+			{Prefix: "", Objects: 1, Bytes: 6},
+			{Prefix: "logs/", Objects: 1, Bytes: 3},
+			{Prefix: "logs/2023/", Objects: 1, Bytes: 1},
+			{Prefix: "logs/2024/", Objects: 2, Bytes: m.Bytes("logs/2024/")},
+		},
+	}, snapshot)
+
+	totals, err := cs.SampleUsage(ctx, "bucket", 0)
+	require.NoError(t, err)
+	require.Equal(t, []PrefixUsage{{Objects: 5, Bytes: m.Bytes("")}}, totals.Prefixes)
+
+	// scoped samples are relative to the scope prefix
+	f.put("bucket", "tenant-a/docs/x.txt", []byte("x"), nil)
+	scoped, err := cs.SampleUsage(tenantContext(), "", 1)
+	require.NoError(t, err)
+	require.Equal(t, []PrefixUsage{{Prefix: "docs/", Objects: 1, Bytes: 1}}, scoped.Prefixes)
+
+	_, err = cs.SampleUsage(ctx, "bucket", -1)
+	require.Equal(t, ErrInvalidUsageDepth, err)
+}
+
+func TestUsageHistory(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "logs/a.log", []byte("aaaa"), nil)
+	cs := newFakeClient(t, f)
+	clock := newFakeClock()
+	WithClock(clock)(cs)
+	ctx := context.Background()
+	metrics := CloudFileRequest{bucket: "metrics", path: "usage"}
+
+	taken := []time.Time{}
+	for day := 0; day < 3; day++ {
+		snapshot, err := cs.SampleUsage(ctx, "bucket", 1)
+		require.NoError(t, err)
+		res, err := cs.WriteUsageSnapshot(ctx, snapshot, metrics)
+		require.NoError(t, err)
+		require.Equal(t, "usage/"+usageObjectName(clock.Now()), res.Attrs.Name)
+		taken = append(taken, snapshot.Taken)
+		f.put("bucket", "logs/day.log", []byte(strings.Repeat("d", day+1)), nil)
+		clock.Advance(24 * time.Hour)
+	}
+	// the series is one object per snapshot, one record per prefix
+	data, _, ok := f.get("metrics", "usage/20240101T000000Z.ndjson")
+	require.True(t, ok)
+	require.Equal(t, `{"taken":"2024-01-01T00:00:00Z","bucket":"bucket","depth":1,"prefix":"logs/","objects":1,"bytes":4}`+"\n", string(data))
+	f.put("metrics", "usage/notes.txt", []byte("not a snapshot"), nil)
+
+	history, err := cs.ReadUsageHistory(ctx, metrics, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	for i, snapshot := range history {
+		require.True(t, taken[i].Equal(snapshot.Taken))
+	}
+	require.Equal(t, []PrefixUsage{{Prefix: "logs/", Objects: 2, Bytes: 6}}, history[2].Prefixes)
+	require.Equal(t, int64(6), history[2].Bytes)
+
+	history, err = cs.ReadUsageHistory(ctx, metrics, taken[1])
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.True(t, taken[1].Equal(history[0].Taken))
+
+	// empty snapshots round trip, under the default prefix
+	empty := UsageSnapshot{Bucket: "empty", Taken: taken[0], Prefixes: []PrefixUsage{}}
+	_, err = cs.WriteUsageSnapshot(ctx, empty, CloudFileRequest{bucket: "bucket"})
+	require.NoError(t, err)
+	history, err = cs.ReadUsageHistory(ctx, CloudFileRequest{bucket: "bucket"}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, "empty", history[0].Bucket)
+	require.Empty(t, history[0].Prefixes)
+}
@@ -0,0 +1,87 @@
+package cloudstorage
+
+import (
+	"context"
+
+	"cloud.google.com/go/storage"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+// VersionListOptions configure ListLatestVersions
+type VersionListOptions struct {
+	// IncludeDeleted yields the newest noncurrent generation of deleted objects, only live objects are yielded otherwise
+	IncludeDeleted bool
+}
+
+// VersionListOption sets version listing options
+type VersionListOption func(o *VersionListOptions)
+
+// WithIncludeDeleted makes version listings yield deleted objects' newest noncurrent generation
+func WithIncludeDeleted() VersionListOption {
+	return func(o *VersionListOptions) {
+		o.IncludeDeleted = true
+	}
+}
+
+// ListLatestVersions streams one version per object name under request path through fn, in name order,
+// the live generation, or with WithIncludeDeleted the newest noncurrent one of deleted objects.
+// The versioned listing is ordered by name then generation, consecutive generations of a name
+// are collapsed as they're listed, memory use doesn't grow with the number of objects.
+// Stops on the first fn error, returned as is.
+func (cs *cloudStorageClient) ListLatestVersions(ctx context.Context, cfr CloudFileRequest, fn func(ObjectVersion) error, opts ...VersionListOption) error {
+	cfr, err := cs.prefixRequest(ctx, cfr)
+	if err != nil {
+		return err
+	}
+	if cfr.bucket == "" {
+		return ErrBucketNameMissing
+	}
+	vOpts := VersionListOptions{}
+	for _, opt := range opts {
+		opt(&vOpts)
+	}
+	op := cs.startOperation(ctx, "ListLatestVersions", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	prefix := dirPrefix(cfr.path)
+	op.object = prefix
+
+	var pending *ObjectVersion
+	yield := func() error {
+		if pending == nil || (!pending.Deleted.IsZero() && !vOpts.IncludeDeleted) {
+			return nil
+		}
+		return fn(*pending)
+	}
+	names := 0
+	it := cs.bucketHandle(cfr).Objects(ctx, &storage.Query{Prefix: prefix, Versions: true})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			op.logger.Error(ERROR_LISTING_VERSIONS, zap.Error(err), zap.String("prefix", prefix))
+			return op.wrapError(err, "%s %s", ERROR_LISTING_VERSIONS, prefix)
+		}
+		v := newObjectVersion(cfr, attrs)
+		if pending != nil && pending.Name == v.Name {
+			// the live generation is the newest, it's listed last
+			if v.Generation > pending.Generation {
+				pending = &v
+			}
+			continue
+		}
+		if err := yield(); err != nil {
+			return err
+		}
+		names++
+		pending = &v
+	}
+	if err := yield(); err != nil {
+		return err
+	}
+	op.logger.Debug("cloud file latest versions listed", zap.String("prefix", prefix), zap.Int("names", names))
+	return nil
+}
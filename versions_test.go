@@ -0,0 +1,55 @@
+package cloudstorage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListLatestVersions(t *testing.T) {
+	f := newFakeGCS()
+	f.versioned = true
+	versionedHistory(t, f)
+	f.put("bucket", "data/a.txt", []byte("a v3"), nil)
+	// generations of a name span listing pages
+	f.pageSize = 2
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr := CloudFileRequest{bucket: "bucket", path: "data"}
+
+	latest := func(opts ...VersionListOption) []ObjectVersion {
+		versions := []ObjectVersion{}
+		require.NoError(t, cs.ListLatestVersions(ctx, cfr, func(v ObjectVersion) error {
+			versions = append(versions, v)
+			return nil
+		}, opts...))
+		return versions
+	}
+	versions := latest()
+	require.Len(t, versions, 2)
+	require.Equal(t, "data/a.txt", versions[0].Name)
+	require.Equal(t, int64(6), versions[0].Generation)
+	require.True(t, versions[0].Deleted.IsZero())
+	require.Equal(t, "data/c.txt", versions[1].Name)
+	require.Equal(t, int64(5), versions[1].Generation)
+
+	// deleted objects yield their newest noncurrent generation
+	versions = latest(WithIncludeDeleted())
+	require.Len(t, versions, 3)
+	require.Equal(t, "data/b.txt", versions[1].Name)
+	require.Equal(t, int64(2), versions[1].Generation)
+	require.False(t, versions[1].Deleted.IsZero())
+
+	stop := errors.New("stop")
+	calls := 0
+	err := cs.ListLatestVersions(ctx, cfr, func(ObjectVersion) error {
+		calls++
+		return stop
+	})
+	require.Equal(t, stop, err)
+	require.Equal(t, 1, calls)
+
+	require.Equal(t, ErrBucketNameMissing, cs.ListLatestVersions(ctx, CloudFileRequest{path: "data"}, func(ObjectVersion) error { return nil }))
+}
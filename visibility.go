@@ -0,0 +1,131 @@
+package cloudstorage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	ERROR_NOT_VISIBLE   string = "uploaded object not visible"
+	ERROR_INVALID_TOKEN string = "visibility token missing bucket, object or generation"
+)
+
+var (
+	ErrNotVisible   = errors.NewAppError(ERROR_NOT_VISIBLE)
+	ErrInvalidToken = errors.NewAppError(ERROR_INVALID_TOKEN)
+)
+
+// Visibility polling backoff of WaitVisible
+const (
+	DEFAULT_VISIBILITY_POLL_INITIAL = 50 * time.Millisecond
+	DEFAULT_VISIBILITY_POLL_MAX     = 2 * time.Second
+)
+
+// VisibilityToken identifies an uploaded object generation by bucket & stored name,
+// serializable to hand off to other processes, waited for with WaitVisible
+type VisibilityToken struct {
+	Bucket     string `json:"bucket"`
+	Object     string `json:"object"`
+	Generation int64  `json:"generation"`
+}
+
+// visibilityToken returns the token of given uploaded attributes
+func visibilityToken(attrs *storage.ObjectAttrs) VisibilityToken {
+	if attrs == nil {
+		return VisibilityToken{}
+	}
+	return VisibilityToken{Bucket: attrs.Bucket, Object: attrs.Name, Generation: attrs.Generation}
+}
+
+// NotVisibleError is returned by WaitVisible when the token's generation isn't observed in time,
+// matches ErrNotVisible with errors.Is & unwraps to the last lookup error, if any
+type NotVisibleError struct {
+	Token VisibilityToken
+	// Observed is the last generation observed, zero when the object wasn't found
+	Observed int64
+	Waited   time.Duration
+	Err      error
+}
+
+func (e NotVisibleError) Error() string {
+	return fmt.Sprintf("%s %s/%s#%d after %s, observed generation %d", ERROR_NOT_VISIBLE, e.Token.Bucket, e.Token.Object, e.Token.Generation, e.Waited, e.Observed)
+}
+
+// Is matches ErrNotVisible
+func (e NotVisibleError) Is(target error) bool {
+	return target == ErrNotVisible
+}
+
+// Unwrap returns the last lookup error
+func (e NotVisibleError) Unwrap() error {
+	return e.Err
+}
+
+// WaitVisible polls the token's object attributes with backoff until its generation, or a later one,
+// is observed, bypassing & invalidating the existence cache, so later lookups through the client
+// see it too. Fails with NotVisibleError once given timeout elapsed, no timeout besides
+// the context's when not positive. Tokens name the stored object, the context scope doesn't apply,
+// scoped clients fail tokens outside their boundary with ErrScopeViolation.
+func (cs *cloudStorageClient) WaitVisible(ctx context.Context, token VisibilityToken, timeout time.Duration) (*ObjectAttrs, error) {
+	if token.Bucket == "" || token.Object == "" || token.Generation <= 0 {
+		return nil, ErrInvalidToken
+	}
+	if cs.bound != nil && !cs.bound.contains(token.Bucket, token.Object) {
+		return nil, cs.bound.violation(token.Bucket, token.Object)
+	}
+	cfr := CloudFileRequest{bucket: token.Bucket}
+	op := cs.startOperation(ctx, "WaitVisible", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	op.object = token.Object
+
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := cs.now()
+	backoff := &Backoff{Initial: DEFAULT_VISIBILITY_POLL_INITIAL, Max: DEFAULT_VISIBILITY_POLL_MAX, Multiplier: DEFAULT_RETRY_MULTIPLIER, Jitter: 0.5}
+	obj := cs.bucketHandle(cfr).Object(token.Object)
+	var observed int64
+	var lastErr error
+	for {
+		cs.invalidate(token.Bucket, token.Object)
+		attrs, err := obj.Attrs(waitCtx)
+		switch {
+		case err == nil && attrs.Generation >= token.Generation:
+			op.logger.Debug("cloud file visible", zap.String("filepath", op.object), zap.Int64("generation", attrs.Generation), zap.Duration("waited", cs.since(start)))
+			return newObjectAttrs(attrs), nil
+		case err == nil:
+			observed, lastErr = attrs.Generation, nil
+			op.logger.Debug("cloud file generation not visible yet", zap.String("filepath", op.object), zap.Int64("observed", observed), zap.Int64("generation", token.Generation))
+		case waitCtx.Err() != nil:
+			// the lookup was cut short by the timeout or the caller's context
+		case err == storage.ErrObjectNotExist || IsRetryable(err):
+			lastErr = err
+			op.logger.Debug("cloud file not visible yet", zap.String("filepath", op.object), zap.Error(err))
+		default:
+			op.logger.Error(ERROR_GETTING_ATTRS, zap.Error(err), zap.String("filepath", op.object))
+			return nil, op.wrapError(err, "%s %s", ERROR_GETTING_ATTRS, op.object)
+		}
+
+		if waitCtx.Err() == nil {
+			// a done context ends the wait below
+			_ = cs.sleep(waitCtx, backoff.Next())
+		}
+		if waitCtx.Err() != nil {
+			if ctx.Err() != nil {
+				return nil, op.wrapError(ctx.Err(), "%s %s", ERROR_NOT_VISIBLE, op.object)
+			}
+			nvErr := NotVisibleError{Token: token, Observed: observed, Waited: cs.since(start), Err: lastErr}
+			op.logger.Error(ERROR_NOT_VISIBLE, zap.Error(nvErr), zap.String("filepath", op.object))
+			return nil, nvErr
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package cloudstorage
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitVisible(t *testing.T) {
+	f := newFakeGCS()
+	cs := newFakeClient(t, f)
+	res, err := cs.Upload(tenantContext(), strings.NewReader("data"), CloudFileRequest{path: "docs", file: "a.txt"})
+	require.NoError(t, err)
+	// tokens name the stored object
+	require.Equal(t, VisibilityToken{Bucket: "bucket", Object: "tenant-a/docs/a.txt", Generation: res.Attrs.Generation}, res.Token)
+
+	attrs, err := cs.WaitVisible(context.Background(), res.Token, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, res.Attrs.Generation, attrs.Generation)
+
+	_, err = cs.WaitVisible(context.Background(), VisibilityToken{Bucket: "bucket", Object: "a.txt"}, time.Second)
+	require.Equal(t, ErrInvalidToken, err)
+}
+
+func TestWaitVisibleBypassesCache(t *testing.T) {
+	f := newFakeGCS()
+	cs, _ := newCachedClient(t, f, 10, time.Minute, time.Minute)
+	waits := []time.Duration{}
+	cs.sleeper = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	ctx := context.Background()
+	cfr := CloudFileRequest{bucket: "bucket", path: "docs", file: "a.txt"}
+	// the object's absence is cached
+	ok, err := cs.Exists(ctx, cfr)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// written by another process, seen after a few lookups
+	f.put("bucket", "docs/a.txt", []byte("data"), nil)
+	_, stored, _ := f.get("bucket", "docs/a.txt")
+	var lookups int64
+	f.fail = func(r *http.Request) int {
+		if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/storage/v1/b/bucket/o/") && atomic.AddInt64(&lookups, 1) <= 2 {
+			return http.StatusNotFound
+		}
+		return 0
+	}
+	attrs, err := cs.WaitVisible(ctx, VisibilityToken{Bucket: "bucket", Object: "docs/a.txt", Generation: stored.Generation}, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, stored.Generation, attrs.Generation)
+	require.Len(t, waits, 2)
+	require.LessOrEqual(t, waits[0], waits[1])
+	require.LessOrEqual(t, waits[0], DEFAULT_VISIBILITY_POLL_INITIAL)
+
+	ok, err = cs.Exists(ctx, cfr)
+	require.NoError(t, err)
+	require.True(t, ok, "the cached absence is dropped")
+}
+
+func TestWaitVisibleTimeout(t *testing.T) {
+	f := newFakeGCS()
+	f.put("bucket", "docs/a.txt", []byte("old"), nil)
+	_, stored, _ := f.get("bucket", "docs/a.txt")
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+
+	_, err := cs.WaitVisible(ctx, VisibilityToken{Bucket: "bucket", Object: "docs/missing.txt", Generation: 1}, 150*time.Millisecond)
+	require.ErrorIs(t, err, ErrNotVisible)
+	require.ErrorIs(t, err, storage.ErrObjectNotExist)
+	var nvErr NotVisibleError
+	require.ErrorAs(t, err, &nvErr)
+	require.Zero(t, nvErr.Observed)
+	require.GreaterOrEqual(t, nvErr.Waited, 150*time.Millisecond)
+
+	// an older generation isn't the uploaded one
+	_, err = cs.WaitVisible(ctx, VisibilityToken{Bucket: "bucket", Object: "docs/a.txt", Generation: stored.Generation + 1}, 100*time.Millisecond)
+	require.ErrorAs(t, err, &nvErr)
+	require.Equal(t, stored.Generation, nvErr.Observed)
+	require.NoError(t, nvErr.Err)
+
+	// the caller's cancellation isn't a timeout
+	cctx, cancel := context.WithCancel(ctx)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = cs.WaitVisible(cctx, VisibilityToken{Bucket: "bucket", Object: "docs/missing.txt", Generation: 1}, time.Minute)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotErrorIs(t, err, ErrNotVisible)
+}
@@ -0,0 +1,568 @@
+package cloudstorage
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/comfforts/errors"
+	"go.uber.org/zap"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	ERROR_WALKING_OBJECTS      string = "error walking objects"
+	ERROR_WALK_FUNC_REQUIRED   string = "walk func missing"
+	ERROR_INVALID_WALK_BOUNDS  string = "walk shard bounds must be ascending names under the walked prefix"
+	ERROR_SAMPLING_WALK_BOUNDS string = "error sampling walk shard bounds"
+)
+
+var (
+	ErrWalkFuncRequired  = errors.NewAppError(ERROR_WALK_FUNC_REQUIRED)
+	ErrInvalidWalkBounds = errors.NewAppError(ERROR_INVALID_WALK_BOUNDS)
+)
+
+const (
+	// DEFAULT_WALK_BUFFER is the number of objects each shard lists ahead of the walk func
+	DEFAULT_WALK_BUFFER = 1000
+	// DEFAULT_WALK_SAMPLES_PER_SHARD is the number of density probes per shard of the sampling pass
+	DEFAULT_WALK_SAMPLES_PER_SHARD = 16
+	// DEFAULT_WALK_SAMPLE_PAGE is the number of names each density probe lists
+	DEFAULT_WALK_SAMPLE_PAGE = 100
+)
+
+// walk keyspaces are over the walkKeyBase printable ASCII characters from walkKeyFirst, positions are exact
+// in walkKeyBits of a float64, the last name is resolved to walkLastDigits characters past the first's
+const (
+	walkKeyBase    = 95
+	walkKeyFirst   = ' '
+	walkKeyBits    = 53
+	walkLastDigits = 4
+)
+
+// WalkFunc receives the walked objects' attributes, one call at a time,
+// a returned error stops the walk & is returned by WalkObjects
+type WalkFunc func(attrs *ObjectAttrs) error
+
+// WalkOptions configure WalkObjects
+type WalkOptions struct {
+	// Shards is the number of name ranges listed concurrently, one lists sequentially, the default
+	Shards int
+	// Bounds are the names splitting the shards, stored names relative to the scope prefix, estimated
+	// with a sampling pass when not set. Names equal to a bound are listed by the shard it starts.
+	Bounds []string
+	// Ordered walks objects in name order across shards, later shards list ahead up to Buffer objects
+	// & wait for the earlier ones, shards are walked as their names are listed otherwise
+	Ordered bool
+	// Buffer is the number of objects each shard lists ahead of the walk func, defaults to DEFAULT_WALK_BUFFER
+	Buffer int
+}
+
+// WalkOption sets walk options
+type WalkOption func(o *WalkOptions)
+
+// WithWalkShards lists the walked prefix in given number of concurrent name ranges
+func WithWalkShards(n int) WalkOption {
+	return func(o *WalkOptions) {
+		o.Shards = n
+	}
+}
+
+// WithWalkBounds sets the names splitting the walked prefix into shards, one more than bounds,
+// instead of estimating them
+func WithWalkBounds(bounds ...string) WalkOption {
+	return func(o *WalkOptions) {
+		o.Bounds = bounds
+	}
+}
+
+// WithWalkOrdered walks objects in name order across shards
+func WithWalkOrdered() WalkOption {
+	return func(o *WalkOptions) {
+		o.Ordered = true
+	}
+}
+
+// WithWalkBuffer sets the number of objects each shard lists ahead of the walk func
+func WithWalkBuffer(n int) WalkOption {
+	return func(o *WalkOptions) {
+		o.Buffer = n
+	}
+}
+
+// WalkObjects streams the attributes of objects under request's path to given func, one call at a time,
+// returns the number of objects walked. Sequential listings of huge prefixes are bound by the service's
+// page round trips, WithWalkShards splits the names under the prefix into lexicographic ranges listed
+// concurrently, with start & end offsets, each range's objects walked in name order. Shard bounds are
+// estimated by a sampling pass when not set with WithWalkBounds: the first & last names under the prefix
+// bound the keyspace & density probes, listing a page at evenly spaced names, estimate the objects between
+// them. Estimates assume printable ASCII names & only balance shards, every object is walked once whatever
+// the bounds. WithWalkOrdered walks in name order across shards. Objects written under the prefix
+// during the walk may or may not be walked.
+func (cs *cloudStorageClient) WalkObjects(ctx context.Context, cfr CloudFileRequest, fn WalkFunc, opts ...WalkOption) (int64, error) {
+	cfr, err := cs.prefixRequest(ctx, cfr)
+	if err != nil {
+		return 0, err
+	}
+	if cfr.bucket == "" {
+		return 0, ErrBucketNameMissing
+	}
+	if fn == nil {
+		return 0, ErrWalkFuncRequired
+	}
+	wOpts := WalkOptions{Shards: 1, Buffer: DEFAULT_WALK_BUFFER}
+	for _, opt := range opts {
+		opt(&wOpts)
+	}
+	if wOpts.Buffer <= 0 {
+		wOpts.Buffer = DEFAULT_WALK_BUFFER
+	}
+	prefix := dirPrefix(cfr.path)
+	bounds := make([]string, len(wOpts.Bounds))
+	for i, bound := range wOpts.Bounds {
+		bounds[i] = cfr.scopePrefix + bound
+		if !strings.HasPrefix(bounds[i], prefix) || (i > 0 && bounds[i] <= bounds[i-1]) {
+			return 0, ErrInvalidWalkBounds
+		}
+	}
+	op := cs.startOperation(ctx, "WalkObjects", cfr)
+	defer op.finish()
+	ctx = op.ctx
+	op.object = prefix
+
+	if len(bounds) == 0 && wOpts.Shards > 1 {
+		if bounds, err = cs.walkBounds(ctx, cfr, prefix, wOpts.Shards); err != nil {
+			op.logger.Error(ERROR_SAMPLING_WALK_BOUNDS, zap.Error(err), zap.String("filepath", prefix))
+			return 0, op.wrapError(err, "%s %s", ERROR_SAMPLING_WALK_BOUNDS, prefix)
+		}
+		op.logger.Debug("walk shard bounds sampled", zap.String("prefix", prefix), zap.Strings("bounds", bounds))
+	}
+
+	walked, err := cs.walkShards(ctx, cfr, prefix, bounds, wOpts, fn)
+	op.bytes = walked.bytes
+	if err != nil {
+		op.logger.Error(ERROR_WALKING_OBJECTS, zap.Error(err), zap.String("filepath", prefix), zap.Int64("walked", walked.objects))
+		return walked.objects, op.wrapError(err, "%s %s", ERROR_WALKING_OBJECTS, prefix)
+	}
+	op.logger.Debug("objects walked", zap.String("prefix", prefix), zap.Int64("walked", walked.objects), zap.Int("shards", len(bounds)+1))
+	return walked.objects, nil
+}
+
+// walkCount is the number & total size of walked objects
+type walkCount struct {
+	objects int64
+	bytes   int64
+}
+
+// walkShards lists the ranges between given bounds concurrently & calls the walk func with their objects,
+// returns the first listing or walk func error
+func (cs *cloudStorageClient) walkShards(ctx context.Context, cfr CloudFileRequest, prefix string, bounds []string, wOpts WalkOptions, fn WalkFunc) (walkCount, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var mu sync.Mutex
+	var firstErr error
+	failed := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		cancel()
+	}
+
+	// unordered shards share a channel, ordered ones are drained one after the other
+	shards := len(bounds) + 1
+	outs := make([]chan *storage.ObjectAttrs, shards)
+	var shared chan *storage.ObjectAttrs
+	if !wOpts.Ordered {
+		shared = make(chan *storage.ObjectAttrs, wOpts.Buffer)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < shards; i++ {
+		q := &storage.Query{Prefix: prefix}
+		if i > 0 {
+			q.StartOffset = bounds[i-1]
+		}
+		if i < len(bounds) {
+			q.EndOffset = bounds[i]
+		}
+		outs[i] = shared
+		if wOpts.Ordered {
+			outs[i] = make(chan *storage.ObjectAttrs, wOpts.Buffer)
+		}
+		wg.Add(1)
+		go func(out chan *storage.ObjectAttrs, q *storage.Query) {
+			defer wg.Done()
+			if wOpts.Ordered {
+				defer close(out)
+			}
+			if err := cs.walkShard(ctx, cfr, q, out); err != nil {
+				failed(err)
+			}
+		}(outs[i], q)
+	}
+	if !wOpts.Ordered {
+		go func() {
+			wg.Wait()
+			close(shared)
+		}()
+		outs = outs[:1]
+	}
+
+	var walked walkCount
+	for _, out := range outs {
+		for attrs := range out {
+			if ctx.Err() != nil {
+				continue
+			}
+			if err := fn(cfr.logicalAttrs(newObjectAttrs(attrs))); err != nil {
+				failed(err)
+				continue
+			}
+			walked.objects++
+			walked.bytes += attrs.Size
+		}
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return walked, firstErr
+	}
+	return walked, ctx.Err()
+}
+
+// walkShard lists given query's objects to given channel, until done or the context is
+func (cs *cloudStorageClient) walkShard(ctx context.Context, cfr CloudFileRequest, q *storage.Query, out chan<- *storage.ObjectAttrs) error {
+	it := cs.bucketHandle(cfr).Objects(ctx, q)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case out <- attrs:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// walkInterval is a keyspace interval of the sampling pass & the names its probe listed
+type walkInterval struct {
+	lo, hi float64
+	names  []string
+	// count is the number of names in the interval, estimated from the density of the page when it filled up
+	count float64
+}
+
+// full reports whether the interval's probe filled a page, the interval may hold more names
+func (iv *walkInterval) full() bool {
+	return len(iv.names) == DEFAULT_WALK_SAMPLE_PAGE
+}
+
+// walkBounds estimates the names splitting the objects under given prefix into given number of
+// about equal shards, fewer when the prefix holds too few names. Intervals of evenly spaced positions
+// between the first & last names are probed, a page each, & the intervals filling a page are split
+// & probed again, densest first, until DEFAULT_WALK_SAMPLES_PER_SHARD probes per shard are spent.
+func (cs *cloudStorageClient) walkBounds(ctx context.Context, cfr CloudFileRequest, prefix string, shards int) ([]string, error) {
+	probe := func(q *storage.Query, max int) ([]string, error) {
+		q.Prefix = prefix
+		if err := q.SetAttrSelection([]string{"Name"}); err != nil {
+			return nil, err
+		}
+		it := cs.bucketHandle(cfr).Objects(ctx, q)
+		it.PageInfo().MaxSize = max
+		names := []string{}
+		for len(names) < max {
+			attrs, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, attrs.Name)
+		}
+		return names, nil
+	}
+	exists := func(from string) (bool, error) {
+		names, err := probe(&storage.Query{StartOffset: from}, 1)
+		return len(names) > 0, err
+	}
+
+	names, err := probe(&storage.Query{}, DEFAULT_WALK_SAMPLE_PAGE)
+	if err != nil || len(names) == 0 {
+		return nil, err
+	}
+	first := names[0]
+	last, err := walkLastName(prefix, first, shards, exists)
+	if err != nil {
+		return nil, err
+	}
+	ks := newWalkKeyspace(first, last, names...)
+	lo, hi := ks.key(first), ks.key(last+strings.Repeat("\x7f", ks.digits))+1
+
+	// the last interval is open ended, names past the last one found are counted there
+	probeAll := func(ivs []*walkInterval) error {
+		sem := make(chan struct{}, shards)
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var firstErr error
+		for _, iv := range ivs {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(iv *walkInterval) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				q := &storage.Query{StartOffset: ks.name(iv.lo)}
+				if iv.hi < hi {
+					q.EndOffset = ks.name(iv.hi)
+				}
+				names, err := probe(q, DEFAULT_WALK_SAMPLE_PAGE)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				iv.names, iv.count = names, float64(len(names))
+				// names the keyspace can't tell apart are counted once listed
+				if iv.full() {
+					kf, kl := ks.key(names[0]), ks.key(names[len(names)-1])
+					if est := iv.count * (iv.hi - kf) / (kl - kf + 1); kl > kf && est > iv.count {
+						iv.count = est
+					}
+				}
+			}(iv)
+		}
+		wg.Wait()
+		return firstErr
+	}
+
+	budget := shards * DEFAULT_WALK_SAMPLES_PER_SHARD
+	initial := shards
+	if hi-lo < float64(initial) {
+		initial = int(hi - lo)
+	}
+	ivs := make([]*walkInterval, initial)
+	for j := range ivs {
+		ivs[j] = &walkInterval{lo: lo + float64(int64((hi-lo)*float64(j)/float64(initial))), hi: hi}
+		if j > 0 {
+			ivs[j-1].hi = ivs[j].lo
+		}
+	}
+	if err := probeAll(ivs); err != nil {
+		return nil, err
+	}
+	for probes := initial; probes+2 <= budget; {
+		// densest full intervals first, split at the middle of the positions past their first name
+		dense := []*walkInterval{}
+		for _, iv := range ivs {
+			if iv.full() && iv.hi-ks.key(iv.names[0]) >= 2 {
+				dense = append(dense, iv)
+			}
+		}
+		if len(dense) == 0 {
+			break
+		}
+		sort.Slice(dense, func(i, j int) bool { return dense[i].count > dense[j].count })
+		if n := (budget - probes) / 2; len(dense) > n {
+			dense = dense[:n]
+		}
+		if len(dense) > shards {
+			dense = dense[:shards]
+		}
+		split := []*walkInterval{}
+		for _, iv := range dense {
+			kf, kl := ks.key(iv.names[0]), ks.key(iv.names[len(iv.names)-1])
+			mid := kf + float64(int64((iv.hi-kf)/2))
+			if kl == kf {
+				mid = kf + 1
+			}
+			split = append(split, &walkInterval{lo: kf, hi: mid}, &walkInterval{lo: mid, hi: iv.hi})
+		}
+		if err := probeAll(split); err != nil {
+			return nil, err
+		}
+		probes += len(split)
+		for i, iv := range dense {
+			*iv = *split[2*i]
+			ivs = append(ivs, split[2*i+1])
+		}
+		sort.Slice(ivs, func(i, j int) bool { return ivs[i].lo < ivs[j].lo })
+	}
+
+	var total float64
+	for _, iv := range ivs {
+		total += iv.count
+	}
+	bounds := []string{}
+	var cum float64
+	j := 0
+	for k := 1; k < shards; k++ {
+		target := total * float64(k) / float64(shards)
+		for j < len(ivs) && (cum+ivs[j].count < target || len(ivs[j].names) == 0) {
+			cum += ivs[j].count
+			j++
+		}
+		if j == len(ivs) {
+			break
+		}
+		// bounds within the names listed are exact, past them they're interpolated up to the interval's end
+		iv := ivs[j]
+		n := float64(len(iv.names))
+		kl := ks.key(iv.names[len(iv.names)-1])
+		bound := iv.names[len(iv.names)-1]
+		switch i := int(target - cum); {
+		case i < len(iv.names):
+			bound = iv.names[i]
+		case iv.count > n:
+			bound = ks.name(kl + 1 + float64(int64((target-cum-n)/(iv.count-n)*(iv.hi-kl-1))))
+		}
+		if bound > first && (len(bounds) == 0 || bound > bounds[len(bounds)-1]) {
+			bounds = append(bounds, bound)
+		}
+	}
+	return bounds, nil
+}
+
+// walkLastName finds the last name under given prefix, or a name before it sharing its first walkLastDigits
+// characters after first's, character by character: the largest printable character c after the name found
+// so far with names at or after name+c, searched with rounds of width concurrent probes. Characters up to
+// first's are known to have names.
+func walkLastName(prefix, first string, width int, exists func(from string) (bool, error)) (string, error) {
+	last := prefix
+	for len(last)-len(commonPrefix([]string{first, last})) < walkLastDigits {
+		// names are at or after last+lo, none at or after last+hi+1, lo -1 before any is found
+		lo, hi := -1, walkKeyBase-1
+		if len(first) > len(last) && strings.HasPrefix(first, last) {
+			if d := int(first[len(last)]) - walkKeyFirst; d >= 0 && d <= hi {
+				lo = d
+			}
+		}
+		for lo < hi {
+			n := hi - lo
+			if n > width {
+				n = width
+			}
+			digits := make([]int, n)
+			found := make([]bool, n)
+			errs := make([]error, n)
+			var wg sync.WaitGroup
+			for i := range digits {
+				digits[i] = lo + 1
+				if n > 1 {
+					digits[i] += i * (hi - lo - 1) / (n - 1)
+				}
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					found[i], errs[i] = exists(last + string(rune(walkKeyFirst+digits[i])))
+				}(i)
+			}
+			wg.Wait()
+			for i, d := range digits {
+				if errs[i] != nil {
+					return "", errs[i]
+				}
+				if !found[i] {
+					hi = d - 1
+					break
+				}
+				lo = d
+			}
+		}
+		if lo < 0 {
+			// no names after the last one found, but for unprintable ones
+			return last, nil
+		}
+		last += string(rune(walkKeyFirst + lo))
+	}
+	return last, nil
+}
+
+// walkKeyspace maps names sharing a common prefix to positions, in name order. The characters past the
+// common prefix are digits of the sampled names' alphabet, one more than the alphabet's size in base
+// with zero for the name's end, so names of a few distinct characters, e.g. decimal or hex, spread over
+// the positions. Characters outside the alphabet take the digit of the alphabet's previous character.
+type walkKeyspace struct {
+	common   string
+	alphabet []byte
+	digits   int
+}
+
+// newWalkKeyspace returns the keyspace of names between given first & last names, over the printable
+// characters of given sample names past their common prefix, all printable ones without any
+func newWalkKeyspace(first, last string, sample ...string) walkKeyspace {
+	ks := walkKeyspace{common: commonPrefix([]string{first, last})}
+	seen := [walkKeyBase]bool{}
+	for _, name := range append(sample, first, last) {
+		if !strings.HasPrefix(name, ks.common) {
+			continue
+		}
+		for i := len(ks.common); i < len(name); i++ {
+			if c := int(name[i]) - walkKeyFirst; c >= 0 && c < walkKeyBase {
+				seen[c] = true
+			}
+		}
+	}
+	for c, ok := range seen {
+		if ok {
+			ks.alphabet = append(ks.alphabet, byte(walkKeyFirst+c))
+		}
+	}
+	if len(ks.alphabet) == 0 {
+		for c := 0; c < walkKeyBase; c++ {
+			ks.alphabet = append(ks.alphabet, byte(walkKeyFirst+c))
+		}
+	}
+	ks.digits = int(walkKeyBits / math.Log2(float64(len(ks.alphabet)+1)))
+	return ks
+}
+
+// key returns the position of given name, names not sharing the common prefix are clamped to its ends
+func (ks walkKeyspace) key(name string) float64 {
+	switch {
+	case name < ks.common:
+		return 0
+	case !strings.HasPrefix(name, ks.common):
+		return ks.key(ks.common + strings.Repeat("\x7f", ks.digits))
+	}
+	base := float64(len(ks.alphabet) + 1)
+	var key float64
+	rest := name[len(ks.common):]
+	for i := 0; i < ks.digits; i++ {
+		d := 0
+		if i < len(rest) {
+			d = sort.Search(len(ks.alphabet), func(j int) bool { return ks.alphabet[j] > rest[i] })
+		}
+		key = key*base + float64(d)
+	}
+	return key
+}
+
+// name returns the name at given position, at or before the names of that position
+func (ks walkKeyspace) name(key float64) string {
+	base := int64(len(ks.alphabet) + 1)
+	digits := make([]int64, ks.digits)
+	k := int64(key)
+	for i := ks.digits - 1; i >= 0; i-- {
+		digits[i] = k % base
+		k /= base
+	}
+	name := []byte(ks.common)
+	for _, d := range digits {
+		if d == 0 {
+			break
+		}
+		name = append(name, ks.alphabet[d-1])
+	}
+	return string(name)
+}
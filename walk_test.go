@@ -0,0 +1,270 @@
+package cloudstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// walkFixture stores given names with listings paged, & objects named around the prefix
+func walkFixture(t *testing.T, names []string) *fakeGCS {
+	f := newFakeGCS()
+	f.pageSize = 50
+	for _, name := range names {
+		f.put("bucket", name, []byte("x"), nil)
+	}
+	for _, name := range []string{"logr/x", "logs-other/x", "logt"} {
+		f.put("bucket", name, []byte("y"), nil)
+	}
+	return f
+}
+
+// seqNames returns count names of given format, of i
+func seqNames(format string, count int) []string {
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf(format, i)
+	}
+	return names
+}
+
+// walkNames walks request's objects, returns the names in walk order, failing the test on duplicates
+func walkNames(t *testing.T, cs *cloudStorageClient, ctx context.Context, cfr CloudFileRequest, opts ...WalkOption) []string {
+	var mu sync.Mutex
+	names := []string{}
+	seen := map[string]bool{}
+	n, err := cs.WalkObjects(ctx, cfr, func(attrs *ObjectAttrs) error {
+		mu.Lock()
+		defer mu.Unlock()
+		require.False(t, seen[attrs.Name], "walked twice %s", attrs.Name)
+		seen[attrs.Name] = true
+		names = append(names, attrs.Name)
+		return nil
+	}, opts...)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(names)), n)
+	return names
+}
+
+func TestWalkObjects(t *testing.T) {
+	want := seqNames("logs/%05d.json", 2000)
+	f := walkFixture(t, want)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "", "logs", 0)
+	require.NoError(t, err)
+
+	require.Equal(t, want, walkNames(t, cs, ctx, cfr))
+
+	// shards walk every object once, in name order within a shard
+	sharded := walkNames(t, cs, ctx, cfr, WithWalkShards(8), WithWalkBuffer(10))
+	require.ElementsMatch(t, want, sharded)
+	require.Equal(t, want, walkNames(t, cs, ctx, cfr, WithWalkShards(8), WithWalkBuffer(10), WithWalkOrdered()))
+
+	// uniform names are sampled into about equal shards
+	bounds, err := cs.walkBounds(ctx, cfr, "logs/", 4)
+	require.NoError(t, err)
+	require.Len(t, bounds, 3)
+	sizes := shardSizes(want, bounds)
+	for _, size := range sizes {
+		require.InDelta(t, 500, size, 100, "shard sizes %v, bounds %v", sizes, bounds)
+	}
+
+	// a single object is one shard
+	f = walkFixture(t, []string{"logs/only"})
+	cs = newFakeClient(t, f)
+	require.Equal(t, []string{"logs/only"}, walkNames(t, cs, ctx, cfr, WithWalkShards(4)))
+	bounds, err = cs.walkBounds(ctx, cfr, "logs/", 4)
+	require.NoError(t, err)
+	require.Empty(t, bounds)
+	require.Equal(t, []string{"logs/only"}, walkNames(t, cs, ctx, cfr, WithWalkBounds("logs/a"), WithWalkOrdered()))
+}
+
+// shardSizes returns the number of given sorted names in each shard of given bounds
+func shardSizes(names, bounds []string) []int {
+	sizes := make([]int, len(bounds)+1)
+	for _, name := range names {
+		sizes[sort.SearchStrings(bounds, name+"\x00")]++
+	}
+	return sizes
+}
+
+func TestWalkObjectsBounds(t *testing.T) {
+	want := append(seqNames("logs/%04d", 1000), "logs/0500", "logs/0500 ", "logs/0500!", "logs/0500\x01", "logs/\x7f", "logs/é")
+	sort.Strings(want)
+	want = dedupeSorted(want)
+	f := walkFixture(t, want)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "", "logs", 0)
+	require.NoError(t, err)
+
+	// names at, just before & just after bounds are walked once, by the shard the bound starts
+	for _, bounds := range [][]string{
+		{"logs/0500"},
+		{"logs/0500\x01", "logs/0500 "},
+		{"logs/", "logs/0000", "logs/0999", "logs/~"},
+		{"logs/0250", "logs/0500!", "logs/0750", "logs/\x7f", "logs/é"},
+	} {
+		require.Equal(t, want, walkNames(t, cs, ctx, cfr, WithWalkBounds(bounds...), WithWalkOrdered()), "bounds %q", bounds)
+		require.ElementsMatch(t, want, walkNames(t, cs, ctx, cfr, WithWalkBounds(bounds...)), "bounds %q", bounds)
+	}
+
+	for _, bounds := range [][]string{{"logs/2", "logs/1"}, {"logs/1", "logs/1"}, {"other/1"}, {"logr/x"}} {
+		_, err := cs.WalkObjects(ctx, cfr, func(*ObjectAttrs) error { return nil }, WithWalkBounds(bounds...))
+		require.ErrorIs(t, err, ErrInvalidWalkBounds, "bounds %q", bounds)
+	}
+	_, err = cs.WalkObjects(ctx, cfr, nil)
+	require.ErrorIs(t, err, ErrWalkFuncRequired)
+}
+
+// dedupeSorted returns given sorted names without repeats
+func dedupeSorted(names []string) []string {
+	out := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func TestWalkObjectsSkewed(t *testing.T) {
+	// clusters at both ends of the keyspace, a few names between, names with a long common prefix
+	want := append(seqNames("logs/a%04d", 1000), seqNames("logs/zz/%04d", 1000)...)
+	want = append(want, seqNames("logs/m%d", 10)...)
+	want = append(want, seqNames("logs/"+strings.Repeat("p", 40)+"/%03d", 300)...)
+	sort.Strings(want)
+	f := walkFixture(t, want)
+	cs := newFakeClient(t, f)
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "", "logs", 0)
+	require.NoError(t, err)
+
+	bounds, err := cs.walkBounds(ctx, cfr, "logs/", 8)
+	require.NoError(t, err)
+	require.NotEmpty(t, bounds)
+	require.True(t, sort.StringsAreSorted(bounds))
+	// the clusters are split, no shard holds most names
+	for _, size := range shardSizes(want, bounds) {
+		require.Less(t, size, len(want)/2, "bounds %q", bounds)
+	}
+	require.ElementsMatch(t, want, walkNames(t, cs, ctx, cfr, WithWalkShards(8)))
+	require.Equal(t, want, walkNames(t, cs, ctx, cfr, WithWalkShards(8), WithWalkOrdered()))
+}
+
+func TestWalkLastName(t *testing.T) {
+	names := append(seqNames("logs/0%05d.json", 2000), "logs/ab", "logs/abc~~~~~~~~~~~zzz")
+	sort.Strings(names)
+	var probes int64
+	exists := func(names []string) func(string) (bool, error) {
+		return func(from string) (bool, error) {
+			atomic.AddInt64(&probes, 1)
+			return sort.SearchStrings(names, from) < len(names), nil
+		}
+	}
+
+	last, err := walkLastName("logs/", "logs/000000.json", 8, exists(names[:2000]))
+	require.NoError(t, err)
+	require.Equal(t, "logs/001999", last, "4 characters past the common prefix are resolved")
+	require.Less(t, atomic.LoadInt64(&probes), int64(6*8*3))
+
+	for _, width := range []int{2, 3, 95, 200} {
+		last, err = walkLastName("logs/", "logs/000000.json", width, exists(names))
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(names[len(names)-1], last), "width %d found %s", width, last)
+	}
+	last, err = walkLastName("logs/", "logs/ab", 4, exists([]string{"logs/ab"}))
+	require.NoError(t, err)
+	require.Equal(t, "logs/ab", last)
+
+	failed := errors.New("probe failed")
+	_, err = walkLastName("logs/", "logs/ab", 4, func(string) (bool, error) { return false, failed })
+	require.ErrorIs(t, err, failed)
+
+	ks := newWalkKeyspace("logs/a", "logs/z", "logs/m/n", "logs/a!")
+	require.Equal(t, "logs/", ks.common)
+	for _, name := range []string{"logs/a", "logs/a ", "logs/a!", "logs/a\x01", "logs/é", "logs/m/n/o/p/q/r/s"} {
+		require.LessOrEqual(t, ks.name(ks.key(name)), name, "positions' names come at or before the names")
+	}
+	require.Less(t, ks.key("logs/a"), ks.key("logs/a!"))
+	require.Less(t, ks.key("logs/a!"), ks.key("logs/m"))
+	require.Equal(t, ks.key("logs/a"), ks.key("logs/b"), "characters outside the alphabet take the previous one's digit")
+}
+
+func TestWalkObjectsScoped(t *testing.T) {
+	f := walkFixture(t, append(seqNames("tenant-a/logs/%03d", 300), "tenant-b/logs/000"))
+	cs := newFakeClient(t, f)
+	ctx := tenantContext()
+	cfr, err := NewScopedFileRequest(ctx, "", "logs", 0)
+	require.NoError(t, err)
+
+	want := seqNames("logs/%03d", 300)
+	require.Equal(t, want, walkNames(t, cs, ctx, cfr, WithWalkShards(4), WithWalkOrdered()))
+	require.Equal(t, want, walkNames(t, cs, ctx, cfr, WithWalkBounds("logs/100", "logs/200"), WithWalkOrdered()))
+	_, err = cs.WalkObjects(ctx, cfr, func(*ObjectAttrs) error { return nil }, WithWalkBounds("tenant-a/logs/100"))
+	require.ErrorIs(t, err, ErrInvalidWalkBounds, "bounds are relative to the scope")
+}
+
+func TestWalkObjectsErrors(t *testing.T) {
+	f := walkFixture(t, seqNames("logs/%04d", 1000))
+	cs := newFakeClient(t, f)
+	cs.config.Retry = &RetryPolicy{MaxAttempts: 1}
+	ctx := context.Background()
+	cfr, err := NewCloudFileRequest("bucket", "", "logs", 0)
+	require.NoError(t, err)
+
+	// the walk func's error stops every shard
+	stop := errors.New("stop")
+	var walked int
+	n, err := cs.WalkObjects(ctx, cfr, func(attrs *ObjectAttrs) error {
+		if walked++; walked == 100 {
+			return stop
+		}
+		return nil
+	}, WithWalkShards(4), WithWalkBuffer(1))
+	require.ErrorIs(t, err, stop)
+	require.Equal(t, int64(99), n)
+	require.Equal(t, 100, walked, "no calls after the error")
+
+	// a failed shard fails the walk
+	f.fail = func(r *http.Request) int {
+		if r.URL.Query().Get("startOffset") == "logs/0500" {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	_, err = cs.WalkObjects(ctx, cfr, func(*ObjectAttrs) error { return nil }, WithWalkBounds("logs/0250", "logs/0500", "logs/0750"), WithWalkOrdered())
+	require.ErrorIs(t, err, ErrPermissionDenied)
+	var se StorageError
+	require.ErrorAs(t, err, &se)
+	require.Equal(t, "WalkObjects", se.Op)
+
+	// so does the sampling pass
+	f.fail = func(r *http.Request) int {
+		if r.URL.Query().Get("maxResults") == "1" {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	_, err = cs.WalkObjects(ctx, cfr, func(*ObjectAttrs) error { return nil }, WithWalkShards(4))
+	require.ErrorIs(t, err, ErrPermissionDenied)
+	require.Contains(t, err.Error(), ERROR_SAMPLING_WALK_BOUNDS)
+
+	// cancelled walks return the context's error
+	f.fail = nil
+	cctx, cancel := context.WithCancel(ctx)
+	_, err = cs.WalkObjects(cctx, cfr, func(*ObjectAttrs) error {
+		cancel()
+		return nil
+	}, WithWalkShards(4))
+	require.ErrorIs(t, err, context.Canceled)
+}